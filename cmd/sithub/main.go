@@ -43,6 +43,7 @@ func main() {
 	opts.bindFlags(runCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(newVersionCmd())
+	rootCmd.AddCommand(newItemsCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -61,6 +62,45 @@ func newVersionCmd() *cobra.Command {
 	}
 }
 
+// newItemsCmd builds the "items" command group for maintenance of item
+// records.
+func newItemsCmd() *cobra.Command {
+	itemsCmd := &cobra.Command{
+		Use:   "items",
+		Short: "Maintain the records of bookable items",
+	}
+
+	opts := newRunOptions()
+	var from, to string
+	var force bool
+	remapCmd := &cobra.Command{
+		Use:   "remap",
+		Short: "Move the bookings and other records of an item to a new item ID",
+		Long: "Move the bookings, floor plan positions, and other records of an item to a new item ID, " +
+			"e.g. after renaming the item in the areas config. Fails if both items are booked on the same day.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := config.LoadWithOverrides(opts.configPath, opts.overrides(cmd))
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			moved, err := startup.RemapItem(cmd.Context(), cfg, from, to, force)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Moved %d records from %s to %s\n", moved, from, to)
+			return nil
+		},
+	}
+	opts.bindFlags(remapCmd)
+	remapCmd.Flags().StringVar(&from, "from", "", "Former item ID")
+	remapCmd.Flags().StringVar(&to, "to", "", "New item ID")
+	remapCmd.Flags().BoolVar(&force, "force", false, "Allow a new item ID that is not in the areas config")
+	_ = remapCmd.MarkFlagRequired("from") //nolint:errcheck // Flag is defined above
+	_ = remapCmd.MarkFlagRequired("to")   //nolint:errcheck // Flag is defined above
+	itemsCmd.AddCommand(remapCmd)
+	return itemsCmd
+}
+
 type runOptions struct {
 	configPath           string
 	listen               string
@@ -75,6 +75,17 @@ func WriteTooManyRequests(c echo.Context, detail string) error {
 	return nil
 }
 
+// WriteError writes a JSON:API error response with an arbitrary status and code.
+// The title is derived from the HTTP status text.
+func WriteError(c echo.Context, status int, detail, code string) error {
+	errResp := NewError(status, http.StatusText(status), detail, code)
+	c.Response().Header().Set(echo.HeaderContentType, JSONAPIContentType)
+	if err := c.JSON(status, errResp); err != nil {
+		return fmt.Errorf("write error response: %w", err)
+	}
+	return nil
+}
+
 // WriteUnsupportedMediaType writes a JSON:API unsupported media type error response.
 func WriteUnsupportedMediaType(c echo.Context, detail string) error {
 	errResp := NewError(http.StatusUnsupportedMediaType, "Unsupported Media Type", detail, "unsupported_media_type")
@@ -64,3 +64,16 @@ func assertErrorResponse(t *testing.T, rec *httptest.ResponseRecorder, status in
 		t.Fatalf("unexpected error response: %#v", resp.Errors)
 	}
 }
+
+func TestWriteError(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := WriteError(c, http.StatusUnprocessableEntity, "Office closed", "area_closed"); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	assertErrorResponse(t, rec, http.StatusUnprocessableEntity, "area_closed")
+}
@@ -0,0 +1,59 @@
+// Package api defines JSON:API response helpers.
+//
+//revive:disable-next-line var-naming
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Headers of the ETag / If-Match checks.
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// ETag returns the entity tag of a resource version.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// SetETag sets the ETag response header to the resource version.
+func SetETag(c echo.Context, version int) {
+	c.Response().Header().Set(HeaderETag, ETag(version))
+}
+
+// IfMatch returns the resource version required by the If-Match request
+// header: 0 when the header is absent or "*", and -1 when it names no version,
+// so that no stored version matches. Weak tags never match, as If-Match uses
+// the strong comparison (RFC 9110, section 13.1.1).
+func IfMatch(c echo.Context) int {
+	value := strings.TrimSpace(c.Request().Header.Get(HeaderIfMatch))
+	if value == "" || value == "*" {
+		return 0
+	}
+	if strings.HasPrefix(value, "W/") {
+		return -1
+	}
+	version, err := strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil || version < 1 {
+		return -1
+	}
+	return version
+}
+
+// Matches reports whether a stored version satisfies the required version
+// returned by IfMatch.
+func Matches(required, version int) bool {
+	return required == 0 || required == version
+}
+
+// WritePreconditionFailed writes a JSON:API 412 response for a stale If-Match.
+func WritePreconditionFailed(c echo.Context) error {
+	return WriteError(c, http.StatusPreconditionFailed,
+		"The resource was modified since it was read; reload it and retry", "precondition_failed")
+}
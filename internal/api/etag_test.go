@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatch(t *testing.T) {
+	t.Parallel()
+	tests := map[string]int{
+		"":         0,
+		"*":        0,
+		`"3"`:      3,
+		`W/"3"`:    -1,
+		"7":        7,
+		`"0"`:      -1,
+		`"abc"`:    -1,
+		`"1", "2"`: -1,
+	}
+	for header, want := range tests {
+		req := httptest.NewRequest(http.MethodPatch, "/", http.NoBody)
+		req.Header.Set("If-Match", header)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		got := IfMatch(c)
+		assert.Equal(t, want, got, header)
+		assert.Equal(t, want >= 0 && (want == 0 || want == 3), Matches(got, 3), header)
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	t.Parallel()
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", http.NoBody), rec)
+	SetETag(c, 4)
+	assert.Equal(t, `"4"`, rec.Header().Get("ETag"))
+
+	c = echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", http.NoBody), rec)
+	assert.NoError(t, WritePreconditionFailed(c))
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
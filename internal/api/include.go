@@ -0,0 +1,63 @@
+// Package api defines JSON:API response helpers.
+//
+//revive:disable-next-line var-naming
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Include is the set of relationship paths requested with the include
+// parameter. A path also includes its prefixes, so "item.item_group" includes
+// "item".
+type Include map[string]bool
+
+// ParseInclude reads the include parameter. Paths not listed in allowed are
+// rejected with a *QueryError.
+func ParseInclude(c echo.Context, allowed []string) (Include, error) {
+	include := Include{}
+	for _, path := range splitList(c.QueryParam("include")) {
+		if !slices.Contains(allowed, path) {
+			return nil, &QueryError{Detail: fmt.Sprintf("Unsupported include path '%s'", path)}
+		}
+		parts := strings.Split(path, ".")
+		for i := range parts {
+			include[strings.Join(parts[:i+1], ".")] = true
+		}
+	}
+	return include, nil
+}
+
+// Included collects the resources of a compound document, once per type and ID.
+type Included struct {
+	seen      map[ResourceIdentifier]struct{}
+	resources []Resource
+}
+
+// Add adds a resource unless it was added before.
+func (in *Included) Add(r Resource) {
+	key := ResourceIdentifier{Type: r.Type, ID: r.ID}
+	if in.seen == nil {
+		in.seen = map[ResourceIdentifier]struct{}{}
+	}
+	if _, ok := in.seen[key]; ok {
+		return
+	}
+	in.seen[key] = struct{}{}
+	in.resources = append(in.resources, r)
+}
+
+// Has reports whether a resource of that type and ID was added.
+func (in *Included) Has(resourceType, id string) bool {
+	_, ok := in.seen[ResourceIdentifier{Type: resourceType, ID: id}]
+	return ok
+}
+
+// Resources returns the collected resources in the order they were added.
+func (in *Included) Resources() []Resource {
+	return in.resources
+}
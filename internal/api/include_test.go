@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIncludeAddsPrefixes(t *testing.T) {
+	t.Parallel()
+	allowed := []string{"item", "item.item_group", "user"}
+
+	c, _ := queryContext("include=item.item_group,user")
+	include, err := ParseInclude(c, allowed)
+	require.NoError(t, err)
+	assert.Equal(t, Include{"item": true, "item.item_group": true, "user": true}, include)
+
+	c, _ = queryContext("")
+	include, err = ParseInclude(c, allowed)
+	require.NoError(t, err)
+	assert.Empty(t, include)
+
+	c, _ = queryContext("include=item.area")
+	_, err = ParseInclude(c, allowed)
+	var queryErr *QueryError
+	require.ErrorAs(t, err, &queryErr)
+	assert.Contains(t, queryErr.Detail, "item.area")
+}
+
+func TestIncludedAddsEachResourceOnce(t *testing.T) {
+	t.Parallel()
+	var included Included
+	assert.False(t, included.Has("items", "desk-1"))
+	included.Add(Resource{Type: "items", ID: "desk-1"})
+	included.Add(Resource{Type: "users", ID: "desk-1"})
+	included.Add(Resource{Type: "items", ID: "desk-1", Attributes: map[string]any{"name": "again"}})
+	assert.True(t, included.Has("items", "desk-1"))
+	require.Len(t, included.Resources(), 2)
+	assert.Nil(t, included.Resources()[0].Attributes)
+}
+
+func TestRelationshipJSON(t *testing.T) {
+	t.Parallel()
+	r := Resource{Type: "bookings", ID: "b1", Relationships: map[string]Relationship{
+		"item": ToOne("items", "desk-1"),
+		"user": ToOne("users", ""),
+	}}
+	raw, err := json.Marshal(r)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"bookings","id":"b1","relationships":{`+
+		`"item":{"data":{"type":"items","id":"desk-1"}},"user":{"data":null}}}`, string(raw))
+}
@@ -0,0 +1,432 @@
+// Package api defines JSON:API response helpers.
+//
+//revive:disable-next-line var-naming
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MaxPageSize is the largest page[size] a collection accepts.
+const MaxPageSize = 200
+
+// bracketParam matches JSON:API family parameters such as filter[name].
+var bracketParam = regexp.MustCompile(`^(filter|fields|page)\[([a-z0-9_-]+)\]$`)
+
+// QueryOptions lists the attributes a collection can be sorted and filtered by.
+// The resource ID can always be used as "id".
+type QueryOptions struct {
+	Sort    []string
+	Filters []string
+}
+
+// SortKey is one field of a sort parameter.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Query holds the page[size], page[cursor], sort, filter[...], and fields[...]
+// parameters of a collection request. Without page[size] the whole collection
+// is returned; without sort the handler's order is kept.
+type Query struct {
+	Size    int
+	Sort    []SortKey
+	Filters map[string][]string
+	Fields  map[string][]string
+	after   *queryCursor
+	sortRaw string
+}
+
+// queryCursor marks the last resource of a page by its sort values and ID.
+type queryCursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v,omitempty"`
+	ID     string `json:"id"`
+}
+
+// Cursor is the position page[cursor] resumes after: the sort values and ID
+// of the last resource of the previous page.
+type Cursor struct {
+	Values []any
+	ID     string
+}
+
+// QueryError reports an invalid collection query parameter.
+type QueryError struct {
+	Detail string
+}
+
+func (e *QueryError) Error() string { return e.Detail }
+
+// ParseQuery reads the JSON:API collection parameters of a request. Sort and
+// filter fields not listed in opts are rejected with a *QueryError.
+func ParseQuery(c echo.Context, opts QueryOptions) (*Query, error) {
+	q := &Query{Filters: map[string][]string{}, Fields: map[string][]string{}}
+	page, err := q.parseFamilies(c, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.parseSort(c.QueryParam("sort"), opts); err != nil {
+		return nil, err
+	}
+	if err := q.parsePage(page["size"], page["cursor"]); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// parseFamilies reads the filter[...] and fields[...] parameters and returns
+// the page[...] parameters.
+func (q *Query) parseFamilies(c echo.Context, opts QueryOptions) (map[string]string, error) {
+	page := map[string]string{}
+	for name, values := range c.QueryParams() {
+		m := bracketParam.FindStringSubmatch(name)
+		if m == nil || len(values) == 0 {
+			continue
+		}
+		family, field := m[1], m[2]
+		switch family {
+		case "filter":
+			if field != "id" && !slices.Contains(opts.Filters, field) {
+				return nil, &QueryError{Detail: fmt.Sprintf("Unsupported filter '%s'", field)}
+			}
+			q.Filters[field] = splitList(values[0])
+		case "fields":
+			q.Fields[field] = splitList(values[0])
+		default:
+			if field != "size" && field != "cursor" {
+				return nil, &QueryError{Detail: fmt.Sprintf("Unsupported page parameter '%s'", field)}
+			}
+			page[field] = values[0]
+		}
+	}
+	return page, nil
+}
+
+func (q *Query) parseSort(raw string, opts QueryOptions) error {
+	q.sortRaw = strings.TrimSpace(raw)
+	for _, field := range splitList(q.sortRaw) {
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if key.Field != "id" && !slices.Contains(opts.Sort, key.Field) {
+			return &QueryError{Detail: fmt.Sprintf("Unsupported sort field '%s'", key.Field)}
+		}
+		q.Sort = append(q.Sort, key)
+	}
+	return nil
+}
+
+func (q *Query) parsePage(rawSize, rawCursor string) error {
+	if rawSize != "" {
+		size, err := strconv.Atoi(rawSize)
+		if err != nil || size < 1 || size > MaxPageSize {
+			return &QueryError{Detail: fmt.Sprintf("page[size] must be between 1 and %d", MaxPageSize)}
+		}
+		q.Size = size
+	}
+	if rawCursor != "" {
+		cursor, err := decodeQueryCursor(rawCursor)
+		if err != nil || cursor.Sort != q.sortRaw {
+			return &QueryError{Detail: "page[cursor] is invalid or was issued for a different sort"}
+		}
+		q.after = cursor
+	}
+	return nil
+}
+
+// Cursor returns the position of page[cursor], or nil without one. Handlers
+// that page in SQL resume after it instead of calling Apply.
+func (q *Query) Cursor() *Cursor {
+	if q.after == nil {
+		return nil
+	}
+	return &Cursor{Values: q.after.Values, ID: q.after.ID}
+}
+
+// NextCursor returns the page[cursor] value that resumes after cur.
+func (q *Query) NextCursor(cur *Cursor) string {
+	return (&queryCursor{Sort: q.sortRaw, Values: cur.Values, ID: cur.ID}).encode()
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+func (cur *queryCursor) encode() string {
+	raw, err := json.Marshal(cur)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeQueryCursor(s string) (*queryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decode cursor: %w", err)
+	}
+	var cursor queryCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, fmt.Errorf("parse cursor: %w", err)
+	}
+	return &cursor, nil
+}
+
+// queryItem is a resource with its attributes decoded for filtering and sorting.
+type queryItem struct {
+	resource Resource
+	attrs    map[string]any
+}
+
+func (it *queryItem) value(field string) any {
+	if field == "id" {
+		return it.resource.ID
+	}
+	return it.attrs[field]
+}
+
+// Apply filters, sorts, pages, and trims resources according to q. It returns
+// the page, the number of resources matching the filters, and the cursor of
+// the next page ("" on the last page).
+func (q *Query) Apply(resources []Resource) (page []Resource, total int, next string) {
+	items := make([]queryItem, 0, len(resources))
+	for _, r := range resources {
+		it := queryItem{resource: r, attrs: attributeMap(r.Attributes)}
+		if q.matches(&it) {
+			items = append(items, it)
+		}
+	}
+	total = len(items)
+
+	if len(q.Sort) > 0 {
+		sort.SliceStable(items, func(i, j int) bool { return q.compare(&items[i], &items[j]) < 0 })
+	}
+	start := q.start(items)
+	end := len(items)
+	if q.Size > 0 && start+q.Size < end {
+		end = start + q.Size
+		next = q.cursorFor(&items[end-1]).encode()
+	}
+
+	page = make([]Resource, 0, end-start)
+	for i := start; i < end; i++ {
+		page = append(page, q.trim(&items[i]))
+	}
+	return page, total, next
+}
+
+func (q *Query) matches(it *queryItem) bool {
+	for field, wanted := range q.Filters {
+		actual := formatValue(it.value(field))
+		if !slices.ContainsFunc(wanted, func(w string) bool { return strings.EqualFold(w, actual) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare orders two items by the sort keys, then by ID.
+func (q *Query) compare(a, b *queryItem) int {
+	for _, key := range q.Sort {
+		if c := compareValues(a.value(key.Field), b.value(key.Field)); c != 0 {
+			if key.Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return strings.Compare(a.resource.ID, b.resource.ID)
+}
+
+// start returns the index of the first item after the cursor. Without sort
+// keys the cursor resumes after the item with the cursor's ID.
+func (q *Query) start(items []queryItem) int {
+	if q.after == nil {
+		return 0
+	}
+	if len(q.Sort) == 0 {
+		for i := range items {
+			if items[i].resource.ID == q.after.ID {
+				return i + 1
+			}
+		}
+		return len(items)
+	}
+	marker := queryItem{resource: Resource{ID: q.after.ID}, attrs: map[string]any{}}
+	for i, key := range q.Sort {
+		if i < len(q.after.Values) {
+			marker.attrs[key.Field] = q.after.Values[i]
+		}
+	}
+	return sort.Search(len(items), func(i int) bool { return q.compare(&items[i], &marker) > 0 })
+}
+
+func (q *Query) cursorFor(it *queryItem) *queryCursor {
+	cur := &queryCursor{Sort: q.sortRaw, ID: it.resource.ID}
+	for _, key := range q.Sort {
+		cur.Values = append(cur.Values, it.value(key.Field))
+	}
+	return cur
+}
+
+// trim applies the sparse fieldset of the resource's type.
+func (q *Query) trim(it *queryItem) Resource {
+	fields, ok := q.Fields[it.resource.Type]
+	if !ok {
+		return it.resource
+	}
+	attrs := make(map[string]any, len(fields))
+	for _, field := range fields {
+		if v, ok := it.attrs[field]; ok {
+			attrs[field] = v
+		}
+	}
+	r := it.resource
+	r.Attributes = attrs
+	return r
+}
+
+// attributeMap decodes resource attributes into a map keyed by JSON name.
+func attributeMap(attrs any) map[string]any {
+	if m, ok := attrs.(map[string]any); ok {
+		return m
+	}
+	result := map[string]any{}
+	raw, err := json.Marshal(attrs)
+	if err != nil {
+		return result
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return map[string]any{}
+	}
+	return result
+}
+
+// compareValues orders numbers numerically, booleans false first, and
+// everything else by its text. Missing values sort first.
+func compareValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(formatValue(a), formatValue(b))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func formatValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// WriteQueryCollection applies q to resources and writes the page with
+// links.self, links.next, and meta.total.
+func WriteQueryCollection(c echo.Context, q *Query, resources []Resource, errLabel string) error {
+	return WriteCompoundCollection(c, q, resources, nil, errLabel)
+}
+
+// WriteCompoundCollection is WriteQueryCollection for compound documents:
+// include, when not nil, returns the resources related to the page, which are
+// written as included. Sparse fieldsets apply to them too.
+func WriteCompoundCollection(
+	c echo.Context, q *Query, resources []Resource, include func(page []Resource) []Resource, errLabel string,
+) error {
+	page, total, next := q.Apply(resources)
+	return writeCollectionPage(c, q, page, total, next, include, errLabel)
+}
+
+// WritePagedCollection writes a page the handler already filtered, sorted,
+// and cut, for example in SQL. total is the number of resources matching the
+// filters and next the page[cursor] of the next page ("" on the last page).
+// Sparse fieldsets and include work as in WriteCompoundCollection.
+func WritePagedCollection(
+	c echo.Context, q *Query, resources []Resource, total int, next string,
+	include func(page []Resource) []Resource, errLabel string,
+) error {
+	page := make([]Resource, 0, len(resources))
+	for _, r := range resources {
+		if _, ok := q.Fields[r.Type]; !ok {
+			page = append(page, r)
+			continue
+		}
+		it := queryItem{resource: r, attrs: attributeMap(r.Attributes)}
+		page = append(page, q.trim(&it))
+	}
+	return writeCollectionPage(c, q, page, total, next, include, errLabel)
+}
+
+func writeCollectionPage(
+	c echo.Context, q *Query, page []Resource, total int, next string,
+	include func(page []Resource) []Resource, errLabel string,
+) error {
+	resp := CollectionResponse{
+		Data:  page,
+		Links: &Links{Self: c.Request().URL.RequestURI()},
+		Meta:  &Meta{Total: total},
+	}
+	if include != nil {
+		for _, r := range include(page) {
+			it := queryItem{resource: r, attrs: attributeMap(r.Attributes)}
+			resp.Included = append(resp.Included, q.trim(&it))
+		}
+	}
+	if next != "" {
+		u := *c.Request().URL
+		params := u.Query()
+		params.Set("page[cursor]", next)
+		u.RawQuery = params.Encode()
+		resp.Links.Next = u.RequestURI()
+	}
+	c.Response().Header().Set(echo.HeaderContentType, JSONAPIContentType)
+	if err := c.JSON(http.StatusOK, resp); err != nil {
+		return fmt.Errorf("%s: %w", errLabel, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type personAttributes struct {
+	Name string `json:"name"`
+	Team string `json:"team"`
+	Age  int    `json:"age"`
+}
+
+var personOptions = QueryOptions{Sort: []string{"name", "age"}, Filters: []string{"team"}}
+
+func people() []Resource {
+	return []Resource{
+		{Type: "people", ID: "p1", Attributes: personAttributes{Name: "Carol", Team: "ops", Age: 41}},
+		{Type: "people", ID: "p2", Attributes: personAttributes{Name: "Alice", Team: "dev", Age: 9}},
+		{Type: "people", ID: "p3", Attributes: personAttributes{Name: "Bob", Team: "dev", Age: 30}},
+		{Type: "people", ID: "p4", Attributes: personAttributes{Name: "Dave", Team: "ops", Age: 30}},
+	}
+}
+
+func queryContext(rawQuery string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/people?"+rawQuery, http.NoBody)
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+func writePeople(t *testing.T, rawQuery string) (*httptest.ResponseRecorder, CollectionResponse) {
+	t.Helper()
+	c, rec := queryContext(rawQuery)
+	q, err := ParseQuery(c, personOptions)
+	require.NoError(t, err)
+	require.NoError(t, WriteQueryCollection(c, q, people(), "write people"))
+	var resp CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec, resp
+}
+
+func ids(resources []Resource) []string {
+	result := make([]string, len(resources))
+	for i, r := range resources {
+		result[i] = r.ID
+	}
+	return result
+}
+
+func TestWriteQueryCollectionKeepsOrderWithoutParams(t *testing.T) {
+	t.Parallel()
+	rec, resp := writePeople(t, "")
+	assert.Equal(t, JSONAPIContentType, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, []string{"p1", "p2", "p3", "p4"}, ids(resp.Data))
+	require.NotNil(t, resp.Meta)
+	assert.Equal(t, 4, resp.Meta.Total)
+	require.NotNil(t, resp.Links)
+	assert.Equal(t, "/api/v1/people?", resp.Links.Self)
+	assert.Empty(t, resp.Links.Next)
+}
+
+func TestWriteQueryCollectionSortsAndFilters(t *testing.T) {
+	t.Parallel()
+	_, resp := writePeople(t, "sort=name")
+	assert.Equal(t, []string{"p2", "p3", "p1", "p4"}, ids(resp.Data))
+
+	_, resp = writePeople(t, "sort=-age,name")
+	assert.Equal(t, []string{"p1", "p3", "p4", "p2"}, ids(resp.Data), "numbers sort numerically")
+
+	_, resp = writePeople(t, url.Values{"filter[team]": {"OPS"}, "sort": {"-name"}}.Encode())
+	assert.Equal(t, []string{"p4", "p1"}, ids(resp.Data))
+	assert.Equal(t, 2, resp.Meta.Total)
+
+	_, resp = writePeople(t, url.Values{"filter[id]": {"p1,p3"}}.Encode())
+	assert.Equal(t, []string{"p1", "p3"}, ids(resp.Data))
+}
+
+func TestWriteQueryCollectionPagesWithCursor(t *testing.T) {
+	t.Parallel()
+	for _, sort := range []string{"", "age"} {
+		var seen []string
+		query := url.Values{"page[size]": {"3"}}
+		if sort != "" {
+			query.Set("sort", sort)
+		}
+		rawQuery := query.Encode()
+		for range 4 {
+			_, resp := writePeople(t, rawQuery)
+			assert.Equal(t, 4, resp.Meta.Total)
+			seen = append(seen, ids(resp.Data)...)
+			if resp.Links.Next == "" {
+				break
+			}
+			_, rawQuery, _ = strings.Cut(resp.Links.Next, "?")
+		}
+		if sort == "" {
+			assert.Equal(t, []string{"p1", "p2", "p3", "p4"}, seen)
+		} else {
+			assert.Equal(t, []string{"p2", "p3", "p4", "p1"}, seen)
+		}
+	}
+}
+
+func TestWritePagedCollectionResumesAfterCursor(t *testing.T) {
+	t.Parallel()
+	c, rec := queryContext(url.Values{"sort": {"name"}, "page[size]": {"1"}, "fields[people]": {"name"}}.Encode())
+	q, err := ParseQuery(c, personOptions)
+	require.NoError(t, err)
+	assert.Nil(t, q.Cursor())
+	next := q.NextCursor(&Cursor{Values: []any{"Alice"}, ID: "p2"})
+	require.NoError(t, WritePagedCollection(c, q, people()[1:2], 4, next, nil, "write people"))
+
+	var resp CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"p2"}, ids(resp.Data))
+	assert.Equal(t, map[string]any{"name": "Alice"}, resp.Data[0].Attributes)
+	assert.Equal(t, 4, resp.Meta.Total)
+	_, rawQuery, _ := strings.Cut(resp.Links.Next, "?")
+
+	c, _ = queryContext(rawQuery)
+	q, err = ParseQuery(c, personOptions)
+	require.NoError(t, err)
+	assert.Equal(t, &Cursor{Values: []any{"Alice"}, ID: "p2"}, q.Cursor())
+}
+
+func TestWriteQueryCollectionAppliesSparseFieldsets(t *testing.T) {
+	t.Parallel()
+	_, resp := writePeople(t, url.Values{"fields[people]": {"name"}, "filter[id]": {"p1"}}.Encode())
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, map[string]any{"name": "Carol"}, resp.Data[0].Attributes)
+
+	_, resp = writePeople(t, url.Values{"fields[other]": {"name"}, "filter[id]": {"p1"}}.Encode())
+	assert.Equal(t, map[string]any{"name": "Carol", "team": "ops", "age": float64(41)}, resp.Data[0].Attributes)
+}
+
+func TestParseQueryRejectsInvalidParams(t *testing.T) {
+	t.Parallel()
+	c, _ := queryContext(url.Values{"sort": {"age"}, "page[size]": {"1"}}.Encode())
+	q, err := ParseQuery(c, personOptions)
+	require.NoError(t, err)
+	_, _, next := q.Apply(people())
+	require.NotEmpty(t, next)
+
+	for _, raw := range []url.Values{
+		{"sort": {"team"}},
+		{"filter[name]": {"Alice"}},
+		{"page[size]": {"0"}},
+		{"page[size]": {"201"}},
+		{"page[number]": {"2"}},
+		{"page[cursor]": {"garbage"}},
+		{"page[cursor]": {next}, "sort": {"-age"}},
+	} {
+		c, _ := queryContext(raw.Encode())
+		_, err := ParseQuery(c, personOptions)
+		var queryErr *QueryError
+		assert.ErrorAs(t, err, &queryErr, raw.Encode())
+	}
+}
@@ -15,19 +15,59 @@ const JSONAPIContentType = "application/vnd.api+json"
 
 // Resource represents a JSON:API resource object.
 type Resource struct {
-	ID         string      `json:"id,omitempty"`
-	Type       string      `json:"type"`
-	Attributes interface{} `json:"attributes,omitempty"`
+	ID            string                  `json:"id,omitempty"`
+	Type          string                  `json:"type"`
+	Attributes    interface{}             `json:"attributes,omitempty"`
+	Relationships map[string]Relationship `json:"relationships,omitempty"`
+	Links         *Links                  `json:"links,omitempty"`
+}
+
+// ResourceIdentifier identifies a related resource.
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Relationship represents a to-one relationship. Data is null when nothing is related.
+type Relationship struct {
+	Data  *ResourceIdentifier `json:"data"`
+	Links *Links              `json:"links,omitempty"`
+}
+
+// ToOne returns a to-one relationship, or an empty one when id is empty.
+func ToOne(resourceType, id string) Relationship {
+	if id == "" {
+		return Relationship{}
+	}
+	return Relationship{Data: &ResourceIdentifier{Type: resourceType, ID: id}}
 }
 
 // SingleResponse wraps a single resource.
 type SingleResponse struct {
-	Data Resource `json:"data"`
+	Data     Resource   `json:"data"`
+	Included []Resource `json:"included,omitempty"`
 }
 
 // CollectionResponse wraps a collection of resources.
 type CollectionResponse struct {
-	Data []Resource `json:"data"`
+	Data     []Resource `json:"data"`
+	Included []Resource `json:"included,omitempty"`
+	Links    *Links     `json:"links,omitempty"`
+	Meta     *Meta      `json:"meta,omitempty"`
+}
+
+// Links holds the links of a document, resource, or relationship. Next is
+// empty on the last page.
+type Links struct {
+	Self    string `json:"self,omitempty"`
+	Related string `json:"related,omitempty"`
+	Next    string `json:"next,omitempty"`
+}
+
+// Meta holds collection metadata. Total counts the resources matching the
+// filters across all pages.
+type Meta struct {
+	Total int `json:"total"`
 }
 
 // MapResources maps items into JSON:API resources.
@@ -66,10 +106,11 @@ func NewError(status int, title, detail, code string) ErrorResponse {
 	}
 }
 
-// ParseBookingDate parses a date query parameter, defaulting to today if empty.
-func ParseBookingDate(value string) (string, error) {
+// ParseBookingDate parses a date query parameter, defaulting to today in the
+// time zone loc if empty.
+func ParseBookingDate(value string, loc *time.Location) (string, error) {
 	if strings.TrimSpace(value) == "" {
-		return time.Now().Format(time.DateOnly), nil
+		return time.Now().In(loc).Format(time.DateOnly), nil
 	}
 	parsed, err := time.Parse(time.DateOnly, value)
 	if err != nil {
@@ -84,10 +125,11 @@ type ItemGroupRequestParams struct {
 	BookingDate string
 }
 
-// ParseItemGroupRequest extracts itemGroupID and booking date from a request.
+// ParseItemGroupRequest extracts itemGroupID and booking date from a request;
+// an empty date defaults to today in the time zone loc.
 // Returns the params or an error if the date is invalid.
-func ParseItemGroupRequest(itemGroupID, dateParam string) (*ItemGroupRequestParams, error) {
-	bookingDate, err := ParseBookingDate(dateParam)
+func ParseItemGroupRequest(itemGroupID, dateParam string, loc *time.Location) (*ItemGroupRequestParams, error) {
+	bookingDate, err := ParseBookingDate(dateParam, loc)
 	if err != nil {
 		return nil, err
 	}
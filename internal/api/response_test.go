@@ -3,6 +3,7 @@ package api
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -41,7 +42,7 @@ func TestMapResources(t *testing.T) {
 
 func TestParseBookingDate(t *testing.T) {
 	t.Run("empty defaults to today", func(t *testing.T) {
-		date, err := ParseBookingDate("")
+		date, err := ParseBookingDate("", time.UTC)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -50,8 +51,19 @@ func TestParseBookingDate(t *testing.T) {
 		}
 	})
 
+	t.Run("empty defaults to today in the given zone", func(t *testing.T) {
+		loc := time.FixedZone("UTC+14", 14*60*60)
+		date, err := ParseBookingDate("", loc)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := time.Now().In(loc).Format(time.DateOnly); date != want {
+			t.Fatalf("expected %s, got %s", want, date)
+		}
+	})
+
 	t.Run("valid date", func(t *testing.T) {
-		date, err := ParseBookingDate("2025-12-25")
+		date, err := ParseBookingDate("2025-12-25", time.UTC)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -61,7 +73,7 @@ func TestParseBookingDate(t *testing.T) {
 	})
 
 	t.Run("invalid date", func(t *testing.T) {
-		_, err := ParseBookingDate("not-a-date")
+		_, err := ParseBookingDate("not-a-date", time.UTC)
 		if err == nil {
 			t.Fatal("expected error for invalid date")
 		}
@@ -70,7 +82,7 @@ func TestParseBookingDate(t *testing.T) {
 
 func TestParseItemGroupRequest(t *testing.T) {
 	t.Run("valid params", func(t *testing.T) {
-		params, err := ParseItemGroupRequest("room-1", "2025-01-15")
+		params, err := ParseItemGroupRequest("room-1", "2025-01-15", time.UTC)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -83,7 +95,7 @@ func TestParseItemGroupRequest(t *testing.T) {
 	})
 
 	t.Run("invalid date", func(t *testing.T) {
-		_, err := ParseItemGroupRequest("room-1", "invalid")
+		_, err := ParseItemGroupRequest("room-1", "invalid", time.UTC)
 		if err == nil {
 			t.Fatal("expected error for invalid date")
 		}
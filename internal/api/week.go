@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseISOWeek parses an ISO 8601 week string (e.g., "2026-W12") and returns
+// the Monday of that week. If the input is empty, returns the Monday of the
+// current week.
+func ParseISOWeek(s string) (time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		now := time.Now()
+		return mondayOfWeek(now), nil
+	}
+
+	// Expected format: YYYY-Www (e.g., 2026-W12 or 2026-W03)
+	parts := strings.SplitN(s, "-W", 2)
+	if len(parts) != 2 {
+		return time.Time{}, fmt.Errorf("invalid ISO week format: %s", s)
+	}
+
+	year, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid year in ISO week: %s", s)
+	}
+
+	week, err := strconv.Atoi(parts[1])
+	if err != nil || week < 1 || week > 53 {
+		return time.Time{}, fmt.Errorf("invalid week number in ISO week: %s", s)
+	}
+	maxWeek := isoWeeksInYear(year)
+	if week > maxWeek {
+		return time.Time{}, fmt.Errorf("invalid week number in ISO week: %s", s)
+	}
+
+	// ISO 8601: Week 1 contains January 4th.
+	// Find January 4th, then back up to Monday of that week, then add (week-1) weeks.
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	jan4Monday := mondayOfWeek(jan4)
+	monday := jan4Monday.AddDate(0, 0, (week-1)*7)
+
+	return monday, nil
+}
+
+// mondayOfWeek returns the Monday of the ISO week containing t.
+func mondayOfWeek(t time.Time) time.Time {
+	year, month, day := t.Date()
+	base := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	weekday := base.Weekday()
+	if weekday == time.Sunday {
+		weekday = 7
+	}
+	offset := int(weekday) - int(time.Monday)
+	return base.AddDate(0, 0, -offset)
+}
+
+// isoWeeksInYear returns the number of ISO weeks in the given year (52 or 53).
+func isoWeeksInYear(year int) int {
+	// ISO 8601: week with Jan 4 is always week 1; Dec 28 always lies in the last ISO week.
+	dec28 := time.Date(year, time.December, 28, 0, 0, 0, 0, time.UTC)
+	_, week := dec28.ISOWeek()
+	return week
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseISOWeek(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		wantDate string
+		wantErr  bool
+	}{
+		{"valid week 1 2026", "2026-W01", "2025-12-29", false},
+		{"valid week 12 2026", "2026-W12", "2026-03-16", false},
+		{"valid week with leading zero", "2026-W03", "2026-01-12", false},
+		{"valid week 53 2026", "2026-W53", "2026-12-28", false},
+		{"empty defaults to current week", "", "", false},
+		{"invalid format", "2026-12", "", true},
+		{"invalid week 53 for non-53 year", "2025-W53", "", true},
+		{"invalid week number", "2026-W54", "", true},
+		{"invalid week zero", "2026-W00", "", true},
+		{"invalid year", "abc-W12", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := ParseISOWeek(tc.input)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.input == "" {
+				// Just verify it returns a Monday
+				assert.Equal(t, time.Monday, result.Weekday())
+			} else {
+				assert.Equal(t, tc.wantDate, result.Format(time.DateOnly))
+				assert.Equal(t, time.Monday, result.Weekday())
+			}
+		})
+	}
+}
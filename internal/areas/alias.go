@@ -0,0 +1,47 @@
+package areas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ItemAliases maps the former IDs of items to their current IDs.
+func (c *Config) ItemAliases() map[string]string {
+	aliases := make(map[string]string)
+	for i := range c.Areas {
+		for j := range c.Areas[i].ItemGroups {
+			for _, item := range c.Areas[i].ItemGroups[j].Items {
+				for _, alias := range item.Aliases {
+					aliases[alias] = item.ID
+				}
+			}
+		}
+	}
+	return aliases
+}
+
+// validateAliases checks that every alias is set and names neither an item
+// nor the alias of another item.
+func validateAliases(cfg *Config) error {
+	owners := make(map[string]string)
+	for i := range cfg.Areas {
+		for j := range cfg.Areas[i].ItemGroups {
+			for _, item := range cfg.Areas[i].ItemGroups[j].Items {
+				for _, alias := range item.Aliases {
+					if strings.TrimSpace(alias) == "" {
+						return fmt.Errorf("item %q: aliases must not be empty", item.ID)
+					}
+					if _, ok := cfg.FindItem(alias); ok {
+						return fmt.Errorf("%w: alias %q of item %q is an item id", ErrDuplicateID, alias, item.ID)
+					}
+					if prev, ok := owners[alias]; ok {
+						return fmt.Errorf("%w: alias %q of item %q is also an alias of item %q",
+							ErrDuplicateID, alias, item.ID, prev)
+					}
+					owners[alias] = item.ID
+				}
+			}
+		}
+	}
+	return nil
+}
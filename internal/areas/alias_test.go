@@ -0,0 +1,58 @@
+package areas
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const aliasTestConfig = `areas:
+  - id: area-1
+    name: Office
+    items:
+      - id: room-1
+        name: Room 1
+        items:
+          - id: desk-1
+            name: Desk 1
+            aliases: [%s]
+          - id: desk-2
+            name: Desk 2
+            aliases: [%s]
+`
+
+func TestItemAliases(t *testing.T) {
+	cfg, err := Parse([]byte(fmt.Sprintf(aliasTestConfig, "ws_101_1, ws_101_9", "ws_101_2")))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	got := cfg.ItemAliases()
+	want := map[string]string{"ws_101_1": "desk-1", "ws_101_9": "desk-1", "ws_101_2": "desk-2"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for alias, id := range want {
+		if got[alias] != id {
+			t.Fatalf("expected alias %q of %q, got %q", alias, id, got[alias])
+		}
+	}
+}
+
+func TestParseRejectsInvalidAliases(t *testing.T) {
+	for _, tc := range []struct {
+		desk1, desk2 string
+		duplicate    bool
+	}{
+		{`""`, "", false},
+		{"desk-2", "", true},
+		{"ws_101_1", "ws_101_1", true},
+	} {
+		_, err := Parse([]byte(fmt.Sprintf(aliasTestConfig, tc.desk1, tc.desk2)))
+		if err == nil {
+			t.Fatalf("expected aliases [%s] and [%s] to be rejected", tc.desk1, tc.desk2)
+		}
+		if tc.duplicate != errors.Is(err, ErrDuplicateID) {
+			t.Fatalf("unexpected error for aliases [%s] and [%s]: %v", tc.desk1, tc.desk2, err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package areas
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// BookingField declares a typed custom field that bookings in an area or item
+// group carry besides the free-text note, e.g. a license plate for parking.
+type BookingField struct {
+	ID    string `yaml:"id"`
+	Label string `yaml:"label,omitempty"`
+	// Type is one of FieldTypeString, FieldTypeNumber, FieldTypeEnum, or FieldTypeBoolean.
+	Type     string `yaml:"type"`
+	Required bool   `yaml:"required,omitempty"`
+	// Pattern is a regular expression the whole value of a string field must match.
+	Pattern string `yaml:"pattern,omitempty"`
+	// Options lists the allowed values of an enum field.
+	Options []string `yaml:"options,omitempty"`
+	// Visibility is FieldPublic (the default), FieldBooker, or FieldAdmin.
+	Visibility string `yaml:"visibility,omitempty"`
+}
+
+// Booking field types.
+const (
+	FieldTypeString  = "string"
+	FieldTypeNumber  = "number"
+	FieldTypeEnum    = "enum"
+	FieldTypeBoolean = "boolean"
+)
+
+// Booking field visibilities, which are also the access levels of viewers.
+// Booker fields are shown to the booked user, the booker, and admins; admin
+// fields only to admins, who are also the only ones to set them.
+const (
+	FieldPublic = "public"
+	FieldBooker = "booker"
+	FieldAdmin  = "admin"
+)
+
+// maxFieldLength is the maximum length of a string field value.
+const maxFieldLength = 200
+
+var fieldIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var fieldVisibilityRank = map[string]int{"": 0, FieldPublic: 0, FieldBooker: 1, FieldAdmin: 2}
+
+// Name returns the label of the field, or its ID if it has none.
+func (f *BookingField) Name() string {
+	if f.Label != "" {
+		return f.Label
+	}
+	return f.ID
+}
+
+// VisibleTo reports whether a viewer with the access level FieldPublic,
+// FieldBooker, or FieldAdmin may see the field.
+func (f *BookingField) VisibleTo(access string) bool {
+	return fieldVisibilityRank[f.Visibility] <= fieldVisibilityRank[access]
+}
+
+// Normalize checks a JSON-decoded value against the field and returns it in
+// its stored form: trimmed strings, float64 numbers, and bools. A nil result
+// means the value is empty.
+func (f *BookingField) Normalize(value any) (any, error) {
+	switch f.Type {
+	case FieldTypeNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a number", f.Name())
+		}
+		return n, nil
+	case FieldTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false", f.Name())
+		}
+		return b, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", f.Name())
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if len(s) > maxFieldLength {
+		return nil, fmt.Errorf("%s must be at most %d characters", f.Name(), maxFieldLength)
+	}
+	if f.Type == FieldTypeEnum {
+		if !containsString(f.Options, s) {
+			return nil, fmt.Errorf("%s must be one of %s", f.Name(), strings.Join(f.Options, ", "))
+		}
+		return s, nil
+	}
+	if f.Pattern != "" {
+		re, err := compileFieldPattern(f.Pattern)
+		if err != nil || !re.MatchString(s) {
+			return nil, fmt.Errorf("%s has an invalid format", f.Name())
+		}
+	}
+	return s, nil
+}
+
+// compileFieldPattern compiles a field pattern that must match the whole value.
+func compileFieldPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("compile field pattern: %w", err)
+	}
+	return re, nil
+}
+
+// BookingFields returns the custom fields of bookings of the item at loc: the
+// fields of its area, followed by those of its item group. An item group field
+// replaces the area field with the same ID.
+func BookingFields(loc *ItemLocation) []BookingField {
+	if loc == nil || len(loc.Area.BookingFields)+len(loc.ItemGroup.BookingFields) == 0 {
+		return nil
+	}
+	fields := make([]BookingField, 0, len(loc.Area.BookingFields)+len(loc.ItemGroup.BookingFields))
+	for _, f := range loc.Area.BookingFields {
+		if !hasField(loc.ItemGroup.BookingFields, f.ID) {
+			fields = append(fields, f)
+		}
+	}
+	return append(fields, loc.ItemGroup.BookingFields...)
+}
+
+func hasField(fields []BookingField, id string) bool {
+	return FindBookingField(fields, id) != nil
+}
+
+// FindBookingField returns the field with the given ID, or nil.
+func FindBookingField(fields []BookingField, id string) *BookingField {
+	for i := range fields {
+		if fields[i].ID == id {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// VisibleFieldValues returns the custom field values of a booking of itemID
+// that a viewer with the given access level may see. Values of fields that are
+// no longer configured are only shown to admins.
+func (c *Config) VisibleFieldValues(itemID string, values map[string]any, access string) map[string]any {
+	if len(values) == 0 {
+		return nil
+	}
+	if access == FieldAdmin {
+		return values
+	}
+	loc, _ := c.FindItemLocation(itemID)
+	fields := BookingFields(loc)
+	visible := make(map[string]any, len(values))
+	for id, value := range values {
+		if f := FindBookingField(fields, id); f != nil && f.VisibleTo(access) {
+			visible[id] = value
+		}
+	}
+	if len(visible) == 0 {
+		return nil
+	}
+	return visible
+}
+
+func validateBookingFields(cfg *Config) error {
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		if err := validateFieldList(area.BookingFields, fmt.Sprintf("area %q", area.ID)); err != nil {
+			return err
+		}
+		for j := range area.ItemGroups {
+			ig := &area.ItemGroups[j]
+			if err := validateFieldList(ig.BookingFields, fmt.Sprintf("item group %q", ig.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateFieldList(fields []BookingField, location string) error {
+	seen := make(map[string]struct{}, len(fields))
+	for i := range fields {
+		f := &fields[i]
+		if !fieldIDPattern.MatchString(f.ID) {
+			return fmt.Errorf("%s: booking field id must be lowercase letters, digits, and underscores: %q",
+				location, f.ID)
+		}
+		if _, ok := seen[f.ID]; ok {
+			return fmt.Errorf("%s: booking field %q is defined twice", location, f.ID)
+		}
+		seen[f.ID] = struct{}{}
+		if err := validateField(f); err != nil {
+			return fmt.Errorf("%s: booking field %q: %w", location, f.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateField(f *BookingField) error {
+	switch f.Type {
+	case FieldTypeString, FieldTypeNumber, FieldTypeBoolean:
+		if len(f.Options) > 0 {
+			return errors.New("options are only allowed for enum fields")
+		}
+	case FieldTypeEnum:
+		if len(f.Options) == 0 {
+			return errors.New("enum fields require options")
+		}
+	default:
+		return fmt.Errorf("type must be string, number, enum, or boolean: %q", f.Type)
+	}
+	if f.Pattern != "" {
+		if f.Type != FieldTypeString {
+			return errors.New("pattern is only allowed for string fields")
+		}
+		if _, err := compileFieldPattern(f.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if _, ok := fieldVisibilityRank[f.Visibility]; !ok {
+		return fmt.Errorf("visibility must be public, booker, or admin: %q", f.Visibility)
+	}
+	if f.Required && f.Visibility == FieldAdmin {
+		return errors.New("admin fields cannot be required")
+	}
+	return nil
+}
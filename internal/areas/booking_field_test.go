@@ -0,0 +1,125 @@
+package areas
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBookingFieldNormalize(t *testing.T) {
+	plate := BookingField{ID: "plate", Label: "License plate", Type: FieldTypeString, Pattern: `[A-Z]+-[0-9]+`}
+	spot := BookingField{ID: "spot", Type: FieldTypeEnum, Options: []string{"north", "south"}}
+	count := BookingField{ID: "count", Type: FieldTypeNumber}
+	charging := BookingField{ID: "charging", Type: FieldTypeBoolean}
+
+	tests := []struct {
+		field   BookingField
+		value   any
+		want    any
+		wantErr string
+	}{
+		{field: plate, value: " AB-12 ", want: "AB-12"},
+		{field: plate, value: "  ", want: nil},
+		{field: plate, value: "xAB-12", wantErr: "License plate has an invalid format"},
+		{field: plate, value: 12.0, wantErr: "License plate must be a string"},
+		{field: plate, value: strings.Repeat("A", 201), wantErr: "at most 200 characters"},
+		{field: spot, value: "south", want: "south"},
+		{field: spot, value: "east", wantErr: "spot must be one of north, south"},
+		{field: count, value: 3.0, want: 3.0},
+		{field: count, value: "3", wantErr: "count must be a number"},
+		{field: charging, value: false, want: false},
+		{field: charging, value: "true", wantErr: "charging must be true or false"},
+	}
+	for _, tt := range tests {
+		got, err := tt.field.Normalize(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s(%v): err = %v, want %q", tt.field.ID, tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s(%v) = %v, %v, want %v", tt.field.ID, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestBookingFieldsAndVisibility(t *testing.T) {
+	cfg := &Config{Areas: []Area{{
+		ID: "garage",
+		BookingFields: []BookingField{
+			{ID: "plate", Type: FieldTypeString, Visibility: FieldBooker},
+			{ID: "note", Type: FieldTypeString},
+		},
+		ItemGroups: []ItemGroup{{
+			ID: "level-1",
+			BookingFields: []BookingField{
+				{ID: "note", Type: FieldTypeString, Visibility: FieldAdmin},
+				{ID: "charging", Type: FieldTypeBoolean},
+			},
+			Items: []Item{{ID: "p1"}},
+		}},
+	}}}
+
+	loc, ok := cfg.FindItemLocation("p1")
+	if !ok {
+		t.Fatal("item p1 not found")
+	}
+	var ids []string
+	for _, f := range BookingFields(loc) {
+		ids = append(ids, f.ID+":"+f.Visibility)
+	}
+	if got := strings.Join(ids, ","); got != "plate:booker,note:admin,charging:" {
+		t.Errorf("BookingFields = %s, want the item group to override the area note", got)
+	}
+
+	values := map[string]any{"plate": "AB-12", "note": "x", "charging": true, "gone": 1.0}
+	visible := map[string]string{
+		FieldPublic: "charging",
+		FieldBooker: "charging,plate",
+		FieldAdmin:  "charging,gone,note,plate",
+	}
+	for access, want := range visible {
+		got := cfg.VisibleFieldValues("p1", values, access)
+		keys := make([]string, 0, len(got))
+		for _, id := range []string{"charging", "gone", "note", "plate"} {
+			if _, ok := got[id]; ok {
+				keys = append(keys, id)
+			}
+		}
+		if strings.Join(keys, ",") != want {
+			t.Errorf("VisibleFieldValues(%s) = %v, want %s", access, got, want)
+		}
+	}
+	if got := cfg.VisibleFieldValues("p1", map[string]any{"note": "x"}, FieldPublic); got != nil {
+		t.Errorf("VisibleFieldValues without visible values = %v, want nil", got)
+	}
+}
+
+func TestValidateBookingFields(t *testing.T) {
+	tests := map[string]BookingField{
+		"booking field id must be lowercase":       {ID: "Plate", Type: FieldTypeString},
+		"type must be string, number, enum":        {ID: "plate", Type: "date"},
+		"enum fields require options":              {ID: "spot", Type: FieldTypeEnum},
+		"options are only allowed for enum fields": {ID: "n", Type: FieldTypeNumber, Options: []string{"1"}},
+		"pattern is only allowed for string":       {ID: "n", Type: FieldTypeNumber, Pattern: "[0-9]+"},
+		"invalid pattern":                          {ID: "plate", Type: FieldTypeString, Pattern: "("},
+		"visibility must be public, booker":        {ID: "plate", Type: FieldTypeString, Visibility: "team"},
+		"admin fields cannot be required": {
+			ID: "cc", Type: FieldTypeString, Required: true, Visibility: FieldAdmin,
+		},
+	}
+	for want, field := range tests {
+		cfg := &Config{Areas: []Area{{ID: "office", BookingFields: []BookingField{field}}}}
+		err := validateBookingFields(cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateBookingFields(%+v) = %v, want %q", field, err, want)
+		}
+	}
+
+	twice := &Config{Areas: []Area{{ID: "office", ItemGroups: []ItemGroup{{
+		ID: "room", BookingFields: []BookingField{{ID: "a", Type: FieldTypeString}, {ID: "a", Type: FieldTypeString}},
+	}}}}}
+	if err := validateBookingFields(twice); err == nil || !strings.Contains(err.Error(), `item group "room"`) {
+		t.Errorf("duplicate field error = %v, want it to name the item group", err)
+	}
+}
@@ -0,0 +1,86 @@
+package areas
+
+import (
+	"fmt"
+	"time"
+)
+
+// Cancellation configures until when bookings can be canceled and which
+// cancellations and missed bookings count against a user's attendance record.
+// All times are area-local.
+type Cancellation struct {
+	// CutoffTime is the time of day (HH:MM) on the booked day from which users
+	// can no longer cancel. Empty allows cancelling until the day ends.
+	CutoffTime string `yaml:"cutoff_time,omitempty"`
+	// LateNoticeHours counts cancellations less than this many hours before the
+	// booked day starts as late. 0 counts cancellations on the booked day only.
+	LateNoticeHours int `yaml:"late_notice_hours,omitempty"`
+	// RequireCheckIn counts bookings that were not checked in on the booked day
+	// as no-shows.
+	RequireCheckIn bool `yaml:"require_check_in,omitempty"`
+}
+
+// CancellationFor returns the cancellation rules of an area: its own, else the
+// global ones, else the zero value (no cut-off, same-day cancellations are late).
+func (c *Config) CancellationFor(area *Area) Cancellation {
+	switch {
+	case area != nil && area.Cancellation != nil:
+		return *area.Cancellation
+	case c != nil && c.Cancellation != nil:
+		return *c.Cancellation
+	default:
+		return Cancellation{}
+	}
+}
+
+// Cutoff returns the moment from which a booking on the YYYY-MM-DD date can no
+// longer be canceled, or the end of the day if no cutoff time is set.
+func (cn *Cancellation) Cutoff(date string, loc *time.Location) time.Time {
+	day, err := time.ParseInLocation(time.DateOnly, date, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	at, err := time.Parse("15:04", cn.CutoffTime)
+	if err != nil {
+		return day.AddDate(0, 0, 1)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+}
+
+// IsLate reports whether canceling a booking on the YYYY-MM-DD date at now
+// counts as a late cancellation.
+func (cn *Cancellation) IsLate(date string, now time.Time) bool {
+	day, err := time.ParseInLocation(time.DateOnly, date, now.Location())
+	if err != nil {
+		return false
+	}
+	return now.After(day.Add(-time.Duration(cn.LateNoticeHours) * time.Hour))
+}
+
+func validateCancellations(cfg *Config) error {
+	if err := validateCancellation(cfg.Cancellation, "global cancellation"); err != nil {
+		return err
+	}
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		if err := validateCancellation(area.Cancellation, fmt.Sprintf("area %q", area.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateCancellation(cn *Cancellation, location string) error {
+	if cn == nil {
+		return nil
+	}
+	if cn.CutoffTime != "" {
+		if _, err := time.Parse("15:04", cn.CutoffTime); err != nil {
+			return fmt.Errorf("%s: cancellation cutoff_time must be HH:MM: %q", location, cn.CutoffTime)
+		}
+	}
+	if cn.LateNoticeHours < 0 {
+		return fmt.Errorf("%s: cancellation late_notice_hours must not be negative", location)
+	}
+	return nil
+}
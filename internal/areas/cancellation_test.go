@@ -0,0 +1,57 @@
+package areas
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancellationFor(t *testing.T) {
+	own := &Cancellation{CutoffTime: "08:00"}
+	cfg := &Config{
+		Cancellation: &Cancellation{LateNoticeHours: 12},
+		Areas:        []Area{{ID: "a"}, {ID: "b", Cancellation: own}},
+	}
+	if got := cfg.CancellationFor(&cfg.Areas[0]); got.LateNoticeHours != 12 {
+		t.Errorf("area without rules = %+v, want the global rules", got)
+	}
+	if got := cfg.CancellationFor(&cfg.Areas[1]); got != *own {
+		t.Errorf("area with rules = %+v, want %+v", got, *own)
+	}
+	if got := (&Config{}).CancellationFor(nil); got != (Cancellation{}) {
+		t.Errorf("no rules = %+v, want the zero value", got)
+	}
+}
+
+func TestCancellationCutoffAndLate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load zone: %v", err)
+	}
+	cn := &Cancellation{CutoffTime: "09:30", LateNoticeHours: 24}
+	if got, want := cn.Cutoff("2026-11-03", berlin), time.Date(2026, 11, 3, 9, 30, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("cutoff = %v, want %v", got, want)
+	}
+	endOfDay := time.Date(2026, 11, 4, 0, 0, 0, 0, berlin)
+	if got, want := (&Cancellation{}).Cutoff("2026-11-03", berlin), endOfDay; !got.Equal(want) {
+		t.Errorf("default cutoff = %v, want the end of the day %v", got, want)
+	}
+
+	if cn.IsLate("2026-11-03", time.Date(2026, 11, 1, 23, 0, 0, 0, berlin)) {
+		t.Errorf("expected cancellation 25 hours ahead to be on time")
+	}
+	if !cn.IsLate("2026-11-03", time.Date(2026, 11, 2, 1, 0, 0, 0, berlin)) {
+		t.Errorf("expected cancellation 23 hours ahead to be late")
+	}
+}
+
+func TestValidateCancellation(t *testing.T) {
+	cfg := &Config{Areas: []Area{{ID: "a", Cancellation: &Cancellation{CutoffTime: "9am"}}}}
+	if err := validateCancellations(cfg); err == nil || !strings.Contains(err.Error(), "cutoff_time") {
+		t.Errorf("expected cutoff_time error, got %v", err)
+	}
+	cfg = &Config{Cancellation: &Cancellation{LateNoticeHours: -1}}
+	if err := validateCancellations(cfg); err == nil {
+		t.Errorf("expected negative late_notice_hours to be rejected")
+	}
+}
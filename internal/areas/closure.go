@@ -0,0 +1,62 @@
+package areas
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Closure describes a period in which bookings are not possible, such as a
+// public holiday or a planned renovation. From and To are inclusive dates in
+// YYYY-MM-DD format; an empty To means a single-day closure.
+type Closure struct {
+	From   string `yaml:"from"`
+	To     string `yaml:"to,omitempty"`
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// EndDate returns the inclusive last day of the closure.
+func (cl *Closure) EndDate() string {
+	if strings.TrimSpace(cl.To) == "" {
+		return cl.From
+	}
+	return cl.To
+}
+
+// Covers reports whether the closure includes the given YYYY-MM-DD date.
+func (cl *Closure) Covers(date string) bool {
+	return date >= cl.From && date <= cl.EndDate()
+}
+
+func validateClosures(cfg *Config) error {
+	for i := range cfg.Closures {
+		if err := validateClosure(&cfg.Closures[i], "global closures"); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		for j := range area.Closures {
+			if err := validateClosure(&area.Closures[j], fmt.Sprintf("area %q", area.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateClosure(cl *Closure, location string) error {
+	if _, err := time.Parse(time.DateOnly, cl.From); err != nil {
+		return fmt.Errorf("%s: closure from must be a YYYY-MM-DD date: %q", location, cl.From)
+	}
+	if cl.To == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.DateOnly, cl.To); err != nil {
+		return fmt.Errorf("%s: closure to must be a YYYY-MM-DD date: %q", location, cl.To)
+	}
+	if cl.To < cl.From {
+		return fmt.Errorf("%s: closure ends (%s) before it starts (%s)", location, cl.To, cl.From)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package areas
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClosureCovers(t *testing.T) {
+	single := Closure{From: "2026-12-24"}
+	if !single.Covers("2026-12-24") || single.Covers("2026-12-25") {
+		t.Fatalf("single-day closure should only cover its own date")
+	}
+
+	rng := Closure{From: "2026-12-24", To: "2026-12-31"}
+	if !rng.Covers("2026-12-27") || !rng.Covers("2026-12-31") || rng.Covers("2027-01-01") {
+		t.Fatalf("range closure should cover inclusive range")
+	}
+}
+
+func TestLoadRejectsInvalidClosure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "areas.yaml")
+	content := `areas:
+  - id: area-1
+    name: Office
+    closures:
+      - from: 2026-08-14
+        to: 2026-08-01
+    items:
+      - id: room-1
+        name: Room 1
+        items:
+          - id: desk-1
+            name: Desk 1
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write areas config: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "before it starts") {
+		t.Fatalf("expected closure range error, got %v", err)
+	}
+}
@@ -16,7 +16,27 @@ var mdiIconNamePattern = regexp.MustCompile(`^mdi-[a-z0-9-]+$`)
 
 // Config holds the areas configuration.
 type Config struct {
+	// Timezone is the IANA time zone of areas without their own (default UTC).
+	Timezone string    `yaml:"timezone,omitempty"`
+	Features []Feature `yaml:"features,omitempty"`
+	Closures []Closure `yaml:"closures,omitempty"`
+	Policies []Policy  `yaml:"policies,omitempty"`
+	// Cancellation holds the cancellation rules of areas without their own.
+	Cancellation *Cancellation `yaml:"cancellation,omitempty"`
+	// Areas are the bookable areas. With nested areas, they are derived from
+	// the tree by SetTree.
 	Areas []Area `yaml:"areas"`
+
+	tree  []Area // areas as nested in the YAML, nil without nesting
+	nodes []Area // every area of tree with inherited settings
+}
+
+// MarshalYAML implements yaml.Marshaler, writing the areas as nested.
+func (c *Config) MarshalYAML() (any, error) {
+	type plain Config
+	out := plain(*c)
+	out.Areas = c.Tree()
+	return out, nil
 }
 
 // ConfigGetter is a function that returns the current areas config.
@@ -121,14 +141,26 @@ func ItemAttributes(name string, equipment []string, warning, availability, icon
 
 // Area describes a bookable area.
 type Area struct {
-	ID                   string      `yaml:"id"`
-	Name                 string      `yaml:"name"`
-	Description          string      `yaml:"description,omitempty"`
-	FloorPlan            string      `yaml:"floor_plan,omitempty"`
-	Icon                 string      `yaml:"icon,omitempty"`
-	MaxBookingsPerPerson int         `yaml:"max_bookings_per_person,omitempty"`
-	ReservedFor          []string    `yaml:"reserved_for,omitempty"`
-	ItemGroups           []ItemGroup `yaml:"items"`
+	ID                   string        `yaml:"id"`
+	Name                 string        `yaml:"name"`
+	Description          string        `yaml:"description,omitempty"`
+	FloorPlan            string        `yaml:"floor_plan,omitempty"`
+	Icon                 string        `yaml:"icon,omitempty"`
+	Timezone             string        `yaml:"timezone,omitempty"`
+	MaxBookingsPerPerson int           `yaml:"max_bookings_per_person,omitempty"`
+	ReservedFor          []string      `yaml:"reserved_for,omitempty"`
+	Closures             []Closure     `yaml:"closures,omitempty"`
+	Policies             []Policy      `yaml:"policies,omitempty"`
+	Lottery              *Lottery      `yaml:"lottery,omitempty"`
+	Cancellation         *Cancellation `yaml:"cancellation,omitempty"`
+	// BookingFields are the custom fields of bookings in the area.
+	BookingFields []BookingField `yaml:"booking_fields,omitempty"`
+	ItemGroups    []ItemGroup    `yaml:"items"`
+	// Areas are nested areas, such as the buildings of a site or the floors
+	// of a building.
+	Areas []Area `yaml:"areas,omitempty"`
+	// ParentID is the ID of the area this one is nested in.
+	ParentID string `yaml:"-"`
 }
 
 // ItemGroup describes a group of bookable items within an area.
@@ -140,18 +172,27 @@ type ItemGroup struct {
 	Icon                 string   `yaml:"icon,omitempty"`
 	MaxBookingsPerPerson int      `yaml:"max_bookings_per_person,omitempty"`
 	ReservedFor          []string `yaml:"reserved_for,omitempty"`
-	Items                []Item   `yaml:"items"`
+	Policies             []Policy `yaml:"policies,omitempty"`
+	Lottery              *Lottery `yaml:"lottery,omitempty"`
+	// BookingFields are the custom fields of bookings in the item group, in
+	// addition to those of its area.
+	BookingFields []BookingField `yaml:"booking_fields,omitempty"`
+	Items         []Item         `yaml:"items"`
 }
 
 // Item describes a bookable item within an item group.
 type Item struct {
-	ID                   string   `yaml:"id"`
-	Name                 string   `yaml:"name"`
-	Equipment            []string `yaml:"equipment"`
-	Warning              string   `yaml:"warning,omitempty"`
-	Icon                 string   `yaml:"icon,omitempty"`
-	MaxBookingsPerPerson int      `yaml:"max_bookings_per_person,omitempty"`
-	ReservedFor          []string `yaml:"reserved_for,omitempty"`
+	ID                   string        `yaml:"id"`
+	Name                 string        `yaml:"name"`
+	Equipment            []string      `yaml:"equipment"`
+	Features             []ItemFeature `yaml:"features,omitempty"`
+	Warning              string        `yaml:"warning,omitempty"`
+	Icon                 string        `yaml:"icon,omitempty"`
+	MaxBookingsPerPerson int           `yaml:"max_bookings_per_person,omitempty"`
+	ReservedFor          []string      `yaml:"reserved_for,omitempty"`
+	// Aliases are former IDs of the item. On startup, bookings and other
+	// records of an alias are moved to the item.
+	Aliases []string `yaml:"aliases,omitempty"`
 }
 
 // IconWarning describes an invalid configured icon reference.
@@ -168,18 +209,47 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("read areas config: %w", err)
 	}
 
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	ApplyFeatureLabels(cfg)
+
+	return cfg, nil
+}
+
+// Parse parses and validates an areas configuration. Unlike Load it does not
+// fill item equipment from features, so the result can be stored or written
+// back as it was given.
+func Parse(data []byte) (*Config, error) {
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("parse areas config: %w", err)
 	}
+	cfg.SetTree(cfg.Areas)
 
 	if err := validateConfig(&cfg); err != nil {
 		return nil, fmt.Errorf("validate areas config: %w", err)
 	}
-
 	return &cfg, nil
 }
 
+// Validate checks an areas configuration that was edited rather than loaded:
+// the checks of Load, ValidateReservations, and icons must be valid mdi icon
+// names instead of only being reported by FindInvalidConfiguredIcons.
+func Validate(cfg *Config) error {
+	if err := validateConfig(cfg); err != nil {
+		return err
+	}
+	if err := ValidateReservations(cfg); err != nil {
+		return err
+	}
+	if warnings := FindInvalidConfiguredIcons(cfg); len(warnings) > 0 {
+		return fmt.Errorf("%w: %s uses %q", ErrInvalidIcon, warnings[0].Location, warnings[0].Icon)
+	}
+	return nil
+}
+
 // supportedFloorPlanExts lists allowed floor plan image extensions.
 var supportedFloorPlanExts = map[string]bool{
 	".jpg":  true,
@@ -191,8 +261,7 @@ var supportedFloorPlanExts = map[string]bool{
 // ValidateFloorPlans checks that all floor_plan references in the config
 // point to existing files with supported formats inside floorPlansDir.
 func ValidateFloorPlans(cfg *Config, floorPlansDir string) error {
-	for i := range cfg.Areas {
-		area := &cfg.Areas[i]
+	return walkAreas(cfg.Tree(), func(area *Area) error {
 		if area.FloorPlan != "" {
 			if err := validateFloorPlanFile(area.FloorPlan, floorPlansDir); err != nil {
 				return fmt.Errorf("area %q: %w", area.ID, err)
@@ -206,8 +275,8 @@ func ValidateFloorPlans(cfg *Config, floorPlansDir string) error {
 				}
 			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // FindInvalidConfiguredIcons returns non-fatal warnings for icon values that cannot
@@ -215,8 +284,8 @@ func ValidateFloorPlans(cfg *Config, floorPlansDir string) error {
 func FindInvalidConfiguredIcons(cfg *Config) []IconWarning {
 	warnings := make([]IconWarning, 0)
 
-	for i := range cfg.Areas {
-		area := &cfg.Areas[i]
+	//nolint:errcheck // The callback never fails
+	_ = walkAreas(cfg.Tree(), func(area *Area) error {
 		if area.Icon != "" && !isValidConfiguredIcon(area.Icon) {
 			warnings = append(warnings, IconWarning{
 				Location: fmt.Sprintf("area %q", area.ID),
@@ -242,7 +311,8 @@ func FindInvalidConfiguredIcons(cfg *Config) []IconWarning {
 				}
 			}
 		}
-	}
+		return nil
+	})
 
 	return warnings
 }
@@ -273,8 +343,14 @@ var ErrReservationConflict = errors.New("reservation conflict")
 // ErrDuplicateID indicates a duplicate identifier in the areas configuration.
 var ErrDuplicateID = errors.New("duplicate id")
 
+// ErrInvalidIcon indicates an icon that is not an mdi icon name.
+var ErrInvalidIcon = errors.New("invalid icon")
+
 // ValidateReservations checks that child reserved_for lists are subsets of parent lists.
 func ValidateReservations(cfg *Config) error {
+	if err := validateNestedReservations(cfg.tree, nil, ""); err != nil {
+		return err
+	}
 	for i := range cfg.Areas {
 		area := &cfg.Areas[i]
 		areaSet := toStringSet(area.ReservedFor)
@@ -350,6 +426,9 @@ func containsString(slice []string, target string) bool {
 }
 
 func validateConfig(cfg *Config) error {
+	if err := validateTree(cfg); err != nil {
+		return err
+	}
 	for i := range cfg.Areas {
 		area := &cfg.Areas[i]
 		if area.ID == "" || area.Name == "" {
@@ -370,6 +449,30 @@ func validateConfig(cfg *Config) error {
 	if err := findDuplicateIDs(cfg); err != nil {
 		return err
 	}
+	if err := validateAliases(cfg); err != nil {
+		return err
+	}
+	if err := validateTimezones(cfg); err != nil {
+		return err
+	}
+	if err := validateFeatures(cfg); err != nil {
+		return err
+	}
+	if err := validateClosures(cfg); err != nil {
+		return err
+	}
+	if err := validatePolicies(cfg); err != nil {
+		return err
+	}
+	if err := validateLotteries(cfg); err != nil {
+		return err
+	}
+	if err := validateCancellations(cfg); err != nil {
+		return err
+	}
+	if err := validateBookingFields(cfg); err != nil {
+		return err
+	}
 	return nil
 }
 
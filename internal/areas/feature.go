@@ -0,0 +1,234 @@
+package areas
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Feature describes an entry of the equipment catalog, such as a
+// height-adjustable table or an external display. Attributes name the numeric
+// values items may record for the feature (e.g. the display size).
+type Feature struct {
+	ID         string             `yaml:"id"`
+	Label      string             `yaml:"label"`
+	Category   string             `yaml:"category,omitempty"`
+	Attributes []FeatureAttribute `yaml:"attributes,omitempty"`
+}
+
+// FeatureAttribute describes a numeric value of a feature.
+type FeatureAttribute struct {
+	ID    string `yaml:"id" json:"id"`
+	Label string `yaml:"label,omitempty" json:"label,omitempty"`
+	Unit  string `yaml:"unit,omitempty" json:"unit,omitempty"`
+}
+
+// ItemFeature references a catalog feature from an item. In YAML it is either
+// the plain feature ID or a mapping with the ID and attribute values:
+//
+//	features:
+//	  - webcam
+//	  - id: display
+//	    size_inch: 32
+type ItemFeature struct {
+	ID     string
+	Values map[string]float64
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (f *ItemFeature) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		f.ID = strings.TrimSpace(node.Value)
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: item feature must be an ID or a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1].Value
+		if key == "id" {
+			f.ID = strings.TrimSpace(value)
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: feature attribute %q must be a number", node.Content[i+1].Line, key)
+		}
+		if f.Values == nil {
+			f.Values = make(map[string]float64)
+		}
+		f.Values[key] = n
+	}
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler, writing the short form when possible.
+func (f ItemFeature) MarshalYAML() (interface{}, error) {
+	if len(f.Values) == 0 {
+		return f.ID, nil
+	}
+	m := map[string]interface{}{"id": f.ID}
+	for k, v := range f.Values {
+		m[k] = v
+	}
+	return m, nil
+}
+
+// FindFeature returns the catalog feature matching the provided id.
+func (c *Config) FindFeature(id string) (*Feature, bool) {
+	for i := range c.Features {
+		if c.Features[i].ID == id {
+			return &c.Features[i], true
+		}
+	}
+	return nil, false
+}
+
+// FeatureRequirement asks for a feature, optionally with minimum attribute values.
+type FeatureRequirement struct {
+	ID  string
+	Min map[string]float64
+}
+
+// HasFeature reports whether the item satisfies the requirement. Items that
+// reference catalog features are matched on those. Items of older configs that
+// only list free-text equipment match when an equipment entry contains the
+// feature's label or ID; minimum values cannot be checked against free text, so
+// such requirements never match there.
+func (c *Config) HasFeature(item *Item, req FeatureRequirement) bool {
+	if len(item.Features) > 0 {
+		for _, f := range item.Features {
+			if f.ID != req.ID {
+				continue
+			}
+			for attr, minValue := range req.Min {
+				if v, ok := f.Values[attr]; !ok || v < minValue {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+	if len(req.Min) > 0 {
+		return false
+	}
+
+	needles := []string{strings.ToLower(req.ID)}
+	if feature, ok := c.FindFeature(req.ID); ok && feature.Label != "" {
+		needles = append(needles, strings.ToLower(feature.Label))
+	}
+	for _, e := range item.Equipment {
+		text := strings.ToLower(e)
+		for _, needle := range needles {
+			if strings.Contains(text, needle) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ApplyFeatureLabels fills the free-text equipment of items that only reference
+// catalog features, so every view that shows the equipment list keeps working.
+func ApplyFeatureLabels(cfg *Config) {
+	for i := range cfg.Areas {
+		for j := range cfg.Areas[i].ItemGroups {
+			ig := &cfg.Areas[i].ItemGroups[j]
+			for k := range ig.Items {
+				item := &ig.Items[k]
+				if len(item.Equipment) > 0 {
+					continue
+				}
+				for _, f := range item.Features {
+					item.Equipment = append(item.Equipment, cfg.featureLabel(&f))
+				}
+			}
+		}
+	}
+}
+
+func (c *Config) featureLabel(f *ItemFeature) string {
+	feature, ok := c.FindFeature(f.ID)
+	if !ok {
+		return f.ID
+	}
+	label := feature.Label
+	var values []string
+	for _, attr := range feature.Attributes {
+		v, ok := f.Values[attr.ID]
+		if !ok {
+			continue
+		}
+		text := strconv.FormatFloat(v, 'f', -1, 64)
+		if attr.Unit != "" {
+			text += " " + attr.Unit
+		}
+		switch {
+		case attr.Label != "":
+			text = attr.Label + " " + text
+		case attr.Unit == "":
+			text = strings.ReplaceAll(attr.ID, "_", " ") + " " + text
+		}
+		values = append(values, text)
+	}
+	if len(values) > 0 {
+		label += " (" + strings.Join(values, ", ") + ")"
+	}
+	return label
+}
+
+func validateFeatures(cfg *Config) error {
+	attrs := make(map[string]map[string]bool, len(cfg.Features))
+	for i := range cfg.Features {
+		f := &cfg.Features[i]
+		if f.ID == "" || f.Label == "" {
+			return fmt.Errorf("feature requires id and label")
+		}
+		if _, dup := attrs[f.ID]; dup {
+			return fmt.Errorf("%w: feature id %q is defined twice", ErrDuplicateID, f.ID)
+		}
+		attrs[f.ID] = make(map[string]bool, len(f.Attributes))
+		for _, a := range f.Attributes {
+			if a.ID == "" || a.ID == "id" {
+				return fmt.Errorf("feature %q: attribute requires an id other than \"id\"", f.ID)
+			}
+			attrs[f.ID][a.ID] = true
+		}
+	}
+
+	for i := range cfg.Areas {
+		for j := range cfg.Areas[i].ItemGroups {
+			items := cfg.Areas[i].ItemGroups[j].Items
+			for k := range items {
+				if err := validateItemFeatures(&items[k], attrs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateItemFeatures(item *Item, attrs map[string]map[string]bool) error {
+	for _, f := range item.Features {
+		known, ok := attrs[f.ID]
+		if !ok {
+			return fmt.Errorf("item %q: unknown feature %q", item.ID, f.ID)
+		}
+		keys := make([]string, 0, len(f.Values))
+		for k := range f.Values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !known[k] {
+				return fmt.Errorf("item %q: feature %q has no attribute %q", item.ID, f.ID, k)
+			}
+		}
+	}
+	return nil
+}
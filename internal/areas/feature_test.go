@@ -0,0 +1,103 @@
+package areas
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const featureCatalog = `features:
+  - id: adjustable-table
+    label: Height-adjustable table
+    category: furniture
+  - id: display
+    label: External display
+    category: screen
+    attributes:
+      - id: size_inch
+        unit: inch
+  - id: webcam
+    label: Webcam
+`
+
+func writeFeatureConfig(t *testing.T, items string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "areas.yaml")
+	content := featureCatalog + `areas:
+  - id: area-1
+    name: Office
+    items:
+      - id: room-1
+        name: Room 1
+        items:
+` + items
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write areas config: %v", err)
+	}
+	return path
+}
+
+func TestLoadParsesItemFeatures(t *testing.T) {
+	path := writeFeatureConfig(t, `          - id: desk-1
+            name: Desk 1
+            features:
+              - adjustable-table
+              - id: display
+                size_inch: 32
+          - id: desk-2
+            name: Desk 2
+            equipment:
+              - Webcam and headset
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	desk, _ := cfg.FindItem("desk-1")
+	if len(desk.Features) != 2 || desk.Features[1].Values["size_inch"] != 32 {
+		t.Fatalf("unexpected features: %+v", desk.Features)
+	}
+	want := []string{"Height-adjustable table", "External display (32 inch)"}
+	if strings.Join(desk.Equipment, "|") != strings.Join(want, "|") {
+		t.Errorf("expected equipment labels %v, got %v", want, desk.Equipment)
+	}
+
+	big := FeatureRequirement{ID: "display", Min: map[string]float64{"size_inch": 27}}
+	huge := FeatureRequirement{ID: "display", Min: map[string]float64{"size_inch": 40}}
+	if !cfg.HasFeature(desk, big) || cfg.HasFeature(desk, huge) {
+		t.Errorf("display minimum not applied")
+	}
+	if cfg.HasFeature(desk, FeatureRequirement{ID: "webcam"}) {
+		t.Errorf("desk-1 has no webcam")
+	}
+
+	legacy, _ := cfg.FindItem("desk-2")
+	if !cfg.HasFeature(legacy, FeatureRequirement{ID: "webcam"}) {
+		t.Errorf("free-text equipment should match the feature label")
+	}
+	if cfg.HasFeature(legacy, big) {
+		t.Errorf("minimum values cannot match free-text equipment")
+	}
+}
+
+func TestLoadRejectsInvalidItemFeatures(t *testing.T) {
+	cases := []struct {
+		features string
+		expected string
+	}{
+		{features: "              - scanner\n", expected: `unknown feature "scanner"`},
+		{features: "              - id: display\n                weight: 3\n", expected: `no attribute "weight"`},
+		{features: "              - id: display\n                size_inch: big\n", expected: "must be a number"},
+	}
+	for _, tc := range cases {
+		item := "          - id: desk-1\n            name: Desk 1\n            features:\n"
+		path := writeFeatureConfig(t, item+tc.features)
+
+		_, err := Load(path)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("expected error containing %q, got %v", tc.expected, err)
+		}
+	}
+}
@@ -17,13 +17,40 @@ func ListHandlerDynamic(getConfig ConfigGetter) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		cfg := getConfig()
 		resources := api.MapResources(cfg.Areas, func(area Area) api.Resource {
-			return api.Resource{
-				Type:       "areas",
-				ID:         area.ID,
-				Attributes: BaseAttributes(area.Name, area.Description, area.FloorPlan, area.Icon),
-			}
+			return AreaResource(&area)
 		})
 
 		return api.WriteCollection(c, resources, "write areas response")
 	}
 }
+
+// TreeHandler returns a JSON:API list of every area of the tree, parents
+// before the areas nested in them, including those that only hold nested
+// areas.
+// GET /api/v1/area-tree
+func TreeHandler(getConfig ConfigGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := getConfig()
+		resources := api.MapResources(cfg.AllAreas(), func(area Area) api.Resource {
+			return TreeAreaResource(cfg, &area)
+		})
+		return api.WriteCollection(c, resources, "write area tree response")
+	}
+}
+
+// ChildrenHandler returns a JSON:API list of the areas nested directly in an
+// area.
+// GET /api/v1/areas/:area_id/areas
+func ChildrenHandler(getConfig ConfigGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := getConfig()
+		areaID := c.Param("area_id")
+		if _, ok := cfg.FindTreeArea(areaID); !ok {
+			return api.WriteNotFound(c, "Area not found")
+		}
+		resources := api.MapResources(cfg.ChildAreas(areaID), func(area Area) api.Resource {
+			return TreeAreaResource(cfg, &area)
+		})
+		return api.WriteCollection(c, resources, "write nested areas response")
+	}
+}
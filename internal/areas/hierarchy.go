@@ -0,0 +1,185 @@
+package areas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetTree sets the areas as nested in the YAML. Areas become the areas that
+// hold item groups or have no nested areas, in tree order, each with the
+// reserved_for, max_bookings_per_person, icon, floor plan, and timezone it
+// inherits from the areas it is nested in.
+func (c *Config) SetTree(tree []Area) {
+	nested := false
+	for i := range tree {
+		if len(tree[i].Areas) > 0 {
+			nested = true
+			break
+		}
+	}
+	if !nested {
+		c.tree, c.nodes = nil, nil
+		c.Areas = tree
+		return
+	}
+	c.tree = tree
+	c.nodes = nil
+	c.Areas = []Area{}
+	c.expand(tree, nil)
+}
+
+// expand appends the areas of tree, nested in parent, to the nodes and the
+// bookable ones to Areas.
+func (c *Config) expand(tree []Area, parent *Area) {
+	for i := range tree {
+		area := tree[i]
+		area.Areas = nil
+		if parent != nil {
+			area.ParentID = parent.ID
+			inherit(&area, parent)
+		}
+		c.nodes = append(c.nodes, area)
+		if len(tree[i].ItemGroups) > 0 || len(tree[i].Areas) == 0 {
+			c.Areas = append(c.Areas, area)
+		}
+		c.expand(tree[i].Areas, &area)
+	}
+}
+
+// inherit fills the settings of area that it takes from parent when unset.
+func inherit(area, parent *Area) {
+	if len(area.ReservedFor) == 0 {
+		area.ReservedFor = parent.ReservedFor
+	}
+	if area.MaxBookingsPerPerson == 0 {
+		area.MaxBookingsPerPerson = parent.MaxBookingsPerPerson
+	}
+	if area.Icon == "" {
+		area.Icon = parent.Icon
+	}
+	if area.FloorPlan == "" {
+		area.FloorPlan = parent.FloorPlan
+	}
+	if area.Timezone == "" {
+		area.Timezone = parent.Timezone
+	}
+}
+
+// Tree returns the areas as nested in the YAML, with their own settings.
+func (c *Config) Tree() []Area {
+	if c.tree != nil {
+		return c.tree
+	}
+	return c.Areas
+}
+
+// AllAreas returns every area of the tree, parents before the areas nested in
+// them, with inherited settings. Areas that only hold nested areas are
+// included; without nesting it is Areas.
+func (c *Config) AllAreas() []Area {
+	if c.nodes != nil {
+		return c.nodes
+	}
+	return c.Areas
+}
+
+// FindTreeArea returns the area of the tree matching the provided id, whether
+// it holds item groups or only nested areas.
+func (c *Config) FindTreeArea(id string) (*Area, bool) {
+	nodes := c.AllAreas()
+	for i := range nodes {
+		if nodes[i].ID == id {
+			return &nodes[i], true
+		}
+	}
+	return nil, false
+}
+
+// ChildAreas returns the areas nested directly in the area with parentID, or
+// the top-level areas if parentID is empty.
+func (c *Config) ChildAreas(parentID string) []Area {
+	var children []Area
+	nodes := c.AllAreas()
+	for i := range nodes {
+		if nodes[i].ParentID == parentID {
+			children = append(children, nodes[i])
+		}
+	}
+	return children
+}
+
+// walkAreas calls fn for every area of tree, parents before the areas nested
+// in them, and stops at the first error.
+func walkAreas(tree []Area, fn func(area *Area) error) error {
+	for i := range tree {
+		if err := fn(&tree[i]); err != nil {
+			return err
+		}
+		if err := walkAreas(tree[i].Areas, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTree checks the areas that are not in Areas, which only hold
+// nested areas: they need an id and a name that no other area uses, and
+// they must not set what only applies to item groups.
+func validateTree(cfg *Config) error {
+	if cfg.tree == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	return walkAreas(cfg.tree, func(area *Area) error {
+		if area.ID == "" || area.Name == "" {
+			return fmt.Errorf("area requires id and name")
+		}
+		if seen[area.ID] {
+			return fmt.Errorf("%w: area id %q is defined twice", ErrDuplicateID, area.ID)
+		}
+		seen[area.ID] = true
+		if len(area.Areas) == 0 || len(area.ItemGroups) > 0 {
+			return nil
+		}
+		var set []string
+		if len(area.Closures) > 0 {
+			set = append(set, "closures")
+		}
+		if len(area.Policies) > 0 {
+			set = append(set, "policies")
+		}
+		if area.Lottery != nil {
+			set = append(set, "lottery")
+		}
+		if area.Cancellation != nil {
+			set = append(set, "cancellation")
+		}
+		if len(area.BookingFields) > 0 {
+			set = append(set, "booking_fields")
+		}
+		if len(set) > 0 {
+			return fmt.Errorf("area %q holds no item groups, so %s would not apply; set them on its nested areas",
+				area.ID, strings.Join(set, ", "))
+		}
+		return nil
+	})
+}
+
+// validateNestedReservations checks that the reserved_for list of every
+// nested area is a subset of the list it would otherwise inherit.
+func validateNestedReservations(tree []Area, parentSet map[string]struct{}, parentID string) error {
+	for i := range tree {
+		area := &tree[i]
+		if err := checkSubset(parentSet, area.ReservedFor, parentID, area.ID, "area"); err != nil {
+			return err
+		}
+		set := parentSet
+		if len(area.ReservedFor) > 0 {
+			set = toStringSet(area.ReservedFor)
+		}
+		if err := validateNestedReservations(area.Areas, set, area.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package areas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+)
+
+const nestedAreasYAML = `areas:
+  - id: hq
+    name: HQ
+    timezone: Europe/Berlin
+    icon: mdi-office-building
+    reserved_for: [ada@example.com, bob@example.com]
+    items: []
+    areas:
+      - id: hq_b2
+        name: Building 2
+        floor_plan: b2.svg
+        max_bookings_per_person: 2
+        items: []
+        areas:
+          - id: hq_b2_3
+            name: 3rd floor
+            reserved_for: [ada@example.com]
+            items:
+              - id: zone_a
+                name: Zone A
+                items:
+                  - id: desk-1
+                    name: Desk 1
+                    equipment: []
+          - id: hq_b2_4
+            name: 4th floor
+            icon: mdi-stairs
+            max_bookings_per_person: 5
+            items: []
+  - id: annex
+    name: Annex
+    items: []
+`
+
+func TestParseNestedAreas(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]byte(nestedAreasYAML))
+	require.NoError(t, err)
+
+	ids := func(list []Area) []string {
+		var result []string
+		for i := range list {
+			result = append(result, list[i].ID)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"hq_b2_3", "hq_b2_4", "annex"}, ids(cfg.Areas), "areas with item groups or no nesting")
+	assert.Equal(t, []string{"hq", "hq_b2", "hq_b2_3", "hq_b2_4", "annex"}, ids(cfg.AllAreas()))
+	assert.Equal(t, []string{"hq", "annex"}, ids(cfg.Tree()))
+	assert.Equal(t, []string{"hq_b2_3", "hq_b2_4"}, ids(cfg.ChildAreas("hq_b2")))
+	assert.Equal(t, []string{"hq", "annex"}, ids(cfg.ChildAreas("")))
+
+	floor, ok := cfg.FindArea("hq_b2_3")
+	require.True(t, ok)
+	assert.Equal(t, "hq_b2", floor.ParentID)
+	assert.Equal(t, "Europe/Berlin", floor.Timezone)
+	assert.Equal(t, "mdi-office-building", floor.Icon)
+	assert.Equal(t, "b2.svg", floor.FloorPlan)
+	assert.Equal(t, 2, floor.MaxBookingsPerPerson)
+	assert.Equal(t, []string{"ada@example.com"}, floor.ReservedFor)
+
+	other, ok := cfg.FindArea("hq_b2_4")
+	require.True(t, ok)
+	assert.Equal(t, "mdi-stairs", other.Icon)
+	assert.Equal(t, 5, other.MaxBookingsPerPerson, "nested areas override inherited settings")
+	assert.Equal(t, []string{"ada@example.com", "bob@example.com"}, other.ReservedFor)
+
+	_, ok = cfg.FindArea("hq_b2")
+	assert.False(t, ok, "areas that only hold nested areas are not bookable")
+	building, ok := cfg.FindTreeArea("hq_b2")
+	require.True(t, ok)
+	assert.Equal(t, "hq", building.ParentID)
+
+	loc, ok := cfg.FindItemLocation("desk-1")
+	require.True(t, ok)
+	assert.Equal(t, "hq_b2_3", loc.Area.ID)
+	assert.False(t, IsReserved(loc, "ada@example.com"))
+	assert.True(t, IsReserved(loc, "bob@example.com"))
+
+	// The areas are written back as nested, without inherited settings.
+	data, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+	again, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Tree(), again.Tree())
+	assert.Equal(t, 1, strings.Count(string(data), "Europe/Berlin"))
+}
+
+func TestParseRejectsInvalidNestedAreas(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		yaml string
+		want error
+	}{
+		"duplicate id": {`areas:
+  - id: hq
+    name: HQ
+    items: []
+    areas:
+      - id: hq
+        name: Floor
+        items: []
+`, ErrDuplicateID},
+		"missing name": {`areas:
+  - id: hq
+    items: []
+    areas:
+      - id: floor
+        name: Floor
+        items: []
+`, nil},
+		"policies without item groups": {`areas:
+  - id: hq
+    name: HQ
+    policies:
+      - name: Limit
+        max_active_bookings: 1
+    items: []
+    areas:
+      - id: floor
+        name: Floor
+        items: []
+`, nil},
+		"invalid inherited timezone": {`areas:
+  - id: hq
+    name: HQ
+    timezone: Mars/Olympus
+    items: []
+    areas:
+      - id: floor
+        name: Floor
+        items: []
+`, nil},
+	} {
+		_, err := Parse([]byte(tc.yaml))
+		require.Error(t, err, name)
+		if tc.want != nil {
+			assert.True(t, errors.Is(err, tc.want), name)
+		}
+	}
+}
+
+func TestValidateReservationsNested(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]byte(strings.Replace(nestedAreasYAML,
+		"reserved_for: [ada@example.com]", "reserved_for: [eve@example.com]", 1)))
+	require.NoError(t, err)
+	err = ValidateReservations(cfg)
+	require.ErrorIs(t, err, ErrReservationConflict)
+	assert.Contains(t, err.Error(), "hq_b2_3")
+}
+
+func TestTreeAndChildrenHandlers(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]byte(nestedAreasYAML))
+	require.NoError(t, err)
+	getConfig := func() *Config { return cfg }
+
+	serve := func(h echo.HandlerFunc, areaID string) (*httptest.ResponseRecorder, []api.Resource) {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", http.NoBody), rec)
+		c.SetParamNames("area_id")
+		c.SetParamValues(areaID)
+		require.NoError(t, h(c))
+		var resp api.CollectionResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec, resp.Data
+	}
+
+	rec, data := serve(TreeHandler(getConfig), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, data, 5)
+	assert.Equal(t, "hq_b2", data[1].ID)
+	attrs, ok := data[1].Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, false, attrs["bookable"])
+	assert.Equal(t, "mdi-office-building", attrs["icon"])
+	assert.Equal(t, "/api/v1/areas/hq_b2/areas", data[1].Links.Related)
+	assert.Equal(t, "hq", data[1].Relationships["parent"].Data.ID)
+
+	rec, data = serve(ChildrenHandler(getConfig), "hq_b2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, data, 2)
+	assert.Equal(t, "hq_b2_3", data[0].ID)
+	attrs, ok = data[0].Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, true, attrs["bookable"])
+	assert.Equal(t, "/api/v1/areas/hq_b2_3/item-groups", data[0].Links.Related)
+
+	rec, _ = serve(ChildrenHandler(getConfig), "nowhere")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,187 @@
+package areas
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lottery switches an area or item group from first-come booking to lottery
+// allocation on its busy days. Users submit requests until the cutoff, then the
+// allocator assigns the items fairly and books them for the winners.
+type Lottery struct {
+	// Weekdays lists the days allocated by lottery (e.g. tuesday); empty means every day.
+	Weekdays []string `yaml:"weekdays,omitempty"`
+	// CutoffDays is how many days before the booked day requests close (default 1).
+	CutoffDays int `yaml:"cutoff_days,omitempty"`
+	// CutoffTime is the area-local time of day (HH:MM) requests close (default 00:00).
+	CutoffTime string `yaml:"cutoff_time,omitempty"`
+	// Waitlist keeps losing requests and books them when an item becomes free.
+	Waitlist bool `yaml:"waitlist,omitempty"`
+	// FairnessWeeks is how far back lost requests raise a user's chances (default 8).
+	FairnessWeeks int `yaml:"fairness_weeks,omitempty"`
+}
+
+// Lottery scope kinds.
+const (
+	LotteryScopeArea      = "area"
+	LotteryScopeItemGroup = "item_group"
+)
+
+const (
+	defaultLotteryCutoffDays    = 1
+	defaultLotteryFairnessWeeks = 8
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// AppliesOn reports whether the YYYY-MM-DD date is allocated by lottery.
+func (l *Lottery) AppliesOn(date string) bool {
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return false
+	}
+	if len(l.Weekdays) == 0 {
+		return true
+	}
+	for _, name := range l.Weekdays {
+		if weekdayNames[strings.ToLower(name)] == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// Cutoff returns the moment requests for the YYYY-MM-DD date close, with the
+// cutoff time taken in the time zone loc.
+func (l *Lottery) Cutoff(date string, loc *time.Location) time.Time {
+	day, err := time.ParseInLocation(time.DateOnly, date, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	days := l.CutoffDays
+	if days == 0 {
+		days = defaultLotteryCutoffDays
+	}
+	cutoff := day.AddDate(0, 0, -days)
+	if t, err := time.Parse("15:04", l.CutoffTime); err == nil {
+		cutoff = time.Date(cutoff.Year(), cutoff.Month(), cutoff.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	}
+	return cutoff
+}
+
+// FairnessWindow returns how far back lost requests are counted.
+func (l *Lottery) FairnessWindow() time.Duration {
+	weeks := l.FairnessWeeks
+	if weeks == 0 {
+		weeks = defaultLotteryFairnessWeeks
+	}
+	return time.Duration(weeks) * 7 * 24 * time.Hour
+}
+
+// LotteryScope is an area or item group with lottery allocation. An area
+// lottery covers all item groups of the area that have no lottery of their own.
+type LotteryScope struct {
+	Kind    string
+	ID      string
+	Name    string
+	Lottery *Lottery
+	Items   []ItemLocation
+	// Zone is the time zone of the scope's area.
+	Zone *time.Location
+}
+
+// Key identifies the scope in stored lottery requests.
+func (s *LotteryScope) Key() string {
+	return s.Kind + ":" + s.ID
+}
+
+// Cutoff returns the moment requests for the YYYY-MM-DD date close.
+func (s *LotteryScope) Cutoff(date string) time.Time {
+	return s.Lottery.Cutoff(date, s.Zone)
+}
+
+// LotteryScopeFor returns the lottery scope of an item location, if any.
+func (c *Config) LotteryScopeFor(loc *ItemLocation) (*LotteryScope, bool) {
+	if loc.ItemGroup != nil && loc.ItemGroup.Lottery != nil {
+		return itemGroupLotteryScope(loc.Area, loc.ItemGroup, c.AreaZone(loc.Area)), true
+	}
+	if loc.Area != nil && loc.Area.Lottery != nil {
+		return areaLotteryScope(loc.Area, c.AreaZone(loc.Area)), true
+	}
+	return nil, false
+}
+
+// LotteryScopes returns every configured lottery scope.
+func (c *Config) LotteryScopes() []*LotteryScope {
+	var result []*LotteryScope
+	for i := range c.Areas {
+		area := &c.Areas[i]
+		zone := c.AreaZone(area)
+		if area.Lottery != nil {
+			result = append(result, areaLotteryScope(area, zone))
+		}
+		for j := range area.ItemGroups {
+			if area.ItemGroups[j].Lottery != nil {
+				result = append(result, itemGroupLotteryScope(area, &area.ItemGroups[j], zone))
+			}
+		}
+	}
+	return result
+}
+
+// FindLotteryScope returns the scope with the given key.
+func (c *Config) FindLotteryScope(key string) (*LotteryScope, bool) {
+	for _, scope := range c.LotteryScopes() {
+		if scope.Key() == key {
+			return scope, true
+		}
+	}
+	return nil, false
+}
+
+func areaLotteryScope(area *Area, zone *time.Location) *LotteryScope {
+	scope := &LotteryScope{Kind: LotteryScopeArea, ID: area.ID, Name: area.Name, Lottery: area.Lottery, Zone: zone}
+	for j := range area.ItemGroups {
+		ig := &area.ItemGroups[j]
+		if ig.Lottery != nil {
+			continue
+		}
+		for k := range ig.Items {
+			scope.Items = append(scope.Items, ItemLocation{Area: area, ItemGroup: ig, Item: &ig.Items[k]})
+		}
+	}
+	return scope
+}
+
+func itemGroupLotteryScope(area *Area, ig *ItemGroup, zone *time.Location) *LotteryScope {
+	scope := &LotteryScope{Kind: LotteryScopeItemGroup, ID: ig.ID, Name: ig.Name, Lottery: ig.Lottery, Zone: zone}
+	for k := range ig.Items {
+		scope.Items = append(scope.Items, ItemLocation{Area: area, ItemGroup: ig, Item: &ig.Items[k]})
+	}
+	return scope
+}
+
+func validateLotteries(cfg *Config) error {
+	for _, scope := range cfg.LotteryScopes() {
+		l := scope.Lottery
+		location := fmt.Sprintf("%s %q", strings.ReplaceAll(scope.Kind, "_", " "), scope.ID)
+		for _, name := range l.Weekdays {
+			if _, ok := weekdayNames[strings.ToLower(name)]; !ok {
+				return fmt.Errorf("%s: lottery weekday %q is not a day name", location, name)
+			}
+		}
+		if l.CutoffDays < 0 || l.FairnessWeeks < 0 {
+			return fmt.Errorf("%s: lottery cutoff_days and fairness_weeks must not be negative", location)
+		}
+		if l.CutoffTime != "" {
+			if _, err := time.Parse("15:04", l.CutoffTime); err != nil {
+				return fmt.Errorf("%s: lottery cutoff_time must be HH:MM: %q", location, l.CutoffTime)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package areas
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLotteryAppliesOn(t *testing.T) {
+	l := &Lottery{Weekdays: []string{"Tuesday", "thursday"}}
+	if !l.AppliesOn("2026-11-03") {
+		t.Errorf("expected lottery on Tuesday")
+	}
+	if l.AppliesOn("2026-11-04") {
+		t.Errorf("expected no lottery on Wednesday")
+	}
+	if !(&Lottery{}).AppliesOn("2026-11-04") {
+		t.Errorf("expected lottery on every day without weekdays")
+	}
+	if l.AppliesOn("not-a-date") {
+		t.Errorf("expected invalid date to be ignored")
+	}
+}
+
+func TestLotteryCutoff(t *testing.T) {
+	got := (&Lottery{}).Cutoff("2026-11-03", time.UTC)
+	if want := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("default cutoff = %v, want %v", got, want)
+	}
+	got = (&Lottery{CutoffDays: 2, CutoffTime: "17:30"}).Cutoff("2026-11-03", time.UTC)
+	if want := time.Date(2026, 11, 1, 17, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("cutoff = %v, want %v", got, want)
+	}
+
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Fatalf("load zone: %v", err)
+	}
+	got = (&Lottery{CutoffTime: "17:30"}).Cutoff("2026-11-03", sydney)
+	if want := time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Sydney cutoff = %v, want %v", got.UTC(), want)
+	}
+}
+
+func TestLotteryScopeForPrefersItemGroup(t *testing.T) {
+	cfg := &Config{Areas: []Area{{
+		ID: "office", Name: "Office", Lottery: &Lottery{},
+		ItemGroups: []ItemGroup{
+			{ID: "room-1", Name: "Room 1", Items: []Item{{ID: "desk-1", Name: "Desk 1"}}},
+			{
+				ID: "room-2", Name: "Room 2", Lottery: &Lottery{Waitlist: true},
+				Items: []Item{{ID: "desk-2", Name: "Desk 2"}},
+			},
+		},
+	}}}
+
+	loc, _ := cfg.FindItemLocation("desk-2")
+	scope, ok := cfg.LotteryScopeFor(loc)
+	if !ok || scope.Key() != "item_group:room-2" {
+		t.Fatalf("expected item group scope, got %+v", scope)
+	}
+
+	area, ok := cfg.FindLotteryScope("area:office")
+	if !ok {
+		t.Fatalf("expected area scope")
+	}
+	if len(area.Items) != 1 || area.Items[0].Item.ID != "desk-1" {
+		t.Errorf("expected area scope to exclude item groups with their own lottery, got %d items", len(area.Items))
+	}
+	if got := len(cfg.LotteryScopes()); got != 2 {
+		t.Errorf("expected 2 scopes, got %d", got)
+	}
+}
+
+func TestValidateLotteries(t *testing.T) {
+	tests := []struct {
+		name    string
+		lottery *Lottery
+		wantErr string
+	}{
+		{name: "valid", lottery: &Lottery{Weekdays: []string{"monday"}, CutoffTime: "08:00"}},
+		{name: "unknown weekday", lottery: &Lottery{Weekdays: []string{"mon"}}, wantErr: "not a day name"},
+		{name: "negative cutoff", lottery: &Lottery{CutoffDays: -1}, wantErr: "must not be negative"},
+		{name: "bad time", lottery: &Lottery{CutoffTime: "8am"}, wantErr: "HH:MM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Areas: []Area{{ID: "office", Name: "Office", Lottery: tt.lottery}}}
+			err := validateLotteries(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
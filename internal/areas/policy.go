@@ -0,0 +1,204 @@
+package areas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policy audiences select which bookings a policy applies to.
+const (
+	// AppliesToAll applies the policy to every booking (the default).
+	AppliesToAll = "all"
+	// AppliesToUsers applies the policy to non-guest bookings.
+	AppliesToUsers = "users"
+	// AppliesToGuests applies the policy to guest bookings only.
+	AppliesToGuests = "guests"
+	// AppliesToMembers applies the policy to users listed in a reserved_for
+	// list anywhere inside the policy's scope.
+	AppliesToMembers = "members"
+	// AppliesToNonMembers applies the policy to non-guest users that are not
+	// listed in any reserved_for list inside the policy's scope.
+	AppliesToNonMembers = "non_members"
+)
+
+// Policy is a declarative booking rule. Policies are attached globally, to an
+// area, or to an item group; the scope determines which bookings are counted.
+// A zero value for a limit means the limit is not set.
+type Policy struct {
+	Name      string `yaml:"name,omitempty"`
+	AppliesTo string `yaml:"applies_to,omitempty"`
+	// MaxDaysPerWeek limits the number of distinct days per ISO week with a
+	// booking inside the scope.
+	MaxDaysPerWeek int `yaml:"max_days_per_week,omitempty"`
+	// MaxPerDay limits the number of bookings per day inside the scope.
+	MaxPerDay int `yaml:"max_per_day,omitempty"`
+	// MaxActiveBookings limits the number of upcoming bookings inside the scope.
+	MaxActiveBookings int `yaml:"max_active_bookings,omitempty"`
+	// MaxWeeksAhead limits how far in advance the scope can be booked, counted
+	// like the global weeks_in_advanced setting. It can only tighten that setting.
+	MaxWeeksAhead int `yaml:"max_weeks_ahead,omitempty"`
+	// MinNoticeHours is the minimum time between booking and the start of the
+	// booked day.
+	MinNoticeHours int `yaml:"min_notice_hours,omitempty"`
+	// MinNoShows and MinLateCancellations restrict the policy to users with at
+	// least that many no-shows or late cancellations (either suffices) in the
+	// last AttendanceWindowDays, e.g. to lower the limits of habitual no-shows.
+	MinNoShows           int `yaml:"min_no_shows,omitempty"`
+	MinLateCancellations int `yaml:"min_late_cancellations,omitempty"`
+	AttendanceWindowDays int `yaml:"attendance_window_days,omitempty"`
+}
+
+// defaultAttendanceWindowDays is how far back attendance conditions look by default.
+const defaultAttendanceWindowDays = 90
+
+// HasAttendanceCondition reports whether the policy only applies to users with
+// a record of no-shows or late cancellations.
+func (p *Policy) HasAttendanceCondition() bool {
+	return p.MinNoShows > 0 || p.MinLateCancellations > 0
+}
+
+// AttendanceWindow returns the number of days the attendance conditions look back.
+func (p *Policy) AttendanceWindow() int {
+	if p.AttendanceWindowDays > 0 {
+		return p.AttendanceWindowDays
+	}
+	return defaultAttendanceWindowDays
+}
+
+// MatchesAttendance reports whether a user with the given number of no-shows and
+// late cancellations in the attendance window meets the policy's conditions.
+// Policies without conditions match everyone.
+func (p *Policy) MatchesAttendance(noShows, lateCancellations int) bool {
+	if !p.HasAttendanceCondition() {
+		return true
+	}
+	return (p.MinNoShows > 0 && noShows >= p.MinNoShows) ||
+		(p.MinLateCancellations > 0 && lateCancellations >= p.MinLateCancellations)
+}
+
+// Audience returns the normalized applies_to value.
+func (p *Policy) Audience() string {
+	if p.AppliesTo == "" {
+		return AppliesToAll
+	}
+	return p.AppliesTo
+}
+
+// Label returns the policy name, or a generic label for unnamed policies.
+func (p *Policy) Label() string {
+	if name := strings.TrimSpace(p.Name); name != "" {
+		return name
+	}
+	return "Booking policy"
+}
+
+// Matches reports whether the policy applies to a booking, given whether it is
+// a guest booking and whether the booked user is a reserved_for member of the
+// policy's scope.
+func (p *Policy) Matches(isGuest, isMember bool) bool {
+	switch p.Audience() {
+	case AppliesToUsers:
+		return !isGuest
+	case AppliesToGuests:
+		return isGuest
+	case AppliesToMembers:
+		return !isGuest && isMember
+	case AppliesToNonMembers:
+		return !isGuest && !isMember
+	default:
+		return true
+	}
+}
+
+// ListsReservedFor reports whether userEmail appears in any reserved_for list
+// of the configuration.
+func (c *Config) ListsReservedFor(userEmail string) bool {
+	for i := range c.Areas {
+		if c.Areas[i].ListsReservedFor(userEmail) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListsReservedFor reports whether userEmail appears in the reserved_for list of
+// the area or of any of its item groups or items.
+func (a *Area) ListsReservedFor(userEmail string) bool {
+	if userEmail == "" {
+		return false
+	}
+	if containsString(a.ReservedFor, userEmail) {
+		return true
+	}
+	for i := range a.ItemGroups {
+		if a.ItemGroups[i].ListsReservedFor(userEmail) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListsReservedFor reports whether userEmail appears in the reserved_for list of
+// the item group or of any of its items.
+func (ig *ItemGroup) ListsReservedFor(userEmail string) bool {
+	if userEmail == "" {
+		return false
+	}
+	if containsString(ig.ReservedFor, userEmail) {
+		return true
+	}
+	for i := range ig.Items {
+		if containsString(ig.Items[i].ReservedFor, userEmail) {
+			return true
+		}
+	}
+	return false
+}
+
+func validatePolicies(cfg *Config) error {
+	if err := validatePolicyList(cfg.Policies, "global policies"); err != nil {
+		return err
+	}
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		if err := validatePolicyList(area.Policies, fmt.Sprintf("area %q", area.ID)); err != nil {
+			return err
+		}
+		for j := range area.ItemGroups {
+			ig := &area.ItemGroups[j]
+			if err := validatePolicyList(ig.Policies, fmt.Sprintf("item group %q", ig.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validatePolicyList(policies []Policy, location string) error {
+	for i := range policies {
+		p := &policies[i]
+		switch p.Audience() {
+		case AppliesToAll, AppliesToUsers, AppliesToGuests, AppliesToMembers, AppliesToNonMembers:
+		default:
+			return fmt.Errorf("%s: policy %q has invalid applies_to %q", location, p.Label(), p.AppliesTo)
+		}
+		if p.MaxDaysPerWeek < 0 || p.MaxPerDay < 0 || p.MaxActiveBookings < 0 ||
+			p.MaxWeeksAhead < 0 || p.MinNoticeHours < 0 {
+			return fmt.Errorf("%s: policy %q has a negative limit", location, p.Label())
+		}
+		if p.MinNoShows < 0 || p.MinLateCancellations < 0 || p.AttendanceWindowDays < 0 {
+			return fmt.Errorf("%s: policy %q has a negative attendance condition", location, p.Label())
+		}
+		if p.HasAttendanceCondition() && p.Audience() == AppliesToGuests {
+			return fmt.Errorf("%s: policy %q: attendance conditions do not apply to guests", location, p.Label())
+		}
+		if p.MaxDaysPerWeek > 7 {
+			return fmt.Errorf("%s: policy %q max_days_per_week must be at most 7", location, p.Label())
+		}
+		if p.MaxDaysPerWeek == 0 && p.MaxPerDay == 0 && p.MaxActiveBookings == 0 &&
+			p.MaxWeeksAhead == 0 && p.MinNoticeHours == 0 {
+			return fmt.Errorf("%s: policy %q sets no limit", location, p.Label())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package areas
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicyMatchesAudience(t *testing.T) {
+	cases := []struct {
+		appliesTo       string
+		guest, member   bool
+		expectedToMatch bool
+	}{
+		{"", true, false, true},
+		{AppliesToUsers, true, false, false},
+		{AppliesToGuests, true, false, true},
+		{AppliesToGuests, false, false, false},
+		{AppliesToMembers, false, true, true},
+		{AppliesToMembers, false, false, false},
+		{AppliesToNonMembers, false, false, true},
+		{AppliesToNonMembers, true, false, false},
+	}
+	for _, tc := range cases {
+		p := Policy{AppliesTo: tc.appliesTo, MaxPerDay: 1}
+		if got := p.Matches(tc.guest, tc.member); got != tc.expectedToMatch {
+			t.Errorf("applies_to %q guest=%v member=%v: got %v", tc.appliesTo, tc.guest, tc.member, got)
+		}
+	}
+}
+
+func TestListsReservedForSearchesWholeScope(t *testing.T) {
+	cfg := &Config{Areas: []Area{{
+		ID: "a1", Name: "Main",
+		ItemGroups: []ItemGroup{
+			{ID: "g1", Name: "Open space", Items: []Item{{ID: "d1", Name: "Desk 1"}}},
+			{ID: "g2", Name: "Team", Items: []Item{
+				{ID: "d2", Name: "Desk 2", ReservedFor: []string{"vip@example.com"}},
+			}},
+		},
+	}}}
+
+	if !cfg.ListsReservedFor("vip@example.com") || !cfg.Areas[0].ListsReservedFor("vip@example.com") {
+		t.Fatalf("expected vip to be listed in the area")
+	}
+	if cfg.Areas[0].ItemGroups[0].ListsReservedFor("vip@example.com") {
+		t.Fatalf("vip should not be listed in item group g1")
+	}
+	if cfg.ListsReservedFor("") {
+		t.Fatalf("empty email must never be a member")
+	}
+}
+
+func TestLoadRejectsInvalidPolicy(t *testing.T) {
+	cases := []struct {
+		policies string
+		expected string
+	}{
+		{
+			policies: "    policies:\n      - name: Parking\n        applies_to: everyone\n        max_per_day: 1\n",
+			expected: "invalid applies_to",
+		},
+		{policies: "    policies:\n      - name: Empty\n", expected: "sets no limit"},
+		{policies: "    policies:\n      - max_days_per_week: 8\n", expected: "at most 7"},
+	}
+	for _, tc := range cases {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "areas.yaml")
+		content := "areas:\n  - id: area-1\n    name: Office\n" + tc.policies + `    items:
+      - id: room-1
+        name: Room 1
+        items:
+          - id: desk-1
+            name: Desk 1
+`
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write areas config: %v", err)
+		}
+
+		_, err := Load(path)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("expected error containing %q, got %v", tc.expected, err)
+		}
+	}
+}
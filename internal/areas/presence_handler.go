@@ -8,6 +8,8 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/delegations"
 	"github.com/thorstenkramm/sithub/internal/users"
 )
 
@@ -20,6 +22,12 @@ type PresenceAttributes struct {
 	ItemGroupID   string `json:"item_group_id"`
 	ItemGroupName string `json:"item_group_name"`
 	Note          string `json:"note"`
+	// WorkStatus is the user's work status on the date (e.g. travelling) if
+	// the user lets the viewer see it.
+	WorkStatus string `json:"work_status,omitempty"`
+	// Private marks a private booking. Users who may not see the booker get
+	// no user, note, or work status.
+	Private bool `json:"private,omitempty"`
 }
 
 // PresenceHandler returns a JSON:API list of users present in an area on a given date.
@@ -37,12 +45,13 @@ func PresenceHandlerDynamic(getConfig ConfigGetter, store *sql.DB) echo.HandlerF
 			return api.WriteNotFound(c, "Area not found")
 		}
 
-		params, err := api.ParseItemGroupRequest(areaID, c.QueryParam("date"))
+		params, err := api.ParseItemGroupRequest(areaID, c.QueryParam("date"), cfg.AreaZone(area))
 		if err != nil {
 			return api.WriteBadRequest(c, "Invalid date. Use YYYY-MM-DD.")
 		}
 
-		presence, err := findAreaPresence(c.Request().Context(), store, area, params.BookingDate)
+		presence, err := findAreaPresence(
+			c.Request().Context(), store, area, params.BookingDate, auth.GetUserFromContext(c))
 		if err != nil {
 			return fmt.Errorf("find area presence: %w", err)
 		}
@@ -84,23 +93,46 @@ func buildItemIndex(area *Area) (itemIDs []string, itemInfo map[string]itemDetai
 	return itemIDs, itemInfo
 }
 
+// findAreaPresence returns the bookings in the area on bookingDate. Work
+// statuses are included if the booked user shows them to everyone, or to
+// their teams and the viewer shares a team with them. Private bookings are
+// hidden from viewers who may not see the booker.
 func findAreaPresence(
-	ctx context.Context, store *sql.DB, area *Area, bookingDate string,
+	ctx context.Context, store *sql.DB, area *Area, bookingDate string, viewer *auth.User,
 ) ([]api.Resource, error) {
 	itemIDs, itemInfo := buildItemIndex(area)
 	if len(itemIDs) == 0 {
 		return []api.Resource{}, nil
 	}
+	vis, err := delegations.LoadBookerVisibility(ctx, store, viewer)
+	if err != nil {
+		return nil, err
+	}
 
+	viewerID := ""
+	if viewer != nil {
+		viewerID = viewer.ID
+	}
 	placeholders, args := api.BuildINClause(itemIDs)
+	args = append([]any{viewerID, viewerID}, args...)
 	args = append(args, bookingDate)
 
 	//nolint:gosec // G201: placeholders are "?" literals from BuildINClause, not user input
 	query := fmt.Sprintf(
-		`SELECT id, item_id, user_id, note
-		 FROM bookings
-		 WHERE item_id IN (%s) AND booking_date = ?
-		 ORDER BY item_id`,
+		`SELECT b.id, b.item_id, b.user_id, b.note,
+		        CASE WHEN b.user_id = ?
+		               OR u.work_status_visibility = 'everyone'
+		               OR (u.work_status_visibility = 'team' AND EXISTS (
+		                   SELECT 1 FROM team_members mine
+		                   JOIN team_members theirs ON theirs.team_id = mine.team_id
+		                   WHERE mine.user_id = ? AND theirs.user_id = b.user_id))
+		             THEN COALESCE(ws.status, '') ELSE '' END,
+		        b.is_private, b.booked_by_user_id
+		 FROM bookings b
+		 LEFT JOIN users u ON u.id = b.user_id
+		 LEFT JOIN work_statuses ws ON ws.user_id = b.user_id AND ws.status_date = b.booking_date AND b.is_guest = 0
+		 WHERE b.item_id IN (%s) AND b.booking_date = ?
+		 ORDER BY b.item_id`,
 		placeholders,
 	)
 
@@ -114,29 +146,40 @@ func findAreaPresence(
 		}
 	}()
 
-	return scanPresenceRows(ctx, store, rows, itemInfo)
+	return scanPresenceRows(ctx, store, rows, itemInfo, vis)
 }
 
 func scanPresenceRows(
 	ctx context.Context, store *sql.DB, rows *sql.Rows, itemInfo map[string]itemDetails,
+	vis *delegations.BookerVisibility,
 ) ([]api.Resource, error) {
 	type booking struct {
-		bookingID string
-		itemID    string
-		userID    string
-		note      string
+		bookingID  string
+		itemID     string
+		userID     string
+		note       string
+		workStatus string
+		isPrivate  bool
 	}
 
 	var bookingList []booking
 	userIDSet := make(map[string]struct{})
 
 	for rows.Next() {
-		var bookingID, itemID, userID, note string
-		if err := rows.Scan(&bookingID, &itemID, &userID, &note); err != nil {
+		var b booking
+		var bookedByUserID string
+		if err := rows.Scan(
+			&b.bookingID, &b.itemID, &b.userID, &b.note, &b.workStatus, &b.isPrivate, &bookedByUserID,
+		); err != nil {
 			return nil, fmt.Errorf("scan area presence: %w", err)
 		}
-		bookingList = append(bookingList, booking{bookingID: bookingID, itemID: itemID, userID: userID, note: note})
-		userIDSet[userID] = struct{}{}
+		if !vis.Sees(b.userID, bookedByUserID, b.isPrivate) {
+			b.userID, b.note, b.workStatus = "", "", ""
+		}
+		bookingList = append(bookingList, b)
+		if b.userID != "" {
+			userIDSet[b.userID] = struct{}{}
+		}
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate area presence: %w", err)
@@ -171,6 +214,8 @@ func scanPresenceRows(
 				ItemGroupID:   info.ItemGroupID,
 				ItemGroupName: info.ItemGroupName,
 				Note:          b.note,
+				WorkStatus:    b.workStatus,
+				Private:       b.isPrivate,
 			},
 		})
 	}
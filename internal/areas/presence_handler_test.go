@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
 	"github.com/thorstenkramm/sithub/internal/db"
 )
 
@@ -39,6 +40,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 			guest_name TEXT NOT NULL DEFAULT '',
 			guest_email TEXT NOT NULL DEFAULT '',
 			note TEXT NOT NULL DEFAULT '',
+			is_private INTEGER NOT NULL DEFAULT 0,
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL,
 			UNIQUE(item_id, booking_date)
@@ -57,7 +59,34 @@ func setupTestDB(t *testing.T) *sql.DB {
 			is_admin INTEGER NOT NULL DEFAULT 0,
 			last_login TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
-			updated_at TEXT NOT NULL
+			updated_at TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1,
+			work_status_visibility TEXT NOT NULL DEFAULT 'team'
+		)
+	`)
+	require.NoError(t, err)
+
+	_, err = store.Exec(`
+		CREATE TABLE IF NOT EXISTS work_statuses (
+			user_id TEXT NOT NULL,
+			status_date TEXT NOT NULL,
+			status TEXT NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY (user_id, status_date)
+		);
+		CREATE TABLE IF NOT EXISTS team_members (
+			team_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			PRIMARY KEY (team_id, user_id)
+		);
+		CREATE TABLE IF NOT EXISTS delegations (
+			id TEXT PRIMARY KEY,
+			user_id TEXT,
+			delegate_id TEXT NOT NULL,
+			created_by_user_id TEXT NOT NULL,
+			created_at TEXT NOT NULL
 		)
 	`)
 	require.NoError(t, err)
@@ -215,6 +244,96 @@ func TestPresenceHandlerReturnsUsersInArea(t *testing.T) {
 	assert.Equal(t, "Room Two", attrs1["item_group_name"])
 }
 
+func TestPresenceHandlerShowsVisibleWorkStatuses(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	cfg := testConfig()
+	seedTestUser(t, store, "user-1", "Alice")
+	seedTestUser(t, store, "user-2", "Bob")
+	seedTestUser(t, store, "user-3", "Carol")
+	seedTestBooking(t, store, "b1", "desk-1", "user-1", "2025-01-20")
+	seedTestBooking(t, store, "b2", "desk-2", "user-2", "2025-01-20")
+	seedTestBooking(t, store, "b3", "desk-3", "user-3", "2025-01-20")
+	now := time.Now().Format(time.RFC3339)
+	_, err := store.Exec(
+		`UPDATE users SET work_status_visibility = 'everyone' WHERE id = 'user-1';
+		 INSERT INTO team_members (team_id, user_id) VALUES ('t1', 'viewer'), ('t1', 'user-2');
+		 INSERT INTO work_statuses (user_id, status_date, status, created_at, updated_at)
+		 VALUES ('user-1', '2025-01-20', 'travelling', ?1, ?1), ('user-2', '2025-01-20', 'office', ?1, ?1),
+		        ('user-3', '2025-01-20', 'travelling', ?1, ?1)`, now)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/areas/area-1/presence?date=2025-01-20", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("area_id")
+	c.SetParamValues("area-1")
+	c.Set("user", &auth.User{ID: "viewer"})
+	require.NoError(t, PresenceHandler(cfg, store)(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data []struct {
+			Attributes PresenceAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 3)
+	assert.Equal(t, "travelling", resp.Data[0].Attributes.WorkStatus, "visible to everyone")
+	assert.Equal(t, "office", resp.Data[1].Attributes.WorkStatus, "shared team")
+	assert.Empty(t, resp.Data[2].Attributes.WorkStatus, "no shared team")
+}
+
+func TestPresenceHandlerHidesPrivateBookers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	cfg := testConfig()
+	seedTestUser(t, store, "user-1", "Alice")
+	seedTestUser(t, store, "user-2", "Bob")
+	seedTestBooking(t, store, "b1", "desk-1", "user-1", "2025-01-20")
+	seedTestBooking(t, store, "b2", "desk-2", "user-2", "2025-01-20")
+	_, err := store.Exec(
+		`UPDATE bookings SET is_private = 1, note = 'secret' WHERE id = 'b1';
+		 INSERT INTO delegations (id, user_id, delegate_id, created_by_user_id, created_at)
+		 VALUES ('d1', 'user-1', 'assistant', 'user-1', '2025-01-01')`)
+	require.NoError(t, err)
+
+	presence := func(viewer *auth.User) []PresenceAttributes {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/areas/area-1/presence?date=2025-01-20", http.NoBody)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("area_id")
+		c.SetParamValues("area-1")
+		c.Set("user", viewer)
+		require.NoError(t, PresenceHandler(cfg, store)(c))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp struct {
+			Data []struct {
+				Attributes PresenceAttributes `json:"attributes"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 2)
+		return []PresenceAttributes{resp.Data[0].Attributes, resp.Data[1].Attributes}
+	}
+
+	got := presence(&auth.User{ID: "user-2"})
+	assert.Equal(t, PresenceAttributes{
+		ItemID: "desk-1", ItemName: "Desk 1", ItemGroupID: "room-1", ItemGroupName: "Room One", Private: true,
+	}, got[0])
+	assert.Equal(t, "Bob", got[1].UserName, "public bookings stay visible")
+
+	for _, viewer := range []*auth.User{{ID: "user-1"}, {ID: "assistant"}, {ID: "admin", IsAdmin: true}} {
+		got = presence(viewer)
+		assert.Equal(t, "Alice", got[0].UserName, viewer.ID)
+		assert.Equal(t, "secret", got[0].Note, viewer.ID)
+		assert.True(t, got[0].Private, viewer.ID)
+	}
+}
+
 func TestPresenceHandlerExcludesOtherDates(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,70 @@
+package areas
+
+import "github.com/thorstenkramm/sithub/internal/api"
+
+// JSON:API resource types of the area hierarchy.
+const (
+	ResourceTypeArea      = "areas"
+	ResourceTypeItemGroup = "item-groups"
+	ResourceTypeItem      = "items"
+)
+
+// AreaResource returns the JSON:API resource of an area with the area it is
+// nested in, if any, linking its item groups.
+func AreaResource(area *Area) api.Resource {
+	res := api.Resource{
+		Type:       ResourceTypeArea,
+		ID:         area.ID,
+		Attributes: BaseAttributes(area.Name, area.Description, area.FloorPlan, area.Icon),
+		Links:      &api.Links{Related: "/api/v1/areas/" + area.ID + "/item-groups"},
+	}
+	if area.ParentID != "" {
+		res.Relationships = map[string]api.Relationship{
+			"parent": api.ToOne(ResourceTypeArea, area.ParentID),
+		}
+	}
+	return res
+}
+
+// TreeAreaResource returns the JSON:API resource of an area of the tree.
+// Bookable areas hold item groups and are listed by ListHandler; the others
+// link the areas nested in them instead.
+func TreeAreaResource(cfg *Config, area *Area) api.Resource {
+	res := AreaResource(area)
+	attrs := BaseAttributes(area.Name, area.Description, area.FloorPlan, area.Icon)
+	_, bookable := cfg.FindArea(area.ID)
+	attrs["bookable"] = bookable
+	res.Attributes = attrs
+	if !bookable {
+		res.Links = &api.Links{Related: "/api/v1/areas/" + area.ID + "/areas"}
+	}
+	return res
+}
+
+// ItemGroupResource returns the JSON:API resource of an item group with its
+// area relationship, linking its items.
+func ItemGroupResource(ig *ItemGroup, areaID string) api.Resource {
+	return api.Resource{
+		Type:       ResourceTypeItemGroup,
+		ID:         ig.ID,
+		Attributes: BaseAttributes(ig.Name, ig.Description, ig.FloorPlan, ig.Icon),
+		Relationships: map[string]api.Relationship{
+			"area": api.ToOne(ResourceTypeArea, areaID),
+		},
+		Links: &api.Links{Related: "/api/v1/item-groups/" + ig.ID + "/items"},
+	}
+}
+
+// ItemResource returns the JSON:API resource of an item with its item group
+// relationship. Booking state is not included.
+func ItemResource(loc *ItemLocation) api.Resource {
+	item := loc.Item
+	return api.Resource{
+		Type:       ResourceTypeItem,
+		ID:         item.ID,
+		Attributes: ItemAttributes(item.Name, item.Equipment, item.Warning, "", item.Icon),
+		Relationships: map[string]api.Relationship{
+			"item_group": api.ToOne(ResourceTypeItemGroup, loc.ItemGroup.ID),
+		},
+	}
+}
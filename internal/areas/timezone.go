@@ -0,0 +1,124 @@
+package areas
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	// Embed the zone database so the single binary does not depend on the host's tzdata.
+	_ "time/tzdata"
+)
+
+// zones caches loaded time zones by IANA name.
+var zones sync.Map
+
+// loadZone returns the named IANA time zone; an empty name is UTC.
+func loadZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location), nil //nolint:forcetypeassert // Only locations are stored
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("load time zone: %w", err)
+	}
+	zones.Store(name, loc)
+	return loc, nil
+}
+
+// zoneOrUTC returns the named time zone, or UTC if the name is not valid.
+// Names are validated when the config is loaded.
+func zoneOrUTC(name string) *time.Location {
+	loc, err := loadZone(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// DefaultZone returns the time zone of areas without their own timezone:
+// the config-wide timezone, or UTC if none is set.
+func (c *Config) DefaultZone() *time.Location {
+	if c == nil {
+		return time.UTC
+	}
+	return zoneOrUTC(c.Timezone)
+}
+
+// AreaZone returns the time zone of the area, falling back to DefaultZone.
+func (c *Config) AreaZone(area *Area) *time.Location {
+	if area == nil || area.Timezone == "" {
+		return c.DefaultZone()
+	}
+	return zoneOrUTC(area.Timezone)
+}
+
+// ItemZone returns the time zone of the area containing the item. Unknown items
+// get the DefaultZone.
+func (c *Config) ItemZone(itemID string) *time.Location {
+	if c == nil {
+		return time.UTC
+	}
+	if loc, ok := c.FindItemLocation(itemID); ok {
+		return c.AreaZone(loc.Area)
+	}
+	return c.DefaultZone()
+}
+
+// ItemGroupZone returns the time zone of the area containing the item group.
+// Unknown item groups get the DefaultZone.
+func (c *Config) ItemGroupZone(itemGroupID string) *time.Location {
+	if c == nil {
+		return time.UTC
+	}
+	for i := range c.Areas {
+		for j := range c.Areas[i].ItemGroups {
+			if c.Areas[i].ItemGroups[j].ID == itemGroupID {
+				return c.AreaZone(&c.Areas[i])
+			}
+		}
+	}
+	return c.DefaultZone()
+}
+
+// TodayRange returns the earliest and the latest current date across the time
+// zones of all areas, for queries that span areas before the per-item date is
+// known.
+func (c *Config) TodayRange(now time.Time) (earliest, latest string) {
+	earliest = LocalDate(now, c.DefaultZone())
+	latest = earliest
+	if c == nil {
+		return earliest, latest
+	}
+	for i := range c.Areas {
+		today := LocalDate(now, c.AreaZone(&c.Areas[i]))
+		earliest = min(earliest, today)
+		latest = max(latest, today)
+	}
+	return earliest, latest
+}
+
+// LocalDate returns the calendar date (YYYY-MM-DD) of t in the time zone loc.
+func LocalDate(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(time.DateOnly)
+}
+
+// CalendarDay returns the calendar date that t shows in its own time zone as
+// midnight UTC, comparable with dates parsed by time.Parse(time.DateOnly, ...).
+func CalendarDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func validateTimezones(cfg *Config) error {
+	if _, err := loadZone(cfg.Timezone); err != nil {
+		return fmt.Errorf("timezone %q: %w", cfg.Timezone, err)
+	}
+	return walkAreas(cfg.Tree(), func(area *Area) error {
+		if _, err := loadZone(area.Timezone); err != nil {
+			return fmt.Errorf("area %q: timezone %q: %w", area.ID, area.Timezone, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,64 @@
+package areas
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZones(t *testing.T) {
+	cfg := &Config{
+		Timezone: "Europe/Berlin",
+		Areas: []Area{
+			{ID: "berlin", ItemGroups: []ItemGroup{{ID: "room-1", Items: []Item{{ID: "desk-1"}}}}},
+			{
+				ID: "sydney", Timezone: "Australia/Sydney",
+				ItemGroups: []ItemGroup{{ID: "room-2", Items: []Item{{ID: "desk-2"}}}},
+			},
+		},
+	}
+
+	if got := cfg.ItemZone("desk-1").String(); got != "Europe/Berlin" {
+		t.Errorf("desk-1 zone = %s, want the default zone", got)
+	}
+	if got := cfg.ItemZone("desk-2").String(); got != "Australia/Sydney" {
+		t.Errorf("desk-2 zone = %s, want the area zone", got)
+	}
+	if got := cfg.ItemGroupZone("room-2").String(); got != "Australia/Sydney" {
+		t.Errorf("room-2 zone = %s, want the area zone", got)
+	}
+	if got := (&Config{}).ItemZone("missing"); got != time.UTC {
+		t.Errorf("zone without config = %s, want UTC", got)
+	}
+
+	// 20:00 UTC is still the same day in Berlin but already the next one in Sydney.
+	now := time.Date(2026, 11, 3, 20, 0, 0, 0, time.UTC)
+	earliest, latest := cfg.TodayRange(now)
+	if earliest != "2026-11-03" || latest != "2026-11-04" {
+		t.Errorf("TodayRange = %s..%s, want 2026-11-03..2026-11-04", earliest, latest)
+	}
+	sydney := now.In(cfg.ItemZone("desk-2"))
+	if got := CalendarDay(sydney); !got.Equal(time.Date(2026, 11, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("CalendarDay = %v, want 2026-11-04", got)
+	}
+}
+
+func TestLoadConfigRejectsUnknownTimezone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "areas.yaml")
+	content := `areas:
+  - id: office
+    name: Office
+    timezone: Mars/Olympus_Mons
+    items: []
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write areas config: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "Mars/Olympus_Mons") {
+		t.Fatalf("expected unknown time zone error, got %v", err)
+	}
+}
@@ -51,7 +51,8 @@ func setupTestDB(t *testing.T) *sql.DB {
 			last_login TEXT NOT NULL DEFAULT '',
 			access_token TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
-			updated_at TEXT NOT NULL
+			updated_at TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1
 		);
 		CREATE UNIQUE INDEX idx_users_email ON users(email);
 		CREATE INDEX idx_users_entra_id ON users(entra_id);
@@ -142,6 +143,46 @@ func TestFetchUserSetsAdminWhenInGroup(t *testing.T) {
 	}
 }
 
+func TestFetchUserSyncsTeamGroups(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := &config.Config{EntraID: config.EntraIDConfig{
+		AuthorizeURL:   "https://example.com/auth",
+		TokenURL:       "https://example.com/token",
+		RedirectURI:    "https://example.com/callback",
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		SyncTeamGroups: true,
+	}}
+
+	svc, err := NewService(cfg, db)
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+	var syncedUser string
+	var syncedGroups []string
+	svc.SetGroupSync(func(_ context.Context, userID string, groupIDs []string) error {
+		syncedUser, syncedGroups = userID, groupIDs
+		return nil
+	})
+
+	client := newGraphClient(map[string]string{
+		graphMeURLWithSelect: graphMeBody,
+		graphMemberOfURL:     graphUsersGroupBody,
+	})
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	user, err := svc.FetchUser(ctx, &oauth2.Token{AccessToken: "token"})
+	if err != nil {
+		t.Fatalf("fetch user: %v", err)
+	}
+	if syncedUser != user.ID || len(syncedGroups) != 1 || syncedGroups[0] != "users" {
+		t.Fatalf("expected groups of %s to be synced, got %s %v", user.ID, syncedUser, syncedGroups)
+	}
+	if user.IsAdmin || !user.IsPermitted {
+		t.Fatalf("team sync must not change permissions, got %#v", user)
+	}
+}
+
 func TestFetchUserRequiresUsersGroupForAdmin(t *testing.T) {
 	db := setupTestDB(t)
 	cfg := &config.Config{
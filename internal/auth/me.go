@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,25 +24,39 @@ func GetUserFromContext(c echo.Context) *User {
 	return user
 }
 
-// MeHandler returns the authenticated user profile.
-func MeHandler() echo.HandlerFunc {
+// MeAttributes returns additional attributes of the /api/v1/me resource, for
+// user data kept by other packages.
+type MeAttributes func(ctx context.Context, user *User) (map[string]interface{}, error)
+
+// MeHandler returns the authenticated user profile, extended by extras.
+func MeHandler(extras ...MeAttributes) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		user := GetUserFromContext(c)
 		if user == nil {
 			return api.WriteUnauthorized(c)
 		}
 
+		attrs := map[string]interface{}{
+			attrDisplayName: user.Name,
+			attrEmail:       user.Email,
+			attrIsAdmin:     user.IsAdmin,
+			attrAuthSource:  user.AuthSource,
+			attrRole:        userRole(user),
+		}
+		for _, extra := range extras {
+			more, err := extra(c.Request().Context(), user)
+			if err != nil {
+				return api.WriteInternalError(c, "load profile", err)
+			}
+			for k, v := range more {
+				attrs[k] = v
+			}
+		}
 		resp := api.SingleResponse{
 			Data: api.Resource{
-				Type: resourceTypeUser,
-				ID:   user.ID,
-				Attributes: map[string]interface{}{
-					attrDisplayName: user.Name,
-					attrEmail:       user.Email,
-					attrIsAdmin:     user.IsAdmin,
-					attrAuthSource:  user.AuthSource,
-					attrRole:        userRole(user),
-				},
+				Type:       resourceTypeUser,
+				ID:         user.ID,
+				Attributes: attrs,
 			},
 		}
 
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -66,3 +67,30 @@ func TestMeHandlerAuthorized(t *testing.T) {
 		t.Fatalf("unexpected is_admin: %v", attrs["is_admin"])
 	}
 }
+
+func TestMeHandlerAddsExtraAttributes(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &User{ID: "u1", Name: "Ada"})
+
+	extra := func(_ context.Context, user *User) (map[string]interface{}, error) {
+		return map[string]interface{}{"no_shows": len(user.ID)}, nil
+	}
+	if err := MeHandler(extra)(c); err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+
+	var resp api.SingleResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	attrs, ok := resp.Data.Attributes.(map[string]interface{})
+	if !ok {
+		t.Fatalf("unexpected attributes type: %T", resp.Data.Attributes)
+	}
+	if attrs["no_shows"] != float64(2) || attrs["display_name"] != "Ada" {
+		t.Fatalf("unexpected attributes: %#v", attrs)
+	}
+}
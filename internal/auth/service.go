@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 
 	"github.com/gorilla/securecookie"
@@ -35,8 +36,13 @@ type Service struct {
 	adminsGroup        string
 	usersGroup         string
 	forceSecureCookies bool
+	groupSync          GroupSyncFunc
 }
 
+// GroupSyncFunc is called on Entra ID login with the user's database ID and
+// the IDs of the groups the user belongs to.
+type GroupSyncFunc func(ctx context.Context, userID string, groupIDs []string) error
+
 // User represents an authenticated user.
 type User struct {
 	ID          string `json:"id"`
@@ -63,7 +69,7 @@ func NewService(cfg *config.Config, store *sql.DB) (*Service, error) {
 
 	if cfg.EntraIDConfigured() {
 		scopes := []string{"openid", "profile", "email", "User.Read"}
-		if cfg.EntraID.AdminsGroupID != "" || cfg.EntraID.UsersGroupID != "" {
+		if cfg.EntraID.AdminsGroupID != "" || cfg.EntraID.UsersGroupID != "" || cfg.EntraID.SyncTeamGroups {
 			scopes = append(scopes, "GroupMember.Read.All")
 		}
 		oauthConfig = &oauth2.Config{
@@ -94,6 +100,12 @@ func NewService(cfg *config.Config, store *sql.DB) (*Service, error) {
 	}, nil
 }
 
+// SetGroupSync registers fn to receive the user's groups on each Entra ID
+// login. It must be called before the service handles requests.
+func (s *Service) SetGroupSync(fn GroupSyncFunc) {
+	s.groupSync = fn
+}
+
 // Store returns the database handle.
 func (s *Service) Store() *sql.DB {
 	return s.store
@@ -190,21 +202,33 @@ func (s *Service) FetchUser(ctx context.Context, token *oauth2.Token) (*User, er
 	isPermitted := s.usersGroup == ""
 	isAdmin := false
 
-	if s.adminsGroup != "" || s.usersGroup != "" {
-		groupIDs, err := s.fetchGroupIDs(ctx, client)
+	var groupIDs []string
+	groupsFetched := false
+	if s.adminsGroup != "" || s.usersGroup != "" || s.groupSync != nil {
+		ids, err := s.fetchGroupIDs(ctx, client)
 		if err == nil {
-			if s.usersGroup != "" {
-				isPermitted = isGroupMember(groupIDs, s.usersGroup)
-			}
-			isAdmin = s.isAdminGroupMember(groupIDs)
+			groupIDs, groupsFetched = ids, true
+		} else if s.groupSync != nil {
+			slog.Warn("fetch groups for team sync", "error", err)
 		}
 	}
+	if groupsFetched && (s.adminsGroup != "" || s.usersGroup != "") {
+		if s.usersGroup != "" {
+			isPermitted = isGroupMember(groupIDs, s.usersGroup)
+		}
+		isAdmin = s.isAdminGroupMember(groupIDs)
+	}
 
 	// Upsert into local DB
 	rec, err := users.UpsertEntraIDUser(ctx, s.store, graph.ID, email, graph.DisplayName, isAdmin)
 	if err != nil {
 		return nil, fmt.Errorf("upsert entra user: %w", err)
 	}
+	if groupsFetched && s.groupSync != nil {
+		if err := s.groupSync(ctx, rec.ID, groupIDs); err != nil {
+			slog.Warn("sync team groups", "user_id", rec.ID, "error", err)
+		}
+	}
 
 	user := &User{
 		ID:          rec.ID,
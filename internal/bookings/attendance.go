@@ -0,0 +1,199 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+)
+
+// Attendance event kinds recorded against a user.
+const (
+	AttendanceLateCancellation = "late_cancellation"
+	AttendanceNoShow           = "no_show"
+)
+
+// noShowLookbackDays bounds how far back no-show detection looks, so that
+// turning on require_check_in does not count bookings from long ago.
+const noShowLookbackDays = 7
+
+// AttendanceStats counts the late cancellations and no-shows of a user.
+type AttendanceStats struct {
+	UserID            string
+	LateCancellations int
+	NoShows           int
+}
+
+// RecordAttendance records a late cancellation or no-show of the booking against
+// userID. Recording the same kind for a booking twice has no effect.
+func RecordAttendance(ctx context.Context, store *sql.DB, userID string, rec *BookingRecord, kind string) error {
+	_, err := store.ExecContext(ctx,
+		`INSERT INTO booking_attendance (booking_id, kind, user_id, item_id, booking_date, recorded_at)
+		 VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (booking_id, kind) DO NOTHING`,
+		rec.ID, kind, userID, rec.ItemID, rec.BookingDate, time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("record %s: %w", kind, err)
+	}
+	return nil
+}
+
+// CheckInBooking marks a booking as checked in and increments its version.
+// Checking in twice keeps the first check-in time.
+func CheckInBooking(ctx context.Context, store *sql.DB, bookingID string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.ExecContext(ctx,
+		`UPDATE bookings SET checked_in_at = ?, updated_at = ?, version = version + 1
+		 WHERE id = ? AND checked_in_at IS NULL`,
+		now, now, bookingID,
+	)
+	if err != nil {
+		return fmt.Errorf("check in booking: %w", err)
+	}
+	return nil
+}
+
+// FindAttendanceStats counts the user's late cancellations and no-shows for
+// bookings on or after fromDate.
+func FindAttendanceStats(ctx context.Context, store *sql.DB, userID, fromDate string) (AttendanceStats, error) {
+	stats := AttendanceStats{UserID: userID}
+	err := store.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(kind = ?), 0), COALESCE(SUM(kind = ?), 0)
+		 FROM booking_attendance WHERE user_id = ? AND booking_date >= ?`,
+		AttendanceLateCancellation, AttendanceNoShow, userID, fromDate,
+	).Scan(&stats.LateCancellations, &stats.NoShows)
+	if err != nil {
+		return stats, fmt.Errorf("query attendance stats: %w", err)
+	}
+	return stats, nil
+}
+
+// ListAttendanceStats returns the stats of every user with late cancellations or
+// no-shows for bookings between fromDate and toDate (inclusive), most no-shows first.
+func ListAttendanceStats(
+	ctx context.Context, store *sql.DB, fromDate, toDate string,
+) (result []AttendanceStats, err error) {
+	rows, err := store.QueryContext(ctx,
+		`SELECT user_id, SUM(kind = ?) AS late, SUM(kind = ?) AS no_shows
+		 FROM booking_attendance WHERE booking_date >= ? AND booking_date <= ?
+		 GROUP BY user_id ORDER BY no_shows DESC, late DESC, user_id`,
+		AttendanceLateCancellation, AttendanceNoShow, fromDate, toDate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query attendance report: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close attendance report rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var s AttendanceStats
+		if err := rows.Scan(&s.UserID, &s.LateCancellations, &s.NoShows); err != nil {
+			return nil, fmt.Errorf("scan attendance stats: %w", err)
+		}
+		result = append(result, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate attendance report: %w", err)
+	}
+	return result, nil
+}
+
+// RecordNoShows records a no-show for every user booking of the last days in an
+// area requiring check-in that was not checked in before the booked day ended
+// in the area's time zone. Guest bookings and bookings still waiting for
+// confirmation are skipped. Returns the number of no-shows recorded.
+func RecordNoShows(ctx context.Context, store *sql.DB, cfg *areas.Config, now time.Time) (int64, error) {
+	var total int64
+	recordedAt := now.UTC().Format(time.RFC3339)
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		itemIDs := collectAreaItemIDs(area)
+		if !cfg.CancellationFor(area).RequireCheckIn || len(itemIDs) == 0 {
+			continue
+		}
+		today := now.In(cfg.AreaZone(area))
+		from := areas.CalendarDay(today).AddDate(0, 0, -noShowLookbackDays).Format(time.DateOnly)
+
+		inClause, inArgs := api.BuildINClause(itemIDs)
+		//nolint:gosec // G202: "?" placeholders from BuildINClause
+		query := `INSERT INTO booking_attendance (booking_id, kind, user_id, item_id, booking_date, recorded_at)
+			SELECT id, ?, user_id, item_id, booking_date, ? FROM bookings
+			WHERE item_id IN (` + inClause + `) AND booking_date >= ? AND booking_date < ?
+			  AND checked_in_at IS NULL AND is_guest = 0 AND pending_confirmation = 0
+			ON CONFLICT (booking_id, kind) DO NOTHING`
+		args := append([]any{AttendanceNoShow, recordedAt}, inArgs...)
+		args = append(args, from, today.Format(time.DateOnly))
+		result, err := store.ExecContext(ctx, query, args...)
+		if err != nil {
+			return total, fmt.Errorf("record no-shows in area %q: %w", area.ID, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("count no-shows: %w", err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// RunNoShowDetection records no-shows every interval until ctx is done.
+func RunNoShowDetection(ctx context.Context, store *sql.DB, getConfig areas.ConfigGetter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := RecordNoShows(ctx, store, getConfig(), time.Now())
+		switch {
+		case err != nil:
+			slog.Error("record no-shows", "error", err)
+		case n > 0:
+			slog.Info("no-shows recorded", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// attendanceLookup loads a user's attendance stats for policy conditions, once
+// per window length.
+type attendanceLookup struct {
+	store   *sql.DB
+	userID  string
+	isGuest bool
+	today   time.Time
+	stats   map[int]AttendanceStats
+}
+
+// matches reports whether the user meets the rule's attendance conditions.
+// Rules without conditions always match; guest bookings never meet conditions.
+func (a *attendanceLookup) matches(ctx context.Context, rule *PolicyRule) (bool, error) {
+	if !rule.HasAttendanceCondition() {
+		return true, nil
+	}
+	if a.isGuest {
+		return false, nil
+	}
+	window := rule.AttendanceWindow()
+	stats, ok := a.stats[window]
+	if !ok {
+		from := a.today.AddDate(0, 0, -window).Format(time.DateOnly)
+		var err error
+		if stats, err = FindAttendanceStats(ctx, a.store, a.userID, from); err != nil {
+			return false, err
+		}
+		if a.stats == nil {
+			a.stats = make(map[int]AttendanceStats)
+		}
+		a.stats[window] = stats
+	}
+	return rule.MatchesAttendance(stats.NoShows, stats.LateCancellations), nil
+}
@@ -0,0 +1,152 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+// attendanceStatsWindowDays is the period the profile and report stats cover by default.
+const attendanceStatsWindowDays = 90
+
+// ErrorCodeCheckInClosed is the JSON:API error code used when a booking can only
+// be checked in on its booked day.
+const ErrorCodeCheckInClosed = "check_in_closed"
+
+// AttendanceStatsAttributes represents a user's attendance stats in the admin report.
+type AttendanceStatsAttributes struct {
+	UserID            string `json:"user_id"`
+	UserName          string `json:"user_name"`
+	LateCancellations int    `json:"late_cancellations"`
+	NoShows           int    `json:"no_shows"`
+}
+
+// CheckInHandler returns a handler for checking in to a booking on the booked
+// day (area-local). Only the booked user and admins can check in; guest
+// bookings cannot be checked in.
+// POST /api/v1/bookings/:id/check-in
+func CheckInHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+
+		ctx := c.Request().Context()
+		bookingID := c.Param("id")
+		booking, err := FindBookingByID(ctx, store, bookingID)
+		if err != nil {
+			return fmt.Errorf("find booking: %w", err)
+		}
+		if booking == nil || (booking.UserID != user.ID && !user.IsAdmin) {
+			return api.WriteNotFound(c, "Booking not found")
+		}
+		if booking.IsGuest {
+			return api.WriteBadRequest(c, "Guest bookings cannot be checked in")
+		}
+		cfg := getConfig()
+		today := areas.LocalDate(time.Now(), cfg.ItemZone(booking.ItemID))
+		if booking.BookingDate != today {
+			return api.WriteError(c, http.StatusConflict,
+				"Bookings can only be checked in on the booked day", ErrorCodeCheckInClosed)
+		}
+
+		if err := CheckInBooking(ctx, store, bookingID); err != nil {
+			return err
+		}
+		slog.Info("booking checked in", "booking_id", bookingID, "checked_in_by", user.ID)
+
+		updated, err := FindBookingByID(ctx, store, bookingID)
+		if err != nil {
+			return fmt.Errorf("reload booking: %w", err)
+		}
+		if updated == nil {
+			return api.WriteNotFound(c, "Booking not found")
+		}
+		return writePatchResponse(c, updated, cfg.VisibleFieldValues(
+			updated.ItemID, updated.Fields, FieldAccess(user, updated.UserID, updated.BookedByUserID)))
+	}
+}
+
+// AttendanceReportHandler returns the late cancellations and no-shows per user
+// for bookings in a date range, most no-shows first. Defaults to the last 90 days,
+// counted from today in the default time zone.
+// GET /api/v1/admin/reports/attendance?from=<date>&to=<date>
+func AttendanceReportHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		today := areas.CalendarDay(time.Now().In(getConfig().DefaultZone()))
+		fromDate := c.QueryParam("from")
+		toDate := c.QueryParam("to")
+		if fromDate == "" {
+			fromDate = today.AddDate(0, 0, -attendanceStatsWindowDays).Format(time.DateOnly)
+		}
+		if toDate == "" {
+			toDate = today.Format(time.DateOnly)
+		}
+		if _, err := time.Parse(time.DateOnly, fromDate); err != nil {
+			return api.WriteBadRequest(c, "Invalid 'from' date. Use YYYY-MM-DD format.")
+		}
+		if _, err := time.Parse(time.DateOnly, toDate); err != nil {
+			return api.WriteBadRequest(c, "Invalid 'to' date. Use YYYY-MM-DD format.")
+		}
+
+		ctx := c.Request().Context()
+		stats, err := ListAttendanceStats(ctx, store, fromDate, toDate)
+		if err != nil {
+			return api.WriteInternalError(c, "list attendance stats", err)
+		}
+		userIDs := make([]string, 0, len(stats))
+		for i := range stats {
+			userIDs = append(userIDs, stats[i].UserID)
+		}
+		names, err := users.FindDisplayNames(ctx, store, userIDs)
+		if err != nil {
+			return api.WriteInternalError(c, "find user names", err)
+		}
+
+		resources := make([]api.Resource, 0, len(stats))
+		for i := range stats {
+			s := &stats[i]
+			resources = append(resources, api.Resource{
+				Type: "attendance-stats",
+				ID:   s.UserID,
+				Attributes: AttendanceStatsAttributes{
+					UserID:            s.UserID,
+					UserName:          names[s.UserID],
+					LateCancellations: s.LateCancellations,
+					NoShows:           s.NoShows,
+				},
+			})
+		}
+		return api.WriteCollection(c, resources, "write attendance report")
+	}
+}
+
+// AttendanceMeAttributes adds the user's late cancellations and no-shows of the
+// last 90 days, counted from today in the default time zone, to the
+// /api/v1/me resource.
+func AttendanceMeAttributes(getConfig areas.ConfigGetter, store *sql.DB) auth.MeAttributes {
+	return func(ctx context.Context, user *auth.User) (map[string]interface{}, error) {
+		today := areas.CalendarDay(time.Now().In(getConfig().DefaultZone()))
+		from := today.AddDate(0, 0, -attendanceStatsWindowDays).Format(time.DateOnly)
+		stats, err := FindAttendanceStats(ctx, store, user.ID, from)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"late_cancellations":     stats.LateCancellations,
+			"no_shows":               stats.NoShows,
+			"attendance_window_days": attendanceStatsWindowDays,
+		}, nil
+	}
+}
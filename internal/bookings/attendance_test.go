@@ -0,0 +1,213 @@
+package bookings
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+func bookingRequest(
+	t *testing.T, h echo.HandlerFunc, method, bookingID string, user *auth.User,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1/bookings/"+bookingID, http.NoBody)
+	req.Header.Set("If-Match", "*")
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(bookingID)
+	c.Set("user", user)
+	require.NoError(t, h(c))
+	return rec
+}
+
+func TestDeleteHandlerCancellationRules(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	today := now.Format(time.DateOnly)
+	yesterday := now.AddDate(0, 0, -1).Format(time.DateOnly)
+	tomorrow := now.AddDate(0, 0, 1).Format(time.DateOnly)
+	user := &auth.User{ID: "user-1", Name: "Test User"}
+	admin := &auth.User{ID: "admin", Name: "Admin", IsAdmin: true}
+
+	t.Run("past bookings only by admins", func(t *testing.T) {
+		t.Parallel()
+		store := setupTestStore(t)
+		seedTestBooking(t, store, "past", "desk-1", "user-1", yesterday)
+		h := DeleteHandler(testAreasConfig(), store, testNotifier())
+
+		rec := bookingRequest(t, h, http.MethodDelete, "past", user)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrorCodeCancellationClosed)
+
+		rec = bookingRequest(t, h, http.MethodDelete, "past", admin)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		stats, err := FindAttendanceStats(t.Context(), store, "user-1", yesterday)
+		require.NoError(t, err)
+		assert.Zero(t, stats.LateCancellations, "admin cancellations are not held against the user")
+	})
+
+	t.Run("cut-off on the booked day", func(t *testing.T) {
+		t.Parallel()
+		store := setupTestStore(t)
+		seedTestBooking(t, store, "today", "desk-1", "user-1", today)
+		cfg := testAreasConfig()
+		cfg.Cancellation = &areas.Cancellation{CutoffTime: "00:00"}
+
+		rec := bookingRequest(t, DeleteHandler(cfg, store, testNotifier()), http.MethodDelete, "today", user)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), "can only be canceled until 00:00")
+	})
+
+	t.Run("late cancellations are recorded", func(t *testing.T) {
+		t.Parallel()
+		store := setupTestStore(t)
+		seedTestBooking(t, store, "today", "desk-1", "user-1", today)
+		seedTestBooking(t, store, "tomorrow", "desk-1", "user-1", tomorrow)
+		seedTestBooking(t, store, "tomorrow-2", "desk-2", "user-1", tomorrow)
+		h := DeleteHandler(testAreasConfig(), store, testNotifier())
+
+		// Without rules only same-day cancellations are late.
+		assert.Equal(t, http.StatusNoContent, bookingRequest(t, h, http.MethodDelete, "today", user).Code)
+		assert.Equal(t, http.StatusNoContent, bookingRequest(t, h, http.MethodDelete, "tomorrow", user).Code)
+		stats, err := FindAttendanceStats(t.Context(), store, "user-1", today)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.LateCancellations)
+
+		cfg := testAreasConfig()
+		cfg.Areas[0].Cancellation = &areas.Cancellation{LateNoticeHours: 48}
+		h = DeleteHandler(cfg, store, testNotifier())
+		assert.Equal(t, http.StatusNoContent, bookingRequest(t, h, http.MethodDelete, "tomorrow-2", user).Code)
+		stats, err = FindAttendanceStats(t.Context(), store, "user-1", today)
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.LateCancellations)
+	})
+}
+
+func TestCheckInHandler(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	now := time.Now().UTC()
+	seedTestBooking(t, store, "today", "desk-1", "user-1", now.Format(time.DateOnly))
+	seedTestBooking(t, store, "tomorrow", "desk-2", "user-1", now.AddDate(0, 0, 1).Format(time.DateOnly))
+	cfg := testAreasConfig()
+	h := CheckInHandler(func() *areas.Config { return cfg }, store)
+	user := &auth.User{ID: "user-1", Name: "Test User"}
+
+	rec := bookingRequest(t, h, http.MethodPost, "today", user)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs, ok := resp.Data.Attributes.(map[string]interface{})
+	require.True(t, ok)
+	assert.NotEmpty(t, attrs["checked_in_at"])
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	rec = bookingRequest(t, h, http.MethodPost, "tomorrow", user)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrorCodeCheckInClosed)
+
+	rec = bookingRequest(t, h, http.MethodPost, "today", &auth.User{ID: "other-user", Name: "Other"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRecordNoShows(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	now := time.Now().UTC()
+	yesterday := now.AddDate(0, 0, -1).Format(time.DateOnly)
+	seedTestBooking(t, store, "missed", "desk-1", "user-1", yesterday)
+	seedTestBooking(t, store, "attended", "desk-2", "user-2", yesterday)
+	seedTestBooking(t, store, "upcoming", "desk-1", "user-1", now.Format(time.DateOnly))
+	twoDaysAgo := now.AddDate(0, 0, -2).Format(time.DateOnly)
+	seedTestBookingWithGuest(t, store, "guest", "desk-2", "user-1", "user-1", twoDaysAgo, true, "Guest", "")
+	_, err := store.Exec(`UPDATE bookings SET checked_in_at = ? WHERE id = 'attended'`, now.Format(time.RFC3339))
+	require.NoError(t, err)
+
+	cfg := testAreasConfig()
+	n, err := RecordNoShows(t.Context(), store, cfg, now)
+	require.NoError(t, err)
+	assert.Zero(t, n, "no-shows are only tracked where check-in is required")
+
+	cfg.Cancellation = &areas.Cancellation{RequireCheckIn: true}
+	n, err = RecordNoShows(t.Context(), store, cfg, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = RecordNoShows(t.Context(), store, cfg, now)
+	require.NoError(t, err)
+	assert.Zero(t, n, "no-shows are recorded once")
+
+	stats, err := ListAttendanceStats(t.Context(), store, yesterday, yesterday)
+	require.NoError(t, err)
+	assert.Equal(t, []AttendanceStats{{UserID: "user-1", NoShows: 1}}, stats)
+}
+
+func TestPolicyLowersLimitForNoShows(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	cfg := testAreasConfig()
+	cfg.Policies = []areas.Policy{{Name: "No-show penalty", MinNoShows: 1, MaxActiveBookings: 1}}
+	now := time.Now().UTC()
+	yesterday := now.AddDate(0, 0, -1).Format(time.DateOnly)
+	tomorrow := now.AddDate(0, 0, 1).Format(time.DateOnly)
+	dayAfter := now.AddDate(0, 0, 2).Format(time.DateOnly)
+
+	missed := &BookingRecord{ID: "missed", ItemID: "desk-1", BookingDate: yesterday}
+	require.NoError(t, RecordAttendance(t.Context(), store, "user-1", missed, AttendanceNoShow))
+	seedTestBooking(t, store, "b1", "desk-1", "user-1", tomorrow)
+	seedTestBooking(t, store, "b2", "desk-2", "user-2", tomorrow)
+
+	h := CreateHandler(cfg, store, testNotifier())
+	create := func(userID string) *httptest.ResponseRecorder {
+		body := `{"data":{"type":"bookings","attributes":{"item_id":"desk-2","booking_date":"` + dayAfter + `"}}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Set("user", &auth.User{ID: userID, Name: userID})
+		require.NoError(t, h(c))
+		return rec
+	}
+
+	rec := create("user-1")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "No-show penalty")
+	assert.Equal(t, http.StatusCreated, create("user-2").Code)
+}
+
+func TestAttendanceReportDefaultsToLocalToday(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	cfg := testAreasConfig()
+	cfg.Timezone = "Pacific/Kiritimati"
+	today := areas.LocalDate(time.Now(), cfg.DefaultZone())
+	missed := &BookingRecord{ID: "missed", ItemID: "desk-1", BookingDate: today}
+	require.NoError(t, RecordAttendance(t.Context(), store, "user-1", missed, AttendanceNoShow))
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", http.NoBody), rec)
+	require.NoError(t, AttendanceReportHandler(func() *areas.Config { return cfg }, store)(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1, "the report ends on today in the default time zone")
+	assert.Equal(t, "user-1", resp.Data[0].ID)
+}
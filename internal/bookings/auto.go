@@ -0,0 +1,600 @@
+package bookings
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/delegations"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const (
+	resourceTypeAutoBooking    = "auto-bookings"
+	resourceTypeItemSuggestion = "item-suggestions"
+
+	defaultSuggestionLimit = 5
+	maxSuggestionLimit     = 20
+	maxAutoRangeDays       = 31
+	historyWindowDays      = 90
+)
+
+// Ranking weights for auto-assignment. History and proximity are scaled so that
+// an explicit preference always outweighs habit.
+const (
+	scoreSameAsLastTime     = 50
+	scorePreferredItemGroup = 30
+	scorePreferredArea      = 20
+	scoreNearColleague      = 25
+	scorePreferredEquipment = 10
+	scorePerPastBooking     = 2
+	maxHistoryScore         = 20
+	// proximityScale is the floor plan distance at which the colleague bonus halves.
+	proximityScale = 100.0
+)
+
+// ErrorCodeNoItemAvailable is the JSON:API error code used when auto-assignment
+// finds no bookable item.
+const ErrorCodeNoItemAvailable = "no_item_available"
+
+// AutoBookingRequest represents an auto-assignment JSON:API payload. Dates come
+// from booking_date, booking_dates, or the weekdays between from_date and to_date.
+type AutoBookingRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			BookingDate        string   `json:"booking_date"`
+			BookingDates       []string `json:"booking_dates"`
+			FromDate           string   `json:"from_date"`
+			ToDate             string   `json:"to_date"`
+			Equipment          []string `json:"equipment"`
+			PreferredEquipment []string `json:"preferred_equipment"`
+			AreaID             string   `json:"area_id"`
+			ItemGroupID        string   `json:"item_group_id"`
+			NearUserID         string   `json:"near_user_id"`
+			SameAsLastTime     bool     `json:"same_as_last_time"`
+			SuggestOnly        bool     `json:"suggest_only"`
+			Limit              int      `json:"limit"`
+			Note               string   `json:"note"`
+			// Fields holds the custom field values of the booked item.
+			Fields map[string]any `json:"fields"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// SuggestionAttributes represents item suggestion resource attributes.
+type SuggestionAttributes struct {
+	ItemID        string   `json:"item_id"`
+	ItemName      string   `json:"item_name"`
+	ItemGroupID   string   `json:"item_group_id"`
+	ItemGroupName string   `json:"item_group_name"`
+	AreaID        string   `json:"area_id"`
+	AreaName      string   `json:"area_name"`
+	Score         int      `json:"score"`
+	Reasons       []string `json:"reasons"`
+}
+
+// AutoBookingAttributes represents the result of an auto-assigned booking.
+type AutoBookingAttributes struct {
+	SuggestionAttributes
+	BookingDates []string `json:"booking_dates"`
+	BookingIDs   []string `json:"booking_ids"`
+}
+
+type autoPreferences struct {
+	equipment          []string
+	preferredEquipment []string
+	areaID             string
+	itemGroupID        string
+	nearUserID         string
+	sameAsLastTime     bool
+}
+
+// suggestion is a scored candidate item.
+type suggestion struct {
+	loc     *areas.ItemLocation
+	score   int
+	reasons []string
+}
+
+// AutoHandlerDynamic returns a handler that picks the best free item for the
+// current user and books it on all requested dates, or only returns the top
+// suggestions when suggest_only is set.
+// POST /api/v1/bookings/auto
+func AutoHandlerDynamic(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+	limits *BookingLimits, guards ...Guard,
+) echo.HandlerFunc {
+	maxWeeks := 0
+	if limits != nil {
+		maxWeeks = limits.WeeksInAdvanced
+	}
+
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		if err := validateContentType(c); err != nil {
+			if errors.Is(err, errResponseWritten) {
+				return nil
+			}
+			return err
+		}
+
+		cfg := getConfig()
+		req, dates, err := parseAutoRequest(c, cfg, maxWeeks)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		a := req.Data.Attributes
+		prefs := &autoPreferences{
+			equipment:          a.Equipment,
+			preferredEquipment: a.PreferredEquipment,
+			areaID:             strings.TrimSpace(a.AreaID),
+			itemGroupID:        strings.TrimSpace(a.ItemGroupID),
+			nearUserID:         strings.TrimSpace(a.NearUserID),
+			sameAsLastTime:     a.SameAsLastTime,
+		}
+
+		ctx := c.Request().Context()
+		member, err := autoBookingUser(ctx, store, user.ID)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		ranked, err := rankItems(ctx, store, cfg, user, member, prefs, dates, guards)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+
+		limit := 1
+		if a.SuggestOnly {
+			limit = cmp.Or(a.Limit, defaultSuggestionLimit)
+		}
+		allowed, violation, err := allowedSuggestions(ctx, store, cfg, limits, member, ranked, dates, limit)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		if a.SuggestOnly {
+			return writeSuggestions(c, allowed)
+		}
+		if len(allowed) == 0 {
+			if violation != nil {
+				return writeTeamError(c, violation)
+			}
+			return api.WriteError(c, http.StatusConflict,
+				"No item matching your preferences is available on all requested dates", ErrorCodeNoItemAvailable)
+		}
+		note := strings.TrimSpace(a.Note)
+		if len(note) > maxNoteLength {
+			return api.WriteBadRequest(c, fmt.Sprintf("Note must be at most %d characters", maxNoteLength))
+		}
+		fields, err := resolveFields(allowed[0].loc, nil, a.Fields, user)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		return bookBestSuggestion(c, store, notifier, member, allowed[0], dates, note, fields,
+			resourceTypeAutoBooking)
+	}
+}
+
+func parseAutoRequest(c echo.Context, cfg *areas.Config, maxWeeks int) (*AutoBookingRequest, []string, error) {
+	var req AutoBookingRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, nil, errBadRequest("Invalid request body")
+	}
+	if req.Data.Type != resourceTypeAutoBooking {
+		return nil, nil, errBadRequest("Resource type must be 'auto-bookings'")
+	}
+	a := req.Data.Attributes
+	if a.Limit < 0 || a.Limit > maxSuggestionLimit {
+		return nil, nil, errBadRequest(fmt.Sprintf(
+			"limit must be between 1 and %d, or 0 for the default of %d", maxSuggestionLimit, defaultSuggestionLimit))
+	}
+
+	allDates := requestedDates(a.BookingDate, a.BookingDates)
+	if a.FromDate != "" || a.ToDate != "" {
+		rangeDates, err := expandWeekdays(strings.TrimSpace(a.FromDate), strings.TrimSpace(a.ToDate))
+		if err != nil {
+			return nil, nil, err
+		}
+		allDates = append(allDates, rangeDates...)
+	}
+	if len(allDates) == 0 {
+		return nil, nil, errBadRequest("booking_date, booking_dates, or from_date and to_date is required")
+	}
+	// The item is not chosen yet; dates past in every area are rejected here,
+	// and items whose area-local today is later are skipped when ranking.
+	earliest, _ := cfg.TodayRange(time.Now())
+	today, _ := time.Parse(time.DateOnly, earliest)
+	dates, err := validateBookingDates(allDates, maxWeeks, today)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(dates)
+	return &req, dates, nil
+}
+
+// expandWeekdays returns the Monday-to-Friday dates between from and to (inclusive).
+func expandWeekdays(from, to string) ([]string, error) {
+	start, errFrom := time.Parse(time.DateOnly, from)
+	end, errTo := time.Parse(time.DateOnly, to)
+	if errFrom != nil || errTo != nil {
+		return nil, errBadRequest("from_date and to_date must be in YYYY-MM-DD format")
+	}
+	if end.Before(start) {
+		return nil, errBadRequest("to_date must not be before from_date")
+	}
+	if end.Sub(start) > maxAutoRangeDays*24*time.Hour {
+		return nil, errBadRequest(fmt.Sprintf("The date range must not exceed %d days", maxAutoRangeDays))
+	}
+
+	var dates []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			dates = append(dates, d.Format(time.DateOnly))
+		}
+	}
+	if len(dates) == 0 {
+		return nil, errBadRequest("The date range contains no weekdays")
+	}
+	return dates, nil
+}
+
+func autoBookingUser(ctx context.Context, store *sql.DB, userID string) (teamMember, error) {
+	rec, err := users.FindByID(ctx, store, userID)
+	if errors.Is(err, users.ErrUserNotFound) {
+		return teamMember{id: userID}, nil
+	}
+	if err != nil {
+		return teamMember{}, fmt.Errorf("find user: %w", err)
+	}
+	return teamMember{id: rec.ID, name: rec.DisplayName, email: strings.TrimSpace(rec.Email)}, nil
+}
+
+// rankItems returns every item the user could book on all dates, best first.
+// Items without the required equipment, booked items, reserved items, and items
+// refused by a guard are left out. viewer is the requesting user; only the
+// colleague's bookings they may see count for near_user_id.
+func rankItems(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, viewer *auth.User, user teamMember,
+	prefs *autoPreferences, dates []string, guards []Guard,
+) ([]suggestion, error) {
+	scorer, err := newAutoScorer(ctx, store, cfg, viewer, user, prefs, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	free, err := NewFreeItems(ctx, store, cfg, guards, user.id, user.id, user.email, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranked []suggestion
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		for j := range area.ItemGroups {
+			ig := &area.ItemGroups[j]
+			for k := range ig.Items {
+				loc := &areas.ItemLocation{Area: area, ItemGroup: ig, Item: &ig.Items[k]}
+				if !hasEquipment(loc.Item, prefs.equipment) {
+					continue
+				}
+				ok, err := free.Bookable(ctx, loc)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					ranked = append(ranked, scorer.score(ctx, loc))
+				}
+			}
+		}
+	}
+
+	// Stable sort keeps configuration order among equal scores.
+	sort.SliceStable(ranked, func(a, b int) bool { return ranked[a].score > ranked[b].score })
+	return ranked, nil
+}
+
+// allowedSuggestions returns up to limit of the ranked suggestions whose items
+// the user's booking policies allow on dates, best first. Policies scoped to
+// an area, item group, or item may refuse the best item but allow a lower
+// ranked one. violation is the first policy violation, for when none is
+// allowed.
+func allowedSuggestions(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, limits *BookingLimits, user teamMember,
+	ranked []suggestion, dates []string, limit int,
+) (allowed []suggestion, violation, err error) {
+	for i := range ranked {
+		if len(allowed) == limit {
+			break
+		}
+		err := CheckPolicies(ctx, store, cfg, limits, ranked[i].loc, user.id, user.email, dates)
+		var rej *Rejection
+		switch {
+		case err == nil:
+			allowed = append(allowed, ranked[i])
+		case errors.As(err, &rej) || errors.Is(err, ErrBookingLimitExceeded):
+			if violation == nil {
+				violation = err
+			}
+		default:
+			return nil, nil, err
+		}
+	}
+	return allowed, violation, nil
+}
+
+// findBookedOnAnyDate returns the IDs of items booked on at least one of dates.
+func findBookedOnAnyDate(ctx context.Context, store *sql.DB, dates []string) (map[string]struct{}, error) {
+	booked := make(map[string]struct{})
+	for _, date := range dates {
+		ids, err := FindBookedItemIDs(ctx, store, date)
+		if err != nil {
+			return nil, fmt.Errorf("find booked items: %w", err)
+		}
+		for id := range ids {
+			booked[id] = struct{}{}
+		}
+	}
+	return booked, nil
+}
+
+// hasEquipment reports whether every wanted entry matches one of the item's
+// equipment entries (case-insensitive substring).
+func hasEquipment(item *areas.Item, wanted []string) bool {
+	for _, w := range wanted {
+		if equipmentMatch(item, w) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// equipmentMatch returns the item's equipment entry that contains wanted, or "".
+func equipmentMatch(item *areas.Item, wanted string) string {
+	needle := strings.ToLower(strings.TrimSpace(wanted))
+	if needle == "" {
+		return ""
+	}
+	for _, e := range item.Equipment {
+		if strings.Contains(strings.ToLower(e), needle) {
+			return e
+		}
+	}
+	return ""
+}
+
+// autoScorer holds the data the ranking needs, loaded once per request.
+type autoScorer struct {
+	prefs       *autoPreferences
+	history     map[string]int
+	lastItemID  string
+	nearName    string
+	nearItems   []*areas.ItemLocation
+	centerCache map[string]map[string]point
+	store       *sql.DB
+}
+
+func newAutoScorer(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, viewer *auth.User, user teamMember,
+	prefs *autoPreferences, dates []string,
+) (*autoScorer, error) {
+	s := &autoScorer{prefs: prefs, store: store, centerCache: make(map[string]map[string]point)}
+
+	today := areas.CalendarDay(time.Now().In(cfg.DefaultZone()))
+	history, err := CountUserItemBookings(ctx, store, user.id,
+		today.AddDate(0, 0, -historyWindowDays).Format(time.DateOnly), today.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	s.history = history
+
+	if prefs.sameAsLastTime {
+		if s.lastItemID, err = FindLastBookedItemID(ctx, store, user.id, dates[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	if prefs.nearUserID != "" {
+		if err := s.loadColleague(ctx, cfg, viewer, prefs.nearUserID, dates); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// loadColleague finds the items the colleague has booked on the requested dates.
+// Private bookings the viewer may not see are left out, so that the ranking
+// does not reveal where the colleague sits.
+func (s *autoScorer) loadColleague(
+	ctx context.Context, cfg *areas.Config, viewer *auth.User, colleagueID string, dates []string,
+) error {
+	rec, err := users.FindByID(ctx, s.store, colleagueID)
+	if errors.Is(err, users.ErrUserNotFound) {
+		return errBadRequest("near_user_id: user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("find colleague: %w", err)
+	}
+	s.nearName = rec.DisplayName
+
+	vis, err := delegations.LoadBookerVisibility(ctx, s.store, viewer)
+	if err != nil {
+		return err
+	}
+	records, err := ListBookingsInRange(ctx, s.store, nil, dates[0], dates[len(dates)-1])
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for i := range records {
+		r := &records[i]
+		if r.UserID != colleagueID || seen[r.ItemID] || !vis.Sees(r.UserID, r.BookedByUserID, r.IsPrivate) {
+			continue
+		}
+		if loc, ok := cfg.FindItemLocation(r.ItemID); ok {
+			seen[r.ItemID] = true
+			s.nearItems = append(s.nearItems, loc)
+		}
+	}
+	return nil
+}
+
+func (s *autoScorer) score(ctx context.Context, loc *areas.ItemLocation) suggestion {
+	sg := suggestion{loc: loc}
+	add := func(points int, reason string) {
+		sg.score += points
+		sg.reasons = append(sg.reasons, reason)
+	}
+
+	if s.lastItemID == loc.Item.ID {
+		add(scoreSameAsLastTime, "Same item as last time")
+	}
+	switch {
+	case s.prefs.itemGroupID != "" && s.prefs.itemGroupID == loc.ItemGroup.ID:
+		add(scorePreferredItemGroup, fmt.Sprintf("In preferred item group %q", loc.ItemGroup.Name))
+	case s.prefs.areaID != "" && s.prefs.areaID == loc.Area.ID:
+		add(scorePreferredArea, fmt.Sprintf("In preferred area %q", loc.Area.Name))
+	}
+	if points := s.proximityScore(ctx, loc); points > 0 {
+		add(points, fmt.Sprintf("Near %s", s.nearName))
+	}
+	for _, wanted := range s.prefs.preferredEquipment {
+		if match := equipmentMatch(loc.Item, wanted); match != "" {
+			add(scorePreferredEquipment, "Has "+match)
+		}
+	}
+	if n := s.history[loc.Item.ID]; n > 0 {
+		add(min(n*scorePerPastBooking, maxHistoryScore),
+			fmt.Sprintf("Booked %d times in the last %d days", n, historyWindowDays))
+	}
+	if sg.reasons == nil {
+		sg.reasons = []string{}
+	}
+	return sg
+}
+
+// proximityScore rewards items close to the colleague's booked items: the full
+// bonus for the same item group, scaled by floor plan distance when both items
+// are drawn on the same floor plan.
+func (s *autoScorer) proximityScore(ctx context.Context, loc *areas.ItemLocation) int {
+	best := 0
+	for _, near := range s.nearItems {
+		if near.Item.ID == loc.Item.ID || near.Area.ID != loc.Area.ID {
+			continue
+		}
+		points := 0
+		plan := firstNonEmpty(loc.ItemGroup.FloorPlan, loc.Area.FloorPlan)
+		nearPlan := firstNonEmpty(near.ItemGroup.FloorPlan, near.Area.FloorPlan)
+		if plan != "" && plan == nearPlan {
+			if d := itemDistance(loc, near, s.centers(ctx, plan)); d < unplacedDistance {
+				points = int(scoreNearColleague * proximityScale / (proximityScale + d))
+			}
+		}
+		if points == 0 && near.ItemGroup.ID == loc.ItemGroup.ID {
+			points = scoreNearColleague
+		}
+		best = max(best, points)
+	}
+	return best
+}
+
+// centers returns the item centers of a floor plan. Load failures are logged and
+// treated as an undrawn plan, which only weakens the proximity ranking.
+func (s *autoScorer) centers(ctx context.Context, plan string) map[string]point {
+	if c, ok := s.centerCache[plan]; ok {
+		return c
+	}
+	c, err := loadItemCenters(ctx, s.store, plan)
+	if err != nil {
+		slog.Warn("load floor plan positions for ranking", "floor_plan", plan, "error", err)
+		c = map[string]point{}
+	}
+	s.centerCache[plan] = c
+	return c
+}
+
+func suggestionAttributes(sg *suggestion) SuggestionAttributes {
+	return SuggestionAttributes{
+		ItemID:        sg.loc.Item.ID,
+		ItemName:      sg.loc.Item.Name,
+		ItemGroupID:   sg.loc.ItemGroup.ID,
+		ItemGroupName: sg.loc.ItemGroup.Name,
+		AreaID:        sg.loc.Area.ID,
+		AreaName:      sg.loc.Area.Name,
+		Score:         sg.score,
+		Reasons:       sg.reasons,
+	}
+}
+
+func writeSuggestions(c echo.Context, ranked []suggestion) error {
+	resources := make([]api.Resource, len(ranked))
+	for i := range ranked {
+		resources[i] = api.Resource{
+			Type:       resourceTypeItemSuggestion,
+			ID:         ranked[i].loc.Item.ID,
+			Attributes: suggestionAttributes(&ranked[i]),
+		}
+	}
+	return api.WriteCollection(c, resources, "write item suggestions response")
+}
+
+// bookBestSuggestion books the chosen item, which the user's booking policies
+// allow, on all dates in one transaction. The response resource has the given
+// type.
+func bookBestSuggestion(
+	c echo.Context, store *sql.DB, notifier notifications.Notifier,
+	user teamMember, best suggestion, dates []string, note string, fields map[string]any,
+	resourceType string,
+) error {
+	ctx := c.Request().Context()
+	tb := &teamBooking{
+		bookedBy: user.id,
+		members:  []teamMember{user},
+		locs:     []*areas.ItemLocation{best.loc},
+		dates:    dates,
+		note:     note,
+		fields:   []map[string]any{fields},
+	}
+	created, err := createTeamBookings(ctx, store, tb)
+	if err != nil {
+		return writeTeamError(c, err)
+	}
+
+	attrs := AutoBookingAttributes{
+		SuggestionAttributes: suggestionAttributes(&best),
+		BookingDates:         dates,
+		BookingIDs:           make([]string, len(created[0])),
+	}
+	for i, b := range created[0] {
+		attrs.BookingIDs[i] = b.ID
+		NotifyBookingCreated(notifier, b)
+	}
+	slog.Info("auto booking created",
+		"type", resourceType,
+		"user_id", user.id,
+		"item_id", best.loc.Item.ID,
+		"dates", len(dates),
+		"score", best.score,
+	)
+	return api.WriteSingle(c, http.StatusCreated, api.Resource{
+		Type:       resourceType,
+		ID:         attrs.BookingIDs[0],
+		Attributes: attrs,
+	}, "write auto booking response")
+}
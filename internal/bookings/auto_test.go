@@ -0,0 +1,240 @@
+package bookings
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+func autoAreasConfig() *areas.Config {
+	cfg := teamAreasConfig()
+	items := cfg.Areas[0].ItemGroups[0].Items
+	items[1].Equipment = []string{"Dual monitor", "Docking station"}
+	items[4].Equipment = []string{"Standing desk", "Dual monitor"}
+	cfg.Areas[0].ItemGroups = append(cfg.Areas[0].ItemGroups, areas.ItemGroup{
+		ID: "room-2", Name: "Room 2",
+		Items: []areas.Item{{ID: "desk-x", Name: "Desk X", Equipment: []string{"Standing desk"}}},
+	})
+	return cfg
+}
+
+func postAutoBooking(
+	t *testing.T, cfg *areas.Config, store *sql.DB, notifier notifications.Notifier, attributes string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	body := `{"data":{"type":"auto-bookings","attributes":{` + attributes + `}}}`
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings/auto", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "me", Name: "Me"})
+
+	h := AutoHandlerDynamic(func() *areas.Config { return cfg }, store, notifier, &BookingLimits{WeeksInAdvanced: 52})
+	require.NoError(t, h(c))
+	return rec
+}
+
+func decodeSuggestions(t *testing.T, rec *httptest.ResponseRecorder) []SuggestionAttributes {
+	t.Helper()
+	var resp struct {
+		Data []struct {
+			Attributes SuggestionAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	result := make([]SuggestionAttributes, len(resp.Data))
+	for i := range resp.Data {
+		result[i] = resp.Data[i].Attributes
+	}
+	return result
+}
+
+func TestAutoHandlerSuggestsWithRequiredEquipment(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTestUser(t, store, "me", "Me")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-e", "someone", date)
+
+	rec := postAutoBooking(t, autoAreasConfig(), store, testNotifier(),
+		`"booking_date":"`+date+`","equipment":["standing"],"suggest_only":true`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	got := decodeSuggestions(t, rec)
+	require.Len(t, got, 1)
+	assert.Equal(t, "desk-x", got[0].ItemID)
+	assert.Equal(t, "room-2", got[0].ItemGroupID)
+	assert.Equal(t, 1, countBookings(t, store), "suggest_only must not book")
+}
+
+func TestAutoHandlerRanksPreferences(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTestUser(t, store, "me", "Me")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	lastWeek := time.Now().UTC().AddDate(0, 0, -7).Format(time.DateOnly)
+	seedTestBooking(t, store, "old", "desk-c", "me", lastWeek)
+
+	rec := postAutoBooking(t, autoAreasConfig(), store, testNotifier(),
+		`"booking_date":"`+date+`","same_as_last_time":true,"preferred_equipment":["monitor"],`+
+			`"item_group_id":"room-1","suggest_only":true,"limit":3`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	got := decodeSuggestions(t, rec)
+	require.Len(t, got, 3)
+	assert.Equal(t, "desk-c", got[0].ItemID)
+	assert.Equal(t, scoreSameAsLastTime+scorePreferredItemGroup+scorePerPastBooking, got[0].Score)
+	assert.Contains(t, got[0].Reasons, "Same item as last time")
+	// desk-b and desk-e tie on group and monitor; config order wins.
+	assert.Equal(t, "desk-b", got[1].ItemID)
+	assert.Equal(t, "desk-e", got[2].ItemID)
+}
+
+func TestAutoHandlerPrefersItemsNearColleague(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "me", "colleague")
+	seedTeamPositions(t, store)
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-d", "colleague", date)
+
+	rec := postAutoBooking(t, autoAreasConfig(), store, testNotifier(),
+		`"booking_date":"`+date+`","near_user_id":"colleague","suggest_only":true,"limit":2`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	got := decodeSuggestions(t, rec)
+	require.Len(t, got, 2)
+	assert.ElementsMatch(t, []string{"desk-c", "desk-e"}, []string{got[0].ItemID, got[1].ItemID})
+	assert.Contains(t, got[0].Reasons, "Near User colleague")
+}
+
+func TestAutoHandlerIgnoresColleaguesPrivateBookings(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "me", "colleague")
+	seedTeamPositions(t, store)
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-d", "colleague", date)
+	_, err := store.ExecContext(t.Context(), "UPDATE bookings SET is_private = 1 WHERE id = 'b1'")
+	require.NoError(t, err)
+
+	rec := postAutoBooking(t, autoAreasConfig(), store, testNotifier(),
+		`"booking_date":"`+date+`","near_user_id":"colleague","suggest_only":true,"limit":5`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	got := decodeSuggestions(t, rec)
+	require.NotEmpty(t, got)
+	for _, s := range got {
+		assert.NotContains(t, s.Reasons, "Near User colleague", s.ItemID)
+	}
+}
+
+func TestAutoHandlerBooksBestItemForRange(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTestUser(t, store, "me", "Me")
+	monday := time.Now().UTC().AddDate(0, 0, 7)
+	for monday.Weekday() != time.Monday {
+		monday = monday.AddDate(0, 0, 1)
+	}
+	sunday := monday.AddDate(0, 0, 6).Format(time.DateOnly)
+	// desk-x would match but is taken on Wednesday.
+	seedTestBooking(t, store, "b1", "desk-x", "someone", monday.AddDate(0, 0, 2).Format(time.DateOnly))
+	notifier := &recordingNotifier{}
+
+	rec := postAutoBooking(t, autoAreasConfig(), store, notifier,
+		`"from_date":"`+monday.Format(time.DateOnly)+`","to_date":"`+sunday+`","equipment":["standing"]`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Data struct {
+			Type       string                `json:"type"`
+			Attributes AutoBookingAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "auto-bookings", resp.Data.Type)
+	assert.Equal(t, "desk-e", resp.Data.Attributes.ItemID)
+	assert.Len(t, resp.Data.Attributes.BookingDates, 5)
+	assert.Len(t, resp.Data.Attributes.BookingIDs, 5)
+	assert.Equal(t, 6, countBookings(t, store))
+	assert.Len(t, notifier.events, 5)
+}
+
+func TestAutoHandlerSkipsItemsRefusedByPolicy(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTestUser(t, store, "me", "Me")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-a", "me", date)
+	cfg := autoAreasConfig()
+	cfg.Areas[0].ItemGroups[0].Policies = []areas.Policy{{Name: "Quiet zone", MaxPerDay: 1}}
+
+	// Room 1 is preferred but its policy refuses a second booking that day.
+	attrs := `"booking_date":"` + date + `","item_group_id":"room-1"`
+	rec := postAutoBooking(t, cfg, store, testNotifier(), attrs+`,"suggest_only":true`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	got := decodeSuggestions(t, rec)
+	require.Len(t, got, 1)
+	assert.Equal(t, "desk-x", got[0].ItemID)
+
+	rec = postAutoBooking(t, cfg, store, testNotifier(), attrs)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"item_id":"desk-x"`)
+
+	// With every item refused, the violation of the best item is reported.
+	cfg.Areas[0].Policies = []areas.Policy{{Name: "One a day", MaxPerDay: 1}}
+	rec = postAutoBooking(t, cfg, store, testNotifier(), attrs)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Quiet zone")
+}
+
+func TestAutoHandlerNoItemAvailable(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTestUser(t, store, "me", "Me")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	rec := postAutoBooking(t, autoAreasConfig(), store, testNotifier(),
+		`"booking_date":"`+date+`","equipment":["projector"]`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrorCodeNoItemAvailable)
+}
+
+func TestAutoHandlerValidation(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	cases := map[string]string{
+		"no dates":       `"suggest_only":true`,
+		"reversed range": `"from_date":"2030-01-10","to_date":"2030-01-01"`,
+		"bad limit":      `"booking_date":"2030-01-01","limit":50`,
+		"weekend only":   `"from_date":"2030-01-05","to_date":"2030-01-06"`,
+	}
+	for name, attrs := range cases {
+		rec := postAutoBooking(t, autoAreasConfig(), store, testNotifier(), attrs)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+	}
+}
@@ -0,0 +1,510 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+const (
+	resourceTypeBundle = "booking-bundles"
+	maxBundleItems     = 10
+)
+
+// ErrorCodeBundlePartUnavailable is the JSON:API error code used when a bundle
+// default finds no free item.
+const ErrorCodeBundlePartUnavailable = "bundle_part_unavailable"
+
+// BundleRequest represents a booking bundle create JSON:API payload: items
+// from several areas (e.g. a desk and a parking lot) booked for the current
+// user on the same dates.
+type BundleRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			ItemIDs      []string `json:"item_ids"`
+			BookingDate  string   `json:"booking_date"`
+			BookingDates []string `json:"booking_dates"`
+			Note         string   `json:"note"`
+			// Fields holds the custom field values per item ID.
+			Fields map[string]map[string]any `json:"fields"`
+			// ApplyDefaults adds the user's bundle defaults unless set to false.
+			ApplyDefaults *bool `json:"apply_defaults"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// BundlePart is an item of a bundle and its bookings, one per date.
+type BundlePart struct {
+	ItemID        string         `json:"item_id"`
+	ItemName      string         `json:"item_name"`
+	ItemGroupID   string         `json:"item_group_id"`
+	ItemGroupName string         `json:"item_group_name"`
+	AreaID        string         `json:"area_id"`
+	AreaName      string         `json:"area_name"`
+	BookingIDs    []string       `json:"booking_ids"`
+	Fields        map[string]any `json:"fields,omitempty"`
+	// FromDefault is set for items added by one of the user's bundle defaults.
+	FromDefault bool `json:"from_default,omitempty"`
+}
+
+// BundleAttributes represents booking bundle resource attributes.
+type BundleAttributes struct {
+	BookingDates []string     `json:"booking_dates"`
+	Note         string       `json:"note,omitempty"`
+	Parts        []BundlePart `json:"parts"`
+}
+
+// bundle is a validated booking bundle; fromDefault[i] is set for tb.locs[i]
+// added by a bundle default.
+type bundle struct {
+	tb          *teamBooking
+	fromDefault []bool
+}
+
+// BundleHandlerDynamic returns a handler that books several items for the
+// current user on the same dates in one transaction. If any part cannot be
+// booked, nothing is. The user's bundle defaults add items from areas the
+// request does not cover.
+// POST /api/v1/bookings/bundles
+func BundleHandlerDynamic(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+	limits *BookingLimits, guards ...Guard,
+) echo.HandlerFunc {
+	maxWeeks := 0
+	if limits != nil {
+		maxWeeks = limits.WeeksInAdvanced
+	}
+
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		if err := validateContentType(c); err != nil {
+			if errors.Is(err, errResponseWritten) {
+				return nil
+			}
+			return err
+		}
+
+		ctx := c.Request().Context()
+		cfg := getConfig()
+		b, err := prepareBundle(ctx, c, store, cfg, user, maxWeeks, guards)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		member := b.tb.members[0]
+		for _, loc := range b.tb.locs {
+			err := CheckPolicies(ctx, store, cfg, limits, loc, member.id, member.email, b.tb.dates)
+			var rej *Rejection
+			if errors.As(err, &rej) {
+				rej = &Rejection{Status: rej.Status, Code: rej.Code, Detail: loc.Item.Name + ": " + rej.Detail}
+				return writeTeamError(c, rej)
+			}
+			if err != nil {
+				return writeTeamError(c, err)
+			}
+		}
+
+		created, err := createTeamBookings(ctx, store, b.tb)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		for _, part := range created {
+			for _, booking := range part {
+				NotifyBookingCreated(notifier, booking)
+			}
+		}
+		slog.Info("booking bundle created",
+			"bundle_id", b.tb.bundleID,
+			"user_id", user.ID,
+			"items", len(b.tb.locs),
+			"dates", len(b.tb.dates),
+		)
+
+		return api.WriteSingle(c, http.StatusCreated, api.Resource{
+			Type:       resourceTypeBundle,
+			ID:         b.tb.bundleID,
+			Attributes: bundleAttributes(b, created),
+		}, "write booking bundle response")
+	}
+}
+
+// prepareBundle parses the request and resolves the items of the bundle,
+// including those added by the user's bundle defaults.
+func prepareBundle(
+	ctx context.Context, c echo.Context, store *sql.DB, cfg *areas.Config,
+	user *auth.User, maxWeeks int, guards []Guard,
+) (*bundle, error) {
+	var req BundleRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, errBadRequest("Invalid request body")
+	}
+	if req.Data.Type != resourceTypeBundle {
+		return nil, errBadRequest("Resource type must be 'booking-bundles'")
+	}
+	a := req.Data.Attributes
+
+	note := strings.TrimSpace(a.Note)
+	if len(note) > maxNoteLength {
+		return nil, errBadRequest(fmt.Sprintf("Note must be at most %d characters", maxNoteLength))
+	}
+	allDates := requestedDates(a.BookingDate, a.BookingDates)
+	if len(allDates) == 0 {
+		return nil, errBadRequest("booking_date or booking_dates is required")
+	}
+	earliest, _ := cfg.TodayRange(time.Now())
+	today, _ := time.Parse(time.DateOnly, earliest)
+	dates, err := validateBookingDates(allDates, maxWeeks, today)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := autoBookingUser(ctx, store, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	b := &bundle{tb: &teamBooking{bookedBy: user.ID, dates: dates, note: note, bundleID: uuid.New().String()}}
+	if err := b.addRequestedItems(ctx, cfg, member, a.ItemIDs, guards); err != nil {
+		return nil, err
+	}
+	for id := range a.Fields {
+		if !b.hasItem(id) {
+			return nil, errBadRequest("fields: " + id + " is not one of item_ids")
+		}
+	}
+	if a.ApplyDefaults == nil || *a.ApplyDefaults {
+		if err := b.addDefaultItems(ctx, store, cfg, member, guards); err != nil {
+			return nil, err
+		}
+	}
+
+	b.tb.fields = make([]map[string]any, len(b.tb.locs))
+	for i, loc := range b.tb.locs {
+		if b.tb.fields[i], err = resolveFields(loc, nil, a.Fields[loc.Item.ID], user); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// requestedDates returns the trimmed, non-empty dates of a request that takes
+// a single date, a list of dates, or both.
+func requestedDates(single string, multiple []string) []string {
+	var dates []string
+	if d := strings.TrimSpace(single); d != "" {
+		dates = append(dates, d)
+	}
+	for _, d := range multiple {
+		if trimmed := strings.TrimSpace(d); trimmed != "" {
+			dates = append(dates, trimmed)
+		}
+	}
+	return dates
+}
+
+// add appends an item booked by member to the bundle.
+func (b *bundle) add(member teamMember, loc *areas.ItemLocation, fromDefault bool) {
+	b.tb.members = append(b.tb.members, member)
+	b.tb.locs = append(b.tb.locs, loc)
+	b.fromDefault = append(b.fromDefault, fromDefault)
+}
+
+func (b *bundle) hasItem(itemID string) bool {
+	for _, loc := range b.tb.locs {
+		if loc.Item.ID == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *bundle) hasArea(areaID string) bool {
+	for _, loc := range b.tb.locs {
+		if loc.Area.ID == areaID {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *bundle) addRequestedItems(
+	ctx context.Context, cfg *areas.Config, member teamMember, itemIDs []string, guards []Guard,
+) error {
+	if len(itemIDs) == 0 {
+		return errBadRequest("item_ids is required")
+	}
+	if len(itemIDs) > maxBundleItems {
+		return errBadRequest(fmt.Sprintf("A bundle can include at most %d items", maxBundleItems))
+	}
+	for _, raw := range itemIDs {
+		id := strings.TrimSpace(raw)
+		if b.hasItem(id) {
+			return errBadRequest("item_ids must not contain duplicates: " + id)
+		}
+		loc, ok := cfg.FindItemLocation(id)
+		if !ok {
+			return &Rejection{Status: http.StatusNotFound, Code: "not_found", Detail: "Item not found: " + id}
+		}
+		if areas.IsReserved(loc, member.email) {
+			return &Rejection{Status: http.StatusForbidden, Code: "forbidden", Detail: reservationForbiddenMessage(loc)}
+		}
+		if err := runGuards(ctx, guards, b.guardRequest(loc, member)); err != nil {
+			return err
+		}
+		b.add(member, loc, false)
+	}
+	return nil
+}
+
+// addDefaultItems adds an item for each of the user's bundle defaults whose
+// area the bundle does not cover yet. Defaults whose area no longer exists
+// are skipped.
+func (b *bundle) addDefaultItems(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, member teamMember, guards []Guard,
+) error {
+	defaults, err := ListBundleDefaults(ctx, store, member.id)
+	if err != nil {
+		return err
+	}
+	for i := range defaults {
+		d := &defaults[i]
+		if b.hasArea(d.AreaID) {
+			continue
+		}
+		area, ok := cfg.FindArea(d.AreaID)
+		if !ok {
+			slog.Warn("skip bundle default of unknown area", "bundle_default_id", d.ID, "area_id", d.AreaID)
+			continue
+		}
+		loc, err := b.selectDefaultItem(ctx, store, cfg, area, d, member, guards)
+		if err != nil {
+			return err
+		}
+		b.add(member, loc, true)
+	}
+	return nil
+}
+
+// selectDefaultItem picks the default's preferred item if it is free on all
+// dates, or else the first free item of its item group or area.
+func (b *bundle) selectDefaultItem(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, area *areas.Area, d *BundleDefault,
+	member teamMember, guards []Guard,
+) (*areas.ItemLocation, error) {
+	free, err := NewFreeItems(ctx, store, cfg, guards, member.id, b.tb.bookedBy, member.email, b.tb.dates)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*areas.ItemLocation
+	var preferred *areas.ItemLocation
+	for i := range area.ItemGroups {
+		ig := &area.ItemGroups[i]
+		if d.ItemGroupID != "" && ig.ID != d.ItemGroupID {
+			continue
+		}
+		for j := range ig.Items {
+			loc := &areas.ItemLocation{Area: area, ItemGroup: ig, Item: &ig.Items[j]}
+			if loc.Item.ID == d.ItemID {
+				preferred = loc
+				continue
+			}
+			candidates = append(candidates, loc)
+		}
+	}
+	if preferred != nil {
+		candidates = append([]*areas.ItemLocation{preferred}, candidates...)
+	}
+
+	for _, loc := range candidates {
+		ok, err := free.Bookable(ctx, loc)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return loc, nil
+		}
+	}
+	if refusal := free.Refusal(); refusal != nil {
+		return nil, refusal
+	}
+	name := area.Name
+	if ig, ok := findItemGroupInArea(area, d.ItemGroupID); ok {
+		name = ig.Name
+	}
+	return nil, &Rejection{
+		Status: http.StatusConflict,
+		Code:   ErrorCodeBundlePartUnavailable,
+		Detail: fmt.Sprintf("No item in %q is free on all requested dates. "+
+			"Book without your bundle defaults by setting apply_defaults to false.", name),
+	}
+}
+
+func (b *bundle) guardRequest(loc *areas.ItemLocation, member teamMember) *GuardRequest {
+	return &GuardRequest{Location: loc, UserID: member.id, BookedByUserID: b.tb.bookedBy, Dates: b.tb.dates}
+}
+
+func findItemGroupInArea(area *areas.Area, id string) (*areas.ItemGroup, bool) {
+	for i := range area.ItemGroups {
+		if area.ItemGroups[i].ID == id {
+			return &area.ItemGroups[i], true
+		}
+	}
+	return nil, false
+}
+
+func bundleAttributes(b *bundle, created [][]*Booking) BundleAttributes {
+	attrs := BundleAttributes{
+		BookingDates: b.tb.dates,
+		Note:         b.tb.note,
+		Parts:        make([]BundlePart, len(b.tb.locs)),
+	}
+	for i, loc := range b.tb.locs {
+		ids := make([]string, len(created[i]))
+		for j, booking := range created[i] {
+			ids[j] = booking.ID
+		}
+		attrs.Parts[i] = BundlePart{
+			ItemID:        loc.Item.ID,
+			ItemName:      loc.Item.Name,
+			ItemGroupID:   loc.ItemGroup.ID,
+			ItemGroupName: loc.ItemGroup.Name,
+			AreaID:        loc.Area.ID,
+			AreaName:      loc.Area.Name,
+			BookingIDs:    ids,
+			Fields:        b.tb.fields[i],
+			FromDefault:   b.fromDefault[i],
+		}
+	}
+	return attrs
+}
+
+// CancelBundleHandler returns a handler that cancels the bookings of a bundle
+// in one transaction. Bookings on past dates are kept. The same users as for
+// single bookings may cancel, and unless they are admins, nothing is canceled
+// when one of the bookings is past its cancellation cut-off.
+// DELETE /api/v1/bookings/bundles/:id
+func CancelBundleHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		bundleID := c.Param("id")
+		ctx := c.Request().Context()
+		records, err := ListBundleBookings(ctx, store, bundleID)
+		if err != nil {
+			return api.WriteInternalError(c, "list bundle bookings", err)
+		}
+		if len(records) == 0 {
+			return api.WriteNotFound(c, "Booking bundle not found")
+		}
+		owner := &records[0]
+		allowed := user.IsAdmin || owner.UserID == user.ID || owner.BookedByUserID == user.ID
+		if !allowed {
+			if allowed, err = canActFor(ctx, store, user, owner.UserID); err != nil {
+				return api.WriteInternalError(c, "check delegation", err)
+			}
+		}
+		if !allowed {
+			return api.WriteNotFound(c, "Booking bundle not found")
+		}
+
+		cfg := getConfig()
+		var canceled, late []BookingRecord
+		for i := range records {
+			rec := &records[i]
+			var area *areas.Area
+			if loc, ok := cfg.FindItemLocation(rec.ItemID); ok {
+				area = loc.Area
+			}
+			rules := cfg.CancellationFor(area)
+			now := time.Now().In(cfg.AreaZone(area))
+			if !user.IsAdmin {
+				if rec.BookingDate < now.Format(time.DateOnly) {
+					continue
+				}
+				if detail := cancellationClosed(&rules, rec.BookingDate, now); detail != "" {
+					return api.WriteError(c, http.StatusConflict, detail, ErrorCodeCancellationClosed)
+				}
+				if rules.IsLate(rec.BookingDate, now) {
+					late = append(late, *rec)
+				}
+			}
+			canceled = append(canceled, *rec)
+		}
+		if len(canceled) == 0 {
+			return api.WriteError(c, http.StatusConflict,
+				"Past bookings cannot be canceled", ErrorCodeCancellationClosed)
+		}
+
+		if err := CancelBookings(ctx, store, notifier, canceled, user.ID, ""); err != nil {
+			return api.WriteInternalError(c, "cancel booking bundle", err)
+		}
+		for i := range late {
+			if err := RecordAttendance(ctx, store, user.ID, &late[i], AttendanceLateCancellation); err != nil {
+				slog.Error("record late cancellation", "booking_id", late[i].ID, "error", err)
+			}
+		}
+		slog.Info("booking bundle canceled",
+			"bundle_id", bundleID,
+			"canceled_by", user.ID,
+			"bookings", len(canceled),
+		)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// ListBundleBookings returns the bookings of a bundle, ordered by date.
+func ListBundleBookings(ctx context.Context, store *sql.DB, bundleID string) (result []BookingRecord, err error) {
+	if bundleID == "" {
+		return nil, nil
+	}
+	rows, err := store.QueryContext(ctx,
+		`SELECT id, item_id, user_id, booking_date, booked_by_user_id, note, created_at, updated_at,
+		        custom_fields, bundle_id, is_private
+		 FROM bookings WHERE bundle_id = ? ORDER BY booking_date, item_id`,
+		bundleID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query bundle bookings: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close bundle bookings rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var b BookingRecord
+		var fields string
+		if err := rows.Scan(&b.ID, &b.ItemID, &b.UserID, &b.BookingDate, &b.BookedByUserID, &b.Note,
+			&b.CreatedAt, &b.UpdatedAt, &fields, &b.BundleID, &b.IsPrivate); err != nil {
+			return nil, fmt.Errorf("scan bundle booking: %w", err)
+		}
+		b.Fields = DecodeFields(fields)
+		result = append(result, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate bundle bookings: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,244 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+const resourceTypeBundleDefault = "bundle-defaults"
+
+// ErrBundleDefaultNotFound indicates the requested bundle default does not exist.
+var ErrBundleDefaultNotFound = errors.New("bundle default not found")
+
+// ErrBundleDefaultExists indicates the user already has a bundle default for the area.
+var ErrBundleDefaultExists = errors.New("bundle default already exists")
+
+// BundleDefault adds an item of AreaID to every bundle UserID books, e.g.
+// "also book parking". ItemGroupID narrows the area and ItemID names the
+// preferred item; both are optional.
+type BundleDefault struct {
+	ID          string
+	UserID      string
+	AreaID      string
+	ItemGroupID string
+	ItemID      string
+	CreatedAt   string
+}
+
+// BundleDefaultAttributes represents bundle default resource attributes.
+type BundleDefaultAttributes struct {
+	AreaID      string `json:"area_id"`
+	ItemGroupID string `json:"item_group_id,omitempty"`
+	ItemID      string `json:"item_id,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type bundleDefaultRequest struct {
+	Data struct {
+		Type       string                  `json:"type"`
+		Attributes BundleDefaultAttributes `json:"attributes"`
+	} `json:"data"`
+}
+
+const bundleDefaultColumns = `id, user_id, area_id, item_group_id, item_id, created_at`
+
+// CreateBundleDefault stores d with a new ID. Returns ErrBundleDefaultExists if
+// the user already has a default for the area.
+func CreateBundleDefault(ctx context.Context, store *sql.DB, d *BundleDefault) error {
+	d.ID = uuid.NewString()
+	d.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err := store.ExecContext(ctx,
+		`INSERT INTO bundle_defaults (`+bundleDefaultColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		d.ID, d.UserID, d.AreaID, d.ItemGroupID, d.ItemID, d.CreatedAt,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return ErrBundleDefaultExists
+		}
+		return fmt.Errorf("insert bundle default: %w", err)
+	}
+	return nil
+}
+
+// FindBundleDefault returns the bundle default with the given ID, or ErrBundleDefaultNotFound.
+func FindBundleDefault(ctx context.Context, store *sql.DB, id string) (*BundleDefault, error) {
+	var d BundleDefault
+	err := store.QueryRowContext(ctx,
+		`SELECT `+bundleDefaultColumns+` FROM bundle_defaults WHERE id = ?`, id,
+	).Scan(&d.ID, &d.UserID, &d.AreaID, &d.ItemGroupID, &d.ItemID, &d.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBundleDefaultNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find bundle default: %w", err)
+	}
+	return &d, nil
+}
+
+// DeleteBundleDefault removes the bundle default with the given ID.
+func DeleteBundleDefault(ctx context.Context, store *sql.DB, id string) error {
+	if _, err := store.ExecContext(ctx, `DELETE FROM bundle_defaults WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete bundle default: %w", err)
+	}
+	return nil
+}
+
+// ListBundleDefaults returns the bundle defaults of a user, oldest first.
+func ListBundleDefaults(ctx context.Context, store *sql.DB, userID string) (result []BundleDefault, err error) {
+	rows, err := store.QueryContext(ctx,
+		`SELECT `+bundleDefaultColumns+` FROM bundle_defaults WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query bundle defaults: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close bundle defaults rows: %w", closeErr)
+		}
+	}()
+	for rows.Next() {
+		var d BundleDefault
+		if err := rows.Scan(&d.ID, &d.UserID, &d.AreaID, &d.ItemGroupID, &d.ItemID, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan bundle default: %w", err)
+		}
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate bundle defaults: %w", err)
+	}
+	return result, nil
+}
+
+// ListBundleDefaultsHandler returns the current user's bundle defaults.
+// GET /api/v1/bundle-defaults
+func ListBundleDefaultsHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		defaults, err := ListBundleDefaults(c.Request().Context(), store, user.ID)
+		if err != nil {
+			return api.WriteInternalError(c, "list bundle defaults", err)
+		}
+		resources := make([]api.Resource, len(defaults))
+		for i := range defaults {
+			resources[i] = bundleDefaultResource(&defaults[i])
+		}
+		return api.WriteCollection(c, resources, "write bundle defaults response")
+	}
+}
+
+// CreateBundleDefaultHandler adds a bundle default for the current user. Only
+// one default per area is allowed.
+// POST /api/v1/bundle-defaults
+func CreateBundleDefaultHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req bundleDefaultRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeBundleDefault {
+			return api.WriteBadRequest(c, "Resource type must be 'bundle-defaults'")
+		}
+		a := req.Data.Attributes
+		d := &BundleDefault{
+			UserID:      user.ID,
+			AreaID:      strings.TrimSpace(a.AreaID),
+			ItemGroupID: strings.TrimSpace(a.ItemGroupID),
+			ItemID:      strings.TrimSpace(a.ItemID),
+		}
+		if detail := validateBundleDefault(getConfig(), d); detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+
+		err := CreateBundleDefault(c.Request().Context(), store, d)
+		if errors.Is(err, ErrBundleDefaultExists) {
+			return api.WriteConflict(c, "You already have a bundle default for this area")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "create bundle default", err)
+		}
+		slog.Info("bundle default created", "bundle_default_id", d.ID, "user_id", user.ID, "area_id", d.AreaID)
+		return api.WriteSingle(c, http.StatusCreated, bundleDefaultResource(d), "write bundle default response")
+	}
+}
+
+// validateBundleDefault returns why d does not match the areas config, or "".
+func validateBundleDefault(cfg *areas.Config, d *BundleDefault) string {
+	if d.AreaID == "" {
+		return "area_id is required"
+	}
+	area, ok := cfg.FindArea(d.AreaID)
+	if !ok {
+		return "Area not found: " + d.AreaID
+	}
+	if d.ItemGroupID != "" {
+		if _, ok := findItemGroupInArea(area, d.ItemGroupID); !ok {
+			return fmt.Sprintf("Item group %s is not part of area %s", d.ItemGroupID, d.AreaID)
+		}
+	}
+	if d.ItemID != "" {
+		loc, ok := cfg.FindItemLocation(d.ItemID)
+		if !ok || loc.Area.ID != d.AreaID || (d.ItemGroupID != "" && loc.ItemGroup.ID != d.ItemGroupID) {
+			return fmt.Sprintf("Item %s is not part of the selected area or item group", d.ItemID)
+		}
+	}
+	return ""
+}
+
+// DeleteBundleDefaultHandler removes one of the current user's bundle defaults.
+// DELETE /api/v1/bundle-defaults/:id
+func DeleteBundleDefaultHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		ctx := c.Request().Context()
+		d, err := FindBundleDefault(ctx, store, c.Param("id"))
+		if errors.Is(err, ErrBundleDefaultNotFound) || (err == nil && d.UserID != user.ID) {
+			return api.WriteNotFound(c, "Bundle default not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find bundle default", err)
+		}
+		if err := DeleteBundleDefault(ctx, store, d.ID); err != nil {
+			return api.WriteInternalError(c, "delete bundle default", err)
+		}
+		slog.Info("bundle default deleted", "bundle_default_id", d.ID, "user_id", user.ID)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func bundleDefaultResource(d *BundleDefault) api.Resource {
+	return api.Resource{
+		Type: resourceTypeBundleDefault,
+		ID:   d.ID,
+		Attributes: BundleDefaultAttributes{
+			AreaID:      d.AreaID,
+			ItemGroupID: d.ItemGroupID,
+			ItemID:      d.ItemID,
+			CreatedAt:   d.CreatedAt,
+		},
+	}
+}
@@ -0,0 +1,216 @@
+package bookings
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+func bundleAreasConfig() *areas.Config {
+	return &areas.Config{Areas: []areas.Area{
+		{
+			ID: "office", Name: "Office",
+			ItemGroups: []areas.ItemGroup{{ID: "room-1", Name: "Room 1", Items: []areas.Item{
+				{ID: "desk-1", Name: "Desk 1"}, {ID: "desk-2", Name: "Desk 2"},
+			}}},
+		},
+		{
+			ID: "garage", Name: "Garage",
+			ItemGroups: []areas.ItemGroup{{ID: "level-1", Name: "Level 1", Items: []areas.Item{
+				{ID: "lot-1", Name: "Lot 1"}, {ID: "lot-2", Name: "Lot 2"},
+			}}},
+		},
+	}}
+}
+
+func postBundle(
+	t *testing.T, cfg *areas.Config, store *sql.DB, notifier notifications.Notifier, user *auth.User, attributes string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	h := BundleHandlerDynamic(func() *areas.Config { return cfg }, store, notifier, &BookingLimits{WeeksInAdvanced: 52})
+	return sendBookingJSON(t, h, http.MethodPost, "",
+		`{"data":{"type":"booking-bundles","attributes":{`+attributes+`}}}`, user)
+}
+
+func bundleParts(t *testing.T, rec *httptest.ResponseRecorder) (string, []BundlePart) {
+	t.Helper()
+	var resp struct {
+		Data struct {
+			ID         string           `json:"id"`
+			Attributes BundleAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Data.ID, resp.Data.Attributes.Parts
+}
+
+func TestBundleHandlerBooksAllOrNothing(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "u1", "u2")
+	cfg := bundleAreasConfig()
+	notifier := &recordingNotifier{}
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	dayAfter := time.Now().UTC().AddDate(0, 0, 2).Format(time.DateOnly)
+	u1 := &auth.User{ID: "u1", Name: "User u1"}
+
+	rec := postBundle(t, cfg, store, notifier, u1,
+		`"item_ids":["desk-1","lot-1"],"booking_dates":["`+date+`","`+dayAfter+`"],"note":"Car pool"`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	bundleID, parts := bundleParts(t, rec)
+	require.Len(t, parts, 2)
+	assert.Equal(t, "desk-1", parts[0].ItemID)
+	assert.Equal(t, "garage", parts[1].AreaID)
+	assert.Len(t, parts[1].BookingIDs, 2)
+	assert.False(t, parts[1].FromDefault)
+
+	records, err := ListBundleBookings(t.Context(), store, bundleID)
+	require.NoError(t, err)
+	assert.Len(t, records, 4)
+	stored, err := FindBookingByID(t.Context(), store, parts[1].BookingIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, bundleID, stored.BundleID)
+	assert.Equal(t, "Car pool", stored.Note)
+	require.Len(t, notifier.events, 4)
+	assert.Equal(t, bundleID, notifier.events[0].BundleID)
+
+	// lot-1 is taken on the first day, so desk-2 must not be booked either.
+	rec = postBundle(t, cfg, store, notifier, &auth.User{ID: "u2"},
+		`"item_ids":["desk-2","lot-1"],"booking_date":"`+date+`"`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, 4, countBookings(t, store))
+
+	cases := map[string]int{
+		`"item_ids":[],"booking_date":"` + date + `"`:                               http.StatusBadRequest,
+		`"item_ids":["desk-2","desk-2"],"booking_date":"` + date + `"`:              http.StatusBadRequest,
+		`"item_ids":["desk-2","nowhere"],"booking_date":"` + date + `"`:             http.StatusNotFound,
+		`"item_ids":["desk-2"]`:                                                     http.StatusBadRequest,
+		`"item_ids":["desk-2"],"booking_date":"` + date + `","fields":{"lot-2":{}}`: http.StatusBadRequest,
+	}
+	for attributes, want := range cases {
+		rec := postBundle(t, cfg, store, notifier, &auth.User{ID: "u2"}, attributes)
+		assert.Equal(t, want, rec.Code, attributes)
+	}
+	assert.Equal(t, 4, countBookings(t, store))
+}
+
+func TestBundleDefaults(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "u1", "u2")
+	cfg := bundleAreasConfig()
+	getConfig := func() *areas.Config { return cfg }
+	notifier := &recordingNotifier{}
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	u1 := &auth.User{ID: "u1", Name: "User u1"}
+	createDefault := func(attributes string) *httptest.ResponseRecorder {
+		return sendBookingJSON(t, CreateBundleDefaultHandler(getConfig, store), http.MethodPost, "",
+			`{"data":{"type":"bundle-defaults","attributes":{`+attributes+`}}}`, u1)
+	}
+
+	// In order: the conflict is with the default created before it.
+	for _, tc := range []struct {
+		attributes string
+		want       int
+	}{
+		{`"area_id":"moon"`, http.StatusBadRequest},
+		{`"area_id":"garage","item_group_id":"room-1"`, http.StatusBadRequest},
+		{`"area_id":"garage","item_id":"desk-1"`, http.StatusBadRequest},
+		{`"item_group_id":"level-1"`, http.StatusBadRequest},
+		{`"area_id":"garage","item_group_id":"level-1","item_id":"lot-2"`, http.StatusCreated},
+		{`"area_id":"garage"`, http.StatusConflict},
+	} {
+		assert.Equal(t, tc.want, createDefault(tc.attributes).Code, tc.attributes)
+	}
+	rec := sendBookingJSON(t, ListBundleDefaultsHandler(store), http.MethodGet, "", "", u1)
+	var list api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	defaultID := list.Data[0].ID
+
+	// The preferred lot is taken, so the default falls back to another one.
+	seedTestBooking(t, store, "taken", "lot-2", "u2", date)
+	rec = postBundle(t, cfg, store, notifier, u1, `"item_ids":["desk-1"],"booking_date":"`+date+`"`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	_, parts := bundleParts(t, rec)
+	require.Len(t, parts, 2)
+	assert.Equal(t, "lot-1", parts[1].ItemID)
+	assert.True(t, parts[1].FromDefault)
+
+	// The garage is full now; the default makes the whole bundle fail.
+	rec = postBundle(t, cfg, store, notifier, u1, `"item_ids":["desk-2"],"booking_date":"`+date+`"`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrorCodeBundlePartUnavailable)
+	assert.Equal(t, 3, countBookings(t, store))
+
+	rec = postBundle(t, cfg, store, notifier, u1,
+		`"item_ids":["desk-2"],"booking_date":"`+date+`","apply_defaults":false`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	_, parts = bundleParts(t, rec)
+	assert.Len(t, parts, 1)
+
+	h := DeleteBundleDefaultHandler(store)
+	rec = sendBookingJSON(t, h, http.MethodDelete, defaultID, "", &auth.User{ID: "u2"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, http.StatusNoContent, sendBookingJSON(t, h, http.MethodDelete, defaultID, "", u1).Code)
+	defaults, err := ListBundleDefaults(t.Context(), store, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, defaults)
+}
+
+func TestCancelBundleHandler(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "u1", "u2")
+	cfg := bundleAreasConfig()
+	getConfig := func() *areas.Config { return cfg }
+	notifier := &recordingNotifier{}
+	u1 := &auth.User{ID: "u1", Name: "User u1"}
+	today := time.Now().UTC().Format(time.DateOnly)
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	rec := postBundle(t, cfg, store, notifier, u1, `"item_ids":["desk-1","lot-1"],"booking_dates":["`+tomorrow+`"]`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	bundleID, _ := bundleParts(t, rec)
+	rec = postBundle(t, cfg, store, notifier, u1, `"item_ids":["desk-2","lot-2"],"booking_dates":["`+today+`"]`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	todayID, _ := bundleParts(t, rec)
+
+	h := CancelBundleHandler(getConfig, store, notifier)
+	assert.Equal(t, http.StatusNotFound, sendBookingJSON(t, h, http.MethodDelete, "missing", "", u1).Code)
+	rec = sendBookingJSON(t, h, http.MethodDelete, bundleID, "", &auth.User{ID: "u2"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	notifier.events = nil
+	rec = sendBookingJSON(t, h, http.MethodDelete, bundleID, "", u1)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	records, err := ListBundleBookings(t.Context(), store, bundleID)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	require.Len(t, notifier.events, 2)
+	assert.Equal(t, notifications.EventBookingCanceled, notifier.events[0].Event)
+	assert.Equal(t, bundleID, notifier.events[0].BundleID)
+
+	// Past the cut-off nothing is canceled, except by admins.
+	cfg.Cancellation = &areas.Cancellation{CutoffTime: "00:00"}
+	rec = sendBookingJSON(t, h, http.MethodDelete, todayID, "", u1)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, 2, countBookings(t, store))
+	rec = sendBookingJSON(t, h, http.MethodDelete, todayID, "", &auth.User{ID: "admin", IsAdmin: true})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Zero(t, countBookings(t, store))
+}
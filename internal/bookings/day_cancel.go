@@ -0,0 +1,68 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+// ListOwnBookingsOn returns the user's own bookings on the YYYY-MM-DD date,
+// without the bookings the user made for guests or colleagues.
+func ListOwnBookingsOn(ctx context.Context, store *sql.DB, userID, date string) ([]BookingRecord, error) {
+	all, err := ListUserBookingsRange(ctx, store, userID, date, date)
+	if err != nil {
+		return nil, err
+	}
+	own := make([]BookingRecord, 0, len(all))
+	for i := range all {
+		if all[i].UserID == userID && !all[i].IsGuest {
+			own = append(own, all[i])
+		}
+	}
+	return own, nil
+}
+
+// CancelDayBookings cancels the user's own bookings on the YYYY-MM-DD date
+// whose cancellation cut-off has not passed, e.g. when the user works remotely
+// that day. It returns the canceled bookings and those that were kept. Late
+// cancellations are recorded as for a cancellation by the user.
+func CancelDayBookings(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, notifier notifications.Notifier,
+	userID, date, reason string,
+) (canceled, kept []BookingRecord, err error) {
+	own, err := ListOwnBookingsOn(ctx, store, userID, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	var late []bool
+	for i := range own {
+		var area *areas.Area
+		if loc, ok := cfg.FindItemLocation(own[i].ItemID); ok {
+			area = loc.Area
+		}
+		rules := cfg.CancellationFor(area)
+		now := time.Now().In(cfg.AreaZone(area))
+		if cancellationClosed(&rules, date, now) != "" {
+			kept = append(kept, own[i])
+			continue
+		}
+		canceled = append(canceled, own[i])
+		late = append(late, rules.IsLate(date, now))
+	}
+	if err := CancelBookings(ctx, store, notifier, canceled, userID, reason); err != nil {
+		return nil, nil, err
+	}
+	for i := range canceled {
+		if !late[i] {
+			continue
+		}
+		if err := RecordAttendance(ctx, store, userID, &canceled[i], AttendanceLateCancellation); err != nil {
+			slog.Error("record late cancellation", "booking_id", canceled[i].ID, "error", err)
+		}
+	}
+	return canceled, kept, nil
+}
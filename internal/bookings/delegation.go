@@ -0,0 +1,126 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/delegations"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+// ErrorCodeDelegationRequired is the JSON:API error code used when a user books
+// for a colleague who has not delegated to them.
+const ErrorCodeDelegationRequired = "delegation_required"
+
+// authorizeOnBehalf checks that user may book for target. Admins and delegates
+// of the target may; anyone else is rejected, or with confirm set, the booking
+// is created pending the target's confirmation (pending is true).
+func authorizeOnBehalf(
+	ctx context.Context, store *sql.DB, user *auth.User, target *users.Record, confirm bool,
+) (pending bool, err error) {
+	if target.ID == user.ID || user.IsAdmin {
+		return false, nil
+	}
+	allowed, err := delegations.CanActFor(ctx, store, user.ID, target.ID)
+	if err != nil {
+		return false, fmt.Errorf("authorize booking on behalf: %w", err)
+	}
+	if allowed {
+		return false, nil
+	}
+	if confirm {
+		return true, nil
+	}
+	return false, &Rejection{
+		Status: http.StatusForbidden,
+		Code:   ErrorCodeDelegationRequired,
+		Detail: fmt.Sprintf("%s has not allowed you to book on their behalf", target.DisplayName),
+	}
+}
+
+// canActFor reports whether user may cancel the bookings of userID as their
+// delegate.
+func canActFor(ctx context.Context, store *sql.DB, user *auth.User, userID string) (bool, error) {
+	allowed, err := delegations.CanActFor(ctx, store, user.ID, userID)
+	if err != nil {
+		return false, fmt.Errorf("check delegation: %w", err)
+	}
+	return allowed, nil
+}
+
+// ConfirmBooking clears the pending confirmation of a booking and increments
+// its version.
+func ConfirmBooking(ctx context.Context, store *sql.DB, bookingID string) error {
+	_, err := store.ExecContext(ctx,
+		`UPDATE bookings SET pending_confirmation = 0, updated_at = ?, version = version + 1
+		 WHERE id = ? AND pending_confirmation = 1`,
+		time.Now().UTC().Format(time.RFC3339), bookingID,
+	)
+	if err != nil {
+		return fmt.Errorf("confirm booking: %w", err)
+	}
+	return nil
+}
+
+// ConfirmHandler returns a handler for the booked user to confirm a booking a
+// colleague made for them outside a delegation. Declining is canceling the
+// booking. Confirming a confirmed booking has no effect.
+// POST /api/v1/bookings/:id/confirm
+func ConfirmHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+
+		ctx := c.Request().Context()
+		bookingID := c.Param("id")
+		booking, err := FindBookingByID(ctx, store, bookingID)
+		if err != nil {
+			return fmt.Errorf("find booking: %w", err)
+		}
+		if booking == nil || booking.UserID != user.ID {
+			return api.WriteNotFound(c, "Booking not found")
+		}
+
+		if booking.PendingConfirmation {
+			if err := ConfirmBooking(ctx, store, bookingID); err != nil {
+				return err
+			}
+			slog.Info("booking confirmed", "booking_id", bookingID, "user_id", user.ID,
+				"booked_by", booking.BookedByUserID)
+			notifier.NotifyAsync(&notifications.BookingEvent{
+				Event:          notifications.EventBookingConfirmed,
+				BookingID:      bookingID,
+				ItemID:         booking.ItemID,
+				UserID:         booking.UserID,
+				BookingDate:    booking.BookingDate,
+				BookedByUserID: booking.BookedByUserID,
+				Fields:         booking.Fields,
+				Timestamp:      time.Now().UTC().Format(time.RFC3339),
+			})
+		}
+
+		updated, err := FindBookingByID(ctx, store, bookingID)
+		if err != nil {
+			return fmt.Errorf("reload booking: %w", err)
+		}
+		if updated == nil {
+			return api.WriteNotFound(c, "Booking not found")
+		}
+		return writePatchResponse(c, updated, getConfig().VisibleFieldValues(
+			updated.ItemID, updated.Fields, FieldAccess(user, updated.UserID, updated.BookedByUserID)))
+	}
+}
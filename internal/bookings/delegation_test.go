@@ -0,0 +1,182 @@
+package bookings
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+func postOnBehalf(
+	t *testing.T, h echo.HandlerFunc, user *auth.User, itemID, forUserID, date string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	body := `{"data":{"type":"bookings","attributes":{"item_id":"` + itemID +
+		`","booking_date":"` + date + `","for_user_id":"` + forUserID + `"}}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Set("user", user)
+	require.NoError(t, h(c))
+	return rec
+}
+
+func TestCreateHandlerOnBehalfRequiresDelegation(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTestUser(t, store, "boss", "Bea Boss")
+	seedTestUser(t, store, "assistant", "Assistant")
+	seedTestUser(t, store, "stranger", "Stranger")
+	seedDelegation(t, store, "boss", "assistant")
+	seedOrgWideDelegate(t, store, "office-manager")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	cfg := testAreasConfig()
+	h := CreateHandlerDynamic(func() *areas.Config { return cfg }, store, testNotifier(), nil)
+
+	rec := postOnBehalf(t, h, &auth.User{ID: "stranger", Name: "Stranger"}, "desk-1", "boss", date)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrorCodeDelegationRequired)
+	assert.Contains(t, rec.Body.String(), "Bea Boss has not allowed you")
+
+	rec = postOnBehalf(t, h, &auth.User{ID: "assistant", Name: "Assistant"}, "desk-1", "boss", date)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = postOnBehalf(t, h, &auth.User{ID: "office-manager", Name: "Office"}, "desk-2", "stranger", date)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// Delegations are not mutual.
+	dayAfter := time.Now().UTC().AddDate(0, 0, 2).Format(time.DateOnly)
+	rec = postOnBehalf(t, h, &auth.User{ID: "boss", Name: "Bea Boss"}, "desk-1", "assistant", dayAfter)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestOnBehalfConfirmation(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTestUser(t, store, "booker", "Booker")
+	seedTestUser(t, store, "target", "Target")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	cfg := testAreasConfig()
+	notifier := &recordingNotifier{}
+	limits := &BookingLimits{OnBehalfWithoutDelegation: OnBehalfConfirm}
+	h := CreateHandlerDynamic(func() *areas.Config { return cfg }, store, notifier, limits)
+
+	rec := postOnBehalf(t, h, &auth.User{ID: "booker", Name: "Booker"}, "desk-1", "target", date)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs, ok := resp.Data.Attributes.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, true, attrs["pending_confirmation"])
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, "target", notifier.events[0].UserID)
+	assert.True(t, notifier.events[0].PendingConfirmation)
+
+	bookingID := resp.Data.ID
+	confirm := ConfirmHandler(func() *areas.Config { return cfg }, store, notifier)
+	rec = bookingRequest(t, confirm, http.MethodPost, bookingID, &auth.User{ID: "booker", Name: "Booker"})
+	assert.Equal(t, http.StatusNotFound, rec.Code, "only the booked user confirms")
+
+	rec = bookingRequest(t, confirm, http.MethodPost, bookingID, &auth.User{ID: "target", Name: "Target"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "pending_confirmation")
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	require.Len(t, notifier.events, 2)
+	assert.Equal(t, notifications.EventBookingConfirmed, notifier.events[1].Event)
+	assert.Equal(t, "booker", notifier.events[1].BookedByUserID)
+
+	// Confirming again changes nothing.
+	rec = bookingRequest(t, confirm, http.MethodPost, bookingID, &auth.User{ID: "target", Name: "Target"})
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	assert.Len(t, notifier.events, 2)
+}
+
+func TestDeleteHandlerDelegation(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	today := time.Now().UTC().Format(time.DateOnly)
+	seedDelegation(t, store, "user-1", "assistant")
+	seedTestBooking(t, store, "b1", "desk-1", "user-1", today)
+	seedTestBookingFull(t, store, "pending", "desk-2", "user-1", "someone", today)
+	_, err := store.Exec(`UPDATE bookings SET pending_confirmation = 1 WHERE id = 'pending'`)
+	require.NoError(t, err)
+	cfg := testAreasConfig()
+	cfg.Cancellation = &areas.Cancellation{CutoffTime: "00:00"}
+	h := DeleteHandler(cfg, store, testNotifier())
+
+	rec := bookingRequest(t, h, http.MethodDelete, "b1", &auth.User{ID: "stranger", Name: "Stranger"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Delegates are held to the cancellation rules like the owner.
+	rec = bookingRequest(t, h, http.MethodDelete, "b1", &auth.User{ID: "assistant", Name: "Assistant"})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrorCodeCancellationClosed)
+
+	// Unconfirmed bookings can be declined after the cut-off, without counting as late.
+	rec = bookingRequest(t, h, http.MethodDelete, "pending", &auth.User{ID: "user-1", Name: "User"})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	stats, err := FindAttendanceStats(t.Context(), store, "user-1", today)
+	require.NoError(t, err)
+	assert.Zero(t, stats.LateCancellations)
+
+	cfg.Cancellation = nil
+	rec = bookingRequest(t, h, http.MethodDelete, "b1", &auth.User{ID: "assistant", Name: "Assistant"})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestTeamHandlerPendingMembers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "lead", "u1", "u2")
+	seedDelegation(t, store, "u1", "lead")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	cfg := teamAreasConfig()
+	body := `{"data":{"type":"team-bookings","attributes":{` +
+		`"member_ids":["u1","u2"],"item_ids":["desk-a","desk-b"],"booking_date":"` + date + `"}}}`
+	post := func(limits *BookingLimits) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings/team", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Set("user", &auth.User{ID: "lead", Name: "Team Lead"})
+		require.NoError(t, TeamHandlerDynamic(func() *areas.Config { return cfg }, store, testNotifier(), limits)(c))
+		return rec
+	}
+
+	rec := post(nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "User u2 has not allowed you")
+	assert.Zero(t, countBookings(t, store))
+
+	rec = post(&BookingLimits{OnBehalfWithoutDelegation: OnBehalfConfirm})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp struct {
+		Data struct {
+			Attributes TeamBookingAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Attributes.Assignments, 2)
+	assert.False(t, resp.Data.Attributes.Assignments[0].PendingConfirmation)
+	assert.True(t, resp.Data.Attributes.Assignments[1].PendingConfirmation)
+
+	pending, err := FindBookingByID(t.Context(), store, resp.Data.Attributes.Assignments[1].BookingIDs[0])
+	require.NoError(t, err)
+	assert.True(t, pending.PendingConfirmation)
+}
@@ -0,0 +1,94 @@
+package bookings
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+// resolveFields validates the custom field values of a booking of the item at
+// loc (see areas.BookingFields) and merges them into current, the stored values
+// of the booking or nil for new bookings. A null value clears a field. Only
+// admins can set admin fields. Returns nil if the booking has no values.
+func resolveFields(
+	loc *areas.ItemLocation, current, values map[string]any, user *auth.User,
+) (map[string]any, error) {
+	fields := areas.BookingFields(loc)
+	merged := make(map[string]any, len(current)+len(values))
+	for id, value := range current {
+		merged[id] = value
+	}
+	for id, raw := range values {
+		f := areas.FindBookingField(fields, id)
+		if f == nil {
+			return nil, errBadRequest(fmt.Sprintf("Unknown booking field %q", id))
+		}
+		if f.Visibility == areas.FieldAdmin && !user.IsAdmin {
+			return nil, errBadRequest(fmt.Sprintf("%s can only be set by admins", f.Name()))
+		}
+		var value any
+		if raw != nil {
+			var err error
+			if value, err = f.Normalize(raw); err != nil {
+				return nil, errBadRequest(err.Error())
+			}
+		}
+		if value == nil {
+			delete(merged, id)
+			continue
+		}
+		merged[id] = value
+	}
+	for i := range fields {
+		if fields[i].Required && merged[fields[i].ID] == nil {
+			return nil, errBadRequest(fields[i].Name() + " is required")
+		}
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// FieldAccess returns the access level (areas.FieldPublic, FieldBooker, or
+// FieldAdmin) of user to the custom fields of a booking for userID made by
+// bookedByUserID.
+func FieldAccess(user *auth.User, userID, bookedByUserID string) string {
+	switch {
+	case user != nil && user.IsAdmin:
+		return areas.FieldAdmin
+	case user != nil && (user.ID == userID || user.ID == bookedByUserID):
+		return areas.FieldBooker
+	default:
+		return areas.FieldPublic
+	}
+}
+
+// encodeFields returns the stored form of custom field values.
+func encodeFields(values map[string]any) string {
+	if len(values) == 0 {
+		return ""
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		slog.Error("encode booking fields", "error", err)
+		return ""
+	}
+	return string(raw)
+}
+
+// DecodeFields parses stored custom field values. Returns nil if there are none.
+func DecodeFields(raw string) map[string]any {
+	if raw == "" {
+		return nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		slog.Warn("decode booking fields", "error", err)
+		return nil
+	}
+	return values
+}
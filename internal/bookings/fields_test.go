@@ -0,0 +1,182 @@
+package bookings
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+func fieldsAreasConfig() *areas.Config {
+	cfg := testAreasConfig()
+	cfg.Areas[0].BookingFields = []areas.BookingField{
+		{ID: "plate", Label: "License plate", Type: areas.FieldTypeString, Required: true,
+			Pattern: `[A-Z]{1,3}-[A-Z]{1,2} [0-9]{1,4}`, Visibility: areas.FieldBooker},
+		{ID: "attendees", Type: areas.FieldTypeNumber},
+	}
+	cfg.Areas[0].ItemGroups[0].BookingFields = []areas.BookingField{
+		{ID: "charging", Type: areas.FieldTypeBoolean},
+		{ID: "cost_center", Type: areas.FieldTypeEnum, Options: []string{"R&D", "Sales"}, Visibility: areas.FieldAdmin},
+	}
+	return cfg
+}
+
+func sendBookingJSON(
+	t *testing.T, h echo.HandlerFunc, method, bookingID, body string, user *auth.User,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1/bookings", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	req.Header.Set("If-Match", "*")
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if bookingID != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(bookingID)
+	}
+	c.Set("user", user)
+	require.NoError(t, h(c))
+	return rec
+}
+
+func bookingFields(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var resp struct {
+		Data struct {
+			Attributes struct {
+				Fields map[string]any `json:"fields"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Data.Attributes.Fields
+}
+
+func TestCreateHandlerBookingFields(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	cfg := fieldsAreasConfig()
+	notifier := &recordingNotifier{}
+	h := CreateHandlerDynamic(func() *areas.Config { return cfg }, store, notifier, nil)
+	user := &auth.User{ID: "user-1", Name: "Test User"}
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	create := func(fields string) *httptest.ResponseRecorder {
+		return sendBookingJSON(t, h, http.MethodPost, "", `{"data":{"type":"bookings","attributes":{`+
+			`"item_id":"desk-1","booking_date":"`+date+`","fields":{`+fields+`}}}}`, user)
+	}
+
+	rejected := map[string]string{
+		``:                                        "License plate is required",
+		`"plate":"   "`:                           "License plate is required",
+		`"plate":"nope"`:                          "License plate has an invalid format",
+		`"plate":"B-XY 12","attendees":"3"`:       "attendees must be a number",
+		`"plate":"B-XY 12","charging":"yes"`:      "charging must be true or false",
+		`"plate":"B-XY 12","cost_center":"R&D"`:   "cost_center can only be set by admins",
+		`"plate":"B-XY 12","color":"red"`:         `Unknown booking field \"color\"`,
+		`"plate":"B-XY 12","charging":true,"x":1`: `Unknown booking field \"x\"`,
+	}
+	for fields, detail := range rejected {
+		rec := create(fields)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, fields)
+		assert.Contains(t, rec.Body.String(), detail, fields)
+	}
+	assert.Zero(t, countBookings(t, store))
+
+	rec := create(`"plate":" B-XY 12 ","attendees":3,"charging":false`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	want := map[string]any{"plate": "B-XY 12", "attendees": float64(3), "charging": false}
+	assert.Equal(t, want, bookingFields(t, rec))
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, want, notifier.events[0].Fields)
+
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	stored, err := FindBookingByID(t.Context(), store, resp.Data.ID)
+	require.NoError(t, err)
+	assert.Equal(t, want, stored.Fields)
+}
+
+func TestPatchHandlerBookingFields(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	cfg := fieldsAreasConfig()
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-1", "user-1", date)
+	require.NoError(t, UpdateDetails(t.Context(), store, "b1", "", map[string]any{"plate": "B-XY 12"}, false, 0))
+	h := PatchHandler(cfg, store)
+	user := &auth.User{ID: "user-1", Name: "Test User"}
+	admin := &auth.User{ID: "admin", Name: "Admin", IsAdmin: true}
+	patch := func(attributes string, u *auth.User) *httptest.ResponseRecorder {
+		return sendBookingJSON(t, h, http.MethodPatch, "b1",
+			`{"data":{"type":"bookings","id":"b1","attributes":{`+attributes+`}}}`, u)
+	}
+
+	rec := patch(`"fields":{"plate":null}`, user)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "License plate is required")
+
+	rec = patch(`"fields":{"cost_center":"Sales"}`, admin)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, map[string]any{"plate": "B-XY 12", "cost_center": "Sales"}, bookingFields(t, rec))
+
+	// Values merge into the stored ones; the owner does not see admin fields.
+	rec = patch(`"fields":{"attendees":4},"note":"late"`, user)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, map[string]any{"plate": "B-XY 12", "attendees": float64(4)}, bookingFields(t, rec))
+	stored, err := FindBookingByID(t.Context(), store, "b1")
+	require.NoError(t, err)
+	assert.Equal(t, "late", stored.Note)
+	assert.Equal(t, "Sales", stored.Fields["cost_center"])
+
+	// A note-only update keeps the fields.
+	rec = patch(`"note":""`, user)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, map[string]any{"plate": "B-XY 12", "attendees": float64(4)}, bookingFields(t, rec))
+
+	rec = patch(``, user)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestBookingFieldVisibility(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	cfg := fieldsAreasConfig()
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-1", "user-1", date)
+	values := map[string]any{"plate": "B-XY 12", "attendees": float64(2), "cost_center": "R&D", "retired": "x"}
+	require.NoError(t, UpdateDetails(t.Context(), store, "b1", "", values, false, 0))
+
+	rec := sendBookingJSON(t, ListHandler(cfg, store), http.MethodGet, "", "", &auth.User{ID: "user-1"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Data []struct {
+			Attributes MyBookingAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, map[string]any{"plate": "B-XY 12", "attendees": float64(2)}, list.Data[0].Attributes.Fields)
+
+	assert.Equal(t, map[string]any{"attendees": float64(2)},
+		cfg.VisibleFieldValues("desk-1", values, FieldAccess(&auth.User{ID: "colleague"}, "user-1", "user-1")))
+	assert.Equal(t, values,
+		cfg.VisibleFieldValues("desk-1", values, FieldAccess(&auth.User{ID: "a", IsAdmin: true}, "user-1", "")))
+
+	records, err := SearchBookings(t.Context(), store, &SearchQuery{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, values, buildSearchAttributes(cfg, &records[0], nil).Fields)
+}
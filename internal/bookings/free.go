@@ -0,0 +1,82 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+)
+
+// FreeItems tells which items a user can book on all of a set of dates. It is
+// the one free-item check of searches and suggestions, so that they offer the
+// same items the booking handlers accept.
+type FreeItems struct {
+	cfg    *areas.Config
+	guards []Guard
+	email  string
+	req    GuardRequest
+	booked map[string]struct{}
+	first  string
+	now    time.Time
+	// refusal is the first guard rejection seen by Bookable.
+	refusal error
+}
+
+// NewFreeItems loads the items booked on any of dates. The items are checked
+// for userID, booked by bookedByUserID; email decides which reserved items the
+// user may book.
+func NewFreeItems(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, guards []Guard,
+	userID, bookedByUserID, email string, dates []string,
+) (*FreeItems, error) {
+	booked, err := findBookedOnAnyDate(ctx, store, dates)
+	if err != nil {
+		return nil, err
+	}
+	f := &FreeItems{
+		cfg:    cfg,
+		guards: guards,
+		email:  email,
+		req:    GuardRequest{UserID: userID, BookedByUserID: bookedByUserID, Dates: dates},
+		booked: booked,
+		now:    time.Now(),
+	}
+	if len(dates) > 0 {
+		f.first = slices.Min(dates)
+	}
+	return f, nil
+}
+
+// Bookable reports whether loc is free on all dates: not booked on any of
+// them, not reserved for other users, not in the past in its area's time
+// zone, and not refused by a guard.
+func (f *FreeItems) Bookable(ctx context.Context, loc *areas.ItemLocation) (bool, error) {
+	if _, taken := f.booked[loc.Item.ID]; taken || areas.IsReserved(loc, f.email) {
+		return false, nil
+	}
+	if f.first < areas.LocalDate(f.now, f.cfg.AreaZone(loc.Area)) {
+		return false, nil
+	}
+	req := f.req
+	req.Location = loc
+	if err := runGuards(ctx, f.guards, &req); err != nil {
+		var rej *Rejection
+		if !errors.As(err, &rej) {
+			return false, fmt.Errorf("check item availability: %w", err)
+		}
+		if f.refusal == nil {
+			f.refusal = err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// Refusal returns the first guard rejection Bookable saw, or nil.
+func (f *FreeItems) Refusal() error {
+	return f.refusal
+}
@@ -0,0 +1,47 @@
+package bookings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeItemsBookable(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	cfg := testAreasConfig()
+	cfg.Areas[0].ItemGroups[0].Items[1].ReservedFor = []string{"boss@example.com"}
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	dayAfter := time.Now().UTC().AddDate(0, 0, 2).Format(time.DateOnly)
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-1", "user-2", dayAfter)
+
+	bookable := func(itemID string, dates []string, guards ...Guard) (bool, *FreeItems) {
+		t.Helper()
+		free, err := NewFreeItems(t.Context(), store, cfg, guards, "user-1", "user-1", "user-1@example.com", dates)
+		require.NoError(t, err)
+		loc, ok := cfg.FindItemLocation(itemID)
+		require.True(t, ok)
+		ok, err = free.Bookable(t.Context(), loc)
+		require.NoError(t, err)
+		return ok, free
+	}
+
+	ok, _ := bookable("desk-1", []string{tomorrow})
+	assert.True(t, ok)
+	ok, _ = bookable("desk-1", []string{tomorrow, dayAfter})
+	assert.False(t, ok, "booked on one of the dates")
+	ok, _ = bookable("desk-2", []string{tomorrow})
+	assert.False(t, ok, "reserved for someone else")
+	ok, _ = bookable("desk-1", []string{yesterday, tomorrow})
+	assert.False(t, ok, "in the past")
+
+	ok, free := bookable("desk-1", []string{tomorrow}, rejectingGuard{})
+	assert.False(t, ok, "refused by a guard")
+	var rej *Rejection
+	require.ErrorAs(t, free.Refusal(), &rej)
+	assert.Equal(t, "area_closed", rej.Code)
+}
@@ -0,0 +1,84 @@
+package bookings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+)
+
+// Guard vets a booking request before any rows are written. Guards let other
+// packages (closures, maintenance windows, ...) veto bookings without the
+// bookings package depending on them.
+type Guard interface {
+	// CheckBooking returns nil to allow the booking, a *Rejection to refuse it,
+	// or any other error for unexpected failures.
+	CheckBooking(ctx context.Context, req *GuardRequest) error
+}
+
+// GuardRequest describes the booking being attempted.
+type GuardRequest struct {
+	Location       *areas.ItemLocation
+	UserID         string
+	BookedByUserID string
+	IsGuest        bool
+	Dates          []string
+}
+
+// Rejection is returned by a Guard to refuse a booking. It is written to the
+// client as a JSON:API error with the given HTTP status and error code.
+type Rejection struct {
+	Status int
+	Code   string
+	Detail string
+}
+
+func (r *Rejection) Error() string { return r.Detail }
+
+// runGuards evaluates guards in order and returns the first failure.
+func runGuards(ctx context.Context, guards []Guard, req *GuardRequest) error {
+	for _, g := range guards {
+		if g == nil {
+			continue
+		}
+		if err := g.CheckBooking(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PassesGuards reports whether every guard accepts req. Rejections only mean
+// the booking is not allowed, for example because the item is not free; other
+// errors are returned.
+func PassesGuards(ctx context.Context, guards []Guard, req *GuardRequest) (bool, error) {
+	err := runGuards(ctx, guards, req)
+	if err == nil {
+		return true, nil
+	}
+	var rej *Rejection
+	if errors.As(err, &rej) {
+		return false, nil
+	}
+	return false, fmt.Errorf("check booking guards: %w", err)
+}
+
+// handleGuards runs the guards and writes a JSON:API error for the first
+// rejection. Returns nil when all guards pass or a rejection was written.
+func handleGuards(c echo.Context, guards []Guard, req *GuardRequest) error {
+	err := runGuards(c.Request().Context(), guards, req)
+	if err == nil {
+		return nil
+	}
+	var rej *Rejection
+	if errors.As(err, &rej) {
+		//nolint:errcheck // Response signals via Committed
+		api.WriteError(c, rej.Status, rej.Detail, rej.Code)
+		return nil
+	}
+	return fmt.Errorf("check booking guards: %w", err)
+}
@@ -0,0 +1,64 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/visitors"
+)
+
+// guestVisit is the visitor of a guest booking. New visitors are saved with the
+// first booked day, so failed bookings leave no visitor records behind.
+type guestVisit struct {
+	visitor         *visitors.Visitor
+	isNew           bool
+	expectedArrival string
+}
+
+// resolveGuest returns the visitor of a guest booking: the saved visitor given
+// by visitor_id, or a new visitor from for_user_name, guest_email, and
+// guest_company hosted by the booking user.
+func resolveGuest(ctx context.Context, store *sql.DB, user *auth.User, req *CreateRequest) (*guestVisit, error) {
+	a := req.Data.Attributes
+	arrival := strings.TrimSpace(a.ExpectedArrival)
+	if !visitors.ValidArrival(arrival) {
+		return nil, errBadRequest("expected_arrival must be in HH:MM format")
+	}
+	if strings.TrimSpace(a.VisitorID) == "" && strings.TrimSpace(a.ForUserName) == "" {
+		return nil, errBadRequest("for_user_name (guest name) is required for guest bookings")
+	}
+	v, isNew, err := visitors.ResolveVisitor(ctx, store, user, a.VisitorID, a.ForUserName, a.GuestEmail, a.GuestCompany)
+	var inputErr *visitors.InputError
+	switch {
+	case errors.Is(err, visitors.ErrVisitorNotFound):
+		return nil, errBadRequest("visitor_id: visitor not found")
+	case errors.As(err, &inputErr):
+		return nil, errBadRequest(inputErr.Detail)
+	case err != nil:
+		return nil, err
+	}
+	return &guestVisit{visitor: v, isNew: isNew, expectedArrival: arrival}, nil
+}
+
+// record saves a new visitor and registers the visit for the booked day, so the
+// guest shows up on the reception list. Failures are logged; the booking stands.
+func (g *guestVisit) record(ctx context.Context, store *sql.DB, bookingDate string) {
+	if g == nil {
+		return
+	}
+	if g.isNew {
+		if err := visitors.CreateVisitor(ctx, store, g.visitor); err != nil {
+			slog.Error("save guest visitor", "visitor_id", g.visitor.ID, "error", err)
+			return
+		}
+		g.isNew = false
+	}
+	_, err := visitors.AddVisit(ctx, store, g.visitor, bookingDate, g.expectedArrival)
+	if err != nil && !errors.Is(err, visitors.ErrDuplicateVisit) {
+		slog.Error("register guest visit", "visitor_id", g.visitor.ID, "booking_date", bookingDate, "error", err)
+	}
+}
@@ -0,0 +1,96 @@
+package bookings
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/visitors"
+)
+
+func postGuestBooking(t *testing.T, store *sql.DB, userID, attributes string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := `{"data":{"type":"bookings","attributes":{"is_guest":true,` + attributes + `}}}`
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: userID, Name: "Host User"})
+	require.NoError(t, CreateHandler(testAreasConfig(), store, testNotifier())(c))
+	return rec
+}
+
+func bookedUserID(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs, ok := resp.Data.Attributes.(map[string]any)
+	require.True(t, ok)
+	userID, ok := attrs["user_id"].(string)
+	require.True(t, ok)
+	return userID
+}
+
+func TestGuestBookingRegistersVisitor(t *testing.T) {
+	t.Parallel()
+	store := setupTestStore(t)
+	day1 := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	day2 := time.Now().UTC().AddDate(0, 0, 2).Format(time.DateOnly)
+
+	rec := postGuestBooking(t, store, "user-1", `"item_id":"desk-1","booking_date":"`+day1+`",`+
+		`"for_user_name":"Jane Guest","guest_email":"jane@example.com","guest_company":"Acme",`+
+		`"expected_arrival":"09:30"`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	visitorID := bookedUserID(t, rec)
+
+	visitor, err := visitors.FindVisitor(t.Context(), store, visitorID)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Guest", visitor.Name)
+	assert.Equal(t, "Acme", visitor.Company)
+	assert.Equal(t, "user-1", visitor.HostUserID)
+
+	entries, err := visitors.ListDay(t.Context(), store, day1, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "09:30", entries[0].ExpectedArrival)
+	assert.Equal(t, "desk-1", entries[0].ItemID)
+
+	// A later visit reuses the saved visitor.
+	rec = postGuestBooking(t, store, "user-1", `"item_id":"desk-1","booking_date":"`+day2+`",`+
+		`"visitor_id":"`+visitorID+`"`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, visitorID, bookedUserID(t, rec))
+
+	all, err := visitors.ListVisitors(t.Context(), store, "", "")
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestGuestBookingRejectsInvalidVisitor(t *testing.T) {
+	t.Parallel()
+	store := setupTestStore(t)
+	day := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	visitor := &visitors.Visitor{Name: "Jane Guest", HostUserID: "user-2"}
+	require.NoError(t, visitors.CreateVisitor(t.Context(), store, visitor))
+
+	rec := postGuestBooking(t, store, "user-1", `"item_id":"desk-1","booking_date":"`+day+`",`+
+		`"visitor_id":"`+visitor.ID+`"`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "visitor not found")
+
+	rec = postGuestBooking(t, store, "user-1", `"item_id":"desk-1","booking_date":"`+day+`",`+
+		`"for_user_name":"Jane Guest","expected_arrival":"9am"`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, 0, countBookings(t, store))
+}
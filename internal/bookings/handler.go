@@ -28,6 +28,24 @@ import (
 type BookingLimits struct {
 	WeeksInAdvanced      int
 	MaxBookingsPerPerson int
+	// OnBehalfWithoutDelegation decides what happens to bookings for users who
+	// have not delegated to the booker: OnBehalfReject (the default) or
+	// OnBehalfConfirm.
+	OnBehalfWithoutDelegation string
+}
+
+// Handling of bookings made for users outside a delegation.
+const (
+	// OnBehalfReject rejects the booking.
+	OnBehalfReject = "reject"
+	// OnBehalfConfirm creates the booking pending the booked user's confirmation.
+	OnBehalfConfirm = "confirm"
+)
+
+// confirmsOnBehalf reports whether bookings outside a delegation wait for
+// confirmation instead of being rejected.
+func (l *BookingLimits) confirmsOnBehalf() bool {
+	return l != nil && l.OnBehalfWithoutDelegation == OnBehalfConfirm
 }
 
 // ErrBookingLimitExceeded indicates a booking limit was reached.
@@ -45,7 +63,17 @@ type CreateRequest struct {
 			ForUserName  string   `json:"for_user_name,omitempty"`
 			IsGuest      bool     `json:"is_guest,omitempty"`
 			GuestEmail   string   `json:"guest_email,omitempty"`
-			Note         string   `json:"note,omitempty"`
+			// VisitorID reuses a saved visitor for a guest booking.
+			VisitorID       string `json:"visitor_id,omitempty"`
+			GuestCompany    string `json:"guest_company,omitempty"`
+			ExpectedArrival string `json:"expected_arrival,omitempty"`
+			Note            string `json:"note,omitempty"`
+			// Fields holds the values of the custom fields of the item's area
+			// and item group, keyed by field ID.
+			Fields map[string]any `json:"fields,omitempty"`
+			// Private hides the booked user from other users. Bookings of
+			// users who book privately by default are always private.
+			Private bool `json:"private,omitempty"`
 		} `json:"attributes"`
 	} `json:"data"`
 }
@@ -60,6 +88,19 @@ type BookingAttributes struct {
 	IsGuest        bool   `json:"is_guest,omitempty"`
 	GuestEmail     string `json:"guest_email,omitempty"`
 	Note           string `json:"note"`
+	CheckedInAt    string `json:"checked_in_at,omitempty"`
+	// PendingConfirmation marks a booking made outside a delegation that the
+	// booked user has not confirmed yet.
+	PendingConfirmation bool `json:"pending_confirmation,omitempty"`
+	// Fields holds the custom field values the requesting user may see.
+	Fields map[string]any `json:"fields,omitempty"`
+	// BundleID links bookings created together in a bundle.
+	BundleID string `json:"bundle_id,omitempty"`
+	// Private hides the booked user from everyone but admins, delegates, and
+	// the people involved.
+	Private bool `json:"private,omitempty"`
+	// Version is the booking's ETag value, to be sent back as If-Match.
+	Version int `json:"version,omitempty"`
 }
 
 // MultiDayBookingResult represents the result of a multi-day booking request.
@@ -70,23 +111,29 @@ type MultiDayBookingResult struct {
 
 // MyBookingAttributes represents booking resource attributes with location info.
 type MyBookingAttributes struct {
-	ItemID           string `json:"item_id"`
-	ItemName         string `json:"item_name"`
-	ItemGroupID      string `json:"item_group_id"`
-	ItemGroupName    string `json:"item_group_name"`
-	AreaID           string `json:"area_id"`
-	AreaName         string `json:"area_name"`
-	BookingDate      string `json:"booking_date"`
-	CreatedAt        string `json:"created_at"`
-	BookedByUserID   string `json:"booked_by_user_id,omitempty"`
-	BookedByUserName string `json:"booked_by_user_name,omitempty"`
-	BookedForMe      bool   `json:"booked_for_me,omitempty"`
-	ForUserID        string `json:"for_user_id,omitempty"`
-	ForUserName      string `json:"for_user_name,omitempty"`
-	IsGuest          bool   `json:"is_guest,omitempty"`
-	GuestName        string `json:"guest_name,omitempty"`
-	GuestEmail       string `json:"guest_email,omitempty"`
-	Note             string `json:"note"`
+	ItemID              string         `json:"item_id"`
+	ItemName            string         `json:"item_name"`
+	ItemGroupID         string         `json:"item_group_id"`
+	ItemGroupName       string         `json:"item_group_name"`
+	AreaID              string         `json:"area_id"`
+	AreaName            string         `json:"area_name"`
+	BookingDate         string         `json:"booking_date"`
+	CreatedAt           string         `json:"created_at"`
+	BookedByUserID      string         `json:"booked_by_user_id,omitempty"`
+	BookedByUserName    string         `json:"booked_by_user_name,omitempty"`
+	BookedForMe         bool           `json:"booked_for_me,omitempty"`
+	ForUserID           string         `json:"for_user_id,omitempty"`
+	ForUserName         string         `json:"for_user_name,omitempty"`
+	IsGuest             bool           `json:"is_guest,omitempty"`
+	GuestName           string         `json:"guest_name,omitempty"`
+	GuestEmail          string         `json:"guest_email,omitempty"`
+	Note                string         `json:"note"`
+	CheckedInAt         string         `json:"checked_in_at,omitempty"`
+	PendingConfirmation bool           `json:"pending_confirmation,omitempty"`
+	Fields              map[string]any `json:"fields,omitempty"`
+	BundleID            string         `json:"bundle_id,omitempty"`
+	Private             bool           `json:"private,omitempty"`
+	Version             int            `json:"version"`
 }
 
 // maxNoteLength is the maximum allowed length for a booking note.
@@ -102,13 +149,24 @@ type PatchRequest struct {
 		ID         string `json:"id"`
 		Attributes struct {
 			Note *string `json:"note"`
+			// Fields replaces the given custom field values; null clears a field.
+			Fields map[string]any `json:"fields"`
+			// Private hides or shows the booked user to other users.
+			Private *bool `json:"private"`
 		} `json:"attributes"`
 	} `json:"data"`
 }
 
-// PatchHandler returns a handler for updating a booking's note.
+// PatchHandler returns a handler for updating a booking's note, custom field
+// values, and privacy.
 // Authorization: booking owner, the person who booked, or admin.
-func PatchHandler(store *sql.DB) echo.HandlerFunc {
+// An If-Match header must carry the booking's current ETag, or 412 is returned.
+func PatchHandler(cfg *areas.Config, store *sql.DB) echo.HandlerFunc {
+	return PatchHandlerDynamic(func() *areas.Config { return cfg }, store)
+}
+
+// PatchHandlerDynamic returns a handler for updating a booking using dynamic config.
+func PatchHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		user := auth.GetUserFromContext(c)
 		if user == nil {
@@ -120,7 +178,7 @@ func PatchHandler(store *sql.DB) echo.HandlerFunc {
 			return api.WriteBadRequest(c, "Booking ID is required")
 		}
 
-		note, err := parsePatchNote(c, bookingID)
+		req, err := parsePatchRequest(c, bookingID)
 		if err != nil {
 			if errors.Is(err, errResponseWritten) {
 				return nil
@@ -137,51 +195,89 @@ func PatchHandler(store *sql.DB) echo.HandlerFunc {
 			return err
 		}
 
-		if err := UpdateNote(ctx, store, bookingID, note); err != nil {
-			return fmt.Errorf("update note: %w", err)
+		attrs := req.Data.Attributes
+		note := booking.Note
+		if attrs.Note != nil {
+			note = *attrs.Note
+		}
+		isPrivate := booking.IsPrivate
+		if attrs.Private != nil {
+			isPrivate = *attrs.Private
+		}
+		cfg := getConfig()
+		fields := booking.Fields
+		if attrs.Fields != nil {
+			loc, _ := cfg.FindItemLocation(booking.ItemID)
+			if fields, err = resolveFields(loc, booking.Fields, attrs.Fields, user); err != nil {
+				return handleValidationError(c, err)
+			}
 		}
 
-		slog.Info("booking note updated",
+		ifVersion := api.IfMatch(c)
+		if !api.Matches(ifVersion, booking.Version) {
+			return api.WritePreconditionFailed(c)
+		}
+		err = UpdateDetails(ctx, store, bookingID, note, fields, isPrivate, ifVersion)
+		if errors.Is(err, ErrVersionConflict) {
+			return api.WritePreconditionFailed(c)
+		}
+		if err != nil {
+			return fmt.Errorf("update booking: %w", err)
+		}
+
+		slog.Info("booking updated",
 			"booking_id", bookingID,
 			"updated_by", user.ID,
 		)
 
-		return writePatchResponse(c, booking, note)
+		updated, err := FindBookingByID(ctx, store, bookingID)
+		if err != nil {
+			return fmt.Errorf("reload booking: %w", err)
+		}
+		if updated == nil {
+			return api.WriteNotFound(c, "Booking not found")
+		}
+		return writePatchResponse(c, updated, cfg.VisibleFieldValues(
+			updated.ItemID, updated.Fields, FieldAccess(user, updated.UserID, updated.BookedByUserID)))
 	}
 }
 
-// parsePatchNote validates content type and parses the note from the PATCH request body.
+// parsePatchRequest validates content type and parses the note, custom field
+// values, and privacy from the PATCH request body; the note is trimmed.
 // Returns errResponseWritten if an error response was already sent.
-func parsePatchNote(c echo.Context, bookingID string) (string, error) {
+func parsePatchRequest(c echo.Context, bookingID string) (*PatchRequest, error) {
 	if err := validateContentType(c); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var req PatchRequest
 	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
 		//nolint:errcheck // Error ignored; response already written
 		api.WriteBadRequest(c, "Invalid request body")
-		return "", errResponseWritten
+		return nil, errResponseWritten
 	}
 	if req.Data.Type != resourceTypeBooking {
 		//nolint:errcheck // Error ignored; response already written
 		api.WriteBadRequest(c, "Resource type must be 'bookings'")
-		return "", errResponseWritten
+		return nil, errResponseWritten
 	}
 	if req.Data.ID == "" {
 		//nolint:errcheck // Error ignored; response already written
 		api.WriteBadRequest(c, "Resource ID is required")
-		return "", errResponseWritten
+		return nil, errResponseWritten
 	}
 	if req.Data.ID != bookingID {
 		//nolint:errcheck // Error ignored; response already written
 		api.WriteBadRequest(c, "Resource ID must match booking ID")
-		return "", errResponseWritten
+		return nil, errResponseWritten
 	}
-	if req.Data.Attributes.Note == nil {
+	if req.Data.Attributes.Note == nil && req.Data.Attributes.Fields == nil && req.Data.Attributes.Private == nil {
 		//nolint:errcheck // Error ignored; response already written
-		api.WriteBadRequest(c, "Note is required")
-		return "", errResponseWritten
+		api.WriteBadRequest(c, "Note, fields, or private is required")
+		return nil, errResponseWritten
+	}
+	if req.Data.Attributes.Note == nil {
+		return &req, nil
 	}
 
 	note := strings.TrimSpace(*req.Data.Attributes.Note)
@@ -189,10 +285,11 @@ func parsePatchNote(c echo.Context, bookingID string) (string, error) {
 		//nolint:errcheck // Error ignored; response already written
 		api.WriteBadRequest(c, fmt.Sprintf(
 			"Note must be at most %d characters", maxNoteLength))
-		return "", errResponseWritten
+		return nil, errResponseWritten
 	}
+	req.Data.Attributes.Note = &note
 
-	return note, nil
+	return &req, nil
 }
 
 // findAuthorizedBooking retrieves a booking and checks that the user is authorized.
@@ -220,13 +317,21 @@ func findAuthorizedBooking(
 // ErrBookingNotFound is a sentinel error for booking not found responses.
 var ErrBookingNotFound = errors.New("booking not found")
 
-func writePatchResponse(c echo.Context, booking *BookingRecord, note string) error {
+// writePatchResponse writes an updated booking with the custom field values
+// the requesting user may see.
+func writePatchResponse(c echo.Context, booking *BookingRecord, fields map[string]any) error {
 	attrs := BookingAttributes{
-		ItemID:      booking.ItemID,
-		UserID:      booking.UserID,
-		BookingDate: booking.BookingDate,
-		CreatedAt:   booking.CreatedAt,
-		Note:        note,
+		ItemID:              booking.ItemID,
+		UserID:              booking.UserID,
+		BookingDate:         booking.BookingDate,
+		CreatedAt:           booking.CreatedAt,
+		Note:                booking.Note,
+		CheckedInAt:         booking.CheckedInAt,
+		PendingConfirmation: booking.PendingConfirmation,
+		BundleID:            booking.BundleID,
+		Fields:              fields,
+		Private:             booking.IsPrivate,
+		Version:             booking.Version,
 	}
 	if booking.BookedByUserID != "" && booking.BookedByUserID != booking.UserID {
 		attrs.BookedByUserID = booking.BookedByUserID
@@ -244,6 +349,7 @@ func writePatchResponse(c echo.Context, booking *BookingRecord, note string) err
 		},
 	}
 
+	api.SetETag(c, booking.Version)
 	c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
 	//nolint:wrapcheck // Terminal response
 	return c.JSON(http.StatusOK, resp)
@@ -251,8 +357,21 @@ func writePatchResponse(c echo.Context, booking *BookingRecord, note string) err
 
 // DeleteHandler returns a handler for canceling a booking.
 // Users can cancel their own bookings or bookings made for them;
-// The person who booked on behalf can also cancel; admins can cancel any booking.
-func DeleteHandler(store *sql.DB, notifier notifications.Notifier) echo.HandlerFunc {
+// The person who booked on behalf and the owner's delegates can also cancel;
+// admins can cancel any booking.
+// An If-Match header must carry the booking's current ETag, or 412 is returned.
+func DeleteHandler(cfg *areas.Config, store *sql.DB, notifier notifications.Notifier) echo.HandlerFunc {
+	return DeleteHandlerDynamic(func() *areas.Config { return cfg }, store, notifier)
+}
+
+// DeleteHandlerDynamic returns a handler for canceling a booking using dynamic config.
+// Users cannot cancel past bookings or, on the booked day, after the area's
+// cancellation cut-off; admins can. Late cancellations are recorded against the
+// canceling user. A booking waiting for the owner's confirmation can always be
+// declined by the owner.
+func DeleteHandlerDynamic(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		user := auth.GetUserFromContext(c)
 		if user == nil {
@@ -275,18 +394,52 @@ func DeleteHandler(store *sql.DB, notifier notifications.Notifier) echo.HandlerF
 			return api.WriteNotFound(c, "Booking not found")
 		}
 
-		// Check authorization: owner, booker, or admin
+		// Check authorization: owner, booker, the owner's delegate, or admin
 		isOwner := booking.UserID == user.ID
 		isBooker := booking.BookedByUserID == user.ID
-		canCancel := isOwner || isBooker || user.IsAdmin
-		if !canCancel {
+		isDelegate := false
+		if !isOwner && !isBooker && !booking.IsGuest {
+			if isDelegate, err = canActFor(ctx, store, user, booking.UserID); err != nil {
+				return err
+			}
+		}
+		if !isOwner && !isBooker && !isDelegate && !user.IsAdmin {
 			return api.WriteNotFound(c, "Booking not found")
 		}
+		adminAction := !isOwner && !isBooker && !isDelegate
+		// Declining a booking that waits for confirmation is always possible.
+		declined := isOwner && booking.PendingConfirmation
+
+		cfg := getConfig()
+		var area *areas.Area
+		if loc, ok := cfg.FindItemLocation(booking.ItemID); ok {
+			area = loc.Area
+		}
+		rules := cfg.CancellationFor(area)
+		now := time.Now().In(cfg.AreaZone(area))
+		if !user.IsAdmin && !declined {
+			if detail := cancellationClosed(&rules, booking.BookingDate, now); detail != "" {
+				return api.WriteError(c, http.StatusConflict, detail, ErrorCodeCancellationClosed)
+			}
+		}
 
-		// Delete the booking
-		if err := DeleteBooking(ctx, store, bookingID); err != nil {
+		// Delete the booking unless it changed since the client read it
+		ifVersion := api.IfMatch(c)
+		if !api.Matches(ifVersion, booking.Version) {
+			return api.WritePreconditionFailed(c)
+		}
+		err = DeleteBooking(ctx, store, bookingID, ifVersion)
+		if errors.Is(err, ErrVersionConflict) {
+			return api.WritePreconditionFailed(c)
+		}
+		if err != nil {
 			return fmt.Errorf("delete booking: %w", err)
 		}
+		if !adminAction && !declined && rules.IsLate(booking.BookingDate, now) {
+			if err := RecordAttendance(ctx, store, user.ID, booking, AttendanceLateCancellation); err != nil {
+				slog.Error("record late cancellation", "booking_id", bookingID, "error", err)
+			}
+		}
 
 		logFields := []any{
 			"booking_id", bookingID,
@@ -296,7 +449,7 @@ func DeleteHandler(store *sql.DB, notifier notifications.Notifier) echo.HandlerF
 		}
 		if !isOwner {
 			logFields = append(logFields, "booking_owner", booking.UserID)
-			if user.IsAdmin && !isBooker {
+			if adminAction {
 				logFields = append(logFields, "admin_action", true)
 			}
 		}
@@ -313,6 +466,8 @@ func DeleteHandler(store *sql.DB, notifier notifications.Notifier) echo.HandlerF
 			GuestName:        booking.GuestName,
 			GuestEmail:       booking.GuestEmail,
 			CanceledByUserID: user.ID,
+			Fields:           booking.Fields,
+			Private:          booking.IsPrivate,
 			Timestamp:        time.Now().UTC().Format(time.RFC3339),
 		})
 
@@ -320,8 +475,33 @@ func DeleteHandler(store *sql.DB, notifier notifications.Notifier) echo.HandlerF
 	}
 }
 
+// ErrorCodeCancellationClosed is the JSON:API error code used when a booking can
+// no longer be canceled.
+const ErrorCodeCancellationClosed = "cancellation_closed"
+
+// cancellationClosed returns why a user can no longer cancel a booking on the
+// YYYY-MM-DD date at now (area-local), or "" if they still can.
+func cancellationClosed(rules *areas.Cancellation, date string, now time.Time) string {
+	if date < now.Format(time.DateOnly) {
+		return "Past bookings cannot be canceled"
+	}
+	if cutoff := rules.Cutoff(date, now.Location()); !now.Before(cutoff) {
+		return fmt.Sprintf("Bookings on %s can only be canceled until %s", date, cutoff.Format("15:04 MST"))
+	}
+	return ""
+}
+
+// myBookingsQueryOptions lists the sort and filter fields of the my bookings
+// and history collections.
+var myBookingsQueryOptions = api.QueryOptions{
+	Sort:    []string{"booking_date", "created_at", "item_name", "item_group_name", "area_name"},
+	Filters: []string{"booking_date", "item_id", "item_group_id", "area_id"},
+}
+
 // ListHandler returns a handler for listing the current user's future bookings.
 // Includes bookings made by the user AND bookings made for the user by others.
+// Supports JSON:API page, sort, filter, and fields parameters, and include
+// paths item, item.item_group, item.item_group.area, user, and booked_by.
 func ListHandler(cfg *areas.Config, store *sql.DB) echo.HandlerFunc {
 	return ListHandlerDynamic(func() *areas.Config { return cfg }, store)
 }
@@ -334,20 +514,33 @@ func ListHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.Handle
 			return api.WriteUnauthorized(c)
 		}
 
-		today := time.Now().UTC().Format(time.DateOnly)
+		query, err := api.ParseQuery(c, myBookingsQueryOptions)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
+		include, err := api.ParseInclude(c, bookingIncludePaths)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
+
+		cfg := getConfig()
+		now := time.Now()
+		earliest, _ := cfg.TodayRange(now)
 		ctx := c.Request().Context()
 
-		records, err := ListUserBookings(ctx, store, user.ID, today)
+		records, err := ListUserBookings(ctx, store, user.ID, earliest)
 		if err != nil {
 			return fmt.Errorf("list user bookings: %w", err)
 		}
+		records = filterByLocalToday(cfg, records, now, true)
 
-		return writeBookingsCollection(ctx, c, getConfig(), store, user.ID, records)
+		return writeBookingsCollection(ctx, c, cfg, store, user, records, query, include)
 	}
 }
 
 // HistoryHandler returns a handler for listing the current user's past bookings.
-// Accepts optional query params: from (start date), to (end date) in YYYY-MM-DD format.
+// Accepts optional query params: from (start date), to (end date) in YYYY-MM-DD format,
+// and the same JSON:API parameters and include paths as ListHandler.
 func HistoryHandler(cfg *areas.Config, store *sql.DB) echo.HandlerFunc {
 	return HistoryHandlerDynamic(func() *areas.Config { return cfg }, store)
 }
@@ -360,19 +553,23 @@ func HistoryHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.Han
 			return api.WriteUnauthorized(c)
 		}
 
-		// Default: last 30 days
-		today := time.Now().UTC()
+		// Default: last 30 days, up to the latest today of all areas; bookings
+		// not yet past in their own area's time zone are dropped below.
+		cfg := getConfig()
+		now := time.Now()
+		_, latest := cfg.TodayRange(now)
+		today, _ := time.Parse(time.DateOnly, latest)
 		defaultFrom := today.AddDate(0, 0, -30).Format(time.DateOnly)
-		defaultTo := today.AddDate(0, 0, -1).Format(time.DateOnly) // Yesterday (past only)
 
 		fromDate := c.QueryParam("from")
 		toDate := c.QueryParam("to")
+		pastOnly := toDate == ""
 
 		if fromDate == "" {
 			fromDate = defaultFrom
 		}
 		if toDate == "" {
-			toDate = defaultTo
+			toDate = latest
 		}
 
 		// Validate dates
@@ -382,21 +579,50 @@ func HistoryHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.Han
 		if _, err := time.Parse(time.DateOnly, toDate); err != nil {
 			return api.WriteBadRequest(c, "Invalid 'to' date. Use YYYY-MM-DD format.")
 		}
+		query, err := api.ParseQuery(c, myBookingsQueryOptions)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
+		include, err := api.ParseInclude(c, bookingIncludePaths)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
 
 		ctx := c.Request().Context()
 		records, err := ListUserBookingsRange(ctx, store, user.ID, fromDate, toDate)
 		if err != nil {
 			return fmt.Errorf("list booking history: %w", err)
 		}
+		if pastOnly {
+			records = filterByLocalToday(cfg, records, now, false)
+		}
 
-		return writeBookingsCollection(ctx, c, getConfig(), store, user.ID, records)
+		return writeBookingsCollection(ctx, c, cfg, store, user, records, query, include)
+	}
+}
+
+// filterByLocalToday keeps the bookings from the current date on (upcoming) or
+// before it (past), where the current date is taken in the time zone of each
+// booked item's area.
+func filterByLocalToday(cfg *areas.Config, records []BookingRecord, now time.Time, upcoming bool) []BookingRecord {
+	kept := records[:0]
+	for i := range records {
+		today := areas.LocalDate(now, cfg.ItemZone(records[i].ItemID))
+		if (records[i].BookingDate >= today) == upcoming {
+			kept = append(kept, records[i])
+		}
 	}
+	return kept
 }
 
 func writeBookingsCollection(
 	ctx context.Context, c echo.Context, cfg *areas.Config, store *sql.DB,
-	currentUserID string, records []BookingRecord,
+	user *auth.User, records []BookingRecord, query *api.Query, include api.Include,
 ) error {
+	// The user booked, or was booked, every listed booking.
+	currentUserID := user.ID
+	fieldAccess := FieldAccess(user, user.ID, user.ID)
+
 	// Collect unique user IDs for display name lookup. This includes the booker
 	// (BookedByUserID) as well as the colleague a booking was made FOR (UserID),
 	// so on-behalf-by-me bookings can surface the colleague's name (FR168).
@@ -429,17 +655,19 @@ func writeBookingsCollection(
 			continue
 		}
 
+		attrs := buildMyBookingAttributes(rec, loc, currentUserID, displayNames)
+		attrs.Fields = cfg.VisibleFieldValues(rec.ItemID, rec.Fields, fieldAccess)
 		resources = append(resources, api.Resource{
-			Type:       resourceTypeBooking,
-			ID:         rec.ID,
-			Attributes: buildMyBookingAttributes(rec, loc, currentUserID, displayNames),
+			Type:          resourceTypeBooking,
+			ID:            rec.ID,
+			Attributes:    attrs,
+			Relationships: bookingRelationships(rec),
 		})
 	}
 
-	resp := api.CollectionResponse{Data: resources}
-	c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
-	//nolint:wrapcheck // Terminal response
-	return c.JSON(http.StatusOK, resp)
+	return api.WriteCompoundCollection(c, query, resources, func(page []api.Resource) []api.Resource {
+		return bookingIncludes(ctx, store, cfg, include, page)
+	}, "write bookings response")
 }
 
 // buildMyBookingAttributes maps a booking record and its resolved item location into the
@@ -451,15 +679,20 @@ func buildMyBookingAttributes(
 	currentUserID string, displayNames map[string]string,
 ) MyBookingAttributes {
 	attrs := MyBookingAttributes{
-		ItemID:        rec.ItemID,
-		ItemName:      loc.Item.Name,
-		ItemGroupID:   loc.ItemGroup.ID,
-		ItemGroupName: loc.ItemGroup.Name,
-		AreaID:        loc.Area.ID,
-		AreaName:      loc.Area.Name,
-		BookingDate:   rec.BookingDate,
-		CreatedAt:     rec.CreatedAt,
-		Note:          rec.Note,
+		ItemID:              rec.ItemID,
+		ItemName:            loc.Item.Name,
+		ItemGroupID:         loc.ItemGroup.ID,
+		ItemGroupName:       loc.ItemGroup.Name,
+		AreaID:              loc.Area.ID,
+		AreaName:            loc.Area.Name,
+		BookingDate:         rec.BookingDate,
+		CreatedAt:           rec.CreatedAt,
+		Note:                rec.Note,
+		CheckedInAt:         rec.CheckedInAt,
+		PendingConfirmation: rec.PendingConfirmation,
+		BundleID:            rec.BundleID,
+		Private:             rec.IsPrivate,
+		Version:             rec.Version,
 	}
 
 	// Include booked_by info if different from user_id
@@ -499,9 +732,10 @@ func CreateHandler(cfg *areas.Config, store *sql.DB, notifier notifications.Noti
 }
 
 // CreateHandlerDynamic returns a handler for creating bookings using dynamic config.
+// Guards are evaluated after reservation and limit checks and may veto the booking.
 func CreateHandlerDynamic(
 	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
-	limits *BookingLimits,
+	limits *BookingLimits, guards ...Guard,
 ) echo.HandlerFunc {
 	maxWeeks := 0
 	if limits != nil {
@@ -514,7 +748,8 @@ func CreateHandlerDynamic(
 			return api.WriteUnauthorized(c)
 		}
 
-		req, itemID, dates, err := parseAndValidateBooking(c, maxWeeks)
+		cfg := getConfig()
+		req, itemID, dates, err := parseAndValidateBooking(c, cfg, maxWeeks)
 		if err != nil || c.Response().Committed {
 			return err
 		}
@@ -525,35 +760,49 @@ func CreateHandlerDynamic(
 				fmt.Sprintf("Note must be at most %d characters", maxNoteLength)))
 		}
 
-		cfg := getConfig()
 		loc, exists := cfg.FindItemLocation(itemID)
 		if !exists {
 			return api.WriteNotFound(c, "Item not found")
 		}
+		fields, err := resolveFields(loc, nil, req.Data.Attributes.Fields, user)
+		if err != nil {
+			return handleValidationError(c, err)
+		}
 
-		params, err := resolveBookingParticipants(c.Request().Context(), store, user, req)
+		params, err := resolveBookingParticipants(c.Request().Context(), store, user, req, limits.confirmsOnBehalf())
 		if err != nil {
 			return handleValidationError(c, err)
 		}
+		params.private = req.Data.Attributes.Private
 
 		// Reservation access applies to the eventual booking target, not just the acting user.
 		if err := handleReservation(c, store, params, loc); err != nil || c.Response().Committed {
 			return err
 		}
 
-		if err := handleBookingLimits(c, store, params, loc, limits); err != nil || c.Response().Committed {
+		rules := PolicyRules(cfg, loc, limits)
+		now := time.Now().In(cfg.AreaZone(loc.Area))
+		err = handleBookingPolicies(c, store, params, loc, rules, dates, now)
+		if err != nil || c.Response().Committed {
+			return err
+		}
+
+		guardReq := &GuardRequest{
+			Location:       loc,
+			UserID:         params.targetUserID,
+			BookedByUserID: params.bookedByUserID,
+			IsGuest:        params.isGuest,
+			Dates:          dates,
+		}
+		if err := handleGuards(c, guards, guardReq); err != nil || c.Response().Committed {
 			return err
 		}
 
 		if len(dates) == 1 {
-			return processBooking(
-				c, store, notifier, itemID, params.targetUserID,
-				params.bookedByUserID, dates[0], note,
-				params.isGuest, params.guestName, params.guestEmail,
-			)
+			return processBooking(c, store, notifier, itemID, params, dates[0], note, fields)
 		}
 
-		return processMultiDayBooking(c, store, notifier, itemID, params, dates, note)
+		return processMultiDayBooking(c, store, notifier, itemID, params, dates, note, fields)
 	}
 }
 
@@ -629,18 +878,33 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
-// handleBookingLimits enforces booking limits and writes the error response if exceeded.
-// Returns nil when limits pass, the user is a guest, or a conflict response was written.
-func handleBookingLimits(
+// handleBookingPolicies evaluates the booking limits and policies and writes the
+// error response for the first violation. now is the current time in the time
+// zone of the item's area. Returns nil when all rules pass or a conflict
+// response was written.
+func handleBookingPolicies(
 	c echo.Context, store *sql.DB, params *bookingParticipants,
-	loc *areas.ItemLocation, limits *BookingLimits,
+	loc *areas.ItemLocation, rules []PolicyRule, dates []string, now time.Time,
 ) error {
+	ctx := c.Request().Context()
+	ev := &policyEvaluation{
+		store:   store,
+		loc:     loc,
+		subject: policySubject{userID: params.targetUserID, isGuest: params.isGuest},
+		dates:   dates,
+		now:     now,
+	}
 	if params.isGuest {
-		return nil
+		ev.subject.userID = params.bookedByUserID
+	} else if needsMembership(rules) {
+		email, err := lookupPolicyEmail(ctx, store, params.targetUserID)
+		if err != nil {
+			return fmt.Errorf("check booking policies: %w", err)
+		}
+		ev.subject.email = email
 	}
-	err := enforceBookingLimits(
-		c.Request().Context(), store, params.targetUserID, loc, limits,
-	)
+
+	err := evaluatePolicies(ctx, ev, rules)
 	if err == nil {
 		return nil
 	}
@@ -649,7 +913,13 @@ func handleBookingLimits(
 		api.WriteConflict(c, err.Error())
 		return nil
 	}
-	return fmt.Errorf("check booking limits: %w", err)
+	var rej *Rejection
+	if errors.As(err, &rej) {
+		//nolint:errcheck // Response signals via Committed
+		api.WriteError(c, rej.Status, rej.Detail, rej.Code)
+		return nil
+	}
+	return fmt.Errorf("check booking policies: %w", err)
 }
 
 // bookingParticipants holds the resolved user info for a booking.
@@ -659,12 +929,18 @@ type bookingParticipants struct {
 	isGuest        bool
 	guestName      string
 	guestEmail     string
+	guest          *guestVisit
+	// pending is set when the booking waits for the target's confirmation.
+	pending bool
+	// private is set when the booker asked for a private booking.
+	private bool
 }
 
 // resolveBookingParticipants determines the target user and booker for a booking.
-// When for_user_id is provided, it validates that the target user exists in the database.
+// When for_user_id is provided, it validates that the target user exists in the
+// database and that the user may book for them (see authorizeOnBehalf).
 func resolveBookingParticipants(
-	ctx context.Context, store *sql.DB, user *auth.User, req *CreateRequest,
+	ctx context.Context, store *sql.DB, user *auth.User, req *CreateRequest, confirm bool,
 ) (*bookingParticipants, error) {
 	params := &bookingParticipants{
 		targetUserID:   user.ID,
@@ -673,26 +949,29 @@ func resolveBookingParticipants(
 
 	// Handle guest booking
 	if req.Data.Attributes.IsGuest {
-		guestName := strings.TrimSpace(req.Data.Attributes.ForUserName)
-		guestEmail := strings.TrimSpace(req.Data.Attributes.GuestEmail)
-		if guestName == "" {
-			return nil, errBadRequest("for_user_name (guest name) is required for guest bookings")
+		guest, err := resolveGuest(ctx, store, user, req)
+		if err != nil {
+			return nil, err
 		}
-		// Generate a unique guest ID
-		params.targetUserID = "guest-" + uuid.New().String()[:8]
+		params.targetUserID = guest.visitor.ID
 		params.isGuest = true
-		params.guestName = guestName
-		params.guestEmail = guestEmail
+		params.guestName = guest.visitor.Name
+		params.guestEmail = guest.visitor.Email
+		params.guest = guest
 		return params, nil
 	}
 
 	// Handle booking on behalf of another user
 	forUserID := strings.TrimSpace(req.Data.Attributes.ForUserID)
 	if forUserID != "" {
-		if _, err := users.FindByID(ctx, store, forUserID); err != nil {
+		target, err := users.FindByID(ctx, store, forUserID)
+		if err != nil {
 			return nil, errBadRequest("for_user_id: user not found")
 		}
 		params.targetUserID = forUserID
+		if params.pending, err = authorizeOnBehalf(ctx, store, user, target, confirm); err != nil {
+			return nil, err
+		}
 	}
 
 	return params, nil
@@ -702,7 +981,7 @@ func resolveBookingParticipants(
 // Returns the parsed request, item ID, and dates. On validation failure, the error
 // response is written and c.Response().Committed is true.
 func parseAndValidateBooking(
-	c echo.Context, maxWeeks int,
+	c echo.Context, cfg *areas.Config, maxWeeks int,
 ) (req *CreateRequest, itemID string, dates []string, err error) {
 	if err = validateContentType(c); err != nil {
 		if errors.Is(err, errResponseWritten) {
@@ -716,7 +995,7 @@ func parseAndValidateBooking(
 		return nil, "", nil, handleValidationError(c, err)
 	}
 
-	itemID, dates, err = validateRequestFieldsMultiDay(req, maxWeeks)
+	itemID, dates, err = validateRequestFieldsMultiDay(req, cfg, maxWeeks)
 	if err != nil {
 		return nil, "", nil, handleValidationError(c, err)
 	}
@@ -730,6 +1009,11 @@ func handleValidationError(c echo.Context, err error) error {
 		//nolint:wrapcheck // Terminal response, no wrapping needed
 		return api.WriteBadRequest(c, valErr.detail)
 	}
+	var rej *Rejection
+	if errors.As(err, &rej) {
+		//nolint:wrapcheck // Terminal response, no wrapping needed
+		return api.WriteError(c, rej.Status, rej.Detail, rej.Code)
+	}
 	return err
 }
 
@@ -755,7 +1039,7 @@ func parseCreateRequest(c echo.Context) (*CreateRequest, error) {
 }
 
 func validateRequestFieldsMultiDay(
-	req *CreateRequest, maxWeeks int,
+	req *CreateRequest, cfg *areas.Config, maxWeeks int,
 ) (itemID string, dates []string, err error) {
 	itemID = strings.TrimSpace(req.Data.Attributes.ItemID)
 	if itemID == "" {
@@ -777,16 +1061,22 @@ func validateRequestFieldsMultiDay(
 		return "", nil, errBadRequest("booking_date or booking_dates is required")
 	}
 
-	// Validate and dedupe dates
-	today := time.Now().UTC().Truncate(24 * time.Hour)
+	today := areas.CalendarDay(time.Now().In(cfg.ItemZone(itemID)))
+	validDates, err := validateBookingDates(allDates, maxWeeks, today)
+	if err != nil {
+		return "", nil, err
+	}
+	return itemID, validDates, nil
+}
+
+// validateBookingDates checks the format and booking horizon of the requested
+// dates and removes duplicates, keeping the request order. today is the
+// current calendar day of the booked items' area (see areas.CalendarDay).
+func validateBookingDates(allDates []string, maxWeeks int, today time.Time) ([]string, error) {
 	// Calculate the booking horizon: current week + maxWeeks additional weeks
 	var maxDate time.Time
 	if maxWeeks > 0 {
-		// End of the Nth week from today's week (Sunday of that week)
-		weekday := today.Weekday()
-		daysUntilMonday := (8 - int(weekday)) % 7
-		nextMonday := today.AddDate(0, 0, daysUntilMonday)
-		maxDate = nextMonday.AddDate(0, 0, maxWeeks*7)
+		maxDate = bookingHorizon(today, maxWeeks)
 	}
 
 	seen := make(map[string]struct{})
@@ -795,13 +1085,13 @@ func validateRequestFieldsMultiDay(
 	for _, dateStr := range allDates {
 		parsedDate, parseErr := time.Parse(time.DateOnly, dateStr)
 		if parseErr != nil {
-			return "", nil, errBadRequest("booking_date must be in YYYY-MM-DD format: " + dateStr)
+			return nil, errBadRequest("booking_date must be in YYYY-MM-DD format: " + dateStr)
 		}
 		if parsedDate.Before(today) {
-			return "", nil, errBadRequest("booking_date cannot be in the past: " + dateStr)
+			return nil, errBadRequest("booking_date cannot be in the past: " + dateStr)
 		}
 		if maxWeeks > 0 && !parsedDate.Before(maxDate) {
-			return "", nil, errBadRequest(fmt.Sprintf(
+			return nil, errBadRequest(fmt.Sprintf(
 				"booking_date is too far in the future (maximum %d weeks in advance): %s",
 				maxWeeks, dateStr,
 			))
@@ -812,7 +1102,7 @@ func validateRequestFieldsMultiDay(
 		}
 	}
 
-	return itemID, validDates, nil
+	return validDates, nil
 }
 
 // validationError is a sentinel for validation errors that need WriteBadRequest.
@@ -826,61 +1116,18 @@ func errBadRequest(detail string) error {
 	return validationError{detail: detail}
 }
 
-// enforceBookingLimits checks all applicable booking limits for a user.
-// It checks at item, item group, area, and global scope.
-// Returns ErrBookingLimitExceeded (wrapped with a descriptive message) if any limit is reached.
-func enforceBookingLimits(
-	ctx context.Context, store *sql.DB, userID string,
-	loc *areas.ItemLocation, limits *BookingLimits,
-) error {
-	if limits == nil {
-		return nil
-	}
-
-	// Check item-level limit (most specific)
-	if err := checkBookingLimit(
-		ctx, store, userID, loc.Item.MaxBookingsPerPerson,
-		[]string{loc.Item.ID},
-		fmt.Sprintf("'%s, %s'", loc.ItemGroup.Name, loc.Item.Name),
-	); err != nil {
-		return err
-	}
-
-	// Check item group level limit
-	if err := checkBookingLimit(
-		ctx, store, userID, loc.ItemGroup.MaxBookingsPerPerson,
-		collectItemIDs(loc.ItemGroup.Items),
-		fmt.Sprintf("'%s'", loc.ItemGroup.Name),
-	); err != nil {
-		return err
-	}
-
-	// Check area-level limit
-	if err := checkBookingLimit(
-		ctx, store, userID, loc.Area.MaxBookingsPerPerson,
-		collectAreaItemIDs(loc.Area),
-		fmt.Sprintf("'%s'", loc.Area.Name),
-	); err != nil {
-		return err
-	}
-
-	// Check global limit
-	return checkBookingLimit(
-		ctx, store, userID, limits.MaxBookingsPerPerson, nil, "",
-	)
-}
-
 // checkBookingLimit verifies that a user has not reached the given limit
-// for the specified item IDs. A limit of 0 means unlimited (no check).
+// for the specified item IDs, counting bookings from today (YYYY-MM-DD) on.
+// A limit of 0 means unlimited (no check).
 // When scopeLabel is empty, the error message omits the scope.
 func checkBookingLimit(
 	ctx context.Context, store *sql.DB, userID string,
-	limit int, itemIDs []string, scopeLabel string,
+	limit int, itemIDs []string, scopeLabel, today string,
 ) error {
 	if limit <= 0 {
 		return nil
 	}
-	count, err := CountUserFutureBookings(ctx, store, userID, itemIDs)
+	count, err := CountUserFutureBookings(ctx, store, userID, itemIDs, today)
 	if err != nil {
 		return err
 	}
@@ -925,12 +1172,12 @@ var errResponseWritten = errors.New("response already written")
 
 func processBooking(
 	c echo.Context, store *sql.DB, notifier notifications.Notifier,
-	itemID, userID, bookedByUserID, bookingDate, note string,
-	isGuest bool, guestName, guestEmail string,
+	itemID string, params *bookingParticipants, bookingDate, note string, fields map[string]any,
 ) error {
 	ctx := c.Request().Context()
+	userID, bookedByUserID, isGuest := params.targetUserID, params.bookedByUserID, params.isGuest
 
-	// Skip duplicate check for guests (they have unique IDs)
+	// Skip duplicate check for guests (the item is booked at most once per day anyway)
 	if !isGuest {
 		existingBookingID, err := FindUserBooking(ctx, store, itemID, userID, bookingDate)
 		if err != nil {
@@ -946,11 +1193,7 @@ func processBooking(
 		}
 	}
 
-	booking, err := CreateBooking(
-		ctx, store, itemID, userID,
-		bookedByUserID, bookingDate, note,
-		isGuest, guestName, guestEmail,
-	)
+	booking, err := createParticipantBooking(ctx, store, itemID, params, bookingDate, note, fields)
 	if err != nil {
 		if errors.Is(err, ErrConflict) {
 			slog.Warn("booking conflict",
@@ -978,9 +1221,10 @@ func processBooking(
 		logFields = append(logFields, "is_guest", true)
 	}
 	slog.Info("booking created", logFields...)
+	params.guest.record(ctx, store, bookingDate)
 
 	// Send notification asynchronously
-	sendBookingCreatedNotification(notifier, booking)
+	NotifyBookingCreated(notifier, booking)
 
 	return writeBookingResponse(c, booking)
 }
@@ -989,7 +1233,7 @@ func processBooking(
 // Returns created bookings and reports conflicts per day.
 func processMultiDayBooking(
 	c echo.Context, store *sql.DB, notifier notifications.Notifier,
-	itemID string, params *bookingParticipants, dates []string, note string,
+	itemID string, params *bookingParticipants, dates []string, note string, fields map[string]any,
 ) error {
 	ctx := c.Request().Context()
 
@@ -997,7 +1241,7 @@ func processMultiDayBooking(
 	var conflicts []string
 
 	for _, bookingDate := range dates {
-		// Skip duplicate check for guests (they have unique IDs)
+		// Skip duplicate check for guests (the item is booked at most once per day anyway)
 		if !params.isGuest {
 			existingBookingID, err := FindUserBooking(
 				ctx, store, itemID, params.targetUserID, bookingDate,
@@ -1011,11 +1255,7 @@ func processMultiDayBooking(
 			}
 		}
 
-		booking, err := CreateBooking(
-			ctx, store, itemID, params.targetUserID,
-			params.bookedByUserID, bookingDate, note,
-			params.isGuest, params.guestName, params.guestEmail,
-		)
+		booking, err := createParticipantBooking(ctx, store, itemID, params, bookingDate, note, fields)
 		if err != nil {
 			if errors.Is(err, ErrConflict) {
 				conflicts = append(conflicts, bookingDate+": item already booked")
@@ -1030,9 +1270,10 @@ func processMultiDayBooking(
 			"user_id", params.targetUserID,
 			"booking_date", bookingDate,
 		)
+		params.guest.record(ctx, store, bookingDate)
 
 		// Send notification asynchronously
-		sendBookingCreatedNotification(notifier, booking)
+		NotifyBookingCreated(notifier, booking)
 
 		attrs := BookingAttributes{
 			ItemID:      booking.ItemID,
@@ -1040,6 +1281,8 @@ func processMultiDayBooking(
 			BookingDate: booking.BookingDate,
 			CreatedAt:   booking.CreatedAt,
 			Note:        booking.Note,
+			Fields:      booking.Fields,
+			Version:     1,
 		}
 		if booking.BookedByUserID != "" && booking.BookedByUserID != booking.UserID {
 			attrs.BookedByUserID = booking.BookedByUserID
@@ -1048,6 +1291,8 @@ func processMultiDayBooking(
 			attrs.IsGuest = true
 			attrs.GuestEmail = booking.GuestEmail
 		}
+		attrs.PendingConfirmation = booking.PendingConfirmation
+		attrs.Private = booking.IsPrivate
 
 		created = append(created, api.Resource{
 			Type:       resourceTypeBooking,
@@ -1074,6 +1319,8 @@ func writeBookingResponse(c echo.Context, booking *Booking) error {
 		BookingDate: booking.BookingDate,
 		CreatedAt:   booking.CreatedAt,
 		Note:        booking.Note,
+		Fields:      booking.Fields,
+		Version:     1,
 	}
 	// Include booked_by info if booking was made on behalf
 	if booking.BookedByUserID != "" && booking.BookedByUserID != booking.UserID {
@@ -1084,6 +1331,8 @@ func writeBookingResponse(c echo.Context, booking *Booking) error {
 		attrs.IsGuest = true
 		attrs.GuestEmail = booking.GuestEmail
 	}
+	attrs.PendingConfirmation = booking.PendingConfirmation
+	attrs.Private = booking.IsPrivate
 
 	resp := api.SingleResponse{
 		Data: api.Resource{
@@ -1093,6 +1342,8 @@ func writeBookingResponse(c echo.Context, booking *Booking) error {
 		},
 	}
 
+	// New bookings start at version 1
+	api.SetETag(c, 1)
 	c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
 	//nolint:wrapcheck // Terminal response, no wrapping needed
 	return c.JSON(http.StatusCreated, resp)
@@ -1111,6 +1362,16 @@ type Booking struct {
 	Note           string
 	CreatedAt      string
 	UpdatedAt      string
+	// PendingConfirmation is set for bookings made outside a delegation that
+	// wait for the booked user's confirmation.
+	PendingConfirmation bool
+	// Fields holds the custom field values keyed by field ID.
+	Fields map[string]any
+	// BundleID links bookings created together in a bundle.
+	BundleID string
+	// IsPrivate hides the booked user from other users. insertBooking sets it
+	// for users who book privately by default.
+	IsPrivate bool
 }
 
 // ErrConflict indicates a booking conflict (item already booked).
@@ -1140,46 +1401,100 @@ func CreateBooking(
 	isGuest bool, guestName, guestEmail string,
 ) (*Booking, error) {
 	now := time.Now().UTC().Format(time.RFC3339)
-	id := uuid.New().String()
+	booking := &Booking{
+		ID:             uuid.New().String(),
+		ItemID:         itemID,
+		UserID:         userID,
+		BookedByUserID: bookedByUserID,
+		BookingDate:    bookingDate,
+		IsGuest:        isGuest,
+		GuestName:      guestName,
+		GuestEmail:     guestEmail,
+		Note:           note,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := insertBooking(ctx, store, booking); err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
 
+// createParticipantBooking inserts the booking described by params.
+func createParticipantBooking(
+	ctx context.Context, store *sql.DB, itemID string, params *bookingParticipants,
+	bookingDate, note string, fields map[string]any,
+) (*Booking, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	booking := &Booking{
+		ID:                  uuid.New().String(),
+		ItemID:              itemID,
+		UserID:              params.targetUserID,
+		BookedByUserID:      params.bookedByUserID,
+		BookingDate:         bookingDate,
+		IsGuest:             params.isGuest,
+		GuestName:           params.guestName,
+		GuestEmail:          params.guestEmail,
+		Note:                note,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+		PendingConfirmation: params.pending,
+		Fields:              fields,
+		IsPrivate:           params.private,
+	}
+	if err := insertBooking(ctx, store, booking); err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// execer is implemented by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertBooking writes b using db, which may be a transaction. The booking is
+// made private if its owner (the host of a guest) books privately by default.
+// Returns ErrConflict if the item is already booked on that date.
+func insertBooking(ctx context.Context, db execer, b *Booking) error {
 	isGuestInt := 0
-	if isGuest {
+	if b.IsGuest {
 		isGuestInt = 1
 	}
+	if !b.IsPrivate {
+		owner := b.UserID
+		if b.IsGuest {
+			owner = b.BookedByUserID
+		}
+		var err error
+		if b.IsPrivate, err = privateByDefault(ctx, db, owner); err != nil {
+			return err
+		}
+	}
 
-	_, err := store.ExecContext(ctx, `
+	_, err := db.ExecContext(ctx, `
 		INSERT INTO bookings
 		(id, item_id, user_id, booked_by_user_id, booking_date,
-		 is_guest, guest_name, guest_email, note, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		id, itemID, userID, bookedByUserID,
-		bookingDate, isGuestInt, guestName, guestEmail, note, now, now,
+		 is_guest, guest_name, guest_email, note, created_at, updated_at, pending_confirmation, custom_fields,
+		 bundle_id, is_private)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		b.ID, b.ItemID, b.UserID, b.BookedByUserID,
+		b.BookingDate, isGuestInt, b.GuestName, b.GuestEmail, b.Note, b.CreatedAt, b.UpdatedAt,
+		b.PendingConfirmation, encodeFields(b.Fields), b.BundleID, b.IsPrivate,
 	)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			return nil, ErrConflict
+			return ErrConflict
 		}
-		return nil, fmt.Errorf("insert booking: %w", err)
+		return fmt.Errorf("insert booking: %w", err)
 	}
-
-	return &Booking{
-		ID:             id,
-		ItemID:         itemID,
-		UserID:         userID,
-		BookedByUserID: bookedByUserID,
-		BookingDate:    bookingDate,
-		IsGuest:        isGuest,
-		GuestName:      guestName,
-		GuestEmail:     guestEmail,
-		Note:           note,
-		CreatedAt:      now,
-		UpdatedAt:      now,
-	}, nil
+	return nil
 }
 
-// sendBookingCreatedNotification sends an async notification for a created booking.
-func sendBookingCreatedNotification(notifier notifications.Notifier, booking *Booking) {
+// NotifyBookingCreated sends an async notification for a created booking.
+func NotifyBookingCreated(notifier notifications.Notifier, booking *Booking) {
 	event := notifications.BookingEvent{
 		Event:       notifications.EventBookingCreated,
 		BookingID:   booking.ID,
@@ -1196,5 +1511,9 @@ func sendBookingCreatedNotification(notifier notifications.Notifier, booking *Bo
 	if booking.BookedByUserID != "" && booking.BookedByUserID != booking.UserID {
 		event.BookedByUserID = booking.BookedByUserID
 	}
+	event.PendingConfirmation = booking.PendingConfirmation
+	event.Fields = booking.Fields
+	event.BundleID = booking.BundleID
+	event.Private = booking.IsPrivate
 	notifier.NotifyAsync(&event)
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -397,6 +398,7 @@ func TestListHandlerReturnsUserFutureBookings(t *testing.T) {
 	assert.Equal(t, "Room 1", attrs0["item_group_name"])
 	assert.Equal(t, "area-1", attrs0["area_id"])
 	assert.Equal(t, "Office", attrs0["area_name"])
+	assert.InDelta(t, 1, attrs0["version"], 0)
 
 	// Second booking should be tomorrow
 	attrs1, ok := resp.Data[1].Attributes.(map[string]interface{})
@@ -500,12 +502,13 @@ func TestDeleteHandlerUnauthorized(t *testing.T) {
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/bookings/booking-1", http.NoBody)
+	req.Header.Set("If-Match", "*")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("id")
 	c.SetParamValues("booking-1")
 
-	h := DeleteHandler(store, testNotifier())
+	h := DeleteHandler(testAreasConfig(), store, testNotifier())
 	require.NoError(t, h(c))
 
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
@@ -518,13 +521,14 @@ func TestDeleteHandlerNotFound(t *testing.T) {
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/bookings/nonexistent", http.NoBody)
+	req.Header.Set("If-Match", "*")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("id")
 	c.SetParamValues("nonexistent")
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := DeleteHandler(store, testNotifier())
+	h := DeleteHandler(testAreasConfig(), store, testNotifier())
 	require.NoError(t, h(c))
 
 	assert.Equal(t, http.StatusNotFound, rec.Code)
@@ -540,13 +544,14 @@ func TestDeleteHandlerOtherUsersBooking(t *testing.T) {
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/bookings/booking-1", http.NoBody)
+	req.Header.Set("If-Match", "*")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("id")
 	c.SetParamValues("booking-1")
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := DeleteHandler(store, testNotifier())
+	h := DeleteHandler(testAreasConfig(), store, testNotifier())
 	require.NoError(t, h(c))
 
 	// Should return 404 to not reveal booking existence
@@ -563,13 +568,14 @@ func TestDeleteHandlerSuccess(t *testing.T) {
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/bookings/booking-1", http.NoBody)
+	req.Header.Set("If-Match", "*")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("id")
 	c.SetParamValues("booking-1")
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := DeleteHandler(store, testNotifier())
+	h := DeleteHandler(testAreasConfig(), store, testNotifier())
 	require.NoError(t, h(c))
 
 	assert.Equal(t, http.StatusNoContent, rec.Code)
@@ -612,13 +618,14 @@ func TestDeleteHandlerAdminCancelCases(t *testing.T) {
 
 			e := echo.New()
 			req := httptest.NewRequest(http.MethodDelete, "/api/v1/bookings/booking-1", http.NoBody)
+			req.Header.Set("If-Match", "*")
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 			c.SetParamNames("id")
 			c.SetParamValues("booking-1")
 			c.Set("user", &auth.User{ID: "admin-user", Name: "Admin User", IsAdmin: true})
 
-			h := DeleteHandler(store, testNotifier())
+			h := DeleteHandler(testAreasConfig(), store, testNotifier())
 			require.NoError(t, h(c))
 
 			assert.Equal(t, http.StatusNoContent, rec.Code)
@@ -638,6 +645,7 @@ func TestCreateHandlerBookOnBehalf(t *testing.T) {
 	cfg := testAreasConfig()
 	store := setupTestStore(t)
 	seedTestUser(t, store, "colleague-1", "Colleague User")
+	seedDelegation(t, store, "colleague-1", "user-1")
 
 	futureDate := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
 	body := `{"data":{"type":"bookings","attributes":{` +
@@ -1039,13 +1047,14 @@ func TestDeleteHandlerOnBehalfBookingCancellation(t *testing.T) {
 
 			e := echo.New()
 			req := httptest.NewRequest(http.MethodDelete, "/api/v1/bookings/booking-1", http.NoBody)
+			req.Header.Set("If-Match", "*")
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 			c.SetParamNames("id")
 			c.SetParamValues("booking-1")
 			c.Set("user", tc.cancelingUser)
 
-			h := DeleteHandler(store, testNotifier())
+			h := DeleteHandler(testAreasConfig(), store, testNotifier())
 			require.NoError(t, h(c))
 
 			assert.Equal(t, http.StatusNoContent, rec.Code)
@@ -1070,6 +1079,7 @@ func TestDeleteHandlerUnrelatedUserCannotCancel(t *testing.T) {
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/bookings/booking-1", http.NoBody)
+	req.Header.Set("If-Match", "*")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("id")
@@ -1077,7 +1087,7 @@ func TestDeleteHandlerUnrelatedUserCannotCancel(t *testing.T) {
 	// Unrelated user trying to cancel
 	c.Set("user", &auth.User{ID: "random-user", Name: "Random"})
 
-	h := DeleteHandler(store, testNotifier())
+	h := DeleteHandler(testAreasConfig(), store, testNotifier())
 	require.NoError(t, h(c))
 
 	// Should return 404 to not reveal booking existence
@@ -1091,12 +1101,13 @@ func TestPatchHandlerUnauthorized(t *testing.T) {
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/bookings/booking-1", http.NoBody)
+	req.Header.Set("If-Match", "*")
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 	c.SetParamNames("id")
 	c.SetParamValues("booking-1")
 
-	h := PatchHandler(store)
+	h := PatchHandler(testAreasConfig(), store)
 	require.NoError(t, h(c))
 
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
@@ -1111,6 +1122,7 @@ func TestPatchHandlerNotFound(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/bookings/nonexistent",
 		bytes.NewBufferString(body))
+	req.Header.Set("If-Match", "*")
 	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -1118,7 +1130,7 @@ func TestPatchHandlerNotFound(t *testing.T) {
 	c.SetParamValues("nonexistent")
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := PatchHandler(store)
+	h := PatchHandler(testAreasConfig(), store)
 	require.NoError(t, h(c))
 
 	assert.Equal(t, http.StatusNotFound, rec.Code)
@@ -1135,6 +1147,7 @@ func TestPatchHandlerSuccess(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/bookings/booking-1",
 		bytes.NewBufferString(body))
+	req.Header.Set("If-Match", "*")
 	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -1142,7 +1155,7 @@ func TestPatchHandlerSuccess(t *testing.T) {
 	c.SetParamValues("booking-1")
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := PatchHandler(store)
+	h := PatchHandler(testAreasConfig(), store)
 	require.NoError(t, h(c))
 
 	assert.Equal(t, http.StatusOK, rec.Code)
@@ -1176,6 +1189,7 @@ func TestPatchHandlerNoteXSSEscaped(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/bookings/booking-1",
 		bytes.NewBufferString(body))
+	req.Header.Set("If-Match", "*")
 	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -1183,7 +1197,7 @@ func TestPatchHandlerNoteXSSEscaped(t *testing.T) {
 	c.SetParamValues("booking-1")
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := PatchHandler(store)
+	h := PatchHandler(testAreasConfig(), store)
 	require.NoError(t, h(c))
 	assert.Equal(t, http.StatusOK, rec.Code)
 
@@ -1221,6 +1235,7 @@ func TestPatchHandlerNoteTooLong(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/bookings/booking-1",
 		bytes.NewBufferString(body))
+	req.Header.Set("If-Match", "*")
 	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -1228,7 +1243,7 @@ func TestPatchHandlerNoteTooLong(t *testing.T) {
 	c.SetParamValues("booking-1")
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := PatchHandler(store)
+	h := PatchHandler(testAreasConfig(), store)
 	require.NoError(t, h(c))
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
@@ -1240,6 +1255,7 @@ func runPatchRequest(t *testing.T, store *sql.DB, bookingID, body string) *httpt
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/bookings/"+bookingID,
 		bytes.NewBufferString(body))
+	req.Header.Set("If-Match", "*")
 	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -1247,7 +1263,7 @@ func runPatchRequest(t *testing.T, store *sql.DB, bookingID, body string) *httpt
 	c.SetParamValues(bookingID)
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := PatchHandler(store)
+	h := PatchHandler(testAreasConfig(), store)
 	require.NoError(t, h(c))
 
 	return rec
@@ -1332,6 +1348,7 @@ func TestPatchHandlerAuthorizationCases(t *testing.T) {
 			e := echo.New()
 			req := httptest.NewRequest(http.MethodPatch, "/api/v1/bookings/booking-1",
 				bytes.NewBufferString(body))
+			req.Header.Set("If-Match", "*")
 			req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
@@ -1339,7 +1356,7 @@ func TestPatchHandlerAuthorizationCases(t *testing.T) {
 			c.SetParamValues("booking-1")
 			c.Set("user", tc.user)
 
-			h := PatchHandler(store)
+			h := PatchHandler(testAreasConfig(), store)
 			require.NoError(t, h(c))
 
 			assert.Equal(t, tc.expectedStatus, rec.Code)
@@ -1356,13 +1373,14 @@ func TestPatchHandlerClearNote(t *testing.T) {
 
 	// First set a note
 	ctx := context.Background()
-	require.NoError(t, UpdateNote(ctx, store, "booking-1", "initial note"))
+	require.NoError(t, UpdateDetails(ctx, store, "booking-1", "initial note", nil, false, 0))
 
 	// Then clear it
 	body := `{"data":{"type":"bookings","id":"booking-1","attributes":{"note":""}}}`
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/bookings/booking-1",
 		bytes.NewBufferString(body))
+	req.Header.Set("If-Match", "*")
 	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
@@ -1370,7 +1388,7 @@ func TestPatchHandlerClearNote(t *testing.T) {
 	c.SetParamValues("booking-1")
 	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
 
-	h := PatchHandler(store)
+	h := PatchHandler(testAreasConfig(), store)
 	require.NoError(t, h(c))
 
 	assert.Equal(t, http.StatusOK, rec.Code)
@@ -1666,3 +1684,117 @@ func TestCreateHandlerReservationUsesBookingTarget(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
+
+type rejectingGuard struct{}
+
+func (rejectingGuard) CheckBooking(_ context.Context, req *GuardRequest) error {
+	return &Rejection{Status: http.StatusConflict, Code: "area_closed", Detail: "closed on " + req.Dates[0]}
+}
+
+type failingGuard struct{}
+
+func (failingGuard) CheckBooking(context.Context, *GuardRequest) error {
+	return errors.New("closures unavailable")
+}
+
+func TestPassesGuards(t *testing.T) {
+	t.Parallel()
+
+	req := &GuardRequest{Dates: []string{"2026-03-02"}}
+	ok, err := PassesGuards(t.Context(), []Guard{nil}, req)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = PassesGuards(t.Context(), []Guard{rejectingGuard{}, failingGuard{}}, req)
+	require.NoError(t, err)
+	assert.False(t, ok, "a rejection is not an error")
+
+	_, err = PassesGuards(t.Context(), []Guard{failingGuard{}}, req)
+	require.Error(t, err)
+}
+
+func TestCreateHandlerGuardRejection(t *testing.T) {
+	t.Parallel()
+
+	cfg := testAreasConfigWithLimits()
+	store := setupTestStore(t)
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	body := `{"data":{"type":"bookings","attributes":{"item_id":"desk-1","booking_date":"` + tomorrow + `"}}}`
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
+
+	limits := &BookingLimits{WeeksInAdvanced: 52}
+	h := CreateHandlerDynamic(func() *areas.Config { return cfg }, store, testNotifier(), limits, rejectingGuard{})
+	require.NoError(t, h(c))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var resp api.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "area_closed", resp.Errors[0].Code)
+	assert.Equal(t, "closed on "+tomorrow, resp.Errors[0].Detail)
+
+	bookingID, err := FindUserBooking(t.Context(), store, "desk-1", "user-1", tomorrow)
+	require.NoError(t, err)
+	assert.Empty(t, bookingID)
+}
+
+func TestPatchAndDeleteHandlerCheckIfMatch(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedTestBooking(t, store, "booking-1", "desk-1", "user-1", tomorrow)
+
+	send := func(method, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/bookings/booking-1", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("booking-1")
+		c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
+		if method == http.MethodDelete {
+			require.NoError(t, DeleteHandler(testAreasConfig(), store, testNotifier())(c))
+		} else {
+			require.NoError(t, PatchHandler(testAreasConfig(), store)(c))
+		}
+		return rec
+	}
+	body := `{"data":{"type":"bookings","id":"booking-1","attributes":{"note":"First"}}}`
+
+	rec := send(http.MethodPatch, body, `"1"`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	assert.Contains(t, rec.Body.String(), `"version":2`)
+
+	rec = send(http.MethodPatch, body, `W/"2"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code, "weak ETag")
+	rec = send(http.MethodPatch, body, `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code, "stale version")
+	rec = send(http.MethodDelete, "", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code, "stale version")
+	rec = send(http.MethodDelete, "", "garbage")
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code, "malformed ETag")
+
+	booking, err := FindBookingByID(context.Background(), store, "booking-1")
+	require.NoError(t, err)
+	require.NotNil(t, booking, "412 must not delete the booking")
+	assert.Equal(t, 2, booking.Version)
+
+	rec = send(http.MethodPatch, body, "")
+	require.Equal(t, http.StatusOK, rec.Code, "no If-Match applies unconditionally")
+	assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+	rec = send(http.MethodDelete, "", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code, "no If-Match applies unconditionally")
+}
@@ -0,0 +1,88 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const resourceTypeUser = "users"
+
+// bookingIncludePaths lists the include paths of the booking collections.
+var bookingIncludePaths = []string{"item", "item.item_group", "item.item_group.area", "user", "booked_by"}
+
+// bookingRelationships returns the item, user, and booker relationships of a
+// booking. Guests have no user account, so their user relationship is empty.
+func bookingRelationships(rec *BookingRecord) map[string]api.Relationship {
+	user := api.ToOne(resourceTypeUser, rec.UserID)
+	if rec.IsGuest {
+		user = api.Relationship{}
+	}
+	return map[string]api.Relationship{
+		"item":      api.ToOne(areas.ResourceTypeItem, rec.ItemID),
+		"user":      user,
+		"booked_by": api.ToOne(resourceTypeUser, rec.BookedByUserID),
+	}
+}
+
+// bookingIncludes returns the resources related to the bookings of a page for
+// the requested include paths. Users are included with their display name only.
+func bookingIncludes(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, include api.Include, page []api.Resource,
+) []api.Resource {
+	var included api.Included
+	if include["item"] {
+		includeItems(&included, cfg, include, page)
+	}
+
+	var userIDs []string
+	for _, name := range []string{"user", "booked_by"} {
+		if !include[name] {
+			continue
+		}
+		for i := range page {
+			if rel := page[i].Relationships[name].Data; rel != nil {
+				userIDs = append(userIDs, rel.ID)
+			}
+		}
+	}
+	if len(userIDs) > 0 {
+		names, err := users.FindDisplayNames(ctx, store, userIDs)
+		if err != nil {
+			slog.Warn("failed to look up display names", "error", err)
+		}
+		for _, id := range userIDs {
+			if name, ok := names[id]; ok {
+				included.Add(api.Resource{
+					Type: resourceTypeUser, ID: id, Attributes: users.ColleagueAttributes{DisplayName: name},
+				})
+			}
+		}
+	}
+	return included.Resources()
+}
+
+// includeItems adds the booked items and, if requested, their item groups and areas.
+func includeItems(included *api.Included, cfg *areas.Config, include api.Include, page []api.Resource) {
+	for i := range page {
+		rel := page[i].Relationships["item"].Data
+		if rel == nil {
+			continue
+		}
+		loc, ok := cfg.FindItemLocation(rel.ID)
+		if !ok {
+			continue
+		}
+		included.Add(areas.ItemResource(loc))
+		if include["item.item_group"] {
+			included.Add(areas.ItemGroupResource(loc.ItemGroup, loc.Area.ID))
+		}
+		if include["item.item_group.area"] {
+			included.Add(areas.AreaResource(loc.Area))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package bookings
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+func includedIDs(resources []api.Resource) []string {
+	ids := make([]string, len(resources))
+	for i, r := range resources {
+		ids[i] = r.Type + "/" + r.ID
+	}
+	return ids
+}
+
+func TestListHandlerIncludesRelatedResources(t *testing.T) {
+	t.Parallel()
+	store := setupTestStore(t)
+	seedTestUser(t, store, "user-1", "Test User")
+	seedTestUser(t, store, "lead-1", "Team Lead")
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	dayAfter := time.Now().UTC().AddDate(0, 0, 2).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-1", "user-1", tomorrow)
+	seedTestBookingFull(t, store, "b2", "desk-1", "user-1", "lead-1", dayAfter)
+
+	list := func(query string) (*httptest.ResponseRecorder, api.CollectionResponse) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/bookings?"+query, http.NoBody)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
+		require.NoError(t, ListHandler(testAreasConfig(), store)(c))
+		var resp api.CollectionResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec, resp
+	}
+
+	_, resp := list("")
+	require.Len(t, resp.Data, 2)
+	assert.Empty(t, resp.Included)
+	rels := resp.Data[1].Relationships
+	assert.Equal(t, &api.ResourceIdentifier{Type: "items", ID: "desk-1"}, rels["item"].Data)
+	assert.Equal(t, &api.ResourceIdentifier{Type: "users", ID: "user-1"}, rels["user"].Data)
+	assert.Equal(t, &api.ResourceIdentifier{Type: "users", ID: "lead-1"}, rels["booked_by"].Data)
+
+	_, resp = list("include=item.item_group.area,booked_by")
+	assert.Equal(t, []string{"items/desk-1", "item-groups/room-1", "areas/area-1", "users/user-1", "users/lead-1"},
+		includedIDs(resp.Included))
+	group := resp.Included[1]
+	assert.Equal(t, &api.ResourceIdentifier{Type: "areas", ID: "area-1"}, group.Relationships["area"].Data)
+	lead, ok := resp.Included[4].Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "Team Lead", lead["display_name"])
+
+	_, resp = list("include=item&fields%5Bitems%5D=name")
+	require.Len(t, resp.Included, 1)
+	assert.Equal(t, map[string]any{"name": "Desk 1"}, resp.Included[0].Attributes)
+
+	rec, _ := list("include=item.area")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSearchHandlerIncludesUsers(t *testing.T) {
+	t.Parallel()
+	store := setupTestStore(t)
+	seedTestUser(t, store, "alice", "Alice")
+	seedTestBookingWithGuest(t, store, "b1", "desk-1", "guest-1", "alice", "2026-03-02", true, "Gina", "")
+
+	rec, resp := searchBookings(t, store, "include=user,booked_by")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, resp.Data, 1)
+	assert.Nil(t, resp.Data[0].Relationships["user"].Data, "guests have no user account")
+	assert.Equal(t, []string{"users/alice"}, includedIDs(resp.Included))
+}
@@ -42,7 +42,7 @@ func TestBookingFlowBroadcastsCreateAndCancelEventsWithActorIdentity(t *testing.
 	e.Use(middleware.LoadUser(svc))
 	e.GET("/api/v1/live", livefeed.Handler(hub), middleware.RequireAuth(svc))
 	e.POST("/api/v1/bookings", CreateHandler(cfg, store, notifier), middleware.RequireAuth(svc))
-	e.DELETE("/api/v1/bookings/:id", DeleteHandler(store, notifier), middleware.RequireAuth(svc))
+	e.DELETE("/api/v1/bookings/:id", DeleteHandler(testAreasConfig(), store, notifier), middleware.RequireAuth(svc))
 
 	srv := httptest.NewServer(e)
 	defer srv.Close()
@@ -123,6 +123,7 @@ func TestBookingFlowBroadcastsCreateAndCancelEventsWithActorIdentity(t *testing.
 		http.NoBody,
 	)
 	require.NoError(t, err)
+	deleteReq.Header.Set("If-Match", createResp.Header.Get("ETag"))
 	deleteReq.AddCookie(adminCookie)
 
 	deleteResp, err := srv.Client().Do(deleteReq)
@@ -0,0 +1,468 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+// ErrorCodePolicyViolation is the JSON:API error code used when a booking breaks
+// a declarative booking policy.
+const ErrorCodePolicyViolation = "policy_violation"
+
+// Policy scopes name the configuration level a rule was declared at.
+const (
+	PolicyScopeGlobal    = "global"
+	PolicyScopeArea      = "area"
+	PolicyScopeItemGroup = "item_group"
+	PolicyScopeItem      = "item"
+)
+
+// PolicyRule is a booking policy bound to the scope it was configured at.
+type PolicyRule struct {
+	areas.Policy
+	Scope     string
+	ScopeID   string
+	ScopeName string
+	// ItemIDs are the items whose bookings count against the rule; nil means all items.
+	ItemIDs []string
+	// Legacy marks rules derived from max_bookings_per_person and weeks_in_advanced.
+	// Their violations keep the historic "booking limit exceeded" wording.
+	Legacy bool
+	// listsMember reports whether an email is a reserved_for member of the scope.
+	listsMember func(email string) bool
+}
+
+// appliesTo reports whether the rule governs a booking by a user with the
+// given email, or a guest booking.
+func (r *PolicyRule) appliesTo(isGuest bool, email string) bool {
+	isMember := r.listsMember != nil && r.listsMember(email)
+	return r.Matches(isGuest, isMember)
+}
+
+// ScopeLabel returns a human-readable description of the rule's scope.
+func (r *PolicyRule) ScopeLabel() string {
+	switch r.Scope {
+	case PolicyScopeArea:
+		return fmt.Sprintf("area %q", r.ScopeName)
+	case PolicyScopeItemGroup:
+		return fmt.Sprintf("item group %q", r.ScopeName)
+	case PolicyScopeItem:
+		return fmt.Sprintf("item %q", r.ScopeName)
+	default:
+		return "all areas"
+	}
+}
+
+// Describe returns a sentence explaining the rule, suitable for display.
+func (r *PolicyRule) Describe() string {
+	var limits []string
+	if r.MaxActiveBookings > 0 {
+		limits = append(limits, fmt.Sprintf("at most %d active bookings", r.MaxActiveBookings))
+	}
+	if r.MaxDaysPerWeek > 0 {
+		limits = append(limits, fmt.Sprintf("at most %d days per week", r.MaxDaysPerWeek))
+	}
+	if r.MaxPerDay > 0 {
+		limits = append(limits, fmt.Sprintf("at most %d bookings per day", r.MaxPerDay))
+	}
+	if r.MaxWeeksAhead > 0 {
+		limits = append(limits, fmt.Sprintf("booking at most %d weeks in advance", r.MaxWeeksAhead))
+	}
+	if r.MinNoticeHours > 0 {
+		limits = append(limits, fmt.Sprintf("at least %d hours notice", r.MinNoticeHours))
+	}
+	return fmt.Sprintf("%s in %s%s: %s.",
+		audienceLabel(r.Audience()), r.ScopeLabel(), r.describeAttendance(), strings.Join(limits, ", "))
+}
+
+// describeAttendance explains the rule's attendance conditions, or returns "".
+func (r *PolicyRule) describeAttendance() string {
+	var conditions []string
+	if r.MinNoShows > 0 {
+		conditions = append(conditions, fmt.Sprintf("%d no-shows", r.MinNoShows))
+	}
+	if r.MinLateCancellations > 0 {
+		conditions = append(conditions, fmt.Sprintf("%d late cancellations", r.MinLateCancellations))
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" with at least %s in the last %d days",
+		strings.Join(conditions, " or "), r.AttendanceWindow())
+}
+
+func audienceLabel(audience string) string {
+	switch audience {
+	case areas.AppliesToUsers:
+		return "Bookings for users"
+	case areas.AppliesToGuests:
+		return "Guest bookings"
+	case areas.AppliesToMembers:
+		return "Bookings by reserved_for members"
+	case areas.AppliesToNonMembers:
+		return "Bookings by users outside reserved_for lists"
+	default:
+		return "All bookings"
+	}
+}
+
+// PolicyRules returns the rules that govern bookings at loc, most specific scope
+// first: the legacy max_bookings_per_person limits, the global weeks_in_advanced
+// horizon, then the declarative policies of the item group, area, and global
+// configuration. loc.Item and loc.ItemGroup may be empty to describe a broader
+// scope. Legacy rules are only included when limits is non-nil.
+func PolicyRules(cfg *areas.Config, loc *areas.ItemLocation, limits *BookingLimits) []PolicyRule {
+	var rules []PolicyRule
+	if limits != nil {
+		rules = append(rules, legacyRules(loc, limits)...)
+	}
+
+	if loc.ItemGroup.ID != "" {
+		for _, p := range loc.ItemGroup.Policies {
+			rules = append(rules, PolicyRule{
+				Policy: p, Scope: PolicyScopeItemGroup,
+				ScopeID: loc.ItemGroup.ID, ScopeName: loc.ItemGroup.Name,
+				ItemIDs:     collectItemIDs(loc.ItemGroup.Items),
+				listsMember: loc.ItemGroup.ListsReservedFor,
+			})
+		}
+	}
+	for _, p := range loc.Area.Policies {
+		rules = append(rules, PolicyRule{
+			Policy: p, Scope: PolicyScopeArea,
+			ScopeID: loc.Area.ID, ScopeName: loc.Area.Name,
+			ItemIDs:     collectAreaItemIDs(loc.Area),
+			listsMember: loc.Area.ListsReservedFor,
+		})
+	}
+	for _, p := range cfg.Policies {
+		rules = append(rules, PolicyRule{Policy: p, Scope: PolicyScopeGlobal, listsMember: cfg.ListsReservedFor})
+	}
+	return rules
+}
+
+func legacyRules(loc *areas.ItemLocation, limits *BookingLimits) []PolicyRule {
+	const name = "Booking limit"
+	var rules []PolicyRule
+	if loc.Item.ID != "" && loc.Item.MaxBookingsPerPerson > 0 {
+		rules = append(rules, PolicyRule{
+			Policy: areas.Policy{
+				Name: name, AppliesTo: areas.AppliesToUsers, MaxActiveBookings: loc.Item.MaxBookingsPerPerson,
+			},
+			Scope: PolicyScopeItem, ScopeID: loc.Item.ID, ScopeName: loc.Item.Name,
+			ItemIDs: []string{loc.Item.ID}, Legacy: true,
+		})
+	}
+	if loc.ItemGroup.ID != "" && loc.ItemGroup.MaxBookingsPerPerson > 0 {
+		rules = append(rules, PolicyRule{
+			Policy: areas.Policy{
+				Name: name, AppliesTo: areas.AppliesToUsers, MaxActiveBookings: loc.ItemGroup.MaxBookingsPerPerson,
+			},
+			Scope: PolicyScopeItemGroup, ScopeID: loc.ItemGroup.ID, ScopeName: loc.ItemGroup.Name,
+			ItemIDs: collectItemIDs(loc.ItemGroup.Items), Legacy: true,
+		})
+	}
+	if loc.Area.MaxBookingsPerPerson > 0 {
+		rules = append(rules, PolicyRule{
+			Policy: areas.Policy{
+				Name: name, AppliesTo: areas.AppliesToUsers, MaxActiveBookings: loc.Area.MaxBookingsPerPerson,
+			},
+			Scope: PolicyScopeArea, ScopeID: loc.Area.ID, ScopeName: loc.Area.Name,
+			ItemIDs: collectAreaItemIDs(loc.Area), Legacy: true,
+		})
+	}
+	if limits.MaxBookingsPerPerson > 0 {
+		rules = append(rules, PolicyRule{
+			Policy: areas.Policy{
+				Name: name, AppliesTo: areas.AppliesToUsers, MaxActiveBookings: limits.MaxBookingsPerPerson,
+			},
+			Scope: PolicyScopeGlobal, Legacy: true,
+		})
+	}
+	if limits.WeeksInAdvanced > 0 {
+		rules = append(rules, PolicyRule{
+			Policy: areas.Policy{Name: "Booking horizon", MaxWeeksAhead: limits.WeeksInAdvanced},
+			Scope:  PolicyScopeGlobal, Legacy: true,
+		})
+	}
+	return rules
+}
+
+// legacyLimitLabel reproduces the scope label of the historic limit messages.
+func (r *PolicyRule) legacyLimitLabel(loc *areas.ItemLocation) string {
+	switch r.Scope {
+	case PolicyScopeItem:
+		return fmt.Sprintf("'%s, %s'", loc.ItemGroup.Name, loc.Item.Name)
+	case PolicyScopeItemGroup, PolicyScopeArea:
+		return fmt.Sprintf("'%s'", r.ScopeName)
+	default:
+		return ""
+	}
+}
+
+// policySubject identifies whose bookings count against a rule. For guest
+// bookings, userID is the booker and their guest bookings are counted. email is
+// only resolved when a rule distinguishes reserved_for members.
+type policySubject struct {
+	userID  string
+	isGuest bool
+	email   string
+}
+
+// policyEvaluation bundles the inputs shared by all rule checks of a booking.
+// now is in the time zone of the item's area, so its date is the local today.
+type policyEvaluation struct {
+	store   *sql.DB
+	loc     *areas.ItemLocation
+	subject policySubject
+	dates   []string
+	now     time.Time
+}
+
+// evaluatePolicies checks every rule that applies to the booking and returns the
+// first violation: ErrBookingLimitExceeded for legacy active-booking limits, a
+// *Rejection for everything else. This is the single place where booking limits
+// are enforced.
+func evaluatePolicies(ctx context.Context, ev *policyEvaluation, rules []PolicyRule) error {
+	// Requests spanning areas are validated against the earliest local today,
+	// so a date can still be past in the time zone of the chosen item.
+	today := ev.now.Format(time.DateOnly)
+	for _, date := range ev.dates {
+		if date < today {
+			return &Rejection{
+				Status: http.StatusBadRequest,
+				Code:   "bad_request",
+				Detail: "booking_date cannot be in the past: " + date,
+			}
+		}
+	}
+	attendance := &attendanceLookup{
+		store: ev.store, userID: ev.subject.userID, isGuest: ev.subject.isGuest, today: areas.CalendarDay(ev.now),
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.appliesTo(ev.subject.isGuest, ev.subject.email) {
+			continue
+		}
+		matches, err := attendance.matches(ctx, rule)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			continue
+		}
+		if err := ev.checkRule(ctx, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckPolicies evaluates the booking limits and policies for a user booking the
+// item at loc on dates. It returns ErrBookingLimitExceeded or a *Rejection for
+// the first violation, like the booking endpoints.
+func CheckPolicies(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, limits *BookingLimits,
+	loc *areas.ItemLocation, userID, email string, dates []string,
+) error {
+	ev := &policyEvaluation{
+		store:   store,
+		loc:     loc,
+		subject: policySubject{userID: userID, email: email},
+		dates:   dates,
+		now:     time.Now().In(cfg.AreaZone(loc.Area)),
+	}
+	return evaluatePolicies(ctx, ev, PolicyRules(cfg, loc, limits))
+}
+
+func (ev *policyEvaluation) checkRule(ctx context.Context, rule *PolicyRule) error {
+	if rule.MaxWeeksAhead > 0 {
+		if err := ev.checkHorizon(rule); err != nil {
+			return err
+		}
+	}
+	if rule.MinNoticeHours > 0 {
+		if err := ev.checkNotice(rule); err != nil {
+			return err
+		}
+	}
+	if rule.MaxActiveBookings > 0 {
+		if err := ev.checkActive(ctx, rule); err != nil {
+			return err
+		}
+	}
+	if rule.MaxPerDay > 0 || rule.MaxDaysPerWeek > 0 {
+		return ev.checkFrequency(ctx, rule)
+	}
+	return nil
+}
+
+func (ev *policyEvaluation) checkHorizon(rule *PolicyRule) error {
+	maxDate := bookingHorizon(areas.CalendarDay(ev.now), rule.MaxWeeksAhead)
+	for _, date := range ev.dates {
+		parsed, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return fmt.Errorf("parse booking date: %w", err)
+		}
+		if !parsed.Before(maxDate) {
+			return violation(rule, fmt.Sprintf(
+				"%s can be booked at most %d weeks in advance (%s is too far ahead)",
+				rule.ScopeLabel(), rule.MaxWeeksAhead, date,
+			))
+		}
+	}
+	return nil
+}
+
+func (ev *policyEvaluation) checkNotice(rule *PolicyRule) error {
+	earliest := ev.now.Add(time.Duration(rule.MinNoticeHours) * time.Hour)
+	for _, date := range ev.dates {
+		parsed, err := time.ParseInLocation(time.DateOnly, date, ev.now.Location())
+		if err != nil {
+			return fmt.Errorf("parse booking date: %w", err)
+		}
+		if parsed.Before(earliest) {
+			return violation(rule, fmt.Sprintf(
+				"bookings in %s require at least %d hours notice (%s is too soon)",
+				rule.ScopeLabel(), rule.MinNoticeHours, date,
+			))
+		}
+	}
+	return nil
+}
+
+func (ev *policyEvaluation) checkActive(ctx context.Context, rule *PolicyRule) error {
+	if rule.Legacy {
+		return checkBookingLimit(
+			ctx, ev.store, ev.subject.userID, rule.MaxActiveBookings, rule.ItemIDs, rule.legacyLimitLabel(ev.loc),
+			ev.now.Format(time.DateOnly),
+		)
+	}
+	today := ev.now.Format(time.DateOnly)
+	existing, err := listCountedBookingDates(
+		ctx, ev.store, ev.subject.userID, ev.subject.isGuest, rule.ItemIDs, today, "9999-12-31",
+	)
+	if err != nil {
+		return err
+	}
+	if total := len(existing) + len(ev.dates); total > rule.MaxActiveBookings {
+		return violation(rule, fmt.Sprintf(
+			"at most %d active bookings in %s (you would have %d)",
+			rule.MaxActiveBookings, rule.ScopeLabel(), total,
+		))
+	}
+	return nil
+}
+
+// checkFrequency enforces max_per_day and max_days_per_week by combining the
+// existing bookings in the affected weeks with the requested dates.
+func (ev *policyEvaluation) checkFrequency(ctx context.Context, rule *PolicyRule) error {
+	sorted := append([]string(nil), ev.dates...)
+	sort.Strings(sorted)
+	from, _ := weekBounds(sorted[0])
+	_, to := weekBounds(sorted[len(sorted)-1])
+
+	existing, err := listCountedBookingDates(
+		ctx, ev.store, ev.subject.userID, ev.subject.isGuest, rule.ItemIDs, from, to,
+	)
+	if err != nil {
+		return err
+	}
+
+	perDay := make(map[string]int)
+	for _, date := range existing {
+		perDay[date]++
+	}
+	for _, date := range sorted {
+		perDay[date]++
+	}
+
+	if rule.MaxPerDay > 0 {
+		for _, date := range sorted {
+			if perDay[date] > rule.MaxPerDay {
+				return violation(rule, fmt.Sprintf(
+					"at most %d bookings per day in %s (%s would have %d)",
+					rule.MaxPerDay, rule.ScopeLabel(), date, perDay[date],
+				))
+			}
+		}
+	}
+
+	if rule.MaxDaysPerWeek > 0 {
+		daysPerWeek := make(map[string]int)
+		for date := range perDay {
+			monday, _ := weekBounds(date)
+			daysPerWeek[monday]++
+		}
+		for _, date := range sorted {
+			monday, _ := weekBounds(date)
+			if daysPerWeek[monday] > rule.MaxDaysPerWeek {
+				return violation(rule, fmt.Sprintf(
+					"at most %d days per week in %s (the week of %s would have %d)",
+					rule.MaxDaysPerWeek, rule.ScopeLabel(), monday, daysPerWeek[monday],
+				))
+			}
+		}
+	}
+	return nil
+}
+
+// weekBounds returns the Monday and Sunday of the ISO week containing date.
+// Unparseable dates yield the date itself for both bounds.
+func weekBounds(date string) (monday, sunday string) {
+	parsed, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return date, date
+	}
+	start := parsed.AddDate(0, 0, -((int(parsed.Weekday()) + 6) % 7))
+	return start.Format(time.DateOnly), start.AddDate(0, 0, 6).Format(time.DateOnly)
+}
+
+// bookingHorizon returns the first date beyond a horizon of the current week
+// plus weeks additional weeks, counted from today.
+func bookingHorizon(today time.Time, weeks int) time.Time {
+	daysUntilMonday := (8 - int(today.Weekday())) % 7
+	nextMonday := today.AddDate(0, 0, daysUntilMonday)
+	return nextMonday.AddDate(0, 0, weeks*7)
+}
+
+func violation(rule *PolicyRule, detail string) *Rejection {
+	return &Rejection{
+		Status: http.StatusConflict,
+		Code:   ErrorCodePolicyViolation,
+		Detail: fmt.Sprintf("%s: %s", rule.Label(), detail),
+	}
+}
+
+// needsMembership reports whether any rule distinguishes reserved_for members.
+func needsMembership(rules []PolicyRule) bool {
+	for i := range rules {
+		switch rules[i].Audience() {
+		case areas.AppliesToMembers, areas.AppliesToNonMembers:
+			return true
+		}
+	}
+	return false
+}
+
+// lookupPolicyEmail returns the email used for reserved_for membership checks.
+// Unknown users have no email and are never members.
+func lookupPolicyEmail(ctx context.Context, store *sql.DB, userID string) (string, error) {
+	rec, err := users.FindByID(ctx, store, userID)
+	if errors.Is(err, users.ErrUserNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("find policy user: %w", err)
+	}
+	return strings.TrimSpace(rec.Email), nil
+}
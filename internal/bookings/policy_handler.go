@@ -0,0 +1,146 @@
+package bookings
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+// PolicyAttributes represents booking policy resource attributes.
+type PolicyAttributes struct {
+	Name              string `json:"name"`
+	Scope             string `json:"scope"`
+	ScopeID           string `json:"scope_id,omitempty"`
+	ScopeName         string `json:"scope_name,omitempty"`
+	AppliesTo         string `json:"applies_to"`
+	AppliesToMe       bool   `json:"applies_to_me"`
+	MaxActiveBookings int    `json:"max_active_bookings,omitempty"`
+	MaxDaysPerWeek    int    `json:"max_days_per_week,omitempty"`
+	MaxPerDay         int    `json:"max_per_day,omitempty"`
+	MaxWeeksAhead     int    `json:"max_weeks_ahead,omitempty"`
+	MinNoticeHours    int    `json:"min_notice_hours,omitempty"`
+	// Attendance conditions restrict the policy to users with a record of
+	// no-shows or late cancellations.
+	MinNoShows           int    `json:"min_no_shows,omitempty"`
+	MinLateCancellations int    `json:"min_late_cancellations,omitempty"`
+	AttendanceWindowDays int    `json:"attendance_window_days,omitempty"`
+	Description          string `json:"description"`
+}
+
+// PoliciesHandler explains the booking limits that apply at a scope, so the UI
+// can tell users why a booking would be refused.
+// GET /api/v1/booking-policies?item_id=<id> (or item_group_id, area_id)
+func PoliciesHandler(getConfig areas.ConfigGetter, store *sql.DB, limits *BookingLimits) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+
+		cfg := getConfig()
+		loc, detail := policyLocation(cfg, c)
+		if loc == nil {
+			if detail == "" {
+				return api.WriteBadRequest(c, "One of item_id, item_group_id, or area_id is required")
+			}
+			return api.WriteNotFound(c, detail)
+		}
+
+		rules := PolicyRules(cfg, loc, limits)
+		email := ""
+		if needsMembership(rules) {
+			var err error
+			email, err = lookupPolicyEmail(c.Request().Context(), store, user.ID)
+			if err != nil {
+				return api.WriteInternalError(c, "check policy membership", err)
+			}
+		}
+
+		attendance := &attendanceLookup{
+			store: store, userID: user.ID, today: areas.CalendarDay(time.Now().In(cfg.AreaZone(loc.Area))),
+		}
+		resources := make([]api.Resource, len(rules))
+		for i := range rules {
+			meetsConditions, err := attendance.matches(c.Request().Context(), &rules[i])
+			if err != nil {
+				return api.WriteInternalError(c, "check policy attendance", err)
+			}
+			resources[i] = policyResource(&rules[i], i, email, meetsConditions)
+		}
+		return api.WriteCollection(c, resources, "write booking policies response")
+	}
+}
+
+// policyLocation resolves the query parameters to a (possibly partial) item
+// location. Returns nil and a not-found detail for unknown IDs, or nil and ""
+// when no scope was given.
+func policyLocation(cfg *areas.Config, c echo.Context) (*areas.ItemLocation, string) {
+	if itemID := c.QueryParam("item_id"); itemID != "" {
+		loc, ok := cfg.FindItemLocation(itemID)
+		if !ok {
+			return nil, "Item not found"
+		}
+		return loc, ""
+	}
+	if groupID := c.QueryParam("item_group_id"); groupID != "" {
+		for i := range cfg.Areas {
+			area := &cfg.Areas[i]
+			for j := range area.ItemGroups {
+				if area.ItemGroups[j].ID == groupID {
+					return &areas.ItemLocation{Area: area, ItemGroup: &area.ItemGroups[j], Item: &areas.Item{}}, ""
+				}
+			}
+		}
+		return nil, "Item group not found"
+	}
+	if areaID := c.QueryParam("area_id"); areaID != "" {
+		area, ok := cfg.FindArea(areaID)
+		if !ok {
+			return nil, "Area not found"
+		}
+		return &areas.ItemLocation{Area: area, ItemGroup: &areas.ItemGroup{}, Item: &areas.Item{}}, ""
+	}
+	return nil, ""
+}
+
+// attendanceWindowDays returns the window of a rule with attendance conditions, or 0.
+func attendanceWindowDays(rule *PolicyRule) int {
+	if !rule.HasAttendanceCondition() {
+		return 0
+	}
+	return rule.AttendanceWindow()
+}
+
+func policyResource(rule *PolicyRule, index int, email string, meetsConditions bool) api.Resource {
+	id := rule.Scope
+	if rule.ScopeID != "" {
+		id += "-" + rule.ScopeID
+	}
+	return api.Resource{
+		Type: "booking-policies",
+		ID:   fmt.Sprintf("%s-%d", id, index+1),
+		Attributes: PolicyAttributes{
+			Name:                 rule.Label(),
+			Scope:                rule.Scope,
+			ScopeID:              rule.ScopeID,
+			ScopeName:            rule.ScopeName,
+			AppliesTo:            rule.Audience(),
+			AppliesToMe:          rule.appliesTo(false, email) && meetsConditions,
+			MaxActiveBookings:    rule.MaxActiveBookings,
+			MaxDaysPerWeek:       rule.MaxDaysPerWeek,
+			MaxPerDay:            rule.MaxPerDay,
+			MaxWeeksAhead:        rule.MaxWeeksAhead,
+			MinNoticeHours:       rule.MinNoticeHours,
+			MinNoShows:           rule.MinNoShows,
+			MinLateCancellations: rule.MinLateCancellations,
+			AttendanceWindowDays: attendanceWindowDays(rule),
+			Description:          rule.Describe(),
+		},
+	}
+}
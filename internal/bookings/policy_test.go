@@ -0,0 +1,233 @@
+package bookings
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+// policyWeekDay returns the given weekday offset (0 = Monday) of a week safely
+// inside the booking horizon.
+func policyWeekDay(offset int) string {
+	monday := bookingHorizon(time.Now().UTC().Truncate(24*time.Hour), 1)
+	return monday.AddDate(0, 0, offset).Format(time.DateOnly)
+}
+
+func postPolicyBooking(
+	t *testing.T, cfg *areas.Config, store *sql.DB, userID, attributes string,
+) (int, api.ErrorResponse) {
+	t.Helper()
+	body := `{"data":{"type":"bookings","attributes":{` + attributes + `}}}`
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: userID, Name: "Test User"})
+
+	limits := &BookingLimits{WeeksInAdvanced: 52}
+	h := CreateHandlerDynamic(func() *areas.Config { return cfg }, store, testNotifier(), limits)
+	require.NoError(t, h(c))
+
+	var resp api.ErrorResponse
+	if rec.Code >= http.StatusBadRequest {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	}
+	return rec.Code, resp
+}
+
+func TestPolicyMaxPerDayInArea(t *testing.T) {
+	t.Parallel()
+
+	cfg := testAreasConfig()
+	cfg.Areas[0].Policies = []areas.Policy{{Name: "Parking", MaxPerDay: 1}}
+	store := setupTestStore(t)
+	date := policyWeekDay(1)
+	seedTestBooking(t, store, "b1", "desk-2", "user-1", date)
+
+	code, resp := postPolicyBooking(t, cfg, store, "user-1", `"item_id":"desk-1","booking_date":"`+date+`"`)
+
+	assert.Equal(t, http.StatusConflict, code)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, ErrorCodePolicyViolation, resp.Errors[0].Code)
+	assert.Contains(t, resp.Errors[0].Detail, "Parking: at most 1 bookings per day")
+	assert.Contains(t, resp.Errors[0].Detail, `area "Office"`)
+
+	code, _ = postPolicyBooking(t, cfg, store, "user-2", `"item_id":"desk-1","booking_date":"`+date+`"`)
+	assert.Equal(t, http.StatusCreated, code)
+}
+
+func TestPolicyMaxDaysPerWeekCountsRequestedDates(t *testing.T) {
+	t.Parallel()
+
+	cfg := testAreasConfig()
+	cfg.Areas[0].ItemGroups[0].Policies = []areas.Policy{{Name: "Fair share", MaxDaysPerWeek: 2}}
+	store := setupTestStore(t)
+	seedTestBooking(t, store, "b1", "desk-2", "user-1", policyWeekDay(0))
+
+	code, resp := postPolicyBooking(t, cfg, store, "user-1",
+		`"item_id":"desk-1","booking_dates":["`+policyWeekDay(2)+`","`+policyWeekDay(3)+`"]`)
+
+	assert.Equal(t, http.StatusConflict, code)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Detail, "at most 2 days per week")
+	assert.Contains(t, resp.Errors[0].Detail, "would have 3")
+
+	// A different week is unaffected.
+	code, _ = postPolicyBooking(t, cfg, store, "user-1", `"item_id":"desk-1","booking_date":"`+policyWeekDay(7)+`"`)
+	assert.Equal(t, http.StatusCreated, code)
+}
+
+func TestPolicyHorizonPerScope(t *testing.T) {
+	t.Parallel()
+
+	cfg := testAreasConfig()
+	cfg.Areas[0].Policies = []areas.Policy{{MaxWeeksAhead: 1}}
+	store := setupTestStore(t)
+
+	code, resp := postPolicyBooking(t, cfg, store, "user-1", `"item_id":"desk-1","booking_date":"`+policyWeekDay(7)+`"`)
+
+	assert.Equal(t, http.StatusConflict, code)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Detail, "at most 1 weeks in advance")
+}
+
+func TestPolicyGuestMinimumNotice(t *testing.T) {
+	t.Parallel()
+
+	cfg := testAreasConfig()
+	cfg.Policies = []areas.Policy{{Name: "Guest notice", AppliesTo: areas.AppliesToGuests, MinNoticeHours: 48}}
+	store := setupTestStore(t)
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	code, resp := postPolicyBooking(t, cfg, store, "user-1",
+		`"item_id":"desk-1","booking_date":"`+tomorrow+`","is_guest":true,"for_user_name":"Visitor"`)
+	assert.Equal(t, http.StatusConflict, code)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Detail, "Guest notice: bookings in all areas require at least 48 hours notice")
+
+	code, _ = postPolicyBooking(t, cfg, store, "user-1", `"item_id":"desk-1","booking_date":"`+tomorrow+`"`)
+	assert.Equal(t, http.StatusCreated, code)
+}
+
+func TestPolicyDifferentLimitsForMembers(t *testing.T) {
+	t.Parallel()
+
+	cfg := testAreasConfig()
+	cfg.Areas[0].ItemGroups[0].Items[1].ReservedFor = []string{"member@test.local"}
+	cfg.Areas[0].Policies = []areas.Policy{
+		{Name: "Visitors", AppliesTo: areas.AppliesToNonMembers, MaxDaysPerWeek: 1},
+		{Name: "Team", AppliesTo: areas.AppliesToMembers, MaxDaysPerWeek: 3},
+	}
+	store := setupTestStore(t)
+	seedTestUserRecord(t, store, "member", "member@test.local", "Member")
+	seedTestUserRecord(t, store, "other", "other@test.local", "Other")
+	seedTestBooking(t, store, "b1", "desk-2", "member", policyWeekDay(0))
+	seedTestBooking(t, store, "b2", "desk-1", "other", policyWeekDay(0))
+
+	code, _ := postPolicyBooking(t, cfg, store, "member", `"item_id":"desk-1","booking_date":"`+policyWeekDay(1)+`"`)
+	assert.Equal(t, http.StatusCreated, code)
+
+	code, resp := postPolicyBooking(t, cfg, store, "other", `"item_id":"desk-1","booking_date":"`+policyWeekDay(2)+`"`)
+	assert.Equal(t, http.StatusConflict, code)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Detail, "Visitors: at most 1 days per week")
+}
+
+func TestPolicyNestedAreasInheritLimit(t *testing.T) {
+	t.Parallel()
+
+	floor := func(id, deskID string, limit int) areas.Area {
+		items := []areas.Item{{ID: deskID + "-a", Name: deskID}, {ID: deskID + "-b", Name: deskID}}
+		return areas.Area{ID: id, Name: id, MaxBookingsPerPerson: limit, ItemGroups: []areas.ItemGroup{{
+			ID: id + "-zone", Name: "Zone", Items: items,
+		}}}
+	}
+	cfg := &areas.Config{}
+	cfg.SetTree([]areas.Area{{
+		ID: "building", Name: "Building", MaxBookingsPerPerson: 1,
+		Areas: []areas.Area{floor("floor-1", "f1", 0), floor("floor-2", "f2", 2)},
+	}})
+	store := setupTestStore(t)
+	seedTestBooking(t, store, "b1", "f1-a", "user-1", policyWeekDay(0))
+	seedTestBooking(t, store, "b2", "f2-a", "user-1", policyWeekDay(0))
+
+	// floor-1 inherits the building's limit of 1 and counts its own bookings.
+	code, resp := postPolicyBooking(t, cfg, store, "user-1", `"item_id":"f1-b","booking_date":"`+policyWeekDay(1)+`"`)
+	assert.Equal(t, http.StatusConflict, code)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Detail, "maximum of 1")
+	assert.Contains(t, resp.Errors[0].Detail, "floor-1")
+
+	// floor-2 overrides it with 2.
+	code, _ = postPolicyBooking(t, cfg, store, "user-1", `"item_id":"f2-b","booking_date":"`+policyWeekDay(1)+`"`)
+	assert.Equal(t, http.StatusCreated, code)
+	code, _ = postPolicyBooking(t, cfg, store, "user-1", `"item_id":"f2-b","booking_date":"`+policyWeekDay(2)+`"`)
+	assert.Equal(t, http.StatusConflict, code)
+}
+
+func TestPoliciesHandlerExplainsRules(t *testing.T) {
+	t.Parallel()
+
+	cfg := testAreasConfigWithLimits()
+	cfg.Areas[0].Policies = []areas.Policy{{Name: "Parking", AppliesTo: areas.AppliesToGuests, MinNoticeHours: 24}}
+	store := setupTestStore(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/booking-policies?item_id=desk-1", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
+
+	limits := &BookingLimits{WeeksInAdvanced: 4, MaxBookingsPerPerson: 10}
+	h := PoliciesHandler(func() *areas.Config { return cfg }, store, limits)
+	require.NoError(t, h(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data []struct {
+			ID         string           `json:"id"`
+			Attributes PolicyAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	descriptions := make([]string, 0, len(resp.Data))
+	for _, d := range resp.Data {
+		descriptions = append(descriptions, d.Attributes.Description)
+	}
+	assert.Contains(t, descriptions, `Bookings for users in item "Desk 1": at most 2 active bookings.`)
+	assert.Contains(t, descriptions, `All bookings in all areas: booking at most 4 weeks in advance.`)
+	assert.Contains(t, descriptions, `Guest bookings in area "Office": at least 24 hours notice.`)
+
+	last := resp.Data[len(resp.Data)-1].Attributes
+	assert.Equal(t, "Parking", last.Name)
+	assert.False(t, last.AppliesToMe)
+}
+
+func TestPoliciesHandlerRequiresScope(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/booking-policies", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
+
+	h := PoliciesHandler(testAreasConfig, setupTestStore(t), nil)
+	require.NoError(t, h(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
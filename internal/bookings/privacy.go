@@ -0,0 +1,137 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+const resourceTypePrivacySettings = "privacy-settings"
+
+// PrivacySettingsAttributes represents a user's booking privacy preference.
+type PrivacySettingsAttributes struct {
+	PrivateBookings bool `json:"private_bookings"`
+}
+
+type privacySettingsRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			PrivateBookings *bool `json:"private_bookings"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// privateByDefault reports whether userID books privately by default.
+func privateByDefault(ctx context.Context, db execer, userID string) (bool, error) {
+	var private bool
+	err := db.QueryRowContext(ctx, `SELECT private_bookings FROM users WHERE id = ?`, userID).Scan(&private)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("find booking privacy: %w", err)
+	}
+	return private, nil
+}
+
+// SetPrivateByDefault stores whether userID books privately by default.
+// Turning it on also makes the user's bookings (and guests) from fromDate on
+// private; turning it off leaves existing bookings as they are.
+func SetPrivateByDefault(ctx context.Context, store *sql.DB, userID string, private bool, fromDate string) error {
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin privacy update: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() //nolint:errcheck // No-op after commit
+	}()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET private_bookings = ?, updated_at = ? WHERE id = ?`, private, now, userID,
+	); err != nil {
+		return fmt.Errorf("update booking privacy: %w", err)
+	}
+	if private {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE bookings SET is_private = 1, updated_at = ?, version = version + 1
+			 WHERE is_private = 0 AND booking_date >= ?
+			   AND (user_id = ? OR (is_guest = 1 AND booked_by_user_id = ?))`,
+			now, fromDate, userID, userID,
+		); err != nil {
+			return fmt.Errorf("make bookings private: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit privacy update: %w", err)
+	}
+	return nil
+}
+
+// PrivacySettingsHandler returns whether the current user books privately by
+// default.
+// GET /api/v1/me/privacy-settings
+func PrivacySettingsHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		private, err := privateByDefault(c.Request().Context(), store, user.ID)
+		if err != nil {
+			return api.WriteInternalError(c, "find booking privacy", err)
+		}
+		return api.WriteSingle(c, http.StatusOK, privacySettingsResource(user.ID, private),
+			"write privacy settings response")
+	}
+}
+
+// UpdatePrivacySettingsHandler sets whether the current user books privately
+// by default. Turning it on also makes the user's upcoming bookings private.
+// PATCH /api/v1/me/privacy-settings
+func UpdatePrivacySettingsHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req privacySettingsRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypePrivacySettings {
+			return api.WriteBadRequest(c, "Resource type must be 'privacy-settings'")
+		}
+		if req.Data.Attributes.PrivateBookings == nil {
+			return api.WriteBadRequest(c, "private_bookings is required")
+		}
+		private := *req.Data.Attributes.PrivateBookings
+		earliest, _ := getConfig().TodayRange(time.Now())
+		if err := SetPrivateByDefault(c.Request().Context(), store, user.ID, private, earliest); err != nil {
+			return api.WriteInternalError(c, "set booking privacy", err)
+		}
+		slog.Info("booking privacy changed", "user_id", user.ID, "private_bookings", private)
+		return api.WriteSingle(c, http.StatusOK, privacySettingsResource(user.ID, private),
+			"write privacy settings response")
+	}
+}
+
+func privacySettingsResource(userID string, private bool) api.Resource {
+	return api.Resource{
+		Type:       resourceTypePrivacySettings,
+		ID:         userID,
+		Attributes: PrivacySettingsAttributes{PrivateBookings: private},
+	}
+}
@@ -0,0 +1,67 @@
+package bookings
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+func TestPrivateBookings(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTestUser(t, store, "user-1", "Test User")
+	cfg := testAreasConfig()
+	getConfig := func() *areas.Config { return cfg }
+	user := &auth.User{ID: "user-1", Name: "Test User"}
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedTestBooking(t, store, "past", "desk-1", "user-1", yesterday)
+	seedTestBooking(t, store, "upcoming", "desk-1", "user-1", tomorrow)
+
+	settings := sendBookingJSON(t, PrivacySettingsHandler(store), http.MethodGet, "", "", user)
+	require.Equal(t, http.StatusOK, settings.Code)
+	assert.Contains(t, settings.Body.String(), `"private_bookings":false`)
+
+	// Turning the preference on makes upcoming bookings private.
+	update := UpdatePrivacySettingsHandler(getConfig, store)
+	settings = sendBookingJSON(t, update, http.MethodPatch, "",
+		`{"data":{"type":"privacy-settings","attributes":{"private_bookings":true}}}`, user)
+	require.Equal(t, http.StatusOK, settings.Code, settings.Body.String())
+	assert.Contains(t, settings.Body.String(), `"private_bookings":true`)
+	past, err := FindBookingByID(t.Context(), store, "past")
+	require.NoError(t, err)
+	assert.False(t, past.IsPrivate)
+	upcoming, err := FindBookingByID(t.Context(), store, "upcoming")
+	require.NoError(t, err)
+	assert.True(t, upcoming.IsPrivate)
+	assert.Equal(t, 2, upcoming.Version)
+
+	// New bookings are private by default.
+	notifier := &recordingNotifier{}
+	rec := sendBookingJSON(t, CreateHandlerDynamic(getConfig, store, notifier, nil), http.MethodPost, "",
+		`{"data":{"type":"bookings","attributes":{"item_id":"desk-2","booking_date":"`+tomorrow+`"}}}`, user)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"private":true`)
+	require.Len(t, notifier.events, 1)
+	assert.True(t, notifier.events[0].Private)
+
+	// A booking can be made public again.
+	rec = sendBookingJSON(t, PatchHandler(cfg, store), http.MethodPatch, "upcoming",
+		`{"data":{"type":"bookings","id":"upcoming","attributes":{"private":false}}}`, user)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), `"private"`)
+
+	for _, body := range []string{
+		`{"data":{"type":"privacy-settings","attributes":{}}}`,
+		`{"data":{"type":"bookings","attributes":{"private_bookings":true}}}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, sendBookingJSON(t, update, http.MethodPatch, "", body, user).Code)
+	}
+}
@@ -0,0 +1,437 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+// defaultSearchPageSize is the page size of a booking search without page[size].
+const defaultSearchPageSize = 50
+
+// searchQueryOptions lists the attributes a booking search can be sorted by.
+var searchQueryOptions = api.QueryOptions{
+	Sort: []string{"booking_date", "created_at", "item_id", "user_id"},
+}
+
+// SearchQuery holds the filters of a booking search across all users.
+type SearchQuery struct {
+	UserID         string
+	BookedByUserID string
+	// ItemIDs limits the search to these items; nil matches all items.
+	ItemIDs  []string
+	FromDate string
+	ToDate   string
+	IsGuest  *bool
+	Note     string
+	// IDs limits the search to these bookings; nil matches all bookings.
+	IDs []string
+	// Sort orders the bookings, with the booking ID breaking ties. Without it
+	// the latest booking date comes first.
+	Sort []api.SortKey
+	// After resumes the search after the booking at this position of Sort.
+	After *api.Cursor
+	// Limit caps the number of bookings returned; 0 returns all of them.
+	Limit int
+}
+
+// SearchBookings returns the bookings of all users matching q in q's order.
+func SearchBookings(ctx context.Context, store *sql.DB, q *SearchQuery) (result []BookingRecord, err error) {
+	if (q.ItemIDs != nil && len(q.ItemIDs) == 0) || (q.IDs != nil && len(q.IDs) == 0) {
+		return nil, nil
+	}
+
+	where, args := searchConditions(q)
+	keys := searchSortKeys(q.Sort)
+	if q.After != nil {
+		cond, afterArgs := searchAfterCondition(keys, q.After)
+		where = append(where, cond)
+		args = append(args, afterArgs...)
+	}
+	order := make([]string, len(keys))
+	for i, key := range keys {
+		order[i] = key.Field
+		if key.Desc {
+			order[i] += " DESC"
+		}
+	}
+	//nolint:gosec // G202: conditions are constants with "?" placeholders, sort fields are whitelisted
+	query := `SELECT id, item_id, user_id, booking_date, booked_by_user_id,
+	                 is_guest, guest_name, guest_email, note, created_at, updated_at, custom_fields, version
+	          FROM bookings
+	          WHERE ` + strings.Join(where, " AND ") + `
+	          ORDER BY ` + strings.Join(order, ", ")
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+	rows, err := store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search bookings: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close booking search rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var b BookingRecord
+		var isGuestInt int
+		var fields string
+		err := rows.Scan(
+			&b.ID, &b.ItemID, &b.UserID, &b.BookingDate,
+			&b.BookedByUserID, &isGuestInt, &b.GuestName, &b.GuestEmail, &b.Note, &b.CreatedAt, &b.UpdatedAt,
+			&fields, &b.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan booking search row: %w", err)
+		}
+		b.IsGuest = isGuestInt == 1
+		b.Fields = DecodeFields(fields)
+		result = append(result, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate booking search rows: %w", err)
+	}
+	return result, nil
+}
+
+// CountSearchBookings returns the number of bookings matching the filters of
+// q, ignoring its position and limit.
+func CountSearchBookings(ctx context.Context, store *sql.DB, q *SearchQuery) (int, error) {
+	if (q.ItemIDs != nil && len(q.ItemIDs) == 0) || (q.IDs != nil && len(q.IDs) == 0) {
+		return 0, nil
+	}
+	where, args := searchConditions(q)
+	var count int
+	//nolint:gosec // G202: conditions are constants with "?" placeholders
+	err := store.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM bookings WHERE `+strings.Join(where, " AND "), args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count bookings: %w", err)
+	}
+	return count, nil
+}
+
+// searchSortKeys returns the order of a search sorted by sort, ending with the
+// booking ID so that every booking has one position.
+func searchSortKeys(sort []api.SortKey) []api.SortKey {
+	if len(sort) == 0 {
+		return []api.SortKey{{Field: "booking_date", Desc: true}, {Field: "id", Desc: true}}
+	}
+	keys := make([]api.SortKey, 0, len(sort)+1)
+	for _, key := range sort {
+		keys = append(keys, key)
+		if key.Field == "id" {
+			return keys
+		}
+	}
+	return append(keys, api.SortKey{Field: "id"})
+}
+
+// searchAfterCondition builds the condition matching the bookings ordered by
+// keys after cur. The cursor holds the values of all keys but the last, which
+// is the booking ID.
+func searchAfterCondition(keys []api.SortKey, cur *api.Cursor) (string, []any) {
+	values := make([]any, len(keys))
+	for i := range keys {
+		if i < len(cur.Values) && i < len(keys)-1 {
+			values[i] = cur.Values[i]
+		}
+	}
+	values[len(keys)-1] = cur.ID
+
+	var alternatives []string
+	var args []any
+	for i, key := range keys {
+		var terms []string
+		for j := range i {
+			terms = append(terms, keys[j].Field+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if key.Desc {
+			op = " < ?"
+		}
+		terms = append(terms, key.Field+op)
+		args = append(args, values[i])
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// searchCursor returns the position of rec in a search ordered by keys.
+func searchCursor(keys []api.SortKey, rec *BookingRecord) *api.Cursor {
+	cur := &api.Cursor{ID: rec.ID}
+	for _, key := range keys[:len(keys)-1] {
+		switch key.Field {
+		case "booking_date":
+			cur.Values = append(cur.Values, rec.BookingDate)
+		case "created_at":
+			cur.Values = append(cur.Values, rec.CreatedAt)
+		case "item_id":
+			cur.Values = append(cur.Values, rec.ItemID)
+		case "user_id":
+			cur.Values = append(cur.Values, rec.UserID)
+		}
+	}
+	return cur
+}
+
+// searchConditions builds the WHERE conditions and args of the search filters.
+func searchConditions(q *SearchQuery) (where []string, args []any) {
+	where = []string{"1 = 1"}
+	if q.UserID != "" {
+		where = append(where, "user_id = ?")
+		args = append(args, q.UserID)
+	}
+	if q.BookedByUserID != "" {
+		where = append(where, "booked_by_user_id = ?")
+		args = append(args, q.BookedByUserID)
+	}
+	if q.ItemIDs != nil {
+		placeholders, itemArgs := api.BuildINClause(q.ItemIDs)
+		where = append(where, "item_id IN ("+placeholders+")")
+		args = append(args, itemArgs...)
+	}
+	if q.FromDate != "" {
+		where = append(where, "booking_date >= ?")
+		args = append(args, q.FromDate)
+	}
+	if q.ToDate != "" {
+		where = append(where, "booking_date <= ?")
+		args = append(args, q.ToDate)
+	}
+	if q.IsGuest != nil {
+		where = append(where, "is_guest = ?")
+		args = append(args, *q.IsGuest)
+	}
+	if q.Note != "" {
+		where = append(where, "LOWER(note) LIKE ?")
+		args = append(args, "%"+strings.ToLower(q.Note)+"%")
+	}
+	if q.IDs != nil {
+		placeholders, idArgs := api.BuildINClause(q.IDs)
+		where = append(where, "id IN ("+placeholders+")")
+		args = append(args, idArgs...)
+	}
+	return where, args
+}
+
+// SearchBookingAttributes represents a booking in the admin booking search.
+type SearchBookingAttributes struct {
+	UserID           string `json:"user_id"`
+	UserName         string `json:"user_name"`
+	BookedByUserID   string `json:"booked_by_user_id"`
+	BookedByUserName string `json:"booked_by_user_name"`
+	ItemID           string `json:"item_id"`
+	ItemName         string `json:"item_name"`
+	ItemGroupID      string `json:"item_group_id"`
+	ItemGroupName    string `json:"item_group_name"`
+	AreaID           string `json:"area_id"`
+	AreaName         string `json:"area_name"`
+	BookingDate      string `json:"booking_date"`
+	IsGuest          bool   `json:"is_guest"`
+	GuestName        string `json:"guest_name,omitempty"`
+	GuestEmail       string `json:"guest_email,omitempty"`
+	Note             string `json:"note"`
+	CreatedAt        string `json:"created_at"`
+	// Fields holds all custom field values, including admin fields.
+	Fields map[string]any `json:"fields,omitempty"`
+	// Version is the booking's ETag value, to be sent back as If-Match.
+	Version int `json:"version"`
+}
+
+// SearchHandler returns a handler for searching the bookings of all users.
+// Filters: user_id, booked_by_user_id, item_id, item_group_id, area_id, from,
+// to, is_guest, note. Supports the JSON:API page, sort, and fields parameters;
+// pages hold defaultSearchPageSize bookings unless page[size] is given, and
+// the latest booking date comes first unless sort is given. Accepts the
+// include paths of the my bookings collection.
+func SearchHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := getConfig()
+		q, err := parseSearchQuery(c, cfg)
+		if err != nil {
+			return handleValidationError(c, err)
+		}
+		query, err := api.ParseQuery(c, searchQueryOptions)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
+		if query.Size == 0 {
+			query.Size = defaultSearchPageSize
+		}
+		include, err := api.ParseInclude(c, bookingIncludePaths)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
+
+		q.IDs = query.Filters["id"]
+		q.Sort = query.Sort
+		q.After = query.Cursor()
+		q.Limit = query.Size + 1
+
+		ctx := c.Request().Context()
+		total, err := CountSearchBookings(ctx, store, q)
+		if err != nil {
+			return fmt.Errorf("search bookings: %w", err)
+		}
+		records, err := SearchBookings(ctx, store, q)
+		if err != nil {
+			return fmt.Errorf("search bookings: %w", err)
+		}
+		next := ""
+		if len(records) > query.Size {
+			records = records[:query.Size]
+			next = query.NextCursor(searchCursor(searchSortKeys(q.Sort), &records[len(records)-1]))
+		}
+
+		names := searchDisplayNames(ctx, store, records)
+		resources := make([]api.Resource, 0, len(records))
+		for i := range records {
+			resources = append(resources, api.Resource{
+				Type:          resourceTypeBooking,
+				ID:            records[i].ID,
+				Attributes:    buildSearchAttributes(cfg, &records[i], names),
+				Relationships: bookingRelationships(&records[i]),
+			})
+		}
+		return api.WritePagedCollection(c, query, resources, total, next, func(page []api.Resource) []api.Resource {
+			return bookingIncludes(ctx, store, cfg, include, page)
+		}, "write booking search")
+	}
+}
+
+// parseSearchQuery reads the filters of a booking search.
+// Item group and area filters are resolved to their items via cfg.
+func parseSearchQuery(c echo.Context, cfg *areas.Config) (*SearchQuery, error) {
+	q := &SearchQuery{
+		UserID:         strings.TrimSpace(c.QueryParam("user_id")),
+		BookedByUserID: strings.TrimSpace(c.QueryParam("booked_by_user_id")),
+		FromDate:       strings.TrimSpace(c.QueryParam("from")),
+		ToDate:         strings.TrimSpace(c.QueryParam("to")),
+		Note:           strings.TrimSpace(c.QueryParam("note")),
+	}
+	if _, err := time.Parse(time.DateOnly, q.FromDate); q.FromDate != "" && err != nil {
+		return nil, errBadRequest("Invalid 'from' date. Use YYYY-MM-DD format.")
+	}
+	if _, err := time.Parse(time.DateOnly, q.ToDate); q.ToDate != "" && err != nil {
+		return nil, errBadRequest("Invalid 'to' date. Use YYYY-MM-DD format.")
+	}
+	if raw := c.QueryParam("is_guest"); raw != "" {
+		isGuest, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errBadRequest("is_guest must be true or false")
+		}
+		q.IsGuest = &isGuest
+	}
+
+	itemIDs, err := searchItemIDs(c, cfg)
+	if err != nil {
+		return nil, err
+	}
+	q.ItemIDs = itemIDs
+	return q, nil
+}
+
+// searchItemIDs resolves the item_id, item_group_id, and area_id filters to the
+// items matching all of them. Returns nil when none is given. item_id is not
+// checked against the config so bookings of removed items stay searchable.
+func searchItemIDs(c echo.Context, cfg *areas.Config) ([]string, error) {
+	var sets [][]string
+	if id := strings.TrimSpace(c.QueryParam("item_id")); id != "" {
+		sets = append(sets, []string{id})
+	}
+	if id := strings.TrimSpace(c.QueryParam("item_group_id")); id != "" {
+		group, ok := cfg.FindItemGroup(id)
+		if !ok {
+			return nil, errBadRequest("Unknown item_group_id")
+		}
+		sets = append(sets, collectItemIDs(group.Items))
+	}
+	if id := strings.TrimSpace(c.QueryParam("area_id")); id != "" {
+		area, ok := cfg.FindArea(id)
+		if !ok {
+			return nil, errBadRequest("Unknown area_id")
+		}
+		sets = append(sets, collectAreaItemIDs(area))
+	}
+	if len(sets) == 0 {
+		return nil, nil
+	}
+
+	result := sets[0]
+	for _, set := range sets[1:] {
+		allowed := make(map[string]struct{}, len(set))
+		for _, id := range set {
+			allowed[id] = struct{}{}
+		}
+		kept := make([]string, 0, len(result))
+		for _, id := range result {
+			if _, ok := allowed[id]; ok {
+				kept = append(kept, id)
+			}
+		}
+		result = kept
+	}
+	return result, nil
+}
+
+// searchDisplayNames resolves the names of the users and bookers of records.
+func searchDisplayNames(ctx context.Context, store *sql.DB, records []BookingRecord) map[string]string {
+	ids := make([]string, 0, 2*len(records))
+	for i := range records {
+		ids = append(ids, records[i].UserID, records[i].BookedByUserID)
+	}
+	names, err := users.FindDisplayNames(ctx, store, ids)
+	if err != nil {
+		slog.Warn("failed to look up display names", "error", err)
+		return map[string]string{}
+	}
+	return names
+}
+
+// buildSearchAttributes maps a booking to its search attributes. Bookings of
+// items no longer in the config keep their item ID with empty location names.
+func buildSearchAttributes(
+	cfg *areas.Config, rec *BookingRecord, names map[string]string,
+) SearchBookingAttributes {
+	attrs := SearchBookingAttributes{
+		UserID:           rec.UserID,
+		UserName:         names[rec.UserID],
+		BookedByUserID:   rec.BookedByUserID,
+		BookedByUserName: names[rec.BookedByUserID],
+		ItemID:           rec.ItemID,
+		BookingDate:      rec.BookingDate,
+		IsGuest:          rec.IsGuest,
+		Note:             rec.Note,
+		CreatedAt:        rec.CreatedAt,
+		Fields:           rec.Fields,
+		Version:          rec.Version,
+	}
+	if rec.IsGuest {
+		attrs.UserName = rec.GuestName
+		attrs.GuestName = rec.GuestName
+		attrs.GuestEmail = rec.GuestEmail
+	}
+	if loc, ok := cfg.FindItemLocation(rec.ItemID); ok {
+		attrs.ItemName = loc.Item.Name
+		attrs.ItemGroupID = loc.ItemGroup.ID
+		attrs.ItemGroupName = loc.ItemGroup.Name
+		attrs.AreaID = loc.Area.ID
+		attrs.AreaName = loc.Area.Name
+	}
+	return attrs
+}
@@ -0,0 +1,149 @@
+package bookings
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+func searchBookings(t *testing.T, store *sql.DB, query string) (*httptest.ResponseRecorder, api.CollectionResponse) {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/bookings?"+query, http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "admin-1", IsAdmin: true})
+	require.NoError(t, SearchHandler(testAreasConfig, store)(c))
+
+	var resp api.CollectionResponse
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	}
+	return rec, resp
+}
+
+func resourceIDs(resources []api.Resource) []string {
+	ids := make([]string, len(resources))
+	for i, r := range resources {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestSearchHandlerFiltersAcrossUsers(t *testing.T) {
+	t.Parallel()
+	store := setupTestStore(t)
+	seedTestUser(t, store, "alice", "Alice")
+	seedTestUser(t, store, "bob", "Bob")
+	seedTestBooking(t, store, "b1", "desk-1", "alice", "2026-03-02")
+	seedTestBooking(t, store, "b2", "desk-2", "alice", "2026-03-03")
+	seedTestBookingFull(t, store, "b3", "desk-1", "alice", "bob", "2026-03-03")
+	seedTestBookingWithGuest(t, store, "b4", "desk-2", "guest-1", "bob", "2026-03-04", true, "Gina", "")
+	seedTestBooking(t, store, "b5", "removed-desk", "bob", "2026-03-05")
+	_, err := store.Exec(`UPDATE bookings SET note = 'Board MEETING prep' WHERE id = 'b2'`)
+	require.NoError(t, err)
+
+	rec, resp := searchBookings(t, store, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"b5", "b4", "b3", "b2", "b1"}, resourceIDs(resp.Data))
+	require.NotNil(t, resp.Links)
+	assert.Empty(t, resp.Links.Next)
+	require.NotNil(t, resp.Meta)
+	assert.Equal(t, 5, resp.Meta.Total)
+
+	_, resp = searchBookings(t, store, "user_id=alice&sort=booking_date")
+	assert.Equal(t, []string{"b1", "b2", "b3"}, resourceIDs(resp.Data))
+
+	_, resp = searchBookings(t, store, "booked_by_user_id=bob&is_guest=false")
+	assert.Equal(t, []string{"b5", "b3"}, resourceIDs(resp.Data))
+
+	_, resp = searchBookings(t, store, "item_id=desk-1&from=2026-03-03&to=2026-03-03")
+	assert.Equal(t, []string{"b3"}, resourceIDs(resp.Data))
+
+	_, resp = searchBookings(t, store, "area_id=area-1&item_group_id=room-1&is_guest=true")
+	assert.Equal(t, []string{"b4"}, resourceIDs(resp.Data))
+
+	_, resp = searchBookings(t, store, "item_group_id=room-1&item_id=removed-desk")
+	assert.Empty(t, resp.Data)
+
+	_, resp = searchBookings(t, store, "note=meeting")
+	require.Equal(t, []string{"b2"}, resourceIDs(resp.Data))
+
+	_, resp = searchBookings(t, store, "user_id=alice&booked_by_user_id=bob")
+	require.Len(t, resp.Data, 1)
+	attrs, ok := resp.Data[0].Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "Alice", attrs["user_name"])
+	assert.Equal(t, "Bob", attrs["booked_by_user_name"])
+	assert.Equal(t, "Desk 1", attrs["item_name"])
+	assert.Equal(t, "Office", attrs["area_name"])
+}
+
+func TestSearchHandlerPaginatesWithCursor(t *testing.T) {
+	t.Parallel()
+	store := setupTestStore(t)
+	seedTestBooking(t, store, "b1", "desk-1", "alice", "2026-03-02")
+	seedTestBooking(t, store, "b2", "desk-2", "alice", "2026-03-02")
+	seedTestBooking(t, store, "b3", "desk-1", "bob", "2026-03-03")
+	seedTestBooking(t, store, "b4", "desk-2", "bob", "2026-03-03")
+	seedTestBooking(t, store, "b5", "desk-1", "carol", "2026-03-04")
+
+	for sort, want := range map[string][]string{
+		"sort=booking_date&":        {"b1", "b2", "b3", "b4", "b5"},
+		"sort=-booking_date,-id&":   {"b5", "b4", "b3", "b2", "b1"},
+		"sort=user_id,-item_id&":    {"b2", "b1", "b4", "b3", "b5"},
+		"":                          {"b5", "b4", "b3", "b2", "b1"},
+		"user_id=bob&sort=item_id&": {"b3", "b4"},
+	} {
+		var seen []string
+		query := sort + "page%5Bsize%5D=2"
+		for range 5 {
+			rec, resp := searchBookings(t, store, query)
+			require.Equal(t, http.StatusOK, rec.Code, query)
+			assert.LessOrEqual(t, len(resp.Data), 2)
+			assert.Equal(t, len(want), resp.Meta.Total, query)
+			seen = append(seen, resourceIDs(resp.Data)...)
+			if resp.Links.Next == "" {
+				break
+			}
+			require.Contains(t, resp.Links.Next, "/api/v1/admin/bookings?")
+			_, query, _ = strings.Cut(resp.Links.Next, "?")
+		}
+		assert.Equal(t, want, seen, sort)
+	}
+}
+
+func TestSearchHandlerRejectsInvalidParams(t *testing.T) {
+	t.Parallel()
+	store := setupTestStore(t)
+	seedTestBooking(t, store, "b1", "desk-1", "alice", "2026-03-02")
+	seedTestBooking(t, store, "b2", "desk-2", "alice", "2026-03-03")
+
+	_, resp := searchBookings(t, store, "sort=booking_date&page%5Bsize%5D=1")
+	require.NotEmpty(t, resp.Links.Next)
+	_, next, _ := strings.Cut(resp.Links.Next, "?")
+
+	for _, query := range []string{
+		"from=03/02/2026",
+		"is_guest=maybe",
+		"sort=note",
+		"page%5Bsize%5D=0",
+		"page%5Bsize%5D=1000",
+		"page%5Bcursor%5D=not-a-cursor",
+		"area_id=unknown",
+		strings.Replace(next, "sort=booking_date", "sort=-booking_date", 1),
+	} {
+		rec, _ := searchBookings(t, store, query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
@@ -4,10 +4,13 @@ package bookings
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/notifications"
 )
 
 // FindBookedItemIDs returns the item IDs with bookings on the given date.
@@ -50,8 +53,30 @@ type BookingRecord struct {
 	Note           string
 	CreatedAt      string
 	UpdatedAt      string
+	// CheckedInAt is set once the booked user checked in. Only FindBookingByID
+	// and ListUserBookingsRange load it.
+	CheckedInAt string
+	// PendingConfirmation is set while a booking made for the user outside a
+	// delegation awaits their confirmation. Only FindBookingByID and
+	// ListUserBookingsRange load it.
+	PendingConfirmation bool
+	// Fields holds the custom field values keyed by field ID. Only
+	// FindBookingByID, ListUserBookingsRange, and SearchBookings load it.
+	Fields map[string]any
+	// BundleID links bookings created together in a bundle. Only
+	// FindBookingByID, ListUserBookingsRange, and ListBundleBookings load it.
+	BundleID string
+	// Version backs the booking's ETag. Only FindBookingByID,
+	// ListUserBookingsRange, and SearchBookings load it.
+	Version int
+	// IsPrivate hides the booked user from other users. Only FindBookingByID,
+	// ListUserBookingsRange, ListBookingsInRange, and ListBundleBookings load it.
+	IsPrivate bool
 }
 
+// ErrVersionConflict indicates the booking changed since the version given in If-Match.
+var ErrVersionConflict = errors.New("booking version conflict")
+
 // ListUserBookings returns all bookings for a user on or after the given date, ordered by booking_date.
 // Includes bookings where user_id matches OR booked_by_user_id matches.
 func ListUserBookings(ctx context.Context, store *sql.DB, userID, fromDate string) (result []BookingRecord, err error) {
@@ -69,14 +94,16 @@ func ListUserBookingsRange(
 
 	if toDate != "" {
 		query = `SELECT id, item_id, user_id, booking_date, booked_by_user_id,
-		                is_guest, guest_name, guest_email, note, created_at, updated_at
+		                is_guest, guest_name, guest_email, note, created_at, updated_at,
+		                COALESCE(checked_in_at, ''), pending_confirmation, custom_fields, bundle_id, is_private, version
 		         FROM bookings
 		         WHERE (user_id = ? OR booked_by_user_id = ?) AND booking_date >= ? AND booking_date <= ?
 		         ORDER BY booking_date DESC`
 		args = []interface{}{userID, userID, fromDate, toDate}
 	} else {
 		query = `SELECT id, item_id, user_id, booking_date, booked_by_user_id,
-		                is_guest, guest_name, guest_email, note, created_at, updated_at
+		                is_guest, guest_name, guest_email, note, created_at, updated_at,
+		                COALESCE(checked_in_at, ''), pending_confirmation, custom_fields, bundle_id, is_private, version
 		         FROM bookings
 		         WHERE (user_id = ? OR booked_by_user_id = ?) AND booking_date >= ?
 		         ORDER BY booking_date ASC`
@@ -96,14 +123,17 @@ func ListUserBookingsRange(
 	for rows.Next() {
 		var b BookingRecord
 		var isGuestInt int
+		var fields string
 		err := rows.Scan(
 			&b.ID, &b.ItemID, &b.UserID, &b.BookingDate,
 			&b.BookedByUserID, &isGuestInt, &b.GuestName, &b.GuestEmail, &b.Note, &b.CreatedAt, &b.UpdatedAt,
+			&b.CheckedInAt, &b.PendingConfirmation, &fields, &b.BundleID, &b.IsPrivate, &b.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan user booking: %w", err)
 		}
 		b.IsGuest = isGuestInt == 1
+		b.Fields = DecodeFields(fields)
 		result = append(result, b)
 	}
 	if err := rows.Err(); err != nil {
@@ -117,13 +147,16 @@ func ListUserBookingsRange(
 func FindBookingByID(ctx context.Context, store *sql.DB, bookingID string) (*BookingRecord, error) {
 	var b BookingRecord
 	var isGuestInt int
+	var fields string
 	err := store.QueryRowContext(ctx,
 		`SELECT id, item_id, user_id, booking_date, booked_by_user_id,
-		        is_guest, guest_name, guest_email, note, created_at, updated_at
+		        is_guest, guest_name, guest_email, note, created_at, updated_at,
+		        COALESCE(checked_in_at, ''), pending_confirmation, custom_fields, bundle_id, version, is_private
 		 FROM bookings WHERE id = ?`,
 		bookingID,
 	).Scan(&b.ID, &b.ItemID, &b.UserID, &b.BookingDate, &b.BookedByUserID,
-		&isGuestInt, &b.GuestName, &b.GuestEmail, &b.Note, &b.CreatedAt, &b.UpdatedAt)
+		&isGuestInt, &b.GuestName, &b.GuestEmail, &b.Note, &b.CreatedAt, &b.UpdatedAt,
+		&b.CheckedInAt, &b.PendingConfirmation, &fields, &b.BundleID, &b.Version, &b.IsPrivate)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -132,38 +165,61 @@ func FindBookingByID(ctx context.Context, store *sql.DB, bookingID string) (*Boo
 		return nil, fmt.Errorf("query booking by id: %w", err)
 	}
 	b.IsGuest = isGuestInt == 1
+	b.Fields = DecodeFields(fields)
 	return &b, nil
 }
 
-// UpdateNote sets the note text on a booking.
-func UpdateNote(ctx context.Context, store *sql.DB, bookingID, note string) error {
+// UpdateDetails sets the note, custom field values, and privacy of a booking
+// and increments its version.
+// A non-zero ifVersion must match the stored version, or ErrVersionConflict is returned.
+func UpdateDetails(
+	ctx context.Context, store *sql.DB, bookingID, note string, fields map[string]any, isPrivate bool,
+	ifVersion int,
+) error {
 	now := time.Now().UTC().Format(time.RFC3339)
-	_, err := store.ExecContext(ctx,
-		"UPDATE bookings SET note = ?, updated_at = ? WHERE id = ?",
-		note, now, bookingID,
+	result, err := store.ExecContext(ctx,
+		`UPDATE bookings SET note = ?, custom_fields = ?, is_private = ?, updated_at = ?, version = version + 1
+		 WHERE id = ? AND (? = 0 OR version = ?)`,
+		note, encodeFields(fields), isPrivate, now, bookingID, ifVersion, ifVersion,
 	)
 	if err != nil {
-		return fmt.Errorf("update booking note: %w", err)
+		return fmt.Errorf("update booking: %w", err)
 	}
-	return nil
+	return versionResult(result, ifVersion)
 }
 
 // DeleteBooking removes a booking by its ID.
-func DeleteBooking(ctx context.Context, store *sql.DB, bookingID string) error {
-	_, err := store.ExecContext(ctx, "DELETE FROM bookings WHERE id = ?", bookingID)
+// A non-zero ifVersion must match the stored version, or ErrVersionConflict is returned.
+func DeleteBooking(ctx context.Context, store *sql.DB, bookingID string, ifVersion int) error {
+	result, err := store.ExecContext(ctx,
+		"DELETE FROM bookings WHERE id = ? AND (? = 0 OR version = ?)", bookingID, ifVersion, ifVersion)
 	if err != nil {
 		return fmt.Errorf("delete booking: %w", err)
 	}
+	return versionResult(result, ifVersion)
+}
+
+// versionResult returns ErrVersionConflict when a versioned write matched no row.
+func versionResult(result sql.Result, ifVersion int) error {
+	if ifVersion == 0 {
+		return nil
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("booking rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrVersionConflict
+	}
 	return nil
 }
 
 // CountUserFutureBookings counts active (today and future) bookings for a user
 // matching the given item IDs. If itemIDs is nil, counts all future bookings.
+// today is the YYYY-MM-DD date in the time zone of the items' area.
 func CountUserFutureBookings(
-	ctx context.Context, store *sql.DB, userID string, itemIDs []string,
+	ctx context.Context, store *sql.DB, userID string, itemIDs []string, today string,
 ) (int, error) {
-	today := time.Now().UTC().Format(time.DateOnly)
-
 	var count int
 	if len(itemIDs) == 0 {
 		err := store.QueryRowContext(ctx,
@@ -194,14 +250,111 @@ func CountUserFutureBookings(
 	return count, nil
 }
 
+// listCountedBookingDates returns the booking date of every booking between
+// fromDate and toDate (inclusive) that counts against a policy subject: the
+// user's own bookings, or the guest bookings made by the user when guests is true.
+// If itemIDs is empty, bookings for all items are returned.
+func listCountedBookingDates(
+	ctx context.Context, store *sql.DB, userID string, guests bool, itemIDs []string, fromDate, toDate string,
+) (dates []string, err error) {
+	query := `SELECT booking_date FROM bookings WHERE user_id = ? AND booking_date >= ? AND booking_date <= ?`
+	if guests {
+		query = `SELECT booking_date FROM bookings
+		         WHERE booked_by_user_id = ? AND is_guest = 1 AND booking_date >= ? AND booking_date <= ?`
+	}
+	args := []any{userID, fromDate, toDate}
+	if len(itemIDs) > 0 {
+		inClause, inArgs := api.BuildINClause(itemIDs)
+		query += ` AND item_id IN (` + inClause + `)` //nolint:gosec // G202: "?" placeholders from BuildINClause
+		args = append(args, inArgs...)
+	}
+
+	rows, err := store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query counted bookings: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close counted bookings rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("scan counted booking: %w", err)
+		}
+		dates = append(dates, date)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate counted bookings: %w", err)
+	}
+	return dates, nil
+}
+
+// CountUserItemBookings returns, per item, how many bookings the user had
+// between fromDate and toDate (inclusive).
+func CountUserItemBookings(
+	ctx context.Context, store *sql.DB, userID, fromDate, toDate string,
+) (counts map[string]int, err error) {
+	rows, err := store.QueryContext(ctx,
+		`SELECT item_id, COUNT(*) FROM bookings
+		 WHERE user_id = ? AND booking_date >= ? AND booking_date <= ?
+		 GROUP BY item_id`,
+		userID, fromDate, toDate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query user item bookings: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close user item bookings rows: %w", closeErr)
+		}
+	}()
+
+	counts = make(map[string]int)
+	for rows.Next() {
+		var itemID string
+		var n int
+		if err := rows.Scan(&itemID, &n); err != nil {
+			return nil, fmt.Errorf("scan user item bookings: %w", err)
+		}
+		counts[itemID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate user item bookings: %w", err)
+	}
+	return counts, nil
+}
+
+// FindLastBookedItemID returns the item of the user's latest booking before
+// beforeDate, or "" if there is none.
+func FindLastBookedItemID(ctx context.Context, store *sql.DB, userID, beforeDate string) (string, error) {
+	var itemID string
+	err := store.QueryRowContext(ctx,
+		`SELECT item_id FROM bookings WHERE user_id = ? AND booking_date < ?
+		 ORDER BY booking_date DESC, created_at DESC LIMIT 1`,
+		userID, beforeDate,
+	).Scan(&itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("find last booked item: %w", err)
+	}
+	return itemID, nil
+}
+
 // ItemBookingInfo contains booking details for an item.
 type ItemBookingInfo struct {
-	BookingID  string
-	UserID     string
-	BookerName string
-	IsGuest    bool
-	GuestName  string
-	Note       string
+	BookingID      string
+	UserID         string
+	BookedByUserID string
+	BookerName     string
+	IsGuest        bool
+	GuestName      string
+	Note           string
+	IsPrivate      bool
 }
 
 // FindItemBookings returns booking info for items on a given date, keyed by item ID.
@@ -210,7 +363,8 @@ type ItemBookingInfo struct {
 func FindItemBookings(
 	ctx context.Context, store *sql.DB, bookingDate string,
 ) (result map[string]ItemBookingInfo, err error) {
-	query := `SELECT id, item_id, user_id, is_guest, guest_name, note FROM bookings WHERE booking_date = ?`
+	query := `SELECT id, item_id, user_id, booked_by_user_id, is_guest, guest_name, note, is_private
+	          FROM bookings WHERE booking_date = ?`
 
 	rows, err := store.QueryContext(ctx, query, bookingDate)
 	if err != nil {
@@ -227,7 +381,8 @@ func FindItemBookings(
 		var info ItemBookingInfo
 		var itemID string
 		var isGuestInt int
-		err := rows.Scan(&info.BookingID, &itemID, &info.UserID, &isGuestInt, &info.GuestName, &info.Note)
+		err := rows.Scan(&info.BookingID, &itemID, &info.UserID, &info.BookedByUserID, &isGuestInt,
+			&info.GuestName, &info.Note, &info.IsPrivate)
 		if err != nil {
 			return nil, fmt.Errorf("scan item booking: %w", err)
 		}
@@ -243,11 +398,13 @@ func FindItemBookings(
 
 // MatrixBookingInfo contains booking details for a single item+date cell.
 type MatrixBookingInfo struct {
-	BookingID  string
-	UserID     string
-	BookerName string
-	IsGuest    bool
-	GuestName  string
+	BookingID      string
+	UserID         string
+	BookedByUserID string
+	BookerName     string
+	IsGuest        bool
+	GuestName      string
+	IsPrivate      bool
 }
 
 // FindMatrixBookings returns booking info for a set of items across multiple dates.
@@ -269,7 +426,7 @@ func FindMatrixBookings(
 
 	//nolint:gosec // G201: placeholders are "?" literals from BuildINClause
 	query := fmt.Sprintf(
-		`SELECT id, item_id, booking_date, user_id, is_guest, guest_name
+		`SELECT id, item_id, booking_date, user_id, booked_by_user_id, is_guest, guest_name, is_private
 		 FROM bookings
 		 WHERE item_id IN (%s) AND booking_date IN (%s)`,
 		itemPlaceholders, datePlaceholders,
@@ -291,7 +448,8 @@ func FindMatrixBookings(
 		var itemID, bookingDate string
 		var isGuestInt int
 		if scanErr := rows.Scan(
-			&info.BookingID, &itemID, &bookingDate, &info.UserID, &isGuestInt, &info.GuestName,
+			&info.BookingID, &itemID, &bookingDate, &info.UserID, &info.BookedByUserID, &isGuestInt,
+			&info.GuestName, &info.IsPrivate,
 		); scanErr != nil {
 			return nil, fmt.Errorf("scan matrix booking: %w", scanErr)
 		}
@@ -304,3 +462,108 @@ func FindMatrixBookings(
 
 	return result, nil
 }
+
+// ListBookingsInRange returns bookings between fromDate and toDate (inclusive),
+// ordered by booking_date. If itemIDs is empty, bookings for all items are returned.
+func ListBookingsInRange(
+	ctx context.Context, store *sql.DB, itemIDs []string, fromDate, toDate string,
+) (result []BookingRecord, err error) {
+	query := `SELECT id, item_id, user_id, booking_date, booked_by_user_id,
+	                 is_guest, guest_name, guest_email, note, created_at, updated_at, is_private,
+	                 custom_fields, bundle_id
+	          FROM bookings
+	          WHERE booking_date >= ? AND booking_date <= ?`
+	args := []any{fromDate, toDate}
+	if len(itemIDs) > 0 {
+		inClause, inArgs := api.BuildINClause(itemIDs)
+		query += ` AND item_id IN (` + inClause + `)` //nolint:gosec // G202: "?" placeholders from BuildINClause
+		args = append(args, inArgs...)
+	}
+	query += ` ORDER BY booking_date, item_id`
+
+	rows, err := store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query bookings in range: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close bookings in range rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var b BookingRecord
+		var isGuestInt int
+		var fields string
+		if err := rows.Scan(
+			&b.ID, &b.ItemID, &b.UserID, &b.BookingDate,
+			&b.BookedByUserID, &isGuestInt, &b.GuestName, &b.GuestEmail, &b.Note, &b.CreatedAt, &b.UpdatedAt,
+			&b.IsPrivate, &fields, &b.BundleID,
+		); err != nil {
+			return nil, fmt.Errorf("scan booking in range: %w", err)
+		}
+		b.IsGuest = isGuestInt == 1
+		b.Fields = DecodeFields(fields)
+		result = append(result, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate bookings in range: %w", err)
+	}
+
+	return result, nil
+}
+
+// CancelBookings deletes the given bookings in a single transaction and sends a
+// cancellation notification for each. canceledByUserID identifies the acting user
+// (usually an admin) and reason is included in the notification payload.
+func CancelBookings(
+	ctx context.Context, store *sql.DB, notifier notifications.Notifier,
+	records []BookingRecord, canceledByUserID, reason string,
+) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin cancel bookings: %w", err)
+	}
+	for i := range records {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM bookings WHERE id = ?", records[i].ID); err != nil {
+			_ = tx.Rollback() //nolint:errcheck // Original error is more relevant
+			return fmt.Errorf("delete booking %s: %w", records[i].ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit cancel bookings: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i := range records {
+		rec := &records[i]
+		slog.Info("booking canceled",
+			"booking_id", rec.ID,
+			"canceled_by", canceledByUserID,
+			"item_id", rec.ItemID,
+			"booking_date", rec.BookingDate,
+			"reason", reason,
+		)
+		notifier.NotifyAsync(&notifications.BookingEvent{
+			Event:            notifications.EventBookingCanceled,
+			BookingID:        rec.ID,
+			ItemID:           rec.ItemID,
+			UserID:           rec.UserID,
+			BookingDate:      rec.BookingDate,
+			IsGuest:          rec.IsGuest,
+			GuestName:        rec.GuestName,
+			GuestEmail:       rec.GuestEmail,
+			CanceledByUserID: canceledByUserID,
+			Reason:           reason,
+			Fields:           rec.Fields,
+			BundleID:         rec.BundleID,
+			Private:          rec.IsPrivate,
+			Timestamp:        now,
+		})
+	}
+	return nil
+}
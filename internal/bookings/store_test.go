@@ -87,7 +87,7 @@ func TestCountUserFutureBookingsAll(t *testing.T) {
 	seedTestBooking(t, store, "b3", "desk-3", "user-1", yesterday) // past, excluded
 	seedTestBooking(t, store, "b4", "desk-4", "user-2", tomorrow)  // other user
 
-	count, err := CountUserFutureBookings(t.Context(), store, "user-1", nil)
+	count, err := CountUserFutureBookings(t.Context(), store, "user-1", nil, time.Now().UTC().Format(time.DateOnly))
 	require.NoError(t, err)
 	assert.Equal(t, 2, count)
 }
@@ -103,7 +103,8 @@ func TestCountUserFutureBookingsFiltered(t *testing.T) {
 	seedTestBooking(t, store, "b2", "desk-2", "user-1", dayAfter)
 	seedTestBooking(t, store, "b3", "desk-3", "user-1", tomorrow)
 
-	count, err := CountUserFutureBookings(t.Context(), store, "user-1", []string{"desk-1", "desk-2"})
+	count, err := CountUserFutureBookings(t.Context(), store, "user-1", []string{"desk-1", "desk-2"},
+		time.Now().UTC().Format(time.DateOnly))
 	require.NoError(t, err)
 	assert.Equal(t, 2, count)
 }
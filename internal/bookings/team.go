@@ -0,0 +1,536 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const (
+	resourceTypeTeamBooking = "team-bookings"
+	maxTeamSize             = 50
+)
+
+// ErrorCodeInsufficientItems is the JSON:API error code used when a team booking
+// cannot find enough free items.
+const ErrorCodeInsufficientItems = "insufficient_items"
+
+// TeamBookingRequest represents a team booking create JSON:API payload.
+// Either ItemGroupID (pick free items) or ItemIDs (one per member) is required.
+type TeamBookingRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			MemberIDs    []string `json:"member_ids"`
+			ItemGroupID  string   `json:"item_group_id"`
+			ItemIDs      []string `json:"item_ids"`
+			BookingDate  string   `json:"booking_date"`
+			BookingDates []string `json:"booking_dates"`
+			Note         string   `json:"note"`
+			// Fields holds custom field values that apply to every member's item.
+			Fields map[string]any `json:"fields"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// TeamAssignment is the item a team member was given for all booked dates.
+type TeamAssignment struct {
+	UserID     string   `json:"user_id"`
+	UserName   string   `json:"user_name"`
+	ItemID     string   `json:"item_id"`
+	ItemName   string   `json:"item_name"`
+	BookingIDs []string `json:"booking_ids"`
+	// PendingConfirmation is set when the member has not delegated to the
+	// booker and must confirm the bookings.
+	PendingConfirmation bool `json:"pending_confirmation,omitempty"`
+}
+
+// TeamBookingAttributes represents team booking resource attributes.
+type TeamBookingAttributes struct {
+	ItemGroupID  string           `json:"item_group_id,omitempty"`
+	BookingDates []string         `json:"booking_dates"`
+	Note         string           `json:"note,omitempty"`
+	Assignments  []TeamAssignment `json:"assignments"`
+}
+
+type teamMember struct {
+	id      string
+	name    string
+	email   string
+	pending bool
+}
+
+// teamBooking is a validated team booking: members[i] gets locs[i] on every date.
+type teamBooking struct {
+	bookedBy string
+	members  []teamMember
+	locs     []*areas.ItemLocation
+	dates    []string
+	note     string
+	// fields[i] holds the custom field values of the bookings of members[i].
+	fields []map[string]any
+	// bundleID links the bookings of a bundle; empty for team bookings.
+	bundleID string
+}
+
+// TeamHandlerDynamic returns a handler that books items for several colleagues at
+// once. All bookings are created in one transaction or not at all. When an item
+// group is given, free items that lie close together on the floor plan are picked.
+// POST /api/v1/bookings/team
+func TeamHandlerDynamic(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+	limits *BookingLimits, guards ...Guard,
+) echo.HandlerFunc {
+	maxWeeks := 0
+	if limits != nil {
+		maxWeeks = limits.WeeksInAdvanced
+	}
+
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		if err := validateContentType(c); err != nil {
+			if errors.Is(err, errResponseWritten) {
+				return nil
+			}
+			return err
+		}
+
+		ctx := c.Request().Context()
+		cfg := getConfig()
+		tb, err := prepareTeamBooking(ctx, c, store, cfg, user, maxWeeks, limits.confirmsOnBehalf(), guards)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+
+		rules := make([][]PolicyRule, len(tb.locs))
+		for i, loc := range tb.locs {
+			rules[i] = PolicyRules(cfg, loc, limits)
+		}
+		if err := checkTeamPolicies(ctx, store, cfg, tb, rules); err != nil {
+			return writeTeamError(c, err)
+		}
+
+		created, err := createTeamBookings(ctx, store, tb)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+
+		teamID := uuid.New().String()
+		notifyTeamBooking(notifier, teamID, tb, created)
+		slog.Info("team booking created",
+			"team_booking_id", teamID,
+			"booked_by", tb.bookedBy,
+			"members", len(tb.members),
+			"dates", len(tb.dates),
+		)
+
+		return api.WriteSingle(c, http.StatusCreated, api.Resource{
+			Type:       resourceTypeTeamBooking,
+			ID:         teamID,
+			Attributes: teamAttributes(tb, created),
+		}, "write team booking response")
+	}
+}
+
+// prepareTeamBooking parses the request, resolves the members, and selects an
+// item for each of them. Members must have delegated to the booker unless
+// confirm lets their bookings wait for confirmation.
+func prepareTeamBooking(
+	ctx context.Context, c echo.Context, store *sql.DB, cfg *areas.Config,
+	user *auth.User, maxWeeks int, confirm bool, guards []Guard,
+) (*teamBooking, error) {
+	var req TeamBookingRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, errBadRequest("Invalid request body")
+	}
+	if req.Data.Type != resourceTypeTeamBooking {
+		return nil, errBadRequest("Resource type must be 'team-bookings'")
+	}
+	a := req.Data.Attributes
+
+	note := strings.TrimSpace(a.Note)
+	if len(note) > maxNoteLength {
+		return nil, errBadRequest(fmt.Sprintf("Note must be at most %d characters", maxNoteLength))
+	}
+
+	allDates := requestedDates(a.BookingDate, a.BookingDates)
+	if len(allDates) == 0 {
+		return nil, errBadRequest("booking_date or booking_dates is required")
+	}
+	earliest, _ := cfg.TodayRange(time.Now())
+	today, _ := time.Parse(time.DateOnly, earliest)
+	dates, err := validateBookingDates(allDates, maxWeeks, today)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := resolveTeamMembers(ctx, store, user, confirm, a.MemberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tb := &teamBooking{bookedBy: user.ID, members: members, dates: dates, note: note}
+	groupID := strings.TrimSpace(a.ItemGroupID)
+	switch {
+	case groupID != "" && len(a.ItemIDs) > 0:
+		return nil, errBadRequest("Provide either item_group_id or item_ids, not both")
+	case groupID != "":
+		tb.locs, err = selectGroupItems(ctx, store, cfg, groupID, tb, guards)
+	case len(a.ItemIDs) > 0:
+		tb.locs, err = selectNamedItems(ctx, cfg, a.ItemIDs, tb, guards)
+	default:
+		return nil, errBadRequest("item_group_id or item_ids is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	tb.fields = make([]map[string]any, len(tb.locs))
+	for i, loc := range tb.locs {
+		if tb.fields[i], err = resolveFields(loc, nil, a.Fields, user); err != nil {
+			return nil, err
+		}
+	}
+	return tb, nil
+}
+
+func resolveTeamMembers(
+	ctx context.Context, store *sql.DB, user *auth.User, confirm bool, ids []string,
+) ([]teamMember, error) {
+	if len(ids) == 0 {
+		return nil, errBadRequest("member_ids is required")
+	}
+	if len(ids) > maxTeamSize {
+		return nil, errBadRequest(fmt.Sprintf("A team booking can include at most %d members", maxTeamSize))
+	}
+
+	seen := make(map[string]struct{}, len(ids))
+	members := make([]teamMember, 0, len(ids))
+	for _, raw := range ids {
+		id := strings.TrimSpace(raw)
+		if _, dup := seen[id]; dup {
+			return nil, errBadRequest("member_ids must not contain duplicates: " + id)
+		}
+		seen[id] = struct{}{}
+
+		rec, err := users.FindByID(ctx, store, id)
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, errBadRequest("member_ids: user not found: " + id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("find team member: %w", err)
+		}
+		pending, err := authorizeOnBehalf(ctx, store, user, rec, confirm)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, teamMember{
+			id: rec.ID, name: rec.DisplayName, email: strings.TrimSpace(rec.Email), pending: pending,
+		})
+	}
+	return members, nil
+}
+
+// selectNamedItems assigns the named items to the members in order.
+func selectNamedItems(
+	ctx context.Context, cfg *areas.Config, itemIDs []string, tb *teamBooking, guards []Guard,
+) ([]*areas.ItemLocation, error) {
+	if len(itemIDs) != len(tb.members) {
+		return nil, errBadRequest(fmt.Sprintf(
+			"item_ids must name one item per member (%d members, %d items)", len(tb.members), len(itemIDs)))
+	}
+
+	seen := make(map[string]struct{}, len(itemIDs))
+	locs := make([]*areas.ItemLocation, len(itemIDs))
+	for i, raw := range itemIDs {
+		id := strings.TrimSpace(raw)
+		if _, dup := seen[id]; dup {
+			return nil, errBadRequest("item_ids must not contain duplicates: " + id)
+		}
+		seen[id] = struct{}{}
+
+		loc, ok := cfg.FindItemLocation(id)
+		if !ok {
+			return nil, &Rejection{Status: http.StatusNotFound, Code: "not_found", Detail: "Item not found: " + id}
+		}
+		member := tb.members[i]
+		if areas.IsReserved(loc, member.email) {
+			return nil, &Rejection{
+				Status: http.StatusForbidden,
+				Code:   "forbidden",
+				Detail: fmt.Sprintf("%s: %s", member.name, reservationForbiddenMessage(loc)),
+			}
+		}
+		if err := runGuards(ctx, guards, teamGuardRequest(loc, member.id, tb)); err != nil {
+			return nil, err
+		}
+		locs[i] = loc
+	}
+	return locs, nil
+}
+
+// selectGroupItems picks one free item per member from the item group,
+// preferring items that are adjacent on the floor plan. Items that any member
+// may not book, that are booked on one of the dates, or that a guard refuses are
+// skipped.
+func selectGroupItems(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, groupID string, tb *teamBooking, guards []Guard,
+) ([]*areas.ItemLocation, error) {
+	area, ig := findItemGroupWithArea(cfg, groupID)
+	if ig == nil {
+		return nil, &Rejection{Status: http.StatusNotFound, Code: "not_found", Detail: "Item group not found"}
+	}
+
+	booked := make(map[string]struct{})
+	for _, date := range tb.dates {
+		ids, err := FindBookedItemIDs(ctx, store, date)
+		if err != nil {
+			return nil, fmt.Errorf("find booked items: %w", err)
+		}
+		for id := range ids {
+			booked[id] = struct{}{}
+		}
+	}
+
+	var candidates []*areas.ItemLocation
+	var refusal error
+	for i := range ig.Items {
+		loc := &areas.ItemLocation{Area: area, ItemGroup: ig, Item: &ig.Items[i]}
+		if _, taken := booked[loc.Item.ID]; taken || reservedForAnyMember(loc, tb.members) {
+			continue
+		}
+		if err := runGuards(ctx, guards, teamGuardRequest(loc, tb.members[0].id, tb)); err != nil {
+			var rej *Rejection
+			if !errors.As(err, &rej) {
+				return nil, err
+			}
+			if refusal == nil {
+				refusal = err
+			}
+			continue
+		}
+		candidates = append(candidates, loc)
+	}
+
+	if len(candidates) < len(tb.members) {
+		// When every item was refused for the same kind of reason (e.g. the
+		// office is closed), that reason is more helpful than a count.
+		if len(candidates) == 0 && refusal != nil {
+			return nil, refusal
+		}
+		return nil, &Rejection{
+			Status: http.StatusConflict,
+			Code:   ErrorCodeInsufficientItems,
+			Detail: fmt.Sprintf("Only %d of %d requested items in %q are free on all requested dates",
+				len(candidates), len(tb.members), ig.Name),
+		}
+	}
+
+	positions, err := loadItemCenters(ctx, store, firstNonEmpty(ig.FloorPlan, area.FloorPlan))
+	if err != nil {
+		return nil, err
+	}
+	return pickAdjacent(candidates, positions, len(tb.members)), nil
+}
+
+func findItemGroupWithArea(cfg *areas.Config, groupID string) (*areas.Area, *areas.ItemGroup) {
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		for j := range area.ItemGroups {
+			if area.ItemGroups[j].ID == groupID {
+				return area, &area.ItemGroups[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+func reservedForAnyMember(loc *areas.ItemLocation, members []teamMember) bool {
+	for _, m := range members {
+		if areas.IsReserved(loc, m.email) {
+			return true
+		}
+	}
+	return false
+}
+
+func teamGuardRequest(loc *areas.ItemLocation, userID string, tb *teamBooking) *GuardRequest {
+	return &GuardRequest{Location: loc, UserID: userID, BookedByUserID: tb.bookedBy, Dates: tb.dates}
+}
+
+// checkTeamPolicies evaluates the booking policies for every member against the
+// item they were given. Violations are prefixed with the member's name.
+func checkTeamPolicies(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, tb *teamBooking, rules [][]PolicyRule,
+) error {
+	now := time.Now()
+	for i, member := range tb.members {
+		ev := &policyEvaluation{
+			store:   store,
+			loc:     tb.locs[i],
+			subject: policySubject{userID: member.id, email: member.email},
+			dates:   tb.dates,
+			now:     now.In(cfg.AreaZone(tb.locs[i].Area)),
+		}
+		err := evaluatePolicies(ctx, ev, rules[i])
+		if err == nil {
+			continue
+		}
+		var rej *Rejection
+		if errors.As(err, &rej) {
+			return &Rejection{Status: rej.Status, Code: rej.Code, Detail: member.name + ": " + rej.Detail}
+		}
+		if errors.Is(err, ErrBookingLimitExceeded) {
+			return fmt.Errorf("%s: %w", member.name, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// createTeamBookings inserts all bookings in a single transaction. If any item
+// was booked in the meantime, nothing is written and ErrConflict is returned.
+// The result holds the bookings per member, in date order.
+func createTeamBookings(ctx context.Context, store *sql.DB, tb *teamBooking) (result [][]*Booking, err error) {
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin team booking: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback() //nolint:errcheck // Rollback after failure, original error wins
+		}
+	}()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	result = make([][]*Booking, len(tb.members))
+	for i, member := range tb.members {
+		for _, date := range tb.dates {
+			b := &Booking{
+				ID:                  uuid.New().String(),
+				ItemID:              tb.locs[i].Item.ID,
+				UserID:              member.id,
+				BookedByUserID:      tb.bookedBy,
+				BookingDate:         date,
+				Note:                tb.note,
+				CreatedAt:           now,
+				UpdatedAt:           now,
+				PendingConfirmation: member.pending,
+				Fields:              tb.fields[i],
+				BundleID:            tb.bundleID,
+			}
+			if err := insertBooking(ctx, tx, b); err != nil {
+				return nil, err
+			}
+			result[i] = append(result[i], b)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit team booking: %w", err)
+	}
+	return result, nil
+}
+
+// notifyTeamBooking sends one booking.created event per booking and a
+// team_booking.created summary for the booker.
+func notifyTeamBooking(notifier notifications.Notifier, teamID string, tb *teamBooking, created [][]*Booking) {
+	summary := &notifications.BookingEvent{
+		Event:          notifications.EventTeamBookingCreated,
+		BookingID:      teamID,
+		UserID:         tb.bookedBy,
+		BookedByUserID: tb.bookedBy,
+		BookingDate:    tb.dates[0],
+		TeamBookingID:  teamID,
+		BookingDates:   tb.dates,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for i, member := range tb.members {
+		summary.MemberUserIDs = append(summary.MemberUserIDs, member.id)
+		for _, b := range created[i] {
+			summary.BookingIDs = append(summary.BookingIDs, b.ID)
+			event := &notifications.BookingEvent{
+				Event:               notifications.EventBookingCreated,
+				BookingID:           b.ID,
+				ItemID:              b.ItemID,
+				UserID:              b.UserID,
+				BookingDate:         b.BookingDate,
+				TeamBookingID:       teamID,
+				Timestamp:           summary.Timestamp,
+				PendingConfirmation: b.PendingConfirmation,
+				Fields:              b.Fields,
+				Private:             b.IsPrivate,
+			}
+			if b.BookedByUserID != b.UserID {
+				event.BookedByUserID = b.BookedByUserID
+			}
+			notifier.NotifyAsync(event)
+		}
+	}
+	notifier.NotifyAsync(summary)
+}
+
+func teamAttributes(tb *teamBooking, created [][]*Booking) TeamBookingAttributes {
+	attrs := TeamBookingAttributes{
+		BookingDates: tb.dates,
+		Note:         tb.note,
+		Assignments:  make([]TeamAssignment, len(tb.members)),
+	}
+	groups := make(map[string]struct{})
+	for i, member := range tb.members {
+		loc := tb.locs[i]
+		groups[loc.ItemGroup.ID] = struct{}{}
+		ids := make([]string, len(created[i]))
+		for j, b := range created[i] {
+			ids[j] = b.ID
+		}
+		attrs.Assignments[i] = TeamAssignment{
+			UserID:              member.id,
+			UserName:            member.name,
+			ItemID:              loc.Item.ID,
+			ItemName:            loc.Item.Name,
+			BookingIDs:          ids,
+			PendingConfirmation: member.pending,
+		}
+	}
+	if len(groups) == 1 {
+		attrs.ItemGroupID = tb.locs[0].ItemGroup.ID
+	}
+	return attrs
+}
+
+// writeTeamError writes the JSON:API error for a failed team booking.
+func writeTeamError(c echo.Context, err error) error {
+	var valErr validationError
+	var rej *Rejection
+	switch {
+	case errors.As(err, &valErr):
+		return api.WriteBadRequest(c, valErr.detail)
+	case errors.As(err, &rej):
+		return api.WriteError(c, rej.Status, rej.Detail, rej.Code)
+	case errors.Is(err, ErrBookingLimitExceeded):
+		return api.WriteConflict(c, err.Error())
+	case errors.Is(err, ErrConflict):
+		return api.WriteConflict(c, "One of the selected items was booked in the meantime. No bookings were created.")
+	default:
+		return api.WriteInternalError(c, "create team booking", err)
+	}
+}
@@ -0,0 +1,96 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/floorplanpos"
+)
+
+// unplacedDistance is the distance assumed to or from an item without a floor
+// plan position, so that positioned items are always preferred.
+const unplacedDistance = 1e9
+
+type point struct {
+	x, y float64
+}
+
+// loadItemCenters returns the center of every item drawn on the floor plan.
+func loadItemCenters(ctx context.Context, store *sql.DB, floorPlan string) (map[string]point, error) {
+	centers := make(map[string]point)
+	if floorPlan == "" {
+		return centers, nil
+	}
+	positions, err := floorplanpos.FindByFloorPlan(ctx, store, floorPlan)
+	if err != nil {
+		return nil, fmt.Errorf("load floor plan positions: %w", err)
+	}
+	for i := range positions {
+		p := &positions[i]
+		if _, seen := centers[p.ItemID]; !seen {
+			centers[p.ItemID] = point{x: p.X + p.Width/2, y: p.Y + p.Height/2}
+		}
+	}
+	return centers, nil
+}
+
+// pickAdjacent chooses n of the candidates that lie close together. Starting
+// from every candidate in turn, it grows a cluster by repeatedly adding the
+// candidate nearest to any item already in the cluster, and keeps the cluster
+// with the smallest total link length. Ties keep configuration order, so items
+// without positions are picked in the order they are configured.
+func pickAdjacent(candidates []*areas.ItemLocation, centers map[string]point, n int) []*areas.ItemLocation {
+	if n >= len(candidates) {
+		return candidates
+	}
+
+	var best []int
+	bestCost := math.Inf(1)
+	for seed := range candidates {
+		cluster, cost := growCluster(candidates, centers, seed, n)
+		if cost < bestCost {
+			best, bestCost = cluster, cost
+		}
+	}
+
+	result := make([]*areas.ItemLocation, len(best))
+	for i, idx := range best {
+		result[i] = candidates[idx]
+	}
+	return result
+}
+
+func growCluster(candidates []*areas.ItemLocation, centers map[string]point, seed, n int) ([]int, float64) {
+	cluster := []int{seed}
+	used := map[int]bool{seed: true}
+	cost := 0.0
+	for len(cluster) < n {
+		next, nextDist := -1, math.Inf(1)
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			for _, member := range cluster {
+				if d := itemDistance(candidates[i], candidates[member], centers); d < nextDist {
+					next, nextDist = i, d
+				}
+			}
+		}
+		cluster = append(cluster, next)
+		used[next] = true
+		cost += nextDist
+	}
+	return cluster, cost
+}
+
+func itemDistance(a, b *areas.ItemLocation, centers map[string]point) float64 {
+	pa, okA := centers[a.Item.ID]
+	pb, okB := centers[b.Item.ID]
+	if !okA || !okB {
+		return unplacedDistance
+	}
+	return math.Hypot(pa.x-pb.x, pa.y-pb.y)
+}
@@ -0,0 +1,227 @@
+package bookings
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/floorplanpos"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*notifications.BookingEvent
+}
+
+func (n *recordingNotifier) NotifyAsync(event *notifications.BookingEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+}
+
+func teamAreasConfig() *areas.Config {
+	desks := make([]areas.Item, 0, 5)
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		desks = append(desks, areas.Item{ID: "desk-" + id, Name: "Desk " + strings.ToUpper(id)})
+	}
+	return &areas.Config{Areas: []areas.Area{{
+		ID: "area-1", Name: "Office",
+		ItemGroups: []areas.ItemGroup{{ID: "room-1", Name: "Room 1", FloorPlan: "room1.svg", Items: desks}},
+	}}}
+}
+
+func seedTeamPositions(t *testing.T, store *sql.DB) {
+	t.Helper()
+	coords := map[string]float64{"desk-a": 0, "desk-b": 500, "desk-c": 100, "desk-d": 110, "desk-e": 120}
+	for itemID, x := range coords {
+		_, err := floorplanpos.Create(context.Background(), store, &floorplanpos.CreateInput{
+			FloorPlan: "room1.svg", ItemID: itemID, X: x, Y: x, Width: 10, Height: 10,
+		})
+		require.NoError(t, err)
+	}
+}
+
+func postTeamBooking(
+	t *testing.T, cfg *areas.Config, store *sql.DB, notifier notifications.Notifier, attributes string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	body := `{"data":{"type":"team-bookings","attributes":{` + attributes + `}}}`
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings/team", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "lead", Name: "Team Lead"})
+	seedOrgWideDelegate(t, store, "lead")
+
+	h := TeamHandlerDynamic(func() *areas.Config { return cfg }, store, notifier, &BookingLimits{WeeksInAdvanced: 52})
+	require.NoError(t, h(c))
+	return rec
+}
+
+func seedTeam(t *testing.T, store *sql.DB, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		seedTestUser(t, store, id, "User "+id)
+	}
+}
+
+func countBookings(t *testing.T, store *sql.DB) int {
+	t.Helper()
+	var n int
+	require.NoError(t, store.QueryRow(`SELECT COUNT(*) FROM bookings`).Scan(&n))
+	return n
+}
+
+func TestTeamHandlerPicksAdjacentFreeItems(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "lead", "u1", "u2")
+	seedTeamPositions(t, store)
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	dayAfter := time.Now().UTC().AddDate(0, 0, 2).Format(time.DateOnly)
+	// desk-c is close to desk-d and desk-e but taken on the second day.
+	seedTestBooking(t, store, "b1", "desk-c", "someone", dayAfter)
+	notifier := &recordingNotifier{}
+
+	rec := postTeamBooking(t, teamAreasConfig(), store, notifier,
+		`"member_ids":["lead","u1"],"item_group_id":"room-1","booking_dates":["`+date+`","`+dayAfter+`"]`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Data struct {
+			ID         string                `json:"id"`
+			Attributes TeamBookingAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs := resp.Data.Attributes
+	require.Len(t, attrs.Assignments, 2)
+	got := []string{attrs.Assignments[0].ItemID, attrs.Assignments[1].ItemID}
+	sort.Strings(got)
+	assert.Equal(t, []string{"desk-d", "desk-e"}, got)
+	assert.Equal(t, "room-1", attrs.ItemGroupID)
+	assert.Len(t, attrs.Assignments[0].BookingIDs, 2)
+	assert.Equal(t, 5, countBookings(t, store))
+
+	// Four booking.created events plus one summary.
+	require.Len(t, notifier.events, 5)
+	summary := notifier.events[4]
+	assert.Equal(t, notifications.EventTeamBookingCreated, summary.Event)
+	assert.Equal(t, resp.Data.ID, summary.TeamBookingID)
+	assert.Len(t, summary.BookingIDs, 4)
+	assert.Equal(t, []string{"lead", "u1"}, summary.MemberUserIDs)
+	assert.Equal(t, notifications.EventBookingCreated, notifier.events[0].Event)
+	assert.Equal(t, resp.Data.ID, notifier.events[0].TeamBookingID)
+}
+
+func TestTeamHandlerIsAllOrNothing(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "u1", "u2")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	dayAfter := time.Now().UTC().AddDate(0, 0, 2).Format(time.DateOnly)
+	seedTestBooking(t, store, "b1", "desk-b", "someone", dayAfter)
+
+	rec := postTeamBooking(t, teamAreasConfig(), store, &recordingNotifier{},
+		`"member_ids":["u1","u2"],"item_ids":["desk-a","desk-b"],"booking_dates":["`+date+`","`+dayAfter+`"]`)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "No bookings were created")
+	assert.Equal(t, 1, countBookings(t, store))
+}
+
+func TestTeamHandlerInsufficientItems(t *testing.T) {
+	t.Parallel()
+
+	cfg := teamAreasConfig()
+	cfg.Areas[0].ItemGroups[0].Items = cfg.Areas[0].ItemGroups[0].Items[:2]
+	store := setupTestStore(t)
+	seedTeam(t, store, "u1", "u2", "u3")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	rec := postTeamBooking(t, cfg, store, &recordingNotifier{},
+		`"member_ids":["u1","u2","u3"],"item_group_id":"room-1","booking_date":"`+date+`"`)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var resp api.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, ErrorCodeInsufficientItems, resp.Errors[0].Code)
+	assert.Contains(t, resp.Errors[0].Detail, "Only 2 of 3")
+}
+
+func TestTeamHandlerAppliesMemberChecks(t *testing.T) {
+	t.Parallel()
+
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	t.Run("reservation", func(t *testing.T) {
+		t.Parallel()
+		cfg := teamAreasConfig()
+		cfg.Areas[0].ItemGroups[0].Items[1].ReservedFor = []string{"u1@test.local"}
+		store := setupTestStore(t)
+		seedTeam(t, store, "u1", "u2")
+
+		rec := postTeamBooking(t, cfg, store, &recordingNotifier{},
+			`"member_ids":["u1","u2"],"item_ids":["desk-a","desk-b"],"booking_date":"`+date+`"`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "User u2")
+		assert.Equal(t, 0, countBookings(t, store))
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		t.Parallel()
+		cfg := teamAreasConfig()
+		cfg.Areas[0].Policies = []areas.Policy{{Name: "Once a day", MaxPerDay: 1}}
+		store := setupTestStore(t)
+		seedTeam(t, store, "u1", "u2")
+		seedTestBooking(t, store, "b1", "desk-e", "u2", date)
+
+		rec := postTeamBooking(t, cfg, store, &recordingNotifier{},
+			`"member_ids":["u1","u2"],"item_group_id":"room-1","booking_date":"`+date+`"`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), "User u2: Once a day")
+		assert.Equal(t, 1, countBookings(t, store))
+	})
+}
+
+func TestTeamHandlerValidation(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "u1")
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	cases := map[string]string{
+		`"item_group_id":"room-1","booking_date":"` + date + `"`:                           "member_ids is required",
+		`"member_ids":["u1"],"booking_date":"` + date + `"`:                                "item_group_id or item_ids",
+		`"member_ids":["u1","u1"],"item_group_id":"room-1","booking_date":"` + date + `"`:  "duplicates",
+		`"member_ids":["nobody"],"item_group_id":"room-1","booking_date":"` + date + `"`:   "user not found",
+		`"member_ids":["u1"],"item_ids":["desk-a","desk-b"],"booking_date":"` + date + `"`: "one item per member",
+	}
+	for attributes, expected := range cases {
+		rec := postTeamBooking(t, teamAreasConfig(), store, &recordingNotifier{}, attributes)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, attributes)
+		assert.Contains(t, rec.Body.String(), expected)
+	}
+}
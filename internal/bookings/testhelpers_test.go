@@ -67,3 +67,33 @@ func seedTestBookingWithGuest(
 	)
 	require.NoError(t, err)
 }
+
+// seedDelegation lets delegateID book for userID, or for every user if userID
+// is empty. Missing users are created.
+func seedDelegation(t *testing.T, store *sql.DB, userID, delegateID string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, id := range []string{userID, delegateID} {
+		if id == "" {
+			continue
+		}
+		_, err := store.Exec(
+			`INSERT OR IGNORE INTO users (id, email, display_name, user_source, created_at, updated_at)
+			 VALUES (?, ?, ?, 'internal', ?, ?)`,
+			id, id+"@test.local", "User "+id, now, now,
+		)
+		require.NoError(t, err)
+	}
+	_, err := store.Exec(
+		`INSERT OR IGNORE INTO delegations (id, user_id, delegate_id, created_by_user_id, created_at)
+		 VALUES (?, NULLIF(?, ''), ?, ?, ?)`,
+		userID+">"+delegateID, userID, delegateID, delegateID, now,
+	)
+	require.NoError(t, err)
+}
+
+// seedOrgWideDelegate lets delegateID book for every user.
+func seedOrgWideDelegate(t *testing.T, store *sql.DB, delegateID string) {
+	t.Helper()
+	seedDelegation(t, store, "", delegateID)
+}
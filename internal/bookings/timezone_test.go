@@ -0,0 +1,106 @@
+package bookings
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+// UTC+14 and UTC-12 are 26 hours apart, so their local dates always differ.
+const (
+	zoneAhead  = "Pacific/Kiritimati"
+	zoneBehind = "Etc/GMT+12"
+)
+
+// twoZoneConfig returns desk-east in an area at UTC+14 and desk-west in an
+// area at UTC-12.
+func twoZoneConfig() *areas.Config {
+	area := func(id, zone, itemID string) areas.Area {
+		return areas.Area{
+			ID: id, Name: id, Timezone: zone,
+			ItemGroups: []areas.ItemGroup{{
+				ID: id + "-room", Name: "Room",
+				Items: []areas.Item{{ID: itemID, Name: itemID}},
+			}},
+		}
+	}
+	return &areas.Config{Areas: []areas.Area{
+		area("east", zoneAhead, "desk-east"),
+		area("west", zoneBehind, "desk-west"),
+	}}
+}
+
+func localToday(t *testing.T, zone string) string {
+	t.Helper()
+	loc, err := time.LoadLocation(zone)
+	require.NoError(t, err)
+	return time.Now().In(loc).Format(time.DateOnly)
+}
+
+func TestCreateHandlerUsesAreaLocalToday(t *testing.T) {
+	t.Parallel()
+	cfg := twoZoneConfig()
+	store := setupTestStore(t)
+	h := CreateHandler(cfg, store, testNotifier())
+
+	create := func(itemID, date string) *httptest.ResponseRecorder {
+		body := `{"data":{"type":"bookings","attributes":{"item_id":"` + itemID +
+			`","booking_date":"` + date + `"}}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
+		require.NoError(t, h(c))
+		return rec
+	}
+
+	westToday := localToday(t, zoneBehind)
+	assert.Equal(t, http.StatusCreated, create("desk-west", westToday).Code)
+	// The same date is already past at UTC+14.
+	rec := create("desk-east", westToday)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "past")
+	assert.Equal(t, http.StatusCreated, create("desk-east", localToday(t, zoneAhead)).Code)
+}
+
+func TestListHandlerFiltersByAreaLocalToday(t *testing.T) {
+	t.Parallel()
+	cfg := twoZoneConfig()
+	store := setupTestStore(t)
+
+	westToday := localToday(t, zoneBehind)
+	seedTestBooking(t, store, "b-west", "desk-west", "user-1", westToday)
+	seedTestBooking(t, store, "b-east", "desk-east", "user-1", westToday)
+
+	list := func(h echo.HandlerFunc) []string {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/bookings", http.NoBody)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Set("user", &auth.User{ID: "user-1", Name: "Test User"})
+		require.NoError(t, h(c))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp api.CollectionResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		ids := make([]string, 0, len(resp.Data))
+		for _, r := range resp.Data {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"b-west"}, list(ListHandler(cfg, store)))
+	assert.Equal(t, []string{"b-east"}, list(HistoryHandler(cfg, store)))
+}
@@ -0,0 +1,270 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/favorites"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+const resourceTypeUsualBooking = "usual-bookings"
+
+// UsualBookingRequest represents a "book my usual" JSON:API payload.
+type UsualBookingRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			BookingDate  string   `json:"booking_date"`
+			BookingDates []string `json:"booking_dates"`
+			Note         string   `json:"note"`
+			// Fields holds the custom field values of the booked item.
+			Fields map[string]any `json:"fields"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// lastBooked is an item the user booked recently.
+type lastBooked struct {
+	loc   *areas.ItemLocation
+	date  string
+	count int
+}
+
+// UsualHandlerDynamic returns a handler that books the current user's usual
+// item on all requested dates: the first favorite free on all dates, else a
+// free item in the item group of a favorite. Users without favorites get
+// their last booked item or a neighbor of it. Favorites of items removed
+// from the areas config are skipped.
+// POST /api/v1/bookings/usual
+func UsualHandlerDynamic(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+	limits *BookingLimits, guards ...Guard,
+) echo.HandlerFunc {
+	maxWeeks := 0
+	if limits != nil {
+		maxWeeks = limits.WeeksInAdvanced
+	}
+
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		if err := validateContentType(c); err != nil {
+			if errors.Is(err, errResponseWritten) {
+				return nil
+			}
+			return err
+		}
+
+		var req UsualBookingRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeUsualBooking {
+			return api.WriteBadRequest(c, "Resource type must be 'usual-bookings'")
+		}
+		a := req.Data.Attributes
+		note := strings.TrimSpace(a.Note)
+		if len(note) > maxNoteLength {
+			return api.WriteBadRequest(c, fmt.Sprintf("Note must be at most %d characters", maxNoteLength))
+		}
+		allDates := requestedDates(a.BookingDate, a.BookingDates)
+		if len(allDates) == 0 {
+			return api.WriteBadRequest(c, "booking_date or booking_dates is required")
+		}
+		cfg := getConfig()
+		earliest, _ := cfg.TodayRange(time.Now())
+		today, _ := time.Parse(time.DateOnly, earliest)
+		dates, err := validateBookingDates(allDates, maxWeeks, today)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+
+		ctx := c.Request().Context()
+		member, err := autoBookingUser(ctx, store, user.ID)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		seeds, err := usualSeeds(ctx, store, cfg, member.id)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		if len(seeds) == 0 {
+			return api.WriteError(c, http.StatusConflict,
+				"You have no favorites and no recent bookings to book from", ErrorCodeNoItemAvailable)
+		}
+		best, ok, err := pickUsualItem(ctx, store, cfg, member, seeds, dates, guards)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		if !ok {
+			return api.WriteError(c, http.StatusConflict,
+				"Neither your usual items nor their item groups are free on all requested dates",
+				ErrorCodeNoItemAvailable)
+		}
+		if err := CheckPolicies(ctx, store, cfg, limits, best.loc, member.id, member.email, dates); err != nil {
+			return writeTeamError(c, err)
+		}
+		fields, err := resolveFields(best.loc, nil, a.Fields, user)
+		if err != nil {
+			return writeTeamError(c, err)
+		}
+		return bookBestSuggestion(c, store, notifier, member, best, dates, note, fields,
+			resourceTypeUsualBooking)
+	}
+}
+
+// usualSeeds returns the user's favorites that still exist, in order of
+// preference, or the last booked item if the user has no favorites. The
+// suggestion reasons name where each item comes from.
+func usualSeeds(ctx context.Context, store *sql.DB, cfg *areas.Config, userID string) ([]suggestion, error) {
+	favs, err := favorites.List(ctx, store, userID)
+	if err != nil {
+		return nil, err
+	}
+	var seeds []suggestion
+	for i := range favs {
+		loc, ok := cfg.FindItemLocation(favs[i].ItemID)
+		if !ok {
+			slog.Warn("skip favorite of unknown item", "user_id", userID, "item_id", favs[i].ItemID)
+			continue
+		}
+		seeds = append(seeds, suggestion{loc: loc, reasons: []string{fmt.Sprintf("Favorite #%d", i+1)}})
+	}
+	if len(favs) > 0 {
+		return seeds, nil
+	}
+
+	recent, err := lastBookedItems(ctx, store, cfg, userID)
+	if err != nil || len(recent) == 0 {
+		return nil, err
+	}
+	return []suggestion{{loc: recent[0].loc, reasons: []string{"Last booked on " + recent[0].date}}}, nil
+}
+
+// pickUsualItem returns the first seed the user can book on all dates, or else
+// the first bookable item in the item group of a seed.
+func pickUsualItem(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, user teamMember,
+	seeds []suggestion, dates []string, guards []Guard,
+) (suggestion, bool, error) {
+	free, err := NewFreeItems(ctx, store, cfg, guards, user.id, user.id, user.email, dates)
+	if err != nil {
+		return suggestion{}, false, err
+	}
+
+	for _, seed := range seeds {
+		if ok, err := free.Bookable(ctx, seed.loc); err != nil || ok {
+			return seed, ok, err
+		}
+	}
+	seen := make(map[string]bool)
+	for _, seed := range seeds {
+		ig := seed.loc.ItemGroup
+		if seen[ig.ID] {
+			continue
+		}
+		seen[ig.ID] = true
+		for i := range ig.Items {
+			loc := &areas.ItemLocation{Area: seed.loc.Area, ItemGroup: ig, Item: &ig.Items[i]}
+			ok, err := free.Bookable(ctx, loc)
+			if err != nil {
+				return suggestion{}, false, err
+			}
+			if ok {
+				reason := fmt.Sprintf("Same item group as %s (%s)", seed.loc.Item.Name, seed.reasons[0])
+				return suggestion{loc: loc, reasons: []string{reason}}, true, nil
+			}
+		}
+	}
+	return suggestion{}, false, nil
+}
+
+// lastBookedItems returns the items the user booked for themselves in the
+// last historyWindowDays, most recently booked first. Items removed from the
+// areas config are left out.
+func lastBookedItems(ctx context.Context, store *sql.DB, cfg *areas.Config, userID string) ([]lastBooked, error) {
+	today := areas.CalendarDay(time.Now().In(cfg.DefaultZone()))
+	records, err := ListUserBookingsRange(ctx, store, userID,
+		today.AddDate(0, 0, -historyWindowDays).Format(time.DateOnly), today.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	var result []lastBooked
+	index := make(map[string]int)
+	for i := range records {
+		r := &records[i]
+		if r.UserID != userID || r.IsGuest {
+			continue
+		}
+		if j, ok := index[r.ItemID]; ok {
+			result[j].count++
+			continue
+		}
+		loc, ok := cfg.FindItemLocation(r.ItemID)
+		if !ok {
+			continue
+		}
+		index[r.ItemID] = len(result)
+		result = append(result, lastBooked{loc: loc, date: r.BookingDate, count: 1})
+	}
+	return result, nil
+}
+
+// FavoriteSuggestionsHandler suggests items the current user booked recently
+// but has not marked as favorites yet, most recently booked first.
+// GET /api/v1/me/favorites/suggestions
+func FavoriteSuggestionsHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		ctx := c.Request().Context()
+		recent, err := lastBookedItems(ctx, store, getConfig(), user.ID)
+		if err != nil {
+			return api.WriteInternalError(c, "list last booked items", err)
+		}
+		favs, err := favorites.List(ctx, store, user.ID)
+		if err != nil {
+			return api.WriteInternalError(c, "list favorites", err)
+		}
+		isFavorite := make(map[string]bool, len(favs))
+		for i := range favs {
+			isFavorite[favs[i].ItemID] = true
+		}
+
+		var suggestions []suggestion
+		for _, r := range recent {
+			if isFavorite[r.loc.Item.ID] {
+				continue
+			}
+			suggestions = append(suggestions, suggestion{
+				loc:   r.loc,
+				score: r.count,
+				reasons: []string{
+					"Last booked on " + r.date,
+					fmt.Sprintf("Booked %d times in the last %d days", r.count, historyWindowDays),
+				},
+			})
+		}
+		if len(suggestions) > defaultSuggestionLimit {
+			suggestions = suggestions[:defaultSuggestionLimit]
+		}
+		return writeSuggestions(c, suggestions)
+	}
+}
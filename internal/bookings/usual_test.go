@@ -0,0 +1,123 @@
+package bookings
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/favorites"
+)
+
+func usualResult(t *testing.T, rec *httptest.ResponseRecorder) AutoBookingAttributes {
+	t.Helper()
+	var resp struct {
+		Data struct {
+			Type       string                `json:"type"`
+			Attributes AutoBookingAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "usual-bookings", resp.Data.Type)
+	return resp.Data.Attributes
+}
+
+func TestUsualHandler(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "u1", "u2")
+	cfg := bundleAreasConfig()
+	notifier := &recordingNotifier{}
+	h := UsualHandlerDynamic(func() *areas.Config { return cfg }, store, notifier, &BookingLimits{WeeksInAdvanced: 52})
+	u1 := &auth.User{ID: "u1", Name: "User u1"}
+	day := func(offset int) string { return time.Now().UTC().AddDate(0, 0, offset).Format(time.DateOnly) }
+	bookUsual := func(date string) *httptest.ResponseRecorder {
+		return sendBookingJSON(t, h, http.MethodPost, "",
+			`{"data":{"type":"usual-bookings","attributes":{"booking_date":"`+date+`"}}}`, u1)
+	}
+
+	rec := bookUsual(day(1))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrorCodeNoItemAvailable)
+
+	// Without favorites the last booked item is used.
+	seedTestBooking(t, store, "past", "lot-2", "u1", day(-1))
+	rec = bookUsual(day(1))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	got := usualResult(t, rec)
+	assert.Equal(t, "lot-2", got.ItemID)
+	assert.Equal(t, []string{"Last booked on " + day(-1)}, got.Reasons)
+	require.Len(t, notifier.events, 1)
+
+	// Favorites of removed items are skipped but still count for the rank.
+	ctx := t.Context()
+	require.NoError(t, favorites.Replace(ctx, store, "u1", []string{"retired", "desk-2", "desk-1"}))
+	rec = bookUsual(day(2))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	got = usualResult(t, rec)
+	assert.Equal(t, "desk-2", got.ItemID)
+	assert.Equal(t, []string{"Favorite #2"}, got.Reasons)
+
+	seedTestBooking(t, store, "taken-3", "desk-2", "u2", day(3))
+	rec = bookUsual(day(3))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"Favorite #3"}, usualResult(t, rec).Reasons)
+
+	// Taken favorites fall back to their item group.
+	require.NoError(t, favorites.Replace(ctx, store, "u1", []string{"desk-2"}))
+	seedTestBooking(t, store, "taken-4", "desk-2", "u2", day(4))
+	rec = bookUsual(day(4))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	got = usualResult(t, rec)
+	assert.Equal(t, "desk-1", got.ItemID)
+	assert.Equal(t, []string{"Same item group as Desk 2 (Favorite #1)"}, got.Reasons)
+
+	seedTestBooking(t, store, "taken-5a", "desk-1", "u2", day(5))
+	seedTestBooking(t, store, "taken-5b", "desk-2", "u2", day(5))
+	before := countBookings(t, store)
+	rec = bookUsual(day(5))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, before, countBookings(t, store))
+
+	rec = sendBookingJSON(t, h, http.MethodPost, "", `{"data":{"type":"usual-bookings","attributes":{}}}`, u1)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestFavoriteSuggestionsHandler(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestStore(t)
+	seedTeam(t, store, "u1", "u2")
+	cfg := bundleAreasConfig()
+	day := func(offset int) string { return time.Now().UTC().AddDate(0, 0, offset).Format(time.DateOnly) }
+	seedTestBooking(t, store, "b1", "lot-1", "u1", day(-3))
+	seedTestBooking(t, store, "b2", "desk-1", "u1", day(-2))
+	seedTestBooking(t, store, "b3", "lot-1", "u1", day(-1))
+	seedTestBooking(t, store, "b4", "retired", "u1", day(-1))
+	seedTestBookingFull(t, store, "b5", "desk-2", "u2", "u1", day(-1))
+	seedTestBookingWithGuest(t, store, "b6", "lot-2", "u1", "u1", day(-1), true, "Guest", "")
+	_, err := favorites.Add(t.Context(), store, "u1", "desk-1")
+	require.NoError(t, err)
+
+	h := FavoriteSuggestionsHandler(func() *areas.Config { return cfg }, store)
+	rec := sendBookingJSON(t, h, http.MethodGet, "", "", &auth.User{ID: "u1"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Data []struct {
+			Attributes SuggestionAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1)
+	got := resp.Data[0].Attributes
+	assert.Equal(t, "lot-1", got.ItemID)
+	assert.Equal(t, 2, got.Score)
+	assert.Equal(t, "Last booked on "+day(-1), got.Reasons[0])
+}
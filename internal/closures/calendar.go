@@ -0,0 +1,139 @@
+package closures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+)
+
+// defaultReason is reported when a closure has no reason configured.
+const defaultReason = "Closed"
+
+// ErrorCodeClosed is the JSON:API error code used when a booking targets a closed day.
+const ErrorCodeClosed = "area_closed"
+
+// ListForArea returns all closures (configured and stored) that overlap
+// [fromDate, toDate] for an area. Global closures are always included. If areaID
+// is empty, closures for every area are returned.
+func ListForArea(
+	ctx context.Context, db *sql.DB, cfg *areas.Config, areaID, fromDate, toDate string,
+) ([]Closure, error) {
+	result := configClosures(cfg, areaID, fromDate, toDate)
+
+	stored, err := FindOverlapping(ctx, db, areaID, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	result = append(result, stored...)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartDate < result[j].StartDate
+	})
+	return result, nil
+}
+
+// configClosures converts closures from the areas YAML into Closure values with
+// stable synthetic IDs so clients can tell them apart from stored closures.
+func configClosures(cfg *areas.Config, areaID, fromDate, toDate string) []Closure {
+	var result []Closure
+	add := func(scope string, list []areas.Closure) {
+		for i := range list {
+			cl := Closure{
+				ID:        fmt.Sprintf("config-%s-%d", scope, i+1),
+				StartDate: list[i].From,
+				EndDate:   list[i].EndDate(),
+				Reason:    list[i].Reason,
+				Source:    SourceConfig,
+			}
+			if scope != "global" {
+				cl.AreaID = scope
+			}
+			if cl.EndDate >= fromDate && cl.StartDate <= toDate {
+				result = append(result, cl)
+			}
+		}
+	}
+
+	add("global", cfg.Closures)
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		if areaID == "" || area.ID == areaID {
+			add(area.ID, area.Closures)
+		}
+	}
+	return result
+}
+
+// ClosedDays returns the closure reason for every date in dates on which the
+// area is closed. Dates without a closure are omitted from the result.
+func ClosedDays(
+	ctx context.Context, db *sql.DB, cfg *areas.Config, areaID string, dates []string,
+) (map[string]string, error) {
+	result := make(map[string]string)
+	if len(dates) == 0 {
+		return result, nil
+	}
+
+	sorted := append([]string(nil), dates...)
+	sort.Strings(sorted)
+	list, err := ListForArea(ctx, db, cfg, areaID, sorted[0], sorted[len(sorted)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, date := range dates {
+		for i := range list {
+			if !list[i].Covers(date) {
+				continue
+			}
+			reason := strings.TrimSpace(list[i].Reason)
+			if reason == "" {
+				reason = defaultReason
+			}
+			result[date] = reason
+			break
+		}
+	}
+	return result, nil
+}
+
+// Guard rejects bookings on days where the item's area is closed.
+// Guard is safe for concurrent use.
+type Guard struct {
+	getConfig areas.ConfigGetter
+	store     *sql.DB
+}
+
+// NewGuard creates a booking guard backed by the areas config and the closures table.
+func NewGuard(getConfig areas.ConfigGetter, store *sql.DB) *Guard {
+	return &Guard{getConfig: getConfig, store: store}
+}
+
+// CheckBooking implements bookings.Guard.
+func (g *Guard) CheckBooking(ctx context.Context, req *bookings.GuardRequest) error {
+	closed, err := ClosedDays(ctx, g.store, g.getConfig(), req.Location.Area.ID, req.Dates)
+	if err != nil {
+		return fmt.Errorf("check closures: %w", err)
+	}
+	if len(closed) == 0 {
+		return nil
+	}
+
+	days := make([]string, 0, len(closed))
+	for _, date := range req.Dates {
+		if reason, ok := closed[date]; ok {
+			days = append(days, fmt.Sprintf("%s (%s)", date, reason))
+		}
+	}
+	return &bookings.Rejection{
+		Status: http.StatusConflict,
+		Code:   ErrorCodeClosed,
+		Detail: fmt.Sprintf("%q is closed on %s", req.Location.Area.Name, strings.Join(days, ", ")),
+	}
+}
@@ -0,0 +1,406 @@
+package closures
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const (
+	resourceTypeClosure = "closures"
+	// maxICSSize caps uploaded holiday calendars.
+	maxICSSize = 1 << 20
+)
+
+// Attributes represents closure resource attributes.
+type Attributes struct {
+	AreaID           string            `json:"area_id,omitempty"`
+	StartDate        string            `json:"start_date"`
+	EndDate          string            `json:"end_date"`
+	Reason           string            `json:"reason,omitempty"`
+	Source           string            `json:"source"`
+	CreatedAt        string            `json:"created_at,omitempty"`
+	CanceledBookings *int              `json:"canceled_bookings,omitempty"`
+	AffectedBookings []AffectedBooking `json:"affected_bookings,omitempty"`
+}
+
+// AffectedBooking describes a booking that a closure would cancel.
+type AffectedBooking struct {
+	BookingID   string `json:"booking_id"`
+	ItemID      string `json:"item_id"`
+	UserID      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	BookingDate string `json:"booking_date"`
+}
+
+type createRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			AreaID    string `json:"area_id"`
+			StartDate string `json:"start_date"`
+			EndDate   string `json:"end_date"`
+			Reason    string `json:"reason"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns configured and stored closures.
+// GET /api/v1/closures?area_id=<id>&from=<date>&to=<date>
+// Defaults to closures from today until one year ahead.
+func ListHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := getConfig()
+		earliest, _ := cfg.TodayRange(time.Now())
+		today, _ := time.Parse(time.DateOnly, earliest)
+		fromDate := c.QueryParam("from")
+		toDate := c.QueryParam("to")
+		if fromDate == "" {
+			fromDate = today.Format(time.DateOnly)
+		}
+		if toDate == "" {
+			toDate = today.AddDate(1, 0, 0).Format(time.DateOnly)
+		}
+		if !isDate(fromDate) || !isDate(toDate) {
+			return api.WriteBadRequest(c, "Invalid 'from' or 'to' date. Use YYYY-MM-DD format.")
+		}
+
+		areaID := c.QueryParam("area_id")
+		if areaID != "" {
+			if _, ok := cfg.FindArea(areaID); !ok {
+				return api.WriteNotFound(c, "Area not found")
+			}
+		}
+
+		list, err := ListForArea(c.Request().Context(), store, cfg, areaID, fromDate, toDate)
+		if err != nil {
+			return fmt.Errorf("list closures: %w", err)
+		}
+
+		resources := api.MapResources(list, func(cl Closure) api.Resource {
+			return toResource(&cl, nil)
+		})
+		return api.WriteCollection(c, resources, "write closures response")
+	}
+}
+
+// CreateHandler creates a closure. Bookings on the closed days are canceled.
+// POST /api/v1/closures[?dry_run=true|?confirm=true]
+//
+// With dry_run=true nothing is stored and the affected bookings are returned for
+// review. If bookings would be canceled, the request must carry confirm=true.
+func CreateHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+
+		var req createRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeClosure {
+			return api.WriteBadRequest(c, "Resource type must be 'closures'")
+		}
+
+		a := req.Data.Attributes
+		input := CreateInput{
+			AreaID:          strings.TrimSpace(a.AreaID),
+			StartDate:       strings.TrimSpace(a.StartDate),
+			EndDate:         strings.TrimSpace(a.EndDate),
+			Reason:          strings.TrimSpace(a.Reason),
+			Source:          SourceAdmin,
+			CreatedByUserID: user.ID,
+		}
+		if input.EndDate == "" {
+			input.EndDate = input.StartDate
+		}
+		if detail := validateInput(getConfig(), &input); detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+
+		return applyClosures(c, getConfig(), store, notifier, user.ID, []CreateInput{input}, false)
+	}
+}
+
+// ImportHandler creates closures from an iCalendar (.ics) request body, e.g. a
+// public holiday calendar. Every VEVENT becomes one closure.
+// POST /api/v1/closures/import?area_id=<id>[&dry_run=true|&confirm=true]
+func ImportHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+
+		events, err := ParseICS(http.MaxBytesReader(c.Response(), c.Request().Body, maxICSSize))
+		if err != nil {
+			return api.WriteBadRequest(c, "Invalid iCalendar data: "+err.Error())
+		}
+		if len(events) == 0 {
+			return api.WriteBadRequest(c, "iCalendar data contains no events")
+		}
+
+		cfg := getConfig()
+		areaID := strings.TrimSpace(c.QueryParam("area_id"))
+		inputs := make([]CreateInput, 0, len(events))
+		for _, ev := range events {
+			input := CreateInput{
+				AreaID:          areaID,
+				StartDate:       ev.StartDate,
+				EndDate:         ev.EndDate,
+				Reason:          ev.Summary,
+				Source:          SourceICS,
+				CreatedByUserID: user.ID,
+			}
+			if detail := validateInput(cfg, &input); detail != "" {
+				return api.WriteBadRequest(c, detail)
+			}
+			inputs = append(inputs, input)
+		}
+
+		return applyClosures(c, cfg, store, notifier, user.ID, inputs, true)
+	}
+}
+
+// DeleteHandler removes a stored closure. Closures from the areas YAML cannot be deleted.
+// DELETE /api/v1/closures/:id
+func DeleteHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := Delete(c.Request().Context(), store, c.Param("id")); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return api.WriteNotFound(c, "Closure not found")
+			}
+			return api.WriteInternalError(c, "delete closure", err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func validateInput(cfg *areas.Config, input *CreateInput) string {
+	if !isDate(input.StartDate) || !isDate(input.EndDate) {
+		return "start_date and end_date must be in YYYY-MM-DD format"
+	}
+	if input.EndDate < input.StartDate {
+		return "end_date must not be before start_date"
+	}
+	if input.AreaID != "" {
+		if _, ok := cfg.FindArea(input.AreaID); !ok {
+			return "area_id: area not found"
+		}
+	}
+	return ""
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse(time.DateOnly, s)
+	return err == nil
+}
+
+// applyClosures previews or stores closures and cancels the affected bookings.
+// collection selects a collection response (imports) over a single resource.
+func applyClosures(
+	c echo.Context, cfg *areas.Config, store *sql.DB, notifier notifications.Notifier,
+	userID string, inputs []CreateInput, collection bool,
+) error {
+	ctx := c.Request().Context()
+	affected, err := findAffectedBookings(ctx, store, cfg, inputs)
+	if err != nil {
+		return fmt.Errorf("find affected bookings: %w", err)
+	}
+
+	total := 0
+	for _, list := range affected {
+		total += len(list)
+	}
+
+	if c.QueryParam("dry_run") == "true" {
+		previews, err := buildPreviews(ctx, store, inputs, affected)
+		if err != nil {
+			return err
+		}
+		return writeClosures(c, http.StatusOK, previews, collection)
+	}
+
+	if total > 0 && c.QueryParam("confirm") != "true" {
+		return api.WriteError(c, http.StatusConflict, fmt.Sprintf(
+			"%d existing bookings would be canceled. Review them with dry_run=true and resend with confirm=true.",
+			total), "confirmation_required")
+	}
+
+	created, err := CreateAll(ctx, store, inputs)
+	if err != nil {
+		return fmt.Errorf("create closures: %w", err)
+	}
+
+	resources := make([]api.Resource, 0, len(created))
+	for i := range created {
+		cl := &created[i]
+		if err := bookings.CancelBookings(ctx, store, notifier, affected[i], userID, closureReason(cl)); err != nil {
+			return fmt.Errorf("cancel bookings for closure: %w", err)
+		}
+		canceled := len(affected[i])
+		resources = append(resources, toResource(cl, &canceled))
+		slog.Info("closure created",
+			"closure_id", cl.ID,
+			"area_id", cl.AreaID,
+			"start_date", cl.StartDate,
+			"end_date", cl.EndDate,
+			"source", cl.Source,
+			"created_by", userID,
+			"canceled_bookings", canceled,
+		)
+	}
+
+	return writeClosures(c, http.StatusCreated, resources, collection)
+}
+
+// findAffectedBookings returns, per input, the bookings that fall inside the
+// closure from the local today on; past bookings are left alone. A booking is
+// attributed to the first closure that covers it.
+func findAffectedBookings(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, inputs []CreateInput,
+) ([][]bookings.BookingRecord, error) {
+	now := time.Now()
+	seen := make(map[string]struct{})
+	result := make([][]bookings.BookingRecord, len(inputs))
+	for i := range inputs {
+		in := &inputs[i]
+		var itemIDs []string
+		// A global closure starts at the earliest local today of all areas;
+		// each booking is checked against its own area's today below.
+		today, _ := cfg.TodayRange(now)
+		if in.AreaID != "" {
+			area, _ := cfg.FindArea(in.AreaID)
+			itemIDs = areaItemIDs(area)
+			if len(itemIDs) == 0 {
+				continue
+			}
+			today = areas.LocalDate(now, cfg.AreaZone(area))
+		}
+		from := max(in.StartDate, today)
+		if from > in.EndDate {
+			continue
+		}
+		records, err := bookings.ListBookingsInRange(ctx, store, itemIDs, from, in.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("list bookings in closure: %w", err)
+		}
+		for j := range records {
+			if records[j].BookingDate < areas.LocalDate(now, cfg.ItemZone(records[j].ItemID)) {
+				continue
+			}
+			if _, dup := seen[records[j].ID]; dup {
+				continue
+			}
+			seen[records[j].ID] = struct{}{}
+			result[i] = append(result[i], records[j])
+		}
+	}
+	return result, nil
+}
+
+func areaItemIDs(area *areas.Area) []string {
+	var ids []string
+	for i := range area.ItemGroups {
+		for _, item := range area.ItemGroups[i].Items {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+func buildPreviews(
+	ctx context.Context, store *sql.DB, inputs []CreateInput, affected [][]bookings.BookingRecord,
+) ([]api.Resource, error) {
+	var userIDs []string
+	for _, list := range affected {
+		for i := range list {
+			if !list[i].IsGuest {
+				userIDs = append(userIDs, list[i].UserID)
+			}
+		}
+	}
+	names, err := users.FindDisplayNames(ctx, store, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("find display names: %w", err)
+	}
+
+	previews := make([]api.Resource, 0, len(inputs))
+	for i := range inputs {
+		in := &inputs[i]
+		cl := Closure{
+			AreaID: in.AreaID, StartDate: in.StartDate, EndDate: in.EndDate,
+			Reason: in.Reason, Source: in.Source,
+		}
+		res := toResource(&cl, nil)
+		attrs, _ := res.Attributes.(Attributes) //nolint:errcheck // toResource always returns Attributes
+		attrs.AffectedBookings = make([]AffectedBooking, 0, len(affected[i]))
+		for _, b := range affected[i] {
+			name := names[b.UserID]
+			if b.IsGuest {
+				name = b.GuestName
+			}
+			attrs.AffectedBookings = append(attrs.AffectedBookings, AffectedBooking{
+				BookingID: b.ID, ItemID: b.ItemID, UserID: b.UserID,
+				UserName: name, BookingDate: b.BookingDate,
+			})
+		}
+		res.Attributes = attrs
+		previews = append(previews, res)
+	}
+	return previews, nil
+}
+
+func closureReason(cl *Closure) string {
+	if cl.Reason == "" {
+		return "Office closed"
+	}
+	return "Office closed: " + cl.Reason
+}
+
+func toResource(cl *Closure, canceled *int) api.Resource {
+	return api.Resource{
+		Type: resourceTypeClosure,
+		ID:   cl.ID,
+		Attributes: Attributes{
+			AreaID:           cl.AreaID,
+			StartDate:        cl.StartDate,
+			EndDate:          cl.EndDate,
+			Reason:           cl.Reason,
+			Source:           cl.Source,
+			CreatedAt:        cl.CreatedAt,
+			CanceledBookings: canceled,
+		},
+	}
+}
+
+func writeClosures(c echo.Context, status int, resources []api.Resource, collection bool) error {
+	if collection {
+		resp := api.CollectionResponse{Data: resources}
+		c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
+		//nolint:wrapcheck // Terminal response
+		return c.JSON(status, resp)
+	}
+	return api.WriteSingle(c, status, resources[0], "write closure response")
+}
@@ -0,0 +1,266 @@
+package closures
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/db"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*notifications.BookingEvent
+}
+
+func (n *recordingNotifier) NotifyAsync(event *notifications.BookingEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+}
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+	return store
+}
+
+func seedBooking(t *testing.T, store *sql.DB, id, itemID, userID, date string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.Exec(`
+		INSERT INTO bookings (id, item_id, user_id, booked_by_user_id, booking_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, id, itemID, userID, userID, date, now, now)
+	require.NoError(t, err)
+}
+
+func testConfig() *areas.Config {
+	return &areas.Config{
+		Closures: []areas.Closure{{From: "2026-12-24", To: "2026-12-26", Reason: "Christmas"}},
+		Areas: []areas.Area{
+			{
+				ID:   "office",
+				Name: "Office",
+				ItemGroups: []areas.ItemGroup{
+					{ID: "room-1", Name: "Room 1", Items: []areas.Item{{ID: "desk-1", Name: "Desk 1"}}},
+				},
+			},
+			{
+				ID:   "garage",
+				Name: "Garage",
+				ItemGroups: []areas.ItemGroup{
+					{ID: "level-1", Name: "Level 1", Items: []areas.Item{{ID: "spot-1", Name: "Spot 1"}}},
+				},
+			},
+		},
+	}
+}
+
+func newAdminContext(method, target, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "admin-1", IsAdmin: true})
+	return c, rec
+}
+
+// closureDay1 and closureDay2 are the future days closureBody closes.
+var (
+	closureDay1 = time.Now().UTC().AddDate(0, 0, 14).Format(time.DateOnly)
+	closureDay2 = time.Now().UTC().AddDate(0, 0, 15).Format(time.DateOnly)
+	closureBody = `{"data":{"type":"closures","attributes":{"area_id":"office",` +
+		`"start_date":"` + closureDay1 + `","end_date":"` + closureDay2 + `","reason":"Renovation"}}}`
+)
+
+func TestCreateHandlerDryRunListsAffectedBookings(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	seedBooking(t, store, "b1", "desk-1", "user-1", closureDay2)
+	seedBooking(t, store, "b2", "spot-1", "user-1", closureDay2)
+
+	c, rec := newAdminContext(http.MethodPost, "/api/v1/closures?dry_run=true", closureBody)
+	notifier := &recordingNotifier{}
+	require.NoError(t, CreateHandler(func() *areas.Config { return testConfig() }, store, notifier)(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Data.ID)
+	attrs, ok := resp.Data.Attributes.(map[string]any)
+	require.True(t, ok)
+	affected, ok := attrs["affected_bookings"].([]any)
+	require.True(t, ok)
+	require.Len(t, affected, 1)
+	first, ok := affected[0].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "b1", first["booking_id"])
+
+	stored, err := FindOverlapping(t.Context(), store, "", "2026-01-01", "2026-12-31")
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+	assert.Empty(t, notifier.events)
+}
+
+func TestCreateHandlerRequiresConfirmation(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	seedBooking(t, store, "b1", "desk-1", "user-1", closureDay1)
+
+	c, rec := newAdminContext(http.MethodPost, "/api/v1/closures", closureBody)
+	require.NoError(t, CreateHandler(func() *areas.Config { return testConfig() }, store, &recordingNotifier{})(c))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "confirmation_required")
+}
+
+func TestCreateHandlerConfirmedCancelsBookings(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	seedBooking(t, store, "b1", "desk-1", "user-1", closureDay1)
+	_, err := store.Exec(`UPDATE bookings SET custom_fields = '{"plate":"B-XY 1"}', bundle_id = 'bundle-1'
+		WHERE id = 'b1'`)
+	require.NoError(t, err)
+
+	c, rec := newAdminContext(http.MethodPost, "/api/v1/closures?confirm=true", closureBody)
+	notifier := &recordingNotifier{}
+	require.NoError(t, CreateHandler(func() *areas.Config { return testConfig() }, store, notifier)(c))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Data.ID)
+	attrs, ok := resp.Data.Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, float64(1), attrs["canceled_bookings"])
+
+	remaining, err := bookings.ListBookingsInRange(t.Context(), store, nil, closureDay1, closureDay2)
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, notifications.EventBookingCanceled, notifier.events[0].Event)
+	assert.Equal(t, "Office closed: Renovation", notifier.events[0].Reason)
+	assert.Equal(t, map[string]any{"plate": "B-XY 1"}, notifier.events[0].Fields)
+	assert.Equal(t, "bundle-1", notifier.events[0].BundleID)
+}
+
+func TestCreateHandlerKeepsPastBookings(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+	seedBooking(t, store, "past", "desk-1", "user-1", yesterday)
+	seedBooking(t, store, "future", "desk-1", "user-1", closureDay1)
+	body := strings.Replace(closureBody, `"start_date":"`+closureDay1, `"start_date":"`+yesterday, 1)
+
+	c, rec := newAdminContext(http.MethodPost, "/api/v1/closures?confirm=true", body)
+	notifier := &recordingNotifier{}
+	require.NoError(t, CreateHandler(func() *areas.Config { return testConfig() }, store, notifier)(c))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	remaining, err := bookings.ListBookingsInRange(t.Context(), store, nil, yesterday, closureDay2)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "past", remaining[0].ID)
+	require.Len(t, notifier.events, 1)
+}
+
+func TestCreateHandlerRejectsUnknownArea(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	body := strings.Replace(closureBody, `"office"`, `"nowhere"`, 1)
+
+	c, rec := newAdminContext(http.MethodPost, "/api/v1/closures", body)
+	require.NoError(t, CreateHandler(func() *areas.Config { return testConfig() }, store, &recordingNotifier{})(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestImportHandlerCreatesClosures(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+
+	c, rec := newAdminContext(http.MethodPost, "/api/v1/closures/import", holidayCalendar)
+	require.NoError(t, ImportHandler(func() *areas.Config { return testConfig() }, store, &recordingNotifier{})(c))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 3)
+
+	stored, err := FindOverlapping(t.Context(), store, "office", "2026-01-01", "2026-12-31")
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+	assert.Equal(t, SourceICS, stored[0].Source)
+}
+
+func TestListHandlerIncludesConfiguredClosures(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/closures?area_id=office&from=2026-12-01&to=2026-12-31", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	require.NoError(t, ListHandler(func() *areas.Config { return testConfig() }, store)(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, "config-global-1", resp.Data[0].ID)
+}
+
+func TestDeleteHandlerNotFound(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+
+	c, rec := newAdminContext(http.MethodDelete, "/api/v1/closures/missing", "")
+	c.SetParamNames("id")
+	c.SetParamValues("missing")
+	require.NoError(t, DeleteHandler(store)(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGuardRejectsClosedDays(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	cfg := testConfig()
+	loc, ok := cfg.FindItemLocation("desk-1")
+	require.True(t, ok)
+
+	guard := NewGuard(func() *areas.Config { return cfg }, store)
+
+	err := guard.CheckBooking(t.Context(), &bookings.GuardRequest{
+		Location: loc, UserID: "user-1", Dates: []string{"2026-12-23", "2026-12-24"},
+	})
+	var rejection *bookings.Rejection
+	require.ErrorAs(t, err, &rejection)
+	assert.Equal(t, http.StatusConflict, rejection.Status)
+	assert.Equal(t, ErrorCodeClosed, rejection.Code)
+	assert.Contains(t, rejection.Detail, "2026-12-24 (Christmas)")
+
+	require.NoError(t, guard.CheckBooking(t.Context(), &bookings.GuardRequest{
+		Location: loc, UserID: "user-1", Dates: []string{"2026-12-23"},
+	}))
+}
@@ -0,0 +1,137 @@
+package closures
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICSEvent is an all-day period parsed from an iCalendar VEVENT.
+// StartDate and EndDate are inclusive YYYY-MM-DD dates.
+type ICSEvent struct {
+	StartDate string
+	EndDate   string
+	Summary   string
+}
+
+const icsDateLayout = "20060102"
+
+// ParseICS extracts events from an iCalendar (.ics) stream, such as a public
+// holiday calendar. Only the date part of DTSTART/DTEND is used. For all-day
+// events DTEND is exclusive, as defined by RFC 5545; events without DTEND last
+// a single day.
+func ParseICS(r io.Reader) ([]ICSEvent, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ICSEvent
+	var current *icsRawEvent
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			current = &icsRawEvent{}
+		case line == "END:VEVENT":
+			if current == nil {
+				continue
+			}
+			ev, err := current.toEvent()
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, ev)
+			current = nil
+		case current != nil:
+			current.apply(line)
+		}
+	}
+	return events, nil
+}
+
+// unfoldICSLines reads content lines, joining folded continuation lines.
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ics: %w", err)
+	}
+	return lines, nil
+}
+
+type icsRawEvent struct {
+	start      string
+	end        string
+	endIsDate  bool
+	summary    string
+	hasEndProp bool
+}
+
+func (e *icsRawEvent) apply(line string) {
+	name, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return
+	}
+	prop, params, _ := strings.Cut(name, ";")
+	switch strings.ToUpper(prop) {
+	case "DTSTART":
+		e.start = value
+	case "DTEND":
+		e.end = value
+		e.hasEndProp = true
+		e.endIsDate = strings.Contains(strings.ToUpper(params), "VALUE=DATE") || len(value) == len(icsDateLayout)
+	case "SUMMARY":
+		e.summary = unescapeICSText(value)
+	}
+}
+
+func (e *icsRawEvent) toEvent() (ICSEvent, error) {
+	start, err := parseICSDate(e.start)
+	if err != nil {
+		return ICSEvent{}, fmt.Errorf("parse DTSTART %q: %w", e.start, err)
+	}
+	end := start
+	if e.hasEndProp {
+		end, err = parseICSDate(e.end)
+		if err != nil {
+			return ICSEvent{}, fmt.Errorf("parse DTEND %q: %w", e.end, err)
+		}
+		if e.endIsDate && end.After(start) {
+			end = end.AddDate(0, 0, -1)
+		}
+	}
+	if end.Before(start) {
+		end = start
+	}
+	return ICSEvent{
+		StartDate: start.Format(time.DateOnly),
+		EndDate:   end.Format(time.DateOnly),
+		Summary:   strings.TrimSpace(e.summary),
+	}, nil
+}
+
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < len(icsDateLayout) {
+		return time.Time{}, fmt.Errorf("value too short")
+	}
+	parsed, err := time.Parse(icsDateLayout, value[:len(icsDateLayout)])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse date: %w", err)
+	}
+	return parsed, nil
+}
+
+func unescapeICSText(s string) string {
+	replacer := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(s)
+}
@@ -0,0 +1,48 @@
+package closures
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const holidayCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261225\r\n" +
+	"DTEND;VALUE=DATE:20261227\r\n" +
+	"SUMMARY:Christmas\\, Boxing Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261231\r\n" +
+	"SUMMARY:New Year's\r\n" +
+	"  Eve\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20261003T090000Z\r\n" +
+	"DTEND:20261003T170000Z\r\n" +
+	"SUMMARY:Unity Day\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	t.Parallel()
+
+	events, err := ParseICS(strings.NewReader(holidayCalendar))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t,
+		ICSEvent{StartDate: "2026-12-25", EndDate: "2026-12-26", Summary: "Christmas, Boxing Day"}, events[0])
+	assert.Equal(t, ICSEvent{StartDate: "2026-12-31", EndDate: "2026-12-31", Summary: "New Year's Eve"}, events[1])
+	assert.Equal(t, ICSEvent{StartDate: "2026-10-03", EndDate: "2026-10-03", Summary: "Unity Day"}, events[2])
+}
+
+func TestParseICSRejectsInvalidDate(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseICS(strings.NewReader("BEGIN:VEVENT\nDTSTART:2026\nEND:VEVENT\n"))
+	require.Error(t, err)
+}
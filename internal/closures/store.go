@@ -0,0 +1,151 @@
+// Package closures manages office closures, public holidays, and blackout dates.
+package closures
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Closure sources.
+const (
+	SourceAdmin  = "admin"
+	SourceICS    = "ics"
+	SourceConfig = "config"
+)
+
+// ErrNotFound indicates the requested closure does not exist.
+var ErrNotFound = errors.New("closure not found")
+
+// Closure represents a closure row from the database. An empty AreaID means the
+// closure applies to all areas. StartDate and EndDate are inclusive.
+type Closure struct {
+	ID              string
+	AreaID          string
+	StartDate       string
+	EndDate         string
+	Reason          string
+	Source          string
+	CreatedByUserID string
+	CreatedAt       string
+	UpdatedAt       string
+}
+
+// Covers reports whether the closure includes the given YYYY-MM-DD date.
+func (cl *Closure) Covers(date string) bool {
+	return date >= cl.StartDate && date <= cl.EndDate
+}
+
+// CreateInput holds fields for creating a closure.
+type CreateInput struct {
+	AreaID          string
+	StartDate       string
+	EndDate         string
+	Reason          string
+	Source          string
+	CreatedByUserID string
+}
+
+const closureColumns = `id, area_id, start_date, end_date, reason, source,
+	created_by_user_id, created_at, updated_at`
+
+// CreateAll inserts closures in a single transaction.
+func CreateAll(ctx context.Context, db *sql.DB, inputs []CreateInput) ([]Closure, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin create closures: %w", err)
+	}
+
+	created := make([]Closure, 0, len(inputs))
+	for i := range inputs {
+		in := &inputs[i]
+		cl := Closure{
+			ID:              uuid.NewString(),
+			AreaID:          in.AreaID,
+			StartDate:       in.StartDate,
+			EndDate:         in.EndDate,
+			Reason:          in.Reason,
+			Source:          in.Source,
+			CreatedByUserID: in.CreatedByUserID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO closures (`+closureColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			cl.ID, cl.AreaID, cl.StartDate, cl.EndDate, cl.Reason, cl.Source,
+			cl.CreatedByUserID, cl.CreatedAt, cl.UpdatedAt,
+		)
+		if err != nil {
+			_ = tx.Rollback() //nolint:errcheck // Original error is more relevant
+			return nil, fmt.Errorf("insert closure: %w", err)
+		}
+		created = append(created, cl)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit closures: %w", err)
+	}
+	return created, nil
+}
+
+// FindOverlapping returns closures that overlap [fromDate, toDate] and apply to
+// the given area (global closures included). If areaID is empty, closures for all
+// areas are returned.
+func FindOverlapping(
+	ctx context.Context, db *sql.DB, areaID, fromDate, toDate string,
+) (result []Closure, err error) {
+	query := `SELECT ` + closureColumns + ` FROM closures WHERE end_date >= ? AND start_date <= ?`
+	args := []any{fromDate, toDate}
+	if areaID != "" {
+		query += ` AND (area_id = '' OR area_id = ?)`
+		args = append(args, areaID)
+	}
+	query += ` ORDER BY start_date, area_id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query closures: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close closures rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var cl Closure
+		if err := rows.Scan(
+			&cl.ID, &cl.AreaID, &cl.StartDate, &cl.EndDate, &cl.Reason, &cl.Source,
+			&cl.CreatedByUserID, &cl.CreatedAt, &cl.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan closure: %w", err)
+		}
+		result = append(result, cl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate closures: %w", err)
+	}
+	return result, nil
+}
+
+// Delete removes a closure by ID.
+func Delete(ctx context.Context, db *sql.DB, id string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM closures WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete closure: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete closure rows: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
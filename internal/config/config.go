@@ -26,6 +26,21 @@ var ErrFloorPlansDirOutsideDataDir = errors.New("areas floor_plans must be insid
 // ErrFloorPlansDirNotFound indicates the floor plans directory does not exist.
 var ErrFloorPlansDirNotFound = errors.New("floor plans directory not found")
 
+// ErrNegativeRetention indicates a negative visitors retention_days.
+var ErrNegativeRetention = errors.New("retention_days must not be negative")
+
+// ErrNegativeIdempotencyRetention indicates a negative bookings idempotency_retention_hours.
+var ErrNegativeIdempotencyRetention = errors.New("idempotency_retention_hours must not be negative")
+
+// ErrInvalidOnBehalf indicates an unknown bookings on_behalf_without_delegation value.
+var ErrInvalidOnBehalf = errors.New(`on_behalf_without_delegation must be "reject" or "confirm"`)
+
+// ErrNegativeDigestWindow indicates a negative notifications subscription_digest_minutes.
+var ErrNegativeDigestWindow = errors.New("subscription_digest_minutes must not be negative")
+
+// ErrInvalidAreasSource indicates an unknown areas source value.
+var ErrInvalidAreasSource = errors.New(`areas source must be "yaml" or "database"`)
+
 // Config holds the full application configuration.
 type Config struct {
 	Main          MainConfig          `mapstructure:"main"`
@@ -34,12 +49,21 @@ type Config struct {
 	Areas         AreasConfig         `mapstructure:"areas"`
 	Bookings      BookingsConfig      `mapstructure:"bookings"`
 	Notifications NotificationsConfig `mapstructure:"notifications"`
+	Visitors      VisitorsConfig      `mapstructure:"visitors"`
 }
 
 // BookingsConfig contains booking limit settings.
 type BookingsConfig struct {
-	WeeksInAdvanced      int `mapstructure:"weeks_in_advanced"`
-	MaxBookingsPerPerson int `mapstructure:"max_bookings_per_person"`
+	WeeksInAdvanced           int    `mapstructure:"weeks_in_advanced"`
+	MaxBookingsPerPerson      int    `mapstructure:"max_bookings_per_person"`
+	IdempotencyRetentionHours int    `mapstructure:"idempotency_retention_hours"`
+	OnBehalfWithoutDelegation string `mapstructure:"on_behalf_without_delegation"`
+}
+
+// VisitorsConfig contains visitor management settings.
+type VisitorsConfig struct {
+	Receptionists []string `mapstructure:"receptionists"`
+	RetentionDays int      `mapstructure:"retention_days"`
 }
 
 // MainConfig contains main server settings.
@@ -66,10 +90,16 @@ type EntraIDConfig struct {
 	ClientSecret  string `mapstructure:"client_secret"`
 	UsersGroupID  string `mapstructure:"users_group_id"`
 	AdminsGroupID string `mapstructure:"admins_group_id"`
+	// SyncTeamGroups adds users to the teams linked to their Entra ID groups on login.
+	SyncTeamGroups bool `mapstructure:"sync_team_groups"`
 }
 
 // AreasConfig contains areas configuration settings.
 type AreasConfig struct {
+	// Source is where areas, item groups, and items are defined: "yaml" keeps
+	// ConfigFile authoritative, "database" lets admins edit them through the
+	// API and only imports ConfigFile while the database has none.
+	Source        string `mapstructure:"source"`
 	ConfigFile    string `mapstructure:"config_file"`
 	FloorPlansDir string `mapstructure:"floor_plans"`
 }
@@ -77,6 +107,9 @@ type AreasConfig struct {
 // NotificationsConfig contains notification settings.
 type NotificationsConfig struct {
 	WebhookURL string `mapstructure:"webhook_url"`
+	// SubscriptionDigestMinutes is how long bookings matching a subscription
+	// are collected before they are sent to the subscriber in one digest.
+	SubscriptionDigestMinutes int `mapstructure:"subscription_digest_minutes"`
 }
 
 // Load reads configuration from a TOML file and environment variables.
@@ -100,12 +133,19 @@ func LoadWithOverrides(path string, overrides map[string]interface{}) (*Config,
 	v.SetDefault("log.file", "")
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.format", "text")
+	v.SetDefault("areas.source", "yaml")
 	v.SetDefault("areas.config_file", "")
 	v.SetDefault("areas.floor_plans", "")
 	v.SetDefault("areas.floor_plans_dir", "")
 	v.SetDefault("bookings.weeks_in_advanced", 5)
 	v.SetDefault("bookings.max_bookings_per_person", 0)
+	v.SetDefault("bookings.idempotency_retention_hours", 24)
+	v.SetDefault("bookings.on_behalf_without_delegation", "reject")
+	v.SetDefault("entraid.sync_team_groups", false)
 	v.SetDefault("notifications.webhook_url", "")
+	v.SetDefault("notifications.subscription_digest_minutes", 30)
+	v.SetDefault("visitors.receptionists", []string{})
+	v.SetDefault("visitors.retention_days", 90)
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("load config: %w", err)
@@ -130,6 +170,24 @@ func LoadWithOverrides(path string, overrides map[string]interface{}) (*Config,
 		return nil, err
 	}
 
+	if cfg.Visitors.RetentionDays < 0 {
+		return nil, fmt.Errorf("validate visitors: %w", ErrNegativeRetention)
+	}
+
+	if cfg.Bookings.IdempotencyRetentionHours < 0 {
+		return nil, fmt.Errorf("validate bookings: %w", ErrNegativeIdempotencyRetention)
+	}
+
+	if cfg.Notifications.SubscriptionDigestMinutes < 0 {
+		return nil, fmt.Errorf("validate notifications: %w", ErrNegativeDigestWindow)
+	}
+
+	switch cfg.Bookings.OnBehalfWithoutDelegation {
+	case "reject", "confirm":
+	default:
+		return nil, fmt.Errorf("validate bookings: %w", ErrInvalidOnBehalf)
+	}
+
 	return &cfg, nil
 }
 
@@ -152,11 +210,13 @@ func (c *Config) EntraIDConfigured() bool {
 }
 
 // resolveAreasConfig validates and resolves the areas config file path
-// relative to data_dir. Absolute paths outside data_dir are rejected.
+// relative to data_dir. Absolute paths outside data_dir are rejected. The file
+// is optional when areas are stored in the database.
 func resolveAreasConfig(cfg *Config) error {
-	raw := strings.TrimSpace(cfg.Areas.ConfigFile)
-	if raw == "" {
-		return fmt.Errorf("validate areas: %w", ErrMissingAreasConfig)
+	switch cfg.Areas.Source {
+	case "yaml", "database":
+	default:
+		return fmt.Errorf("validate areas: %w", ErrInvalidAreasSource)
 	}
 
 	dataDir, err := filepath.Abs(cfg.Main.DataDir)
@@ -164,6 +224,14 @@ func resolveAreasConfig(cfg *Config) error {
 		return fmt.Errorf("resolve data_dir: %w", err)
 	}
 
+	raw := strings.TrimSpace(cfg.Areas.ConfigFile)
+	if raw == "" {
+		if cfg.Areas.Source == "database" {
+			return resolveFloorPlansDir(cfg, dataDir)
+		}
+		return fmt.Errorf("validate areas: %w", ErrMissingAreasConfig)
+	}
+
 	var resolved string
 	if filepath.IsAbs(raw) {
 		resolved = filepath.Clean(raw)
@@ -317,3 +317,154 @@ config_file = "`+outsidePath+`"
 		t.Fatalf("expected ErrAreasConfigOutsideDataDir, got %v", err)
 	}
 }
+
+func TestLoadVisitorsConfig(t *testing.T) {
+	dataDir := t.TempDir()
+	areasPath := writeAreasConfigIn(t, dataDir)
+	base := `
+[main]
+data_dir = "` + dataDir + `"
+
+[areas]
+config_file = "` + areasPath + `"
+`
+	cfg, err := Load(writeConfig(t, base))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Visitors.RetentionDays != 90 {
+		t.Fatalf("expected default retention 90, got %d", cfg.Visitors.RetentionDays)
+	}
+
+	cfg, err = Load(writeConfig(t, base+`
+[visitors]
+receptionists = ["desk@example.com"]
+retention_days = 30
+`))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if len(cfg.Visitors.Receptionists) != 1 || cfg.Visitors.Receptionists[0] != "desk@example.com" {
+		t.Fatalf("unexpected receptionists: %v", cfg.Visitors.Receptionists)
+	}
+	if cfg.Visitors.RetentionDays != 30 {
+		t.Fatalf("expected retention 30, got %d", cfg.Visitors.RetentionDays)
+	}
+
+	_, err = Load(writeConfig(t, base+`
+[visitors]
+retention_days = -1
+`))
+	if !errors.Is(err, ErrNegativeRetention) {
+		t.Fatalf("expected ErrNegativeRetention, got %v", err)
+	}
+}
+
+func TestLoadIdempotencyRetention(t *testing.T) {
+	dataDir := t.TempDir()
+	areasPath := writeAreasConfigIn(t, dataDir)
+	base := `
+[main]
+data_dir = "` + dataDir + `"
+
+[areas]
+config_file = "` + areasPath + `"
+`
+	cfg, err := Load(writeConfig(t, base))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Bookings.IdempotencyRetentionHours != 24 {
+		t.Fatalf("expected default idempotency retention 24, got %d", cfg.Bookings.IdempotencyRetentionHours)
+	}
+
+	_, err = Load(writeConfig(t, base+`
+[bookings]
+idempotency_retention_hours = -1
+`))
+	if !errors.Is(err, ErrNegativeIdempotencyRetention) {
+		t.Fatalf("expected ErrNegativeIdempotencyRetention, got %v", err)
+	}
+}
+
+func TestLoadSubscriptionDigestMinutes(t *testing.T) {
+	dataDir := t.TempDir()
+	areasPath := writeAreasConfigIn(t, dataDir)
+	base := `
+[main]
+data_dir = "` + dataDir + `"
+
+[areas]
+config_file = "` + areasPath + `"
+`
+	cfg, err := Load(writeConfig(t, base))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Notifications.SubscriptionDigestMinutes != 30 {
+		t.Fatalf("expected default digest window 30, got %d", cfg.Notifications.SubscriptionDigestMinutes)
+	}
+
+	_, err = Load(writeConfig(t, base+`
+[notifications]
+subscription_digest_minutes = -5
+`))
+	if !errors.Is(err, ErrNegativeDigestWindow) {
+		t.Fatalf("expected ErrNegativeDigestWindow, got %v", err)
+	}
+}
+
+func TestLoadOnBehalfWithoutDelegation(t *testing.T) {
+	dataDir := t.TempDir()
+	areasPath := writeAreasConfigIn(t, dataDir)
+	base := `
+[main]
+data_dir = "` + dataDir + `"
+
+[areas]
+config_file = "` + areasPath + `"
+`
+	cfg, err := Load(writeConfig(t, base))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Bookings.OnBehalfWithoutDelegation != "reject" {
+		t.Fatalf("expected default reject, got %q", cfg.Bookings.OnBehalfWithoutDelegation)
+	}
+
+	_, err = Load(writeConfig(t, base+`
+[bookings]
+on_behalf_without_delegation = "allow"
+`))
+	if !errors.Is(err, ErrInvalidOnBehalf) {
+		t.Fatalf("expected ErrInvalidOnBehalf, got %v", err)
+	}
+}
+
+func TestLoadAreasSource(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg, err := Load(writeConfig(t, `
+[main]
+data_dir = "`+dataDir+`"
+
+[areas]
+source = "database"
+`))
+	if err != nil {
+		t.Fatalf("expected database areas without a config file, got %v", err)
+	}
+	if cfg.Areas.Source != "database" || cfg.Areas.ConfigFile != "" {
+		t.Fatalf("unexpected areas config %+v", cfg.Areas)
+	}
+
+	_, err = Load(writeConfig(t, `
+[main]
+data_dir = "`+dataDir+`"
+
+[areas]
+source = "ldap"
+`))
+	if !errors.Is(err, ErrInvalidAreasSource) {
+		t.Fatalf("expected ErrInvalidAreasSource, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+-- Office closures created through the admin API or imported from .ics files.
+-- An empty area_id means the closure applies to every area.
+CREATE TABLE closures (
+  id TEXT PRIMARY KEY,
+  area_id TEXT NOT NULL DEFAULT '',
+  start_date TEXT NOT NULL,
+  end_date TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  source TEXT NOT NULL CHECK (source IN ('admin', 'ics')),
+  created_by_user_id TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE INDEX idx_closures_dates ON closures(start_date, end_date);
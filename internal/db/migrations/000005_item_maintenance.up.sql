@@ -0,0 +1,13 @@
+-- Out-of-service periods for individual items (broken equipment, construction, ...).
+CREATE TABLE item_maintenance (
+  id TEXT PRIMARY KEY,
+  item_id TEXT NOT NULL,
+  start_date TEXT NOT NULL,
+  end_date TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_by_user_id TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE INDEX idx_item_maintenance_item_dates ON item_maintenance(item_id, start_date, end_date);
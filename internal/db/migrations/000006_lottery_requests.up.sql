@@ -0,0 +1,20 @@
+-- Booking requests for days allocated by lottery. scope_key is "area:<id>" or
+-- "item_group:<id>"; preferences holds ranked item IDs, comma-separated.
+-- status: pending, won, lost, waitlisted, or withdrawn.
+CREATE TABLE lottery_requests (
+  id TEXT PRIMARY KEY,
+  scope_key TEXT NOT NULL,
+  booking_date TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  preferences TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'pending',
+  draw_position INTEGER NOT NULL DEFAULT 0,
+  item_id TEXT NOT NULL DEFAULT '',
+  booking_id TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  UNIQUE(scope_key, booking_date, user_id)
+);
+
+CREATE INDEX idx_lottery_requests_status ON lottery_requests(status, booking_date);
+CREATE INDEX idx_lottery_requests_user ON lottery_requests(user_id, booking_date);
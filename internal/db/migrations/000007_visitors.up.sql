@@ -0,0 +1,31 @@
+-- Guest records that hosts can reuse across visits. id starts with "guest-" and
+-- is used as bookings.user_id for the visitor's guest bookings.
+CREATE TABLE visitors (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  email TEXT NOT NULL DEFAULT '',
+  company TEXT NOT NULL DEFAULT '',
+  host_user_id TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE INDEX idx_visitors_host ON visitors(host_user_id);
+
+-- One visit per visitor and day. expected_arrival is HH:MM local office time.
+-- status: expected, arrived, or left.
+CREATE TABLE visits (
+  id TEXT PRIMARY KEY,
+  visitor_id TEXT NOT NULL REFERENCES visitors(id) ON DELETE CASCADE,
+  host_user_id TEXT NOT NULL,
+  visit_date TEXT NOT NULL,
+  expected_arrival TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'expected',
+  arrived_at TEXT NOT NULL DEFAULT '',
+  left_at TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  UNIQUE(visitor_id, visit_date)
+);
+
+CREATE INDEX idx_visits_date ON visits(visit_date);
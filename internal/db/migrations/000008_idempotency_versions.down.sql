@@ -0,0 +1,4 @@
+ALTER TABLE floor_plan_positions DROP COLUMN version;
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE bookings DROP COLUMN version;
+DROP TABLE IF EXISTS idempotency_keys;
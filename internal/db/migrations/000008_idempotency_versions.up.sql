@@ -0,0 +1,21 @@
+-- Stored responses of requests sent with an Idempotency-Key header, replayed
+-- when the same user retries the key. status 0 marks a request in progress.
+CREATE TABLE idempotency_keys (
+  user_id TEXT NOT NULL,
+  idempotency_key TEXT NOT NULL,
+  method TEXT NOT NULL,
+  path TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  status INTEGER NOT NULL DEFAULT 0,
+  content_type TEXT NOT NULL DEFAULT '',
+  body BLOB,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_created ON idempotency_keys(created_at);
+
+-- Versions back the ETag / If-Match checks; every edit increments them.
+ALTER TABLE bookings ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE floor_plan_positions ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
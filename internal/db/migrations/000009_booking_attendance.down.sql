@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS booking_attendance;
+ALTER TABLE bookings DROP COLUMN checked_in_at;
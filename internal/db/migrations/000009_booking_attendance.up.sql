@@ -0,0 +1,17 @@
+-- Set when the booked user confirms they arrived; bookings in areas that
+-- require check-in and stay unchecked count as no-shows.
+ALTER TABLE bookings ADD COLUMN checked_in_at TEXT;
+
+-- Late cancellations and no-shows per user. Canceled bookings are deleted, so
+-- the event keeps the booking's item and date.
+CREATE TABLE booking_attendance (
+  booking_id TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  item_id TEXT NOT NULL,
+  booking_date TEXT NOT NULL,
+  recorded_at TEXT NOT NULL,
+  PRIMARY KEY (booking_id, kind)
+);
+
+CREATE INDEX idx_booking_attendance_user ON booking_attendance(user_id, booking_date);
@@ -0,0 +1,4 @@
+ALTER TABLE bookings DROP COLUMN pending_confirmation;
+DROP INDEX IF EXISTS idx_delegations_delegate;
+DROP INDEX IF EXISTS idx_delegations_pair;
+DROP TABLE IF EXISTS delegations;
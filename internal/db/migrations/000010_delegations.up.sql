@@ -0,0 +1,17 @@
+-- Delegates (assistants, team leads) may book and cancel for the user who
+-- granted the delegation. user_id is NULL for org-wide delegates defined by
+-- admins, who may act for every user.
+CREATE TABLE delegations (
+  id TEXT PRIMARY KEY,
+  user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+  delegate_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_by_user_id TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_delegations_pair ON delegations(COALESCE(user_id, ''), delegate_id);
+CREATE INDEX idx_delegations_delegate ON delegations(delegate_id);
+
+-- Bookings made for a user outside a delegation wait for the user's confirmation
+-- when bookings.on_behalf_without_delegation is "confirm".
+ALTER TABLE bookings ADD COLUMN pending_confirmation INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Values of the custom booking fields declared in the areas config, as a JSON
+-- object keyed by field ID; empty when the booking has none.
+ALTER TABLE bookings ADD COLUMN custom_fields TEXT NOT NULL DEFAULT '';
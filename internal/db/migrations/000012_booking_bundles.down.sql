@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS bundle_defaults;
+DROP INDEX IF EXISTS idx_bookings_bundle_id;
+ALTER TABLE bookings DROP COLUMN bundle_id;
@@ -0,0 +1,17 @@
+-- Bookings created together as a bundle (e.g. a desk and a parking lot) share
+-- a bundle ID; empty for bookings outside a bundle.
+ALTER TABLE bookings ADD COLUMN bundle_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_bookings_bundle_id ON bookings(bundle_id) WHERE bundle_id != '';
+
+-- Items users want added to every bundle they book, e.g. "also book parking".
+-- item_group_id narrows the area, item_id names the preferred item.
+CREATE TABLE bundle_defaults (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  area_id TEXT NOT NULL,
+  item_group_id TEXT NOT NULL DEFAULT '',
+  item_id TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  UNIQUE (user_id, area_id)
+);
@@ -0,0 +1,10 @@
+-- Favorite items per user, tried in position order by "book my usual".
+-- item_id is not checked against the areas config: favorites of removed
+-- items are kept and flagged as missing.
+CREATE TABLE favorites (
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  item_id TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (user_id, item_id)
+);
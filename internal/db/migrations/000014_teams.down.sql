@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_team_members_user;
+DROP TABLE IF EXISTS team_members;
+DROP INDEX IF EXISTS idx_teams_idp_group;
+DROP TABLE IF EXISTS teams;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_subscriptions_target;
+DROP TABLE IF EXISTS subscriptions;
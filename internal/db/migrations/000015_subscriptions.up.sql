@@ -0,0 +1,14 @@
+-- Booking subscriptions: users follow a colleague, an item group (room), or
+-- an area on one date. Matching booking events are sent to the subscriber in
+-- digests. booking_date is only set for area subscriptions.
+CREATE TABLE subscriptions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  target_id TEXT NOT NULL,
+  booking_date TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  UNIQUE (user_id, kind, target_id, booking_date)
+);
+
+CREATE INDEX idx_subscriptions_target ON subscriptions(kind, target_id);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN work_status_visibility;
+DROP INDEX IF EXISTS idx_work_statuses_date;
+DROP TABLE IF EXISTS work_statuses;
@@ -0,0 +1,17 @@
+-- Daily work status per user (office, remote, travelling, off), independent
+-- of bookings.
+CREATE TABLE work_statuses (
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status_date TEXT NOT NULL,
+  status TEXT NOT NULL,
+  note TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  PRIMARY KEY (user_id, status_date)
+);
+
+CREATE INDEX idx_work_statuses_date ON work_statuses(status_date);
+
+-- Who sees a user's work statuses: everyone, members of a shared team, or
+-- nobody but the user.
+ALTER TABLE users ADD COLUMN work_status_visibility TEXT NOT NULL DEFAULT 'team';
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN private_bookings;
+ALTER TABLE bookings DROP COLUMN is_private;
@@ -0,0 +1,5 @@
+-- Private bookings hide who booked an item from other users.
+ALTER TABLE bookings ADD COLUMN is_private INTEGER NOT NULL DEFAULT 0;
+
+-- Users who book privately by default.
+ALTER TABLE users ADD COLUMN private_bookings INTEGER NOT NULL DEFAULT 0;
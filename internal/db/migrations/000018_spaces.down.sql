@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS space_settings;
+DROP INDEX IF EXISTS idx_spaces_parent;
+DROP TABLE IF EXISTS spaces;
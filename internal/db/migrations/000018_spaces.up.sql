@@ -0,0 +1,26 @@
+-- Areas, item groups, and items edited through the admin API when the areas
+-- source is "database". definition holds the node's settings as in the areas
+-- YAML, without its children, so import and export keep every setting.
+-- Archived nodes keep their ID so that old bookings still resolve.
+CREATE TABLE spaces (
+  kind TEXT NOT NULL,
+  id TEXT NOT NULL,
+  parent_id TEXT NOT NULL DEFAULT '',
+  position INTEGER NOT NULL,
+  archived INTEGER NOT NULL DEFAULT 0,
+  definition TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  version INTEGER NOT NULL DEFAULT 1,
+  PRIMARY KEY (kind, id)
+);
+
+CREATE INDEX idx_spaces_parent ON spaces(kind, parent_id, position);
+
+-- The top-level settings of the areas YAML (time zone, features, closures,
+-- policies, cancellation). The row exists once spaces have been imported.
+CREATE TABLE space_settings (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  definition TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
@@ -0,0 +1,2 @@
+-- The ETag header of a stored response, replayed with it.
+ALTER TABLE idempotency_keys ADD COLUMN etag TEXT NOT NULL DEFAULT '';
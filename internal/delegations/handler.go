@@ -0,0 +1,189 @@
+package delegations
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const resourceTypeDelegation = "delegations"
+
+// Attributes represents delegation resource attributes. UserID and UserName
+// are empty for org-wide delegates.
+type Attributes struct {
+	UserID          string `json:"user_id,omitempty"`
+	UserName        string `json:"user_name,omitempty"`
+	DelegateID      string `json:"delegate_id"`
+	DelegateName    string `json:"delegate_name"`
+	OrgWide         bool   `json:"org_wide"`
+	CreatedByUserID string `json:"created_by_user_id"`
+	CreatedAt       string `json:"created_at"`
+}
+
+type createRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			UserID     string `json:"user_id"`
+			DelegateID string `json:"delegate_id"`
+			OrgWide    bool   `json:"org_wide"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns the delegations the current user granted or holds.
+// GET /api/v1/delegations
+func ListHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		return writeList(c, store, user.ID)
+	}
+}
+
+// AdminListHandler returns all delegations, including org-wide delegates.
+// GET /api/v1/admin/delegations
+func AdminListHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return writeList(c, store, "")
+	}
+}
+
+// CreateHandler grants a delegate the right to book and cancel for the current
+// user. Admins can grant delegations for any user (user_id) and define
+// org-wide delegates (org_wide).
+// POST /api/v1/delegations
+func CreateHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req createRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeDelegation {
+			return api.WriteBadRequest(c, "Resource type must be 'delegations'")
+		}
+		a := req.Data.Attributes
+		delegateID := strings.TrimSpace(a.DelegateID)
+		userID := strings.TrimSpace(a.UserID)
+		if delegateID == "" {
+			return api.WriteBadRequest(c, "delegate_id is required")
+		}
+		switch {
+		case a.OrgWide && userID != "":
+			return api.WriteBadRequest(c, "Provide either user_id or org_wide, not both")
+		case a.OrgWide || (userID != "" && userID != user.ID):
+			if !user.IsAdmin {
+				return api.WriteForbiddenDetail(c, "Only admins can grant delegations for other users")
+			}
+		default:
+			userID = user.ID
+		}
+		if delegateID == userID {
+			return api.WriteBadRequest(c, "Users cannot delegate to themselves")
+		}
+
+		ctx := c.Request().Context()
+		for _, id := range []string{userID, delegateID} {
+			if id == "" {
+				continue
+			}
+			if _, err := users.FindByID(ctx, store, id); errors.Is(err, users.ErrUserNotFound) {
+				return api.WriteBadRequest(c, "User not found: "+id)
+			} else if err != nil {
+				return api.WriteInternalError(c, "find user", err)
+			}
+		}
+
+		d, err := Create(ctx, store, userID, delegateID, user.ID)
+		if errors.Is(err, ErrExists) {
+			return api.WriteConflict(c, "This delegation already exists")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "create delegation", err)
+		}
+		slog.Info("delegation granted",
+			"delegation_id", d.ID, "user_id", d.UserID, "delegate_id", d.DelegateID, "granted_by", user.ID)
+
+		names, err := users.FindDisplayNames(ctx, store, []string{d.UserID, d.DelegateID})
+		if err != nil {
+			return api.WriteInternalError(c, "find user names", err)
+		}
+		return api.WriteSingle(c, http.StatusCreated, delegationResource(d, names), "write delegation response")
+	}
+}
+
+// DeleteHandler revokes a delegation. The granting user, the delegate, and
+// admins can revoke it; org-wide delegations only the delegate and admins.
+// DELETE /api/v1/delegations/:id
+func DeleteHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		ctx := c.Request().Context()
+		d, err := Find(ctx, store, c.Param("id"))
+		if errors.Is(err, ErrNotFound) {
+			return api.WriteNotFound(c, "Delegation not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find delegation", err)
+		}
+		if !user.IsAdmin && d.DelegateID != user.ID && (d.OrgWide() || d.UserID != user.ID) {
+			return api.WriteNotFound(c, "Delegation not found")
+		}
+		if err := Delete(ctx, store, d.ID); err != nil {
+			return api.WriteInternalError(c, "delete delegation", err)
+		}
+		slog.Info("delegation revoked", "delegation_id", d.ID, "revoked_by", user.ID)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func writeList(c echo.Context, store *sql.DB, userID string) error {
+	ctx := c.Request().Context()
+	list, err := List(ctx, store, userID)
+	if err != nil {
+		return api.WriteInternalError(c, "list delegations", err)
+	}
+	ids := make([]string, 0, 2*len(list))
+	for i := range list {
+		ids = append(ids, list[i].UserID, list[i].DelegateID)
+	}
+	names, err := users.FindDisplayNames(ctx, store, ids)
+	if err != nil {
+		return api.WriteInternalError(c, "find user names", err)
+	}
+	resources := api.MapResources(list, func(d Delegation) api.Resource { return delegationResource(&d, names) })
+	return api.WriteCollection(c, resources, "write delegations response")
+}
+
+func delegationResource(d *Delegation, names map[string]string) api.Resource {
+	attrs := Attributes{
+		UserID:          d.UserID,
+		DelegateID:      d.DelegateID,
+		DelegateName:    names[d.DelegateID],
+		OrgWide:         d.OrgWide(),
+		CreatedByUserID: d.CreatedByUserID,
+		CreatedAt:       d.CreatedAt,
+	}
+	if d.UserID != "" {
+		attrs.UserName = names[d.UserID]
+	}
+	return api.Resource{Type: resourceTypeDelegation, ID: d.ID, Attributes: attrs}
+}
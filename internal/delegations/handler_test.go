@@ -0,0 +1,161 @@
+package delegations
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/db"
+)
+
+var (
+	boss      = &auth.User{ID: "boss", Name: "Bea Boss"}
+	assistant = &auth.User{ID: "assistant", Name: "Assistant"}
+	admin     = &auth.User{ID: "admin", Name: "Admin", IsAdmin: true}
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, u := range []*auth.User{boss, assistant, admin, {ID: "other", Name: "Other"}} {
+		_, err := store.Exec(
+			`INSERT INTO users (id, email, display_name, user_source, created_at, updated_at)
+			 VALUES (?, ?, ?, 'internal', ?, ?)`,
+			u.ID, u.ID+"@example.com", u.Name, now, now,
+		)
+		require.NoError(t, err)
+	}
+	return store
+}
+
+func serve(
+	t *testing.T, h echo.HandlerFunc, user *auth.User, method, body, id string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1/delegations", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if id != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+	}
+	c.Set("user", user)
+	require.NoError(t, h(c))
+	return rec
+}
+
+func createBody(attributes string) string {
+	return `{"data":{"type":"delegations","attributes":{` + attributes + `}}}`
+}
+
+func TestCreateHandler(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	h := CreateHandler(store)
+
+	rec := serve(t, h, boss, http.MethodPost, createBody(`"delegate_id":"assistant"`), "")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp struct {
+		Data struct {
+			ID         string     `json:"id"`
+			Attributes Attributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, Attributes{
+		UserID: "boss", UserName: "Bea Boss", DelegateID: "assistant", DelegateName: "Assistant",
+		CreatedByUserID: "boss", CreatedAt: resp.Data.Attributes.CreatedAt,
+	}, resp.Data.Attributes)
+
+	rec = serve(t, h, boss, http.MethodPost, createBody(`"delegate_id":"assistant"`), "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	cases := map[string]int{
+		`"delegate_id":"boss"`:                            http.StatusBadRequest,
+		`"delegate_id":"nobody"`:                          http.StatusBadRequest,
+		`"delegate_id":""`:                                http.StatusBadRequest,
+		`"delegate_id":"assistant","user_id":"other"`:     http.StatusForbidden,
+		`"delegate_id":"assistant","org_wide":true`:       http.StatusForbidden,
+		`"delegate_id":"boss","user_id":"boss"`:           http.StatusBadRequest,
+		`"delegate_id":"other","user_id":"boss"`:          http.StatusCreated,
+		`"delegate_id":"x","user_id":"y","org_wide":true`: http.StatusBadRequest,
+	}
+	for attributes, want := range cases {
+		rec := serve(t, h, boss, http.MethodPost, createBody(attributes), "")
+		assert.Equal(t, want, rec.Code, attributes)
+	}
+
+	rec = serve(t, h, admin, http.MethodPost, createBody(`"delegate_id":"assistant","org_wide":true`), "")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = serve(t, h, admin, http.MethodPost, createBody(`"delegate_id":"boss","user_id":"other"`), "")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	ok, err := CanActFor(t.Context(), store, "assistant", "anyone")
+	require.NoError(t, err)
+	assert.True(t, ok, "org-wide delegates act for everyone")
+	ok, err = CanActFor(t.Context(), store, "boss", "other")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = CanActFor(t.Context(), store, "other", "assistant")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestListAndDeleteHandlers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	ctx := t.Context()
+	granted, err := Create(ctx, store, "boss", "assistant", "boss")
+	require.NoError(t, err)
+	orgWide, err := Create(ctx, store, "", "assistant", "admin")
+	require.NoError(t, err)
+	unrelated, err := Create(ctx, store, "other", "admin", "other")
+	require.NoError(t, err)
+
+	ids := func(rec *httptest.ResponseRecorder) []string {
+		var resp api.CollectionResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		out := make([]string, 0, len(resp.Data))
+		for _, r := range resp.Data {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+	assert.ElementsMatch(t, []string{granted.ID}, ids(serve(t, ListHandler(store), boss, http.MethodGet, "", "")))
+	assert.ElementsMatch(t, []string{granted.ID, orgWide.ID},
+		ids(serve(t, ListHandler(store), assistant, http.MethodGet, "", "")))
+	assert.ElementsMatch(t, []string{granted.ID, orgWide.ID, unrelated.ID},
+		ids(serve(t, AdminListHandler(store), admin, http.MethodGet, "", "")))
+
+	h := DeleteHandler(store)
+	assert.Equal(t, http.StatusNotFound, serve(t, h, boss, http.MethodDelete, "", orgWide.ID).Code)
+	assert.Equal(t, http.StatusNotFound, serve(t, h, boss, http.MethodDelete, "", unrelated.ID).Code)
+	assert.Equal(t, http.StatusNoContent, serve(t, h, assistant, http.MethodDelete, "", orgWide.ID).Code)
+	assert.Equal(t, http.StatusNoContent, serve(t, h, boss, http.MethodDelete, "", granted.ID).Code)
+	assert.Equal(t, http.StatusNoContent, serve(t, h, admin, http.MethodDelete, "", unrelated.ID).Code)
+	assert.Equal(t, http.StatusNotFound, serve(t, h, admin, http.MethodDelete, "", unrelated.ID).Code)
+
+	list, err := List(ctx, store, "")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
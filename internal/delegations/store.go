@@ -0,0 +1,132 @@
+// Package delegations manages who may book and cancel on behalf of whom.
+package delegations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrNotFound indicates the requested delegation does not exist.
+var ErrNotFound = errors.New("delegation not found")
+
+// ErrExists indicates the delegate already holds the delegation.
+var ErrExists = errors.New("delegation already exists")
+
+// Delegation allows DelegateID to book and cancel for UserID. An empty UserID
+// marks an org-wide delegate, who may act for every user.
+type Delegation struct {
+	ID              string
+	UserID          string
+	DelegateID      string
+	CreatedByUserID string
+	CreatedAt       string
+}
+
+// OrgWide reports whether the delegate may act for every user.
+func (d *Delegation) OrgWide() bool {
+	return d.UserID == ""
+}
+
+const delegationColumns = `id, COALESCE(user_id, ''), delegate_id, created_by_user_id, created_at`
+
+// Create grants delegateID the right to act for userID, or for every user if
+// userID is empty. Returns ErrExists if the delegation is already granted.
+func Create(ctx context.Context, db *sql.DB, userID, delegateID, createdBy string) (*Delegation, error) {
+	d := &Delegation{
+		ID:              uuid.NewString(),
+		UserID:          userID,
+		DelegateID:      delegateID,
+		CreatedByUserID: createdBy,
+		CreatedAt:       time.Now().UTC().Format(time.RFC3339),
+	}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO delegations (id, user_id, delegate_id, created_by_user_id, created_at)
+		 VALUES (?, NULLIF(?, ''), ?, ?, ?)`,
+		d.ID, d.UserID, d.DelegateID, d.CreatedByUserID, d.CreatedAt,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return nil, ErrExists
+		}
+		return nil, fmt.Errorf("insert delegation: %w", err)
+	}
+	return d, nil
+}
+
+// Find returns the delegation with the given ID, or ErrNotFound.
+func Find(ctx context.Context, db *sql.DB, id string) (*Delegation, error) {
+	var d Delegation
+	err := db.QueryRowContext(ctx,
+		`SELECT `+delegationColumns+` FROM delegations WHERE id = ?`, id,
+	).Scan(&d.ID, &d.UserID, &d.DelegateID, &d.CreatedByUserID, &d.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find delegation: %w", err)
+	}
+	return &d, nil
+}
+
+// Delete removes the delegation with the given ID.
+func Delete(ctx context.Context, db *sql.DB, id string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM delegations WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete delegation: %w", err)
+	}
+	return nil
+}
+
+// List returns the delegations granted by or to userID, including org-wide
+// delegations held by userID. An empty userID lists all delegations.
+func List(ctx context.Context, db *sql.DB, userID string) (result []Delegation, err error) {
+	query := `SELECT ` + delegationColumns + ` FROM delegations`
+	var args []any
+	if userID != "" {
+		query += ` WHERE user_id = ? OR delegate_id = ?`
+		args = append(args, userID, userID)
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query delegations: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close delegations rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var d Delegation
+		if err := rows.Scan(&d.ID, &d.UserID, &d.DelegateID, &d.CreatedByUserID, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan delegation: %w", err)
+		}
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate delegations: %w", err)
+	}
+	return result, nil
+}
+
+// CanActFor reports whether delegateID may book and cancel for userID, either
+// through a delegation from userID or as an org-wide delegate.
+func CanActFor(ctx context.Context, db *sql.DB, delegateID, userID string) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM delegations WHERE delegate_id = ? AND (user_id = ? OR user_id IS NULL)`,
+		delegateID, userID,
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("check delegation: %w", err)
+	}
+	return n > 0, nil
+}
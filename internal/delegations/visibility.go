@@ -0,0 +1,54 @@
+package delegations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+// BookerVisibility tells whether a viewer sees who holds a private booking.
+// Admins, the booked user, whoever made the booking, and the booked user's
+// delegates do; everyone else only sees that the item is occupied.
+type BookerVisibility struct {
+	viewer     *auth.User
+	orgWide    bool
+	delegators map[string]bool
+}
+
+// LoadBookerVisibility loads the delegations held by viewer.
+func LoadBookerVisibility(ctx context.Context, store *sql.DB, viewer *auth.User) (*BookerVisibility, error) {
+	v := &BookerVisibility{viewer: viewer, delegators: map[string]bool{}}
+	if viewer == nil || viewer.IsAdmin {
+		return v, nil
+	}
+	list, err := List(ctx, store, viewer.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load booker visibility: %w", err)
+	}
+	for i := range list {
+		switch {
+		case list[i].DelegateID != viewer.ID:
+		case list[i].OrgWide():
+			v.orgWide = true
+		default:
+			v.delegators[list[i].UserID] = true
+		}
+	}
+	return v, nil
+}
+
+// Sees reports whether the viewer sees the booked user of a booking for
+// userID made by bookedByUserID.
+func (v *BookerVisibility) Sees(userID, bookedByUserID string, isPrivate bool) bool {
+	switch {
+	case !isPrivate:
+		return true
+	case v.viewer == nil:
+		return false
+	case v.viewer.IsAdmin, v.viewer.ID == userID, v.viewer.ID == bookedByUserID:
+		return true
+	}
+	return v.orgWide || v.delegators[userID]
+}
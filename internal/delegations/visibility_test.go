@@ -0,0 +1,42 @@
+package delegations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+func TestBookerVisibility(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	_, err := Create(t.Context(), store, "boss", "assistant", "admin")
+	require.NoError(t, err)
+	_, err = Create(t.Context(), store, "", "other", "admin")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		viewer *auth.User
+		want   bool
+	}{
+		{boss, true},
+		{&auth.User{ID: "booker"}, true},
+		{assistant, true},
+		{&auth.User{ID: "other"}, true},
+		{admin, true},
+		{&auth.User{ID: "colleague"}, false},
+		{nil, false},
+	} {
+		vis, err := LoadBookerVisibility(t.Context(), store, tc.viewer)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, vis.Sees("boss", "booker", true), tc.viewer)
+		assert.True(t, vis.Sees("boss", "booker", false), "public bookings are visible to everyone")
+	}
+
+	vis, err := LoadBookerVisibility(t.Context(), store, boss)
+	require.NoError(t, err)
+	assert.False(t, vis.Sees("assistant", "assistant", true), "delegations are not mutual")
+}
@@ -0,0 +1,203 @@
+package favorites
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+const resourceTypeFavorite = "favorites"
+
+// Attributes represents favorite resource attributes. The item, item group,
+// and area fields are empty and Missing is set when the item was removed from
+// the areas config.
+type Attributes struct {
+	ItemID        string `json:"item_id"`
+	ItemName      string `json:"item_name,omitempty"`
+	ItemGroupID   string `json:"item_group_id,omitempty"`
+	ItemGroupName string `json:"item_group_name,omitempty"`
+	AreaID        string `json:"area_id,omitempty"`
+	AreaName      string `json:"area_name,omitempty"`
+	Position      int    `json:"position"`
+	Missing       bool   `json:"missing"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type createRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			ItemID string `json:"item_id"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type replaceRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			ItemIDs []string `json:"item_ids"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns the current user's favorites in order of preference.
+// GET /api/v1/me/favorites
+func ListHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		return writeList(c, getConfig(), store, user.ID)
+	}
+}
+
+// CreateHandler appends an item to the current user's favorites.
+// POST /api/v1/me/favorites
+func CreateHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req createRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeFavorite {
+			return api.WriteBadRequest(c, "Resource type must be 'favorites'")
+		}
+		itemID := strings.TrimSpace(req.Data.Attributes.ItemID)
+		if itemID == "" {
+			return api.WriteBadRequest(c, "item_id is required")
+		}
+		cfg := getConfig()
+		if _, ok := cfg.FindItemLocation(itemID); !ok {
+			return api.WriteNotFound(c, "Item not found")
+		}
+
+		f, err := Add(c.Request().Context(), store, user.ID, itemID)
+		switch {
+		case errors.Is(err, ErrExists):
+			return api.WriteConflict(c, "This item already is a favorite")
+		case errors.Is(err, ErrLimitReached):
+			return api.WriteConflict(c, fmt.Sprintf("You can have at most %d favorites", MaxFavorites))
+		case err != nil:
+			return api.WriteInternalError(c, "add favorite", err)
+		}
+		slog.Info("favorite added", "user_id", user.ID, "item_id", itemID)
+		return api.WriteSingle(c, http.StatusCreated, favoriteResource(cfg, f), "write favorite response")
+	}
+}
+
+// ReplaceHandler replaces the current user's favorites with the given items,
+// e.g. to reorder them. Favorites of removed items may be kept.
+// PUT /api/v1/me/favorites
+func ReplaceHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req replaceRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeFavorite {
+			return api.WriteBadRequest(c, "Resource type must be 'favorites'")
+		}
+		itemIDs := req.Data.Attributes.ItemIDs
+		if len(itemIDs) > MaxFavorites {
+			return api.WriteBadRequest(c, fmt.Sprintf("You can have at most %d favorites", MaxFavorites))
+		}
+
+		ctx := c.Request().Context()
+		current, err := List(ctx, store, user.ID)
+		if err != nil {
+			return api.WriteInternalError(c, "list favorites", err)
+		}
+		known := make(map[string]bool, len(current))
+		for i := range current {
+			known[current[i].ItemID] = true
+		}
+		cfg := getConfig()
+		seen := make(map[string]bool, len(itemIDs))
+		for i, id := range itemIDs {
+			id = strings.TrimSpace(id)
+			if id == "" || seen[id] {
+				return api.WriteBadRequest(c, "item_ids must be unique and not empty")
+			}
+			if _, ok := cfg.FindItemLocation(id); !ok && !known[id] {
+				return api.WriteNotFound(c, "Item not found: "+id)
+			}
+			seen[id] = true
+			itemIDs[i] = id
+		}
+
+		if err := Replace(ctx, store, user.ID, itemIDs); err != nil {
+			return api.WriteInternalError(c, "replace favorites", err)
+		}
+		slog.Info("favorites replaced", "user_id", user.ID, "count", len(itemIDs))
+		return writeList(c, cfg, store, user.ID)
+	}
+}
+
+// DeleteHandler removes an item from the current user's favorites.
+// DELETE /api/v1/me/favorites/:item_id
+func DeleteHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		itemID := c.Param("item_id")
+		err := Remove(c.Request().Context(), store, user.ID, itemID)
+		if errors.Is(err, ErrNotFound) {
+			return api.WriteNotFound(c, "Favorite not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "delete favorite", err)
+		}
+		slog.Info("favorite removed", "user_id", user.ID, "item_id", itemID)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func writeList(c echo.Context, cfg *areas.Config, store *sql.DB, userID string) error {
+	list, err := List(c.Request().Context(), store, userID)
+	if err != nil {
+		return api.WriteInternalError(c, "list favorites", err)
+	}
+	resources := make([]api.Resource, len(list))
+	for i := range list {
+		// Positions may have gaps after removals; report the rank instead.
+		list[i].Position = i + 1
+		resources[i] = favoriteResource(cfg, &list[i])
+	}
+	return api.WriteCollection(c, resources, "write favorites response")
+}
+
+func favoriteResource(cfg *areas.Config, f *Favorite) api.Resource {
+	attrs := Attributes{ItemID: f.ItemID, Position: f.Position, CreatedAt: f.CreatedAt}
+	if loc, ok := cfg.FindItemLocation(f.ItemID); ok {
+		attrs.ItemName = loc.Item.Name
+		attrs.ItemGroupID = loc.ItemGroup.ID
+		attrs.ItemGroupName = loc.ItemGroup.Name
+		attrs.AreaID = loc.Area.ID
+		attrs.AreaName = loc.Area.Name
+	} else {
+		attrs.Missing = true
+	}
+	return api.Resource{Type: resourceTypeFavorite, ID: f.ItemID, Attributes: attrs}
+}
@@ -0,0 +1,166 @@
+package favorites
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/db"
+)
+
+var user = &auth.User{ID: "u1", Name: "User One"}
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = store.Exec(
+		`INSERT INTO users (id, email, display_name, user_source, created_at, updated_at)
+		 VALUES (?, ?, ?, 'internal', ?, ?)`,
+		user.ID, "u1@example.com", user.Name, now, now,
+	)
+	require.NoError(t, err)
+	return store
+}
+
+func testConfig() *areas.Config {
+	return &areas.Config{Areas: []areas.Area{{
+		ID: "office", Name: "Office",
+		ItemGroups: []areas.ItemGroup{{ID: "room-1", Name: "Room 1", Items: []areas.Item{
+			{ID: "desk-1", Name: "Desk 1"}, {ID: "desk-2", Name: "Desk 2"}, {ID: "desk-3", Name: "Desk 3"},
+		}}},
+	}}}
+}
+
+func serve(t *testing.T, h echo.HandlerFunc, method, body, itemID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1/me/favorites", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if itemID != "" {
+		c.SetParamNames("item_id")
+		c.SetParamValues(itemID)
+	}
+	c.Set("user", user)
+	require.NoError(t, h(c))
+	return rec
+}
+
+func listAttributes(t *testing.T, rec *httptest.ResponseRecorder) []Attributes {
+	t.Helper()
+	var resp struct {
+		Data []struct {
+			Attributes Attributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	out := make([]Attributes, len(resp.Data))
+	for i := range resp.Data {
+		out[i] = resp.Data[i].Attributes
+	}
+	return out
+}
+
+func TestCreateAndDeleteHandlers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	cfg := testConfig()
+	getConfig := func() *areas.Config { return cfg }
+	h := CreateHandler(getConfig, store)
+	create := func(itemID string) *httptest.ResponseRecorder {
+		return serve(t, h, http.MethodPost, `{"data":{"type":"favorites","attributes":{"item_id":"`+itemID+`"}}}`, "")
+	}
+
+	rec := create("desk-2")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"item_group_name":"Room 1"`)
+	require.Equal(t, http.StatusCreated, create("desk-1").Code)
+	assert.Equal(t, http.StatusConflict, create("desk-1").Code)
+	assert.Equal(t, http.StatusNotFound, create("nowhere").Code)
+	assert.Equal(t, http.StatusBadRequest, create(" ").Code)
+	rec = serve(t, h, http.MethodPost, `{"data":{"type":"items","attributes":{"item_id":"desk-3"}}}`, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	list := listAttributes(t, serve(t, ListHandler(getConfig, store), http.MethodGet, "", ""))
+	require.Len(t, list, 2)
+	assert.Equal(t, "desk-2", list[0].ItemID)
+	assert.Equal(t, 2, list[1].Position)
+
+	del := DeleteHandler(store)
+	assert.Equal(t, http.StatusNoContent, serve(t, del, http.MethodDelete, "", "desk-2").Code)
+	assert.Equal(t, http.StatusNotFound, serve(t, del, http.MethodDelete, "", "desk-2").Code)
+	list = listAttributes(t, serve(t, ListHandler(getConfig, store), http.MethodGet, "", ""))
+	require.Len(t, list, 1)
+	assert.Equal(t, 1, list[0].Position)
+}
+
+func TestFavoriteLimit(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	for i := range MaxFavorites {
+		_, err := Add(t.Context(), store, user.ID, "item-"+string(rune('a'+i)))
+		require.NoError(t, err)
+	}
+	_, err := Add(t.Context(), store, user.ID, "desk-1")
+	assert.ErrorIs(t, err, ErrLimitReached)
+}
+
+func TestReplaceHandlerKeepsMissingItems(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	ctx := t.Context()
+	for _, id := range []string{"desk-1", "retired-desk"} {
+		_, err := Add(ctx, store, user.ID, id)
+		require.NoError(t, err)
+	}
+	cfg := testConfig()
+	getConfig := func() *areas.Config { return cfg }
+
+	list := listAttributes(t, serve(t, ListHandler(getConfig, store), http.MethodGet, "", ""))
+	require.Len(t, list, 2)
+	assert.False(t, list[0].Missing)
+	assert.True(t, list[1].Missing)
+	assert.Empty(t, list[1].AreaID)
+
+	h := ReplaceHandler(getConfig, store)
+	replace := func(ids string) *httptest.ResponseRecorder {
+		return serve(t, h, http.MethodPut, `{"data":{"type":"favorites","attributes":{"item_ids":[`+ids+`]}}}`, "")
+	}
+	assert.Equal(t, http.StatusBadRequest, replace(`"desk-3","desk-3"`).Code)
+	assert.Equal(t, http.StatusNotFound, replace(`"other-retired-desk"`).Code)
+
+	rec := replace(`"desk-3","retired-desk","desk-1"`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	list = listAttributes(t, rec)
+	require.Len(t, list, 3)
+	assert.Equal(t, "desk-3", list[0].ItemID)
+	assert.True(t, list[1].Missing)
+	assert.Equal(t, 3, list[2].Position)
+
+	rec = replace(``)
+	require.Equal(t, http.StatusOK, rec.Code)
+	stored, err := List(ctx, store, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
@@ -0,0 +1,154 @@
+// Package favorites manages the items users book most often, in their order
+// of preference.
+package favorites
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxFavorites is the maximum number of favorites per user.
+const MaxFavorites = 20
+
+// ErrNotFound indicates the item is not one of the user's favorites.
+var ErrNotFound = errors.New("favorite not found")
+
+// ErrExists indicates the item already is one of the user's favorites.
+var ErrExists = errors.New("favorite already exists")
+
+// ErrLimitReached indicates the user already has MaxFavorites favorites.
+var ErrLimitReached = errors.New("favorite limit reached")
+
+// Favorite marks ItemID as a favorite of UserID. Lower positions are
+// preferred.
+type Favorite struct {
+	UserID    string
+	ItemID    string
+	Position  int
+	CreatedAt string
+}
+
+// List returns the favorites of a user in order of preference.
+func List(ctx context.Context, db *sql.DB, userID string) (result []Favorite, err error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT user_id, item_id, position, created_at FROM favorites
+		 WHERE user_id = ? ORDER BY position, created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query favorites: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close favorites rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var f Favorite
+		if err := rows.Scan(&f.UserID, &f.ItemID, &f.Position, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan favorite: %w", err)
+		}
+		result = append(result, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate favorites: %w", err)
+	}
+	return result, nil
+}
+
+// Add appends itemID to the user's favorites. Returns ErrExists if it is
+// already a favorite and ErrLimitReached if the user has MaxFavorites.
+func Add(ctx context.Context, db *sql.DB, userID, itemID string) (*Favorite, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var count, exists, last int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(item_id = ?), 0), COALESCE(MAX(position), 0)
+		 FROM favorites WHERE user_id = ?`, itemID, userID,
+	).Scan(&count, &exists, &last)
+	if err != nil {
+		return nil, fmt.Errorf("count favorites: %w", err)
+	}
+	if exists > 0 {
+		return nil, ErrExists
+	}
+	if count >= MaxFavorites {
+		return nil, ErrLimitReached
+	}
+
+	f := &Favorite{
+		UserID:    userID,
+		ItemID:    itemID,
+		Position:  last + 1,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO favorites (user_id, item_id, position, created_at) VALUES (?, ?, ?, ?)`,
+		f.UserID, f.ItemID, f.Position, f.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("insert favorite: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit favorite: %w", err)
+	}
+	return f, nil
+}
+
+// Replace sets the user's favorites to itemIDs, in that order. Items that
+// stay favorites keep their creation time.
+func Replace(ctx context.Context, db *sql.DB, userID string, itemIDs []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, itemID := range itemIDs {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO favorites (user_id, item_id, position, created_at) VALUES (?, ?, ?, ?)
+			 ON CONFLICT (user_id, item_id) DO UPDATE SET position = excluded.position`,
+			userID, itemID, i+1, now,
+		); err != nil {
+			return fmt.Errorf("upsert favorite: %w", err)
+		}
+	}
+	query := `DELETE FROM favorites WHERE user_id = ?`
+	args := []any{userID}
+	if len(itemIDs) > 0 {
+		query += ` AND item_id NOT IN (?` + strings.Repeat(", ?", len(itemIDs)-1) + `)`
+		for _, id := range itemIDs {
+			args = append(args, id)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("delete favorites: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit favorites: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes itemID from the user's favorites, or returns ErrNotFound.
+func Remove(ctx context.Context, db *sql.DB, userID, itemID string) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM favorites WHERE user_id = ? AND item_id = ?`, userID, itemID)
+	if err != nil {
+		return fmt.Errorf("delete favorite: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete favorite: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
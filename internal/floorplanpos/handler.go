@@ -11,6 +11,12 @@ import (
 	"github.com/thorstenkramm/sithub/internal/api"
 )
 
+// queryOptions lists the sort and filter fields of the positions collection.
+var queryOptions = api.QueryOptions{
+	Sort:    []string{"item_id", "label", "x", "y", "created_at", "updated_at"},
+	Filters: []string{"item_id", "label"},
+}
+
 func toResource(p Position) api.Resource { //nolint:gocritic // value needed for MapResources
 	attrs := map[string]interface{}{
 		"floor_plan":   p.FloorPlan,
@@ -35,12 +41,17 @@ func toResource(p Position) api.Resource { //nolint:gocritic // value needed for
 
 // ListHandler returns positions for a floor plan.
 // GET /api/v1/floor-plan-positions?floor_plan=<filename>
+// Supports JSON:API page, sort, filter, and fields parameters.
 func ListHandler(db *sql.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		floorPlan := c.QueryParam("floor_plan")
 		if floorPlan == "" {
 			return api.WriteBadRequest(c, "Missing floor_plan query parameter")
 		}
+		query, err := api.ParseQuery(c, queryOptions)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
 
 		positions, err := FindByFloorPlan(c.Request().Context(), db, floorPlan)
 		if err != nil {
@@ -48,7 +59,7 @@ func ListHandler(db *sql.DB) echo.HandlerFunc {
 		}
 
 		resources := api.MapResources(positions, toResource)
-		return api.WriteCollection(c, resources, "write positions response")
+		return api.WriteQueryCollection(c, query, resources, "write positions response")
 	}
 }
 
@@ -96,6 +107,7 @@ func CreateHandler(db *sql.DB) echo.HandlerFunc {
 			return api.WriteInternalError(c, "create position", err)
 		}
 
+		api.SetETag(c, pos.Version)
 		resource := toResource(*pos)
 		return api.WriteSingle(c, http.StatusCreated, resource, "write position response")
 	}
@@ -117,6 +129,7 @@ type updateRequest struct {
 
 // UpdateHandler updates a position.
 // PUT /api/v1/floor-plan-positions/:id
+// An If-Match header must carry the position's current ETag, or 412 is returned.
 func UpdateHandler(db *sql.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		id := c.Param("id")
@@ -134,14 +147,19 @@ func UpdateHandler(db *sql.DB) echo.HandlerFunc {
 			Width:       a.Width,
 			Height:      a.Height,
 			BorderWidth: a.BorderWidth,
+			IfVersion:   api.IfMatch(c),
 		})
 		if err != nil {
 			if errors.Is(err, ErrNotFound) {
 				return api.WriteNotFound(c, "Position not found")
 			}
+			if errors.Is(err, ErrVersionConflict) {
+				return api.WritePreconditionFailed(c)
+			}
 			return api.WriteInternalError(c, "update position", err)
 		}
 
+		api.SetETag(c, pos.Version)
 		resource := toResource(*pos)
 		return api.WriteSingle(c, http.StatusOK, resource, "write position response")
 	}
@@ -149,14 +167,18 @@ func UpdateHandler(db *sql.DB) echo.HandlerFunc {
 
 // DeleteHandler removes a position.
 // DELETE /api/v1/floor-plan-positions/:id
+// An If-Match header must carry the position's current ETag, or 412 is returned.
 func DeleteHandler(db *sql.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		id := c.Param("id")
 
-		if err := Delete(c.Request().Context(), db, id); err != nil {
+		if err := Delete(c.Request().Context(), db, id, api.IfMatch(c)); err != nil {
 			if errors.Is(err, ErrNotFound) {
 				return api.WriteNotFound(c, "Position not found")
 			}
+			if errors.Is(err, ErrVersionConflict) {
+				return api.WritePreconditionFailed(c)
+			}
 			return api.WriteInternalError(c, "delete position", err)
 		}
 
@@ -99,3 +99,34 @@ func TestDeleteHandlerDeletesExistingPosition(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, positions)
 }
+
+func TestUpdateAndDeleteHandlerCheckIfMatch(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	pos, err := Create(t.Context(), store, &CreateInput{FloorPlan: "office.svg", ItemID: "desk-1"})
+	require.NoError(t, err)
+
+	send := func(handler echo.HandlerFunc, method, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/floor-plan-positions/"+pos.ID, bytes.NewBufferString(body))
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(pos.ID)
+		require.NoError(t, handler(c))
+		return rec
+	}
+	body := `{"data":{"type":"floor-plan-positions","attributes":{"x":25}}}`
+
+	rec := send(UpdateHandler(store), http.MethodPut, body, `"1"`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	rec = send(UpdateHandler(store), http.MethodPut, body, `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	rec = send(DeleteHandler(store), http.MethodDelete, "", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	rec = send(DeleteHandler(store), http.MethodDelete, "", `"2"`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -12,8 +12,13 @@ import (
 	"github.com/google/uuid"
 )
 
-// ErrNotFound indicates the requested position does not exist.
-var ErrNotFound = errors.New("position not found")
+// Sentinel errors for position operations.
+var (
+	// ErrNotFound indicates the requested position does not exist.
+	ErrNotFound = errors.New("position not found")
+	// ErrVersionConflict indicates the position changed since the caller read it.
+	ErrVersionConflict = errors.New("position version conflict")
+)
 
 // Position represents an item's rectangle on a floor plan.
 type Position struct {
@@ -28,6 +33,7 @@ type Position struct {
 	BorderWidth int
 	CreatedAt   string
 	UpdatedAt   string
+	Version     int
 }
 
 // CreateInput holds fields for creating a position.
@@ -50,6 +56,8 @@ type UpdateInput struct {
 	Width       *float64
 	Height      *float64
 	BorderWidth *int
+	// IfVersion, when non-zero, only applies the update if the position still has this version.
+	IfVersion int
 }
 
 // Create inserts a new floor plan position.
@@ -78,15 +86,15 @@ func Create(ctx context.Context, db *sql.DB, input *CreateInput) (*Position, err
 		ID: id, FloorPlan: input.FloorPlan, ItemID: input.ItemID,
 		Label: input.Label, X: input.X, Y: input.Y,
 		Width: input.Width, Height: input.Height, BorderWidth: bw,
-		CreatedAt: now, UpdatedAt: now,
+		CreatedAt: now, UpdatedAt: now, Version: 1,
 	}, nil
 }
 
 // FindByFloorPlan returns all positions for a given floor plan filename.
 func FindByFloorPlan(ctx context.Context, db *sql.DB, floorPlan string) ([]Position, error) {
 	rows, err := db.QueryContext(ctx,
-		`SELECT id, floor_plan, item_id, label, x, y, width, height, border_width, created_at, updated_at
-		FROM floor_plan_positions WHERE floor_plan = ? ORDER BY item_id`,
+		`SELECT id, floor_plan, item_id, label, x, y, width, height, border_width, created_at, updated_at,
+		version FROM floor_plan_positions WHERE floor_plan = ? ORDER BY item_id`,
 		floorPlan,
 	)
 	if err != nil {
@@ -101,7 +109,7 @@ func FindByFloorPlan(ctx context.Context, db *sql.DB, floorPlan string) ([]Posit
 		var p Position
 		if err := rows.Scan(&p.ID, &p.FloorPlan, &p.ItemID, &p.Label,
 			&p.X, &p.Y, &p.Width, &p.Height, &p.BorderWidth,
-			&p.CreatedAt, &p.UpdatedAt); err != nil {
+			&p.CreatedAt, &p.UpdatedAt, &p.Version); err != nil {
 			return nil, fmt.Errorf("scan position: %w", err)
 		}
 		result = append(result, p)
@@ -112,11 +120,11 @@ func FindByFloorPlan(ctx context.Context, db *sql.DB, floorPlan string) ([]Posit
 	return result, nil
 }
 
-// Update modifies an existing position.
+// Update modifies an existing position and bumps its version.
 func Update(ctx context.Context, db *sql.DB, id string, input UpdateInput) (*Position, error) {
 	now := time.Now().UTC().Format(time.RFC3339)
 
-	setClauses := []string{"updated_at = ?"}
+	setClauses := []string{"updated_at = ?", "version = version + 1"}
 	args := []interface{}{now}
 
 	if input.Label != nil {
@@ -144,54 +152,61 @@ func Update(ctx context.Context, db *sql.DB, id string, input UpdateInput) (*Pos
 		args = append(args, *input.BorderWidth)
 	}
 
-	args = append(args, id)
+	args = append(args, id, input.IfVersion, input.IfVersion)
 
 	// setClauses contains only hardcoded column names, not user input.
 	query := "UPDATE floor_plan_positions SET " + //nolint:gosec // G202 false positive
-		strings.Join(setClauses, ", ") + " WHERE id = ?"
+		strings.Join(setClauses, ", ") + " WHERE id = ? AND (? = 0 OR version = ?)"
 
 	result, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("update position: %w", err)
 	}
-
-	n, errRows := result.RowsAffected()
-	if errRows != nil {
-		n = 0
-	}
-	if n == 0 {
-		return nil, ErrNotFound
+	if err := checkVersionedWrite(ctx, db, result, id, input.IfVersion); err != nil {
+		return nil, err
 	}
 
 	return FindByID(ctx, db, id)
 }
 
-// Delete removes a position by ID.
-func Delete(ctx context.Context, db *sql.DB, id string) error {
+// Delete removes a position by ID. When ifVersion is non-zero the position is
+// only removed if it still has that version.
+func Delete(ctx context.Context, db *sql.DB, id string, ifVersion int) error {
 	result, err := db.ExecContext(ctx,
-		`DELETE FROM floor_plan_positions WHERE id = ?`, id)
+		`DELETE FROM floor_plan_positions WHERE id = ? AND (? = 0 OR version = ?)`, id, ifVersion, ifVersion)
 	if err != nil {
 		return fmt.Errorf("delete position: %w", err)
 	}
+	return checkVersionedWrite(ctx, db, result, id, ifVersion)
+}
+
+// checkVersionedWrite maps a versioned write that touched no row to
+// ErrNotFound or, if the position still exists, to ErrVersionConflict.
+func checkVersionedWrite(ctx context.Context, db *sql.DB, result sql.Result, id string, ifVersion int) error {
 	n, errRows := result.RowsAffected()
 	if errRows != nil {
 		n = 0
 	}
-	if n == 0 {
-		return ErrNotFound
+	if n > 0 {
+		return nil
+	}
+	if ifVersion != 0 {
+		if _, err := FindByID(ctx, db, id); err == nil {
+			return ErrVersionConflict
+		}
 	}
-	return nil
+	return ErrNotFound
 }
 
 // FindByID returns a single position by ID.
 func FindByID(ctx context.Context, db *sql.DB, id string) (*Position, error) {
 	var p Position
 	err := db.QueryRowContext(ctx,
-		`SELECT id, floor_plan, item_id, label, x, y, width, height, border_width, created_at, updated_at
-		FROM floor_plan_positions WHERE id = ?`, id,
+		`SELECT id, floor_plan, item_id, label, x, y, width, height, border_width, created_at, updated_at,
+		version FROM floor_plan_positions WHERE id = ?`, id,
 	).Scan(&p.ID, &p.FloorPlan, &p.ItemID, &p.Label,
 		&p.X, &p.Y, &p.Width, &p.Height, &p.BorderWidth,
-		&p.CreatedAt, &p.UpdatedAt)
+		&p.CreatedAt, &p.UpdatedAt, &p.Version)
 	if err != nil {
 		return nil, fmt.Errorf("find position: %w", err)
 	}
@@ -86,7 +86,7 @@ func TestDelete(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	require.NoError(t, Delete(ctx, store, pos.ID))
+	require.NoError(t, Delete(ctx, store, pos.ID, 0))
 
 	positions, err := FindByFloorPlan(ctx, store, "office.svg")
 	require.NoError(t, err)
@@ -98,7 +98,7 @@ func TestDeleteNotFound(t *testing.T) {
 	store := setupTestDB(t)
 	ctx := context.Background()
 
-	err := Delete(ctx, store, "nonexistent")
+	err := Delete(ctx, store, "nonexistent", 0)
 	require.ErrorIs(t, err, ErrNotFound)
 }
 
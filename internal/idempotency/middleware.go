@@ -0,0 +1,143 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+)
+
+const (
+	// HeaderKey is the request header carrying the client's idempotency key.
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed marks responses replayed from a stored earlier response.
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Middleware makes requests sent with an Idempotency-Key header safe to retry.
+// The first request with a key is processed and its response stored for the
+// retention window; retries with the same key and payload get the stored
+// response instead of being processed again. Server errors are not stored.
+// Requests without the header, and all requests when retention is 0, pass through.
+// The middleware must run after authentication, as keys are scoped per user.
+func Middleware(db *sql.DB, retention time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := strings.TrimSpace(c.Request().Header.Get(HeaderKey))
+			user := auth.GetUserFromContext(c)
+			if key == "" || retention <= 0 || user == nil {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				detail := fmt.Sprintf("%s must be at most %d characters", HeaderKey, maxKeyLength)
+				return api.WriteBadRequest(c, detail)
+			}
+
+			req, err := newRequest(c, user.ID, key)
+			if err != nil {
+				return err
+			}
+			ctx := c.Request().Context()
+			stored, err := Reserve(ctx, db, req, time.Now().Add(-retention))
+			switch {
+			case errors.Is(err, ErrKeyReused):
+				return api.WriteError(c, http.StatusUnprocessableEntity,
+					"The Idempotency-Key was already used for a different request", "idempotency_key_reused")
+			case errors.Is(err, ErrInProgress):
+				return api.WriteError(c, http.StatusConflict,
+					"A request with this Idempotency-Key is still being processed", "idempotency_key_in_progress")
+			case err != nil:
+				return fmt.Errorf("reserve idempotency key: %w", err)
+			case stored != nil:
+				return replay(c, stored)
+			}
+
+			return process(c, next, db, req)
+		}
+	}
+}
+
+// newRequest reads the request body for hashing and restores it for the handler.
+func newRequest(c echo.Context, userID, key string) (*Request, error) {
+	httpReq := c.Request()
+	body, err := io.ReadAll(httpReq.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	httpReq.Body = io.NopCloser(bytes.NewReader(body))
+
+	sum := sha256.Sum256(append([]byte(httpReq.Method+"\n"+httpReq.URL.Path+"\n"), body...))
+	return &Request{
+		UserID: userID,
+		Key:    key,
+		Method: httpReq.Method,
+		Path:   httpReq.URL.Path,
+		Hash:   hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// process runs the handler and stores its response, or releases the key if
+// the handler failed so that the client can retry.
+func process(c echo.Context, next echo.HandlerFunc, db *sql.DB, req *Request) error {
+	res := c.Response()
+	capture := &captureWriter{ResponseWriter: res.Writer}
+	res.Writer = capture
+	err := next(c)
+	res.Writer = capture.ResponseWriter
+
+	// The request context may be canceled once the handler is done; the
+	// bookkeeping must still happen.
+	ctx := context.WithoutCancel(c.Request().Context())
+	if err != nil || !res.Committed || res.Status >= http.StatusInternalServerError {
+		if releaseErr := Release(ctx, db, req); releaseErr != nil {
+			slog.Error("release idempotency key", "error", releaseErr)
+		}
+		return err
+	}
+	stored := &Response{
+		Status:      res.Status,
+		ContentType: res.Header().Get(echo.HeaderContentType),
+		ETag:        res.Header().Get(api.HeaderETag),
+		Body:        capture.body.Bytes(),
+	}
+	if err := Complete(ctx, db, req, stored); err != nil {
+		slog.Error("store idempotent response", "error", err)
+	}
+	return nil
+}
+
+func replay(c echo.Context, stored *Response) error {
+	c.Response().Header().Set(HeaderReplayed, "true")
+	if stored.ETag != "" {
+		c.Response().Header().Set(api.HeaderETag, stored.ETag)
+	}
+	//nolint:wrapcheck // Terminal response
+	return c.Blob(stored.Status, stored.ContentType, stored.Body)
+}
+
+// captureWriter copies the response body while it is written to the client.
+type captureWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	_, _ = w.body.Write(b) //nolint:errcheck // bytes.Buffer writes never fail
+	//nolint:wrapcheck // Pass-through writer
+	return w.ResponseWriter.Write(b)
+}
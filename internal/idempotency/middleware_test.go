@@ -0,0 +1,145 @@
+package idempotency
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/db"
+)
+
+func setupTestStore(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+	return store
+}
+
+// countingServer echoes the request body with the given status and an ETag,
+// and counts calls.
+func countingServer(store *sql.DB, retention time.Duration, status int, calls *int) *echo.Echo {
+	e := echo.New()
+	setUser := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user", &auth.User{ID: c.Request().Header.Get("X-User")})
+			return next(c)
+		}
+	}
+	e.POST("/api/v1/bookings", func(c echo.Context) error {
+		*calls++
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set("ETag", `"1"`)
+		return c.Blob(status, "application/vnd.api+json", body)
+	}, setUser, Middleware(store, retention))
+	return e
+}
+
+func post(e *echo.Echo, user, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bookings", strings.NewReader(body))
+	req.Header.Set("X-User", user)
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddlewareReplaysStoredResponse(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	e := countingServer(setupTestStore(t), time.Hour, http.StatusCreated, &calls)
+
+	first := post(e, "user-1", "key-1", `{"n":1}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(HeaderReplayed))
+
+	retry := post(e, "user-1", "key-1", `{"n":1}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(HeaderReplayed))
+	assert.Equal(t, "application/vnd.api+json", retry.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, `"1"`, retry.Header().Get("ETag"))
+	assert.JSONEq(t, `{"n":1}`, retry.Body.String())
+	assert.Equal(t, 1, calls, "retry must not reach the handler")
+
+	// Keys are scoped per user, and requests without a key always pass through.
+	assert.Equal(t, http.StatusCreated, post(e, "user-2", "key-1", `{"n":1}`).Code)
+	assert.Equal(t, http.StatusCreated, post(e, "user-1", "", `{"n":1}`).Code)
+	assert.Equal(t, 3, calls)
+}
+
+func TestMiddlewareRejectsReusedKey(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	e := countingServer(setupTestStore(t), time.Hour, http.StatusCreated, &calls)
+
+	require.Equal(t, http.StatusCreated, post(e, "user-1", "key-1", `{"n":1}`).Code)
+	rec := post(e, "user-1", "key-1", `{"n":2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "idempotency_key_reused")
+
+	rec = post(e, "user-1", strings.Repeat("k", maxKeyLength+1), `{"n":1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMiddlewareDoesNotStoreServerErrors(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	e := countingServer(setupTestStore(t), time.Hour, http.StatusServiceUnavailable, &calls)
+
+	post(e, "user-1", "key-1", `{"n":1}`)
+	rec := post(e, "user-1", "key-1", `{"n":1}`)
+	assert.Empty(t, rec.Header().Get(HeaderReplayed))
+	assert.Equal(t, 2, calls, "failed requests are processed again")
+}
+
+func TestMiddlewareDisabledWithoutRetention(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	e := countingServer(setupTestStore(t), 0, http.StatusCreated, &calls)
+
+	post(e, "user-1", "key-1", `{"n":1}`)
+	post(e, "user-1", "key-1", `{"n":1}`)
+	assert.Equal(t, 2, calls)
+}
+
+func TestReserveReportsInProgressAndExpires(t *testing.T) {
+	t.Parallel()
+	store := setupTestStore(t)
+	ctx := t.Context()
+	req := &Request{UserID: "user-1", Key: "key-1", Method: http.MethodPost, Path: "/x", Hash: "h"}
+
+	stored, err := Reserve(ctx, store, req, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	_, err = Reserve(ctx, store, req, time.Now().Add(-time.Hour))
+	require.ErrorIs(t, err, ErrInProgress)
+
+	// Once the key is older than the retention window it is claimed anew.
+	stored, err = Reserve(ctx, store, req, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	require.NoError(t, Complete(ctx, store, req, &Response{Status: http.StatusCreated, Body: []byte("ok")}))
+	n, err := Purge(ctx, store, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
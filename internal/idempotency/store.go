@@ -0,0 +1,151 @@
+// Package idempotency stores responses of requests sent with an Idempotency-Key
+// header and replays them when a client retries the same request.
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Sentinel errors returned by Reserve.
+var (
+	// ErrInProgress indicates the first request with the key has not finished yet.
+	ErrInProgress = errors.New("idempotent request in progress")
+	// ErrKeyReused indicates the key was already used for a different request.
+	ErrKeyReused = errors.New("idempotency key reused for a different request")
+)
+
+// Response is a stored response that is replayed for retried requests.
+type Response struct {
+	Status      int
+	ContentType string
+	// ETag is the ETag header of the response, if any.
+	ETag string
+	Body []byte
+}
+
+// Request identifies a request sent with an Idempotency-Key header.
+type Request struct {
+	UserID string
+	Key    string
+	Method string
+	Path   string
+	Hash   string
+}
+
+// Reserve claims the key for a new request. It returns nil if the caller should
+// process the request, or the stored response if the same request already
+// completed after the since time. Keys stored before since have expired and are
+// claimed anew.
+func Reserve(ctx context.Context, db *sql.DB, req *Request, since time.Time) (*Response, error) {
+	cutoff := since.UTC().Format(time.RFC3339)
+	if _, err := db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND created_at < ?`,
+		req.UserID, req.Key, cutoff,
+	); err != nil {
+		return nil, fmt.Errorf("expire idempotency key: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	result, err := db.ExecContext(ctx,
+		`INSERT INTO idempotency_keys (user_id, idempotency_key, method, path, request_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (user_id, idempotency_key) DO NOTHING`,
+		req.UserID, req.Key, req.Method, req.Path, req.Hash, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("reserve idempotency key rows: %w", err)
+	}
+	if n == 1 {
+		return nil, nil
+	}
+
+	var hash string
+	var resp Response
+	err = db.QueryRowContext(ctx,
+		`SELECT request_hash, status, content_type, etag, body FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?`,
+		req.UserID, req.Key,
+	).Scan(&hash, &resp.Status, &resp.ContentType, &resp.ETag, &resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("find idempotency key: %w", err)
+	}
+	switch {
+	case hash != req.Hash:
+		return nil, ErrKeyReused
+	case resp.Status == 0:
+		return nil, ErrInProgress
+	}
+	return &resp, nil
+}
+
+// Complete stores the response of a reserved request for later replays.
+func Complete(ctx context.Context, db *sql.DB, req *Request, resp *Response) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status = ?, content_type = ?, etag = ?, body = ?
+		WHERE user_id = ? AND idempotency_key = ?`,
+		resp.Status, resp.ContentType, resp.ETag, resp.Body, req.UserID, req.Key,
+	)
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release drops the reservation of a request that failed, so that a retry with
+// the same key is processed again.
+func Release(ctx context.Context, db *sql.DB, req *Request) error {
+	_, err := db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND status = 0`,
+		req.UserID, req.Key,
+	)
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Purge removes keys stored before the given time and returns their number.
+func Purge(ctx context.Context, db *sql.DB, before time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE created_at < ?`, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("count purged idempotency keys: %w", err)
+	}
+	return n, nil
+}
+
+// RunPurge purges keys older than retention every interval until ctx is done.
+// A retention of 0 disables idempotency keys, so there is nothing to purge.
+func RunPurge(ctx context.Context, db *sql.DB, retention, interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := Purge(ctx, db, time.Now().Add(-retention))
+		switch {
+		case err != nil:
+			slog.Error("purge idempotency keys", "error", err)
+		case n > 0:
+			slog.Info("idempotency keys purged", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
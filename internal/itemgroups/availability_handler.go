@@ -4,8 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -20,6 +18,9 @@ type DayAvailability struct {
 	Weekday   string `json:"weekday"`
 	Total     int    `json:"total"`
 	Available int    `json:"available"`
+	// Closed is set when the area is closed on this day; Available is then 0.
+	Closed        bool   `json:"closed,omitempty"`
+	ClosureReason string `json:"closure_reason,omitempty"`
 }
 
 // ItemGroupAvailabilityAttributes holds per-item-group weekly availability.
@@ -45,7 +46,7 @@ func AvailabilityHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) ech
 		}
 
 		weekParam := c.QueryParam("week")
-		monday, err := parseISOWeek(weekParam)
+		monday, err := api.ParseISOWeek(weekParam)
 		if err != nil {
 			return api.WriteBadRequest(c, "Invalid week parameter. Use ISO 8601 format: YYYY-Www (e.g., 2026-W12).")
 		}
@@ -57,7 +58,12 @@ func AvailabilityHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) ech
 		weekdays := weekdayDates(monday, dayCount)
 		ctx := c.Request().Context()
 
-		resources, err := buildAvailabilityResources(ctx, store, area, weekdays)
+		blocks, err := loadDayBlocks(ctx, store, cfg, area, formatDates(weekdays))
+		if err != nil {
+			return err
+		}
+
+		resources, err := buildAvailabilityResources(ctx, store, area, weekdays, blocks)
 		if err != nil {
 			return fmt.Errorf("build availability: %w", err)
 		}
@@ -66,56 +72,6 @@ func AvailabilityHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) ech
 	}
 }
 
-// parseISOWeek parses an ISO 8601 week string (e.g., "2026-W12") and returns
-// the Monday of that week. If the input is empty, returns the Monday of the
-// current week.
-func parseISOWeek(s string) (time.Time, error) {
-	if strings.TrimSpace(s) == "" {
-		now := time.Now()
-		return mondayOfWeek(now), nil
-	}
-
-	// Expected format: YYYY-Www (e.g., 2026-W12 or 2026-W03)
-	parts := strings.SplitN(s, "-W", 2)
-	if len(parts) != 2 {
-		return time.Time{}, fmt.Errorf("invalid ISO week format: %s", s)
-	}
-
-	year, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid year in ISO week: %s", s)
-	}
-
-	week, err := strconv.Atoi(parts[1])
-	if err != nil || week < 1 || week > 53 {
-		return time.Time{}, fmt.Errorf("invalid week number in ISO week: %s", s)
-	}
-	maxWeek := isoWeeksInYear(year)
-	if week > maxWeek {
-		return time.Time{}, fmt.Errorf("invalid week number in ISO week: %s", s)
-	}
-
-	// ISO 8601: Week 1 contains January 4th.
-	// Find January 4th, then back up to Monday of that week, then add (week-1) weeks.
-	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
-	jan4Monday := mondayOfWeek(jan4)
-	monday := jan4Monday.AddDate(0, 0, (week-1)*7)
-
-	return monday, nil
-}
-
-// mondayOfWeek returns the Monday of the ISO week containing t.
-func mondayOfWeek(t time.Time) time.Time {
-	year, month, day := t.Date()
-	base := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
-	weekday := base.Weekday()
-	if weekday == time.Sunday {
-		weekday = 7
-	}
-	offset := int(weekday) - int(time.Monday)
-	return base.AddDate(0, 0, -offset)
-}
-
 // weekdayDates returns dates for a week starting at monday.
 // count is typically 5 (Mon-Fri) or 7 (Mon-Sun).
 func weekdayDates(monday time.Time, count int) []time.Time {
@@ -127,6 +83,15 @@ func weekdayDates(monday time.Time, count int) []time.Time {
 }
 
 // weekdayAbbreviation returns a two-letter weekday abbreviation.
+// formatDates formats dates as YYYY-MM-DD strings.
+func formatDates(days []time.Time) []string {
+	result := make([]string, len(days))
+	for i, d := range days {
+		result[i] = d.Format(time.DateOnly)
+	}
+	return result
+}
+
 func weekdayAbbreviation(d time.Weekday) string {
 	switch d {
 	case time.Monday:
@@ -148,16 +113,8 @@ func weekdayAbbreviation(d time.Weekday) string {
 	}
 }
 
-// isoWeeksInYear returns the number of ISO weeks in the given year (52 or 53).
-func isoWeeksInYear(year int) int {
-	// ISO 8601: week with Jan 4 is always week 1; Dec 28 always lies in the last ISO week.
-	dec28 := time.Date(year, time.December, 28, 0, 0, 0, 0, time.UTC)
-	_, week := dec28.ISOWeek()
-	return week
-}
-
 func buildAvailabilityResources(
-	ctx context.Context, store *sql.DB, area *areas.Area, weekdays []time.Time,
+	ctx context.Context, store *sql.DB, area *areas.Area, weekdays []time.Time, blocks *dayBlocks,
 ) ([]api.Resource, error) {
 	// Collect all item IDs for this area to query bookings.
 	var totalItems int
@@ -194,19 +151,23 @@ func buildAvailabilityResources(
 			dateStr := day.Format(time.DateOnly)
 			bookedCount := 0
 			for _, itemID := range igItemIDs {
-				if bookingCounts[itemID+"|"+dateStr] > 0 {
+				_, down := blocks.maintenance(itemID, dateStr)
+				if bookingCounts[itemID+"|"+dateStr] > 0 || down {
 					bookedCount++
 				}
 			}
 			available := totalItems - bookedCount
-			if available < 0 {
+			reason, isClosed := blocks.closure(dateStr)
+			if available < 0 || isClosed {
 				available = 0
 			}
 			days[i] = DayAvailability{
-				Date:      dateStr,
-				Weekday:   weekdayAbbreviation(day.Weekday()),
-				Total:     totalItems,
-				Available: available,
+				Date:          dateStr,
+				Weekday:       weekdayAbbreviation(day.Weekday()),
+				Total:         totalItems,
+				Available:     available,
+				Closed:        isClosed,
+				ClosureReason: reason,
 			}
 		}
 
@@ -1,6 +1,7 @@
 package itemgroups
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -12,49 +13,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
 )
 
-func TestParseISOWeek(t *testing.T) {
-	t.Parallel()
-
-	tests := []struct {
-		name     string
-		input    string
-		wantDate string
-		wantErr  bool
-	}{
-		{"valid week 1 2026", "2026-W01", "2025-12-29", false},
-		{"valid week 12 2026", "2026-W12", "2026-03-16", false},
-		{"valid week with leading zero", "2026-W03", "2026-01-12", false},
-		{"valid week 53 2026", "2026-W53", "2026-12-28", false},
-		{"empty defaults to current week", "", "", false},
-		{"invalid format", "2026-12", "", true},
-		{"invalid week 53 for non-53 year", "2025-W53", "", true},
-		{"invalid week number", "2026-W54", "", true},
-		{"invalid week zero", "2026-W00", "", true},
-		{"invalid year", "abc-W12", "", true},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-			result, err := parseISOWeek(tc.input)
-			if tc.wantErr {
-				require.Error(t, err)
-				return
-			}
-			require.NoError(t, err)
-			if tc.input == "" {
-				// Just verify it returns a Monday
-				assert.Equal(t, time.Monday, result.Weekday())
-			} else {
-				assert.Equal(t, tc.wantDate, result.Format(time.DateOnly))
-				assert.Equal(t, time.Monday, result.Weekday())
-			}
-		})
-	}
-}
-
 func TestWeekdayDates(t *testing.T) {
 	t.Parallel()
 
@@ -316,3 +277,80 @@ func TestAvailabilityHandlerDefaultsToCurrentWeek(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, "MO", firstDay["weekday"])
 }
+
+func TestAvailabilityHandlerFlagsClosedDays(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	cfg := testConfig()
+	cfg.Closures = []areas.Closure{{From: "2026-01-21", Reason: "Public holiday"}}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/areas/area-1/item-groups/availability?week=2026-W04", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("area_id")
+	c.SetParamValues("area-1")
+
+	h := AvailabilityHandler(cfg, store)
+	require.NoError(t, h(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs, ok := resp.Data[0].Attributes.(map[string]any)
+	require.True(t, ok)
+	days, ok := attrs["days"].([]any)
+	require.True(t, ok)
+
+	wed, ok := days[2].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, true, wed["closed"])
+	assert.Equal(t, "Public holiday", wed["closure_reason"])
+	assert.Equal(t, float64(0), wed["available"])
+
+	tue, ok := days[1].(map[string]any)
+	require.True(t, ok)
+	assert.Nil(t, tue["closed"])
+	assert.Equal(t, float64(2), tue["available"])
+}
+
+func TestAvailabilityHandlerExcludesItemsUnderMaintenance(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	cfg := testConfig()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.ExecContext(context.Background(),
+		`INSERT INTO item_maintenance (id, item_id, start_date, end_date, reason, created_at, updated_at)
+		 VALUES ('m-1', 'item-1', '2026-01-19', '2026-01-19', '', ?, ?)`, now, now)
+	require.NoError(t, err)
+	seedBooking(t, store, "b1", "item-2", "user-1", "2026-01-19")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/areas/area-1/item-groups/availability?week=2026-W04", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("area_id")
+	c.SetParamValues("area-1")
+
+	h := AvailabilityHandler(cfg, store)
+	require.NoError(t, h(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs, ok := resp.Data[0].Attributes.(map[string]any)
+	require.True(t, ok)
+	days, ok := attrs["days"].([]any)
+	require.True(t, ok)
+
+	mon, ok := days[0].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, float64(0), mon["available"])
+	tue, ok := days[1].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, float64(2), tue["available"])
+}
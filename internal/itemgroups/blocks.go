@@ -0,0 +1,46 @@
+package itemgroups
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/closures"
+	"github.com/thorstenkramm/sithub/internal/maintenance"
+)
+
+// dayBlocks holds the reasons why items cannot be booked on the visible days.
+type dayBlocks struct {
+	// closed maps date -> closure reason for the whole area.
+	closed map[string]string
+	// unavailable maps item ID -> date -> maintenance reason.
+	unavailable map[string]map[string]string
+}
+
+// loadDayBlocks collects closures and maintenance windows for an area.
+func loadDayBlocks(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, area *areas.Area, dates []string,
+) (*dayBlocks, error) {
+	closed, err := closures.ClosedDays(ctx, store, cfg, area.ID, dates)
+	if err != nil {
+		return nil, fmt.Errorf("find closed days: %w", err)
+	}
+	unavailable, err := maintenance.UnavailableDays(ctx, store, collectAreaItemIDs(area), dates)
+	if err != nil {
+		return nil, fmt.Errorf("find unavailable items: %w", err)
+	}
+	return &dayBlocks{closed: closed, unavailable: unavailable}, nil
+}
+
+// closure returns the closure reason for date, if the area is closed.
+func (b *dayBlocks) closure(date string) (string, bool) {
+	reason, ok := b.closed[date]
+	return reason, ok
+}
+
+// maintenance returns the maintenance reason for an item on date, if any.
+func (b *dayBlocks) maintenance(itemID, date string) (string, bool) {
+	reason, ok := b.unavailable[itemID][date]
+	return reason, ok
+}
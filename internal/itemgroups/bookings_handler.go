@@ -9,6 +9,9 @@ import (
 
 	"github.com/thorstenkramm/sithub/internal/api"
 	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/delegations"
 	"github.com/thorstenkramm/sithub/internal/users"
 )
 
@@ -21,6 +24,11 @@ type ItemGroupBookingAttributes struct {
 	BookingDate string `json:"booking_date"`
 	IsGuest     bool   `json:"is_guest,omitempty"`
 	Note        string `json:"note"`
+	// Fields holds the custom field values the requesting user may see.
+	Fields map[string]any `json:"fields,omitempty"`
+	// Private marks a private booking. Users who may not see the booker get
+	// no user, note, or custom field values.
+	Private bool `json:"private,omitempty"`
 }
 
 // BookingsHandler returns a JSON:API list of bookings for an item group on a given date.
@@ -37,12 +45,13 @@ func BookingsHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.Ha
 			return api.WriteNotFound(c, "Item group not found")
 		}
 
-		params, err := api.ParseItemGroupRequest(ig.ID, c.QueryParam("date"))
+		params, err := api.ParseItemGroupRequest(ig.ID, c.QueryParam("date"), cfg.ItemGroupZone(ig.ID))
 		if err != nil {
 			return api.WriteBadRequest(c, "Invalid booking date. Use YYYY-MM-DD.")
 		}
 
-		igBookings, err := findItemGroupBookings(c.Request().Context(), store, ig, params.BookingDate)
+		igBookings, err := findItemGroupBookings(
+			c.Request().Context(), store, cfg, auth.GetUserFromContext(c), ig, params.BookingDate)
 		if err != nil {
 			return fmt.Errorf("find item group bookings: %w", err)
 		}
@@ -62,16 +71,23 @@ func validateItemGroupParam(cfg *areas.Config, itemGroupID string) (*areas.ItemG
 
 // itemGroupBookingRecord represents a booking row joined with item info.
 type itemGroupBookingRecord struct {
-	ID          string
-	ItemID      string
-	UserID      string
-	BookingDate string
-	IsGuest     bool
-	Note        string
+	ID             string
+	ItemID         string
+	UserID         string
+	BookedByUserID string
+	BookingDate    string
+	IsGuest        bool
+	Note           string
+	Fields         map[string]any
+	IsPrivate      bool
 }
 
+// findItemGroupBookings returns the bookings of the item group on bookingDate
+// with the custom field values viewer may see. Private bookings viewer may not
+// see are returned without user, note, and custom field values.
 func findItemGroupBookings(
-	ctx context.Context, store *sql.DB, ig *areas.ItemGroup, bookingDate string,
+	ctx context.Context, store *sql.DB, cfg *areas.Config, viewer *auth.User,
+	ig *areas.ItemGroup, bookingDate string,
 ) ([]api.Resource, error) {
 	// Build list of item IDs in this item group
 	itemIDs := make([]string, 0, len(ig.Items))
@@ -90,7 +106,7 @@ func findItemGroupBookings(
 
 	//nolint:gosec // G201: placeholders are "?" literals from BuildINClause, not user input
 	query := fmt.Sprintf(
-		`SELECT id, item_id, user_id, booking_date, is_guest, note
+		`SELECT id, item_id, user_id, booked_by_user_id, booking_date, is_guest, note, custom_fields, is_private
 		 FROM bookings
 		 WHERE item_id IN (%s) AND booking_date = ?
 		 ORDER BY item_id`,
@@ -113,13 +129,16 @@ func findItemGroupBookings(
 	for rows.Next() {
 		var rec itemGroupBookingRecord
 		var isGuestInt int
+		var fields string
 		err := rows.Scan(
-			&rec.ID, &rec.ItemID, &rec.UserID, &rec.BookingDate, &isGuestInt, &rec.Note,
+			&rec.ID, &rec.ItemID, &rec.UserID, &rec.BookedByUserID, &rec.BookingDate, &isGuestInt, &rec.Note, &fields,
+			&rec.IsPrivate,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan item group booking: %w", err)
 		}
 		rec.IsGuest = isGuestInt == 1
+		rec.Fields = bookings.DecodeFields(fields)
 		records = append(records, rec)
 		userIDs = append(userIDs, rec.UserID)
 	}
@@ -132,22 +151,28 @@ func findItemGroupBookings(
 	if err != nil {
 		return nil, fmt.Errorf("find display names: %w", err)
 	}
+	vis, err := delegations.LoadBookerVisibility(ctx, store, viewer)
+	if err != nil {
+		return nil, err
+	}
 
 	resources := make([]api.Resource, 0, len(records))
 	for _, rec := range records {
-		resources = append(resources, api.Resource{
-			Type: "bookings",
-			ID:   rec.ID,
-			Attributes: ItemGroupBookingAttributes{
-				ItemID:      rec.ItemID,
-				ItemName:    itemNames[rec.ItemID],
-				UserID:      rec.UserID,
-				UserName:    displayNames[rec.UserID],
-				BookingDate: rec.BookingDate,
-				IsGuest:     rec.IsGuest,
-				Note:        rec.Note,
-			},
-		})
+		attrs := ItemGroupBookingAttributes{
+			ItemID:      rec.ItemID,
+			ItemName:    itemNames[rec.ItemID],
+			BookingDate: rec.BookingDate,
+			Private:     rec.IsPrivate,
+		}
+		if vis.Sees(rec.UserID, rec.BookedByUserID, rec.IsPrivate) {
+			attrs.UserID = rec.UserID
+			attrs.UserName = displayNames[rec.UserID]
+			attrs.IsGuest = rec.IsGuest
+			attrs.Note = rec.Note
+			attrs.Fields = cfg.VisibleFieldValues(
+				rec.ItemID, rec.Fields, bookings.FieldAccess(viewer, rec.UserID, rec.BookedByUserID))
+		}
+		resources = append(resources, api.Resource{Type: "bookings", ID: rec.ID, Attributes: attrs})
 	}
 
 	return resources, nil
@@ -39,6 +39,8 @@ func setupTestDB(t *testing.T) *sql.DB {
 			guest_name TEXT NOT NULL DEFAULT '',
 			guest_email TEXT NOT NULL DEFAULT '',
 			note TEXT NOT NULL DEFAULT '',
+			is_private INTEGER NOT NULL DEFAULT 0,
+			custom_fields TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL,
 			UNIQUE(item_id, booking_date)
@@ -57,11 +59,52 @@ func setupTestDB(t *testing.T) *sql.DB {
 			is_admin INTEGER NOT NULL DEFAULT 0,
 			last_login TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1
+		)
+	`)
+	require.NoError(t, err)
+
+	_, err = store.Exec(`
+		CREATE TABLE IF NOT EXISTS closures (
+			id TEXT PRIMARY KEY,
+			area_id TEXT NOT NULL DEFAULT '',
+			start_date TEXT NOT NULL,
+			end_date TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL,
+			created_by_user_id TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		)
 	`)
 	require.NoError(t, err)
 
+	_, err = store.Exec(`
+		CREATE TABLE IF NOT EXISTS item_maintenance (
+			id TEXT PRIMARY KEY,
+			item_id TEXT NOT NULL,
+			start_date TEXT NOT NULL,
+			end_date TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_by_user_id TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err)
+
+	_, err = store.Exec(`
+		CREATE TABLE IF NOT EXISTS delegations (
+			id TEXT PRIMARY KEY,
+			user_id TEXT,
+			delegate_id TEXT NOT NULL,
+			created_by_user_id TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err)
+
 	return store
 }
 
@@ -24,11 +24,7 @@ func ListHandlerDynamic(getConfig areas.ConfigGetter) echo.HandlerFunc {
 		}
 
 		resources := api.MapResources(area.ItemGroups, func(ig areas.ItemGroup) api.Resource {
-			return api.Resource{
-				Type:       "item-groups",
-				ID:         ig.ID,
-				Attributes: areas.BaseAttributes(ig.Name, ig.Description, ig.FloorPlan, ig.Icon),
-			}
+			return areas.ItemGroupResource(&ig, area.ID)
 		})
 
 		return api.WriteCollection(c, resources, "write item groups response")
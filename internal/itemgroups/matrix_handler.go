@@ -12,13 +12,16 @@ import (
 	"github.com/thorstenkramm/sithub/internal/areas"
 	"github.com/thorstenkramm/sithub/internal/auth"
 	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/delegations"
 	"github.com/thorstenkramm/sithub/internal/users"
 )
 
 // MatrixDayMeta describes a single visible day in the matrix header.
 type MatrixDayMeta struct {
-	Date    string `json:"date"`
-	Weekday string `json:"weekday"`
+	Date          string `json:"date"`
+	Weekday       string `json:"weekday"`
+	Closed        bool   `json:"closed,omitempty"`
+	ClosureReason string `json:"closure_reason,omitempty"`
 }
 
 // MatrixCell holds booking state for one item on one day.
@@ -29,6 +32,11 @@ type MatrixCell struct {
 	BookerUserID string `json:"booker_user_id,omitempty"`
 	BookedByMe   bool   `json:"booked_by_me"`
 	BookingID    string `json:"booking_id,omitempty"`
+	// Private marks a private booking; the booker is only shown to admins,
+	// delegates, and the people involved.
+	Private bool `json:"private,omitempty"`
+	// UnavailableReason is set when the item is under maintenance on this day.
+	UnavailableReason string `json:"unavailable_reason,omitempty"`
 }
 
 // MatrixItem holds metadata and cells for a single item row.
@@ -66,7 +74,7 @@ func MatrixHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.Hand
 			return api.WriteNotFound(c, "Area not found")
 		}
 
-		monday, err := parseISOWeek(c.QueryParam("week"))
+		monday, err := api.ParseISOWeek(c.QueryParam("week"))
 		if err != nil {
 			return api.WriteBadRequest(c, "Invalid week parameter. Use ISO 8601 format: YYYY-Www (e.g., 2026-W12).")
 		}
@@ -83,7 +91,17 @@ func MatrixHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.Hand
 
 		currentUserID, userEmail := resolveMatrixUser(ctx, store, user)
 
-		resources, err := buildMatrixResources(ctx, store, area, weekdays, isAdmin, currentUserID, userEmail)
+		blocks, err := loadDayBlocks(ctx, store, cfg, area, formatDates(weekdays))
+		if err != nil {
+			return err
+		}
+
+		vis, err := delegations.LoadBookerVisibility(ctx, store, user)
+		if err != nil {
+			return err
+		}
+		viewer := &matrixViewer{vis: vis, isAdmin: isAdmin, userID: currentUserID, email: userEmail}
+		resources, err := buildMatrixResources(ctx, store, area, weekdays, blocks, viewer)
 		if err != nil {
 			return fmt.Errorf("build matrix: %w", err)
 		}
@@ -104,18 +122,23 @@ func resolveMatrixUser(ctx context.Context, store *sql.DB, user *auth.User) (use
 	return user.ID, rec.Email
 }
 
+// matrixViewer is the user requesting the matrix.
+type matrixViewer struct {
+	vis     *delegations.BookerVisibility
+	isAdmin bool
+	userID  string
+	email   string
+}
+
 func buildMatrixResources(
 	ctx context.Context, store *sql.DB, area *areas.Area, weekdays []time.Time,
-	isAdmin bool, currentUserID, userEmail string,
+	blocks *dayBlocks, viewer *matrixViewer,
 ) ([]api.Resource, error) {
 	// Collect all item IDs for one batch query.
 	allItemIDs := collectAreaItemIDs(area)
 
 	// Build date strings for the visible week.
-	dateStrings := make([]string, len(weekdays))
-	for i, d := range weekdays {
-		dateStrings[i] = d.Format(time.DateOnly)
-	}
+	dateStrings := formatDates(weekdays)
 
 	// One query: fetch all bookings for these items and dates.
 	matrixBookings, err := bookings.FindMatrixBookings(ctx, store, allItemIDs, dateStrings)
@@ -129,9 +152,12 @@ func buildMatrixResources(
 	// Build day metadata (shared across all groups).
 	daysMeta := make([]MatrixDayMeta, len(weekdays))
 	for i, d := range weekdays {
+		reason, isClosed := blocks.closure(dateStrings[i])
 		daysMeta[i] = MatrixDayMeta{
-			Date:    dateStrings[i],
-			Weekday: weekdayAbbreviation(d.Weekday()),
+			Date:          dateStrings[i],
+			Weekday:       weekdayAbbreviation(d.Weekday()),
+			Closed:        isClosed,
+			ClosureReason: reason,
 		}
 	}
 
@@ -139,7 +165,7 @@ func buildMatrixResources(
 	resources := make([]api.Resource, 0, len(area.ItemGroups))
 	for i := range area.ItemGroups {
 		ig := &area.ItemGroups[i]
-		items := buildMatrixItems(ig, area, matrixBookings, dateStrings, isAdmin, currentUserID, userEmail)
+		items := buildMatrixItems(ig, area, matrixBookings, dateStrings, blocks, viewer)
 
 		resources = append(resources, api.Resource{
 			Type: matrixResourceType,
@@ -202,8 +228,8 @@ func resolveMatrixBookerNames(
 
 func buildMatrixItems(
 	ig *areas.ItemGroup, parentArea *areas.Area,
-	mb map[string]bookings.MatrixBookingInfo, dateStrings []string,
-	isAdmin bool, currentUserID, userEmail string,
+	mb map[string]bookings.MatrixBookingInfo, dateStrings []string, blocks *dayBlocks,
+	viewer *matrixViewer,
 ) []MatrixItem {
 	items := make([]MatrixItem, 0, len(ig.Items))
 	for j := range ig.Items {
@@ -211,12 +237,12 @@ func buildMatrixItems(
 
 		// Check reservation at item level.
 		reserved := false
-		if userEmail != "" {
+		if viewer.email != "" {
 			loc := &areas.ItemLocation{Area: parentArea, ItemGroup: ig, Item: item}
-			reserved = areas.IsReserved(loc, userEmail)
+			reserved = areas.IsReserved(loc, viewer.email)
 		}
 
-		cells := buildMatrixCells(item.ID, mb, dateStrings, isAdmin, currentUserID)
+		cells := buildMatrixCells(item.ID, mb, dateStrings, blocks, viewer)
 
 		equip := item.Equipment
 		if equip == nil {
@@ -237,7 +263,7 @@ func buildMatrixItems(
 
 func buildMatrixCells(
 	itemID string, mb map[string]bookings.MatrixBookingInfo,
-	dateStrings []string, isAdmin bool, currentUserID string,
+	dateStrings []string, blocks *dayBlocks, viewer *matrixViewer,
 ) []MatrixCell {
 	cells := make([]MatrixCell, len(dateStrings))
 	for i, dateStr := range dateStrings {
@@ -248,17 +274,26 @@ func buildMatrixCells(
 			Date:         dateStr,
 			Availability: "free",
 		}
+		if _, isClosed := blocks.closure(dateStr); isClosed {
+			cell.Availability = "closed"
+		} else if reason, down := blocks.maintenance(itemID, dateStr); down {
+			cell.Availability = "unavailable"
+			cell.UnavailableReason = reason
+		}
 
 		if occupied {
 			cell.Availability = "occupied"
+			cell.Private = info.IsPrivate
+		}
+		if occupied && viewer.vis.Sees(info.UserID, info.BookedByUserID, info.IsPrivate) {
 			cell.BookerName = info.BookerName
 			if !info.IsGuest {
 				cell.BookerUserID = info.UserID
 			}
-			cell.BookedByMe = currentUserID != "" && info.UserID == currentUserID
+			cell.BookedByMe = viewer.userID != "" && info.UserID == viewer.userID
 
 			// Only expose booking_id to the booking owner or admins.
-			if isAdmin || (currentUserID != "" && info.UserID == currentUserID) {
+			if viewer.isAdmin || (viewer.userID != "" && info.UserID == viewer.userID) {
 				cell.BookingID = info.BookingID
 			}
 		}
@@ -225,6 +225,38 @@ func TestMatrixHandlerOccupiedCellsWithDisplayName(t *testing.T) {
 	assert.Equal(t, false, tueCell["booked_by_me"])
 }
 
+func TestMatrixHandlerHidesPrivateBookers(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	cfg := matrixTestConfig()
+
+	seedUser(t, store, "user-1", "Ada Lovelace")
+	seedBooking(t, store, "b1", "item-1", "user-1", "2026-01-19")
+	_, err := store.Exec(`UPDATE bookings SET is_private = 1 WHERE id = 'b1'`)
+	require.NoError(t, err)
+
+	mondayCell := func(user *auth.User) map[string]any {
+		c, rec := newMatrixRequest(t,
+			"/api/v1/areas/area-1/item-groups/matrix?week=2026-W04", "area-1", user)
+		require.NoError(t, MatrixHandler(cfg, store)(c))
+		var resp api.CollectionResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		items := attrSlice(t, resourceAttrs(t, resp.Data[0]), "items")
+		return cellAt(t, cellsOf(t, itemAt(t, items, 0)), 0)
+	}
+
+	cell := mondayCell(&auth.User{ID: "user-2"})
+	assert.Equal(t, "occupied", cell["availability"])
+	assert.Equal(t, true, cell["private"])
+	assert.Empty(t, cell["booker_name"])
+	assert.Empty(t, cell["booker_user_id"])
+	assert.Empty(t, cell["booking_id"])
+
+	cell = mondayCell(&auth.User{ID: "user-1"})
+	assert.Equal(t, "Ada Lovelace", cell["booker_name"])
+	assert.Equal(t, true, cell["booked_by_me"])
+}
+
 func TestMatrixHandlerGuestBookingShowsGuestName(t *testing.T) {
 	t.Parallel()
 	store := setupTestDB(t)
@@ -285,6 +317,69 @@ func TestMatrixHandlerReservedFreeCell(t *testing.T) {
 	assert.Equal(t, true, itemAt(t, items, 0)["reserved"])
 }
 
+func TestMatrixHandlerFlagsClosedDays(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	cfg := matrixTestConfig()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.ExecContext(context.Background(),
+		`INSERT INTO closures (id, area_id, start_date, end_date, reason, source, created_at, updated_at)
+		 VALUES ('cl-1', 'area-1', '2026-01-20', '2026-01-20', 'Maintenance', 'admin', ?, ?)`, now, now)
+	require.NoError(t, err)
+
+	c, rec := newMatrixRequest(t,
+		"/api/v1/areas/area-1/item-groups/matrix?week=2026-W04", "area-1", nil)
+
+	h := MatrixHandler(cfg, store)
+	require.NoError(t, h(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs := resourceAttrs(t, resp.Data[0])
+
+	days := attrSlice(t, attrs, "days")
+	tue, ok := days[1].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, true, tue["closed"])
+	assert.Equal(t, "Maintenance", tue["closure_reason"])
+
+	cells := cellsOf(t, itemAt(t, attrSlice(t, attrs, "items"), 0))
+	assert.Equal(t, "free", cellAt(t, cells, 0)["availability"])
+	assert.Equal(t, "closed", cellAt(t, cells, 1)["availability"])
+}
+
+func TestMatrixHandlerFlagsItemsUnderMaintenance(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	cfg := matrixTestConfig()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.ExecContext(context.Background(),
+		`INSERT INTO item_maintenance (id, item_id, start_date, end_date, reason, created_at, updated_at)
+		 VALUES ('m-1', 'item-2', '2026-01-20', '2026-01-21', 'Broken monitor', ?, ?)`, now, now)
+	require.NoError(t, err)
+
+	c, rec := newMatrixRequest(t,
+		"/api/v1/areas/area-1/item-groups/matrix?week=2026-W04", "area-1", nil)
+
+	h := MatrixHandler(cfg, store)
+	require.NoError(t, h(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	items := attrSlice(t, resourceAttrs(t, resp.Data[0]), "items")
+
+	cells := cellsOf(t, itemAt(t, items, 1))
+	assert.Equal(t, "free", cellAt(t, cells, 0)["availability"])
+	assert.Equal(t, "unavailable", cellAt(t, cells, 1)["availability"])
+	assert.Equal(t, "Broken monitor", cellAt(t, cells, 1)["unavailable_reason"])
+	assert.Equal(t, "unavailable", cellAt(t, cells, 2)["availability"])
+
+	// Other items are unaffected.
+	assert.Equal(t, "free", cellAt(t, cellsOf(t, itemAt(t, items, 0)), 1)["availability"])
+}
+
 func TestMatrixHandlerBookingIDVisibility(t *testing.T) {
 	t.Parallel()
 	store := setupTestDB(t)
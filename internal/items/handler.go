@@ -12,11 +12,14 @@ import (
 	"github.com/thorstenkramm/sithub/internal/areas"
 	"github.com/thorstenkramm/sithub/internal/auth"
 	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/delegations"
+	"github.com/thorstenkramm/sithub/internal/maintenance"
 	"github.com/thorstenkramm/sithub/internal/users"
 )
 
 // ListHandler returns a JSON:API list of items for an item group.
-// Occupied items include booker_name for all users; booking_id is admin-only.
+// Occupied items include booker_name for all users unless the booking is
+// private; booking_id is admin-only.
 func ListHandler(cfg *areas.Config, store *sql.DB) echo.HandlerFunc {
 	return ListHandlerDynamic(func() *areas.Config { return cfg }, store)
 }
@@ -31,7 +34,7 @@ func ListHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.Handle
 			return api.WriteNotFound(c, "Item group not found")
 		}
 
-		bookingDate, err := api.ParseBookingDate(c.QueryParam("date"))
+		bookingDate, err := api.ParseBookingDate(c.QueryParam("date"), cfg.ItemGroupZone(ig.ID))
 		if err != nil {
 			return api.WriteBadRequest(c, "Invalid booking date. Use YYYY-MM-DD.")
 		}
@@ -44,13 +47,23 @@ func ListHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.Handle
 		if err != nil {
 			return err
 		}
+		vis, err := delegations.LoadBookerVisibility(ctx, store, user)
+		if err != nil {
+			return err
+		}
 
 		resolveBookerNames(ctx, store, itemBookings)
 
+		unavailable, err := loadUnavailableItems(ctx, store, ig, bookingDate)
+		if err != nil {
+			return err
+		}
+
 		currentUserID, userEmail := resolveCurrentUser(ctx, store, user)
 		parentArea := findParentArea(cfg, itemGroupID)
 
-		resources := buildItemResources(ig, parentArea, itemBookings, isAdmin, currentUserID, userEmail)
+		resources := buildItemResources(
+			ig, parentArea, itemBookings, unavailable, vis, isAdmin, currentUserID, userEmail)
 		return api.WriteCollection(c, resources, "write items response")
 	}
 }
@@ -87,6 +100,26 @@ func loadItemBookings(
 	return info, nil
 }
 
+// loadUnavailableItems returns the maintenance reason for every item of the
+// group that is out of service on bookingDate.
+func loadUnavailableItems(
+	ctx context.Context, store *sql.DB, ig *areas.ItemGroup, bookingDate string,
+) (map[string]string, error) {
+	itemIDs := make([]string, len(ig.Items))
+	for i := range ig.Items {
+		itemIDs[i] = ig.Items[i].ID
+	}
+	days, err := maintenance.UnavailableDays(ctx, store, itemIDs, []string{bookingDate})
+	if err != nil {
+		return nil, fmt.Errorf("list unavailable items: %w", err)
+	}
+	result := make(map[string]string, len(days))
+	for itemID, reasons := range days {
+		result[itemID] = reasons[bookingDate]
+	}
+	return result, nil
+}
+
 // resolveBookerNames looks up display names for all user IDs in the bookings map
 // and populates the BookerName field. For guest bookings, uses the stored guest name
 // directly. Lookup errors are silently ignored to avoid breaking the items response
@@ -120,9 +153,21 @@ func resolveBookerNames(
 	}
 }
 
-// applyBookingAttrs populates booking-related attributes on an item.
-func applyBookingAttrs(attrs map[string]any, info *bookings.ItemBookingInfo, isAdmin bool, currentUserID string) {
+// applyBookingAttrs populates booking-related attributes on an item. Private
+// bookings the user may not see only show as occupied.
+func applyBookingAttrs(
+	attrs map[string]any, info *bookings.ItemBookingInfo, vis *delegations.BookerVisibility,
+	isAdmin bool, currentUserID string,
+) {
 	attrs["availability"] = "occupied"
+	if info.IsPrivate {
+		attrs["private"] = true
+	}
+	if !vis.Sees(info.UserID, info.BookedByUserID, info.IsPrivate) {
+		attrs["booker_name"] = ""
+		attrs["booked_by_me"] = false
+		return
+	}
 	attrs["booker_name"] = info.BookerName
 	if !info.IsGuest {
 		attrs["booker_user_id"] = info.UserID
@@ -138,18 +183,26 @@ func applyBookingAttrs(attrs map[string]any, info *bookings.ItemBookingInfo, isA
 
 func buildItemResources(
 	ig *areas.ItemGroup, parentArea *areas.Area,
-	itemBookings map[string]bookings.ItemBookingInfo,
-	isAdmin bool, currentUserID, userEmail string,
+	itemBookings map[string]bookings.ItemBookingInfo, unavailable map[string]string,
+	vis *delegations.BookerVisibility, isAdmin bool, currentUserID, userEmail string,
 ) []api.Resource {
 	return api.MapResources(ig.Items, func(item areas.Item) api.Resource {
 		attrs := areas.ItemAttributes(item.Name, item.Equipment, item.Warning, "", item.Icon)
 		if info, booked := itemBookings[item.ID]; booked {
 			bi := info
-			applyBookingAttrs(attrs, &bi, isAdmin, currentUserID)
+			applyBookingAttrs(attrs, &bi, vis, isAdmin, currentUserID)
 		} else {
 			attrs["availability"] = "available"
 		}
 
+		// Items under maintenance cannot be booked; existing bookings stay visible.
+		if reason, down := unavailable[item.ID]; down {
+			attrs["unavailable_reason"] = reason
+			if attrs["availability"] == "available" {
+				attrs["availability"] = "unavailable"
+			}
+		}
+
 		// Check if item is reserved for other users
 		if userEmail != "" {
 			loc := &areas.ItemLocation{Item: &item, ItemGroup: ig}
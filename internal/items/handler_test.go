@@ -61,6 +61,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 			guest_name TEXT NOT NULL DEFAULT '',
 			guest_email TEXT NOT NULL DEFAULT '',
 			note TEXT NOT NULL DEFAULT '',
+			is_private INTEGER NOT NULL DEFAULT 0,
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL,
 			UNIQUE(item_id, booking_date)
@@ -79,11 +80,37 @@ func setupTestDB(t *testing.T) *sql.DB {
 			is_admin INTEGER NOT NULL DEFAULT 0,
 			last_login TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1
+		)
+	`)
+	require.NoError(t, err)
+
+	_, err = store.Exec(`
+		CREATE TABLE IF NOT EXISTS item_maintenance (
+			id TEXT PRIMARY KEY,
+			item_id TEXT NOT NULL,
+			start_date TEXT NOT NULL,
+			end_date TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_by_user_id TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		)
 	`)
 	require.NoError(t, err)
 
+	_, err = store.Exec(`
+		CREATE TABLE IF NOT EXISTS delegations (
+			id TEXT PRIMARY KEY,
+			user_id TEXT,
+			delegate_id TEXT NOT NULL,
+			created_by_user_id TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err)
+
 	return store
 }
 
@@ -382,6 +409,51 @@ func TestListHandlerMarksBookingsMadeByOtherUsers(t *testing.T) {
 	assert.Equal(t, false, attrs0["booked_by_me"])
 }
 
+func TestListHandlerHidesPrivateBookers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	cfg := testConfig()
+
+	seedTestUser(t, store)
+	seedTestBooking(t, store)
+	_, err := store.Exec(
+		`UPDATE bookings SET is_private = 1, note = 'secret' WHERE id = 'b1';
+		 INSERT INTO delegations (id, user_id, delegate_id, created_by_user_id, created_at)
+		 VALUES ('d1', 'user-1', 'assistant', 'user-1', '2025-01-01')`)
+	require.NoError(t, err)
+
+	firstItem := func(user *auth.User) map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/item-groups/ig-1/items?date=2025-01-20", http.NoBody)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("item_group_id")
+		c.SetParamValues("ig-1")
+		c.Set("user", user)
+		require.NoError(t, ListHandler(cfg, store)(c))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp api.CollectionResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		attrs, ok := resp.Data[0].Attributes.(map[string]any)
+		require.True(t, ok)
+		return attrs
+	}
+
+	attrs := firstItem(&auth.User{ID: "user-2"})
+	assert.Equal(t, "occupied", attrs["availability"])
+	assert.Equal(t, true, attrs["private"])
+	assert.Empty(t, attrs["booker_name"])
+	assert.Nil(t, attrs["booker_user_id"])
+	assert.Nil(t, attrs["note"])
+
+	for _, user := range []*auth.User{{ID: "user-1"}, {ID: "assistant"}, {ID: "admin", IsAdmin: true}} {
+		attrs = firstItem(user)
+		assert.Equal(t, "Alice Smith", attrs["booker_name"], user.ID)
+		assert.Equal(t, "secret", attrs["note"], user.ID)
+	}
+}
+
 func TestListHandlerGuestBookingShowsGuestName(t *testing.T) {
 	t.Parallel()
 
@@ -416,3 +488,40 @@ func TestListHandlerGuestBookingShowsGuestName(t *testing.T) {
 	assert.Equal(t, "John Visitor", attrs0["booker_name"])
 	assert.Nil(t, attrs0["booker_user_id"])
 }
+
+func TestListHandlerMarksItemsUnderMaintenance(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	cfg := testConfig()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.ExecContext(context.Background(),
+		`INSERT INTO item_maintenance (id, item_id, start_date, end_date, reason, created_at, updated_at)
+		 VALUES ('m-1', 'item-2', '2025-01-18', '2025-01-24', 'Broken chair', ?, ?)`, now, now)
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/item-groups/ig-1/items?date=2025-01-20", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("item_group_id")
+	c.SetParamValues("ig-1")
+
+	h := ListHandler(cfg, store)
+	require.NoError(t, h(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+
+	attrs0, ok := resp.Data[0].Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "available", attrs0["availability"])
+	assert.NotContains(t, attrs0, "unavailable_reason")
+
+	attrs1, ok := resp.Data[1].Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "unavailable", attrs1["availability"])
+	assert.Equal(t, "Broken chair", attrs1["unavailable_reason"])
+}
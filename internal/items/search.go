@@ -0,0 +1,201 @@
+package items
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+)
+
+// FeatureItem describes a catalog feature of an item in API responses.
+type FeatureItem struct {
+	ID       string             `json:"id"`
+	Label    string             `json:"label"`
+	Category string             `json:"category,omitempty"`
+	Values   map[string]float64 `json:"values,omitempty"`
+}
+
+// FeaturesHandler returns the equipment catalog from the areas configuration.
+// GET /api/v1/features
+func FeaturesHandler(getConfig areas.ConfigGetter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := getConfig()
+		resources := api.MapResources(cfg.Features, func(f areas.Feature) api.Resource {
+			attrs := map[string]any{
+				"label":      f.Label,
+				"attributes": f.Attributes,
+			}
+			if f.Attributes == nil {
+				attrs["attributes"] = []areas.FeatureAttribute{}
+			}
+			if f.Category != "" {
+				attrs["category"] = f.Category
+			}
+			return api.Resource{Type: "features", ID: f.ID, Attributes: attrs}
+		})
+		return api.WriteCollection(c, resources, "write features response")
+	}
+}
+
+// SearchHandlerDynamic returns the items of all areas that are free on a date
+// and have all requested features.
+// GET /api/v1/items/search?date=<date>&features=<id>,<id>&min[<feature>.<attribute>]=<n>
+func SearchHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB, guards ...bookings.Guard) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := getConfig()
+		bookingDate, err := api.ParseBookingDate(c.QueryParam("date"), cfg.DefaultZone())
+		if err != nil {
+			return api.WriteBadRequest(c, "Invalid booking date. Use YYYY-MM-DD.")
+		}
+		reqs, detail := parseFeatureRequirements(cfg, c)
+		if detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+		areaID := c.QueryParam("area_id")
+		if areaID != "" {
+			if _, ok := cfg.FindArea(areaID); !ok {
+				return api.WriteNotFound(c, "Area not found")
+			}
+		}
+		text := strings.ToLower(strings.TrimSpace(c.QueryParam("q")))
+
+		ctx := c.Request().Context()
+		user := auth.GetUserFromContext(c)
+		currentUserID, userEmail := resolveCurrentUser(ctx, store, user)
+		free, err := bookings.NewFreeItems(ctx, store, cfg, guards,
+			currentUserID, currentUserID, userEmail, []string{bookingDate})
+		if err != nil {
+			return fmt.Errorf("find free items: %w", err)
+		}
+
+		resources := make([]api.Resource, 0)
+		for _, loc := range allItemLocations(cfg, areaID) {
+			if !matchesSearch(cfg, loc.Item, reqs, text) {
+				continue
+			}
+			ok, err := free.Bookable(ctx, loc)
+			if err != nil {
+				return err
+			}
+			if ok {
+				resources = append(resources, searchResource(cfg, loc))
+			}
+		}
+		return api.WriteCollection(c, resources, "write item search response")
+	}
+}
+
+// parseFeatureRequirements reads the features and min[...] query parameters.
+// Returns a bad-request detail for unknown features or malformed minimums.
+func parseFeatureRequirements(cfg *areas.Config, c echo.Context) ([]areas.FeatureRequirement, string) {
+	var reqs []areas.FeatureRequirement
+	index := make(map[string]int)
+	require := func(id string) (*areas.FeatureRequirement, string) {
+		if i, ok := index[id]; ok {
+			return &reqs[i], ""
+		}
+		if _, ok := cfg.FindFeature(id); !ok && len(cfg.Features) > 0 {
+			return nil, fmt.Sprintf("Unknown feature %q", id)
+		}
+		index[id] = len(reqs)
+		reqs = append(reqs, areas.FeatureRequirement{ID: id})
+		return &reqs[len(reqs)-1], ""
+	}
+
+	for _, id := range strings.Split(c.QueryParam("features"), ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		if _, detail := require(id); detail != "" {
+			return nil, detail
+		}
+	}
+
+	for key, values := range c.QueryParams() {
+		inner, ok := strings.CutPrefix(key, "min[")
+		if !ok || !strings.HasSuffix(inner, "]") {
+			continue
+		}
+		featureID, attr, ok := strings.Cut(strings.TrimSuffix(inner, "]"), ".")
+		if !ok || featureID == "" || attr == "" {
+			return nil, fmt.Sprintf("Invalid parameter %q. Use min[<feature>.<attribute>]=<number>.", key)
+		}
+		n, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, fmt.Sprintf("Parameter %q must be a number", key)
+		}
+		req, detail := require(featureID)
+		if detail != "" {
+			return nil, detail
+		}
+		if req.Min == nil {
+			req.Min = make(map[string]float64)
+		}
+		req.Min[attr] = n
+	}
+	return reqs, ""
+}
+
+func allItemLocations(cfg *areas.Config, areaID string) []*areas.ItemLocation {
+	var result []*areas.ItemLocation
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		if areaID != "" && area.ID != areaID {
+			continue
+		}
+		for j := range area.ItemGroups {
+			ig := &area.ItemGroups[j]
+			for k := range ig.Items {
+				result = append(result, &areas.ItemLocation{Area: area, ItemGroup: ig, Item: &ig.Items[k]})
+			}
+		}
+	}
+	return result
+}
+
+func matchesSearch(cfg *areas.Config, item *areas.Item, reqs []areas.FeatureRequirement, text string) bool {
+	for _, req := range reqs {
+		if !cfg.HasFeature(item, req) {
+			return false
+		}
+	}
+	if text == "" || strings.Contains(strings.ToLower(item.Name), text) {
+		return true
+	}
+	for _, e := range item.Equipment {
+		if strings.Contains(strings.ToLower(e), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func searchResource(cfg *areas.Config, loc *areas.ItemLocation) api.Resource {
+	attrs := areas.ItemAttributes(loc.Item.Name, loc.Item.Equipment, loc.Item.Warning, "available", loc.Item.Icon)
+	attrs["area_id"] = loc.Area.ID
+	attrs["area_name"] = loc.Area.Name
+	attrs["item_group_id"] = loc.ItemGroup.ID
+	attrs["item_group_name"] = loc.ItemGroup.Name
+	attrs["features"] = itemFeatures(cfg, loc.Item)
+	return api.Resource{Type: "items", ID: loc.Item.ID, Attributes: attrs}
+}
+
+func itemFeatures(cfg *areas.Config, item *areas.Item) []FeatureItem {
+	result := make([]FeatureItem, 0, len(item.Features))
+	for _, f := range item.Features {
+		fi := FeatureItem{ID: f.ID, Label: f.ID, Values: f.Values}
+		if feature, ok := cfg.FindFeature(f.ID); ok {
+			fi.Label = feature.Label
+			fi.Category = feature.Category
+		}
+		result = append(result, fi)
+	}
+	return result
+}
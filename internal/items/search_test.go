@@ -0,0 +1,151 @@
+package items
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+)
+
+func searchConfig() *areas.Config {
+	cfg := testConfig()
+	cfg.Features = []areas.Feature{
+		{ID: "adjustable-table", Label: "Height-adjustable table"},
+		{ID: "display", Label: "External display", Attributes: []areas.FeatureAttribute{{ID: "size_inch"}}},
+		{ID: "webcam", Label: "Webcam"},
+	}
+	cfg.Areas = append(cfg.Areas, areas.Area{
+		ID: "area-2", Name: "Area Two",
+		ItemGroups: []areas.ItemGroup{{ID: "ig-2", Name: "Room 201", Items: []areas.Item{
+			{ID: "item-3", Name: "Desk 3", Features: []areas.ItemFeature{
+				{ID: "adjustable-table"}, {ID: "webcam"}, {ID: "display", Values: map[string]float64{"size_inch": 27}},
+			}},
+			{ID: "item-4", Name: "Desk 4", Features: []areas.ItemFeature{{ID: "adjustable-table"}}},
+			{ID: "item-5", Name: "Desk 5", Equipment: []string{"Height-adjustable table", "Webcam"}},
+		}}},
+	})
+	return cfg
+}
+
+// The searched dates: item-1 is booked on searchDay1, item-5 on searchDay2.
+var (
+	searchDay1 = time.Now().UTC().AddDate(0, 0, 2).Format(time.DateOnly)
+	searchDay2 = time.Now().UTC().AddDate(0, 0, 3).Format(time.DateOnly)
+)
+
+func runSearch(t *testing.T, cfg *areas.Config, query string, guards ...bookings.Guard) *httptest.ResponseRecorder {
+	t.Helper()
+	store := setupTestDB(t)
+	_, err := store.ExecContext(context.Background(),
+		`INSERT INTO bookings (id, item_id, user_id, booking_date, created_at, updated_at)
+		 VALUES ('b1', 'item-1', 'user-1', ?, '', ''), ('b2', 'item-5', 'user-2', ?, '', '')`,
+		searchDay1, searchDay2)
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/items/search?"+query, http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "user-1"})
+
+	require.NoError(t, SearchHandlerDynamic(func() *areas.Config { return cfg }, store, guards...)(c))
+	return rec
+}
+
+func searchIDs(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	ids := make([]string, len(resp.Data))
+	for i := range resp.Data {
+		ids[i] = resp.Data[i].ID
+	}
+	return ids
+}
+
+func TestSearchHandlerFiltersByFeatures(t *testing.T) {
+	t.Parallel()
+
+	rec := runSearch(t, searchConfig(), "date="+searchDay1+"&features=adjustable-table,webcam")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"item-3", "item-5"}, searchIDs(t, rec))
+
+	rec = runSearch(t, searchConfig(), "date="+searchDay2+"&features=webcam")
+	assert.Equal(t, []string{"item-3"}, searchIDs(t, rec), "booked items are left out")
+}
+
+func TestSearchHandlerMinimumValues(t *testing.T) {
+	t.Parallel()
+
+	rec := runSearch(t, searchConfig(), "date="+searchDay1+"&min%5Bdisplay.size_inch%5D=27")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"item-3"}, searchIDs(t, rec))
+	assert.Contains(t, rec.Body.String(), `"area_name":"Area Two"`)
+
+	rec = runSearch(t, searchConfig(), "date="+searchDay1+"&min%5Bdisplay.size_inch%5D=32")
+	assert.Empty(t, searchIDs(t, rec))
+}
+
+func TestSearchHandlerFreeTextAndBookedItems(t *testing.T) {
+	t.Parallel()
+
+	// item-1 is booked on searchDay1.
+	rec := runSearch(t, searchConfig(), "date="+searchDay1+"&q=monitor")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"item-2"}, searchIDs(t, rec))
+}
+
+func TestSearchHandlerRejectsUnknownFeature(t *testing.T) {
+	t.Parallel()
+
+	rec := runSearch(t, searchConfig(), "features=scanner")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = runSearch(t, searchConfig(), "min%5Bdisplay%5D=3")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+type closedGuard struct{}
+
+func (closedGuard) CheckBooking(_ context.Context, req *bookings.GuardRequest) error {
+	if req.Location.Area.ID == "area-2" {
+		return &bookings.Rejection{Status: http.StatusConflict, Code: "area_closed", Detail: "Closed"}
+	}
+	return nil
+}
+
+func TestSearchHandlerHidesGuardedItems(t *testing.T) {
+	t.Parallel()
+
+	rec := runSearch(t, searchConfig(), "date="+searchDay2, closedGuard{})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"item-1", "item-2"}, searchIDs(t, rec))
+}
+
+func TestFeaturesHandler(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/features", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := searchConfig()
+	require.NoError(t, FeaturesHandler(func() *areas.Config { return cfg })(c))
+	assert.Equal(t, []string{"adjustable-table", "display", "webcam"}, searchIDs(t, rec))
+	assert.Contains(t, rec.Body.String(), `"attributes":[{"id":"size_inch"}]`)
+}
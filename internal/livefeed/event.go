@@ -22,19 +22,30 @@ const (
 // Personally identifying details (guest name, guest email) are intentionally
 // omitted so the broadcast payload is safe to deliver to all connected
 // clients. Clients only need enough to refresh their visible slice and to
-// recognize whether the change originated from their own session.
+// recognize whether the change originated from their own session. Private
+// bookings are sent without a user ID.
 type Event struct {
 	Type        EventType `json:"type"`
 	BookingID   string    `json:"booking_id"`
 	ItemID      string    `json:"item_id"`
 	UserID      string    `json:"user_id"`
 	BookingDate string    `json:"booking_date"`
+	Private     bool      `json:"private,omitempty"`
 	Timestamp   string    `json:"timestamp"`
 }
 
+// isBroadcast reports whether an event changes booking state and must be
+// pushed to every client. Personal notices (e.g. an item going out of service)
+// are only delivered through the other notifiers.
+func isBroadcast(t notifications.EventType) bool {
+	return t == notifications.EventBookingCreated || t == notifications.EventBookingCanceled
+}
+
 // fromBookingEvent maps an internal notification event to the public live
-// payload, dropping PII fields (guest_name, guest_email).
+// payload, dropping PII fields (guest_name, guest_email) and the user of
+// private bookings.
 func fromBookingEvent(src *notifications.BookingEvent) Event {
+	src = src.WithoutBooker()
 	userID := src.UserID
 	switch src.Event {
 	case notifications.EventBookingCreated:
@@ -53,6 +64,7 @@ func fromBookingEvent(src *notifications.BookingEvent) Event {
 		ItemID:      src.ItemID,
 		UserID:      userID,
 		BookingDate: src.BookingDate,
+		Private:     src.Private,
 		Timestamp:   src.Timestamp,
 	}
 }
@@ -103,7 +103,7 @@ func (h *Hub) Unregister(c *Client) {
 // hub's broadcast queue is full or the hub has shut down, the event is
 // dropped with a warning. Booking handlers must never wait on the hub.
 func (h *Hub) NotifyAsync(event *notifications.BookingEvent) {
-	if event == nil || !h.running.Load() {
+	if event == nil || !h.running.Load() || !isBroadcast(event.Event) {
 		return
 	}
 	ev := fromBookingEvent(event)
@@ -216,3 +216,33 @@ func TestNotifyAsyncDropsWhenQueueFull(t *testing.T) {
 		t.Fatal("NotifyAsync blocked when broadcast queue was full")
 	}
 }
+
+func TestNotifyAsyncSkipsPersonalEvents(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub()
+	hub.running.Store(true)
+
+	hub.NotifyAsync(&notifications.BookingEvent{Event: notifications.EventBookingItemUnavailable, BookingID: "b1"})
+	assert.Empty(t, hub.broadcast)
+
+	hub.NotifyAsync(&notifications.BookingEvent{Event: notifications.EventBookingCanceled, BookingID: "b1"})
+	assert.Len(t, hub.broadcast, 1)
+}
+
+func TestNotifyAsyncHidesPrivateBooker(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub()
+	hub.running.Store(true)
+
+	hub.NotifyAsync(&notifications.BookingEvent{
+		Event: notifications.EventBookingCreated, BookingID: "b1", ItemID: "desk1",
+		UserID: "alice", BookedByUserID: "bob", Private: true,
+	})
+	require.Len(t, hub.broadcast, 1)
+	got := <-hub.broadcast
+	assert.Equal(t, "desk1", got.ItemID)
+	assert.Empty(t, got.UserID)
+	assert.True(t, got.Private)
+}
@@ -0,0 +1,351 @@
+package lottery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+// Allocator draws pending requests once their cutoff has passed and books
+// waitlisted requests when items become free. Allocator is not safe for
+// concurrent use; run a single instance.
+type Allocator struct {
+	getConfig areas.ConfigGetter
+	store     *sql.DB
+	notifier  notifications.Notifier
+	limits    *bookings.BookingLimits
+	guards    []bookings.Guard
+	now       func() time.Time
+	random    func() float64
+}
+
+// NewAllocator creates an allocator. guards are checked for every item before it
+// is assigned, so closures and maintenance windows are respected; the lottery
+// guard itself must not be among them.
+func NewAllocator(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+	limits *bookings.BookingLimits, guards ...bookings.Guard,
+) *Allocator {
+	return &Allocator{
+		getConfig: getConfig,
+		store:     store,
+		notifier:  notifier,
+		limits:    limits,
+		guards:    guards,
+		now:       time.Now,
+		random:    rand.Float64, //nolint:gosec // G404: math/rand/v2 is seeded from the OS; draws are not secrets
+	}
+}
+
+// Run processes due requests every interval until ctx is done.
+func (a *Allocator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.RunDue(ctx); err != nil {
+			slog.Error("lottery allocation", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue draws every scope and date whose cutoff has passed, then offers free
+// items to waitlisted requests.
+func (a *Allocator) RunDue(ctx context.Context) error {
+	cfg := a.getConfig()
+	// Each scope has its own local today; list from the earliest and skip past days per scope.
+	now := a.now()
+	today, _ := cfg.TodayRange(now)
+
+	pending, err := ListByStatus(ctx, a.store, StatusPending, today)
+	if err != nil {
+		return err
+	}
+	for _, batch := range groupRequests(pending) {
+		scope, ok := cfg.FindLotteryScope(batch[0].ScopeKey)
+		if !ok {
+			slog.Warn("lottery scope no longer configured", "scope", batch[0].ScopeKey)
+			continue
+		}
+		if batch[0].BookingDate < areas.LocalDate(now, scope.Zone) || now.Before(scope.Cutoff(batch[0].BookingDate)) {
+			continue
+		}
+		if err := a.draw(ctx, cfg, scope, batch); err != nil {
+			return err
+		}
+	}
+
+	waiting, err := ListByStatus(ctx, a.store, StatusWaitlisted, today)
+	if err != nil {
+		return err
+	}
+	for _, batch := range groupRequests(waiting) {
+		scope, ok := cfg.FindLotteryScope(batch[0].ScopeKey)
+		if !ok || batch[0].BookingDate < areas.LocalDate(now, scope.Zone) {
+			continue
+		}
+		if err := a.promote(ctx, cfg, scope, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupRequests splits requests sorted by scope and date into one batch per
+// scope and date.
+func groupRequests(list []Request) [][]Request {
+	var result [][]Request
+	for i := 0; i < len(list); {
+		j := i + 1
+		for j < len(list) && list[j].ScopeKey == list[i].ScopeKey && list[j].BookingDate == list[i].BookingDate {
+			j++
+		}
+		result = append(result, list[i:j])
+		i = j
+	}
+	return result
+}
+
+// draw orders the requests by weighted lottery and assigns free items in that
+// order. Each recent loss adds one to a user's weight, so people who lost
+// recently are more likely to be drawn early.
+func (a *Allocator) draw(ctx context.Context, cfg *areas.Config, scope *areas.LotteryScope, reqs []Request) error {
+	date := reqs[0].BookingDate
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return fmt.Errorf("parse lottery date: %w", err)
+	}
+	userIDs := make([]string, len(reqs))
+	for i := range reqs {
+		userIDs[i] = reqs[i].UserID
+	}
+	since := day.Add(-scope.Lottery.FairnessWindow()).Format(time.DateOnly)
+	losses, err := CountLosses(ctx, a.store, userIDs, since, date)
+	if err != nil {
+		return err
+	}
+
+	order := a.weightedOrder(reqs, losses)
+	pool, err := a.openItems(ctx, scope, date)
+	if err != nil {
+		return err
+	}
+
+	won := 0
+	for i, r := range order {
+		r.DrawPosition = i + 1
+		if err := a.assign(ctx, cfg, r, pool); err != nil {
+			return err
+		}
+		if r.Status == StatusPending {
+			r.Status = StatusLost
+			if scope.Lottery.Waitlist {
+				r.Status = StatusWaitlisted
+			}
+		}
+		if err := a.finish(ctx, r, "No item was left for your request"); err != nil {
+			return err
+		}
+		if r.Status == StatusWon {
+			won++
+		}
+	}
+	slog.Info("lottery drawn", "scope", scope.Key(), "booking_date", date, "requests", len(reqs), "won", won)
+	return nil
+}
+
+// promote offers free items to waitlisted requests in draw order. Requests that
+// cannot take any of the free items (e.g. because of a booking policy) are
+// dropped from the waitlist, so the items open up for everyone.
+func (a *Allocator) promote(ctx context.Context, cfg *areas.Config, scope *areas.LotteryScope, reqs []Request) error {
+	pool, err := a.openItems(ctx, scope, reqs[0].BookingDate)
+	if err != nil {
+		return err
+	}
+	for i := range reqs {
+		if len(pool.free) == 0 {
+			return nil
+		}
+		r := &reqs[i]
+		if err := a.assign(ctx, cfg, r, pool); err != nil {
+			return err
+		}
+		if r.Status == StatusWaitlisted {
+			r.Status = StatusLost
+		}
+		if err := a.finish(ctx, r, "None of the free items can be booked for you"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// itemPool tracks the free items of a scope on a date and the users who
+// already have a booking there.
+type itemPool struct {
+	date   string
+	free   []*areas.ItemLocation
+	booked map[string]bool
+}
+
+func (a *Allocator) openItems(ctx context.Context, scope *areas.LotteryScope, date string) (*itemPool, error) {
+	taken, err := bookings.FindItemBookings(ctx, a.store, date)
+	if err != nil {
+		return nil, fmt.Errorf("find lottery bookings: %w", err)
+	}
+	pool := &itemPool{date: date, booked: make(map[string]bool)}
+	for i := range scope.Items {
+		loc := &scope.Items[i]
+		if info, ok := taken[loc.Item.ID]; ok {
+			pool.booked[info.UserID] = true
+			continue
+		}
+		req := &bookings.GuardRequest{Location: loc, Dates: []string{date}}
+		ok, err := bookings.PassesGuards(ctx, a.guards, req)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			pool.free = append(pool.free, loc)
+		}
+	}
+	return pool, nil
+}
+
+// assign books the user's most preferred free item, or else the first free item
+// they may book, and marks the request as won. The request is left unchanged
+// when no item fits. Users who already booked in the scope are withdrawn.
+func (a *Allocator) assign(ctx context.Context, cfg *areas.Config, r *Request, pool *itemPool) error {
+	if pool.booked[r.UserID] {
+		r.Status = StatusWithdrawn
+		return nil
+	}
+	email := ""
+	rec, err := users.FindByID(ctx, a.store, r.UserID)
+	switch {
+	case err == nil:
+		email = rec.Email
+	case !errors.Is(err, users.ErrUserNotFound):
+		return fmt.Errorf("find lottery user: %w", err)
+	}
+
+	for _, idx := range candidateOrder(r.Preferences, pool.free) {
+		loc := pool.free[idx]
+		if areas.IsReserved(loc, email) {
+			continue
+		}
+		err := bookings.CheckPolicies(ctx, a.store, cfg, a.limits, loc, r.UserID, email, []string{r.BookingDate})
+		var rej *bookings.Rejection
+		if errors.As(err, &rej) || errors.Is(err, bookings.ErrBookingLimitExceeded) {
+			// Policies count the user's bookings, not the item, so no other item helps.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		b, err := bookings.CreateBooking(
+			ctx, a.store, loc.Item.ID, r.UserID, r.UserID, r.BookingDate, "", false, "", "")
+		if errors.Is(err, bookings.ErrConflict) {
+			pool.free = append(pool.free[:idx], pool.free[idx+1:]...)
+			return a.assign(ctx, cfg, r, pool)
+		}
+		if err != nil {
+			return fmt.Errorf("book lottery item: %w", err)
+		}
+		pool.free = append(pool.free[:idx], pool.free[idx+1:]...)
+		pool.booked[r.UserID] = true
+		r.Status = StatusWon
+		r.ItemID = b.ItemID
+		r.BookingID = b.ID
+		bookings.NotifyBookingCreated(a.notifier, b)
+		return nil
+	}
+	return nil
+}
+
+// candidateOrder returns indexes into free: preferred items in preference order,
+// then the remaining items in configuration order.
+func candidateOrder(preferences []string, free []*areas.ItemLocation) []int {
+	order := make([]int, 0, len(free))
+	used := make(map[int]bool, len(free))
+	for _, id := range preferences {
+		for i, loc := range free {
+			if loc.Item.ID == id && !used[i] {
+				order = append(order, i)
+				used[i] = true
+			}
+		}
+	}
+	for i := range free {
+		if !used[i] {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// finish stores the outcome and notifies the user.
+func (a *Allocator) finish(ctx context.Context, r *Request, lostReason string) error {
+	if err := Resolve(ctx, a.store, r); err != nil {
+		return err
+	}
+	event := &notifications.BookingEvent{
+		BookingID:        r.BookingID,
+		ItemID:           r.ItemID,
+		UserID:           r.UserID,
+		BookingDate:      r.BookingDate,
+		LotteryRequestID: r.ID,
+		Timestamp:        a.now().UTC().Format(time.RFC3339),
+	}
+	switch r.Status {
+	case StatusWon:
+		event.Event = notifications.EventLotteryWon
+	case StatusWaitlisted:
+		event.Event = notifications.EventLotteryWaitlisted
+		event.Reason = lostReason
+	case StatusLost:
+		event.Event = notifications.EventLotteryLost
+		event.Reason = lostReason
+	default:
+		return nil
+	}
+	a.notifier.NotifyAsync(event)
+	return nil
+}
+
+// weightedOrder draws a random order in which a request with weight w is w
+// times as likely to come first as one with weight 1 (Efraimidis-Spirakis).
+func (a *Allocator) weightedOrder(reqs []Request, losses map[string]int) []*Request {
+	type keyed struct {
+		req *Request
+		key float64
+	}
+	list := make([]keyed, len(reqs))
+	for i := range reqs {
+		weight := float64(1 + losses[reqs[i].UserID])
+		list[i] = keyed{req: &reqs[i], key: -math.Log(1-a.random()) / weight}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].key < list[j].key })
+
+	order := make([]*Request, len(list))
+	for i := range list {
+		order[i] = list[i].req
+	}
+	return order
+}
@@ -0,0 +1,225 @@
+package lottery
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/db"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*notifications.BookingEvent
+}
+
+func (n *recordingNotifier) NotifyAsync(event *notifications.BookingEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+}
+
+func (n *recordingNotifier) count(event notifications.EventType) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	total := 0
+	for _, e := range n.events {
+		if e.Event == event {
+			total++
+		}
+	}
+	return total
+}
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+	return store
+}
+
+// testConfig has a lottery on room-1 with two desks; room-2 books first come, first served.
+func testConfig() *areas.Config {
+	return &areas.Config{Areas: []areas.Area{{
+		ID:   "office",
+		Name: "Office",
+		ItemGroups: []areas.ItemGroup{
+			{
+				ID: "room-1", Name: "Room 1", Lottery: &areas.Lottery{Waitlist: true},
+				Items: []areas.Item{{ID: "desk-1", Name: "Desk 1"}, {ID: "desk-2", Name: "Desk 2"}},
+			},
+			{ID: "room-2", Name: "Room 2", Items: []areas.Item{{ID: "desk-3", Name: "Desk 3"}}},
+		},
+	}}}
+}
+
+// lotteryDate returns a date two weeks ahead, so requests are still open.
+func lotteryDate() string {
+	return time.Now().UTC().AddDate(0, 0, 14).Format(time.DateOnly)
+}
+
+func newTestAllocator(store *sql.DB, notifier notifications.Notifier, now time.Time) *Allocator {
+	a := NewAllocator(func() *areas.Config { return testConfig() }, store, notifier,
+		&bookings.BookingLimits{WeeksInAdvanced: 52})
+	a.now = func() time.Time { return now }
+	a.random = func() float64 { return 0.5 }
+	return a
+}
+
+func seedLoss(t *testing.T, store *sql.DB, userID, date string) {
+	t.Helper()
+	r, err := Create(t.Context(), store, "item_group:room-1", date, userID, nil)
+	require.NoError(t, err)
+	r.Status = StatusLost
+	require.NoError(t, Resolve(t.Context(), store, r))
+}
+
+func requestStatus(t *testing.T, store *sql.DB, id string) *Request {
+	t.Helper()
+	r, err := FindByID(t.Context(), store, id)
+	require.NoError(t, err)
+	return r
+}
+
+func TestRunDueSkipsRequestsBeforeCutoff(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	date := lotteryDate()
+	r, err := Create(t.Context(), store, "item_group:room-1", date, "user-1", []string{"desk-1"})
+	require.NoError(t, err)
+
+	a := newTestAllocator(store, &recordingNotifier{}, time.Now())
+	require.NoError(t, a.RunDue(t.Context()))
+	assert.Equal(t, StatusPending, requestStatus(t, store, r.ID).Status)
+}
+
+func TestRunDueDrawsByWeightAndPreference(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	date := lotteryDate()
+	day, err := time.Parse(time.DateOnly, date)
+	require.NoError(t, err)
+	seedLoss(t, store, "user-3", day.AddDate(0, 0, -7).Format(time.DateOnly))
+	seedLoss(t, store, "user-3", day.AddDate(0, 0, -14).Format(time.DateOnly))
+
+	ctx := t.Context()
+	r1, err := Create(ctx, store, "item_group:room-1", date, "user-1", []string{"desk-2"})
+	require.NoError(t, err)
+	r2, err := Create(ctx, store, "item_group:room-1", date, "user-2", []string{"desk-2"})
+	require.NoError(t, err)
+	r3, err := Create(ctx, store, "item_group:room-1", date, "user-3", []string{"desk-2"})
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	a := newTestAllocator(store, notifier, day.Add(-time.Hour))
+	require.NoError(t, a.RunDue(ctx))
+
+	// user-3 lost twice recently and is drawn first, so gets the preferred desk.
+	got3 := requestStatus(t, store, r3.ID)
+	assert.Equal(t, StatusWon, got3.Status)
+	assert.Equal(t, "desk-2", got3.ItemID)
+	assert.Equal(t, 1, got3.DrawPosition)
+
+	got1, got2 := requestStatus(t, store, r1.ID), requestStatus(t, store, r2.ID)
+	statuses := []string{got1.Status, got2.Status}
+	assert.ElementsMatch(t, []string{StatusWon, StatusWaitlisted}, statuses)
+	winner := got1
+	if got2.Status == StatusWon {
+		winner = got2
+	}
+	assert.Equal(t, "desk-1", winner.ItemID)
+	assert.NotEmpty(t, winner.BookingID)
+
+	assert.Equal(t, 2, notifier.count(notifications.EventLotteryWon))
+	assert.Equal(t, 1, notifier.count(notifications.EventLotteryWaitlisted))
+	assert.Equal(t, 2, notifier.count(notifications.EventBookingCreated))
+}
+
+func TestRunDuePromotesWaitlistWhenItemFreed(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	ctx := t.Context()
+	date := lotteryDate()
+	day, err := time.Parse(time.DateOnly, date)
+	require.NoError(t, err)
+
+	first, err := Create(ctx, store, "item_group:room-1", date, "user-1", nil)
+	require.NoError(t, err)
+	second, err := Create(ctx, store, "item_group:room-1", date, "user-2", nil)
+	require.NoError(t, err)
+	third, err := Create(ctx, store, "item_group:room-1", date, "user-3", nil)
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	a := newTestAllocator(store, notifier, day.Add(-time.Hour))
+	require.NoError(t, a.RunDue(ctx))
+
+	var waiting *Request
+	var winner *Request
+	for _, id := range []string{first.ID, second.ID, third.ID} {
+		r := requestStatus(t, store, id)
+		switch r.Status {
+		case StatusWaitlisted:
+			waiting = r
+		case StatusWon:
+			winner = r
+		}
+	}
+	require.NotNil(t, waiting)
+	require.NotNil(t, winner)
+
+	// Nothing changes while every item is taken.
+	require.NoError(t, a.RunDue(ctx))
+	assert.Equal(t, StatusWaitlisted, requestStatus(t, store, waiting.ID).Status)
+
+	_, err = store.ExecContext(ctx, `DELETE FROM bookings WHERE id = ?`, winner.BookingID)
+	require.NoError(t, err)
+	require.NoError(t, a.RunDue(ctx))
+
+	promoted := requestStatus(t, store, waiting.ID)
+	assert.Equal(t, StatusWon, promoted.Status)
+	assert.Equal(t, winner.ItemID, promoted.ItemID)
+}
+
+func TestRunDueWithdrawsUsersWhoAlreadyBooked(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	ctx := t.Context()
+	date := lotteryDate()
+	day, err := time.Parse(time.DateOnly, date)
+	require.NoError(t, err)
+
+	_, err = bookings.CreateBooking(ctx, store, "desk-1", "user-1", "user-1", date, "", false, "", "")
+	require.NoError(t, err)
+	r, err := Create(ctx, store, "item_group:room-1", date, "user-1", []string{"desk-2"})
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	a := newTestAllocator(store, notifier, day.Add(-time.Hour))
+	require.NoError(t, a.RunDue(ctx))
+
+	assert.Equal(t, StatusWithdrawn, requestStatus(t, store, r.ID).Status)
+	assert.Empty(t, notifier.events)
+}
+
+func TestWeightedOrderFavoursRecentLosers(t *testing.T) {
+	t.Parallel()
+	a := &Allocator{random: func() float64 { return 0.5 }}
+	reqs := []Request{{ID: "a", UserID: "user-1"}, {ID: "b", UserID: "user-2"}}
+
+	order := a.weightedOrder(reqs, map[string]int{"user-2": 3})
+	require.Len(t, order, 2)
+	assert.Equal(t, "b", order[0].ID)
+	assert.Equal(t, "a", order[1].ID)
+}
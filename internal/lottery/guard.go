@@ -0,0 +1,70 @@
+package lottery
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+)
+
+// JSON:API error codes used when a booking targets a lottery day.
+const (
+	// ErrorCodeLotteryOpen means requests are still being collected.
+	ErrorCodeLotteryOpen = "lottery_open"
+	// ErrorCodeLotteryPending means the cutoff passed but the items are not
+	// allocated yet, or free items are still offered to the waitlist.
+	ErrorCodeLotteryPending = "lottery_pending"
+)
+
+// Guard rejects direct bookings on lottery days until the lottery is drawn and
+// its waitlist is served. Afterwards the remaining items can be booked first
+// come, first served. Guard is safe for concurrent use.
+type Guard struct {
+	getConfig areas.ConfigGetter
+	store     *sql.DB
+	now       func() time.Time
+}
+
+// NewGuard creates a booking guard backed by the areas config and the lottery requests table.
+func NewGuard(getConfig areas.ConfigGetter, store *sql.DB) *Guard {
+	return &Guard{getConfig: getConfig, store: store, now: time.Now}
+}
+
+// CheckBooking implements bookings.Guard.
+func (g *Guard) CheckBooking(ctx context.Context, req *bookings.GuardRequest) error {
+	scope, ok := g.getConfig().LotteryScopeFor(req.Location)
+	if !ok {
+		return nil
+	}
+	for _, date := range req.Dates {
+		if !scope.Lottery.AppliesOn(date) {
+			continue
+		}
+		cutoff := scope.Cutoff(date)
+		if g.now().Before(cutoff) {
+			return &bookings.Rejection{
+				Status: http.StatusConflict,
+				Code:   ErrorCodeLotteryOpen,
+				Detail: fmt.Sprintf("%s is allocated by lottery on %s. Submit a lottery request before %s.",
+					scope.Name, date, cutoff.Format("2006-01-02 15:04 MST")),
+			}
+		}
+		waiting, err := HasOpen(ctx, g.store, scope.Key(), date)
+		if err != nil {
+			return err
+		}
+		if waiting {
+			return &bookings.Rejection{
+				Status: http.StatusConflict,
+				Code:   ErrorCodeLotteryPending,
+				Detail: fmt.Sprintf("The lottery for %s on %s is being allocated. Please try again in a few minutes.",
+					scope.Name, date),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package lottery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const (
+	resourceType   = "lottery-requests"
+	maxPreferences = 10
+)
+
+// ErrorCodeLotteryClosed is the JSON:API error code used when a request is
+// submitted after the cutoff.
+const ErrorCodeLotteryClosed = "lottery_closed"
+
+// Attributes represents lottery request resource attributes.
+type Attributes struct {
+	ScopeType    string   `json:"scope_type"`
+	ScopeID      string   `json:"scope_id"`
+	ScopeName    string   `json:"scope_name,omitempty"`
+	BookingDate  string   `json:"booking_date"`
+	Preferences  []string `json:"preferences"`
+	Cutoff       string   `json:"cutoff,omitempty"`
+	Status       string   `json:"status"`
+	DrawPosition int      `json:"draw_position,omitempty"`
+	ItemID       string   `json:"item_id,omitempty"`
+	ItemName     string   `json:"item_name,omitempty"`
+	BookingID    string   `json:"booking_id,omitempty"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+type createRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			BookingDate string   `json:"booking_date"`
+			AreaID      string   `json:"area_id"`
+			ItemGroupID string   `json:"item_group_id"`
+			Preferences []string `json:"preferences"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns the current user's lottery requests from today on.
+// GET /api/v1/lottery-requests
+func ListHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		cfg := getConfig()
+		today, _ := cfg.TodayRange(time.Now())
+		list, err := ListForUser(c.Request().Context(), store, user.ID, today)
+		if err != nil {
+			return api.WriteInternalError(c, "list lottery requests", err)
+		}
+		resources := api.MapResources(list, func(r Request) api.Resource {
+			return toResource(cfg, &r)
+		})
+		return api.WriteCollection(c, resources, "write lottery requests response")
+	}
+}
+
+// CreateHandler submits a lottery request for the current user. The scope is
+// taken from the first preferred item, or from item_group_id or area_id.
+// POST /api/v1/lottery-requests
+func CreateHandler(getConfig areas.ConfigGetter, store *sql.DB, limits *bookings.BookingLimits) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req createRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceType {
+			return api.WriteBadRequest(c, "Resource type must be 'lottery-requests'")
+		}
+		a := req.Data.Attributes
+		date := strings.TrimSpace(a.BookingDate)
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return api.WriteBadRequest(c, "booking_date must be in YYYY-MM-DD format")
+		}
+
+		cfg := getConfig()
+		scope, detail := resolveScope(cfg, a.AreaID, a.ItemGroupID, a.Preferences)
+		if scope == nil {
+			return api.WriteBadRequest(c, detail)
+		}
+		if date < areas.LocalDate(time.Now(), scope.Zone) {
+			return api.WriteBadRequest(c, "booking_date must not be in the past")
+		}
+		if !scope.Lottery.AppliesOn(date) {
+			return api.WriteBadRequest(c, fmt.Sprintf(
+				"%s is not allocated by lottery on %s. Book an item directly.", scope.Name, date))
+		}
+		if cutoff := scope.Cutoff(date); !time.Now().Before(cutoff) {
+			return api.WriteError(c, http.StatusConflict,
+				fmt.Sprintf("Lottery requests for %s on %s closed at %s.", scope.Name, date,
+					cutoff.Format("2006-01-02 15:04 MST")), ErrorCodeLotteryClosed)
+		}
+
+		ctx := c.Request().Context()
+		if err := checkEligibility(ctx, store, cfg, limits, scope, user.ID, date, a.Preferences); err != nil {
+			return writeEligibilityError(c, err)
+		}
+
+		r, err := Create(ctx, store, scope.Key(), date, user.ID, a.Preferences)
+		if errors.Is(err, ErrDuplicate) {
+			return api.WriteConflict(c, "You already requested "+scope.Name+" on "+date)
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "create lottery request", err)
+		}
+		return api.WriteSingle(c, http.StatusCreated, toResource(cfg, r), "write lottery request response")
+	}
+}
+
+// DeleteHandler withdraws a pending or waitlisted lottery request. Users can
+// withdraw their own requests; admins can withdraw any.
+// DELETE /api/v1/lottery-requests/:id
+func DeleteHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		ctx := c.Request().Context()
+		r, err := FindByID(ctx, store, c.Param("id"))
+		if errors.Is(err, ErrNotFound) || (err == nil && r.UserID != user.ID && !user.IsAdmin) {
+			return api.WriteNotFound(c, "Lottery request not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find lottery request", err)
+		}
+		if r.Status != StatusPending && r.Status != StatusWaitlisted {
+			return api.WriteConflict(c, "Only pending or waitlisted requests can be withdrawn")
+		}
+		r.Status = StatusWithdrawn
+		if err := Resolve(ctx, store, r); err != nil {
+			return api.WriteInternalError(c, "withdraw lottery request", err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// resolveScope finds the lottery scope of a request. Returns nil and a
+// bad-request detail when the target is unknown, has no lottery, or the
+// preferences span several scopes.
+func resolveScope(cfg *areas.Config, areaID, itemGroupID string, preferences []string) (*areas.LotteryScope, string) {
+	if len(preferences) > maxPreferences {
+		return nil, fmt.Sprintf("At most %d preferences are allowed", maxPreferences)
+	}
+	var scope *areas.LotteryScope
+	for _, itemID := range preferences {
+		loc, ok := cfg.FindItemLocation(itemID)
+		if !ok {
+			return nil, fmt.Sprintf("Item %q not found", itemID)
+		}
+		s, ok := cfg.LotteryScopeFor(loc)
+		if !ok {
+			return nil, fmt.Sprintf("Item %q is not allocated by lottery", itemID)
+		}
+		if scope != nil && scope.Key() != s.Key() {
+			return nil, "All preferred items must belong to the same lottery"
+		}
+		scope = s
+	}
+	if scope != nil {
+		return scope, ""
+	}
+
+	switch {
+	case itemGroupID != "":
+		if s, ok := cfg.FindLotteryScope(areas.LotteryScopeItemGroup + ":" + itemGroupID); ok {
+			return s, ""
+		}
+		for _, s := range cfg.LotteryScopes() {
+			for i := range s.Items {
+				if s.Items[i].ItemGroup.ID == itemGroupID {
+					return s, ""
+				}
+			}
+		}
+		return nil, fmt.Sprintf("Item group %q is not allocated by lottery", itemGroupID)
+	case areaID != "":
+		if s, ok := cfg.FindLotteryScope(areas.LotteryScopeArea + ":" + areaID); ok {
+			return s, ""
+		}
+		return nil, fmt.Sprintf("Area %q is not allocated by lottery", areaID)
+	}
+	return nil, "One of preferences, item_group_id, or area_id is required"
+}
+
+// checkEligibility rejects preferences reserved for other users and requests
+// that would break a booking policy. Violations are returned as
+// *bookings.Rejection or bookings.ErrBookingLimitExceeded.
+func checkEligibility(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, limits *bookings.BookingLimits,
+	scope *areas.LotteryScope, userID, date string, preferences []string,
+) error {
+	email := ""
+	rec, err := users.FindByID(ctx, store, userID)
+	switch {
+	case err == nil:
+		email = rec.Email
+	case !errors.Is(err, users.ErrUserNotFound):
+		return fmt.Errorf("find user: %w", err)
+	}
+
+	for _, itemID := range preferences {
+		loc, _ := cfg.FindItemLocation(itemID)
+		if areas.IsReserved(loc, email) {
+			return &bookings.Rejection{
+				Status: http.StatusForbidden,
+				Code:   "forbidden",
+				Detail: fmt.Sprintf("%s is reserved for other users", loc.Item.Name),
+			}
+		}
+	}
+	if len(scope.Items) == 0 {
+		return &bookings.Rejection{
+			Status: http.StatusBadRequest, Code: "bad_request", Detail: scope.Name + " has no items",
+		}
+	}
+	loc := &scope.Items[0]
+	if len(preferences) > 0 {
+		loc, _ = cfg.FindItemLocation(preferences[0])
+	}
+	return bookings.CheckPolicies(ctx, store, cfg, limits, loc, userID, email, []string{date})
+}
+
+func writeEligibilityError(c echo.Context, err error) error {
+	var rej *bookings.Rejection
+	switch {
+	case errors.As(err, &rej):
+		return api.WriteError(c, rej.Status, rej.Detail, rej.Code)
+	case errors.Is(err, bookings.ErrBookingLimitExceeded):
+		return api.WriteConflict(c, err.Error())
+	default:
+		return api.WriteInternalError(c, "check lottery request", err)
+	}
+}
+
+func toResource(cfg *areas.Config, r *Request) api.Resource {
+	kind, id, _ := strings.Cut(r.ScopeKey, ":")
+	attrs := Attributes{
+		ScopeType:    kind,
+		ScopeID:      id,
+		BookingDate:  r.BookingDate,
+		Preferences:  r.Preferences,
+		Status:       r.Status,
+		DrawPosition: r.DrawPosition,
+		ItemID:       r.ItemID,
+		BookingID:    r.BookingID,
+		CreatedAt:    r.CreatedAt,
+		UpdatedAt:    r.UpdatedAt,
+	}
+	if attrs.Preferences == nil {
+		attrs.Preferences = []string{}
+	}
+	if scope, ok := cfg.FindLotteryScope(r.ScopeKey); ok {
+		attrs.ScopeName = scope.Name
+		attrs.Cutoff = scope.Cutoff(r.BookingDate).Format(time.RFC3339)
+	}
+	if r.ItemID != "" {
+		if item, ok := cfg.FindItem(r.ItemID); ok {
+			attrs.ItemName = item.Name
+		}
+	}
+	return api.Resource{Type: resourceType, ID: r.ID, Attributes: attrs}
+}
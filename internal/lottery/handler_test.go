@@ -0,0 +1,148 @@
+package lottery
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+)
+
+func newUserContext(method, target, body, userID string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: userID})
+	return c, rec
+}
+
+func createBody(date, prefs string) string {
+	return `{"data":{"type":"lottery-requests","attributes":{"booking_date":"` + date +
+		`","preferences":[` + prefs + `]}}}`
+}
+
+func newCreateHandler(store *sql.DB) echo.HandlerFunc {
+	getConfig := func() *areas.Config { return testConfig() }
+	return CreateHandler(getConfig, store, &bookings.BookingLimits{WeeksInAdvanced: 52})
+}
+
+func TestCreateHandlerCreatesPendingRequest(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	h := newCreateHandler(store)
+	date := lotteryDate()
+
+	body := createBody(date, `"desk-2","desk-1"`)
+	c, rec := newUserContext(http.MethodPost, "/api/v1/lottery-requests", body, "user-1")
+	require.NoError(t, h(c))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs, ok := resp.Data.Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "item_group", attrs["scope_type"])
+	assert.Equal(t, "room-1", attrs["scope_id"])
+	assert.Equal(t, StatusPending, attrs["status"])
+	assert.Equal(t, []any{"desk-2", "desk-1"}, attrs["preferences"])
+
+	c, rec = newUserContext(http.MethodPost, "/api/v1/lottery-requests", createBody(date, `"desk-1"`), "user-1")
+	require.NoError(t, h(c))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestCreateHandlerRejectsInvalidRequests(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	h := newCreateHandler(store)
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "no lottery", body: createBody(lotteryDate(), `"desk-3"`), wantCode: http.StatusBadRequest},
+		{name: "mixed scopes", body: createBody(lotteryDate(), `"desk-1","desk-3"`), wantCode: http.StatusBadRequest},
+		{name: "no target", body: createBody(lotteryDate(), ``), wantCode: http.StatusBadRequest},
+		{
+			name: "after cutoff", body: createBody(today, `"desk-1"`),
+			wantCode: http.StatusConflict, wantErr: ErrorCodeLotteryClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c, rec := newUserContext(http.MethodPost, "/api/v1/lottery-requests", tt.body, "user-1")
+			require.NoError(t, h(c))
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantErr != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeleteHandlerWithdrawsOwnRequest(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	r, err := Create(t.Context(), store, "item_group:room-1", lotteryDate(), "user-1", nil)
+	require.NoError(t, err)
+
+	c, rec := newUserContext(http.MethodDelete, "/api/v1/lottery-requests/"+r.ID, "", "user-2")
+	c.SetParamNames("id")
+	c.SetParamValues(r.ID)
+	require.NoError(t, DeleteHandler(store)(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	c, rec = newUserContext(http.MethodDelete, "/api/v1/lottery-requests/"+r.ID, "", "user-1")
+	c.SetParamNames("id")
+	c.SetParamValues(r.ID)
+	require.NoError(t, DeleteHandler(store)(c))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, StatusWithdrawn, requestStatus(t, store, r.ID).Status)
+}
+
+func TestGuardBlocksBookingsUntilDrawn(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	cfg := testConfig()
+	date := lotteryDate()
+	day, err := time.Parse(time.DateOnly, date)
+	require.NoError(t, err)
+	g := NewGuard(func() *areas.Config { return cfg }, store)
+	loc, _ := cfg.FindItemLocation("desk-1")
+	req := &bookings.GuardRequest{Location: loc, UserID: "user-1", Dates: []string{date}}
+
+	var rej *bookings.Rejection
+	require.True(t, errors.As(g.CheckBooking(t.Context(), req), &rej))
+	assert.Equal(t, ErrorCodeLotteryOpen, rej.Code)
+
+	r, err := Create(t.Context(), store, "item_group:room-1", date, "user-2", nil)
+	require.NoError(t, err)
+	g.now = func() time.Time { return day.Add(-time.Hour) }
+	require.True(t, errors.As(g.CheckBooking(t.Context(), req), &rej))
+	assert.Equal(t, ErrorCodeLotteryPending, rej.Code)
+
+	r.Status = StatusLost
+	require.NoError(t, Resolve(t.Context(), store, r))
+	require.NoError(t, g.CheckBooking(t.Context(), req))
+
+	other, _ := cfg.FindItemLocation("desk-3")
+	g.now = time.Now
+	require.NoError(t, g.CheckBooking(t.Context(), &bookings.GuardRequest{Location: other, Dates: []string{date}}))
+}
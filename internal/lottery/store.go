@@ -0,0 +1,206 @@
+// Package lottery allocates items on oversubscribed days by weighted lottery
+// instead of first come, first served.
+package lottery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+)
+
+// Request statuses.
+const (
+	StatusPending    = "pending"
+	StatusWon        = "won"
+	StatusLost       = "lost"
+	StatusWaitlisted = "waitlisted"
+	StatusWithdrawn  = "withdrawn"
+)
+
+// ErrNotFound indicates the requested lottery request does not exist.
+var ErrNotFound = errors.New("lottery request not found")
+
+// ErrDuplicate indicates the user already requested the scope on that date.
+var ErrDuplicate = errors.New("duplicate lottery request")
+
+// Request represents a lottery_requests row. Preferences lists item IDs in the
+// order the user prefers them. DrawPosition is the rank drawn by the allocator
+// (1 = drawn first) and orders the waitlist.
+type Request struct {
+	ID           string
+	ScopeKey     string
+	BookingDate  string
+	UserID       string
+	Preferences  []string
+	Status       string
+	DrawPosition int
+	ItemID       string
+	BookingID    string
+	CreatedAt    string
+	UpdatedAt    string
+}
+
+const requestColumns = `id, scope_key, booking_date, user_id, preferences, status, draw_position,
+	item_id, booking_id, created_at, updated_at`
+
+// Create inserts a pending request.
+func Create(
+	ctx context.Context, db *sql.DB, scopeKey, bookingDate, userID string, preferences []string,
+) (*Request, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	r := &Request{
+		ID:          uuid.NewString(),
+		ScopeKey:    scopeKey,
+		BookingDate: bookingDate,
+		UserID:      userID,
+		Preferences: preferences,
+		Status:      StatusPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO lottery_requests (`+requestColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.ScopeKey, r.BookingDate, r.UserID, strings.Join(r.Preferences, ","), r.Status, r.DrawPosition,
+		r.ItemID, r.BookingID, r.CreatedAt, r.UpdatedAt,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return nil, ErrDuplicate
+		}
+		return nil, fmt.Errorf("insert lottery request: %w", err)
+	}
+	return r, nil
+}
+
+// FindByID returns a lottery request by ID.
+func FindByID(ctx context.Context, db *sql.DB, id string) (*Request, error) {
+	list, err := query(ctx, db, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	return &list[0], nil
+}
+
+// ListForUser returns a user's requests for dates on or after fromDate.
+func ListForUser(ctx context.Context, db *sql.DB, userID, fromDate string) ([]Request, error) {
+	return query(ctx, db, `WHERE user_id = ? AND booking_date >= ? ORDER BY booking_date, created_at`, userID, fromDate)
+}
+
+// ListByStatus returns requests with the given status for dates on or after
+// fromDate, ordered by scope, date, and draw position.
+func ListByStatus(ctx context.Context, db *sql.DB, status, fromDate string) ([]Request, error) {
+	return query(ctx, db,
+		`WHERE status = ? AND booking_date >= ? ORDER BY scope_key, booking_date, draw_position, created_at`,
+		status, fromDate)
+}
+
+// HasOpen reports whether requests of the scope and date are still undrawn or
+// waiting for a free item.
+func HasOpen(ctx context.Context, db *sql.DB, scopeKey, bookingDate string) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM lottery_requests WHERE scope_key = ? AND booking_date = ? AND status IN (?, ?)`,
+		scopeKey, bookingDate, StatusPending, StatusWaitlisted,
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("count open lottery requests: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CountLosses returns how many lost or waitlisted requests each user had for
+// dates between fromDate and beforeDate (exclusive).
+func CountLosses(
+	ctx context.Context, db *sql.DB, userIDs []string, fromDate, beforeDate string,
+) (counts map[string]int, err error) {
+	counts = make(map[string]int, len(userIDs))
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+	placeholders, args := api.BuildINClause(userIDs)
+	//nolint:gosec // G202: "?" placeholders from BuildINClause
+	q := `SELECT user_id, COUNT(*) FROM lottery_requests
+		WHERE status IN (?, ?) AND booking_date >= ? AND booking_date < ? AND user_id IN (` + placeholders + `)
+		GROUP BY user_id`
+	args = append([]any{StatusLost, StatusWaitlisted, fromDate, beforeDate}, args...)
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("count lottery losses: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close lottery loss rows: %w", closeErr)
+		}
+	}()
+	for rows.Next() {
+		var userID string
+		var n int
+		if err := rows.Scan(&userID, &n); err != nil {
+			return nil, fmt.Errorf("scan lottery losses: %w", err)
+		}
+		counts[userID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate lottery losses: %w", err)
+	}
+	return counts, nil
+}
+
+// Resolve records the outcome of a request.
+func Resolve(ctx context.Context, db *sql.DB, r *Request) error {
+	r.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err := db.ExecContext(ctx,
+		`UPDATE lottery_requests SET status = ?, draw_position = ?, item_id = ?, booking_id = ?, updated_at = ?
+		 WHERE id = ?`,
+		r.Status, r.DrawPosition, r.ItemID, r.BookingID, r.UpdatedAt, r.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update lottery request: %w", err)
+	}
+	return nil
+}
+
+func query(ctx context.Context, db *sql.DB, where string, args ...any) (result []Request, err error) {
+	//nolint:gosec // G202: where clauses are constants with "?" placeholders
+	rows, err := db.QueryContext(ctx, `SELECT `+requestColumns+` FROM lottery_requests `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query lottery requests: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close lottery request rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var r Request
+		var prefs string
+		if err := rows.Scan(
+			&r.ID, &r.ScopeKey, &r.BookingDate, &r.UserID, &prefs, &r.Status, &r.DrawPosition,
+			&r.ItemID, &r.BookingID, &r.CreatedAt, &r.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan lottery request: %w", err)
+		}
+		if prefs != "" {
+			r.Preferences = strings.Split(prefs, ",")
+		}
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate lottery requests: %w", err)
+	}
+	return result, nil
+}
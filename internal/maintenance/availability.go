@@ -0,0 +1,165 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+)
+
+// defaultReason is reported when a window has no reason.
+const defaultReason = "Out of service"
+
+// ErrorCodeUnavailable is the JSON:API error code used when a booking targets an
+// item that is out of service.
+const ErrorCodeUnavailable = "item_unavailable"
+
+// UnavailableDays returns, per item ID, the dates (from dates) on which the item
+// is under maintenance, mapped to the reason. Items without maintenance on any of
+// the dates are omitted.
+func UnavailableDays(
+	ctx context.Context, db *sql.DB, itemIDs, dates []string,
+) (map[string]map[string]string, error) {
+	result := make(map[string]map[string]string)
+	if len(itemIDs) == 0 || len(dates) == 0 {
+		return result, nil
+	}
+
+	sorted := append([]string(nil), dates...)
+	sort.Strings(sorted)
+	windows, err := FindOverlapping(ctx, db, itemIDs, sorted[0], sorted[len(sorted)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range windows {
+		w := &windows[i]
+		for _, date := range dates {
+			if !w.Covers(date) {
+				continue
+			}
+			if result[w.ItemID] == nil {
+				result[w.ItemID] = make(map[string]string)
+			}
+			if _, seen := result[w.ItemID][date]; !seen {
+				result[w.ItemID][date] = reasonOf(w)
+			}
+		}
+	}
+	return result, nil
+}
+
+func reasonOf(w *Window) string {
+	if reason := strings.TrimSpace(w.Reason); reason != "" {
+		return reason
+	}
+	return defaultReason
+}
+
+// Guard rejects bookings for items that are under maintenance on a requested date.
+type Guard struct {
+	store *sql.DB
+}
+
+// NewGuard creates a booking guard backed by the item_maintenance table.
+func NewGuard(store *sql.DB) *Guard {
+	return &Guard{store: store}
+}
+
+// CheckBooking implements bookings.Guard.
+func (g *Guard) CheckBooking(ctx context.Context, req *bookings.GuardRequest) error {
+	item := req.Location.Item
+	unavailable, err := UnavailableDays(ctx, g.store, []string{item.ID}, req.Dates)
+	if err != nil {
+		return fmt.Errorf("check maintenance: %w", err)
+	}
+	days := unavailable[item.ID]
+	if len(days) == 0 {
+		return nil
+	}
+
+	list := make([]string, 0, len(days))
+	for _, date := range req.Dates {
+		if reason, ok := days[date]; ok {
+			list = append(list, fmt.Sprintf("%s (%s)", date, reason))
+		}
+	}
+	return &bookings.Rejection{
+		Status: http.StatusConflict,
+		Code:   ErrorCodeUnavailable,
+		Detail: fmt.Sprintf("%q is out of service on %s", item.Name, strings.Join(list, ", ")),
+	}
+}
+
+// FindAlternative returns a free item on date that could replace itemID,
+// preferring the same item group, then the rest of the area. Items that are
+// booked, under maintenance, or reserved for someone other than userEmail are
+// skipped. Returns nil if there is no alternative.
+func FindAlternative(
+	ctx context.Context, db *sql.DB, cfg *areas.Config, itemID, date, userEmail string,
+) (*areas.Item, error) {
+	loc, ok := cfg.FindItemLocation(itemID)
+	if !ok {
+		return nil, nil
+	}
+
+	candidates := alternativeCandidates(loc, itemID)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	booked, err := bookings.FindBookedItemIDs(ctx, db, date)
+	if err != nil {
+		return nil, fmt.Errorf("find booked items: %w", err)
+	}
+	ids := make([]string, len(candidates))
+	for i := range candidates {
+		ids[i] = candidates[i].Item.ID
+	}
+	unavailable, err := UnavailableDays(ctx, db, ids, []string{date})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		cand := &candidates[i]
+		if _, taken := booked[cand.Item.ID]; taken {
+			continue
+		}
+		if _, down := unavailable[cand.Item.ID]; down {
+			continue
+		}
+		if areas.IsReserved(cand, userEmail) {
+			continue
+		}
+		return cand.Item, nil
+	}
+	return nil, nil
+}
+
+// alternativeCandidates lists the items of loc's area except itemID, starting
+// with the items of the same item group.
+func alternativeCandidates(loc *areas.ItemLocation, itemID string) []areas.ItemLocation {
+	var same, other []areas.ItemLocation
+	for i := range loc.Area.ItemGroups {
+		ig := &loc.Area.ItemGroups[i]
+		for j := range ig.Items {
+			item := &ig.Items[j]
+			if item.ID == itemID {
+				continue
+			}
+			cand := areas.ItemLocation{Area: loc.Area, ItemGroup: ig, Item: item}
+			if ig.ID == loc.ItemGroup.ID {
+				same = append(same, cand)
+			} else {
+				other = append(other, cand)
+			}
+		}
+	}
+	return append(same, other...)
+}
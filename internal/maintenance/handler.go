@@ -0,0 +1,341 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const resourceType = "maintenance-windows"
+
+// Attributes represents maintenance window resource attributes.
+type Attributes struct {
+	ItemID    string `json:"item_id"`
+	ItemName  string `json:"item_name,omitempty"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Reason    string `json:"reason,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	// NotifiedBookings is the number of existing bookings whose owners were
+	// notified. Only set in create and update responses.
+	NotifiedBookings *int `json:"notified_bookings,omitempty"`
+}
+
+type createRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			ItemID    string `json:"item_id"`
+			StartDate string `json:"start_date"`
+			EndDate   string `json:"end_date"`
+			Reason    string `json:"reason"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type updateRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			StartDate *string `json:"start_date"`
+			EndDate   *string `json:"end_date"`
+			Reason    *string `json:"reason"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns maintenance windows.
+// GET /api/v1/maintenance-windows?item_id=<id>&from=<date>&to=<date>
+// Defaults to windows from today until one year ahead.
+func ListHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := getConfig()
+		earliest, _ := cfg.TodayRange(time.Now())
+		today, _ := time.Parse(time.DateOnly, earliest)
+		fromDate := c.QueryParam("from")
+		toDate := c.QueryParam("to")
+		if fromDate == "" {
+			fromDate = today.Format(time.DateOnly)
+		}
+		if toDate == "" {
+			toDate = today.AddDate(1, 0, 0).Format(time.DateOnly)
+		}
+		if !isDate(fromDate) || !isDate(toDate) {
+			return api.WriteBadRequest(c, "Invalid 'from' or 'to' date. Use YYYY-MM-DD format.")
+		}
+
+		var itemIDs []string
+		if itemID := c.QueryParam("item_id"); itemID != "" {
+			itemIDs = []string{itemID}
+		}
+
+		windows, err := FindOverlapping(c.Request().Context(), store, itemIDs, fromDate, toDate)
+		if err != nil {
+			return api.WriteInternalError(c, "list maintenance windows", err)
+		}
+
+		resources := api.MapResources(windows, func(w Window) api.Resource {
+			return toResource(cfg, &w, nil)
+		})
+		return api.WriteCollection(c, resources, "write maintenance windows response")
+	}
+}
+
+// CreateHandler creates a maintenance window. Owners of existing bookings for the
+// item inside the window are notified, with an alternative item if one is free.
+// POST /api/v1/maintenance-windows
+func CreateHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+
+		var req createRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceType {
+			return api.WriteBadRequest(c, "Resource type must be 'maintenance-windows'")
+		}
+
+		a := req.Data.Attributes
+		in := &CreateInput{
+			ItemID:          strings.TrimSpace(a.ItemID),
+			StartDate:       strings.TrimSpace(a.StartDate),
+			EndDate:         strings.TrimSpace(a.EndDate),
+			Reason:          strings.TrimSpace(a.Reason),
+			CreatedByUserID: user.ID,
+		}
+		if in.EndDate == "" {
+			in.EndDate = in.StartDate
+		}
+
+		cfg := getConfig()
+		if _, ok := cfg.FindItem(in.ItemID); !ok {
+			return api.WriteBadRequest(c, "item_id: item not found")
+		}
+		if detail := validateRange(in.StartDate, in.EndDate); detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+
+		ctx := c.Request().Context()
+		w, err := Create(ctx, store, in)
+		if err != nil {
+			return api.WriteInternalError(c, "create maintenance window", err)
+		}
+
+		notified, err := notifyAffected(ctx, store, cfg, notifier, w, nil)
+		if err != nil {
+			return api.WriteInternalError(c, "notify affected bookings", err)
+		}
+
+		slog.Info("maintenance window created",
+			"window_id", w.ID,
+			"item_id", w.ItemID,
+			"start_date", w.StartDate,
+			"end_date", w.EndDate,
+			"created_by", user.ID,
+			"notified_bookings", notified,
+		)
+		resource := toResource(cfg, w, &notified)
+		return api.WriteSingle(c, http.StatusCreated, resource, "write maintenance window response")
+	}
+}
+
+// UpdateHandler changes the dates or reason of a maintenance window. Owners of
+// bookings that newly fall inside the window are notified.
+// PATCH /api/v1/maintenance-windows/:id
+func UpdateHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req updateRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceType {
+			return api.WriteBadRequest(c, "Resource type must be 'maintenance-windows'")
+		}
+
+		ctx := c.Request().Context()
+		before, err := FindByID(ctx, store, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return api.WriteNotFound(c, "Maintenance window not found")
+			}
+			return api.WriteInternalError(c, "find maintenance window", err)
+		}
+
+		a := req.Data.Attributes
+		a.StartDate, a.EndDate, a.Reason = trimmed(a.StartDate), trimmed(a.EndDate), trimmed(a.Reason)
+		start, end := before.StartDate, before.EndDate
+		if a.StartDate != nil {
+			start = *a.StartDate
+		}
+		if a.EndDate != nil {
+			end = *a.EndDate
+		}
+		if detail := validateRange(start, end); detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+
+		w, err := Update(ctx, store, before.ID, UpdateInput{
+			StartDate: a.StartDate,
+			EndDate:   a.EndDate,
+			Reason:    a.Reason,
+		})
+		if err != nil {
+			return api.WriteInternalError(c, "update maintenance window", err)
+		}
+
+		cfg := getConfig()
+		notified, err := notifyAffected(ctx, store, cfg, notifier, w, before)
+		if err != nil {
+			return api.WriteInternalError(c, "notify affected bookings", err)
+		}
+		resource := toResource(cfg, w, &notified)
+		return api.WriteSingle(c, http.StatusOK, resource, "write maintenance window response")
+	}
+}
+
+// DeleteHandler removes a maintenance window, making the item bookable again.
+// DELETE /api/v1/maintenance-windows/:id
+func DeleteHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := Delete(c.Request().Context(), store, c.Param("id")); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return api.WriteNotFound(c, "Maintenance window not found")
+			}
+			return api.WriteInternalError(c, "delete maintenance window", err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// trimmed returns s without surrounding white space, or nil if s is nil.
+func trimmed(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	return &t
+}
+
+func validateRange(startDate, endDate string) string {
+	if !isDate(startDate) || !isDate(endDate) {
+		return "start_date and end_date must be in YYYY-MM-DD format"
+	}
+	if endDate < startDate {
+		return "end_date must not be before start_date"
+	}
+	return ""
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse(time.DateOnly, s)
+	return err == nil
+}
+
+// notifyAffected sends an item-unavailable event for every upcoming booking of
+// the item inside w. Bookings already covered by previous (the window before an
+// update) were notified before and are skipped. Returns the number of events sent.
+func notifyAffected(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, notifier notifications.Notifier,
+	w, previous *Window,
+) (int, error) {
+	from := w.StartDate
+	if today := areas.LocalDate(time.Now(), cfg.ItemZone(w.ItemID)); from < today {
+		from = today
+	}
+	if from > w.EndDate {
+		return 0, nil
+	}
+
+	records, err := bookings.ListBookingsInRange(ctx, store, []string{w.ItemID}, from, w.EndDate)
+	if err != nil {
+		return 0, fmt.Errorf("list affected bookings: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	notified := 0
+	for i := range records {
+		rec := &records[i]
+		if previous != nil && previous.Covers(rec.BookingDate) {
+			continue
+		}
+		event := &notifications.BookingEvent{
+			Event:          notifications.EventBookingItemUnavailable,
+			BookingID:      rec.ID,
+			ItemID:         rec.ItemID,
+			UserID:         rec.UserID,
+			BookingDate:    rec.BookingDate,
+			IsGuest:        rec.IsGuest,
+			GuestName:      rec.GuestName,
+			GuestEmail:     rec.GuestEmail,
+			BookedByUserID: rec.BookedByUserID,
+			Reason:         reasonOf(w),
+			Timestamp:      now,
+		}
+		alt, err := FindAlternative(ctx, store, cfg, rec.ItemID, rec.BookingDate, bookingOwnerEmail(ctx, store, rec))
+		if err != nil {
+			return notified, err
+		}
+		if alt != nil {
+			event.AlternativeItemID = alt.ID
+			event.AlternativeItemName = alt.Name
+		}
+		notifier.NotifyAsync(event)
+		notified++
+	}
+	return notified, nil
+}
+
+// bookingOwnerEmail returns the email used for reservation checks: the booked
+// user's, or the booker's for guest bookings. Lookup failures yield "", which
+// only excludes reserved items from the suggestions.
+func bookingOwnerEmail(ctx context.Context, store *sql.DB, rec *bookings.BookingRecord) string {
+	userID := rec.UserID
+	if rec.IsGuest && rec.BookedByUserID != "" {
+		userID = rec.BookedByUserID
+	}
+	u, err := users.FindByID(ctx, store, userID)
+	if err != nil || u == nil {
+		return ""
+	}
+	return u.Email
+}
+
+func toResource(cfg *areas.Config, w *Window, notified *int) api.Resource {
+	attrs := Attributes{
+		ItemID:           w.ItemID,
+		StartDate:        w.StartDate,
+		EndDate:          w.EndDate,
+		Reason:           w.Reason,
+		CreatedAt:        w.CreatedAt,
+		UpdatedAt:        w.UpdatedAt,
+		NotifiedBookings: notified,
+	}
+	if item, ok := cfg.FindItem(w.ItemID); ok {
+		attrs.ItemName = item.Name
+	}
+	return api.Resource{Type: resourceType, ID: w.ID, Attributes: attrs}
+}
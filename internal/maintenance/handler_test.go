@@ -0,0 +1,266 @@
+package maintenance
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/db"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*notifications.BookingEvent
+}
+
+func (n *recordingNotifier) NotifyAsync(event *notifications.BookingEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+}
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+	return store
+}
+
+func seedBooking(t *testing.T, store *sql.DB, id, itemID, userID, date string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.Exec(`
+		INSERT INTO bookings (id, item_id, user_id, booked_by_user_id, booking_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, id, itemID, userID, userID, date, now, now)
+	require.NoError(t, err)
+}
+
+func testConfig() *areas.Config {
+	return &areas.Config{
+		Areas: []areas.Area{
+			{
+				ID:   "office",
+				Name: "Office",
+				ItemGroups: []areas.ItemGroup{
+					{
+						ID:   "room-1",
+						Name: "Room 1",
+						Items: []areas.Item{
+							{ID: "desk-1", Name: "Desk 1"},
+							{ID: "desk-2", Name: "Desk 2"},
+							{ID: "desk-3", Name: "Desk 3", ReservedFor: []string{"boss@example.com"}},
+						},
+					},
+					{ID: "room-2", Name: "Room 2", Items: []areas.Item{{ID: "desk-4", Name: "Desk 4"}}},
+				},
+			},
+		},
+	}
+}
+
+func getTestConfig() *areas.Config { return testConfig() }
+
+func daysFromNow(n int) string {
+	return time.Now().UTC().AddDate(0, 0, n).Format(time.DateOnly)
+}
+
+func newAdminContext(method, target, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "admin-1", IsAdmin: true})
+	return c, rec
+}
+
+func createBody(itemID, start, end, reason string) string {
+	return `{"data":{"type":"maintenance-windows","attributes":{"item_id":"` + itemID +
+		`","start_date":"` + start + `","end_date":"` + end + `","reason":"` + reason + `"}}}`
+}
+
+func TestCreateHandlerNotifiesAffectedBookingsWithAlternative(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	day1, day2 := daysFromNow(1), daysFromNow(2)
+	seedBooking(t, store, "b1", "desk-1", "user-1", day1)
+	seedBooking(t, store, "b2", "desk-1", "user-2", day2)
+	seedBooking(t, store, "b3", "desk-2", "user-3", day2)
+
+	notifier := &recordingNotifier{}
+	c, rec := newAdminContext(http.MethodPost, "/api/v1/maintenance-windows",
+		createBody("desk-1", day1, day2, "Broken monitor"))
+	require.NoError(t, CreateHandler(getTestConfig, store, notifier)(c))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	attrs, ok := resp.Data.Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "Desk 1", attrs["item_name"])
+	assert.Equal(t, float64(2), attrs["notified_bookings"])
+
+	require.Len(t, notifier.events, 2)
+	first, second := notifier.events[0], notifier.events[1]
+	assert.Equal(t, notifications.EventBookingItemUnavailable, first.Event)
+	assert.Equal(t, "Broken monitor", first.Reason)
+	assert.Equal(t, "desk-2", first.AlternativeItemID)
+	// desk-2 is booked on day2 and desk-3 is reserved, so the next free item is in room-2.
+	assert.Equal(t, "desk-4", second.AlternativeItemID)
+	assert.Equal(t, "Desk 4", second.AlternativeItemName)
+
+	// Bookings are kept.
+	remaining, err := bookings.ListBookingsInRange(t.Context(), store, []string{"desk-1"}, day1, day2)
+	require.NoError(t, err)
+	assert.Len(t, remaining, 2)
+}
+
+func TestCreateHandlerValidation(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"unknown item", createBody("nope", "2026-11-02", "2026-11-03", "")},
+		{"invalid date", createBody("desk-1", "02.11.2026", "", "")},
+		{"end before start", createBody("desk-1", "2026-11-03", "2026-11-02", "")},
+		{"wrong type", `{"data":{"type":"closures","attributes":{"item_id":"desk-1","start_date":"2026-11-02"}}}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			c, rec := newAdminContext(http.MethodPost, "/api/v1/maintenance-windows", tc.body)
+			require.NoError(t, CreateHandler(getTestConfig, store, &recordingNotifier{})(c))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestUpdateHandlerNotifiesOnlyNewlyCoveredBookings(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	day1, day3 := daysFromNow(1), daysFromNow(3)
+	seedBooking(t, store, "b1", "desk-1", "user-1", day1)
+	seedBooking(t, store, "b2", "desk-1", "user-2", day3)
+
+	w, err := Create(t.Context(), store, &CreateInput{ItemID: "desk-1", StartDate: day1, EndDate: day1})
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	body := `{"data":{"type":"maintenance-windows","attributes":{"end_date":"` + day3 + `"}}}`
+	c, rec := newAdminContext(http.MethodPatch, "/api/v1/maintenance-windows/"+w.ID, body)
+	c.SetParamNames("id")
+	c.SetParamValues(w.ID)
+	require.NoError(t, UpdateHandler(getTestConfig, store, notifier)(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, "b2", notifier.events[0].BookingID)
+	assert.Equal(t, defaultReason, notifier.events[0].Reason)
+}
+
+func TestUpdateHandlerValidatesRequest(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	day1, day2 := daysFromNow(1), daysFromNow(2)
+	w, err := Create(t.Context(), store, &CreateInput{ItemID: "desk-1", StartDate: day1, EndDate: day1})
+	require.NoError(t, err)
+
+	patch := func(body string) int {
+		c, rec := newAdminContext(http.MethodPatch, "/api/v1/maintenance-windows/"+w.ID, body)
+		c.SetParamNames("id")
+		c.SetParamValues(w.ID)
+		require.NoError(t, UpdateHandler(getTestConfig, store, &recordingNotifier{})(c))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusBadRequest,
+		patch(`{"data":{"type":"bookings","attributes":{"end_date":"`+day2+`"}}}`))
+	assert.Equal(t, http.StatusOK,
+		patch(`{"data":{"type":"maintenance-windows","attributes":{"end_date":" `+day2+` "}}}`))
+
+	updated, err := FindByID(t.Context(), store, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, day2, updated.EndDate)
+}
+
+func TestDeleteHandlerNotFound(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+
+	c, rec := newAdminContext(http.MethodDelete, "/api/v1/maintenance-windows/missing", "")
+	c.SetParamNames("id")
+	c.SetParamValues("missing")
+	require.NoError(t, DeleteHandler(store)(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestListHandlerFiltersByItem(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	_, err := Create(t.Context(), store,
+		&CreateInput{ItemID: "desk-1", StartDate: daysFromNow(1), EndDate: daysFromNow(2)})
+	require.NoError(t, err)
+	_, err = Create(t.Context(), store,
+		&CreateInput{ItemID: "desk-2", StartDate: daysFromNow(1), EndDate: daysFromNow(1)})
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/maintenance-windows?item_id=desk-2", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	require.NoError(t, ListHandler(getTestConfig, store)(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, resourceType, resp.Data[0].Type)
+}
+
+func TestGuardRejectsItemsUnderMaintenance(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	cfg := testConfig()
+	_, err := Create(t.Context(), store, &CreateInput{
+		ItemID: "desk-1", StartDate: "2026-11-02", EndDate: "2026-11-06", Reason: "Construction",
+	})
+	require.NoError(t, err)
+
+	guard := NewGuard(store)
+	loc, ok := cfg.FindItemLocation("desk-1")
+	require.True(t, ok)
+
+	err = guard.CheckBooking(t.Context(), &bookings.GuardRequest{
+		Location: loc, UserID: "user-1", Dates: []string{"2026-11-01", "2026-11-02"},
+	})
+	var rejection *bookings.Rejection
+	require.ErrorAs(t, err, &rejection)
+	assert.Equal(t, ErrorCodeUnavailable, rejection.Code)
+	assert.Equal(t, `"Desk 1" is out of service on 2026-11-02 (Construction)`, rejection.Detail)
+
+	other, ok := cfg.FindItemLocation("desk-2")
+	require.True(t, ok)
+	require.NoError(t, guard.CheckBooking(t.Context(), &bookings.GuardRequest{
+		Location: other, UserID: "user-1", Dates: []string{"2026-11-02"},
+	}))
+}
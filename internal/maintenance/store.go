@@ -0,0 +1,172 @@
+// Package maintenance manages out-of-service windows for individual items.
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+)
+
+// ErrNotFound indicates the requested maintenance window does not exist.
+var ErrNotFound = errors.New("maintenance window not found")
+
+// Window represents an item_maintenance row. StartDate and EndDate are inclusive.
+type Window struct {
+	ID              string
+	ItemID          string
+	StartDate       string
+	EndDate         string
+	Reason          string
+	CreatedByUserID string
+	CreatedAt       string
+	UpdatedAt       string
+}
+
+// Covers reports whether the window includes the given YYYY-MM-DD date.
+func (w *Window) Covers(date string) bool {
+	return date >= w.StartDate && date <= w.EndDate
+}
+
+// CreateInput holds fields for creating a maintenance window.
+type CreateInput struct {
+	ItemID          string
+	StartDate       string
+	EndDate         string
+	Reason          string
+	CreatedByUserID string
+}
+
+// UpdateInput holds optional fields for updating a maintenance window.
+type UpdateInput struct {
+	StartDate *string
+	EndDate   *string
+	Reason    *string
+}
+
+const windowColumns = `id, item_id, start_date, end_date, reason, created_by_user_id, created_at, updated_at`
+
+// Create inserts a new maintenance window.
+func Create(ctx context.Context, db *sql.DB, in *CreateInput) (*Window, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	w := &Window{
+		ID:              uuid.NewString(),
+		ItemID:          in.ItemID,
+		StartDate:       in.StartDate,
+		EndDate:         in.EndDate,
+		Reason:          in.Reason,
+		CreatedByUserID: in.CreatedByUserID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO item_maintenance (`+windowColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		w.ID, w.ItemID, w.StartDate, w.EndDate, w.Reason, w.CreatedByUserID, w.CreatedAt, w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert maintenance window: %w", err)
+	}
+	return w, nil
+}
+
+// FindByID returns a maintenance window by ID.
+func FindByID(ctx context.Context, db *sql.DB, id string) (*Window, error) {
+	var w Window
+	err := db.QueryRowContext(ctx,
+		`SELECT `+windowColumns+` FROM item_maintenance WHERE id = ?`, id,
+	).Scan(&w.ID, &w.ItemID, &w.StartDate, &w.EndDate, &w.Reason, &w.CreatedByUserID, &w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find maintenance window: %w", err)
+	}
+	return &w, nil
+}
+
+// Update applies non-nil fields of in to the window and returns the result.
+func Update(ctx context.Context, db *sql.DB, id string, in UpdateInput) (*Window, error) {
+	w, err := FindByID(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.StartDate != nil {
+		w.StartDate = *in.StartDate
+	}
+	if in.EndDate != nil {
+		w.EndDate = *in.EndDate
+	}
+	if in.Reason != nil {
+		w.Reason = *in.Reason
+	}
+	w.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	_, err = db.ExecContext(ctx,
+		`UPDATE item_maintenance SET start_date = ?, end_date = ?, reason = ?, updated_at = ? WHERE id = ?`,
+		w.StartDate, w.EndDate, w.Reason, w.UpdatedAt, w.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("update maintenance window: %w", err)
+	}
+	return w, nil
+}
+
+// FindOverlapping returns windows overlapping [fromDate, toDate], ordered by
+// start date. If itemIDs is empty, windows for all items are returned.
+func FindOverlapping(
+	ctx context.Context, db *sql.DB, itemIDs []string, fromDate, toDate string,
+) (result []Window, err error) {
+	query := `SELECT ` + windowColumns + ` FROM item_maintenance WHERE end_date >= ? AND start_date <= ?`
+	args := []any{fromDate, toDate}
+	if len(itemIDs) > 0 {
+		placeholders, inArgs := api.BuildINClause(itemIDs)
+		query += ` AND item_id IN (` + placeholders + `)` //nolint:gosec // G202: "?" placeholders from BuildINClause
+		args = append(args, inArgs...)
+	}
+	query += ` ORDER BY start_date, item_id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query maintenance windows: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close maintenance rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var w Window
+		if err := rows.Scan(
+			&w.ID, &w.ItemID, &w.StartDate, &w.EndDate, &w.Reason, &w.CreatedByUserID, &w.CreatedAt, &w.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan maintenance window: %w", err)
+		}
+		result = append(result, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate maintenance windows: %w", err)
+	}
+	return result, nil
+}
+
+// Delete removes a maintenance window by ID.
+func Delete(ctx context.Context, db *sql.DB, id string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM item_maintenance WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete maintenance window: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete maintenance window rows: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
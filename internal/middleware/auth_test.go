@@ -126,7 +126,8 @@ func setupMiddlewareTestDB(t *testing.T) *sql.DB {
 			last_login TEXT NOT NULL DEFAULT '',
 			access_token TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
-			updated_at TEXT NOT NULL
+			updated_at TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1
 		);
 		CREATE UNIQUE INDEX idx_users_email ON users(email);
 	`)
@@ -19,8 +19,48 @@ const (
 	EventBookingCreated EventType = "booking.created"
 	// EventBookingCanceled is sent when a booking is canceled.
 	EventBookingCanceled EventType = "booking.canceled"
+	// EventBookingConfirmed is sent when a user confirms a booking that was
+	// made for them outside a delegation. BookedByUserID is the booker.
+	EventBookingConfirmed EventType = "booking.confirmed"
+	// EventBookingItemUnavailable is sent when a booked item goes out of service.
+	// The booking is kept; the event may suggest an alternative item.
+	EventBookingItemUnavailable EventType = "booking.item_unavailable"
+	// EventTeamBookingCreated summarizes a team booking. BookingIDs lists the
+	// individual bookings, which are also sent as booking.created events.
+	EventTeamBookingCreated EventType = "team_booking.created"
+	// EventLotteryWon is sent when a lottery request was drawn and booked.
+	// The booking is also sent as a booking.created event.
+	EventLotteryWon EventType = "lottery.won"
+	// EventLotteryLost is sent when a lottery request got no item.
+	EventLotteryLost EventType = "lottery.lost"
+	// EventLotteryWaitlisted is sent when a lottery request got no item but
+	// stays on the waitlist.
+	EventLotteryWaitlisted EventType = "lottery.waitlisted"
+	// EventVisitorArrived is sent to the host when their visitor checks in.
+	EventVisitorArrived EventType = "visitor.arrived"
+	// EventSubscriptionDigest sends a subscriber the bookings matching their
+	// subscriptions since the last digest. UserID is the subscriber.
+	EventSubscriptionDigest EventType = "subscription.digest"
 )
 
+// DigestEntry is a booking change in a subscription digest. It only holds
+// what any signed-in user sees on the booking views: no booking ID, guest
+// email, note, or custom field values. UserID is empty for guest bookings.
+type DigestEntry struct {
+	Event          EventType `json:"event"`
+	SubscriptionID string    `json:"subscription_id"`
+	BookingDate    string    `json:"booking_date"`
+	ItemID         string    `json:"item_id"`
+	ItemName       string    `json:"item_name,omitempty"`
+	ItemGroupID    string    `json:"item_group_id,omitempty"`
+	ItemGroupName  string    `json:"item_group_name,omitempty"`
+	AreaID         string    `json:"area_id,omitempty"`
+	AreaName       string    `json:"area_name,omitempty"`
+	UserID         string    `json:"user_id,omitempty"`
+	UserName       string    `json:"user_name"`
+	Timestamp      string    `json:"timestamp"`
+}
+
 // BookingEvent represents a notification payload for booking events.
 type BookingEvent struct {
 	Event       EventType `json:"event"`
@@ -33,12 +73,62 @@ type BookingEvent struct {
 	GuestEmail  string    `json:"guest_email,omitempty"`
 	// BookedByUserID is set when booking was made on behalf of someone.
 	BookedByUserID string `json:"booked_by_user_id,omitempty"`
+	// PendingConfirmation is set when a booking made on behalf of UserID waits
+	// for their confirmation.
+	PendingConfirmation bool `json:"pending_confirmation,omitempty"`
 	// CanceledByUserID is set when a booking is canceled.
 	CanceledByUserID string `json:"canceled_by_user_id,omitempty"`
+	// Fields holds the custom field values of the booking, keyed by field ID.
+	Fields map[string]any `json:"fields,omitempty"`
+	// Reason explains system-initiated events, e.g. an office closure or item maintenance.
+	Reason string `json:"reason,omitempty"`
+	// AlternativeItemID and AlternativeItemName suggest a free item when the
+	// booked item is unavailable.
+	AlternativeItemID   string `json:"alternative_item_id,omitempty"`
+	AlternativeItemName string `json:"alternative_item_name,omitempty"`
+	// BundleID links the events of bookings created together in a bundle.
+	BundleID string `json:"bundle_id,omitempty"`
+	// TeamBookingID links the events of a team booking.
+	TeamBookingID string `json:"team_booking_id,omitempty"`
+	// BookingIDs, BookingDates, and MemberUserIDs describe a team booking summary.
+	BookingIDs    []string `json:"booking_ids,omitempty"`
+	BookingDates  []string `json:"booking_dates,omitempty"`
+	MemberUserIDs []string `json:"member_user_ids,omitempty"`
+	// LotteryRequestID links lottery events to the user's request.
+	LotteryRequestID string `json:"lottery_request_id,omitempty"`
+	// VisitID and GuestCompany describe a visitor check-in; UserID is the host.
+	VisitID      string `json:"visit_id,omitempty"`
+	GuestCompany string `json:"guest_company,omitempty"`
+	// Digest lists the booking changes of a subscription digest.
+	Digest []DigestEntry `json:"digest,omitempty"`
+	// Private marks the booking.created and booking.canceled events of a
+	// private booking, whose booked user only admins, delegates, and the
+	// people involved may see. Personal notices to the booked user are not
+	// marked.
+	Private bool `json:"private,omitempty"`
 	// Timestamp is when the event occurred.
 	Timestamp string `json:"timestamp"`
 }
 
+// WithoutBooker returns a copy of the event of a private booking without the
+// users involved, guest details, and custom field values. Other events are
+// returned unchanged.
+func (e *BookingEvent) WithoutBooker() *BookingEvent {
+	if !e.Private {
+		return e
+	}
+	redacted := *e
+	redacted.UserID = ""
+	redacted.GuestName = ""
+	redacted.GuestEmail = ""
+	redacted.GuestCompany = ""
+	redacted.BookedByUserID = ""
+	redacted.CanceledByUserID = ""
+	redacted.MemberUserIDs = nil
+	redacted.Fields = nil
+	return &redacted
+}
+
 // Notifier sends booking notifications.
 type Notifier interface {
 	// NotifyAsync sends a notification asynchronously.
@@ -90,7 +180,7 @@ func (n *WebhookNotifier) send(event *BookingEvent) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	body, err := json.Marshal(*event)
+	body, err := json.Marshal(*event.WithoutBooker())
 	if err != nil {
 		return fmt.Errorf("marshal event: %w", err)
 	}
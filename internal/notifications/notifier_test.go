@@ -113,6 +113,44 @@ func TestWebhookNotifierSendsCancelEvent(t *testing.T) {
 	assert.Equal(t, "admin-1", receivedEvent.CanceledByUserID)
 }
 
+func TestWebhookNotifierHidesPrivateBooker(t *testing.T) {
+	t.Parallel()
+
+	var receivedEvent BookingEvent
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer wg.Done()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&receivedEvent))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	event := BookingEvent{
+		Event:          EventBookingCreated,
+		BookingID:      "booking-123",
+		ItemID:         "desk-1",
+		UserID:         "user-1",
+		BookedByUserID: "user-2",
+		BookingDate:    "2026-01-20",
+		Fields:         map[string]any{"plate": "B-XY 12"},
+		Private:        true,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	notifier.NotifyAsync(&event)
+	wg.Wait()
+
+	assert.True(t, receivedEvent.Private)
+	assert.Equal(t, "desk-1", receivedEvent.ItemID)
+	assert.Empty(t, receivedEvent.UserID)
+	assert.Empty(t, receivedEvent.BookedByUserID)
+	assert.Empty(t, receivedEvent.Fields)
+	assert.Equal(t, "user-1", event.UserID, "the original event is kept for other notifiers")
+}
+
 func TestWebhookNotifierHandlesServerError(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,161 @@
+package orphans
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const (
+	resourceTypeBooking  = "orphaned-bookings"
+	resourceTypePosition = "orphaned-floor-plan-positions"
+	// cancelReason is sent with the notifications of canceled bookings.
+	cancelReason = "The booked item was removed"
+)
+
+// BookingAttributes represents orphaned booking resource attributes.
+type BookingAttributes struct {
+	ItemID      string `json:"item_id"`
+	UserID      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	BookingDate string `json:"booking_date"`
+	IsGuest     bool   `json:"is_guest,omitempty"`
+	GuestName   string `json:"guest_name,omitempty"`
+}
+
+// PositionAttributes represents orphaned floor plan position resource attributes.
+type PositionAttributes struct {
+	ItemID    string `json:"item_id"`
+	FloorPlan string `json:"floor_plan"`
+	Label     string `json:"label,omitempty"`
+}
+
+// BookingsHandler returns the bookings of items missing from the areas
+// config, from today on unless from is given.
+// GET /api/v1/admin/orphaned-bookings?from=<date>
+func BookingsHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := getConfig()
+		fromDate := c.QueryParam("from")
+		if fromDate == "" {
+			fromDate, _ = cfg.TodayRange(time.Now())
+		} else if _, err := time.Parse(time.DateOnly, fromDate); err != nil {
+			return api.WriteBadRequest(c, "Invalid 'from' date. Use YYYY-MM-DD format.")
+		}
+		ctx := c.Request().Context()
+		records, err := FindBookings(ctx, store, cfg, fromDate)
+		if err != nil {
+			return api.WriteInternalError(c, "find orphaned bookings", err)
+		}
+		return writeBookings(c, store, records)
+	}
+}
+
+// PositionsHandler returns the floor plan positions of items missing from the
+// areas config. They are removed through the floor plan position endpoints.
+// GET /api/v1/admin/orphaned-floor-plan-positions
+func PositionsHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		positions, err := FindPositions(c.Request().Context(), store, getConfig())
+		if err != nil {
+			return api.WriteInternalError(c, "find orphaned floor plan positions", err)
+		}
+		return api.WriteCollection(c, api.MapResources(positions, func(p Position) api.Resource {
+			return api.Resource{
+				Type: resourceTypePosition,
+				ID:   p.ID,
+				Attributes: PositionAttributes{
+					ItemID:    p.ItemID,
+					FloorPlan: p.FloorPlan,
+					Label:     p.Label,
+				},
+			}
+		}), "write orphaned floor plan positions response")
+	}
+}
+
+// CancelHandler cancels the bookings from today on of items that were removed
+// from the areas config and notifies the booked users. item_id limits it to
+// the given items (comma-separated); renamed items should be remapped
+// instead. It returns the canceled bookings.
+// POST /api/v1/admin/orphaned-bookings/cancel?item_id=<ids>
+func CancelHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		cfg := getConfig()
+		only := make(map[string]bool)
+		for _, itemID := range strings.Split(c.QueryParam("item_id"), ",") {
+			itemID = strings.TrimSpace(itemID)
+			if itemID == "" {
+				continue
+			}
+			if _, ok := cfg.FindItem(itemID); ok {
+				return api.WriteBadRequest(c, fmt.Sprintf("Item %q is in the areas config", itemID))
+			}
+			only[itemID] = true
+		}
+
+		ctx := c.Request().Context()
+		today, _ := cfg.TodayRange(time.Now())
+		records, err := FindBookings(ctx, store, cfg, today)
+		if err != nil {
+			return api.WriteInternalError(c, "find orphaned bookings", err)
+		}
+		if len(only) > 0 {
+			selected := records[:0]
+			for i := range records {
+				if only[records[i].ItemID] {
+					selected = append(selected, records[i])
+				}
+			}
+			records = selected
+		}
+		if err := bookings.CancelBookings(ctx, store, notifier, records, user.ID, cancelReason); err != nil {
+			return api.WriteInternalError(c, "cancel orphaned bookings", err)
+		}
+		slog.Info("orphaned bookings canceled", "canceled_by", user.ID, "bookings", len(records))
+		return writeBookings(c, store, records)
+	}
+}
+
+func writeBookings(c echo.Context, store *sql.DB, records []bookings.BookingRecord) error {
+	userIDs := make([]string, 0, len(records))
+	for i := range records {
+		userIDs = append(userIDs, records[i].UserID)
+	}
+	names, err := users.FindDisplayNames(c.Request().Context(), store, userIDs)
+	if err != nil {
+		slog.Warn("failed to look up display names", "error", err)
+		names = map[string]string{}
+	}
+	return api.WriteCollection(c, api.MapResources(records, func(rec bookings.BookingRecord) api.Resource {
+		return api.Resource{
+			Type: resourceTypeBooking,
+			ID:   rec.ID,
+			Attributes: BookingAttributes{
+				ItemID:      rec.ItemID,
+				UserID:      rec.UserID,
+				UserName:    names[rec.UserID],
+				BookingDate: rec.BookingDate,
+				IsGuest:     rec.IsGuest,
+				GuestName:   rec.GuestName,
+			},
+		}
+	}), "write orphaned bookings response")
+}
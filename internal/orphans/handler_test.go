@@ -0,0 +1,119 @@
+package orphans
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*notifications.BookingEvent
+}
+
+func (n *recordingNotifier) NotifyAsync(event *notifications.BookingEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+}
+
+func serve(t *testing.T, h echo.HandlerFunc, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, http.NoBody)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Set("user", &auth.User{ID: "admin-1", IsAdmin: true})
+	require.NoError(t, h(c))
+	return rec
+}
+
+func decodeCollection(t *testing.T, rec *httptest.ResponseRecorder) []api.Resource {
+	t.Helper()
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Data
+}
+
+func TestBookingsAndPositionsHandlers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	seedUser(t, store, "ada", "Ada")
+	getConfig := func() *areas.Config { return testConfig() }
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedBooking(t, store, "b1", "ws_999", "ada", tomorrow)
+	seedBooking(t, store, "b2", "ws_999", "ada", "2020-01-06")
+	seedBooking(t, store, "b3", "desk-1", "ada", tomorrow)
+	seedPosition(t, store, "p1", "floor.svg", "ws_999")
+
+	rec := serve(t, BookingsHandler(getConfig, store), http.MethodGet, "/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	data := decodeCollection(t, rec)
+	require.Len(t, data, 1)
+	assert.Equal(t, "b1", data[0].ID)
+	assert.Equal(t, "orphaned-bookings", data[0].Type)
+	attrs, ok := data[0].Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "ws_999", attrs["item_id"])
+	assert.Equal(t, "Ada", attrs["user_name"])
+
+	rec = serve(t, BookingsHandler(getConfig, store), http.MethodGet, "/?from=2020-01-01")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, decodeCollection(t, rec), 2)
+	rec = serve(t, BookingsHandler(getConfig, store), http.MethodGet, "/?from=yesterday")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(t, PositionsHandler(getConfig, store), http.MethodGet, "/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	data = decodeCollection(t, rec)
+	require.Len(t, data, 1)
+	assert.Equal(t, "p1", data[0].ID)
+}
+
+func TestCancelHandler(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	getConfig := func() *areas.Config { return testConfig() }
+	notifier := &recordingNotifier{}
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	seedBooking(t, store, "b1", "ws_998", "ada", tomorrow)
+	seedBooking(t, store, "b2", "ws_999", "bob", tomorrow)
+	seedBooking(t, store, "b3", "ws_999", "bob", "2020-01-06")
+	seedBooking(t, store, "b4", "desk-1", "bob", tomorrow)
+	cancel := CancelHandler(getConfig, store, notifier)
+
+	rec := serve(t, cancel, http.MethodPost, "/?item_id=desk-1")
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "items in the config are not canceled")
+
+	rec = serve(t, cancel, http.MethodPost, "/?item_id=ws_999")
+	require.Equal(t, http.StatusOK, rec.Code)
+	data := decodeCollection(t, rec)
+	require.Len(t, data, 1)
+	assert.Equal(t, "b2", data[0].ID)
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, notifications.EventBookingCanceled, notifier.events[0].Event)
+	assert.Equal(t, "admin-1", notifier.events[0].CanceledByUserID)
+
+	rec = serve(t, cancel, http.MethodPost, "/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	data = decodeCollection(t, rec)
+	require.Len(t, data, 1)
+	assert.Equal(t, "b1", data[0].ID)
+
+	var remaining int
+	require.NoError(t, store.QueryRow(`SELECT COUNT(*) FROM bookings`).Scan(&remaining))
+	assert.Equal(t, 2, remaining, "past bookings and bookings of configured items are kept")
+}
@@ -0,0 +1,314 @@
+// Package orphans finds bookings and floor plan positions of items that are
+// missing from the areas config, and moves the records of renamed items to
+// their new IDs.
+package orphans
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+)
+
+// lastDate is later than any booking date.
+const lastDate = "9999-12-31"
+
+// ErrConflict indicates that both items of a remap are booked on the same day.
+var ErrConflict = errors.New("both items are booked on the same day")
+
+// ErrInvalidRemap indicates a remap without distinct item IDs.
+var ErrInvalidRemap = errors.New("remap requires two different item ids")
+
+// Position is a floor plan position of an item.
+type Position struct {
+	ID        string
+	FloorPlan string
+	ItemID    string
+	Label     string
+}
+
+// FindBookings returns the bookings from fromDate on whose items are missing
+// from cfg, ordered by date and item.
+func FindBookings(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, fromDate string,
+) ([]bookings.BookingRecord, error) {
+	itemIDs, err := missingItemIDs(ctx, store, cfg,
+		`SELECT DISTINCT item_id FROM bookings WHERE booking_date >= ?`, fromDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(itemIDs) == 0 {
+		return nil, nil
+	}
+	records, err := bookings.ListBookingsInRange(ctx, store, itemIDs, fromDate, lastDate)
+	if err != nil {
+		return nil, fmt.Errorf("list orphaned bookings: %w", err)
+	}
+	return records, nil
+}
+
+// FindPositions returns the floor plan positions whose items are missing from
+// cfg, ordered by floor plan and item.
+func FindPositions(ctx context.Context, store *sql.DB, cfg *areas.Config) (result []Position, err error) {
+	rows, err := store.QueryContext(ctx,
+		`SELECT id, floor_plan, item_id, label FROM floor_plan_positions ORDER BY floor_plan, item_id`)
+	if err != nil {
+		return nil, fmt.Errorf("query floor plan positions: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close floor plan position rows: %w", closeErr)
+		}
+	}()
+	for rows.Next() {
+		var p Position
+		if err := rows.Scan(&p.ID, &p.FloorPlan, &p.ItemID, &p.Label); err != nil {
+			return nil, fmt.Errorf("scan floor plan position: %w", err)
+		}
+		if _, ok := cfg.FindItem(p.ItemID); !ok {
+			result = append(result, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate floor plan positions: %w", err)
+	}
+	return result, nil
+}
+
+// missingItemIDs returns the item IDs selected by query that are missing from
+// cfg.
+func missingItemIDs(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, query string, args ...any,
+) (result []string, err error) {
+	rows, err := store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query item ids: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close item id rows: %w", closeErr)
+		}
+	}()
+	for rows.Next() {
+		var itemID string
+		if err := rows.Scan(&itemID); err != nil {
+			return nil, fmt.Errorf("scan item id: %w", err)
+		}
+		if _, ok := cfg.FindItem(itemID); !ok {
+			result = append(result, itemID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate item ids: %w", err)
+	}
+	return result, nil
+}
+
+// Remap moves the bookings, floor plan positions, maintenance windows,
+// attendance records, lottery requests, booking defaults, and favorites of
+// item from to item to, and returns how many records moved. It fails with
+// ErrConflict, and moves nothing, if both items are booked on the same day.
+// Floor plan positions and favorites that to already has are kept, and those
+// of from dropped.
+func Remap(ctx context.Context, store *sql.DB, from, to string) (int64, error) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if from == "" || to == "" || from == to {
+		return 0, ErrInvalidRemap
+	}
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin remap: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() //nolint:errcheck // No-op after commit
+	}()
+
+	if err := checkBookingConflicts(ctx, tx, from, to); err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	var moved int64
+	for _, stmt := range []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE bookings SET item_id = ?, updated_at = ?, version = version + 1 WHERE item_id = ?`,
+			[]any{to, now, from}},
+		{`UPDATE OR IGNORE floor_plan_positions SET item_id = ?, updated_at = ?, version = version + 1
+		  WHERE item_id = ?`, []any{to, now, from}},
+		{`UPDATE item_maintenance SET item_id = ?, updated_at = ? WHERE item_id = ?`, []any{to, now, from}},
+		{`UPDATE booking_attendance SET item_id = ? WHERE item_id = ?`, []any{to, from}},
+		{`UPDATE lottery_requests SET item_id = ?, updated_at = ? WHERE item_id = ?`, []any{to, now, from}},
+		{`UPDATE bundle_defaults SET item_id = ? WHERE item_id = ?`, []any{to, from}},
+		{`UPDATE OR IGNORE favorites SET item_id = ? WHERE item_id = ?`, []any{to, from}},
+	} {
+		res, err := tx.ExecContext(ctx, stmt.query, stmt.args...)
+		if err != nil {
+			return 0, fmt.Errorf("remap item: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("remap item: %w", err)
+		}
+		moved += n
+	}
+	for _, table := range []string{"floor_plan_positions", "favorites"} {
+		//nolint:gosec // G202: table names are constants
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE item_id = ?`, from); err != nil {
+			return 0, fmt.Errorf("drop duplicate %s: %w", table, err)
+		}
+	}
+	n, err := remapPreferences(ctx, tx, from, to, now)
+	if err != nil {
+		return 0, err
+	}
+	moved += n
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit remap: %w", err)
+	}
+	return moved, nil
+}
+
+func checkBookingConflicts(ctx context.Context, tx *sql.Tx, from, to string) (err error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT booking_date FROM bookings WHERE item_id = ?
+		 AND booking_date IN (SELECT booking_date FROM bookings WHERE item_id = ?)
+		 ORDER BY booking_date`, from, to)
+	if err != nil {
+		return fmt.Errorf("query remap conflicts: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close remap conflict rows: %w", closeErr)
+		}
+	}()
+	var dates []string
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return fmt.Errorf("scan remap conflict: %w", err)
+		}
+		dates = append(dates, date)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate remap conflicts: %w", err)
+	}
+	if len(dates) > 0 {
+		return fmt.Errorf("%w: %q and %q on %s", ErrConflict, from, to, strings.Join(dates, ", "))
+	}
+	return nil
+}
+
+// remapPreferences replaces from with to in the ranked items of lottery
+// requests.
+func remapPreferences(ctx context.Context, tx *sql.Tx, from, to, now string) (int64, error) {
+	type request struct{ id, preferences string }
+	var requests []request
+	err := func() (err error) {
+		// LIKE also matches other IDs where from has "_" or "%"; the exact
+		// match is checked below.
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id, preferences FROM lottery_requests WHERE ',' || preferences || ',' LIKE ?`,
+			"%,"+from+",%")
+		if err != nil {
+			return fmt.Errorf("query lottery preferences: %w", err)
+		}
+		defer func() {
+			if closeErr := rows.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("close lottery preference rows: %w", closeErr)
+			}
+		}()
+		for rows.Next() {
+			var r request
+			if err := rows.Scan(&r.id, &r.preferences); err != nil {
+				return fmt.Errorf("scan lottery preferences: %w", err)
+			}
+			requests = append(requests, r)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate lottery preferences: %w", err)
+		}
+		return nil
+	}()
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, r := range requests {
+		prefs := strings.Split(r.preferences, ",")
+		changed := false
+		for i := range prefs {
+			if prefs[i] == from {
+				prefs[i] = to
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE lottery_requests SET preferences = ?, updated_at = ? WHERE id = ?`,
+			strings.Join(prefs, ","), now, r.id,
+		); err != nil {
+			return 0, fmt.Errorf("remap lottery preferences: %w", err)
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// Reconcile moves the records of item aliases to their items and logs the
+// bookings and floor plan positions that still reference missing items. It
+// runs on startup.
+func Reconcile(ctx context.Context, store *sql.DB, cfg *areas.Config) error {
+	aliases := cfg.ItemAliases()
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	for _, alias := range names {
+		moved, err := Remap(ctx, store, alias, aliases[alias])
+		if errors.Is(err, ErrConflict) {
+			slog.Warn("records of item alias not moved", "alias", alias, "item_id", aliases[alias], "err", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if moved > 0 {
+			slog.Info("records of item alias moved", "alias", alias, "item_id", aliases[alias], "records", moved)
+		}
+	}
+
+	records, err := FindBookings(ctx, store, cfg, "")
+	if err != nil {
+		return err
+	}
+	positions, err := FindPositions(ctx, store, cfg)
+	if err != nil {
+		return err
+	}
+	if len(records) > 0 || len(positions) > 0 {
+		itemIDs := make(map[string]struct{})
+		for i := range records {
+			itemIDs[records[i].ItemID] = struct{}{}
+		}
+		for i := range positions {
+			itemIDs[positions[i].ItemID] = struct{}{}
+		}
+		slog.Warn("bookings or floor plan positions reference items missing from the areas config; "+
+			"see GET /api/v1/admin/orphaned-bookings",
+			"bookings", len(records), "floor_plan_positions", len(positions), "items", len(itemIDs))
+	}
+	return nil
+}
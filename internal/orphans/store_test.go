@@ -0,0 +1,161 @@
+package orphans
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/db"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+	return store
+}
+
+func exec(t *testing.T, store *sql.DB, query string, args ...any) {
+	t.Helper()
+	_, err := store.Exec(query, args...)
+	require.NoError(t, err)
+}
+
+func seedBooking(t *testing.T, store *sql.DB, id, itemID, userID, date string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	exec(t, store, `
+		INSERT INTO bookings (id, item_id, user_id, booked_by_user_id, booking_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, id, itemID, userID, userID, date, now, now)
+}
+
+func seedPosition(t *testing.T, store *sql.DB, id, floorPlan, itemID string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	exec(t, store, `
+		INSERT INTO floor_plan_positions (id, floor_plan, item_id, x, y, width, height, created_at, updated_at)
+		VALUES (?, ?, ?, 0, 0, 10, 10, ?, ?)`, id, floorPlan, itemID, now, now)
+}
+
+func seedUser(t *testing.T, store *sql.DB, id, name string) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	exec(t, store, `
+		INSERT INTO users (id, email, display_name, user_source, created_at, updated_at)
+		VALUES (?, ?, ?, 'internal', ?, ?)`, id, id+"@example.com", name, now, now)
+}
+
+func itemOf(t *testing.T, store *sql.DB, table, id string) string {
+	t.Helper()
+	var itemID string
+	//nolint:gosec // G202: table names are test constants
+	require.NoError(t, store.QueryRow(`SELECT item_id FROM `+table+` WHERE id = ?`, id).Scan(&itemID))
+	return itemID
+}
+
+func testConfig() *areas.Config {
+	return &areas.Config{
+		Areas: []areas.Area{{
+			ID:   "office",
+			Name: "Office",
+			ItemGroups: []areas.ItemGroup{{
+				ID:   "room-1",
+				Name: "Room 1",
+				Items: []areas.Item{
+					{ID: "desk-1", Name: "Desk 1", Aliases: []string{"ws_101_1"}},
+					{ID: "desk-2", Name: "Desk 2"},
+				},
+			}},
+		}},
+	}
+}
+
+func TestFindOrphans(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	cfg := testConfig()
+	seedBooking(t, store, "b1", "desk-1", "ada", "2026-11-02")
+	seedBooking(t, store, "b2", "ws_999", "ada", "2026-11-03")
+	seedBooking(t, store, "b3", "ws_999", "bob", "2026-10-01")
+	seedBooking(t, store, "b4", "ws_101_1", "bob", "2026-11-04")
+	seedPosition(t, store, "p1", "floor.svg", "desk-1")
+	seedPosition(t, store, "p2", "floor.svg", "ws_999")
+
+	records, err := FindBookings(t.Context(), store, cfg, "2026-11-01")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "b2", records[0].ID)
+	assert.Equal(t, "b4", records[1].ID)
+
+	records, err = FindBookings(t.Context(), store, cfg, "")
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	positions, err := FindPositions(t.Context(), store, cfg)
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, Position{ID: "p2", FloorPlan: "floor.svg", ItemID: "ws_999"}, positions[0])
+
+	// Reconcile moves the records of aliases to their items.
+	require.NoError(t, Reconcile(t.Context(), store, cfg))
+	assert.Equal(t, "desk-1", itemOf(t, store, "bookings", "b4"))
+	records, err = FindBookings(t.Context(), store, cfg, "")
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestRemap(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	seedUser(t, store, "ada", "Ada")
+	now := time.Now().UTC().Format(time.RFC3339)
+	seedBooking(t, store, "b1", "old", "ada", "2026-11-02")
+	seedBooking(t, store, "b2", "desk-2", "ada", "2026-11-03")
+	seedPosition(t, store, "p1", "floor.svg", "old")
+	seedPosition(t, store, "p2", "annex.svg", "old")
+	seedPosition(t, store, "p3", "annex.svg", "desk-2")
+	exec(t, store, `INSERT INTO item_maintenance (id, item_id, start_date, end_date, created_at, updated_at)
+		VALUES ('m1', 'old', '2026-11-01', '2026-11-05', ?, ?)`, now, now)
+	exec(t, store, `INSERT INTO favorites (user_id, item_id, position, created_at) VALUES ('ada', 'old', 0, ?)`, now)
+	exec(t, store, `INSERT INTO lottery_requests
+		(id, scope_key, booking_date, user_id, preferences, created_at, updated_at)
+		VALUES ('l1', 'area:office', '2026-11-09', 'ada', 'desk-9,old,oldest', ?, ?)`, now, now)
+
+	moved, err := Remap(t.Context(), store, "old", "desk-2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), moved, "booking, one position, maintenance, favorite, lottery request")
+
+	assert.Equal(t, "desk-2", itemOf(t, store, "bookings", "b1"))
+	assert.Equal(t, "desk-2", itemOf(t, store, "floor_plan_positions", "p1"))
+	assert.Equal(t, "desk-2", itemOf(t, store, "item_maintenance", "m1"))
+	var count int
+	require.NoError(t, store.QueryRow(`SELECT COUNT(*) FROM floor_plan_positions WHERE item_id = 'old'`).Scan(&count))
+	assert.Zero(t, count, "positions on floor plans where the new item is placed are dropped")
+	require.NoError(t, store.QueryRow(`SELECT COUNT(*) FROM favorites WHERE item_id = 'desk-2'`).Scan(&count))
+	assert.Equal(t, 1, count)
+	var prefs string
+	require.NoError(t, store.QueryRow(`SELECT preferences FROM lottery_requests WHERE id = 'l1'`).Scan(&prefs))
+	assert.Equal(t, "desk-9,desk-2,oldest", prefs)
+
+	// Both items booked on the same day: nothing moves.
+	seedBooking(t, store, "b3", "desk-3", "ada", "2026-11-03")
+	_, err = Remap(t.Context(), store, "desk-3", "desk-2")
+	require.ErrorIs(t, err, ErrConflict)
+	assert.Contains(t, err.Error(), "2026-11-03")
+	assert.Equal(t, "desk-3", itemOf(t, store, "bookings", "b3"))
+
+	for _, ids := range [][2]string{{"", "desk-2"}, {"desk-2", " "}, {"desk-2", "desk-2"}} {
+		_, err := Remap(t.Context(), store, ids[0], ids[1])
+		assert.ErrorIs(t, err, ErrInvalidRemap, ids)
+	}
+}
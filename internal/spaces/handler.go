@@ -0,0 +1,295 @@
+package spaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+)
+
+const (
+	// maxConfigSize caps imported areas YAML.
+	maxConfigSize = 1 << 20
+	// yamlContentType is the media type of exported areas YAML.
+	yamlContentType = "application/yaml"
+)
+
+// Attributes that describe where a node is rather than what it is. Everything
+// else in the attributes of a space is its definition as in the areas YAML.
+const (
+	attrPosition  = "position"
+	attrArchived  = "archived"
+	attrCreatedAt = "created_at"
+	attrUpdatedAt = "updated_at"
+)
+
+// resourceTypes maps node kinds to their JSON:API resource types.
+var resourceTypes = map[Kind]string{
+	KindArea:      "areas",
+	KindItemGroup: "item-groups",
+	KindItem:      "items",
+}
+
+// parentAttributes maps node kinds to the attribute naming their parent. It
+// is optional for areas, which are top-level areas without one.
+var parentAttributes = map[Kind]string{
+	KindArea:      "parent_id",
+	KindItemGroup: "area_id",
+	KindItem:      "item_group_id",
+}
+
+type spaceRequest struct {
+	Data struct {
+		Type       string         `json:"type"`
+		ID         string         `json:"id"`
+		Attributes map[string]any `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns the areas, item groups, or items, archived ones
+// included, ordered by parent and position.
+// GET /api/v1/admin/{areas,item-groups,items}
+func ListHandler(source *Source, kind Kind) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		nodes, err := source.Nodes(c.Request().Context(), kind)
+		if err != nil {
+			return api.WriteInternalError(c, "list spaces", err)
+		}
+		return api.WriteCollection(c, api.MapResources(nodes, func(n Node) api.Resource {
+			return nodeResource(&n)
+		}), "write spaces response")
+	}
+}
+
+// CreateHandler creates an area, item group, or item. Its attributes are its
+// settings as in the areas YAML plus its parent and position among its
+// siblings; it is appended if position is missing.
+// POST /api/v1/admin/{areas,item-groups,items}
+func CreateHandler(source *Source, kind Kind) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !source.Editable() {
+			return writeChangeError(c, ErrReadOnly, "change space")
+		}
+		req, ok, err := decodeSpaceRequest(c, kind)
+		if !ok {
+			return err
+		}
+		id := strings.TrimSpace(req.Data.ID)
+		if id == "" {
+			return api.WriteBadRequest(c, "id is required")
+		}
+		n := &Node{Kind: kind, ID: id, Position: -1, Definition: map[string]any{}}
+		if detail := applyAttributes(n, req.Data.Attributes); detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+		if parentKey := parentAttributes[kind]; kind != KindArea && n.ParentID == "" {
+			return api.WriteBadRequest(c, parentKey+" is required")
+		}
+		if err := source.Create(c.Request().Context(), n); err != nil {
+			return writeChangeError(c, err, "create space")
+		}
+		slog.Info("space created", "kind", kind, "id", n.ID, "parent_id", n.ParentID)
+		return writeNode(c, source, http.StatusCreated, kind, n.ID)
+	}
+}
+
+// UpdateHandler changes, moves, reorders, archives, or restores an area, item
+// group, or item. Definition attributes set to null are removed. Archived
+// spaces and everything below them disappear from the areas config, but their
+// bookings are kept.
+// PATCH /api/v1/admin/{areas,item-groups,items}/:id
+func UpdateHandler(source *Source, kind Kind) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !source.Editable() {
+			return writeChangeError(c, ErrReadOnly, "change space")
+		}
+		req, ok, err := decodeSpaceRequest(c, kind)
+		if !ok {
+			return err
+		}
+		ctx := c.Request().Context()
+		n, err := source.Find(ctx, kind, c.Param("id"))
+		if errors.Is(err, ErrNotFound) {
+			return api.WriteNotFound(c, kindTitle(kind)+" not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find space", err)
+		}
+		required := api.IfMatch(c)
+		if !api.Matches(required, n.Version) {
+			return api.WritePreconditionFailed(c)
+		}
+		n.Position = -1
+		if detail := applyAttributes(n, req.Data.Attributes); detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+		if err := source.Update(ctx, n, required); err != nil {
+			return writeChangeError(c, err, "update space")
+		}
+		slog.Info("space updated", "kind", kind, "id", n.ID, "parent_id", n.ParentID, "archived", n.Archived)
+		return writeNode(c, source, http.StatusOK, kind, n.ID)
+	}
+}
+
+// ExportHandler returns the areas config as YAML, e.g. to keep it under
+// version control.
+// GET /api/v1/admin/areas-config
+func ExportHandler(source *Source) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		data, err := source.Export(c.Request().Context())
+		if err != nil {
+			return api.WriteInternalError(c, "export areas config", err)
+		}
+		return c.Blob(http.StatusOK, yamlContentType, data)
+	}
+}
+
+// ImportHandler replaces the spaces with the areas YAML in the request body.
+// Spaces missing from it are archived. It returns the resulting areas config.
+// PUT /api/v1/admin/areas-config
+func ImportHandler(source *Source) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !source.Editable() {
+			return writeChangeError(c, ErrReadOnly, "import areas config")
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxConfigSize))
+		if err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		cfg, err := areas.Parse(data)
+		if err != nil {
+			return api.WriteError(c, http.StatusBadRequest, err.Error(), "invalid_areas_config")
+		}
+		ctx := c.Request().Context()
+		if err := source.Import(ctx, cfg); err != nil {
+			return writeChangeError(c, err, "import areas config")
+		}
+		slog.Info("areas config imported", "areas", len(cfg.Areas))
+		data, err = source.Export(ctx)
+		if err != nil {
+			return api.WriteInternalError(c, "export areas config", err)
+		}
+		return c.Blob(http.StatusOK, yamlContentType, data)
+	}
+}
+
+// decodeSpaceRequest parses a space payload. If ok is false, the error
+// response was written and err is the handler's return value.
+func decodeSpaceRequest(c echo.Context, kind Kind) (req *spaceRequest, ok bool, err error) {
+	req = &spaceRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(req); err != nil {
+		return nil, false, api.WriteBadRequest(c, "Invalid request body")
+	}
+	if req.Data.Type != resourceTypes[kind] {
+		return nil, false, api.WriteBadRequest(c, fmt.Sprintf("Resource type must be '%s'", resourceTypes[kind]))
+	}
+	return req, true, nil
+}
+
+// applyAttributes sets the given attributes on n and returns why they are
+// invalid, or "". Whether the resulting definition is valid is checked when
+// the change is stored.
+func applyAttributes(n *Node, attrs map[string]any) string {
+	parentKey := parentAttributes[n.Kind]
+	for key, value := range attrs {
+		switch key {
+		case "id", attrCreatedAt, attrUpdatedAt:
+			// Read-only.
+		case childrenKey, nestedKey:
+			return "Children are managed through their own endpoints"
+		case attrPosition:
+			position, ok := value.(float64)
+			if !ok || position < 0 || position != float64(int(position)) {
+				return "position must be a non-negative integer"
+			}
+			n.Position = int(position)
+		case attrArchived:
+			archived, ok := value.(bool)
+			if !ok {
+				return "archived must be a boolean"
+			}
+			n.Archived = archived
+		case parentKey:
+			if value == nil && n.Kind == KindArea {
+				n.ParentID = ""
+				continue
+			}
+			parentID, ok := value.(string)
+			if !ok || strings.TrimSpace(parentID) == "" {
+				return parentKey + " must be a non-empty string"
+			}
+			n.ParentID = strings.TrimSpace(parentID)
+		default:
+			if value == nil {
+				delete(n.Definition, key)
+				continue
+			}
+			n.Definition[key] = value
+		}
+	}
+	return ""
+}
+
+// writeChangeError writes the response for an error of a change to the spaces.
+func writeChangeError(c echo.Context, err error, label string) error {
+	var validationErr *ValidationError
+	switch {
+	case errors.Is(err, ErrReadOnly):
+		return api.WriteError(c, http.StatusConflict,
+			"Spaces are defined in the areas YAML; set areas.source to \"database\" to edit them",
+			"spaces_read_only")
+	case errors.As(err, &validationErr):
+		return api.WriteError(c, http.StatusBadRequest, validationErr.Error(), "invalid_areas_config")
+	case errors.Is(err, ErrExists):
+		return api.WriteConflict(c, "A space of this kind with this ID already exists")
+	case errors.Is(err, ErrParentNotFound):
+		return api.WriteBadRequest(c, "Parent not found")
+	case errors.Is(err, ErrNestingCycle):
+		return api.WriteBadRequest(c, "An area cannot be nested in itself or in an area nested in it")
+	case errors.Is(err, ErrNotFound):
+		return api.WriteNotFound(c, "Space not found")
+	case errors.Is(err, ErrVersionConflict):
+		return api.WritePreconditionFailed(c)
+	}
+	return api.WriteInternalError(c, label, err)
+}
+
+// writeNode writes the stored node of kind with id.
+func writeNode(c echo.Context, source *Source, status int, kind Kind, id string) error {
+	n, err := source.Find(c.Request().Context(), kind, id)
+	if err != nil {
+		return api.WriteInternalError(c, "find space", err)
+	}
+	api.SetETag(c, n.Version)
+	return api.WriteSingle(c, status, nodeResource(n), "write space response")
+}
+
+func nodeResource(n *Node) api.Resource {
+	attrs := make(map[string]any, len(n.Definition)+5)
+	for k, v := range n.Definition {
+		attrs[k] = v
+	}
+	if n.Kind != KindArea || n.ParentID != "" {
+		attrs[parentAttributes[n.Kind]] = n.ParentID
+	}
+	attrs[attrPosition] = n.Position
+	attrs[attrArchived] = n.Archived
+	if n.CreatedAt != "" {
+		attrs[attrCreatedAt] = n.CreatedAt
+		attrs[attrUpdatedAt] = n.UpdatedAt
+	}
+	return api.Resource{Type: resourceTypes[n.Kind], ID: n.ID, Attributes: attrs}
+}
+
+func kindTitle(k Kind) string {
+	label := kindLabel(k)
+	return strings.ToUpper(label[:1]) + label[1:]
+}
@@ -0,0 +1,198 @@
+package spaces
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+)
+
+func serve(
+	t *testing.T, h echo.HandlerFunc, method, body string, header map[string]string, params ...string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if len(params) == 2 {
+		c.SetParamNames(params[0])
+		c.SetParamValues(params[1])
+	}
+	require.NoError(t, h(c))
+	return rec
+}
+
+func decodeResource(t *testing.T, rec *httptest.ResponseRecorder) api.Resource {
+	t.Helper()
+	var resp struct {
+		Data api.Resource `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Data
+}
+
+func TestCreateAndUpdateHandlers(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	create := CreateHandler(source, KindItem)
+	update := UpdateHandler(source, KindItem)
+
+	rec := serve(t, create, http.MethodPost, `{"data":{"type":"items","id":"desk-4","attributes":{
+		"item_group_id":"room-2","position":0,"name":"Desk 4","equipment":["Dock"],"max_bookings_per_person":1}}}`, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+	attrs, ok := decodeResource(t, rec).Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "room-2", attrs["item_group_id"])
+	assert.InDelta(t, 0, attrs["position"], 0)
+	assert.Equal(t, "Desk 4", attrs["name"])
+	assert.Equal(t, []string{"desk-4", "desk-3"}, itemIDs(source.Config(), "room-2"))
+	assert.Equal(t, 1, source.Config().Areas[0].ItemGroups[1].Items[0].MaxBookingsPerPerson)
+
+	// Null removes a setting; a stale If-Match is rejected.
+	body := `{"data":{"type":"items","id":"desk-4","attributes":{"max_bookings_per_person":null,"position":1}}}`
+	rec = serve(t, update, http.MethodPatch, body, map[string]string{"If-Match": `"1"`}, "id", "desk-4")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	assert.Equal(t, []string{"desk-3", "desk-4"}, itemIDs(source.Config(), "room-2"))
+	assert.Zero(t, source.Config().Areas[0].ItemGroups[1].Items[1].MaxBookingsPerPerson)
+	rec = serve(t, update, http.MethodPatch, body, map[string]string{"If-Match": `"1"`}, "id", "desk-4")
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	for _, tc := range []struct {
+		h      echo.HandlerFunc
+		body   string
+		status int
+	}{
+		{create, `{"data":{"type":"items","attributes":{"item_group_id":"room-2","name":"Desk"}}}`,
+			http.StatusBadRequest},
+		{create, `{"data":{"type":"items","id":"desk-5","attributes":{"name":"Desk 5"}}}`, http.StatusBadRequest},
+		{create, `{"data":{"type":"areas","id":"desk-5","attributes":{"item_group_id":"room-2"}}}`,
+			http.StatusBadRequest},
+		{create, `{"data":{"type":"items","id":"desk-5","attributes":{"item_group_id":"room-9","name":"Desk"}}}`,
+			http.StatusBadRequest},
+		{create, `{"data":{"type":"items","id":"desk-5","attributes":{"item_group_id":"room-2","name":"Desk",
+			"icon":"no-such-icon"}}}`, http.StatusBadRequest},
+		{create, `{"data":{"type":"items","id":"desk-3","attributes":{"item_group_id":"room-2","name":"Desk"}}}`,
+			http.StatusConflict},
+		{update, `{"data":{"type":"items","attributes":{"position":-1}}}`, http.StatusBadRequest},
+		{update, `{"data":{"type":"items","attributes":{"items":[]}}}`, http.StatusBadRequest},
+		{update, `{"data":{"type":"items","attributes":{"name":null}}}`, http.StatusBadRequest},
+	} {
+		rec := serve(t, tc.h, http.MethodPost, tc.body, nil, "id", "desk-4")
+		assert.Equal(t, tc.status, rec.Code, tc.body)
+	}
+	rec = serve(t, update, http.MethodPatch, `{"data":{"type":"items","attributes":{}}}`, nil, "id", "desk-9")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestListHandlerIncludesArchived(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	rec := serve(t, UpdateHandler(source, KindItemGroup), http.MethodPatch,
+		`{"data":{"type":"item-groups","attributes":{"archived":true}}}`, nil, "id", "room-2")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = serve(t, ListHandler(source, KindItemGroup), http.MethodGet, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	attrs, ok := resp.Data[1].Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "room-2", resp.Data[1].ID)
+	assert.Equal(t, true, attrs["archived"])
+}
+
+func TestImportAndExportHandlers(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	rec := serve(t, ImportHandler(source), http.MethodPut, "areas:\n  - id: annex\n    name: Annex\n", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/yaml", rec.Header().Get(echo.HeaderContentType))
+	require.Len(t, source.Config().Areas, 1)
+	assert.Equal(t, "annex", source.Config().Areas[0].ID)
+
+	rec = serve(t, ExportHandler(source), http.MethodGet, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	cfg, err := areas.Parse(rec.Body.Bytes())
+	require.NoError(t, err)
+	require.Len(t, cfg.Areas, 1)
+	assert.Equal(t, "Annex", cfg.Areas[0].Name)
+
+	for _, body := range []string{"areas: [", "areas:\n  - id: annex\n", "timezone: Mars/Olympus\nareas: []\n"} {
+		rec = serve(t, ImportHandler(source), http.MethodPut, body, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestHandlersInYAMLMode(t *testing.T) {
+	t.Parallel()
+
+	path := writeAreasFile(t, testAreasYAML)
+	cfg, err := areas.Load(path)
+	require.NoError(t, err)
+	source := NewYAMLSource(cfg, path)
+
+	rec := serve(t, ListHandler(source, KindArea), http.MethodGet, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"id":"office"`)
+
+	for _, h := range []echo.HandlerFunc{
+		CreateHandler(source, KindArea), UpdateHandler(source, KindArea), ImportHandler(source),
+	} {
+		rec := serve(t, h, http.MethodPost, `{"data":{"type":"areas","id":"office","attributes":{}}}`, nil,
+			"id", "office")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), "spaces_read_only")
+	}
+
+	rec = serve(t, ExportHandler(source), http.MethodGet, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, testAreasYAML, rec.Body.String())
+}
+
+func TestNestedAreaHandlers(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	rec := serve(t, CreateHandler(source, KindArea), http.MethodPost,
+		`{"data":{"type":"areas","id":"floor-2","attributes":{"parent_id":"office","name":"Floor 2","items":[]}}}`, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "children are managed through their own endpoints")
+
+	rec = serve(t, CreateHandler(source, KindArea), http.MethodPost,
+		`{"data":{"type":"areas","id":"floor-2","attributes":{"parent_id":"office","name":"Floor 2"}}}`, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	attrs, ok := decodeResource(t, rec).Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "office", attrs["parent_id"])
+	_, ok = source.Config().FindArea("floor-2")
+	assert.True(t, ok)
+
+	rec = serve(t, UpdateHandler(source, KindArea), http.MethodPatch,
+		`{"data":{"type":"areas","attributes":{"parent_id":"floor-2"}}}`, nil, "id", "office")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(t, UpdateHandler(source, KindArea), http.MethodPatch,
+		`{"data":{"type":"areas","attributes":{"parent_id":null}}}`, nil, "id", "floor-2")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	attrs, ok = decodeResource(t, rec).Attributes.(map[string]any)
+	require.True(t, ok)
+	assert.NotContains(t, attrs, "parent_id")
+	assert.Len(t, source.Config().ChildAreas(""), 2)
+}
@@ -0,0 +1,321 @@
+package spaces
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+)
+
+// Sources of the areas config (the areas.source setting).
+const (
+	ModeYAML     = "yaml"
+	ModeDatabase = "database"
+)
+
+// ErrReadOnly indicates a change while the areas YAML is authoritative.
+var ErrReadOnly = errors.New("spaces are defined in the areas YAML")
+
+// ErrParentNotFound indicates a parent area or item group that does not exist.
+var ErrParentNotFound = errors.New("parent space not found")
+
+// ErrNestingCycle indicates an area nested in itself or in an area nested in it.
+var ErrNestingCycle = errors.New("area cannot be nested in itself")
+
+// ValidationError reports why a change would leave the areas config invalid.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// Source provides the current areas config. In YAML mode it is the areas file
+// loaded at startup and cannot be changed; in database mode it is rebuilt from
+// the database after every change, which is validated like the areas file.
+type Source struct {
+	mode          string
+	store         *sql.DB
+	yamlPath      string
+	floorPlansDir string
+	mu            sync.Mutex // serializes changes
+	current       atomic.Pointer[areas.Config]
+}
+
+// NewYAMLSource returns a read-only source of cfg, loaded from the areas file
+// at path.
+func NewYAMLSource(cfg *areas.Config, path string) *Source {
+	s := &Source{mode: ModeYAML, yamlPath: path}
+	s.current.Store(cfg)
+	return s
+}
+
+// OpenDatabase returns a source backed by store. If the database holds no
+// spaces yet, the areas file at yamlPath is imported; without one, it starts
+// empty. Floor plans are checked in floorPlansDir if it is set.
+func OpenDatabase(ctx context.Context, store *sql.DB, yamlPath, floorPlansDir string) (*Source, error) {
+	s := &Source{mode: ModeDatabase, store: store, yamlPath: yamlPath, floorPlansDir: floorPlansDir}
+	_, ok, err := loadSettings(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.importFile(ctx); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := s.load(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	if floorPlansDir != "" {
+		if err := areas.ValidateFloorPlans(cfg, floorPlansDir); err != nil {
+			return nil, fmt.Errorf("validate floor plans: %w", err)
+		}
+	}
+	areas.ApplyFeatureLabels(cfg)
+	s.current.Store(cfg)
+	return s, nil
+}
+
+// importFile stores the areas file, checked like the file is in YAML mode, as
+// the first spaces of the database.
+func (s *Source) importFile(ctx context.Context) error {
+	cfg := &areas.Config{}
+	if s.yamlPath != "" {
+		// #nosec G304 -- path comes from explicit configuration.
+		data, err := os.ReadFile(s.yamlPath)
+		if err != nil {
+			return fmt.Errorf("read areas config: %w", err)
+		}
+		if cfg, err = areas.Parse(data); err != nil {
+			return err
+		}
+		if err := areas.ValidateReservations(cfg); err != nil {
+			return fmt.Errorf("validate reservations: %w", err)
+		}
+	}
+
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin spaces import: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() //nolint:errcheck // No-op after commit
+	}()
+	if err := importConfig(ctx, tx, cfg); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit spaces import: %w", err)
+	}
+	slog.Info("areas imported into the database", "file", s.yamlPath, "areas", len(cfg.Areas))
+	return nil
+}
+
+// Config returns the current areas config. It is an areas.ConfigGetter.
+func (s *Source) Config() *areas.Config {
+	return s.current.Load()
+}
+
+// Editable reports whether spaces can be changed, which they can unless the
+// areas YAML is authoritative.
+func (s *Source) Editable() bool {
+	return s.mode == ModeDatabase
+}
+
+// load assembles the areas config stored in the database, as written, without
+// feature labels.
+func (s *Source) load(ctx context.Context, q querier) (*areas.Config, error) {
+	settings, _, err := loadSettings(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := listNodes(ctx, q, "")
+	if err != nil {
+		return nil, err
+	}
+	return assemble(settings, nodes)
+}
+
+// Nodes returns the nodes of kind, archived ones included. In YAML mode they
+// come from the areas file and none are archived.
+func (s *Source) Nodes(ctx context.Context, kind Kind) ([]Node, error) {
+	if s.mode == ModeYAML {
+		_, nodes, err := flatten(s.Config())
+		if err != nil {
+			return nil, err
+		}
+		result := make([]Node, 0, len(nodes))
+		for i := range nodes {
+			if nodes[i].Kind == kind {
+				result = append(result, nodes[i])
+			}
+		}
+		return result, nil
+	}
+	return listNodes(ctx, s.store, kind)
+}
+
+// Find returns the node of kind with id, or ErrNotFound.
+func (s *Source) Find(ctx context.Context, kind Kind, id string) (*Node, error) {
+	if s.mode == ModeYAML {
+		nodes, err := s.Nodes(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+		for i := range nodes {
+			if nodes[i].ID == id {
+				return &nodes[i], nil
+			}
+		}
+		return nil, ErrNotFound
+	}
+	return findNode(ctx, s.store, kind, id)
+}
+
+// Create stores n at n.Position among the children of its parent, or after
+// them if the position is negative.
+func (s *Source) Create(ctx context.Context, n *Node) error {
+	return s.change(ctx, func(tx *sql.Tx) error {
+		if err := checkParent(ctx, tx, n); err != nil {
+			return err
+		}
+		if err := insertNode(ctx, tx, n); err != nil {
+			return err
+		}
+		return place(ctx, tx, n.Kind, n.ID, n.ParentID, n.Position)
+	})
+}
+
+// Update stores the definition, parent, and archived flag of n. The node is
+// moved to n.Position if it is not negative, or after its new siblings if its
+// parent changed. It fails with ErrVersionConflict unless the stored version
+// matches ifVersion (0 skips the check).
+func (s *Source) Update(ctx context.Context, n *Node, ifVersion int) error {
+	return s.change(ctx, func(tx *sql.Tx) error {
+		stored, err := findNode(ctx, tx, n.Kind, n.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkParent(ctx, tx, n); err != nil {
+			return err
+		}
+		if err := updateNode(ctx, tx, n, ifVersion); err != nil {
+			return err
+		}
+		if n.Position >= 0 || n.ParentID != stored.ParentID {
+			return place(ctx, tx, n.Kind, n.ID, n.ParentID, n.Position)
+		}
+		return nil
+	})
+}
+
+// Import replaces the spaces with cfg. Nodes missing from cfg are archived.
+func (s *Source) Import(ctx context.Context, cfg *areas.Config) error {
+	return s.change(ctx, func(tx *sql.Tx) error {
+		return importConfig(ctx, tx, cfg)
+	})
+}
+
+// Export returns the areas config as YAML. In YAML mode it is the areas file.
+func (s *Source) Export(ctx context.Context) ([]byte, error) {
+	if s.mode == ModeYAML && s.yamlPath != "" {
+		// #nosec G304 -- path comes from explicit configuration.
+		data, err := os.ReadFile(s.yamlPath)
+		if err != nil {
+			return nil, fmt.Errorf("read areas config: %w", err)
+		}
+		return data, nil
+	}
+	cfg := s.Config()
+	if s.mode == ModeDatabase {
+		var err error
+		if cfg, err = s.load(ctx, s.store); err != nil {
+			return nil, err
+		}
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("encode areas config: %w", err)
+	}
+	return data, nil
+}
+
+// change runs fn in a transaction and commits it if the resulting areas config
+// is valid, which then becomes the current one.
+func (s *Source) change(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if !s.Editable() {
+		return ErrReadOnly
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin spaces change: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() //nolint:errcheck // No-op after commit
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	cfg, err := s.load(ctx, tx)
+	if err != nil {
+		return &ValidationError{Err: err}
+	}
+	if err := areas.Validate(cfg); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if s.floorPlansDir != "" {
+		if err := areas.ValidateFloorPlans(cfg, s.floorPlansDir); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit spaces change: %w", err)
+	}
+	areas.ApplyFeatureLabels(cfg)
+	s.current.Store(cfg)
+	return nil
+}
+
+// checkParent returns ErrParentNotFound unless the parent of n exists, and
+// ErrNestingCycle if an area would be nested in itself. Areas without a
+// parent are top-level areas.
+func checkParent(ctx context.Context, q querier, n *Node) error {
+	if n.Kind == KindArea && n.ParentID == "" {
+		return nil
+	}
+	parent, err := findNode(ctx, q, n.Kind.Parent(), n.ParentID)
+	if errors.Is(err, ErrNotFound) {
+		return ErrParentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if n.Kind != KindArea {
+		return nil
+	}
+	for {
+		if parent.ID == n.ID {
+			return ErrNestingCycle
+		}
+		if parent.ParentID == "" {
+			return nil
+		}
+		if parent, err = findNode(ctx, q, KindArea, parent.ParentID); err != nil {
+			return err
+		}
+	}
+}
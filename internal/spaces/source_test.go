@@ -0,0 +1,324 @@
+package spaces
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/db"
+)
+
+const testAreasYAML = `timezone: Europe/Berlin
+features:
+  - id: monitor
+    label: Monitor
+areas:
+  - id: office
+    name: Office
+    items:
+      - id: room-1
+        name: Room 1
+        items:
+          - id: desk-1
+            name: Desk 1
+            equipment: [Dock]
+            features:
+              - id: monitor
+          - id: desk-2
+            name: Desk 2
+            equipment: []
+      - id: room-2
+        name: Room 2
+        items:
+          - id: desk-3
+            name: Desk 3
+            equipment: []
+`
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+	return store
+}
+
+func writeAreasFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "areas.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func openTestSource(t *testing.T) *Source {
+	t.Helper()
+	source, err := OpenDatabase(t.Context(), setupTestDB(t), writeAreasFile(t, testAreasYAML), "")
+	require.NoError(t, err)
+	return source
+}
+
+func itemIDs(cfg *areas.Config, groupID string) []string {
+	var ids []string
+	for _, area := range cfg.Areas {
+		for _, ig := range area.ItemGroups {
+			if ig.ID != groupID {
+				continue
+			}
+			for _, item := range ig.Items {
+				ids = append(ids, item.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func TestOpenDatabaseImportsAreasFile(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	path := writeAreasFile(t, testAreasYAML)
+	source, err := OpenDatabase(t.Context(), store, path, "")
+	require.NoError(t, err)
+	assert.True(t, source.Editable())
+
+	want, err := areas.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, source.Config(), "the database holds the areas file")
+
+	// Once imported, the database is authoritative and the file is ignored.
+	require.NoError(t, os.WriteFile(path, []byte("areas: []\n"), 0o600))
+	reopened, err := OpenDatabase(t.Context(), store, path, "")
+	require.NoError(t, err)
+	assert.Equal(t, want, reopened.Config())
+}
+
+func TestOpenDatabaseWithoutAreasFile(t *testing.T) {
+	t.Parallel()
+
+	source, err := OpenDatabase(t.Context(), setupTestDB(t), "", "")
+	require.NoError(t, err)
+	assert.Empty(t, source.Config().Areas)
+
+	n := &Node{Kind: KindArea, ID: "office", Position: -1, Definition: map[string]any{"name": "Office"}}
+	require.NoError(t, source.Create(t.Context(), n))
+	require.Len(t, source.Config().Areas, 1)
+	assert.Equal(t, "Office", source.Config().Areas[0].Name)
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	data, err := source.Export(t.Context())
+	require.NoError(t, err)
+	cfg, err := areas.Parse(data)
+	require.NoError(t, err)
+	areas.ApplyFeatureLabels(cfg)
+	assert.Equal(t, source.Config(), cfg)
+}
+
+func TestCreateAndReorder(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	ctx := t.Context()
+
+	n := &Node{Kind: KindItem, ID: "desk-0", ParentID: "room-1", Position: 0,
+		Definition: map[string]any{"name": "Desk 0", "equipment": []any{}}}
+	require.NoError(t, source.Create(ctx, n))
+	assert.Equal(t, []string{"desk-0", "desk-1", "desk-2"}, itemIDs(source.Config(), "room-1"))
+
+	// Moving an item to another group appends it there unless a position is given.
+	n.ParentID = "room-2"
+	n.Position = -1
+	require.NoError(t, source.Update(ctx, n, 1))
+	assert.Equal(t, []string{"desk-1", "desk-2"}, itemIDs(source.Config(), "room-1"))
+	assert.Equal(t, []string{"desk-3", "desk-0"}, itemIDs(source.Config(), "room-2"))
+
+	n.Position = 0
+	require.NoError(t, source.Update(ctx, n, 0))
+	assert.Equal(t, []string{"desk-0", "desk-3"}, itemIDs(source.Config(), "room-2"))
+
+	stored, err := source.Find(ctx, KindItem, "desk-3")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Position)
+
+	assert.ErrorIs(t, source.Update(ctx, n, 1), ErrVersionConflict)
+	assert.ErrorIs(t, source.Create(ctx, n), ErrExists)
+	assert.ErrorIs(t, source.Create(ctx, &Node{Kind: KindItem, ID: "desk-9", ParentID: "room-9",
+		Definition: map[string]any{"name": "Desk 9"}}), ErrParentNotFound)
+}
+
+func TestArchiveAndRestore(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	ctx := t.Context()
+
+	group, err := source.Find(ctx, KindItemGroup, "room-1")
+	require.NoError(t, err)
+	group.Archived = true
+	group.Position = -1
+	require.NoError(t, source.Update(ctx, group, 0))
+	assert.Empty(t, itemIDs(source.Config(), "room-1"), "items of archived groups are hidden")
+	require.Len(t, source.Config().Areas[0].ItemGroups, 1)
+
+	// Archived spaces keep their IDs, so bookings of them still resolve when
+	// they are restored.
+	assert.ErrorIs(t, source.Create(ctx, &Node{Kind: KindItemGroup, ID: "room-1", ParentID: "office",
+		Definition: map[string]any{"name": "Room 1"}}), ErrExists)
+
+	group.Archived = false
+	require.NoError(t, source.Update(ctx, group, 0))
+	assert.Equal(t, []string{"desk-1", "desk-2"}, itemIDs(source.Config(), "room-1"))
+}
+
+func TestChangesAreValidated(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	ctx := t.Context()
+	before := source.Config()
+
+	for name, n := range map[string]*Node{
+		"missing name": {Kind: KindItem, ID: "desk-9", ParentID: "room-1",
+			Definition: map[string]any{"equipment": []any{}}},
+		"unknown setting": {Kind: KindItem, ID: "desk-9", ParentID: "room-1",
+			Definition: map[string]any{"name": "Desk 9", "colour": "red"}},
+		"unknown feature": {Kind: KindItem, ID: "desk-9", ParentID: "room-1",
+			Definition: map[string]any{"name": "Desk 9", "features": []any{map[string]any{"id": "sofa"}}}},
+		"invalid icon": {Kind: KindItem, ID: "desk-9", ParentID: "room-1",
+			Definition: map[string]any{"name": "Desk 9", "icon": "no-such-icon"}},
+		"invalid timezone": {Kind: KindArea, ID: "annex",
+			Definition: map[string]any{"name": "Annex", "timezone": "Mars/Olympus"}},
+	} {
+		n.Position = -1
+		var validationErr *ValidationError
+		require.ErrorAs(t, source.Create(ctx, n), &validationErr, name)
+		_, err := source.Find(ctx, n.Kind, n.ID)
+		require.ErrorIs(t, err, ErrNotFound, "%s: the change is rolled back", name)
+	}
+	assert.Same(t, before, source.Config())
+
+	// Numbers decoded from JSON are float64.
+	n := &Node{Kind: KindItem, ID: "desk-9", ParentID: "room-1", Position: -1,
+		Definition: map[string]any{"name": "Desk 9", "max_bookings_per_person": float64(2)}}
+	require.NoError(t, source.Create(ctx, n))
+}
+
+func TestImportArchivesMissingSpaces(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	ctx := t.Context()
+	cfg, err := areas.Parse([]byte(`areas:
+  - id: office
+    name: Head Office
+    items:
+      - id: room-2
+        name: Room 2
+        items:
+          - id: desk-3
+            name: Desk 3
+            equipment: []
+          - id: desk-1
+            name: Desk 1
+            equipment: []
+`))
+	require.NoError(t, err)
+	require.NoError(t, source.Import(ctx, cfg))
+
+	assert.Equal(t, "Head Office", source.Config().Areas[0].Name)
+	assert.Empty(t, source.Config().Features)
+	assert.Equal(t, []string{"desk-3", "desk-1"}, itemIDs(source.Config(), "room-2"))
+	for kind, id := range map[Kind]string{KindItemGroup: "room-1", KindItem: "desk-2"} {
+		n, err := source.Find(ctx, kind, id)
+		require.NoError(t, err)
+		assert.True(t, n.Archived, id)
+	}
+	n, err := source.Find(ctx, KindItem, "desk-1")
+	require.NoError(t, err)
+	assert.False(t, n.Archived)
+	assert.Equal(t, "room-2", n.ParentID)
+}
+
+func TestYAMLSourceIsReadOnly(t *testing.T) {
+	t.Parallel()
+
+	path := writeAreasFile(t, testAreasYAML)
+	cfg, err := areas.Load(path)
+	require.NoError(t, err)
+	source := NewYAMLSource(cfg, path)
+	ctx := t.Context()
+
+	assert.False(t, source.Editable())
+	assert.Same(t, cfg, source.Config())
+	items, err := source.Nodes(ctx, KindItem)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "room-1", items[0].ParentID)
+	assert.Equal(t, "Desk 1", items[0].Definition["name"])
+
+	assert.ErrorIs(t, source.Create(ctx, &items[0]), ErrReadOnly)
+	assert.ErrorIs(t, source.Import(ctx, cfg), ErrReadOnly)
+	data, err := source.Export(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, testAreasYAML, string(data))
+}
+
+func TestNestedAreas(t *testing.T) {
+	t.Parallel()
+
+	source := openTestSource(t)
+	ctx := t.Context()
+
+	site := &Node{Kind: KindArea, ID: "hq", Position: 0,
+		Definition: map[string]any{"name": "HQ", "icon": "mdi-city", "items": []any{}}}
+	require.NoError(t, source.Create(ctx, site))
+	office, err := source.Find(ctx, KindArea, "office")
+	require.NoError(t, err)
+	office.ParentID = "hq"
+	office.Position = -1
+	require.NoError(t, source.Update(ctx, office, 0))
+
+	cfg := source.Config()
+	require.Len(t, cfg.Areas, 1, "areas that only hold nested areas are not bookable")
+	assert.Equal(t, "office", cfg.Areas[0].ID)
+	assert.Equal(t, "hq", cfg.Areas[0].ParentID)
+	assert.Equal(t, "mdi-city", cfg.Areas[0].Icon)
+	assert.Equal(t, []string{"desk-1", "desk-2"}, itemIDs(cfg, "room-1"))
+
+	// The export keeps the nesting and round-trips.
+	data, err := source.Export(ctx)
+	require.NoError(t, err)
+	exported, err := areas.Parse(data)
+	require.NoError(t, err)
+	require.Len(t, exported.Tree(), 1)
+	assert.Equal(t, "office", exported.Tree()[0].Areas[0].ID)
+	require.NoError(t, source.Import(ctx, exported))
+	office, err = source.Find(ctx, KindArea, "office")
+	require.NoError(t, err)
+	assert.Equal(t, "hq", office.ParentID)
+	assert.False(t, office.Archived)
+
+	site, err = source.Find(ctx, KindArea, "hq")
+	require.NoError(t, err)
+	site.ParentID = "office"
+	assert.ErrorIs(t, source.Update(ctx, site, 0), ErrNestingCycle)
+	site.ParentID = "hq"
+	assert.ErrorIs(t, source.Update(ctx, site, 0), ErrNestingCycle)
+
+	// Archiving the site hides the areas nested in it.
+	site.ParentID = ""
+	site.Archived = true
+	require.NoError(t, source.Update(ctx, site, 0))
+	assert.Empty(t, source.Config().Areas)
+}
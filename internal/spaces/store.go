@@ -0,0 +1,280 @@
+package spaces
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+)
+
+// ErrNotFound indicates that a node does not exist.
+var ErrNotFound = errors.New("space not found")
+
+// ErrExists indicates that a node with the same kind and ID exists, archived
+// or not.
+var ErrExists = errors.New("space already exists")
+
+// ErrVersionConflict indicates that a node changed since it was read.
+var ErrVersionConflict = errors.New("space version conflict")
+
+const nodeColumns = `kind, id, parent_id, position, archived, definition, created_at, updated_at, version`
+
+// querier is a database or transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNode(row rowScanner) (*Node, error) {
+	var n Node
+	var kind, def string
+	if err := row.Scan(
+		&kind, &n.ID, &n.ParentID, &n.Position, &n.Archived, &def, &n.CreatedAt, &n.UpdatedAt, &n.Version,
+	); err != nil {
+		return nil, err
+	}
+	n.Kind = Kind(kind)
+	n.Definition = map[string]any{}
+	if err := yaml.Unmarshal([]byte(def), &n.Definition); err != nil {
+		return nil, fmt.Errorf("decode definition of %s %q: %w", kindLabel(n.Kind), n.ID, err)
+	}
+	return &n, nil
+}
+
+// loadSettings returns the top-level settings; ok is false if spaces were
+// never imported into the database.
+func loadSettings(ctx context.Context, q querier) (settings map[string]any, ok bool, err error) {
+	var def string
+	err = q.QueryRowContext(ctx, `SELECT definition FROM space_settings WHERE id = 1`).Scan(&def)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("query space settings: %w", err)
+	}
+	settings = map[string]any{}
+	if err := yaml.Unmarshal([]byte(def), &settings); err != nil {
+		return nil, false, fmt.Errorf("decode space settings: %w", err)
+	}
+	return settings, true, nil
+}
+
+func saveSettings(ctx context.Context, q querier, settings map[string]any) error {
+	def, err := yaml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("encode space settings: %w", err)
+	}
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO space_settings (id, definition, updated_at) VALUES (1, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`,
+		string(def), time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("save space settings: %w", err)
+	}
+	return nil
+}
+
+// listNodes returns the nodes of kind, or of every kind if kind is empty,
+// archived ones included, ordered by parent and position.
+func listNodes(ctx context.Context, q querier, kind Kind) (result []Node, err error) {
+	query := `SELECT ` + nodeColumns + ` FROM spaces`
+	var args []any
+	if kind != "" {
+		query += ` WHERE kind = ?`
+		args = append(args, string(kind))
+	}
+	query += ` ORDER BY parent_id, position, id`
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query spaces: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close spaces rows: %w", closeErr)
+		}
+	}()
+	for rows.Next() {
+		n, err := scanNode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan space: %w", err)
+		}
+		result = append(result, *n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate spaces: %w", err)
+	}
+	return result, nil
+}
+
+func findNode(ctx context.Context, q querier, kind Kind, id string) (*Node, error) {
+	n, err := scanNode(q.QueryRowContext(ctx,
+		`SELECT `+nodeColumns+` FROM spaces WHERE kind = ? AND id = ?`, string(kind), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find space: %w", err)
+	}
+	return n, nil
+}
+
+func insertNode(ctx context.Context, q querier, n *Node) error {
+	def, err := yaml.Marshal(n.Definition)
+	if err != nil {
+		return fmt.Errorf("encode definition: %w", err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO spaces (`+nodeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)`,
+		string(n.Kind), n.ID, n.ParentID, n.Position, n.Archived, string(def), now, now,
+	)
+	if isUniqueViolation(err) {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("insert space: %w", err)
+	}
+	n.CreatedAt, n.UpdatedAt, n.Version = now, now, 1
+	return nil
+}
+
+// updateNode stores the parent, archived flag, and definition of n and bumps
+// its version. The update fails with ErrVersionConflict unless the stored
+// version matches ifVersion (0 skips the check).
+func updateNode(ctx context.Context, q querier, n *Node, ifVersion int) error {
+	def, err := yaml.Marshal(n.Definition)
+	if err != nil {
+		return fmt.Errorf("encode definition: %w", err)
+	}
+	res, err := q.ExecContext(ctx,
+		`UPDATE spaces SET parent_id = ?, archived = ?, definition = ?, updated_at = ?, version = version + 1
+		 WHERE kind = ? AND id = ? AND (? = 0 OR version = ?)`,
+		n.ParentID, n.Archived, string(def), time.Now().UTC().Format(time.RFC3339),
+		string(n.Kind), n.ID, ifVersion, ifVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("update space: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("update space: %w", err)
+	} else if affected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// place moves the node to position among the children of its parent, or to
+// the end if position is negative or past the end, and renumbers the
+// siblings from 0.
+func place(ctx context.Context, q querier, kind Kind, id, parentID string, position int) error {
+	rows, err := q.QueryContext(ctx,
+		`SELECT id FROM spaces WHERE kind = ? AND parent_id = ? AND id != ? ORDER BY position, id`,
+		string(kind), parentID, id)
+	if err != nil {
+		return fmt.Errorf("query siblings: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var sibling string
+		if err := rows.Scan(&sibling); err != nil {
+			_ = rows.Close() //nolint:errcheck // Scan error takes precedence
+			return fmt.Errorf("scan sibling: %w", err)
+		}
+		ids = append(ids, sibling)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close() //nolint:errcheck // Iteration error takes precedence
+		return fmt.Errorf("iterate siblings: %w", err)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("close sibling rows: %w", err)
+	}
+	if position < 0 || position > len(ids) {
+		position = len(ids)
+	}
+	ids = append(ids[:position], append([]string{id}, ids[position:]...)...)
+	for i, sibling := range ids {
+		if _, err := q.ExecContext(ctx,
+			`UPDATE spaces SET position = ? WHERE kind = ? AND id = ?`, i, string(kind), sibling,
+		); err != nil {
+			return fmt.Errorf("renumber siblings: %w", err)
+		}
+	}
+	return nil
+}
+
+// importConfig makes the database hold cfg: its settings replace the stored
+// ones, its nodes are created or updated and unarchived, and stored nodes
+// missing from cfg are archived so that their bookings still resolve.
+func importConfig(ctx context.Context, q querier, cfg *areas.Config) error {
+	settings, nodes, err := flatten(cfg)
+	if err != nil {
+		return err
+	}
+	if err := saveSettings(ctx, q, settings); err != nil {
+		return err
+	}
+	stored, err := listNodes(ctx, q, "")
+	if err != nil {
+		return err
+	}
+	existing := make(map[Kind]map[string]bool)
+	for i := range stored {
+		if existing[stored[i].Kind] == nil {
+			existing[stored[i].Kind] = make(map[string]bool)
+		}
+		existing[stored[i].Kind][stored[i].ID] = true
+	}
+
+	imported := make(map[Kind]map[string]bool)
+	for i := range nodes {
+		n := &nodes[i]
+		if imported[n.Kind] == nil {
+			imported[n.Kind] = make(map[string]bool)
+		}
+		imported[n.Kind][n.ID] = true
+		if !existing[n.Kind][n.ID] {
+			if err := insertNode(ctx, q, n); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := updateNode(ctx, q, n, 0); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, `UPDATE spaces SET position = ? WHERE kind = ? AND id = ?`,
+			n.Position, string(n.Kind), n.ID); err != nil {
+			return fmt.Errorf("update space position: %w", err)
+		}
+	}
+	for i := range stored {
+		n := &stored[i]
+		if imported[n.Kind][n.ID] || n.Archived {
+			continue
+		}
+		n.Archived = true
+		if err := updateNode(ctx, q, n, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
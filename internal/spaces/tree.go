@@ -0,0 +1,223 @@
+// Package spaces stores areas, item groups, and items in the database so that
+// admins can edit them through the API, and converts them to and from the
+// areas YAML.
+package spaces
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+)
+
+// Kind is the level of a node in the spaces tree.
+type Kind string
+
+// Node kinds, from the top of the tree down.
+const (
+	KindArea      Kind = "area"
+	KindItemGroup Kind = "item_group"
+	KindItem      Kind = "item"
+)
+
+// childrenKey is the YAML key of the item groups of an area and the items of
+// an item group, and nestedKey that of the areas nested in an area. Children
+// are separate nodes, so definitions never hold them.
+const (
+	childrenKey = "items"
+	nestedKey   = "areas"
+)
+
+// Node is an area, item group, or item. Definition holds its settings as in
+// the areas YAML, without its ID and children. ParentID is empty for
+// top-level areas and names the area a nested area is in.
+type Node struct {
+	Kind       Kind
+	ID         string
+	ParentID   string
+	Position   int
+	Archived   bool
+	Definition map[string]any
+	CreatedAt  string
+	UpdatedAt  string
+	Version    int
+}
+
+// Parent returns the kind of the node's parent. Areas may be nested in
+// other areas, but need not be.
+func (k Kind) Parent() Kind {
+	switch k {
+	case KindArea, KindItemGroup:
+		return KindArea
+	case KindItem:
+		return KindItemGroup
+	default:
+		return ""
+	}
+}
+
+// flatten splits cfg into its top-level settings and the nodes of its areas,
+// item groups, and items, each numbered by its position among its siblings.
+func flatten(cfg *areas.Config) (settings map[string]any, nodes []Node, err error) {
+	top := *cfg
+	top.SetTree(nil)
+	if settings, err = toDefinition(&top); err != nil {
+		return nil, nil, err
+	}
+	delete(settings, "areas")
+
+	add := func(kind Kind, id, parentID string, position int, v any) error {
+		def, err := toDefinition(v)
+		if err != nil {
+			return err
+		}
+		delete(def, "id")
+		delete(def, childrenKey)
+		delete(def, nestedKey)
+		nodes = append(nodes, Node{Kind: kind, ID: id, ParentID: parentID, Position: position, Definition: def})
+		return nil
+	}
+	var addAreas func(tree []areas.Area, parentID string) error
+	addAreas = func(tree []areas.Area, parentID string) error {
+		for i := range tree {
+			area := tree[i]
+			area.ItemGroups, area.Areas = nil, nil
+			if err := add(KindArea, area.ID, parentID, i, &area); err != nil {
+				return err
+			}
+			for j := range tree[i].ItemGroups {
+				ig := tree[i].ItemGroups[j]
+				ig.Items = nil
+				if err := add(KindItemGroup, ig.ID, area.ID, j, &ig); err != nil {
+					return err
+				}
+				for k := range tree[i].ItemGroups[j].Items {
+					item := &tree[i].ItemGroups[j].Items[k]
+					if err := add(KindItem, item.ID, ig.ID, k, item); err != nil {
+						return err
+					}
+				}
+			}
+			if err := addAreas(tree[i].Areas, area.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := addAreas(cfg.Tree(), ""); err != nil {
+		return nil, nil, err
+	}
+	return settings, nodes, nil
+}
+
+// assemble builds the areas config from its top-level settings and nodes,
+// which must be ordered by position. Archived nodes and the nodes below them
+// are left out.
+func assemble(settings map[string]any, nodes []Node) (*areas.Config, error) {
+	var cfg areas.Config
+	if err := fromDefinition(settings, &cfg); err != nil {
+		return nil, fmt.Errorf("decode settings: %w", err)
+	}
+	nested := make(map[string][]areas.Area)
+	groups := make(map[string][]areas.ItemGroup)
+	items := make(map[string][]areas.Item)
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Archived {
+			continue
+		}
+		switch n.Kind {
+		case KindArea:
+			var area areas.Area
+			if err := decodeNode(n, &area); err != nil {
+				return nil, err
+			}
+			nested[n.ParentID] = append(nested[n.ParentID], area)
+		case KindItemGroup:
+			var ig areas.ItemGroup
+			if err := decodeNode(n, &ig); err != nil {
+				return nil, err
+			}
+			groups[n.ParentID] = append(groups[n.ParentID], ig)
+		case KindItem:
+			var item areas.Item
+			if err := decodeNode(n, &item); err != nil {
+				return nil, err
+			}
+			items[n.ParentID] = append(items[n.ParentID], item)
+		}
+	}
+	var build func(parentID string) []areas.Area
+	build = func(parentID string) []areas.Area {
+		tree := []areas.Area{}
+		for i := range nested[parentID] {
+			area := nested[parentID][i]
+			area.ItemGroups = groups[area.ID]
+			for j := range area.ItemGroups {
+				area.ItemGroups[j].Items = items[area.ItemGroups[j].ID]
+			}
+			area.Areas = build(area.ID)
+			if len(area.Areas) == 0 {
+				area.Areas = nil
+			}
+			tree = append(tree, area)
+		}
+		return tree
+	}
+	cfg.SetTree(build(""))
+	return &cfg, nil
+}
+
+// decodeNode decodes the definition of n into the area, item group, or item
+// v. Unknown settings are rejected.
+func decodeNode(n *Node, v any) error {
+	def := make(map[string]any, len(n.Definition)+1)
+	for k, val := range n.Definition {
+		def[k] = val
+	}
+	def["id"] = n.ID
+	if err := fromDefinition(def, v); err != nil {
+		return fmt.Errorf("%s %q: %w", kindLabel(n.Kind), n.ID, err)
+	}
+	return nil
+}
+
+// toDefinition returns v as its YAML mapping.
+func toDefinition(v any) (map[string]any, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode definition: %w", err)
+	}
+	def := map[string]any{}
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("decode definition: %w", err)
+	}
+	return def, nil
+}
+
+// fromDefinition decodes the YAML mapping def into v, rejecting unknown keys.
+func fromDefinition(def map[string]any, v any) error {
+	data, err := yaml.Marshal(def)
+	if err != nil {
+		return fmt.Errorf("encode definition: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("decode definition: %w", err)
+	}
+	return nil
+}
+
+func kindLabel(k Kind) string {
+	switch k {
+	case KindArea:
+		return "area"
+	case KindItemGroup:
+		return "item group"
+	default:
+		return "item"
+	}
+}
@@ -0,0 +1,49 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/thorstenkramm/sithub/internal/config"
+	"github.com/thorstenkramm/sithub/internal/db"
+	"github.com/thorstenkramm/sithub/internal/orphans"
+)
+
+// ErrUnknownItem indicates a remap to an item missing from the areas config.
+var ErrUnknownItem = errors.New("item not found in the areas config")
+
+// RemapItem moves the bookings and other records of item from to item to, e.g.
+// after the item was renamed, and returns how many records moved. Unless force
+// is set, to must be an item of the areas config.
+func RemapItem(ctx context.Context, cfg *config.Config, from, to string, force bool) (moved int64, err error) {
+	store, err := db.Open(cfg.Main.DataDir)
+	if err != nil {
+		return 0, fmt.Errorf("open database: %w", err)
+	}
+	defer func() {
+		if closeErr := store.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close database: %w", closeErr)
+		}
+	}()
+	if err := db.RunMigrations(store); err != nil {
+		return 0, fmt.Errorf("run migrations: %w", err)
+	}
+
+	if !force {
+		source, err := openSpaces(ctx, cfg, store)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := source.Config().FindItem(to); !ok {
+			return 0, fmt.Errorf("%w: %q", ErrUnknownItem, to)
+		}
+	}
+	moved, err = orphans.Remap(ctx, store, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("remap item: %w", err)
+	}
+	slog.Info("item remapped", "from", from, "to", to, "records", moved)
+	return moved, nil
+}
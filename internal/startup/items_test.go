@@ -0,0 +1,65 @@
+package startup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/config"
+	"github.com/thorstenkramm/sithub/internal/db"
+)
+
+func TestRemapItem(t *testing.T) {
+	dataDir := t.TempDir()
+	areasFile := filepath.Join(dataDir, "areas.yaml")
+	content := `areas:
+  - id: area-1
+    name: Area 1
+    items:
+      - id: ig-1
+        name: Room 1
+        items:
+          - id: desk-1
+            name: Desk 1
+`
+	if err := os.WriteFile(areasFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("write areas config: %v", err)
+	}
+	cfg := &config.Config{
+		Main:  config.MainConfig{DataDir: dataDir},
+		Areas: config.AreasConfig{ConfigFile: areasFile},
+	}
+
+	store, err := db.Open(dataDir)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.RunMigrations(store); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := store.Exec(`INSERT INTO bookings (id, item_id, user_id, booking_date, created_at, updated_at)
+		VALUES ('b1', 'ws_101_1', 'user-1', '2026-11-02', ?, ?)`, now, now); err != nil {
+		t.Fatalf("seed booking: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close database: %v", err)
+	}
+
+	if _, err := RemapItem(t.Context(), cfg, "ws_101_1", "desk-9", false); !errors.Is(err, ErrUnknownItem) {
+		t.Fatalf("expected ErrUnknownItem, got %v", err)
+	}
+	moved, err := RemapItem(t.Context(), cfg, "ws_101_1", "desk-1", false)
+	if err != nil {
+		t.Fatalf("remap item: %v", err)
+	}
+	if moved != 1 {
+		t.Fatalf("expected 1 moved record, got %d", moved)
+	}
+	moved, err = RemapItem(t.Context(), cfg, "desk-1", "desk-9", true)
+	if err != nil || moved != 1 {
+		t.Fatalf("expected forced remap of 1 record, got %d, %v", moved, err)
+	}
+}
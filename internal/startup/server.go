@@ -22,16 +22,28 @@ import (
 	"github.com/thorstenkramm/sithub/internal/areas"
 	"github.com/thorstenkramm/sithub/internal/auth"
 	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/closures"
 	"github.com/thorstenkramm/sithub/internal/config"
 	"github.com/thorstenkramm/sithub/internal/db"
+	"github.com/thorstenkramm/sithub/internal/delegations"
+	"github.com/thorstenkramm/sithub/internal/favorites"
 	"github.com/thorstenkramm/sithub/internal/floorplanpos"
+	"github.com/thorstenkramm/sithub/internal/idempotency"
 	"github.com/thorstenkramm/sithub/internal/itemgroups"
 	"github.com/thorstenkramm/sithub/internal/items"
 	"github.com/thorstenkramm/sithub/internal/livefeed"
+	"github.com/thorstenkramm/sithub/internal/lottery"
+	"github.com/thorstenkramm/sithub/internal/maintenance"
 	"github.com/thorstenkramm/sithub/internal/middleware"
 	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/orphans"
+	"github.com/thorstenkramm/sithub/internal/spaces"
+	"github.com/thorstenkramm/sithub/internal/subscriptions"
 	"github.com/thorstenkramm/sithub/internal/system"
+	"github.com/thorstenkramm/sithub/internal/teams"
 	"github.com/thorstenkramm/sithub/internal/users"
+	"github.com/thorstenkramm/sithub/internal/visitors"
+	"github.com/thorstenkramm/sithub/internal/workstatus"
 )
 
 // Run starts the HTTP server and blocks until it shuts down.
@@ -58,10 +70,13 @@ func Run(ctx context.Context, cfg *config.Config, version string) error {
 		return fmt.Errorf("run migrations: %w", err)
 	}
 
-	areasConfig, err := loadAndValidateAreas(cfg)
+	source, err := openSpaces(ctx, cfg, store)
 	if err != nil {
 		return err
 	}
+	if err := orphans.Reconcile(ctx, store, source.Config()); err != nil {
+		return fmt.Errorf("reconcile item ids: %w", err)
+	}
 
 	avatarsDir, err := ensureAvatarsDir(cfg.Main.DataDir)
 	if err != nil {
@@ -72,11 +87,20 @@ func Run(ctx context.Context, cfg *config.Config, version string) error {
 	if err != nil {
 		return fmt.Errorf("init auth service: %w", err)
 	}
+	if cfg.EntraID.SyncTeamGroups {
+		authService.SetGroupSync(func(ctx context.Context, userID string, groupIDs []string) error {
+			return teams.SyncIdPGroups(ctx, store, userID, groupIDs)
+		})
+	}
 
 	webhookNotifier := notifications.NewNotifier(cfg.Notifications.WebhookURL)
 	hub := livefeed.NewHub()
 	go hub.Run(ctx)
-	notifier := notifications.MultiNotifier{webhookNotifier, hub}
+	getConfig := source.Config
+	digestWindow := time.Duration(cfg.Notifications.SubscriptionDigestMinutes) * time.Minute
+	digester := subscriptions.NewDigester(getConfig, store, webhookNotifier, digestWindow)
+	go digester.Run(ctx, time.Minute)
+	notifier := notifications.MultiNotifier{webhookNotifier, hub, digester}
 
 	e.Use(middleware.LoadUser(authService))
 	e.Use(middleware.RedirectForbidden(authService))
@@ -87,13 +111,23 @@ func Run(ctx context.Context, cfg *config.Config, version string) error {
 	}
 
 	bookingLimits := &bookings.BookingLimits{
-		WeeksInAdvanced:      cfg.Bookings.WeeksInAdvanced,
-		MaxBookingsPerPerson: cfg.Bookings.MaxBookingsPerPerson,
+		WeeksInAdvanced:           cfg.Bookings.WeeksInAdvanced,
+		MaxBookingsPerPerson:      cfg.Bookings.MaxBookingsPerPerson,
+		OnBehalfWithoutDelegation: cfg.Bookings.OnBehalfWithoutDelegation,
 	}
 
+	allocator := lottery.NewAllocator(getConfig, store, notifier, bookingLimits,
+		closures.NewGuard(getConfig, store), maintenance.NewGuard(store))
+	go allocator.Run(ctx, time.Minute)
+	go visitors.RunPurge(ctx, store, cfg.Visitors.RetentionDays, time.Hour)
+	go bookings.RunNoShowDetection(ctx, store, getConfig, time.Hour)
+	idempotencyRetention := time.Duration(cfg.Bookings.IdempotencyRetentionHours) * time.Hour
+	go idempotency.RunPurge(ctx, store, idempotencyRetention, time.Hour)
+
 	//nolint:contextcheck // Echo handlers use request context.
-	registerRoutes(e, authService, areasConfig, cfg.Areas.FloorPlansDir, avatarsDir, store,
-		notifier, hub, bookingLimits, version)
+	registerRoutes(e, authService, source, cfg.Areas.FloorPlansDir, avatarsDir, store,
+		notifier, hub, bookingLimits, visitors.Receptionists(cfg.Visitors.Receptionists),
+		idempotencyRetention, version)
 	registerSPAHandlers(e, webFS)
 
 	addr := fmt.Sprintf("%s:%d", cfg.Main.Listen, cfg.Main.Port)
@@ -222,12 +256,13 @@ func safeHostSource(host string) string {
 }
 
 func registerRoutes(
-	e *echo.Echo, authService *auth.Service, areasConfig *areas.Config,
+	e *echo.Echo, authService *auth.Service, source *spaces.Source,
 	floorPlansDir, avatarsDir string, store *sql.DB, notifier notifications.Notifier,
-	liveHub *livefeed.Hub, bookingLimits *bookings.BookingLimits, version string,
+	liveHub *livefeed.Hub, bookingLimits *bookings.BookingLimits, receptionists visitors.Receptionists,
+	idempotencyRetention time.Duration, version string,
 ) {
-	// Helper to get current config (returns the same config, loaded at startup)
-	getConfig := func() *areas.Config { return areasConfig }
+	// Helper to get the current config (changes with admin edits in database mode)
+	getConfig := source.Config
 
 	// OAuth routes
 	e.GET("/oauth/login", auth.LoginHandler(authService))
@@ -251,9 +286,14 @@ func registerRoutes(
 	}
 	e.GET("/api/v1/settings", system.SettingsHandler(weeksInAdvanced), requireAuth)
 	e.GET("/api/v1/version", system.Version(version), requireAuth)
-	e.GET("/api/v1/me", auth.MeHandler(), requireAuth)
+	e.GET("/api/v1/me", auth.MeHandler(bookings.AttendanceMeAttributes(getConfig, store)), requireAuth)
 	e.PATCH("/api/v1/me", auth.UpdateMeHandler(authService), requireAuth)
+	bookingGuards := []bookings.Guard{
+		closures.NewGuard(getConfig, store), maintenance.NewGuard(store), lottery.NewGuard(getConfig, store),
+	}
 	e.GET("/api/v1/areas", areas.ListHandlerDynamic(getConfig), requireAuth)
+	e.GET("/api/v1/area-tree", areas.TreeHandler(getConfig), requireAuth)
+	e.GET("/api/v1/areas/:area_id/areas", areas.ChildrenHandler(getConfig), requireAuth)
 	e.GET("/api/v1/areas/:area_id/item-groups",
 		itemgroups.ListHandlerDynamic(getConfig), requireAuth)
 	e.GET("/api/v1/areas/:area_id/item-groups/availability",
@@ -264,15 +304,80 @@ func registerRoutes(
 		areas.PresenceHandlerDynamic(getConfig, store), requireAuth)
 	e.GET("/api/v1/item-groups/:item_group_id/items",
 		items.ListHandlerDynamic(getConfig, store), requireAuth)
+	e.GET("/api/v1/items/search",
+		items.SearchHandlerDynamic(getConfig, store,
+			bookingGuards...), requireAuth)
+	e.GET("/api/v1/features", items.FeaturesHandler(getConfig), requireAuth)
 	e.GET("/api/v1/item-groups/:item_group_id/bookings",
 		itemgroups.BookingsHandlerDynamic(getConfig, store), requireAuth)
 	e.GET("/api/v1/bookings", bookings.ListHandlerDynamic(getConfig, store), requireAuth)
 	e.GET("/api/v1/bookings/history",
 		bookings.HistoryHandlerDynamic(getConfig, store), requireAuth)
+	// Booking creation can be retried safely with an Idempotency-Key header
+	idempotent := idempotency.Middleware(store, idempotencyRetention)
 	e.POST("/api/v1/bookings",
-		bookings.CreateHandlerDynamic(getConfig, store, notifier, bookingLimits), requireAuth)
-	e.PATCH("/api/v1/bookings/:id", bookings.PatchHandler(store), requireAuth)
-	e.DELETE("/api/v1/bookings/:id", bookings.DeleteHandler(store, notifier), requireAuth)
+		bookings.CreateHandlerDynamic(getConfig, store, notifier, bookingLimits,
+			bookingGuards...), requireAuth, idempotent)
+	e.POST("/api/v1/bookings/team",
+		bookings.TeamHandlerDynamic(getConfig, store, notifier, bookingLimits,
+			bookingGuards...), requireAuth, idempotent)
+	e.POST("/api/v1/bookings/auto",
+		bookings.AutoHandlerDynamic(getConfig, store, notifier, bookingLimits,
+			bookingGuards...), requireAuth, idempotent)
+	e.POST("/api/v1/bookings/bundles",
+		bookings.BundleHandlerDynamic(getConfig, store, notifier, bookingLimits,
+			bookingGuards...), requireAuth, idempotent)
+	e.POST("/api/v1/bookings/usual",
+		bookings.UsualHandlerDynamic(getConfig, store, notifier, bookingLimits,
+			bookingGuards...), requireAuth, idempotent)
+	e.DELETE("/api/v1/bookings/bundles/:id",
+		bookings.CancelBundleHandler(getConfig, store, notifier), requireAuth)
+	e.GET("/api/v1/booking-policies",
+		bookings.PoliciesHandler(getConfig, store, bookingLimits), requireAuth)
+	e.PATCH("/api/v1/bookings/:id", bookings.PatchHandlerDynamic(getConfig, store), requireAuth)
+	e.DELETE("/api/v1/bookings/:id", bookings.DeleteHandlerDynamic(getConfig, store, notifier), requireAuth)
+	e.POST("/api/v1/bookings/:id/check-in", bookings.CheckInHandler(getConfig, store), requireAuth)
+	e.POST("/api/v1/bookings/:id/confirm", bookings.ConfirmHandler(getConfig, store, notifier), requireAuth)
+
+	// Items added to every bundle the user books, e.g. parking
+	e.GET("/api/v1/bundle-defaults", bookings.ListBundleDefaultsHandler(store), requireAuth)
+	e.POST("/api/v1/bundle-defaults", bookings.CreateBundleDefaultHandler(getConfig, store), requireAuth)
+	e.DELETE("/api/v1/bundle-defaults/:id", bookings.DeleteBundleDefaultHandler(store), requireAuth)
+
+	// Favorite items, tried in order by "book my usual"
+	e.GET("/api/v1/me/favorites", favorites.ListHandler(getConfig, store), requireAuth)
+	e.POST("/api/v1/me/favorites", favorites.CreateHandler(getConfig, store), requireAuth)
+	e.PUT("/api/v1/me/favorites", favorites.ReplaceHandler(getConfig, store), requireAuth)
+	e.GET("/api/v1/me/favorites/suggestions", bookings.FavoriteSuggestionsHandler(getConfig, store), requireAuth)
+	e.DELETE("/api/v1/me/favorites/:item_id", favorites.DeleteHandler(store), requireAuth)
+
+	// Delegations for booking on behalf of others
+	e.GET("/api/v1/delegations", delegations.ListHandler(store), requireAuth)
+	e.POST("/api/v1/delegations", delegations.CreateHandler(store), requireAuth)
+	e.DELETE("/api/v1/delegations/:id", delegations.DeleteHandler(store), requireAuth)
+
+	// Teams and where their members are this week
+	e.GET("/api/v1/teams", teams.ListHandler(store), requireAuth)
+	e.GET("/api/v1/teams/:id/members", teams.ListMembersHandler(store), requireAuth)
+	e.GET("/api/v1/teams/:id/week", teams.WeekHandlerDynamic(getConfig, store), requireAuth)
+	e.GET("/api/v1/teams/:id/week/suggestion",
+		teams.DaySuggestionHandlerDynamic(getConfig, store, bookingGuards...), requireAuth)
+
+	// Subscriptions to colleagues, item groups, and areas
+	e.GET("/api/v1/subscriptions", subscriptions.ListHandler(getConfig, store), requireAuth)
+	e.POST("/api/v1/subscriptions", subscriptions.CreateHandler(getConfig, store), requireAuth)
+	e.DELETE("/api/v1/subscriptions/:id", subscriptions.DeleteHandler(store), requireAuth)
+
+	// Daily work statuses (office, remote, travelling, off)
+	e.GET("/api/v1/me/work-statuses", workstatus.ListHandler(store), requireAuth)
+	e.PUT("/api/v1/me/work-statuses/:date", workstatus.SetHandler(getConfig, store, notifier), requireAuth)
+	e.DELETE("/api/v1/me/work-statuses/:date", workstatus.DeleteHandler(store), requireAuth)
+	e.GET("/api/v1/me/work-status-settings", workstatus.SettingsHandler(store), requireAuth)
+	e.PATCH("/api/v1/me/work-status-settings", workstatus.UpdateSettingsHandler(store), requireAuth)
+
+	// Private bookings hide the booker from other users
+	e.GET("/api/v1/me/privacy-settings", bookings.PrivacySettingsHandler(store), requireAuth)
+	e.PATCH("/api/v1/me/privacy-settings", bookings.UpdatePrivacySettingsHandler(getConfig, store), requireAuth)
 
 	// Live feed (WebSocket) for real-time booking updates.
 	e.GET("/api/v1/live", livefeed.Handler(liveHub), requireAuth)
@@ -300,6 +405,23 @@ func registerRoutes(
 	e.PATCH("/api/v1/users/:id", users.UpdateHandler(store), requireAuth, requireAdmin)
 	e.DELETE("/api/v1/users/:id", users.DeleteHandler(store), requireAuth, requireAdmin)
 
+	// Booking search across all users (admin only)
+	e.GET("/api/v1/admin/bookings", bookings.SearchHandler(getConfig, store), requireAuth, requireAdmin)
+	e.GET("/api/v1/admin/reports/attendance",
+		bookings.AttendanceReportHandler(getConfig, store), requireAuth, requireAdmin)
+	e.GET("/api/v1/admin/orphaned-bookings", orphans.BookingsHandler(getConfig, store), requireAuth, requireAdmin)
+	e.POST("/api/v1/admin/orphaned-bookings/cancel",
+		orphans.CancelHandler(getConfig, store, notifier), requireAuth, requireAdmin)
+	e.GET("/api/v1/admin/orphaned-floor-plan-positions",
+		orphans.PositionsHandler(getConfig, store), requireAuth, requireAdmin)
+	e.GET("/api/v1/admin/delegations", delegations.AdminListHandler(store), requireAuth, requireAdmin)
+	e.GET("/api/v1/admin/teams", teams.AdminListHandler(store), requireAuth, requireAdmin)
+	e.POST("/api/v1/admin/teams", teams.CreateHandler(store), requireAuth, requireAdmin)
+	e.PATCH("/api/v1/admin/teams/:id", teams.UpdateHandler(store), requireAuth, requireAdmin)
+	e.DELETE("/api/v1/admin/teams/:id", teams.DeleteHandler(store), requireAuth, requireAdmin)
+	e.POST("/api/v1/admin/teams/:id/members", teams.AddMemberHandler(store), requireAuth, requireAdmin)
+	e.DELETE("/api/v1/admin/teams/:id/members/:user_id", teams.RemoveMemberHandler(store), requireAuth, requireAdmin)
+
 	// Floor plan positions (read: any authenticated user, write: admin only)
 	e.GET("/api/v1/floor-plan-positions",
 		floorplanpos.ListHandler(store), requireAuth)
@@ -309,6 +431,101 @@ func registerRoutes(
 		floorplanpos.UpdateHandler(store), requireAuth, requireAdmin)
 	e.DELETE("/api/v1/floor-plan-positions/:id",
 		floorplanpos.DeleteHandler(store), requireAuth, requireAdmin)
+
+	registerSpaceRoutes(e, source, requireAuth, requireAdmin)
+	registerClosureRoutes(e, getConfig, store, notifier, requireAuth, requireAdmin)
+	registerMaintenanceRoutes(e, getConfig, store, notifier, requireAuth, requireAdmin)
+	registerLotteryRoutes(e, getConfig, store, bookingLimits, requireAuth)
+	registerVisitorRoutes(e, getConfig, store, notifier, receptionists, requireAuth)
+}
+
+// registerSpaceRoutes wires the admin endpoints that edit areas, item groups,
+// and items, and import or export the areas YAML.
+func registerSpaceRoutes(e *echo.Echo, source *spaces.Source, requireAuth, requireAdmin echo.MiddlewareFunc) {
+	for kind, path := range map[spaces.Kind]string{
+		spaces.KindArea:      "/api/v1/admin/areas",
+		spaces.KindItemGroup: "/api/v1/admin/item-groups",
+		spaces.KindItem:      "/api/v1/admin/items",
+	} {
+		e.GET(path, spaces.ListHandler(source, kind), requireAuth, requireAdmin)
+		e.POST(path, spaces.CreateHandler(source, kind), requireAuth, requireAdmin)
+		e.PATCH(path+"/:id", spaces.UpdateHandler(source, kind), requireAuth, requireAdmin)
+	}
+	e.GET("/api/v1/admin/areas-config", spaces.ExportHandler(source), requireAuth, requireAdmin)
+	e.PUT("/api/v1/admin/areas-config", spaces.ImportHandler(source), requireAuth, requireAdmin)
+}
+
+// registerClosureRoutes wires office closure endpoints (read: any authenticated user, write: admin only).
+func registerClosureRoutes(
+	e *echo.Echo, getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+	requireAuth, requireAdmin echo.MiddlewareFunc,
+) {
+	e.GET("/api/v1/closures", closures.ListHandler(getConfig, store), requireAuth)
+	e.POST("/api/v1/closures",
+		closures.CreateHandler(getConfig, store, notifier), requireAuth, requireAdmin)
+	e.POST("/api/v1/closures/import",
+		closures.ImportHandler(getConfig, store, notifier), requireAuth, requireAdmin)
+	e.DELETE("/api/v1/closures/:id",
+		closures.DeleteHandler(store), requireAuth, requireAdmin)
+}
+
+// registerMaintenanceRoutes wires item maintenance window endpoints (read: any authenticated user, write: admin only).
+func registerMaintenanceRoutes(
+	e *echo.Echo, getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+	requireAuth, requireAdmin echo.MiddlewareFunc,
+) {
+	e.GET("/api/v1/maintenance-windows", maintenance.ListHandler(getConfig, store), requireAuth)
+	e.POST("/api/v1/maintenance-windows",
+		maintenance.CreateHandler(getConfig, store, notifier), requireAuth, requireAdmin)
+	e.PATCH("/api/v1/maintenance-windows/:id",
+		maintenance.UpdateHandler(getConfig, store, notifier), requireAuth, requireAdmin)
+	e.DELETE("/api/v1/maintenance-windows/:id",
+		maintenance.DeleteHandler(store), requireAuth, requireAdmin)
+}
+
+// registerLotteryRoutes wires lottery request endpoints for the current user.
+func registerLotteryRoutes(
+	e *echo.Echo, getConfig areas.ConfigGetter, store *sql.DB, bookingLimits *bookings.BookingLimits,
+	requireAuth echo.MiddlewareFunc,
+) {
+	e.GET("/api/v1/lottery-requests", lottery.ListHandler(getConfig, store), requireAuth)
+	e.POST("/api/v1/lottery-requests", lottery.CreateHandler(getConfig, store, bookingLimits), requireAuth)
+	e.DELETE("/api/v1/lottery-requests/:id", lottery.DeleteHandler(store), requireAuth)
+}
+
+// registerVisitorRoutes wires visitor and visit endpoints. Receptionists and
+// admins manage the visitors of all hosts; other users only their own.
+func registerVisitorRoutes(
+	e *echo.Echo, getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+	receptionists visitors.Receptionists, requireAuth echo.MiddlewareFunc,
+) {
+	e.GET("/api/v1/visitors", visitors.ListVisitorsHandler(store, receptionists), requireAuth)
+	e.POST("/api/v1/visitors", visitors.CreateVisitorHandler(store), requireAuth)
+	e.GET("/api/v1/visits", visitors.ListVisitsHandler(getConfig, store, receptionists), requireAuth)
+	e.POST("/api/v1/visits", visitors.CreateVisitHandler(getConfig, store), requireAuth)
+	e.POST("/api/v1/visits/:id/check-in",
+		visitors.CheckInHandler(getConfig, store, notifier, receptionists), requireAuth)
+	e.POST("/api/v1/visits/:id/check-out",
+		visitors.CheckOutHandler(getConfig, store, receptionists), requireAuth)
+	e.DELETE("/api/v1/visits/:id", visitors.DeleteVisitHandler(store, receptionists), requireAuth)
+}
+
+// openSpaces returns the source of the areas config: the areas YAML, or the
+// database if areas.source is "database".
+func openSpaces(ctx context.Context, cfg *config.Config, store *sql.DB) (*spaces.Source, error) {
+	if cfg.Areas.Source != spaces.ModeDatabase {
+		areasConfig, err := loadAndValidateAreas(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return spaces.NewYAMLSource(areasConfig, cfg.Areas.ConfigFile), nil
+	}
+	source, err := spaces.OpenDatabase(ctx, store, cfg.Areas.ConfigFile, cfg.Areas.FloorPlansDir)
+	if err != nil {
+		return nil, fmt.Errorf("open spaces: %w", err)
+	}
+	logInvalidIcons(source.Config())
+	return source, nil
 }
 
 func loadAndValidateAreas(cfg *config.Config) (*areas.Config, error) {
@@ -316,13 +533,7 @@ func loadAndValidateAreas(cfg *config.Config) (*areas.Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("load areas config: %w", err)
 	}
-	for _, warning := range areas.FindInvalidConfiguredIcons(areasConfig) {
-		slog.Warn(
-			"invalid configured icon; frontend will fall back to the default icon",
-			"location", warning.Location,
-			"icon", warning.Icon,
-		)
-	}
+	logInvalidIcons(areasConfig)
 	if cfg.Areas.FloorPlansDir != "" {
 		if err := areas.ValidateFloorPlans(areasConfig, cfg.Areas.FloorPlansDir); err != nil {
 			return nil, fmt.Errorf("validate floor plans: %w", err)
@@ -334,6 +545,16 @@ func loadAndValidateAreas(cfg *config.Config) (*areas.Config, error) {
 	return areasConfig, nil
 }
 
+func logInvalidIcons(areasConfig *areas.Config) {
+	for _, warning := range areas.FindInvalidConfiguredIcons(areasConfig) {
+		slog.Warn(
+			"invalid configured icon; frontend will fall back to the default icon",
+			"location", warning.Location,
+			"icon", warning.Icon,
+		)
+	}
+}
+
 func ensureAvatarsDir(dataDir string) (string, error) {
 	dir := filepath.Join(dataDir, "avatars")
 	if err := os.MkdirAll(dir, 0o750); err != nil {
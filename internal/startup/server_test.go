@@ -29,6 +29,7 @@ import (
 	"github.com/thorstenkramm/sithub/internal/livefeed"
 	"github.com/thorstenkramm/sithub/internal/middleware"
 	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/spaces"
 )
 
 func TestRunShutsDownOnContextCancel(t *testing.T) {
@@ -224,9 +225,9 @@ func TestBodyLimitSkipsAvatarUpload(t *testing.T) {
 	e.Use(middleware.LoadUser(authService))
 	avatarsDir := t.TempDir()
 	registerRoutes(
-		e, authService, &areas.Config{},
+		e, authService, spaces.NewYAMLSource(&areas.Config{}, ""),
 		t.TempDir(), avatarsDir, nil,
-		notifications.NewNotifier(""), livefeed.NewHub(), nil, "test-version",
+		notifications.NewNotifier(""), livefeed.NewHub(), nil, nil, 0, "test-version",
 	)
 
 	body, contentType := multipartAvatarBody(t, paddedPNG(t, 3<<20))
@@ -258,9 +259,9 @@ func TestAvatarUploadHasRouteSpecificBodyLimit(t *testing.T) {
 	authService := newTestAuthService(t)
 	e.Use(middleware.LoadUser(authService))
 	registerRoutes(
-		e, authService, &areas.Config{},
+		e, authService, spaces.NewYAMLSource(&areas.Config{}, ""),
 		t.TempDir(), t.TempDir(), nil,
-		notifications.NewNotifier(""), livefeed.NewHub(), nil, "test-version",
+		notifications.NewNotifier(""), livefeed.NewHub(), nil, nil, 0, "test-version",
 	)
 
 	body, contentType := multipartAvatarBody(t, paddedPNG(t, 5<<20))
@@ -281,9 +282,9 @@ func TestBodyLimitAllowsNormalBookingRequest(t *testing.T) {
 	e.Use(middleware.LoadUser(authService))
 	store := setupStartupTestStore(t)
 	registerRoutes(
-		e, authService, testAreasConfig(),
+		e, authService, spaces.NewYAMLSource(testAreasConfig(), ""),
 		t.TempDir(), t.TempDir(), store,
-		notifications.NewNotifier(""), livefeed.NewHub(), nil, "test-version",
+		notifications.NewNotifier(""), livefeed.NewHub(), nil, nil, 0, "test-version",
 	)
 
 	bookingDate := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
@@ -386,9 +387,9 @@ func setupTestRouter(t *testing.T) *echo.Echo {
 	authService := newTestAuthService(t)
 	e.Use(middleware.LoadUser(authService))
 	registerRoutes(
-		e, authService, &areas.Config{},
+		e, authService, spaces.NewYAMLSource(&areas.Config{}, ""),
 		t.TempDir(), t.TempDir(), nil,
-		notifications.NewNotifier(""), livefeed.NewHub(), nil, "test-version",
+		notifications.NewNotifier(""), livefeed.NewHub(), nil, nil, 0, "test-version",
 	)
 	return e
 }
@@ -420,9 +421,9 @@ func TestFloorPlanPositionsWriteRouteRequiresAdmin(t *testing.T) {
 	authService := newTestAuthService(t)
 	e.Use(middleware.LoadUser(authService))
 	registerRoutes(
-		e, authService, &areas.Config{},
+		e, authService, spaces.NewYAMLSource(&areas.Config{}, ""),
 		t.TempDir(), t.TempDir(), nil,
-		notifications.NewNotifier(""), livefeed.NewHub(), nil, "test-version",
+		notifications.NewNotifier(""), livefeed.NewHub(), nil, nil, 0, "test-version",
 	)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/floor-plan-positions", http.NoBody)
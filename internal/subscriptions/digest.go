@@ -0,0 +1,219 @@
+package subscriptions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+// eventBuffer is the size of the digester's incoming event queue. Like the
+// live feed hub, NotifyAsync drops events with a warning rather than blocking
+// the booking handler when the queue is full.
+const eventBuffer = 256
+
+// Digester collects the booking events matching subscriptions and sends each
+// subscriber one subscription.digest event per window instead of one message
+// per booking. A booking created and canceled within a window is left out.
+//
+// Pending digests are kept in memory and sent when Run returns.
+type Digester struct {
+	getConfig areas.ConfigGetter
+	store     *sql.DB
+	out       notifications.Notifier
+	window    time.Duration
+	events    chan *notifications.BookingEvent
+	running   atomic.Bool
+	// pending is only accessed by the Run goroutine.
+	pending map[string]*pendingDigest
+}
+
+type pendingDigest struct {
+	since   time.Time
+	entries []pendingEntry
+}
+
+// pendingEntry keeps the booking ID next to the entry to drop bookings that
+// were canceled before the digest was sent.
+type pendingEntry struct {
+	bookingID string
+	entry     notifications.DigestEntry
+}
+
+// NewDigester creates an unstarted digester that sends digests to out. Call
+// Run in a goroutine to start it.
+func NewDigester(
+	getConfig areas.ConfigGetter, store *sql.DB, out notifications.Notifier, window time.Duration,
+) *Digester {
+	return &Digester{
+		getConfig: getConfig,
+		store:     store,
+		out:       out,
+		window:    window,
+		events:    make(chan *notifications.BookingEvent, eventBuffer),
+		pending:   make(map[string]*pendingDigest),
+	}
+}
+
+// NotifyAsync implements notifications.Notifier. It queues created and
+// canceled bookings for matching and never blocks.
+func (d *Digester) NotifyAsync(event *notifications.BookingEvent) {
+	if event == nil || !d.running.Load() {
+		return
+	}
+	if event.Event != notifications.EventBookingCreated && event.Event != notifications.EventBookingCanceled {
+		return
+	}
+	select {
+	case d.events <- event:
+	default:
+		slog.Warn("subscription queue full, dropping event", "event", event.Event, "booking_id", event.BookingID)
+	}
+}
+
+// Run matches queued events against the subscriptions and sends the digests
+// whose window has passed, checking every interval. Area subscriptions for
+// past dates are purged once a day. Run blocks until ctx is canceled and then
+// sends all pending digests.
+func (d *Digester) Run(ctx context.Context, interval time.Duration) {
+	d.running.Store(true)
+	defer d.running.Store(false)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	purged := ""
+	for {
+		select {
+		case <-ctx.Done():
+			d.flush(time.Time{})
+			return
+		case ev := <-d.events:
+			if err := d.collect(ctx, ev); err != nil {
+				slog.Error("match subscriptions", "booking_id", ev.BookingID, "error", err)
+			}
+		case now := <-ticker.C:
+			d.flush(now)
+			if today := now.UTC().Format(time.DateOnly); today != purged {
+				purged = today
+				// Dates are local to the area, so keep yesterday's subscriptions.
+				before := now.UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+				if n, err := PurgeExpired(ctx, d.store, before); err != nil {
+					slog.Error("purge subscriptions", "error", err)
+				} else if n > 0 {
+					slog.Info("expired subscriptions purged", "before", before, "count", n)
+				}
+			}
+		}
+	}
+}
+
+// collect adds the event to the pending digest of every subscriber following
+// the booked user, item group, or area. Subscribers are not told about their
+// own bookings or changes they made themselves, and guest bookings do not
+// match subscriptions to the booking user. Private bookings are left out.
+func (d *Digester) collect(ctx context.Context, ev *notifications.BookingEvent) error {
+	if ev.Private {
+		return nil
+	}
+	entry := notifications.DigestEntry{
+		Event:       ev.Event,
+		BookingDate: ev.BookingDate,
+		ItemID:      ev.ItemID,
+		Timestamp:   ev.Timestamp,
+	}
+	var itemGroupID, areaID string
+	if loc, ok := d.getConfig().FindItemLocation(ev.ItemID); ok {
+		itemGroupID, areaID = loc.ItemGroup.ID, loc.Area.ID
+		entry.ItemName = loc.Item.Name
+		entry.ItemGroupID, entry.ItemGroupName = loc.ItemGroup.ID, loc.ItemGroup.Name
+		entry.AreaID, entry.AreaName = loc.Area.ID, loc.Area.Name
+	}
+	followedUserID := ev.UserID
+	if ev.IsGuest {
+		followedUserID = ""
+	}
+
+	subs, err := FindMatching(ctx, d.store, followedUserID, itemGroupID, areaID, ev.BookingDate)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	if ev.IsGuest {
+		entry.UserName = ev.GuestName
+	} else {
+		names, err := users.FindDisplayNames(ctx, d.store, []string{ev.UserID})
+		if err != nil {
+			return fmt.Errorf("find user names: %w", err)
+		}
+		entry.UserID, entry.UserName = ev.UserID, names[ev.UserID]
+	}
+
+	actor := ev.BookedByUserID
+	if ev.Event == notifications.EventBookingCanceled {
+		actor = ev.CanceledByUserID
+	}
+	seen := make(map[string]bool, len(subs))
+	for i := range subs {
+		s := &subs[i]
+		if seen[s.UserID] || s.UserID == ev.UserID || s.UserID == actor {
+			continue
+		}
+		seen[s.UserID] = true
+		e := entry
+		e.SubscriptionID = s.ID
+		d.add(s.UserID, ev.BookingID, &e)
+	}
+	return nil
+}
+
+// add appends the entry to the subscriber's pending digest. A cancellation
+// removes the pending creation of the same booking instead.
+func (d *Digester) add(subscriberID, bookingID string, e *notifications.DigestEntry) {
+	p := d.pending[subscriberID]
+	if p == nil {
+		p = &pendingDigest{since: time.Now()}
+		d.pending[subscriberID] = p
+	}
+	if e.Event == notifications.EventBookingCanceled {
+		for i := range p.entries {
+			pe := &p.entries[i]
+			if pe.bookingID == bookingID && pe.entry.Event == notifications.EventBookingCreated {
+				p.entries = append(p.entries[:i], p.entries[i+1:]...)
+				return
+			}
+		}
+	}
+	p.entries = append(p.entries, pendingEntry{bookingID: bookingID, entry: *e})
+}
+
+// flush sends the digests that have been pending for the window at now, or
+// all digests if now is zero.
+func (d *Digester) flush(now time.Time) {
+	for subscriberID, p := range d.pending {
+		if !now.IsZero() && now.Sub(p.since) < d.window {
+			continue
+		}
+		delete(d.pending, subscriberID)
+		if len(p.entries) == 0 {
+			continue
+		}
+		entries := make([]notifications.DigestEntry, len(p.entries))
+		for i := range p.entries {
+			entries[i] = p.entries[i].entry
+		}
+		d.out.NotifyAsync(&notifications.BookingEvent{
+			Event:     notifications.EventSubscriptionDigest,
+			UserID:    subscriberID,
+			Digest:    entries,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
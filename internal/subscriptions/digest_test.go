@@ -0,0 +1,127 @@
+package subscriptions
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*notifications.BookingEvent
+}
+
+func (r *recordingNotifier) NotifyAsync(event *notifications.BookingEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordingNotifier) digests() map[string][]notifications.DigestEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string][]notifications.DigestEntry, len(r.events))
+	for _, ev := range r.events {
+		out[ev.UserID] = ev.Digest
+	}
+	return out
+}
+
+func TestDigesterCollectsMatchingBookings(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	ctx := t.Context()
+	date := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	for _, s := range []*Subscription{
+		{UserID: ada.ID, Kind: KindUser, TargetID: bob.ID},
+		{UserID: ada.ID, Kind: KindItemGroup, TargetID: "room-1"},
+		{UserID: eve.ID, Kind: KindArea, TargetID: "office", BookingDate: date},
+	} {
+		require.NoError(t, Create(ctx, store, s))
+	}
+
+	out := &recordingNotifier{}
+	d := NewDigester(testConfig, store, out, time.Hour)
+	for _, ev := range []*notifications.BookingEvent{
+		// Matches Ada twice (Bob and room 1) and Eve (office on date).
+		{Event: notifications.EventBookingCreated, BookingID: "b1", ItemID: "desk-1", UserID: bob.ID,
+			BookingDate: date, Fields: map[string]any{"secret": "x"}},
+		// Booked and canceled within the window: left out for everyone.
+		{Event: notifications.EventBookingCreated, BookingID: "b2", ItemID: "desk-2", UserID: bob.ID,
+			BookingDate: date},
+		{Event: notifications.EventBookingCanceled, BookingID: "b2", ItemID: "desk-2", UserID: bob.ID,
+			BookingDate: date},
+		// Eve's own booking in the office, and a booking Eve made for Bob.
+		{Event: notifications.EventBookingCreated, BookingID: "b3", ItemID: "desk-2", UserID: eve.ID,
+			BookingDate: date},
+		{Event: notifications.EventBookingCreated, BookingID: "b4", ItemID: "desk-2", UserID: bob.ID,
+			BookedByUserID: eve.ID, BookingDate: date},
+		// Bob's guest in room 1 matches the item group but not Bob.
+		{Event: notifications.EventBookingCreated, BookingID: "b5", ItemID: "desk-1", UserID: bob.ID,
+			IsGuest: true, GuestName: "Gus", GuestEmail: "gus@example.com", BookingDate: date},
+		// Bob's private booking in room 1 is not shared with subscribers.
+		{Event: notifications.EventBookingCreated, BookingID: "b7", ItemID: "desk-1", UserID: bob.ID,
+			BookingDate: date, Private: true},
+		// The garage is not followed by anyone.
+		{Event: notifications.EventBookingCreated, BookingID: "b6", ItemID: "p1", UserID: eve.ID,
+			BookingDate: date},
+	} {
+		require.NoError(t, d.collect(ctx, ev))
+	}
+
+	d.flush(time.Now())
+	assert.Empty(t, out.digests(), "digests wait for the window")
+	d.flush(time.Now().Add(time.Hour))
+
+	got := out.digests()
+	require.Len(t, got, 2)
+	adaDigest := got[ada.ID]
+	require.Len(t, adaDigest, 3)
+	assert.Equal(t, "desk-1", adaDigest[0].ItemID)
+	assert.Equal(t, "Bob", adaDigest[0].UserName)
+	assert.Equal(t, "Room 1", adaDigest[0].ItemGroupName)
+	assert.Equal(t, "desk-2", adaDigest[1].ItemID)
+	assert.Equal(t, "Gus", adaDigest[2].UserName)
+	assert.Empty(t, adaDigest[2].UserID)
+
+	eveDigest := got[eve.ID]
+	require.Len(t, eveDigest, 2)
+	assert.Equal(t, "desk-1", eveDigest[0].ItemID)
+	assert.Equal(t, "Gus", eveDigest[1].UserName)
+
+	// Digests only carry what other users see of a booking.
+	payload, err := json.Marshal(out.events)
+	require.NoError(t, err)
+	assert.NotContains(t, string(payload), "gus@example.com")
+	assert.NotContains(t, string(payload), "secret")
+	assert.NotContains(t, string(payload), `"b1"`)
+
+	d.flush(time.Time{})
+	assert.Len(t, out.events, 2, "nothing left to send")
+}
+
+func TestPurgeExpired(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	ctx := t.Context()
+	require.NoError(t, Create(ctx, store, &Subscription{UserID: ada.ID, Kind: KindUser, TargetID: bob.ID}))
+	require.NoError(t, Create(ctx, store,
+		&Subscription{UserID: ada.ID, Kind: KindArea, TargetID: "office", BookingDate: "2026-01-05"}))
+	require.NoError(t, Create(ctx, store,
+		&Subscription{UserID: ada.ID, Kind: KindArea, TargetID: "office", BookingDate: "2026-01-06"}))
+
+	n, err := PurgeExpired(ctx, store, "2026-01-06")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	list, err := List(ctx, store, ada.ID)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
@@ -0,0 +1,213 @@
+package subscriptions
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const resourceTypeSubscription = "subscriptions"
+
+// Attributes represents subscription resource attributes. TargetName is empty
+// if the followed item group or area was removed from the areas config.
+type Attributes struct {
+	Kind       string `json:"kind"`
+	TargetID   string `json:"target_id"`
+	TargetName string `json:"target_name"`
+	Date       string `json:"date,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type createRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			Kind     string `json:"kind"`
+			TargetID string `json:"target_id"`
+			Date     string `json:"date"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns the current user's subscriptions.
+// GET /api/v1/subscriptions
+func ListHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		ctx := c.Request().Context()
+		list, err := List(ctx, store, user.ID)
+		if err != nil {
+			return api.WriteInternalError(c, "list subscriptions", err)
+		}
+		var userIDs []string
+		for i := range list {
+			if list[i].Kind == KindUser {
+				userIDs = append(userIDs, list[i].TargetID)
+			}
+		}
+		names, err := users.FindDisplayNames(ctx, store, userIDs)
+		if err != nil {
+			return api.WriteInternalError(c, "find user names", err)
+		}
+		cfg := getConfig()
+		resources := api.MapResources(list, func(s Subscription) api.Resource {
+			return subscriptionResource(&s, targetName(cfg, &s, names))
+		})
+		return api.WriteCollection(c, resources, "write subscriptions response")
+	}
+}
+
+// CreateHandler subscribes the current user to the bookings of a colleague
+// (kind user), an item group (kind item_group), or an area on a date (kind
+// area). Matching bookings are sent in digests through the notification
+// channels.
+// POST /api/v1/subscriptions
+func CreateHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req createRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeSubscription {
+			return api.WriteBadRequest(c, "Resource type must be 'subscriptions'")
+		}
+		a := req.Data.Attributes
+		s := &Subscription{
+			UserID:      user.ID,
+			Kind:        a.Kind,
+			TargetID:    strings.TrimSpace(a.TargetID),
+			BookingDate: strings.TrimSpace(a.Date),
+		}
+		if s.TargetID == "" {
+			return api.WriteBadRequest(c, "target_id is required")
+		}
+		if s.Kind != KindArea && s.BookingDate != "" {
+			return api.WriteBadRequest(c, "date is only allowed for area subscriptions")
+		}
+
+		ctx := c.Request().Context()
+		cfg := getConfig()
+		var name string
+		switch s.Kind {
+		case KindUser:
+			if s.TargetID == user.ID {
+				return api.WriteBadRequest(c, "Users cannot follow themselves")
+			}
+			rec, err := users.FindByID(ctx, store, s.TargetID)
+			if errors.Is(err, users.ErrUserNotFound) {
+				return api.WriteBadRequest(c, "User not found: "+s.TargetID)
+			}
+			if err != nil {
+				return api.WriteInternalError(c, "find user", err)
+			}
+			name = rec.DisplayName
+		case KindItemGroup:
+			ig, ok := cfg.FindItemGroup(s.TargetID)
+			if !ok {
+				return api.WriteNotFound(c, "Item group not found")
+			}
+			name = ig.Name
+		case KindArea:
+			area, ok := cfg.FindArea(s.TargetID)
+			if !ok {
+				return api.WriteNotFound(c, "Area not found")
+			}
+			date, err := time.Parse(time.DateOnly, s.BookingDate)
+			if err != nil {
+				return api.WriteBadRequest(c, "Area subscriptions need a date. Use YYYY-MM-DD.")
+			}
+			if date.Format(time.DateOnly) < time.Now().In(cfg.AreaZone(area)).Format(time.DateOnly) {
+				return api.WriteBadRequest(c, "date must not be in the past")
+			}
+			name = area.Name
+		default:
+			return api.WriteBadRequest(c, "kind must be 'user', 'item_group', or 'area'")
+		}
+
+		err := Create(ctx, store, s)
+		if errors.Is(err, ErrExists) {
+			return api.WriteConflict(c, "This subscription already exists")
+		}
+		if errors.Is(err, ErrLimitReached) {
+			return api.WriteConflict(c, "A user can have at most 50 subscriptions")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "create subscription", err)
+		}
+		slog.Info("subscription created",
+			"subscription_id", s.ID, "user_id", s.UserID, "kind", s.Kind, "target_id", s.TargetID)
+		return api.WriteSingle(c, http.StatusCreated, subscriptionResource(s, name), "write subscription response")
+	}
+}
+
+// DeleteHandler removes one of the current user's subscriptions.
+// DELETE /api/v1/subscriptions/:id
+func DeleteHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		ctx := c.Request().Context()
+		s, err := Find(ctx, store, c.Param("id"))
+		if errors.Is(err, ErrNotFound) || (err == nil && s.UserID != user.ID) {
+			return api.WriteNotFound(c, "Subscription not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find subscription", err)
+		}
+		if err := Delete(ctx, store, s.ID); err != nil {
+			return api.WriteInternalError(c, "delete subscription", err)
+		}
+		slog.Info("subscription deleted", "subscription_id", s.ID, "user_id", s.UserID)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func targetName(cfg *areas.Config, s *Subscription, userNames map[string]string) string {
+	switch s.Kind {
+	case KindUser:
+		return userNames[s.TargetID]
+	case KindItemGroup:
+		if ig, ok := cfg.FindItemGroup(s.TargetID); ok {
+			return ig.Name
+		}
+	case KindArea:
+		if area, ok := cfg.FindArea(s.TargetID); ok {
+			return area.Name
+		}
+	}
+	return ""
+}
+
+func subscriptionResource(s *Subscription, name string) api.Resource {
+	return api.Resource{
+		Type: resourceTypeSubscription,
+		ID:   s.ID,
+		Attributes: Attributes{
+			Kind:       s.Kind,
+			TargetID:   s.TargetID,
+			TargetName: name,
+			Date:       s.BookingDate,
+			CreatedAt:  s.CreatedAt,
+		},
+	}
+}
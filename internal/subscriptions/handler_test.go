@@ -0,0 +1,154 @@
+package subscriptions
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/db"
+)
+
+var (
+	ada = &auth.User{ID: "ada", Name: "Ada"}
+	bob = &auth.User{ID: "bob", Name: "Bob"}
+	eve = &auth.User{ID: "eve", Name: "Eve"}
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, u := range []*auth.User{ada, bob, eve} {
+		_, err := store.Exec(
+			`INSERT INTO users (id, email, display_name, user_source, created_at, updated_at)
+			 VALUES (?, ?, ?, 'internal', ?, ?)`,
+			u.ID, u.ID+"@example.com", u.Name, now, now,
+		)
+		require.NoError(t, err)
+	}
+	return store
+}
+
+func testConfig() *areas.Config {
+	return &areas.Config{Areas: []areas.Area{
+		{
+			ID: "office", Name: "Office",
+			ItemGroups: []areas.ItemGroup{
+				{ID: "room-1", Name: "Room 1", Items: []areas.Item{{ID: "desk-1", Name: "Desk 1"}}},
+				{ID: "room-2", Name: "Room 2", Items: []areas.Item{{ID: "desk-2", Name: "Desk 2"}}},
+			},
+		},
+		{
+			ID: "garage", Name: "Garage",
+			ItemGroups: []areas.ItemGroup{{ID: "level-1", Name: "Level 1", Items: []areas.Item{{ID: "p1"}}}},
+		},
+	}}
+}
+
+func serve(t *testing.T, h echo.HandlerFunc, user *auth.User, method, body, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1/subscriptions", bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if id != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+	}
+	c.Set("user", user)
+	require.NoError(t, h(c))
+	return rec
+}
+
+func subscribe(t *testing.T, store *sql.DB, user *auth.User, kind, targetID, date string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"data": map[string]any{
+		"type":       "subscriptions",
+		"attributes": map[string]string{"kind": kind, "target_id": targetID, "date": date},
+	}})
+	require.NoError(t, err)
+	return serve(t, CreateHandler(testConfig, store), user, http.MethodPost, string(body), "")
+}
+
+func TestSubscriptionHandlers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	yesterday := time.Now().AddDate(0, 0, -2).Format(time.DateOnly)
+
+	rec := subscribe(t, store, ada, KindUser, "bob", "")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp struct {
+		Data struct {
+			ID         string     `json:"id"`
+			Attributes Attributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Bob", resp.Data.Attributes.TargetName)
+	require.Equal(t, http.StatusCreated, subscribe(t, store, ada, KindItemGroup, "room-1", "").Code)
+	require.Equal(t, http.StatusCreated, subscribe(t, store, ada, KindArea, "office", tomorrow).Code)
+
+	for _, tc := range []struct {
+		kind, target, date string
+		want               int
+	}{
+		{KindUser, "bob", "", http.StatusConflict},
+		{KindUser, "ada", "", http.StatusBadRequest},
+		{KindUser, "nobody", "", http.StatusBadRequest},
+		{KindUser, "eve", tomorrow, http.StatusBadRequest},
+		{KindItemGroup, "room-9", "", http.StatusNotFound},
+		{KindArea, "office", "", http.StatusBadRequest},
+		{KindArea, "office", yesterday, http.StatusBadRequest},
+		{KindArea, "attic", tomorrow, http.StatusNotFound},
+		{"item", "desk-1", "", http.StatusBadRequest},
+		{KindUser, " ", "", http.StatusBadRequest},
+	} {
+		assert.Equal(t, tc.want, subscribe(t, store, ada, tc.kind, tc.target, tc.date).Code, tc)
+	}
+
+	rec = serve(t, ListHandler(testConfig, store), ada, http.MethodGet, "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Data, 3)
+	assert.Contains(t, rec.Body.String(), `"target_name":"Room 1"`)
+	assert.Contains(t, rec.Body.String(), `"date":"`+tomorrow+`"`)
+
+	del := DeleteHandler(store)
+	assert.Equal(t, http.StatusNotFound, serve(t, del, bob, http.MethodDelete, "", resp.Data.ID).Code)
+	assert.Equal(t, http.StatusNoContent, serve(t, del, ada, http.MethodDelete, "", resp.Data.ID).Code)
+	assert.Equal(t, http.StatusNotFound, serve(t, del, ada, http.MethodDelete, "", resp.Data.ID).Code)
+}
+
+func TestSubscriptionLimit(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	for i := range MaxSubscriptions {
+		date := time.Now().AddDate(0, 0, i+1).Format(time.DateOnly)
+		require.NoError(t, Create(t.Context(), store,
+			&Subscription{UserID: ada.ID, Kind: KindArea, TargetID: "office", BookingDate: date}))
+	}
+	rec := subscribe(t, store, ada, KindUser, "bob", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "at most 50")
+}
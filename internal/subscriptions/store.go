@@ -0,0 +1,169 @@
+// Package subscriptions lets users follow colleagues, item groups, and areas
+// and sends them digests of the matching bookings.
+package subscriptions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Subscription kinds.
+const (
+	KindUser      = "user"
+	KindItemGroup = "item_group"
+	KindArea      = "area"
+)
+
+// MaxSubscriptions is the maximum number of subscriptions per user.
+const MaxSubscriptions = 50
+
+// ErrNotFound indicates the requested subscription does not exist.
+var ErrNotFound = errors.New("subscription not found")
+
+// ErrExists indicates the user already has the same subscription.
+var ErrExists = errors.New("subscription already exists")
+
+// ErrLimitReached indicates the user already has MaxSubscriptions.
+var ErrLimitReached = errors.New("subscription limit reached")
+
+// Subscription lets UserID follow the bookings of a user, an item group, or
+// an area on BookingDate, depending on Kind.
+type Subscription struct {
+	ID          string
+	UserID      string
+	Kind        string
+	TargetID    string
+	BookingDate string
+	CreatedAt   string
+}
+
+const subscriptionColumns = `id, user_id, kind, target_id, booking_date, created_at`
+
+func scanSubscription(row interface{ Scan(...any) error }, s *Subscription) error {
+	return row.Scan(&s.ID, &s.UserID, &s.Kind, &s.TargetID, &s.BookingDate, &s.CreatedAt)
+}
+
+// Create stores s with a new ID. Returns ErrExists if the user has the same
+// subscription and ErrLimitReached if the user has MaxSubscriptions.
+func Create(ctx context.Context, db *sql.DB, s *Subscription) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var count int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM subscriptions WHERE user_id = ?`, s.UserID,
+	).Scan(&count); err != nil {
+		return fmt.Errorf("count subscriptions: %w", err)
+	}
+	if count >= MaxSubscriptions {
+		return ErrLimitReached
+	}
+
+	s.ID = uuid.NewString()
+	s.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO subscriptions (`+subscriptionColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.ID, s.UserID, s.Kind, s.TargetID, s.BookingDate, s.CreatedAt,
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("insert subscription: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit subscription: %w", err)
+	}
+	return nil
+}
+
+// Find returns the subscription with the given ID, or ErrNotFound.
+func Find(ctx context.Context, db *sql.DB, id string) (*Subscription, error) {
+	var s Subscription
+	err := scanSubscription(db.QueryRowContext(ctx,
+		`SELECT `+subscriptionColumns+` FROM subscriptions WHERE id = ?`, id), &s)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find subscription: %w", err)
+	}
+	return &s, nil
+}
+
+// Delete removes the subscription with the given ID.
+func Delete(ctx context.Context, db *sql.DB, id string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete subscription: %w", err)
+	}
+	return nil
+}
+
+// List returns the subscriptions of a user, oldest first.
+func List(ctx context.Context, db *sql.DB, userID string) ([]Subscription, error) {
+	return query(ctx, db,
+		`SELECT `+subscriptionColumns+` FROM subscriptions WHERE user_id = ? ORDER BY created_at, id`, userID)
+}
+
+// FindMatching returns the subscriptions following userID, itemGroupID, or
+// areaID on bookingDate. Empty IDs match nothing.
+func FindMatching(
+	ctx context.Context, db *sql.DB, userID, itemGroupID, areaID, bookingDate string,
+) ([]Subscription, error) {
+	return query(ctx, db,
+		`SELECT `+subscriptionColumns+` FROM subscriptions
+		 WHERE (kind = ? AND target_id = ? AND target_id != '')
+		    OR (kind = ? AND target_id = ? AND target_id != '')
+		    OR (kind = ? AND target_id = ? AND target_id != '' AND booking_date = ?)
+		 ORDER BY created_at, id`,
+		KindUser, userID, KindItemGroup, itemGroupID, KindArea, areaID, bookingDate)
+}
+
+// PurgeExpired deletes the area subscriptions for dates before the given
+// date (YYYY-MM-DD) and returns how many were deleted.
+func PurgeExpired(ctx context.Context, db *sql.DB, before string) (int64, error) {
+	res, err := db.ExecContext(ctx,
+		`DELETE FROM subscriptions WHERE booking_date != '' AND booking_date < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge subscriptions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("purge subscriptions: %w", err)
+	}
+	return n, nil
+}
+
+func query(ctx context.Context, db *sql.DB, q string, args ...any) (result []Subscription, err error) {
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query subscriptions: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close subscriptions rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var s Subscription
+		if err := scanSubscription(rows, &s); err != nil {
+			return nil, fmt.Errorf("scan subscription: %w", err)
+		}
+		result = append(result, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate subscriptions: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,344 @@
+package teams
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const (
+	resourceTypeTeam       = "teams"
+	resourceTypeTeamMember = "team-members"
+	maxTeamNameLength      = 100
+)
+
+// Attributes represents team resource attributes.
+type Attributes struct {
+	Name        string `json:"name"`
+	IdPGroupID  string `json:"idp_group_id,omitempty"`
+	MemberCount int    `json:"member_count"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// MemberAttributes represents team member resource attributes.
+type MemberAttributes struct {
+	UserID    string `json:"user_id"`
+	UserName  string `json:"user_name"`
+	Source    string `json:"source"`
+	CreatedAt string `json:"created_at"`
+}
+
+type teamRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			Name       *string `json:"name"`
+			IdPGroupID *string `json:"idp_group_id"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type memberRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			UserID string `json:"user_id"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns the teams the current user is a member of.
+// GET /api/v1/teams
+func ListHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		return writeList(c, store, user.ID)
+	}
+}
+
+// AdminListHandler returns all teams.
+// GET /api/v1/admin/teams
+func AdminListHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return writeList(c, store, "")
+	}
+}
+
+// CreateHandler creates a team. Members of the Entra ID group idp_group_id are
+// added to the team on their next login.
+// POST /api/v1/admin/teams
+func CreateHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req, ok, err := decodeTeamRequest(c)
+		if !ok {
+			return err
+		}
+		a := req.Data.Attributes
+		if a.Name == nil {
+			return api.WriteBadRequest(c, "name is required")
+		}
+		t := &Team{}
+		if detail := applyTeamAttributes(t, a.Name, a.IdPGroupID); detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+		err = Create(c.Request().Context(), store, t)
+		if errors.Is(err, ErrExists) {
+			return api.WriteConflict(c, "A team with this name or IdP group already exists")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "create team", err)
+		}
+		slog.Info("team created", "team_id", t.ID, "name", t.Name, "idp_group_id", t.IdPGroupID)
+		return api.WriteSingle(c, http.StatusCreated, teamResource(t), "write team response")
+	}
+}
+
+// UpdateHandler renames a team or changes its IdP group.
+// PATCH /api/v1/admin/teams/:id
+func UpdateHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req, ok, err := decodeTeamRequest(c)
+		if !ok {
+			return err
+		}
+		ctx := c.Request().Context()
+		t, err := Find(ctx, store, c.Param("id"))
+		if errors.Is(err, ErrNotFound) {
+			return api.WriteNotFound(c, "Team not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find team", err)
+		}
+		a := req.Data.Attributes
+		if detail := applyTeamAttributes(t, a.Name, a.IdPGroupID); detail != "" {
+			return api.WriteBadRequest(c, detail)
+		}
+		err = Update(ctx, store, t)
+		if errors.Is(err, ErrExists) {
+			return api.WriteConflict(c, "A team with this name or IdP group already exists")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "update team", err)
+		}
+		slog.Info("team updated", "team_id", t.ID, "name", t.Name, "idp_group_id", t.IdPGroupID)
+		return api.WriteSingle(c, http.StatusOK, teamResource(t), "write team response")
+	}
+}
+
+// decodeTeamRequest parses a team payload. If ok is false, the error response
+// was written and err is the handler's return value.
+func decodeTeamRequest(c echo.Context) (req *teamRequest, ok bool, err error) {
+	req = &teamRequest{}
+	if err := json.NewDecoder(c.Request().Body).Decode(req); err != nil {
+		return nil, false, api.WriteBadRequest(c, "Invalid request body")
+	}
+	if req.Data.Type != resourceTypeTeam {
+		return nil, false, api.WriteBadRequest(c, "Resource type must be 'teams'")
+	}
+	return req, true, nil
+}
+
+// applyTeamAttributes sets the given attributes on t and returns why they are
+// invalid, or "".
+func applyTeamAttributes(t *Team, name, idpGroupID *string) string {
+	if name != nil {
+		t.Name = strings.TrimSpace(*name)
+		if t.Name == "" {
+			return "name must not be empty"
+		}
+		if len(t.Name) > maxTeamNameLength {
+			return "name must be at most 100 characters"
+		}
+	}
+	if idpGroupID != nil {
+		t.IdPGroupID = strings.TrimSpace(*idpGroupID)
+	}
+	return ""
+}
+
+// DeleteHandler deletes a team and its memberships.
+// DELETE /api/v1/admin/teams/:id
+func DeleteHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		t, err := Find(ctx, store, c.Param("id"))
+		if errors.Is(err, ErrNotFound) {
+			return api.WriteNotFound(c, "Team not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find team", err)
+		}
+		if err := Delete(ctx, store, t.ID); err != nil {
+			return api.WriteInternalError(c, "delete team", err)
+		}
+		slog.Info("team deleted", "team_id", t.ID, "name", t.Name)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// ListMembersHandler returns the members of a team. Only members and admins
+// can see a team.
+// GET /api/v1/teams/:id/members
+func ListMembersHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, ok, err := findVisibleTeam(c, store)
+		if !ok {
+			return err
+		}
+		ctx := c.Request().Context()
+		members, err := ListMembers(ctx, store, t.ID)
+		if err != nil {
+			return api.WriteInternalError(c, "list team members", err)
+		}
+		ids := make([]string, len(members))
+		for i := range members {
+			ids[i] = members[i].UserID
+		}
+		names, err := users.FindDisplayNames(ctx, store, ids)
+		if err != nil {
+			return api.WriteInternalError(c, "find user names", err)
+		}
+		resources := api.MapResources(members, func(m Member) api.Resource { return memberResource(&m, names) })
+		return api.WriteCollection(c, resources, "write team members response")
+	}
+}
+
+// AddMemberHandler adds a user to a team.
+// POST /api/v1/admin/teams/:id/members
+func AddMemberHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req memberRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeTeamMember {
+			return api.WriteBadRequest(c, "Resource type must be 'team-members'")
+		}
+		userID := strings.TrimSpace(req.Data.Attributes.UserID)
+		if userID == "" {
+			return api.WriteBadRequest(c, "user_id is required")
+		}
+
+		ctx := c.Request().Context()
+		t, err := Find(ctx, store, c.Param("id"))
+		if errors.Is(err, ErrNotFound) {
+			return api.WriteNotFound(c, "Team not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find team", err)
+		}
+		rec, err := users.FindByID(ctx, store, userID)
+		if errors.Is(err, users.ErrUserNotFound) {
+			return api.WriteBadRequest(c, "User not found: "+userID)
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find user", err)
+		}
+
+		m, err := AddMember(ctx, store, t.ID, rec.ID, SourceManual)
+		if errors.Is(err, ErrMemberExists) {
+			return api.WriteConflict(c, "The user already is a member of this team")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "add team member", err)
+		}
+		slog.Info("team member added", "team_id", t.ID, "user_id", rec.ID)
+		names := map[string]string{rec.ID: rec.DisplayName}
+		return api.WriteSingle(c, http.StatusCreated, memberResource(m, names), "write team member response")
+	}
+}
+
+// RemoveMemberHandler removes a user from a team. Members synced from an IdP
+// group are added again on their next login while they are in the group.
+// DELETE /api/v1/admin/teams/:id/members/:user_id
+func RemoveMemberHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		teamID, userID := c.Param("id"), c.Param("user_id")
+		err := RemoveMember(c.Request().Context(), store, teamID, userID)
+		if errors.Is(err, ErrMemberNotFound) {
+			return api.WriteNotFound(c, "Team member not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "remove team member", err)
+		}
+		slog.Info("team member removed", "team_id", teamID, "user_id", userID)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// findVisibleTeam loads the team of the :id parameter if the current user is a
+// member or an admin. If ok is false, the error response was written and err
+// is the handler's return value.
+func findVisibleTeam(c echo.Context, store *sql.DB) (t *Team, ok bool, err error) {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		return nil, false, api.WriteUnauthorized(c)
+	}
+	ctx := c.Request().Context()
+	t, err = Find(ctx, store, c.Param("id"))
+	if errors.Is(err, ErrNotFound) {
+		return nil, false, api.WriteNotFound(c, "Team not found")
+	}
+	if err != nil {
+		return nil, false, api.WriteInternalError(c, "find team", err)
+	}
+	if !user.IsAdmin {
+		member, err := IsMember(ctx, store, t.ID, user.ID)
+		if err != nil {
+			return nil, false, api.WriteInternalError(c, "check team member", err)
+		}
+		if !member {
+			return nil, false, api.WriteNotFound(c, "Team not found")
+		}
+	}
+	return t, true, nil
+}
+
+func writeList(c echo.Context, store *sql.DB, userID string) error {
+	list, err := List(c.Request().Context(), store, userID)
+	if err != nil {
+		return api.WriteInternalError(c, "list teams", err)
+	}
+	resources := api.MapResources(list, func(t Team) api.Resource { return teamResource(&t) })
+	return api.WriteCollection(c, resources, "write teams response")
+}
+
+func teamResource(t *Team) api.Resource {
+	return api.Resource{
+		Type: resourceTypeTeam,
+		ID:   t.ID,
+		Attributes: Attributes{
+			Name:        t.Name,
+			IdPGroupID:  t.IdPGroupID,
+			MemberCount: t.MemberCount,
+			CreatedAt:   t.CreatedAt,
+			UpdatedAt:   t.UpdatedAt,
+		},
+	}
+}
+
+func memberResource(m *Member, names map[string]string) api.Resource {
+	return api.Resource{
+		Type: resourceTypeTeamMember,
+		ID:   m.UserID,
+		Attributes: MemberAttributes{
+			UserID:    m.UserID,
+			UserName:  names[m.UserID],
+			Source:    m.Source,
+			CreatedAt: m.CreatedAt,
+		},
+	}
+}
@@ -0,0 +1,196 @@
+package teams
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/db"
+)
+
+var (
+	ada   = &auth.User{ID: "ada", Name: "Ada"}
+	bob   = &auth.User{ID: "bob", Name: "Bob"}
+	eve   = &auth.User{ID: "eve", Name: "Eve"}
+	admin = &auth.User{ID: "admin", Name: "Admin", IsAdmin: true}
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, u := range []*auth.User{ada, bob, eve, admin} {
+		_, err := store.Exec(
+			`INSERT INTO users (id, email, display_name, user_source, created_at, updated_at)
+			 VALUES (?, ?, ?, 'internal', ?, ?)`,
+			u.ID, u.ID+"@example.com", u.Name, now, now,
+		)
+		require.NoError(t, err)
+	}
+	return store
+}
+
+func serve(
+	t *testing.T, h echo.HandlerFunc, user *auth.User, method, target, body string, params ...string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	c.Set("user", user)
+	require.NoError(t, h(c))
+	return rec
+}
+
+func resourceIDs(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	ids := make([]string, 0, len(resp.Data))
+	for _, r := range resp.Data {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func createTeam(t *testing.T, store *sql.DB, name, idpGroupID string, members ...string) *Team {
+	t.Helper()
+	team := &Team{Name: name, IdPGroupID: idpGroupID}
+	require.NoError(t, Create(t.Context(), store, team))
+	for _, id := range members {
+		_, err := AddMember(t.Context(), store, team.ID, id, SourceManual)
+		require.NoError(t, err)
+	}
+	return team
+}
+
+func TestTeamAdminHandlers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	create := func(attributes string) *httptest.ResponseRecorder {
+		return serve(t, CreateHandler(store), admin, http.MethodPost, "/api/v1/admin/teams",
+			`{"data":{"type":"teams","attributes":{`+attributes+`}}}`)
+	}
+
+	rec := create(`"name":" Platform ","idp_group_id":"grp-1"`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp struct {
+		Data struct {
+			ID         string     `json:"id"`
+			Attributes Attributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	teamID := resp.Data.ID
+	assert.Equal(t, "Platform", resp.Data.Attributes.Name)
+
+	for attributes, want := range map[string]int{
+		`"name":"Platform"`:                     http.StatusConflict,
+		`"name":"Other","idp_group_id":"grp-1"`: http.StatusConflict,
+		`"name":"  "`:                           http.StatusBadRequest,
+		`"idp_group_id":"grp-2"`:                http.StatusBadRequest,
+		`"name":"Sales"`:                        http.StatusCreated,
+	} {
+		assert.Equal(t, want, create(attributes).Code, attributes)
+	}
+
+	rec = serve(t, UpdateHandler(store), admin, http.MethodPatch, "/", `{"data":{"type":"teams","attributes":`+
+		`{"name":"Platform Team","idp_group_id":""}}}`, "id", teamID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "idp_group_id")
+	rec = serve(t, UpdateHandler(store), admin, http.MethodPatch, "/", `{"data":{"type":"teams","attributes":`+
+		`{"name":"Sales"}}}`, "id", teamID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	add := func(userID string) *httptest.ResponseRecorder {
+		return serve(t, AddMemberHandler(store), admin, http.MethodPost, "/",
+			`{"data":{"type":"team-members","attributes":{"user_id":"`+userID+`"}}}`, "id", teamID)
+	}
+	require.Equal(t, http.StatusCreated, add("ada").Code)
+	require.Equal(t, http.StatusCreated, add("bob").Code)
+	assert.Equal(t, http.StatusConflict, add("ada").Code)
+	assert.Equal(t, http.StatusBadRequest, add("nobody").Code)
+
+	assert.Equal(t, []string{teamID}, resourceIDs(t, serve(t, ListHandler(store), ada, http.MethodGet, "/", "")))
+	assert.Empty(t, resourceIDs(t, serve(t, ListHandler(store), eve, http.MethodGet, "/", "")))
+	assert.Len(t, resourceIDs(t, serve(t, AdminListHandler(store), admin, http.MethodGet, "/", "")), 2)
+
+	members := ListMembersHandler(store)
+	rec = serve(t, members, bob, http.MethodGet, "/", "", "id", teamID)
+	assert.Equal(t, []string{"ada", "bob"}, resourceIDs(t, rec))
+	assert.Equal(t, http.StatusNotFound, serve(t, members, eve, http.MethodGet, "/", "", "id", teamID).Code)
+
+	remove := RemoveMemberHandler(store)
+	rec = serve(t, remove, admin, http.MethodDelete, "/", "", "id", teamID, "user_id", "bob")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = serve(t, remove, admin, http.MethodDelete, "/", "", "id", teamID, "user_id", "bob")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.Equal(t, http.StatusNoContent, serve(t, DeleteHandler(store), admin, http.MethodDelete, "/", "",
+		"id", teamID).Code)
+	assert.Equal(t, http.StatusNotFound, serve(t, DeleteHandler(store), admin, http.MethodDelete, "/", "",
+		"id", teamID).Code)
+}
+
+func TestSyncIdPGroups(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	ctx := t.Context()
+	platform := createTeam(t, store, "Platform", "grp-platform")
+	sales := createTeam(t, store, "Sales", "grp-sales", "ada")
+	manual := createTeam(t, store, "Lunch", "")
+
+	teamsOf := func() []string {
+		list, err := List(ctx, store, "ada")
+		require.NoError(t, err)
+		names := make([]string, len(list))
+		for i := range list {
+			names[i] = list[i].Name
+		}
+		return names
+	}
+
+	require.NoError(t, SyncIdPGroups(ctx, store, "ada", []string{"grp-platform", "grp-unknown"}))
+	assert.Equal(t, []string{"Platform", "Sales"}, teamsOf(), "manual memberships are kept")
+
+	_, err := AddMember(ctx, store, manual.ID, "ada", SourceManual)
+	require.NoError(t, err)
+	require.NoError(t, SyncIdPGroups(ctx, store, "ada", []string{"grp-platform"}))
+	members, err := ListMembers(ctx, store, platform.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, SourceIdP, members[0].Source)
+
+	// Leaving the group removes the synced membership only.
+	require.NoError(t, SyncIdPGroups(ctx, store, "ada", nil))
+	assert.Equal(t, []string{"Lunch", "Sales"}, teamsOf())
+	ok, err := IsMember(ctx, store, sales.ID, "ada")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
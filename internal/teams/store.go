@@ -0,0 +1,272 @@
+// Package teams manages teams of users and shows when team members are in.
+package teams
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Membership sources.
+const (
+	SourceManual = "manual"
+	SourceIdP    = "idp"
+)
+
+// ErrNotFound indicates the requested team does not exist.
+var ErrNotFound = errors.New("team not found")
+
+// ErrExists indicates another team has the same name or IdP group.
+var ErrExists = errors.New("team already exists")
+
+// ErrMemberNotFound indicates the user is not a member of the team.
+var ErrMemberNotFound = errors.New("team member not found")
+
+// ErrMemberExists indicates the user already is a member of the team.
+var ErrMemberExists = errors.New("team member already exists")
+
+// Team is a named group of users. Members of the Entra ID group IdPGroupID
+// are added on login.
+type Team struct {
+	ID          string
+	Name        string
+	IdPGroupID  string
+	MemberCount int
+	CreatedAt   string
+	UpdatedAt   string
+}
+
+// Member is a user's membership in a team.
+type Member struct {
+	TeamID    string
+	UserID    string
+	Source    string
+	CreatedAt string
+}
+
+const teamColumns = `t.id, t.name, t.idp_group_id,
+	(SELECT COUNT(*) FROM team_members m WHERE m.team_id = t.id), t.created_at, t.updated_at`
+
+func scanTeam(row interface{ Scan(...any) error }, t *Team) error {
+	return row.Scan(&t.ID, &t.Name, &t.IdPGroupID, &t.MemberCount, &t.CreatedAt, &t.UpdatedAt)
+}
+
+// Create stores t with a new ID. Returns ErrExists if the name or IdP group
+// is taken.
+func Create(ctx context.Context, db *sql.DB, t *Team) error {
+	t.ID = uuid.NewString()
+	t.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	t.UpdatedAt = t.CreatedAt
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO teams (id, name, idp_group_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		t.ID, t.Name, t.IdPGroupID, t.CreatedAt, t.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("insert team: %w", err)
+	}
+	return nil
+}
+
+// Update stores the name and IdP group of t. Returns ErrExists if the name or
+// IdP group is taken and ErrNotFound if the team does not exist.
+func Update(ctx context.Context, db *sql.DB, t *Team) error {
+	t.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	res, err := db.ExecContext(ctx,
+		`UPDATE teams SET name = ?, idp_group_id = ?, updated_at = ? WHERE id = ?`,
+		t.Name, t.IdPGroupID, t.UpdatedAt, t.ID,
+	)
+	if isUniqueViolation(err) {
+		return ErrExists
+	}
+	if err != nil {
+		return fmt.Errorf("update team: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("update team: %w", err)
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Find returns the team with the given ID, or ErrNotFound.
+func Find(ctx context.Context, db *sql.DB, id string) (*Team, error) {
+	var t Team
+	err := scanTeam(db.QueryRowContext(ctx, `SELECT `+teamColumns+` FROM teams t WHERE t.id = ?`, id), &t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find team: %w", err)
+	}
+	return &t, nil
+}
+
+// Delete removes the team with the given ID and its memberships.
+func Delete(ctx context.Context, db *sql.DB, id string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM teams WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete team: %w", err)
+	}
+	return nil
+}
+
+// List returns the teams userID is a member of, or all teams if userID is
+// empty, ordered by name.
+func List(ctx context.Context, db *sql.DB, userID string) (result []Team, err error) {
+	query := `SELECT ` + teamColumns + ` FROM teams t`
+	var args []any
+	if userID != "" {
+		query += ` WHERE t.id IN (SELECT team_id FROM team_members WHERE user_id = ?)`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY t.name, t.id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query teams: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close teams rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var t Team
+		if err := scanTeam(rows, &t); err != nil {
+			return nil, fmt.Errorf("scan team: %w", err)
+		}
+		result = append(result, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate teams: %w", err)
+	}
+	return result, nil
+}
+
+// ListMembers returns the members of a team in the order they joined.
+func ListMembers(ctx context.Context, db *sql.DB, teamID string) (result []Member, err error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT team_id, user_id, source, created_at FROM team_members
+		 WHERE team_id = ? ORDER BY created_at, user_id`, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("query team members: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close team members rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.TeamID, &m.UserID, &m.Source, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan team member: %w", err)
+		}
+		result = append(result, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate team members: %w", err)
+	}
+	return result, nil
+}
+
+// IsMember reports whether userID is a member of the team.
+func IsMember(ctx context.Context, db *sql.DB, teamID, userID string) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM team_members WHERE team_id = ? AND user_id = ?`, teamID, userID,
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("check team member: %w", err)
+	}
+	return n > 0, nil
+}
+
+// AddMember adds userID to the team. Returns ErrMemberExists if the user
+// already is a member.
+func AddMember(ctx context.Context, db *sql.DB, teamID, userID, source string) (*Member, error) {
+	m := &Member{TeamID: teamID, UserID: userID, Source: source, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO team_members (team_id, user_id, source, created_at) VALUES (?, ?, ?, ?)`,
+		m.TeamID, m.UserID, m.Source, m.CreatedAt,
+	)
+	if isUniqueViolation(err) {
+		return nil, ErrMemberExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert team member: %w", err)
+	}
+	return m, nil
+}
+
+// RemoveMember removes userID from the team, or returns ErrMemberNotFound.
+func RemoveMember(ctx context.Context, db *sql.DB, teamID, userID string) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM team_members WHERE team_id = ? AND user_id = ?`, teamID, userID)
+	if err != nil {
+		return fmt.Errorf("delete team member: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete team member: %w", err)
+	}
+	if n == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// SyncIdPGroups makes userID a member of every team whose IdP group is in
+// groupIDs and removes the memberships synced from other groups. Memberships
+// added by admins are kept.
+func SyncIdPGroups(ctx context.Context, db *sql.DB, userID string, groupIDs []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	inGroups := `''`
+	args := []any{userID}
+	if len(groupIDs) > 0 {
+		inGroups = `?` + strings.Repeat(", ?", len(groupIDs)-1)
+		for _, id := range groupIDs {
+			args = append(args, id)
+		}
+	}
+	//nolint:gosec // G202: inGroups holds "?" placeholders only
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM team_members WHERE user_id = ? AND source = '`+SourceIdP+`'
+		 AND team_id NOT IN (SELECT id FROM teams WHERE idp_group_id IN (`+inGroups+`))`, args...,
+	); err != nil {
+		return fmt.Errorf("delete synced team members: %w", err)
+	}
+	insertArgs := append([]any{userID, time.Now().UTC().Format(time.RFC3339)}, args[1:]...)
+	//nolint:gosec // G202: inGroups holds "?" placeholders only
+	if _, err := tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO team_members (team_id, user_id, source, created_at)
+		 SELECT id, ?, '`+SourceIdP+`', ? FROM teams WHERE idp_group_id != '' AND idp_group_id IN (`+inGroups+`)`,
+		insertArgs...,
+	); err != nil {
+		return fmt.Errorf("insert synced team members: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit team sync: %w", err)
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
@@ -0,0 +1,326 @@
+package teams
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/delegations"
+	"github.com/thorstenkramm/sithub/internal/users"
+	"github.com/thorstenkramm/sithub/internal/workstatus"
+)
+
+const (
+	resourceTypeTeamWeek = "team-week-members"
+	resourceTypeTeamDay  = "team-day-suggestions"
+	workdaysPerWeek      = 5
+	daysPerWeek          = 7
+	errorCodeNoTeamDay   = "no_team_day"
+)
+
+// WeekAttributes represents a team member's bookings and work statuses in an
+// ISO week. WorkStatuses only holds the statuses the member lets the viewer
+// see.
+type WeekAttributes struct {
+	UserID       string           `json:"user_id"`
+	UserName     string           `json:"user_name"`
+	Bookings     []WeekBooking    `json:"bookings"`
+	WorkStatuses []WeekWorkStatus `json:"work_statuses"`
+}
+
+// WeekWorkStatus is a team member's work status on a date.
+type WeekWorkStatus struct {
+	Date   string `json:"date"`
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// WeekBooking is a booking of a team member. The item, item group, and area
+// names are empty if the item was removed from the areas config.
+type WeekBooking struct {
+	BookingID     string `json:"booking_id"`
+	BookingDate   string `json:"booking_date"`
+	ItemID        string `json:"item_id"`
+	ItemName      string `json:"item_name,omitempty"`
+	ItemGroupID   string `json:"item_group_id,omitempty"`
+	ItemGroupName string `json:"item_group_name,omitempty"`
+	AreaID        string `json:"area_id,omitempty"`
+	AreaName      string `json:"area_name,omitempty"`
+}
+
+// DaySuggestionAttributes represents the suggested team day of a week. The
+// area and item group have the most free items on the date; they are empty if
+// no item is free and every member is booked already.
+type DaySuggestionAttributes struct {
+	Date string `json:"date"`
+	// MembersIn lists the names of the members already booked on the date.
+	MembersIn      []string `json:"members_in"`
+	MembersMissing int      `json:"members_missing"`
+	AreaID         string   `json:"area_id,omitempty"`
+	AreaName       string   `json:"area_name,omitempty"`
+	ItemGroupID    string   `json:"item_group_id,omitempty"`
+	ItemGroupName  string   `json:"item_group_name,omitempty"`
+	FreeItems      int      `json:"free_items"`
+}
+
+// WeekHandlerDynamic returns each team member's bookings across all areas and
+// work statuses for the ISO week given by the week query parameter (default:
+// current week).
+// GET /api/v1/teams/:id/week
+func WeekHandlerDynamic(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, ok, err := findVisibleTeam(c, store)
+		if !ok {
+			return err
+		}
+		monday, err := api.ParseISOWeek(c.QueryParam("week"))
+		if err != nil {
+			return api.WriteBadRequest(c, "Invalid week. Use YYYY-Www.")
+		}
+		ctx := c.Request().Context()
+		viewer := auth.GetUserFromContext(c)
+		members, names, records, err := loadTeamWeek(ctx, store, viewer, t.ID, monday, daysPerWeek)
+		if err != nil {
+			return api.WriteInternalError(c, "load team week", err)
+		}
+
+		statuses, err := visibleWorkStatuses(ctx, store, viewer.ID, members,
+			monday, daysPerWeek)
+		if err != nil {
+			return api.WriteInternalError(c, "load work statuses", err)
+		}
+
+		cfg := getConfig()
+		byUser := make(map[string][]WeekBooking, len(members))
+		for i := range records {
+			r := &records[i]
+			byUser[r.UserID] = append(byUser[r.UserID], weekBooking(cfg, r))
+		}
+		resources := make([]api.Resource, len(members))
+		for i := range members {
+			id := members[i].UserID
+			list := byUser[id]
+			if list == nil {
+				list = []WeekBooking{}
+			}
+			userStatuses := statuses[id]
+			if userStatuses == nil {
+				userStatuses = []WeekWorkStatus{}
+			}
+			resources[i] = api.Resource{
+				Type: resourceTypeTeamWeek,
+				ID:   id,
+				Attributes: WeekAttributes{
+					UserID: id, UserName: names[id], Bookings: list, WorkStatuses: userStatuses,
+				},
+			}
+		}
+		return api.WriteCollection(c, resources, "write team week response")
+	}
+}
+
+// DaySuggestionHandlerDynamic suggests the workday of the ISO week on which
+// most team members are already booked and one item group still has a free
+// item for every member who is not. Past days are skipped; ties go to the
+// earlier day.
+// GET /api/v1/teams/:id/week/suggestion
+func DaySuggestionHandlerDynamic(
+	getConfig areas.ConfigGetter, store *sql.DB, guards ...bookings.Guard,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		t, ok, err := findVisibleTeam(c, store)
+		if !ok {
+			return err
+		}
+		monday, err := api.ParseISOWeek(c.QueryParam("week"))
+		if err != nil {
+			return api.WriteBadRequest(c, "Invalid week. Use YYYY-Www.")
+		}
+		ctx := c.Request().Context()
+		members, names, records, err := loadTeamWeek(
+			ctx, store, auth.GetUserFromContext(c), t.ID, monday, workdaysPerWeek)
+		if err != nil {
+			return api.WriteInternalError(c, "load team week", err)
+		}
+		if len(members) == 0 {
+			return api.WriteError(c, http.StatusConflict, "The team has no members", errorCodeNoTeamDay)
+		}
+
+		cfg := getConfig()
+		in := make(map[string]map[string]bool)
+		for i := range records {
+			r := &records[i]
+			if in[r.BookingDate] == nil {
+				in[r.BookingDate] = make(map[string]bool)
+			}
+			in[r.BookingDate][r.UserID] = true
+		}
+
+		var best *DaySuggestionAttributes
+		earliest, _ := cfg.TodayRange(time.Now())
+		for i := range workdaysPerWeek {
+			date := monday.AddDate(0, 0, i).Format(time.DateOnly)
+			if date < earliest || (best != nil && len(in[date]) <= len(best.MembersIn)) {
+				continue
+			}
+			missing := len(members) - len(in[date])
+			loc, free, err := roomiestItemGroup(ctx, store, cfg, date, guards)
+			if err != nil {
+				return api.WriteInternalError(c, "find free items", err)
+			}
+			if free < missing {
+				continue
+			}
+			best = &DaySuggestionAttributes{Date: date, MembersIn: []string{}, MembersMissing: missing, FreeItems: free}
+			if loc != nil {
+				best.AreaID, best.AreaName = loc.Area.ID, loc.Area.Name
+				best.ItemGroupID, best.ItemGroupName = loc.ItemGroup.ID, loc.ItemGroup.Name
+			}
+			for j := range members {
+				if in[date][members[j].UserID] {
+					best.MembersIn = append(best.MembersIn, names[members[j].UserID])
+				}
+			}
+		}
+		if best == nil {
+			return api.WriteError(c, http.StatusConflict,
+				"No day of this week has enough free items in one item group for the team", errorCodeNoTeamDay)
+		}
+		return api.WriteSingle(c, http.StatusOK, api.Resource{
+			Type:       resourceTypeTeamDay,
+			ID:         best.Date,
+			Attributes: best,
+		}, "write team day suggestion response")
+	}
+}
+
+// loadTeamWeek returns the team's members, their display names, and their own
+// bookings (not those for guests) in the days starting at monday. Private
+// bookings are left out unless viewer may see who holds them.
+func loadTeamWeek(
+	ctx context.Context, store *sql.DB, viewer *auth.User, teamID string, monday time.Time, days int,
+) ([]Member, map[string]string, []bookings.BookingRecord, error) {
+	members, err := ListMembers(ctx, store, teamID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ids := make([]string, len(members))
+	isMember := make(map[string]bool, len(members))
+	for i := range members {
+		ids[i] = members[i].UserID
+		isMember[ids[i]] = true
+	}
+	names, err := users.FindDisplayNames(ctx, store, ids)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("find user names: %w", err)
+	}
+
+	all, err := bookings.ListBookingsInRange(ctx, store, nil,
+		monday.Format(time.DateOnly), monday.AddDate(0, 0, days-1).Format(time.DateOnly))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	vis, err := delegations.LoadBookerVisibility(ctx, store, viewer)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	records := make([]bookings.BookingRecord, 0, len(all))
+	for i := range all {
+		r := &all[i]
+		if isMember[r.UserID] && !r.IsGuest && vis.Sees(r.UserID, r.BookedByUserID, r.IsPrivate) {
+			records = append(records, all[i])
+		}
+	}
+	return members, names, records, nil
+}
+
+// visibleWorkStatuses returns the work statuses of the members in the days
+// starting at monday that viewerID may see. Admins who are not members of the
+// team only see the statuses visible to everyone.
+func visibleWorkStatuses(
+	ctx context.Context, store *sql.DB, viewerID string, members []Member, monday time.Time, days int,
+) (map[string][]WeekWorkStatus, error) {
+	ids := make([]string, len(members))
+	viewerIsMember := false
+	for i := range members {
+		ids[i] = members[i].UserID
+		viewerIsMember = viewerIsMember || ids[i] == viewerID
+	}
+	visibilities, err := workstatus.FindVisibilities(ctx, store, ids)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := workstatus.ListRange(ctx, store, ids,
+		monday.Format(time.DateOnly), monday.AddDate(0, 0, days-1).Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]WeekWorkStatus, len(members))
+	for i := range entries {
+		e := &entries[i]
+		if workstatus.CanSee(viewerID, e.UserID, visibilities[e.UserID], viewerIsMember) {
+			result[e.UserID] = append(result[e.UserID], WeekWorkStatus{Date: e.Date, Status: e.Status, Note: e.Note})
+		}
+	}
+	return result, nil
+}
+
+// roomiestItemGroup returns the first item of the item group with the most
+// items free on date, and that number. Reserved items and items refused by a
+// guard do not count as free.
+func roomiestItemGroup(
+	ctx context.Context, store *sql.DB, cfg *areas.Config, date string, guards []bookings.Guard,
+) (*areas.ItemLocation, int, error) {
+	booked, err := bookings.FindBookedItemIDs(ctx, store, date)
+	if err != nil {
+		return nil, 0, fmt.Errorf("find booked items: %w", err)
+	}
+	var best *areas.ItemLocation
+	bestFree := 0
+	for i := range cfg.Areas {
+		area := &cfg.Areas[i]
+		for j := range area.ItemGroups {
+			ig := &area.ItemGroups[j]
+			free := 0
+			for k := range ig.Items {
+				loc := &areas.ItemLocation{Area: area, ItemGroup: ig, Item: &ig.Items[k]}
+				if _, taken := booked[loc.Item.ID]; taken || areas.IsReserved(loc, "") {
+					continue
+				}
+				req := &bookings.GuardRequest{Location: loc, Dates: []string{date}}
+				ok, err := bookings.PassesGuards(ctx, guards, req)
+				if err != nil {
+					return nil, 0, err
+				}
+				if ok {
+					free++
+				}
+			}
+			if free > bestFree {
+				best = &areas.ItemLocation{Area: area, ItemGroup: ig, Item: &ig.Items[0]}
+				bestFree = free
+			}
+		}
+	}
+	return best, bestFree, nil
+}
+
+func weekBooking(cfg *areas.Config, r *bookings.BookingRecord) WeekBooking {
+	b := WeekBooking{BookingID: r.ID, BookingDate: r.BookingDate, ItemID: r.ItemID}
+	if loc, ok := cfg.FindItemLocation(r.ItemID); ok {
+		b.ItemName = loc.Item.Name
+		b.ItemGroupID = loc.ItemGroup.ID
+		b.ItemGroupName = loc.ItemGroup.Name
+		b.AreaID = loc.Area.ID
+		b.AreaName = loc.Area.Name
+	}
+	return b
+}
@@ -0,0 +1,170 @@
+package teams
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/workstatus"
+)
+
+func weekAreasConfig() *areas.Config {
+	return &areas.Config{Areas: []areas.Area{
+		{
+			ID: "office", Name: "Office",
+			ItemGroups: []areas.ItemGroup{
+				{ID: "small", Name: "Small room", Items: []areas.Item{{ID: "s1", Name: "S1"}, {ID: "s2", Name: "S2"}}},
+				{ID: "big", Name: "Big room", Items: []areas.Item{
+					{ID: "b1", Name: "B1"}, {ID: "b2", Name: "B2"}, {ID: "b3", Name: "B3"},
+				}},
+			},
+		},
+		{
+			ID: "garage", Name: "Garage",
+			ItemGroups: []areas.ItemGroup{{ID: "level-1", Name: "Level 1", Items: []areas.Item{{ID: "p1"}}}},
+		},
+	}}
+}
+
+func seedBooking(t *testing.T, store *sql.DB, id, itemID, userID, date string, isGuest bool) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.Exec(
+		`INSERT INTO bookings (id, item_id, user_id, booked_by_user_id, booking_date,
+		 is_guest, guest_name, guest_email, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, '', '', ?, ?)`,
+		id, itemID, userID, userID, date, isGuest, now, now,
+	)
+	require.NoError(t, err)
+}
+
+// nextWeek returns the ISO week after the current one and its Monday.
+func nextWeek() (string, time.Time) {
+	now := time.Now().UTC()
+	monday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday = monday.AddDate(0, 0, 7-(int(monday.Weekday())+6)%7)
+	year, week := monday.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week), monday
+}
+
+func TestWeekHandler(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	team := createTeam(t, store, "Platform", "", "ada", "bob")
+	week, monday := nextWeek()
+	day := func(i int) string { return monday.AddDate(0, 0, i).Format(time.DateOnly) }
+	seedBooking(t, store, "ada-mon", "b1", "ada", day(0), false)
+	seedBooking(t, store, "ada-car", "p1", "ada", day(0), false)
+	seedBooking(t, store, "ada-sun", "retired", "ada", day(6), false)
+	seedBooking(t, store, "ada-guest", "b2", "ada", day(1), true)
+	seedBooking(t, store, "eve-mon", "b2", "eve", day(0), false)
+	seedBooking(t, store, "bob-next", "b1", "bob", day(7), false)
+	require.NoError(t, workstatus.Set(t.Context(), store,
+		&workstatus.Entry{UserID: "ada", Date: day(2), Status: workstatus.StatusRemote, Note: "dentist"}))
+
+	h := WeekHandlerDynamic(weekAreasConfig, store)
+	rec := serve(t, h, bob, http.MethodGet, "/?week="+week, "", "id", team.ID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		Data []struct {
+			ID         string         `json:"id"`
+			Attributes WeekAttributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	adaWeek := resp.Data[0].Attributes
+	assert.Equal(t, "Ada", adaWeek.UserName)
+	require.Len(t, adaWeek.Bookings, 3)
+	assert.Equal(t, "Big room", adaWeek.Bookings[0].ItemGroupName)
+	assert.Equal(t, "garage", adaWeek.Bookings[1].AreaID)
+	assert.Equal(t, "retired", adaWeek.Bookings[2].ItemID)
+	assert.Empty(t, adaWeek.Bookings[2].AreaID)
+	assert.Empty(t, resp.Data[1].Attributes.Bookings)
+	assert.Equal(t, []WeekWorkStatus{{Date: day(2), Status: "remote", Note: "dentist"}}, adaWeek.WorkStatuses)
+
+	assert.Equal(t, http.StatusNotFound, serve(t, h, eve, http.MethodGet, "/", "", "id", team.ID).Code)
+
+	// Admins see the team, but work statuses default to team members only.
+	rec = serve(t, h, admin, http.MethodGet, "/?week="+week, "", "id", team.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Data[0].Attributes.WorkStatuses)
+	require.NoError(t, workstatus.SetVisibility(t.Context(), store, "ada", workstatus.VisibilityEveryone))
+	rec = serve(t, h, admin, http.MethodGet, "/?week="+week, "", "id", team.ID)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data[0].Attributes.WorkStatuses, 1)
+
+	// Private bookings are only listed for viewers who may see the booker.
+	_, err := store.Exec(`UPDATE bookings SET is_private = 1 WHERE id = 'ada-car'`)
+	require.NoError(t, err)
+	rec = serve(t, h, bob, http.MethodGet, "/?week="+week, "", "id", team.ID)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data[0].Attributes.Bookings, 2)
+	rec = serve(t, h, admin, http.MethodGet, "/?week="+week, "", "id", team.ID)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data[0].Attributes.Bookings, 3)
+
+	rec = serve(t, h, ada, http.MethodGet, "/?week=2026-12", "", "id", team.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDaySuggestionHandler(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	team := createTeam(t, store, "Platform", "", "ada", "bob", "eve")
+	week, monday := nextWeek()
+	day := func(i int) string { return monday.AddDate(0, 0, i).Format(time.DateOnly) }
+
+	// Tuesday has two members in and one item free in the big room.
+	seedBooking(t, store, "ada-tue", "b1", "ada", day(1), false)
+	seedBooking(t, store, "bob-tue", "b2", "bob", day(1), false)
+	seedBooking(t, store, "x-tue", "s1", "admin", day(1), false)
+	seedBooking(t, store, "x-tue2", "s2", "admin", day(1), false)
+	// Thursday has one member in and the big room has two free items.
+	seedBooking(t, store, "ada-thu", "b1", "ada", day(3), false)
+
+	h := DaySuggestionHandlerDynamic(weekAreasConfig, store)
+	suggest := func() (int, DaySuggestionAttributes) {
+		rec := serve(t, h, ada, http.MethodGet, "/?week="+week, "", "id", team.ID)
+		var resp struct {
+			Data struct {
+				Attributes DaySuggestionAttributes `json:"attributes"`
+			} `json:"data"`
+		}
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec.Code, resp.Data.Attributes
+	}
+
+	code, got := suggest()
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, day(1), got.Date)
+	assert.Equal(t, []string{"Ada", "Bob"}, got.MembersIn)
+	assert.Equal(t, 1, got.MembersMissing)
+	assert.Equal(t, "big", got.ItemGroupID)
+
+	// Without room on Tuesday, Thursday wins over the emptier days.
+	seedBooking(t, store, "x-tue3", "b3", "admin", day(1), false)
+	seedBooking(t, store, "x-tue4", "p1", "admin", day(1), false)
+	code, got = suggest()
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, day(3), got.Date)
+	assert.Equal(t, 2, got.MembersMissing)
+	assert.Equal(t, 2, got.FreeItems)
+
+	empty := createTeam(t, store, "Empty", "")
+	rec := serve(t, h, admin, http.MethodGet, "/", "", "id", empty.ID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), errorCodeNoTeamDay)
+}
@@ -21,6 +21,17 @@ const (
 	resourceTypeUser   = "users"
 )
 
+// userQueryOptions lists the sort and filter fields of the users collection.
+var userQueryOptions = api.QueryOptions{
+	Sort:    []string{"display_name", "email", "role", "auth_source", "last_login", "created_at"},
+	Filters: []string{"email", "role", "auth_source", "is_admin"},
+}
+
+// colleagueQueryOptions lists the sort and filter fields of the colleagues collection.
+var colleagueQueryOptions = api.QueryOptions{
+	Sort: []string{"display_name"},
+}
+
 // UserAttributes represents user resource attributes for JSON:API responses.
 type UserAttributes struct {
 	Email       string `json:"email"`
@@ -34,8 +45,13 @@ type UserAttributes struct {
 }
 
 // ListHandler returns a handler for listing all users.
+// Supports JSON:API page, sort, filter, and fields parameters.
 func ListHandler(store *sql.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		query, err := api.ParseQuery(c, userQueryOptions)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
 		ctx := c.Request().Context()
 
 		records, err := ListAll(ctx, store)
@@ -50,10 +66,7 @@ func ListHandler(store *sql.DB) echo.HandlerFunc {
 				Attributes: recordToAttributes(&rec),
 			}
 		})
-
-		resp := api.CollectionResponse{Data: resources}
-		c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
-		return c.JSON(http.StatusOK, resp)
+		return api.WriteQueryCollection(c, query, resources, "write users response")
 	}
 }
 
@@ -64,8 +77,13 @@ type ColleagueAttributes struct {
 
 // ColleaguesHandler returns a handler for listing all users with minimal data.
 // This endpoint is accessible to all authenticated users (not admin-only).
+// Supports JSON:API page, sort, filter, and fields parameters.
 func ColleaguesHandler(store *sql.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		query, err := api.ParseQuery(c, colleagueQueryOptions)
+		if err != nil {
+			return api.WriteBadRequest(c, err.Error())
+		}
 		ctx := c.Request().Context()
 
 		colleagues, err := ListColleagues(ctx, store)
@@ -80,10 +98,7 @@ func ColleaguesHandler(store *sql.DB) echo.HandlerFunc {
 				Attributes: ColleagueAttributes{DisplayName: col.DisplayName},
 			}
 		})
-
-		resp := api.CollectionResponse{Data: resources}
-		c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
-		return c.JSON(http.StatusOK, resp)
+		return api.WriteQueryCollection(c, query, resources, "write colleagues response")
 	}
 }
 
@@ -111,6 +126,7 @@ func GetHandler(store *sql.DB) echo.HandlerFunc {
 				Attributes: recordToAttributes(rec),
 			},
 		}
+		api.SetETag(c, rec.Version)
 		c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
 		return c.JSON(http.StatusOK, resp)
 	}
@@ -171,6 +187,7 @@ func CreateHandler(store *sql.DB) echo.HandlerFunc {
 				Attributes: recordToAttributes(rec),
 			},
 		}
+		api.SetETag(c, rec.Version)
 		c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
 		return c.JSON(http.StatusCreated, resp)
 	}
@@ -188,6 +205,7 @@ type updateUserRequest struct {
 }
 
 // UpdateHandler returns a handler for updating a user (admin only).
+// An If-Match header must carry the user's current ETag, or 412 is returned.
 func UpdateHandler(store *sql.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		userID := c.Param("id")
@@ -202,12 +220,19 @@ func UpdateHandler(store *sql.DB) echo.HandlerFunc {
 
 		ctx := c.Request().Context()
 
-		if err := handlePasswordResetIfRequested(ctx, c, store, userID, req.Data.Attributes.Password); err != nil {
+		// The steps below write their own error responses; stop once one did.
+		ifVersion := api.IfMatch(c)
+		if err := checkIfMatch(ctx, c, store, userID, ifVersion); err != nil || c.Response().Committed {
 			return err
 		}
 
-		rec, err := applyFieldUpdates(ctx, c, store, userID, req.Data.Attributes)
-		if err != nil {
+		err := handlePasswordResetIfRequested(ctx, c, store, userID, req.Data.Attributes.Password)
+		if err != nil || c.Response().Committed {
+			return err
+		}
+
+		rec, err := applyFieldUpdates(ctx, c, store, userID, ifVersion, req.Data.Attributes)
+		if err != nil || rec == nil {
 			return err
 		}
 
@@ -215,6 +240,26 @@ func UpdateHandler(store *sql.DB) echo.HandlerFunc {
 	}
 }
 
+// checkIfMatch writes a 412 response if the user no longer has the version
+// required by the If-Match header. It checks before any write so that a stale
+// request does not reset the password.
+func checkIfMatch(ctx context.Context, c echo.Context, store *sql.DB, userID string, ifVersion int) error {
+	if ifVersion == 0 {
+		return nil
+	}
+	rec, err := FindByID(ctx, store, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return api.WriteNotFound(c, "User not found") //nolint:wrapcheck // Terminal response
+	}
+	if err != nil {
+		return fmt.Errorf("find user for if-match: %w", err)
+	}
+	if !api.Matches(ifVersion, rec.Version) {
+		return api.WritePreconditionFailed(c) //nolint:wrapcheck // Terminal response
+	}
+	return nil
+}
+
 func handlePasswordResetIfRequested(
 	ctx context.Context, c echo.Context, store *sql.DB, userID string, password *string,
 ) error {
@@ -252,7 +297,7 @@ func handlePasswordResetIfRequested(
 	return nil
 }
 
-func applyFieldUpdates(ctx context.Context, c echo.Context, store *sql.DB, userID string, attrs struct {
+func applyFieldUpdates(ctx context.Context, c echo.Context, store *sql.DB, userID string, ifVersion int, attrs struct {
 	Email       *string `json:"email,omitempty"`
 	DisplayName *string `json:"display_name,omitempty"`
 	IsAdmin     *bool   `json:"is_admin,omitempty"`
@@ -262,6 +307,7 @@ func applyFieldUpdates(ctx context.Context, c echo.Context, store *sql.DB, userI
 		Email:       trimPtr(attrs.Email),
 		DisplayName: trimPtr(attrs.DisplayName),
 		IsAdmin:     attrs.IsAdmin,
+		IfVersion:   ifVersion,
 	}
 
 	rec, err := UpdateUser(ctx, store, userID, fields)
@@ -272,6 +318,9 @@ func applyFieldUpdates(ctx context.Context, c echo.Context, store *sql.DB, userI
 		//nolint:wrapcheck // Terminal response
 		return nil, api.WriteConflict(c, "A user with this email already exists")
 	}
+	if errors.Is(err, ErrVersionConflict) {
+		return nil, api.WritePreconditionFailed(c) //nolint:wrapcheck // Terminal response
+	}
 	if err != nil {
 		return nil, fmt.Errorf("update user: %w", err)
 	}
@@ -287,11 +336,13 @@ func respondWithUpdatedUser(c echo.Context, rec *Record) error {
 			Attributes: recordToAttributes(rec),
 		},
 	}
+	api.SetETag(c, rec.Version)
 	c.Response().Header().Set(echo.HeaderContentType, api.JSONAPIContentType)
 	return c.JSON(http.StatusOK, resp) //nolint:wrapcheck // Terminal response
 }
 
 // DeleteHandler returns a handler for deleting a local user (admin only).
+// An If-Match header must carry the user's current ETag, or 412 is returned.
 func DeleteHandler(store *sql.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		userID := c.Param("id")
@@ -320,7 +371,15 @@ func DeleteHandler(store *sql.DB) echo.HandlerFunc {
 			return api.WriteBadRequest(c, "Only local users can be deleted (Entra ID users are managed externally)")
 		}
 
-		if err := DeleteUser(ctx, store, userID); err != nil {
+		ifVersion := api.IfMatch(c)
+		if !api.Matches(ifVersion, rec.Version) {
+			return api.WritePreconditionFailed(c)
+		}
+		err = DeleteUser(ctx, store, userID, ifVersion)
+		if errors.Is(err, ErrVersionConflict) {
+			return api.WritePreconditionFailed(c)
+		}
+		if err != nil {
 			return fmt.Errorf("delete user: %w", err)
 		}
 
@@ -44,7 +44,8 @@ func setupHandlerDB(t *testing.T) *sql.DB {
 			last_login TEXT NOT NULL DEFAULT '',
 			access_token TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
-			updated_at TEXT NOT NULL
+			updated_at TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1
 		);
 		CREATE UNIQUE INDEX idx_users_email ON users(email);
 		CREATE INDEX idx_users_entra_id ON users(entra_id);
@@ -92,6 +93,34 @@ func TestListHandler(t *testing.T) {
 	assert.Len(t, resp.Data, 2)
 }
 
+func TestListHandlerQueryParams(t *testing.T) {
+	db := setupHandlerDB(t)
+	seedUser(t, db, "alice@test.com", "Alice", "internal", false)
+	seedUser(t, db, "bob@test.com", "Bob", "internal", true)
+	seedUser(t, db, "carol@test.com", "Carol", "internal", false)
+
+	e := echo.New()
+	target := "/api/v1/users?filter%5Brole%5D=user&sort=-display_name&page%5Bsize%5D=1&fields%5Busers%5D=email"
+	req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	require.NoError(t, ListHandler(db)(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, map[string]any{"email": "carol@test.com"}, resp.Data[0].Attributes)
+	assert.Equal(t, 2, resp.Meta.Total)
+	assert.Contains(t, resp.Links.Next, "page%5Bcursor%5D=")
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/users?sort=password", http.NoBody)
+	rec = httptest.NewRecorder()
+	require.NoError(t, ListHandler(db)(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
 func TestGetHandler(t *testing.T) {
 	db := setupHandlerDB(t)
 	user := seedUser(t, db, "alice@test.com", "Alice", "internal", false)
@@ -316,3 +345,39 @@ func TestDeleteHandlerNotFound(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
+
+func TestUpdateAndDeleteHandlerCheckIfMatch(t *testing.T) {
+	db := setupHandlerDB(t)
+	user := seedUser(t, db, "alice@test.com", "Alice", "internal", false)
+
+	send := func(handler echo.HandlerFunc, method, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/users/"+user.ID, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(user.ID)
+		c.Set("user", &testUser{ID: "admin-user"})
+		require.NoError(t, handler(c))
+		return rec
+	}
+	rename := `{"data":{"attributes":{"display_name":"Alice Updated"}}}`
+	reset := `{"data":{"attributes":{"password":"NewSecurePassword!!"}}}`
+
+	rec := send(UpdateHandler(db), http.MethodPatch, rename, `"1"`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	rec = send(UpdateHandler(db), http.MethodPatch, reset, `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	stored, err := FindByID(t.Context(), db, user.ID)
+	require.NoError(t, err)
+	assert.Error(t, VerifyPassword(stored.PasswordHash, "NewSecurePassword!!"), "stale request must not reset password")
+
+	rec = send(DeleteHandler(db), http.MethodDelete, "", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = send(DeleteHandler(db), http.MethodDelete, "", `"2"`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
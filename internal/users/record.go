@@ -15,6 +15,7 @@ type Record struct {
 	LastLogin    string
 	CreatedAt    string
 	UpdatedAt    string
+	Version      int
 }
 
 // Sentinel errors for user operations.
@@ -22,4 +23,6 @@ var (
 	ErrEmailConflict = errors.New("email already exists")
 	ErrUserNotFound  = errors.New("user not found")
 	ErrNotLocalUser  = errors.New("operation only allowed for local users")
+	// ErrVersionConflict is returned when a user changed since the caller read it.
+	ErrVersionConflict = errors.New("user version conflict")
 )
@@ -17,7 +17,7 @@ const (
 	bcryptCost  = 12
 	userColumns = `id, email, display_name, password_hash,
 		user_source, entra_id, is_admin, last_login,
-		created_at, updated_at`
+		created_at, updated_at, version`
 )
 
 // FindByID returns a user by primary key, or ErrUserNotFound.
@@ -169,14 +169,17 @@ type UpdateFields struct {
 	Email       *string
 	DisplayName *string
 	IsAdmin     *bool
+	// IfVersion, when non-zero, only applies the update if the user still has this version.
+	IfVersion int
 }
 
-// UpdateUser applies partial updates to a user.
+// UpdateUser applies partial updates to a user and bumps its version.
+// Returns ErrVersionConflict if fields.IfVersion no longer matches.
 func UpdateUser(ctx context.Context, db *sql.DB, id string, fields UpdateFields) (*Record, error) {
 	now := time.Now().UTC().Format(time.RFC3339)
 
 	// Build dynamic SET clause
-	setClauses := []string{"updated_at = ?"}
+	setClauses := []string{"updated_at = ?", "version = version + 1"}
 	args := []interface{}{now}
 
 	if fields.Email != nil {
@@ -196,11 +199,11 @@ func UpdateUser(ctx context.Context, db *sql.DB, id string, fields UpdateFields)
 		args = append(args, isAdminInt)
 	}
 
-	args = append(args, id)
+	args = append(args, id, fields.IfVersion, fields.IfVersion)
 
 	// setClauses contains only hardcoded column names, not user input.
 	query := "UPDATE users SET " + //nolint:gosec // G202 false positive
-		strings.Join(setClauses, ", ") + " WHERE id = ?"
+		strings.Join(setClauses, ", ") + " WHERE id = ? AND (? = 0 OR version = ?)"
 
 	result, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
@@ -211,31 +214,42 @@ func UpdateUser(ctx context.Context, db *sql.DB, id string, fields UpdateFields)
 		return nil, fmt.Errorf("update user: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return nil, fmt.Errorf("update user rows: %w", err)
-	}
-	if rows == 0 {
-		return nil, ErrUserNotFound
+	if err := checkVersionedWrite(ctx, db, result, id, fields.IfVersion); err != nil {
+		return nil, err
 	}
 
 	return FindByID(ctx, db, id)
 }
 
-// DeleteUser removes a user by ID. Returns ErrUserNotFound if no row was deleted.
-func DeleteUser(ctx context.Context, db *sql.DB, id string) error {
-	result, err := db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
+// DeleteUser removes a user by ID. When ifVersion is non-zero the user is only
+// deleted if it still has that version. Returns ErrUserNotFound if no row was
+// deleted, or ErrVersionConflict if the version no longer matches.
+func DeleteUser(ctx context.Context, db *sql.DB, id string, ifVersion int) error {
+	result, err := db.ExecContext(ctx,
+		"DELETE FROM users WHERE id = ? AND (? = 0 OR version = ?)", id, ifVersion, ifVersion)
 	if err != nil {
 		return fmt.Errorf("delete user: %w", err)
 	}
+	return checkVersionedWrite(ctx, db, result, id, ifVersion)
+}
+
+// checkVersionedWrite maps a versioned write that touched no row to
+// ErrUserNotFound or, if the user still exists, to ErrVersionConflict.
+func checkVersionedWrite(ctx context.Context, db *sql.DB, result sql.Result, id string, ifVersion int) error {
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("delete user rows: %w", err)
+		return fmt.Errorf("versioned write rows: %w", err)
 	}
-	if rows == 0 {
+	if rows > 0 {
+		return nil
+	}
+	if ifVersion == 0 {
 		return ErrUserNotFound
 	}
-	return nil
+	if _, err := FindByID(ctx, db, id); err != nil {
+		return err
+	}
+	return ErrVersionConflict
 }
 
 // UpdatePasswordHash sets a new password hash for a user.
@@ -395,7 +409,7 @@ func scanOne(row *sql.Row) (*Record, error) {
 	err := row.Scan(
 		&rec.ID, &rec.Email, &rec.DisplayName, &rec.PasswordHash,
 		&rec.UserSource, &rec.EntraID, &isAdminInt, &rec.LastLogin,
-		&rec.CreatedAt, &rec.UpdatedAt,
+		&rec.CreatedAt, &rec.UpdatedAt, &rec.Version,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrUserNotFound
@@ -417,7 +431,7 @@ func scanRow(row rowScanner) (*Record, error) {
 	err := row.Scan(
 		&rec.ID, &rec.Email, &rec.DisplayName, &rec.PasswordHash,
 		&rec.UserSource, &rec.EntraID, &isAdminInt, &rec.LastLogin,
-		&rec.CreatedAt, &rec.UpdatedAt,
+		&rec.CreatedAt, &rec.UpdatedAt, &rec.Version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("scan user row: %w", err)
@@ -30,7 +30,8 @@ func setupTestDB(t *testing.T) *sql.DB {
 			last_login TEXT NOT NULL DEFAULT '',
 			access_token TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
-			updated_at TEXT NOT NULL
+			updated_at TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1
 		);
 		CREATE UNIQUE INDEX idx_users_email ON users(email);
 		CREATE INDEX idx_users_entra_id ON users(entra_id);
@@ -344,7 +345,7 @@ func TestDeleteUser(t *testing.T) {
 	created, err := CreateLocalUser(ctx, db, "henry@example.com", "Henry", hash, false)
 	require.NoError(t, err)
 
-	err = DeleteUser(ctx, db, created.ID)
+	err = DeleteUser(ctx, db, created.ID, 0)
 	require.NoError(t, err)
 
 	_, err = FindByID(ctx, db, created.ID)
@@ -356,7 +357,7 @@ func TestDeleteUser_NotFound(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	err := DeleteUser(ctx, db, "nonexistent")
+	err := DeleteUser(ctx, db, "nonexistent", 0)
 	assert.ErrorIs(t, err, ErrUserNotFound)
 }
 
@@ -0,0 +1,470 @@
+package visitors
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+	"github.com/thorstenkramm/sithub/internal/users"
+)
+
+const (
+	resourceTypeVisitor = "visitors"
+	resourceTypeVisit   = "visits"
+	maxNameLength       = 200
+)
+
+// Receptionists lists the email addresses of users who run the reception desk.
+// Receptionists and admins see and check in the visitors of all hosts; other
+// users only manage their own visitors.
+type Receptionists []string
+
+// Allows reports whether the user may manage the visitors of all hosts.
+func (r Receptionists) Allows(user *auth.User) bool {
+	if user.IsAdmin {
+		return true
+	}
+	for _, email := range r {
+		if email != "" && strings.EqualFold(strings.TrimSpace(email), user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// VisitorAttributes represents visitor resource attributes.
+type VisitorAttributes struct {
+	Name       string `json:"name"`
+	Email      string `json:"email,omitempty"`
+	Company    string `json:"company,omitempty"`
+	HostUserID string `json:"host_user_id"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// VisitAttributes represents visit resource attributes.
+type VisitAttributes struct {
+	VisitorID       string `json:"visitor_id"`
+	VisitorName     string `json:"visitor_name"`
+	VisitorEmail    string `json:"visitor_email,omitempty"`
+	Company         string `json:"company,omitempty"`
+	HostUserID      string `json:"host_user_id"`
+	HostName        string `json:"host_name,omitempty"`
+	VisitDate       string `json:"visit_date"`
+	ExpectedArrival string `json:"expected_arrival,omitempty"`
+	Status          string `json:"status"`
+	ArrivedAt       string `json:"arrived_at,omitempty"`
+	LeftAt          string `json:"left_at,omitempty"`
+	BookingID       string `json:"booking_id,omitempty"`
+	ItemID          string `json:"item_id,omitempty"`
+	ItemName        string `json:"item_name,omitempty"`
+	AreaName        string `json:"area_name,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+type visitorInput struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Company string `json:"company"`
+}
+
+type createVisitorRequest struct {
+	Data struct {
+		Type       string       `json:"type"`
+		Attributes visitorInput `json:"attributes"`
+	} `json:"data"`
+}
+
+type createVisitRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			visitorInput
+			VisitorID       string `json:"visitor_id"`
+			VisitDate       string `json:"visit_date"`
+			ExpectedArrival string `json:"expected_arrival"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListVisitorsHandler returns the visitors of the current user, or of all hosts
+// for receptionists, filtered by the q query parameter.
+// GET /api/v1/visitors
+func ListVisitorsHandler(store *sql.DB, receptionists Receptionists) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		host := user.ID
+		if receptionists.Allows(user) {
+			host = ""
+		}
+		list, err := ListVisitors(c.Request().Context(), store, host, c.QueryParam("q"))
+		if err != nil {
+			return api.WriteInternalError(c, "list visitors", err)
+		}
+		resources := api.MapResources(list, func(v Visitor) api.Resource { return visitorResource(&v) })
+		return api.WriteCollection(c, resources, "write visitors response")
+	}
+}
+
+// CreateVisitorHandler saves a visitor record hosted by the current user.
+// POST /api/v1/visitors
+func CreateVisitorHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req createVisitorRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeVisitor {
+			return api.WriteBadRequest(c, "Resource type must be 'visitors'")
+		}
+		v, detail := newVisitor(&req.Data.Attributes, user.ID)
+		if v == nil {
+			return api.WriteBadRequest(c, detail)
+		}
+		if err := CreateVisitor(c.Request().Context(), store, v); err != nil {
+			return api.WriteInternalError(c, "create visitor", err)
+		}
+		return api.WriteSingle(c, http.StatusCreated, visitorResource(v), "write visitor response")
+	}
+}
+
+// ListVisitsHandler returns the visits of a day (default today) ordered by
+// expected arrival. Receptionists see all visits; other users their own.
+// GET /api/v1/visits
+func ListVisitsHandler(getConfig areas.ConfigGetter, store *sql.DB, receptionists Receptionists) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		date, err := api.ParseBookingDate(c.QueryParam("date"), getConfig().DefaultZone())
+		if err != nil {
+			return api.WriteBadRequest(c, "date must be in YYYY-MM-DD format")
+		}
+		host := user.ID
+		if receptionists.Allows(user) {
+			host = ""
+		}
+		ctx := c.Request().Context()
+		entries, err := ListDay(ctx, store, date, host)
+		if err != nil {
+			return api.WriteInternalError(c, "list visits", err)
+		}
+		hostIDs := make([]string, len(entries))
+		for i := range entries {
+			hostIDs[i] = entries[i].HostUserID
+		}
+		hostNames, err := users.FindDisplayNames(ctx, store, hostIDs)
+		if err != nil {
+			return api.WriteInternalError(c, "find visit hosts", err)
+		}
+		cfg := getConfig()
+		resources := api.MapResources(entries, func(e DayEntry) api.Resource {
+			return visitResource(cfg, &e, hostNames[e.HostUserID])
+		})
+		return api.WriteCollection(c, resources, "write visits response")
+	}
+}
+
+// CreateVisitHandler registers an expected visit hosted by the current user,
+// for a saved visitor (visitor_id) or a new one (name, email, company).
+// POST /api/v1/visits
+func CreateVisitHandler(getConfig areas.ConfigGetter, store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req createVisitRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeVisit {
+			return api.WriteBadRequest(c, "Resource type must be 'visits'")
+		}
+		a := req.Data.Attributes
+		date := strings.TrimSpace(a.VisitDate)
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return api.WriteBadRequest(c, "visit_date must be in YYYY-MM-DD format")
+		}
+		if date < areas.LocalDate(time.Now(), getConfig().DefaultZone()) {
+			return api.WriteBadRequest(c, "visit_date must not be in the past")
+		}
+		arrival := strings.TrimSpace(a.ExpectedArrival)
+		if !ValidArrival(arrival) {
+			return api.WriteBadRequest(c, "expected_arrival must be in HH:MM format")
+		}
+
+		ctx := c.Request().Context()
+		visitor, isNew, err := ResolveVisitor(ctx, store, user, a.VisitorID, a.Name, a.Email, a.Company)
+		if err != nil {
+			return writeResolveError(c, err)
+		}
+		if isNew {
+			if err := CreateVisitor(ctx, store, visitor); err != nil {
+				return api.WriteInternalError(c, "create visitor", err)
+			}
+		}
+		visit, err := AddVisit(ctx, store, visitor, date, arrival)
+		if errors.Is(err, ErrDuplicateVisit) {
+			return api.WriteConflict(c, fmt.Sprintf("%s is already expected on %s", visitor.Name, date))
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "create visit", err)
+		}
+		entry := &DayEntry{
+			Visit: *visit, VisitorName: visitor.Name, VisitorEmail: visitor.Email, VisitorCompany: visitor.Company,
+		}
+		resource := visitResource(getConfig(), entry, user.Name)
+		return api.WriteSingle(c, http.StatusCreated, resource, "write visit response")
+	}
+}
+
+// CheckInHandler marks a visitor as arrived and notifies the host.
+// POST /api/v1/visits/:id/check-in
+func CheckInHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier, receptionists Receptionists,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return changeStatus(c, getConfig, store, receptionists, StatusExpected, func(e *DayEntry) {
+			e.Status = StatusArrived
+			e.ArrivedAt = time.Now().UTC().Format(time.RFC3339)
+			notifier.NotifyAsync(&notifications.BookingEvent{
+				Event:        notifications.EventVisitorArrived,
+				BookingID:    e.BookingID,
+				ItemID:       e.ItemID,
+				UserID:       e.HostUserID,
+				BookingDate:  e.VisitDate,
+				IsGuest:      true,
+				GuestName:    e.VisitorName,
+				GuestEmail:   e.VisitorEmail,
+				GuestCompany: e.VisitorCompany,
+				VisitID:      e.ID,
+				Timestamp:    e.ArrivedAt,
+			})
+		})
+	}
+}
+
+// CheckOutHandler marks an arrived visitor as gone.
+// POST /api/v1/visits/:id/check-out
+func CheckOutHandler(getConfig areas.ConfigGetter, store *sql.DB, receptionists Receptionists) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return changeStatus(c, getConfig, store, receptionists, StatusArrived, func(e *DayEntry) {
+			e.Status = StatusLeft
+			e.LeftAt = time.Now().UTC().Format(time.RFC3339)
+		})
+	}
+}
+
+// DeleteVisitHandler cancels a visit that has not started yet.
+// DELETE /api/v1/visits/:id
+func DeleteVisitHandler(store *sql.DB, receptionists Receptionists) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		ctx := c.Request().Context()
+		visit, err := findAccessibleVisit(ctx, store, c.Param("id"), user, receptionists)
+		if errors.Is(err, ErrVisitNotFound) {
+			return api.WriteNotFound(c, "Visit not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "find visit", err)
+		}
+		if visit.Status != StatusExpected {
+			return api.WriteConflict(c, "Only expected visits can be canceled")
+		}
+		if err := DeleteVisit(ctx, store, visit.ID); err != nil {
+			return api.WriteInternalError(c, "delete visit", err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// ResolveVisitor returns the saved visitor with visitorID, or else a new
+// visitor hosted by user, which the caller must save with CreateVisitor. New
+// visitors already have their ID. Users can only reuse their own visitors;
+// admins can reuse any. Errors are ErrVisitorNotFound, an *InputError, or a
+// store error.
+func ResolveVisitor(
+	ctx context.Context, store *sql.DB, user *auth.User, visitorID, name, email, company string,
+) (v *Visitor, isNew bool, err error) {
+	if id := strings.TrimSpace(visitorID); id != "" {
+		v, err := FindVisitor(ctx, store, id)
+		if err != nil {
+			return nil, false, err
+		}
+		if v.HostUserID != user.ID && !user.IsAdmin {
+			return nil, false, ErrVisitorNotFound
+		}
+		return v, false, nil
+	}
+	v, detail := newVisitor(&visitorInput{Name: name, Email: email, Company: company}, user.ID)
+	if v == nil {
+		return nil, false, &InputError{Detail: detail}
+	}
+	v.ID = NewID()
+	return v, true, nil
+}
+
+// InputError describes invalid visitor details.
+type InputError struct {
+	Detail string
+}
+
+func (e *InputError) Error() string { return e.Detail }
+
+func writeResolveError(c echo.Context, err error) error {
+	var inputErr *InputError
+	switch {
+	case errors.Is(err, ErrVisitorNotFound):
+		return api.WriteBadRequest(c, "visitor_id: visitor not found")
+	case errors.As(err, &inputErr):
+		return api.WriteBadRequest(c, inputErr.Detail)
+	default:
+		return api.WriteInternalError(c, "resolve visitor", err)
+	}
+}
+
+// newVisitor validates the input and returns an unsaved visitor, or nil and a
+// bad-request detail.
+func newVisitor(in *visitorInput, hostUserID string) (*Visitor, string) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return nil, "name is required"
+	}
+	if len(name) > maxNameLength {
+		return nil, fmt.Sprintf("name must be at most %d characters", maxNameLength)
+	}
+	return &Visitor{
+		Name:       name,
+		Email:      strings.TrimSpace(in.Email),
+		Company:    strings.TrimSpace(in.Company),
+		HostUserID: hostUserID,
+	}, ""
+}
+
+// changeStatus moves a visit from the from status to the status set by apply,
+// then writes the updated visit.
+func changeStatus(
+	c echo.Context, getConfig areas.ConfigGetter, store *sql.DB, receptionists Receptionists,
+	from string, apply func(*DayEntry),
+) error {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		return api.WriteUnauthorized(c)
+	}
+	ctx := c.Request().Context()
+	visit, err := findAccessibleVisit(ctx, store, c.Param("id"), user, receptionists)
+	if errors.Is(err, ErrVisitNotFound) {
+		return api.WriteNotFound(c, "Visit not found")
+	}
+	if err != nil {
+		return api.WriteInternalError(c, "find visit", err)
+	}
+	if visit.Status != from {
+		return api.WriteConflict(c, fmt.Sprintf("Visit is %s, expected %s", visit.Status, from))
+	}
+
+	entries, err := ListDay(ctx, store, visit.VisitDate, visit.HostUserID)
+	if err != nil {
+		return api.WriteInternalError(c, "find visit", err)
+	}
+	var entry *DayEntry
+	for i := range entries {
+		if entries[i].ID == visit.ID {
+			entry = &entries[i]
+		}
+	}
+	if entry == nil {
+		return api.WriteNotFound(c, "Visit not found")
+	}
+	apply(entry)
+	if err := UpdateVisitStatus(ctx, store, &entry.Visit); err != nil {
+		return api.WriteInternalError(c, "update visit", err)
+	}
+	hostNames, err := users.FindDisplayNames(ctx, store, []string{entry.HostUserID})
+	if err != nil {
+		return api.WriteInternalError(c, "find visit host", err)
+	}
+	resource := visitResource(getConfig(), entry, hostNames[entry.HostUserID])
+	return api.WriteSingle(c, http.StatusOK, resource, "write visit response")
+}
+
+// findAccessibleVisit returns the visit when the user hosts it or is a
+// receptionist; otherwise ErrVisitNotFound.
+func findAccessibleVisit(
+	ctx context.Context, store *sql.DB, id string, user *auth.User, receptionists Receptionists,
+) (*Visit, error) {
+	visit, err := FindVisit(ctx, store, id)
+	if err != nil {
+		return nil, err
+	}
+	if visit.HostUserID != user.ID && !receptionists.Allows(user) {
+		return nil, ErrVisitNotFound
+	}
+	return visit, nil
+}
+
+func visitorResource(v *Visitor) api.Resource {
+	return api.Resource{
+		Type: resourceTypeVisitor,
+		ID:   v.ID,
+		Attributes: VisitorAttributes{
+			Name:       v.Name,
+			Email:      v.Email,
+			Company:    v.Company,
+			HostUserID: v.HostUserID,
+			CreatedAt:  v.CreatedAt,
+			UpdatedAt:  v.UpdatedAt,
+		},
+	}
+}
+
+func visitResource(cfg *areas.Config, e *DayEntry, hostName string) api.Resource {
+	attrs := VisitAttributes{
+		VisitorID:       e.VisitorID,
+		VisitorName:     e.VisitorName,
+		VisitorEmail:    e.VisitorEmail,
+		Company:         e.VisitorCompany,
+		HostUserID:      e.HostUserID,
+		HostName:        hostName,
+		VisitDate:       e.VisitDate,
+		ExpectedArrival: e.ExpectedArrival,
+		Status:          e.Status,
+		ArrivedAt:       e.ArrivedAt,
+		LeftAt:          e.LeftAt,
+		BookingID:       e.BookingID,
+		ItemID:          e.ItemID,
+		CreatedAt:       e.CreatedAt,
+		UpdatedAt:       e.UpdatedAt,
+	}
+	if e.ItemID != "" {
+		if loc, ok := cfg.FindItemLocation(e.ItemID); ok {
+			attrs.ItemName = loc.Item.Name
+			attrs.AreaName = loc.Area.Name
+		}
+	}
+	return api.Resource{Type: resourceTypeVisit, ID: e.ID, Attributes: attrs}
+}
@@ -0,0 +1,210 @@
+package visitors
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/db"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []*notifications.BookingEvent
+}
+
+func (n *recordingNotifier) NotifyAsync(event *notifications.BookingEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+}
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+	return store
+}
+
+func testConfig() *areas.Config {
+	return &areas.Config{Areas: []areas.Area{{
+		ID:   "office",
+		Name: "Office",
+		ItemGroups: []areas.ItemGroup{
+			{ID: "room-1", Name: "Room 1", Items: []areas.Item{{ID: "desk-1", Name: "Desk 1"}}},
+		},
+	}}}
+}
+
+func getTestConfig() *areas.Config { return testConfig() }
+
+var (
+	host         = &auth.User{ID: "host-1", Name: "Hannah Host", Email: "hannah@example.com"}
+	colleague    = &auth.User{ID: "user-2", Name: "Colin", Email: "colin@example.com"}
+	receptionist = &auth.User{ID: "desk-1", Name: "Reception", Email: "Desk@Example.com"}
+	desk         = Receptionists{"desk@example.com"}
+)
+
+func newContext(method, target, body string, user *auth.User) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", user)
+	return c, rec
+}
+
+func withID(c echo.Context, id string) echo.Context {
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	return c
+}
+
+func decodeCollection(t *testing.T, rec *httptest.ResponseRecorder) []api.Resource {
+	t.Helper()
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Data
+}
+
+// createTestVisit registers a visit by host for the given day and returns its ID.
+func createTestVisit(t *testing.T, store *sql.DB, date, name, arrival string) string {
+	t.Helper()
+	body := `{"data":{"type":"visits","attributes":{"name":"` + name + `","company":"Acme",` +
+		`"visit_date":"` + date + `","expected_arrival":"` + arrival + `"}}}`
+	c, rec := newContext(http.MethodPost, "/api/v1/visits", body, host)
+	require.NoError(t, CreateVisitHandler(getTestConfig, store)(c))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Data.ID
+}
+
+func TestReceptionistsAllows(t *testing.T) {
+	t.Parallel()
+	assert.True(t, desk.Allows(receptionist))
+	assert.True(t, desk.Allows(&auth.User{ID: "admin", IsAdmin: true}))
+	assert.False(t, desk.Allows(host))
+	assert.False(t, Receptionists{""}.Allows(&auth.User{ID: "anon"}))
+}
+
+func TestListVisitsScopesToHostUnlessReceptionist(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	createTestVisit(t, store, date, "Zoe Late", "")
+	createTestVisit(t, store, date, "Adam Early", "08:15")
+	createTestVisit(t, store, date, "Bea Mid", "10:00")
+
+	list := func(user *auth.User) []api.Resource {
+		c, rec := newContext(http.MethodGet, "/api/v1/visits?date="+date, "", user)
+		require.NoError(t, ListVisitsHandler(getTestConfig, store, desk)(c))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return decodeCollection(t, rec)
+	}
+
+	all := list(receptionist)
+	require.Len(t, all, 3)
+	names := make([]any, len(all))
+	for i, r := range all {
+		attrs, ok := r.Attributes.(map[string]any)
+		require.True(t, ok)
+		names[i] = attrs["visitor_name"]
+	}
+	assert.Equal(t, []any{"Adam Early", "Bea Mid", "Zoe Late"}, names)
+
+	assert.Len(t, list(host), 3)
+	assert.Empty(t, list(colleague))
+}
+
+func TestCheckInNotifiesHost(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	date := time.Now().UTC().Format(time.DateOnly)
+	id := createTestVisit(t, store, date, "Jane Guest", "09:00")
+	notifier := &recordingNotifier{}
+	checkIn := CheckInHandler(getTestConfig, store, notifier, desk)
+
+	c, rec := newContext(http.MethodPost, "/api/v1/visits/"+id+"/check-in", "", colleague)
+	require.NoError(t, checkIn(withID(c, id)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	c, rec = newContext(http.MethodPost, "/api/v1/visits/"+id+"/check-in", "", receptionist)
+	require.NoError(t, checkIn(withID(c, id)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, notifier.events, 1)
+	event := notifier.events[0]
+	assert.Equal(t, notifications.EventVisitorArrived, event.Event)
+	assert.Equal(t, host.ID, event.UserID)
+	assert.Equal(t, "Jane Guest", event.GuestName)
+	assert.Equal(t, "Acme", event.GuestCompany)
+	assert.Equal(t, id, event.VisitID)
+
+	c, rec = newContext(http.MethodPost, "/api/v1/visits/"+id+"/check-in", "", receptionist)
+	require.NoError(t, checkIn(withID(c, id)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	c, rec = newContext(http.MethodPost, "/api/v1/visits/"+id+"/check-out", "", host)
+	require.NoError(t, CheckOutHandler(getTestConfig, store, desk)(withID(c, id)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	visit, err := FindVisit(t.Context(), store, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusLeft, visit.Status)
+	assert.NotEmpty(t, visit.ArrivedAt)
+	assert.NotEmpty(t, visit.LeftAt)
+
+	c, rec = newContext(http.MethodDelete, "/api/v1/visits/"+id, "", host)
+	require.NoError(t, DeleteVisitHandler(store, desk)(withID(c, id)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestCreateVisitReusesVisitor(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	date := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	c, rec := newContext(http.MethodPost, "/api/v1/visitors",
+		`{"data":{"type":"visitors","attributes":{"name":"Jane Guest","email":"jane@example.com"}}}`, host)
+	require.NoError(t, CreateVisitorHandler(store)(c))
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var resp api.SingleResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	visitorID := resp.Data.ID
+
+	body := `{"data":{"type":"visits","attributes":{"visitor_id":"` + visitorID + `","visit_date":"` + date + `"}}}`
+	c, rec = newContext(http.MethodPost, "/api/v1/visits", body, host)
+	require.NoError(t, CreateVisitHandler(getTestConfig, store)(c))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	c, rec = newContext(http.MethodPost, "/api/v1/visits", body, host)
+	require.NoError(t, CreateVisitHandler(getTestConfig, store)(c))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	c, rec = newContext(http.MethodPost, "/api/v1/visits", body, colleague)
+	require.NoError(t, CreateVisitHandler(getTestConfig, store)(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	c, rec = newContext(http.MethodGet, "/api/v1/visitors?q=jane", "", colleague)
+	require.NoError(t, ListVisitorsHandler(store, desk)(c))
+	assert.Empty(t, decodeCollection(t, rec))
+	c, rec = newContext(http.MethodGet, "/api/v1/visitors?q=jane", "", host)
+	require.NoError(t, ListVisitorsHandler(store, desk)(c))
+	assert.Len(t, decodeCollection(t, rec), 1)
+}
@@ -0,0 +1,84 @@
+package visitors
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PurgeResult counts the rows removed or anonymized by Purge.
+type PurgeResult struct {
+	Visits        int64
+	Visitors      int64
+	GuestBookings int64
+}
+
+// Purge removes guest personal data from before the YYYY-MM-DD date: visits,
+// visitors without later visits or bookings, and the guest name and email of
+// past guest bookings. The bookings themselves are kept for statistics.
+func Purge(ctx context.Context, db *sql.DB, before string) (result PurgeResult, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("begin purge: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback() //nolint:errcheck // Rollback after failure, original error wins
+		}
+	}()
+
+	steps := []struct {
+		count *int64
+		query string
+		args  []any
+	}{
+		{&result.Visits, `DELETE FROM visits WHERE visit_date < ?`, []any{before}},
+		{&result.Visitors, `DELETE FROM visitors WHERE created_at < ?
+			AND NOT EXISTS (SELECT 1 FROM visits WHERE visits.visitor_id = visitors.id)
+			AND NOT EXISTS (SELECT 1 FROM bookings WHERE bookings.user_id = visitors.id
+				AND bookings.booking_date >= ?)`, []any{before, before}},
+		{&result.GuestBookings, `UPDATE bookings SET guest_name = '', guest_email = '', version = version + 1
+			WHERE is_guest = 1 AND booking_date < ? AND (guest_name != '' OR guest_email != '')`, []any{before}},
+	}
+	for _, step := range steps {
+		res, err := tx.ExecContext(ctx, step.query, step.args...)
+		if err != nil {
+			return result, fmt.Errorf("purge guest data: %w", err)
+		}
+		if *step.count, err = res.RowsAffected(); err != nil {
+			return result, fmt.Errorf("count purged guest data: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("commit purge: %w", err)
+	}
+	return result, nil
+}
+
+// RunPurge purges guest data older than retentionDays every interval until ctx
+// is done. A retentionDays of 0 keeps guest data forever.
+func RunPurge(ctx context.Context, db *sql.DB, retentionDays int, interval time.Duration) {
+	if retentionDays <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		before := time.Now().UTC().AddDate(0, 0, -retentionDays).Format(time.DateOnly)
+		result, err := Purge(ctx, db, before)
+		switch {
+		case err != nil:
+			slog.Error("purge guest data", "error", err)
+		case result != PurgeResult{}:
+			slog.Info("guest data purged", "before", before, "visits", result.Visits,
+				"visitors", result.Visitors, "guest_bookings", result.GuestBookings)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package visitors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeRemovesOldGuestData(t *testing.T) {
+	t.Parallel()
+	store := setupTestDB(t)
+	ctx := t.Context()
+
+	old := &Visitor{Name: "Old Guest", Email: "old@example.com", HostUserID: "host-1"}
+	require.NoError(t, CreateVisitor(ctx, store, old))
+	recent := &Visitor{Name: "Recent Guest", HostUserID: "host-1"}
+	require.NoError(t, CreateVisitor(ctx, store, recent))
+	_, err := store.ExecContext(ctx, `UPDATE visitors SET created_at = '2026-01-01T00:00:00Z'`)
+	require.NoError(t, err)
+
+	_, err = AddVisit(ctx, store, old, "2026-02-01", "")
+	require.NoError(t, err)
+	_, err = AddVisit(ctx, store, recent, "2026-02-01", "")
+	require.NoError(t, err)
+	_, err = AddVisit(ctx, store, recent, "2026-06-01", "")
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = store.ExecContext(ctx, `INSERT INTO bookings
+		(id, item_id, user_id, booked_by_user_id, booking_date, is_guest, guest_name, guest_email,
+		 created_at, updated_at)
+		VALUES ('b1', 'desk-1', ?, 'host-1', '2026-02-01', 1, 'Old Guest', 'old@example.com', ?, ?)`,
+		old.ID, now, now)
+	require.NoError(t, err)
+
+	result, err := Purge(ctx, store, "2026-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, PurgeResult{Visits: 2, Visitors: 1, GuestBookings: 1}, result)
+
+	_, err = FindVisitor(ctx, store, old.ID)
+	require.ErrorIs(t, err, ErrVisitorNotFound)
+	_, err = FindVisitor(ctx, store, recent.ID)
+	require.NoError(t, err)
+
+	var name, email string
+	require.NoError(t, store.QueryRowContext(ctx,
+		`SELECT guest_name, guest_email FROM bookings WHERE id = 'b1'`).Scan(&name, &email))
+	assert.Empty(t, name)
+	assert.Empty(t, email)
+
+	result, err = Purge(ctx, store, "2026-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, PurgeResult{}, result)
+}
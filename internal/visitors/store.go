@@ -0,0 +1,265 @@
+// Package visitors manages guest records, their visits, and reception check-in.
+package visitors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Visit statuses.
+const (
+	StatusExpected = "expected"
+	StatusArrived  = "arrived"
+	StatusLeft     = "left"
+)
+
+// ErrVisitorNotFound indicates the requested visitor does not exist.
+var ErrVisitorNotFound = errors.New("visitor not found")
+
+// ErrVisitNotFound indicates the requested visit does not exist.
+var ErrVisitNotFound = errors.New("visit not found")
+
+// ErrDuplicateVisit indicates the visitor already has a visit on that day.
+var ErrDuplicateVisit = errors.New("duplicate visit")
+
+// Visitor represents a visitors row. The ID doubles as bookings.user_id for the
+// visitor's guest bookings.
+type Visitor struct {
+	ID         string
+	Name       string
+	Email      string
+	Company    string
+	HostUserID string
+	CreatedAt  string
+	UpdatedAt  string
+}
+
+// Visit represents a visits row.
+type Visit struct {
+	ID              string
+	VisitorID       string
+	HostUserID      string
+	VisitDate       string
+	ExpectedArrival string
+	Status          string
+	ArrivedAt       string
+	LeftAt          string
+	CreatedAt       string
+	UpdatedAt       string
+}
+
+// DayEntry is a visit with its visitor and the guest booking of that day, if any.
+type DayEntry struct {
+	Visit
+	VisitorName    string
+	VisitorEmail   string
+	VisitorCompany string
+	BookingID      string
+	ItemID         string
+}
+
+const (
+	visitorColumns = `id, name, email, company, host_user_id, created_at, updated_at`
+	visitColumns   = `id, visitor_id, host_user_id, visit_date, expected_arrival, status,
+		arrived_at, left_at, created_at, updated_at`
+)
+
+// NewID returns a new visitor ID.
+func NewID() string {
+	return "guest-" + uuid.NewString()
+}
+
+// ValidArrival reports whether s is empty or a HH:MM time.
+func ValidArrival(s string) bool {
+	if s == "" {
+		return true
+	}
+	_, err := time.Parse("15:04", s)
+	return err == nil && len(s) == len("15:04")
+}
+
+// CreateVisitor inserts a visitor. A missing ID is generated.
+func CreateVisitor(ctx context.Context, db *sql.DB, v *Visitor) error {
+	if v.ID == "" {
+		v.ID = NewID()
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	v.CreatedAt, v.UpdatedAt = now, now
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO visitors (`+visitorColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		v.ID, v.Name, v.Email, v.Company, v.HostUserID, v.CreatedAt, v.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert visitor: %w", err)
+	}
+	return nil
+}
+
+// FindVisitor returns a visitor by ID.
+func FindVisitor(ctx context.Context, db *sql.DB, id string) (*Visitor, error) {
+	var v Visitor
+	err := db.QueryRowContext(ctx, `SELECT `+visitorColumns+` FROM visitors WHERE id = ?`, id).Scan(
+		&v.ID, &v.Name, &v.Email, &v.Company, &v.HostUserID, &v.CreatedAt, &v.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVisitorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find visitor: %w", err)
+	}
+	return &v, nil
+}
+
+// ListVisitors returns visitors ordered by name. An empty hostUserID lists the
+// visitors of all hosts; query filters by name, email, or company.
+func ListVisitors(ctx context.Context, db *sql.DB, hostUserID, query string) (result []Visitor, err error) {
+	where := []string{"1 = 1"}
+	var args []any
+	if hostUserID != "" {
+		where = append(where, "host_user_id = ?")
+		args = append(args, hostUserID)
+	}
+	if q := strings.TrimSpace(query); q != "" {
+		pattern := "%" + strings.ToLower(q) + "%"
+		where = append(where, "(LOWER(name) LIKE ? OR LOWER(email) LIKE ? OR LOWER(company) LIKE ?)")
+		args = append(args, pattern, pattern, pattern)
+	}
+	//nolint:gosec // G202: where clauses are constants with "?" placeholders
+	rows, err := db.QueryContext(ctx,
+		`SELECT `+visitorColumns+` FROM visitors WHERE `+strings.Join(where, " AND ")+` ORDER BY name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list visitors: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close visitor rows: %w", closeErr)
+		}
+	}()
+	for rows.Next() {
+		var v Visitor
+		err := rows.Scan(&v.ID, &v.Name, &v.Email, &v.Company, &v.HostUserID, &v.CreatedAt, &v.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan visitor: %w", err)
+		}
+		result = append(result, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate visitors: %w", err)
+	}
+	return result, nil
+}
+
+// AddVisit registers an expected visit. Returns ErrDuplicateVisit when the
+// visitor already has a visit on that day.
+func AddVisit(ctx context.Context, db *sql.DB, visitor *Visitor, visitDate, expectedArrival string) (*Visit, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	v := &Visit{
+		ID:              uuid.NewString(),
+		VisitorID:       visitor.ID,
+		HostUserID:      visitor.HostUserID,
+		VisitDate:       visitDate,
+		ExpectedArrival: expectedArrival,
+		Status:          StatusExpected,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO visits (`+visitColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		v.ID, v.VisitorID, v.HostUserID, v.VisitDate, v.ExpectedArrival, v.Status,
+		v.ArrivedAt, v.LeftAt, v.CreatedAt, v.UpdatedAt,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return nil, ErrDuplicateVisit
+		}
+		return nil, fmt.Errorf("insert visit: %w", err)
+	}
+	return v, nil
+}
+
+// FindVisit returns a visit by ID.
+func FindVisit(ctx context.Context, db *sql.DB, id string) (*Visit, error) {
+	var v Visit
+	err := db.QueryRowContext(ctx, `SELECT `+visitColumns+` FROM visits WHERE id = ?`, id).Scan(
+		&v.ID, &v.VisitorID, &v.HostUserID, &v.VisitDate, &v.ExpectedArrival, &v.Status,
+		&v.ArrivedAt, &v.LeftAt, &v.CreatedAt, &v.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVisitNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find visit: %w", err)
+	}
+	return &v, nil
+}
+
+// UpdateVisitStatus stores the status and check-in/out times of a visit.
+func UpdateVisitStatus(ctx context.Context, db *sql.DB, v *Visit) error {
+	v.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err := db.ExecContext(ctx,
+		`UPDATE visits SET status = ?, arrived_at = ?, left_at = ?, updated_at = ? WHERE id = ?`,
+		v.Status, v.ArrivedAt, v.LeftAt, v.UpdatedAt, v.ID)
+	if err != nil {
+		return fmt.Errorf("update visit: %w", err)
+	}
+	return nil
+}
+
+// DeleteVisit removes a visit.
+func DeleteVisit(ctx context.Context, db *sql.DB, id string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM visits WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete visit: %w", err)
+	}
+	return nil
+}
+
+// ListDay returns the visits of a day ordered by expected arrival, then name.
+// Visits without an expected arrival come last. A non-empty hostUserID limits
+// the list to that host's visitors.
+func ListDay(ctx context.Context, db *sql.DB, visitDate, hostUserID string) (result []DayEntry, err error) {
+	q := `SELECT v.id, v.visitor_id, v.host_user_id, v.visit_date, v.expected_arrival, v.status,
+			v.arrived_at, v.left_at, v.created_at, v.updated_at, g.name, g.email, g.company,
+			COALESCE((SELECT b.id FROM bookings b
+				WHERE b.user_id = v.visitor_id AND b.booking_date = v.visit_date LIMIT 1), ''),
+			COALESCE((SELECT b.item_id FROM bookings b
+				WHERE b.user_id = v.visitor_id AND b.booking_date = v.visit_date LIMIT 1), '')
+		FROM visits v JOIN visitors g ON g.id = v.visitor_id
+		WHERE v.visit_date = ?`
+	args := []any{visitDate}
+	if hostUserID != "" {
+		q += ` AND v.host_user_id = ?`
+		args = append(args, hostUserID)
+	}
+	q += ` ORDER BY v.expected_arrival = '', v.expected_arrival, g.name`
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list visits: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close visit rows: %w", closeErr)
+		}
+	}()
+	for rows.Next() {
+		var e DayEntry
+		if err := rows.Scan(
+			&e.ID, &e.VisitorID, &e.HostUserID, &e.VisitDate, &e.ExpectedArrival, &e.Status,
+			&e.ArrivedAt, &e.LeftAt, &e.CreatedAt, &e.UpdatedAt, &e.VisitorName, &e.VisitorEmail,
+			&e.VisitorCompany, &e.BookingID, &e.ItemID,
+		); err != nil {
+			return nil, fmt.Errorf("scan visit: %w", err)
+		}
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate visits: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,295 @@
+package workstatus
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+const (
+	resourceTypeWorkStatus = "work-statuses"
+	resourceTypeSettings   = "work-status-settings"
+	maxNoteLength          = 200
+	defaultRangeDays       = 28
+	maxRangeDays           = 92
+)
+
+// Attributes represents work status resource attributes. CanceledBookingIDs
+// and OpenBookings are only set in the response to setting a status: the
+// bookings canceled on request, and the user's bookings left on a remote or
+// off day.
+type Attributes struct {
+	Date               string        `json:"date"`
+	Status             string        `json:"status"`
+	Note               string        `json:"note,omitempty"`
+	CreatedAt          string        `json:"created_at"`
+	UpdatedAt          string        `json:"updated_at"`
+	CanceledBookingIDs []string      `json:"canceled_booking_ids,omitempty"`
+	OpenBookings       []OpenBooking `json:"open_bookings,omitempty"`
+}
+
+// OpenBooking is a booking on a day the user does not work in the office.
+type OpenBooking struct {
+	BookingID string `json:"booking_id"`
+	ItemID    string `json:"item_id"`
+	ItemName  string `json:"item_name,omitempty"`
+}
+
+// SettingsAttributes represents the work status settings of a user.
+type SettingsAttributes struct {
+	Visibility string `json:"visibility"`
+}
+
+type setRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			Status         string `json:"status"`
+			Note           string `json:"note"`
+			CancelBookings bool   `json:"cancel_bookings"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type settingsRequest struct {
+	Data struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			Visibility string `json:"visibility"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// ListHandler returns the current user's work statuses from the from date to
+// the to date (default: the next four weeks).
+// GET /api/v1/me/work-statuses
+func ListHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		from, to, ok := parseRange(c.QueryParam("from"), c.QueryParam("to"))
+		if !ok {
+			return api.WriteBadRequest(c, "Invalid range. Use from and to as YYYY-MM-DD, at most 92 days apart.")
+		}
+		list, err := ListRange(c.Request().Context(), store, []string{user.ID}, from, to)
+		if err != nil {
+			return api.WriteInternalError(c, "list work statuses", err)
+		}
+		resources := api.MapResources(list, func(e Entry) api.Resource { return statusResource(&e) })
+		return api.WriteCollection(c, resources, "write work statuses response")
+	}
+}
+
+// SetHandler sets the current user's work status on a date. For remote and
+// off days, cancel_bookings cancels the user's own bookings on the date unless
+// their cancellation cut-off has passed; bookings left are returned as
+// open_bookings so clients can offer to cancel them.
+// PUT /api/v1/me/work-statuses/:date
+func SetHandler(
+	getConfig areas.ConfigGetter, store *sql.DB, notifier notifications.Notifier,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		cfg := getConfig()
+		date, ok := parseDate(c.Param("date"))
+		if !ok {
+			return api.WriteBadRequest(c, "Invalid date. Use YYYY-MM-DD.")
+		}
+		if earliest, _ := cfg.TodayRange(time.Now()); date < earliest {
+			return api.WriteBadRequest(c, "date must not be in the past")
+		}
+		var req setRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeWorkStatus {
+			return api.WriteBadRequest(c, "Resource type must be 'work-statuses'")
+		}
+		a := req.Data.Attributes
+		if !ValidStatus(a.Status) {
+			return api.WriteBadRequest(c, "status must be 'office', 'remote', 'travelling', or 'off'")
+		}
+		awayDay := a.Status == StatusRemote || a.Status == StatusOff
+		if a.CancelBookings && !awayDay {
+			return api.WriteBadRequest(c, "cancel_bookings is only allowed for remote and off days")
+		}
+		note := strings.TrimSpace(a.Note)
+		if utf8.RuneCountInString(note) > maxNoteLength {
+			return api.WriteBadRequest(c, "note must be at most 200 characters")
+		}
+
+		ctx := c.Request().Context()
+		e := &Entry{UserID: user.ID, Date: date, Status: a.Status, Note: note}
+		if err := Set(ctx, store, e); err != nil {
+			return api.WriteInternalError(c, "set work status", err)
+		}
+		slog.Info("work status set", "user_id", user.ID, "date", date, "status", e.Status)
+
+		attrs := statusAttributes(e)
+		if awayDay {
+			var open []bookings.BookingRecord
+			var err error
+			if a.CancelBookings {
+				var canceled []bookings.BookingRecord
+				canceled, open, err = bookings.CancelDayBookings(ctx, store, cfg, notifier,
+					user.ID, date, "work status: "+e.Status)
+				for i := range canceled {
+					attrs.CanceledBookingIDs = append(attrs.CanceledBookingIDs, canceled[i].ID)
+				}
+			} else {
+				open, err = bookings.ListOwnBookingsOn(ctx, store, user.ID, date)
+			}
+			if err != nil {
+				return api.WriteInternalError(c, "cancel day bookings", err)
+			}
+			for i := range open {
+				ob := OpenBooking{BookingID: open[i].ID, ItemID: open[i].ItemID}
+				if item, ok := cfg.FindItem(open[i].ItemID); ok {
+					ob.ItemName = item.Name
+				}
+				attrs.OpenBookings = append(attrs.OpenBookings, ob)
+			}
+		}
+		res := api.Resource{Type: resourceTypeWorkStatus, ID: e.Date, Attributes: attrs}
+		return api.WriteSingle(c, http.StatusOK, res, "write work status response")
+	}
+}
+
+// DeleteHandler removes the current user's work status on a date.
+// DELETE /api/v1/me/work-statuses/:date
+func DeleteHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		date := c.Param("date")
+		err := Delete(c.Request().Context(), store, user.ID, date)
+		if errors.Is(err, ErrNotFound) {
+			return api.WriteNotFound(c, "Work status not found")
+		}
+		if err != nil {
+			return api.WriteInternalError(c, "delete work status", err)
+		}
+		slog.Info("work status deleted", "user_id", user.ID, "date", date)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// SettingsHandler returns who sees the current user's work statuses.
+// GET /api/v1/me/work-status-settings
+func SettingsHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		visibilities, err := FindVisibilities(c.Request().Context(), store, []string{user.ID})
+		if err != nil {
+			return api.WriteInternalError(c, "find work status visibility", err)
+		}
+		return api.WriteSingle(c, http.StatusOK, settingsResource(user.ID, visibilities[user.ID]),
+			"write work status settings response")
+	}
+}
+
+// UpdateSettingsHandler sets who sees the current user's work statuses:
+// everyone, members of a shared team (the default), or nobody.
+// PATCH /api/v1/me/work-status-settings
+func UpdateSettingsHandler(store *sql.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			return api.WriteUnauthorized(c)
+		}
+		var req settingsRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return api.WriteBadRequest(c, "Invalid request body")
+		}
+		if req.Data.Type != resourceTypeSettings {
+			return api.WriteBadRequest(c, "Resource type must be 'work-status-settings'")
+		}
+		visibility := req.Data.Attributes.Visibility
+		if !ValidVisibility(visibility) {
+			return api.WriteBadRequest(c, "visibility must be 'everyone', 'team', or 'nobody'")
+		}
+		if err := SetVisibility(c.Request().Context(), store, user.ID, visibility); err != nil {
+			return api.WriteInternalError(c, "set work status visibility", err)
+		}
+		slog.Info("work status visibility changed", "user_id", user.ID, "visibility", visibility)
+		return api.WriteSingle(c, http.StatusOK, settingsResource(user.ID, visibility),
+			"write work status settings response")
+	}
+}
+
+func parseDate(s string) (string, bool) {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return "", false
+	}
+	return d.Format(time.DateOnly), true
+}
+
+// parseRange returns the from and to dates of a list request. An empty from
+// is today (UTC) and an empty to is four weeks after from.
+func parseRange(fromParam, toParam string) (from, to string, ok bool) {
+	start := time.Now().UTC()
+	if fromParam != "" {
+		var err error
+		if start, err = time.Parse(time.DateOnly, fromParam); err != nil {
+			return "", "", false
+		}
+	}
+	end := start.AddDate(0, 0, defaultRangeDays-1)
+	if toParam != "" {
+		var err error
+		if end, err = time.Parse(time.DateOnly, toParam); err != nil {
+			return "", "", false
+		}
+	}
+	from, to = start.Format(time.DateOnly), end.Format(time.DateOnly)
+	if to < from || end.Sub(start) >= maxRangeDays*24*time.Hour {
+		return "", "", false
+	}
+	return from, to, true
+}
+
+func statusResource(e *Entry) api.Resource {
+	return api.Resource{Type: resourceTypeWorkStatus, ID: e.Date, Attributes: statusAttributes(e)}
+}
+
+func statusAttributes(e *Entry) Attributes {
+	return Attributes{
+		Date:      e.Date,
+		Status:    e.Status,
+		Note:      e.Note,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+func settingsResource(userID, visibility string) api.Resource {
+	return api.Resource{
+		Type:       resourceTypeSettings,
+		ID:         userID,
+		Attributes: SettingsAttributes{Visibility: visibility},
+	}
+}
@@ -0,0 +1,243 @@
+package workstatus
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+	"github.com/thorstenkramm/sithub/internal/areas"
+	"github.com/thorstenkramm/sithub/internal/auth"
+	"github.com/thorstenkramm/sithub/internal/bookings"
+	"github.com/thorstenkramm/sithub/internal/db"
+	"github.com/thorstenkramm/sithub/internal/notifications"
+)
+
+var (
+	ada = &auth.User{ID: "ada", Name: "Ada"}
+	bob = &auth.User{ID: "bob", Name: "Bob"}
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := db.Open(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	require.NoError(t, db.RunMigrations(store))
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, u := range []*auth.User{ada, bob} {
+		_, err := store.Exec(
+			`INSERT INTO users (id, email, display_name, user_source, created_at, updated_at)
+			 VALUES (?, ?, ?, 'internal', ?, ?)`,
+			u.ID, u.ID+"@example.com", u.Name, now, now,
+		)
+		require.NoError(t, err)
+	}
+	return store
+}
+
+// testConfig has an office without a cancellation cut-off and a lab whose
+// bookings can no longer be canceled on the booked day.
+func testConfig() *areas.Config {
+	return &areas.Config{Areas: []areas.Area{
+		{
+			ID: "office", Name: "Office",
+			ItemGroups: []areas.ItemGroup{
+				{ID: "room-1", Name: "Room 1", Items: []areas.Item{
+					{ID: "desk-1", Name: "Desk 1"}, {ID: "desk-2"}, {ID: "desk-3"},
+				}},
+			},
+		},
+		{
+			ID: "lab", Name: "Lab", Cancellation: &areas.Cancellation{CutoffTime: "00:00"},
+			ItemGroups: []areas.ItemGroup{
+				{ID: "bench", Name: "Bench", Items: []areas.Item{{ID: "lab-1", Name: "Lab 1"}}},
+			},
+		},
+	}}
+}
+
+func seedBooking(t *testing.T, store *sql.DB, id, itemID, userID, date string, isGuest bool) {
+	t.Helper()
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err := store.Exec(
+		`INSERT INTO bookings (id, item_id, user_id, booked_by_user_id, booking_date,
+		 is_guest, guest_name, guest_email, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, '', '', ?, ?)`,
+		id, itemID, userID, userID, date, isGuest, now, now,
+	)
+	require.NoError(t, err)
+}
+
+func serve(
+	t *testing.T, h echo.HandlerFunc, user *auth.User, method, target, body, date string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, api.JSONAPIContentType)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if date != "" {
+		c.SetParamNames("date")
+		c.SetParamValues(date)
+	}
+	c.Set("user", user)
+	require.NoError(t, h(c))
+	return rec
+}
+
+func setStatus(t *testing.T, store *sql.DB, date string, attrs map[string]any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"data": map[string]any{"type": "work-statuses", "attributes": attrs}})
+	require.NoError(t, err)
+	h := SetHandler(testConfig, store, &notifications.NoopNotifier{})
+	return serve(t, h, ada, http.MethodPut, "/api/v1/me/work-statuses/"+date, string(body), date)
+}
+
+func decodeStatus(t *testing.T, rec *httptest.ResponseRecorder) Attributes {
+	t.Helper()
+	var resp struct {
+		Data struct {
+			Attributes Attributes `json:"attributes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Data.Attributes
+}
+
+func TestSetHandlerOffersAndCancelsDayBookings(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	today, _ := testConfig().TodayRange(time.Now())
+	seedBooking(t, store, "desk", "desk-1", ada.ID, today, false)
+	seedBooking(t, store, "lab", "lab-1", ada.ID, today, false)
+	seedBooking(t, store, "guest", "desk-2", ada.ID, today, true)
+	seedBooking(t, store, "bob", "desk-3", bob.ID, today, false)
+
+	rec := setStatus(t, store, today, map[string]any{"status": "remote", "note": " home "})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	got := decodeStatus(t, rec)
+	assert.Equal(t, "remote", got.Status)
+	assert.Equal(t, "home", got.Note)
+	assert.Empty(t, got.CanceledBookingIDs)
+	assert.ElementsMatch(t, []OpenBooking{
+		{BookingID: "desk", ItemID: "desk-1", ItemName: "Desk 1"},
+		{BookingID: "lab", ItemID: "lab-1", ItemName: "Lab 1"},
+	}, got.OpenBookings)
+
+	rec = setStatus(t, store, today, map[string]any{"status": "off", "cancel_bookings": true})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	got = decodeStatus(t, rec)
+	assert.Equal(t, "off", got.Status)
+	assert.Equal(t, []string{"desk"}, got.CanceledBookingIDs)
+	assert.Equal(t, []OpenBooking{{BookingID: "lab", ItemID: "lab-1", ItemName: "Lab 1"}}, got.OpenBookings)
+
+	left, err := bookings.ListOwnBookingsOn(t.Context(), store, ada.ID, today)
+	require.NoError(t, err)
+	require.Len(t, left, 1)
+	left, err = bookings.ListOwnBookingsOn(t.Context(), store, bob.ID, today)
+	require.NoError(t, err)
+	assert.Len(t, left, 1, "other users' bookings stay")
+
+	rec = setStatus(t, store, today, map[string]any{"status": "office"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, decodeStatus(t, rec).OpenBookings, "office days offer nothing to cancel")
+}
+
+func TestSetHandlerValidation(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	lastWeek := time.Now().AddDate(0, 0, -7).Format(time.DateOnly)
+	for _, tc := range []struct {
+		date  string
+		attrs map[string]any
+	}{
+		{tomorrow, map[string]any{"status": "sick"}},
+		{tomorrow, map[string]any{"status": "office", "cancel_bookings": true}},
+		{tomorrow, map[string]any{"status": "travelling", "cancel_bookings": true}},
+		{tomorrow, map[string]any{"status": "office", "note": strings.Repeat("x", 201)}},
+		{lastWeek, map[string]any{"status": "office"}},
+		{"someday", map[string]any{"status": "office"}},
+	} {
+		assert.Equal(t, http.StatusBadRequest, setStatus(t, store, tc.date, tc.attrs).Code, tc)
+	}
+
+	h := SetHandler(testConfig, store, &notifications.NoopNotifier{})
+	rec := serve(t, h, ada, http.MethodPut, "/", `{"data":{"type":"bookings"}}`, tomorrow)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestListAndDeleteHandlers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	require.Equal(t, http.StatusOK, setStatus(t, store, tomorrow, map[string]any{"status": "travelling"}).Code)
+	require.NoError(t, Set(t.Context(), store, &Entry{UserID: bob.ID, Date: tomorrow, Status: StatusOff}))
+
+	list := ListHandler(store)
+	rec := serve(t, list, ada, http.MethodGet, "/api/v1/me/work-statuses", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp api.CollectionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, tomorrow, resp.Data[0].ID)
+	assert.Contains(t, rec.Body.String(), `"status":"travelling"`)
+
+	for _, query := range []string{"?from=2026-01-10&to=2026-01-09", "?from=2026-01-01&to=2026-06-01", "?to=x"} {
+		rec = serve(t, list, ada, http.MethodGet, "/api/v1/me/work-statuses"+query, "", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	del := DeleteHandler(store)
+	assert.Equal(t, http.StatusNoContent, serve(t, del, ada, http.MethodDelete, "/", "", tomorrow).Code)
+	assert.Equal(t, http.StatusNotFound, serve(t, del, ada, http.MethodDelete, "/", "", tomorrow).Code)
+	entries, err := ListRange(t.Context(), store, []string{bob.ID}, tomorrow, tomorrow)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestSettingsHandlers(t *testing.T) {
+	t.Parallel()
+
+	store := setupTestDB(t)
+	rec := serve(t, SettingsHandler(store), ada, http.MethodGet, "/", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"visibility":"team"`)
+
+	update := UpdateSettingsHandler(store)
+	body := `{"data":{"type":"work-status-settings","attributes":{"visibility":"nobody"}}}`
+	rec = serve(t, update, ada, http.MethodPatch, "/", body, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	visibilities, err := FindVisibilities(t.Context(), store, []string{ada.ID, bob.ID})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{ada.ID: VisibilityNobody, bob.ID: VisibilityTeam}, visibilities)
+
+	body = `{"data":{"type":"work-status-settings","attributes":{"visibility":"friends"}}}`
+	assert.Equal(t, http.StatusBadRequest, serve(t, update, ada, http.MethodPatch, "/", body, "").Code)
+}
+
+func TestCanSee(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, CanSee("ada", "ada", VisibilityNobody, false))
+	assert.True(t, CanSee("bob", "ada", VisibilityEveryone, false))
+	assert.True(t, CanSee("bob", "ada", VisibilityTeam, true))
+	assert.False(t, CanSee("bob", "ada", VisibilityTeam, false))
+	assert.False(t, CanSee("bob", "ada", VisibilityNobody, true))
+}
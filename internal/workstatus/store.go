@@ -0,0 +1,185 @@
+// Package workstatus tracks where users work each day, independent of their
+// bookings.
+package workstatus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thorstenkramm/sithub/internal/api"
+)
+
+// Work statuses.
+const (
+	StatusOffice     = "office"
+	StatusRemote     = "remote"
+	StatusTravelling = "travelling"
+	StatusOff        = "off"
+)
+
+// Visibilities of a user's work statuses. The user always sees their own.
+const (
+	VisibilityEveryone = "everyone"
+	VisibilityTeam     = "team"
+	VisibilityNobody   = "nobody"
+)
+
+// ErrNotFound indicates the user has no work status on the date.
+var ErrNotFound = errors.New("work status not found")
+
+// Entry is the work status of a user on a date.
+type Entry struct {
+	UserID    string
+	Date      string
+	Status    string
+	Note      string
+	CreatedAt string
+	UpdatedAt string
+}
+
+// ValidStatus reports whether s is a known work status.
+func ValidStatus(s string) bool {
+	switch s {
+	case StatusOffice, StatusRemote, StatusTravelling, StatusOff:
+		return true
+	}
+	return false
+}
+
+// ValidVisibility reports whether v is a known visibility.
+func ValidVisibility(v string) bool {
+	return v == VisibilityEveryone || v == VisibilityTeam || v == VisibilityNobody
+}
+
+// CanSee reports whether viewerID may see the work statuses of ownerID, who
+// chose visibility. sharesTeam tells whether both are members of one team.
+func CanSee(viewerID, ownerID, visibility string, sharesTeam bool) bool {
+	switch {
+	case viewerID == ownerID, visibility == VisibilityEveryone:
+		return true
+	case visibility == VisibilityTeam:
+		return sharesTeam
+	}
+	return false
+}
+
+// Set stores e, replacing the user's status on the date.
+func Set(ctx context.Context, db *sql.DB, e *Entry) error {
+	e.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if _, err := db.ExecContext(ctx,
+		`INSERT INTO work_statuses (user_id, status_date, status, note, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (user_id, status_date) DO UPDATE
+		 SET status = excluded.status, note = excluded.note, updated_at = excluded.updated_at`,
+		e.UserID, e.Date, e.Status, e.Note, e.UpdatedAt, e.UpdatedAt,
+	); err != nil {
+		return fmt.Errorf("upsert work status: %w", err)
+	}
+	if err := db.QueryRowContext(ctx,
+		`SELECT created_at FROM work_statuses WHERE user_id = ? AND status_date = ?`, e.UserID, e.Date,
+	).Scan(&e.CreatedAt); err != nil {
+		return fmt.Errorf("find work status: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the user's status on the date, or returns ErrNotFound.
+func Delete(ctx context.Context, db *sql.DB, userID, date string) error {
+	res, err := db.ExecContext(ctx,
+		`DELETE FROM work_statuses WHERE user_id = ? AND status_date = ?`, userID, date)
+	if err != nil {
+		return fmt.Errorf("delete work status: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete work status: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListRange returns the statuses of the given users from one YYYY-MM-DD date
+// to another (inclusive), ordered by date and user.
+func ListRange(ctx context.Context, db *sql.DB, userIDs []string, from, to string) (result []Entry, err error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	placeholders, args := api.BuildINClause(userIDs)
+	args = append(args, from, to)
+	//nolint:gosec // G201: placeholders are "?" literals from BuildINClause, not user input
+	query := fmt.Sprintf(
+		`SELECT user_id, status_date, status, note, created_at, updated_at FROM work_statuses
+		 WHERE user_id IN (%s) AND status_date >= ? AND status_date <= ?
+		 ORDER BY status_date, user_id`,
+		placeholders,
+	)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query work statuses: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close work statuses rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.UserID, &e.Date, &e.Status, &e.Note, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan work status: %w", err)
+		}
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate work statuses: %w", err)
+	}
+	return result, nil
+}
+
+// FindVisibilities returns the work status visibility of each given user.
+func FindVisibilities(ctx context.Context, db *sql.DB, userIDs []string) (result map[string]string, err error) {
+	result = make(map[string]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	placeholders, args := api.BuildINClause(userIDs)
+	//nolint:gosec // G201: placeholders are "?" literals from BuildINClause, not user input
+	query := fmt.Sprintf(`SELECT id, work_status_visibility FROM users WHERE id IN (%s)`, placeholders)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query work status visibilities: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close visibilities rows: %w", closeErr)
+		}
+	}()
+
+	for rows.Next() {
+		var id, visibility string
+		if err := rows.Scan(&id, &visibility); err != nil {
+			return nil, fmt.Errorf("scan work status visibility: %w", err)
+		}
+		result[id] = visibility
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate work status visibilities: %w", err)
+	}
+	return result, nil
+}
+
+// SetVisibility stores who sees the user's work statuses.
+func SetVisibility(ctx context.Context, db *sql.DB, userID, visibility string) error {
+	if _, err := db.ExecContext(ctx,
+		`UPDATE users SET work_status_visibility = ?, updated_at = ? WHERE id = ?`,
+		visibility, time.Now().UTC().Format(time.RFC3339), userID,
+	); err != nil {
+		return fmt.Errorf("update work status visibility: %w", err)
+	}
+	return nil
+}
@@ -242,6 +242,8 @@ describe('updateBookingNote', () => {
     });
 
     expect(result.data.attributes.note).toBe('Arriving late');
+    const headers = mockFetch.mock.lastCall?.[1].headers as Headers;
+    expect(headers.has('If-Match')).toBe(false);
   });
 
   it('throws ApiError on error response', async () => {
@@ -278,6 +280,19 @@ describe('cancelBooking', () => {
     });
   });
 
+  it('sends the booking version as If-Match', async () => {
+    mockFetch.mockResolvedValueOnce({
+      ok: true
+    });
+
+    await cancelBooking('booking-123', 3);
+
+    expect(mockFetch).toHaveBeenCalledWith('/api/v1/bookings/booking-123', {
+      method: 'DELETE',
+      headers: { Accept: 'application/vnd.api+json', 'If-Match': '"3"' }
+    });
+  });
+
   it('throws ApiError on 404 response', async () => {
     mockFetch.mockResolvedValueOnce({
       ok: false,
@@ -7,6 +7,7 @@ export interface BookingAttributes {
   booking_date: string;
   created_at: string;
   note: string;
+  version?: number;
 }
 
 export interface MyBookingAttributes {
@@ -27,6 +28,7 @@ export interface MyBookingAttributes {
   guest_name?: string;
   guest_email?: string;
   note: string;
+  version?: number;
 }
 
 export interface CreateBookingPayload {
@@ -177,9 +179,16 @@ export function fetchBookingHistory(params?: BookingHistoryParams) {
   return apiRequest<CollectionResponse<MyBookingAttributes>>(url);
 }
 
-export function updateBookingNote(bookingId: string, note: string) {
+// ifMatch returns the If-Match header for a booking version; without a known
+// version no header is sent and the change applies to whatever is stored.
+function ifMatch(version?: number): Record<string, string> {
+  return version ? { 'If-Match': `"${version}"` } : {};
+}
+
+export function updateBookingNote(bookingId: string, note: string, version?: number) {
   return apiRequest<SingleResponse<BookingAttributes>>(`/api/v1/bookings/${bookingId}`, {
     method: 'PATCH',
+    headers: ifMatch(version),
     body: JSON.stringify({
       data: {
         type: 'bookings',
@@ -190,11 +199,12 @@ export function updateBookingNote(bookingId: string, note: string) {
   });
 }
 
-export async function cancelBooking(bookingId: string): Promise<void> {
+export async function cancelBooking(bookingId: string, version?: number): Promise<void> {
   const response = await fetch(`/api/v1/bookings/${bookingId}`, {
     method: 'DELETE',
     headers: {
-      Accept: 'application/vnd.api+json'
+      Accept: 'application/vnd.api+json',
+      ...ifMatch(version)
     }
   });
 